@@ -622,22 +622,53 @@ func mappedRegistryModule(cmd commandModel) (registryModule, error) {
 func staticWorkflowModules() []registryModule {
 	ids := []string{
 		"api.call",
+		"gateway",
+		"instance.browser.cdp",
+		"instance.browser.eval",
+		"instance.browser.navigate",
+		"instance.browser.pdf",
+		"instance.browser.record",
+		"instance.browser.screenshot",
 		"instance.browser.vnc",
 		"instance.code.run",
 		"instance.debug",
 		"instance.exec",
 		"instance.file.download",
+		"instance.forward",
 		"instance.file.upload",
 		"instance.get",
 		"instance.login",
 		"instance.mobile.adb",
 		"instance.mobile.connect",
 		"instance.mobile.disconnect",
+		"instance.mobile.info",
+		"instance.mobile.install",
+		"instance.mobile.keyevent",
 		"instance.mobile.list",
+		"instance.mobile.logcat",
+		"instance.mobile.mirror",
+		"instance.mobile.pull",
+		"instance.mobile.push",
+		"instance.mobile.record",
+		"instance.mobile.screenshot",
+		"instance.mobile.supervise",
+		"instance.mobile.swipe",
+		"instance.mobile.tap",
+		"instance.mobile.text",
 		"instance.mobile.tunnel",
 		"instance.proxy",
+		"instance.prune",
+		"pool.create",
+		"pool.drain",
+		"pool.replenish",
+		"pool.status",
+		"session.end",
+		"session.list",
 		"tool.get",
 		"tool.fork",
+		"tunnel.list",
+		"tunnel.logs",
+		"tunnel.stop",
 		// Identity & Credential modules — workflow adapter mode.
 		// Remove from this list when migrating to mixed-api mode.
 		"credential.oauth2.acquire",
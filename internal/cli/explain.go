@@ -123,18 +123,47 @@ func explainCodeData(code string) (ExplainData, bool) {
 		base.Meaning = "The requested sandbox instance or local tunnel record does not exist."
 		base.AffectedCommands = affectedCommands(code)
 		base.Fix = []string{"agr instance list", "Use an active InstanceId, or recreate/connect the resource."}
+	case "POOL_NOT_FOUND":
+		base.Kind = output.KindNotFound
+		base.ExitCode = output.ExitGenericError
+		base.Meaning = "The requested warm pool is not registered on this machine."
+		base.AffectedCommands = affectedCommands(code)
+		base.Fix = []string{"agr pool status", "agr pool create --name <name> --tool-id <tool-id> --size <n>"}
+	case "TUNNEL_NOT_FOUND":
+		base.Kind = output.KindNotFound
+		base.ExitCode = output.ExitGenericError
+		base.Meaning = "No background proxy or ADB tunnel on this machine matches the given ID, or its log no longer exists."
+		base.AffectedCommands = affectedCommands(code)
+		base.Fix = []string{"agr tunnel list"}
+	case "SESSION_NOT_FOUND":
+		base.Kind = output.KindNotFound
+		base.ExitCode = output.ExitGenericError
+		base.Meaning = "The requested session is not recorded on this machine; it may already have ended."
+		base.AffectedCommands = affectedCommands(code)
+		base.Fix = []string{"agr session list"}
 	case "MISSING_CLOUD_CREDENTIALS", "AUTH_FAILED":
 		base.Kind = output.KindAuthOrPermission
 		base.ExitCode = output.ExitAuthOrPermission
 		base.Meaning = "Tencent Cloud credentials are missing, invalid, or unauthorized."
 		base.AffectedCommands = affectedCommands(code)
 		base.Fix = []string{"agr init --secret-id <id> --secret-key <key>", "agr doctor"}
+	case "PARTIAL_DELETE_FAILED", "PARTIAL_POOL_FILL", "PARTIAL_EXEC_FAILED", "PARTIAL_STOP_FAILED", "PARTIAL_CONNECT_FAILED":
+		base.Kind = output.KindPartialSuccess
+		base.ExitCode = output.ExitPartialSuccess
+		base.Meaning = meaningForCLIUsageCode(code)
+		base.AffectedCommands = affectedCommands(code)
+		base.Fix = fixForCLIUsageCode(code)
 	case "INVALID_USAGE", "JQ_REQUIRES_JSON", "SKELETON_UNSUPPORTED", "CONFLICTING_FLAGS", "CONFLICTING_INPUTS",
 		"MISSING_CODE", "INVALID_ENV", "INVALID_PORT", "INVALID_PAGINATION", "INVALID_LOCAL_PATH", "INVALID_ADDRESS",
-		"INVALID_SHELL", "INVALID_CLEANUP", "MISSING_SEPARATOR", "STDOUT_CONFLICT", "ADB_NOT_FOUND", "UNSUPPORTED_LANGUAGE",
+		"INVALID_SHELL", "INVALID_CLEANUP", "INVALID_COLLECT", "MISSING_SEPARATOR", "STDOUT_CONFLICT", "ADB_NOT_FOUND", "UNSUPPORTED_LANGUAGE",
 		"MISSING_ACTION", "NDJSON_REQUIRES_STREAM", "STREAM_JSON_CONFLICT", "TTY_REQUIRED", "UNIMPLEMENTED_COMMAND",
-		"CONFIG_EXISTS", "CONFIG_INIT_FAILED", "DOCTOR_CHECKS_FAILED", "PARTIAL_DELETE_FAILED", "TOOL_NOT_FOUND",
-		"INVALID_REQUEST_INPUT":
+		"CONFIG_EXISTS", "CONFIG_INIT_FAILED", "DOCTOR_CHECKS_FAILED", "TOOL_NOT_FOUND",
+		"INVALID_REQUEST_INPUT", "INVALID_POOL_SIZE", "INVALID_POOL_NAME",
+		"INVALID_SESSION_NAME", "INVALID_SESSION_TTL", "INVALID_MAX_PARALLEL",
+		"JSON_REQUIRES_BACKGROUND", "INVALID_PORTS_FILE", "INVALID_TAIL", "AMBIGUOUS_TUNNEL",
+		"INVALID_AUTH", "INVALID_ALLOW_IP", "INVALID_TLS", "INVALID_INTERVAL", "INVALID_TIMEOUT", "INVALID_URL",
+		"INVALID_DURATION", "INVALID_COORDINATE", "INVALID_KEYCODE", "INVALID_LOG_LEVEL",
+		"SCRCPY_NOT_FOUND", "INVALID_BIT_RATE":
 		base.Kind = output.KindUsage
 		base.ExitCode = output.ExitUsage
 		base.Meaning = meaningForCLIUsageCode(code)
@@ -170,6 +199,18 @@ func explainCodeData(code string) (ExplainData, bool) {
 		base.Meaning = "The client token has already been used to create a resource."
 		base.AffectedCommands = affectedCommands(code)
 		base.Fix = []string{"Use a new --client-token for a new resource, or recover the original resource from your local record."}
+	case "POOL_CONFLICT":
+		base.Kind = output.KindConflict
+		base.ExitCode = output.ExitGenericError
+		base.Meaning = "A warm pool with the same name already exists for a different tool."
+		base.AffectedCommands = affectedCommands(code)
+		base.Fix = []string{"Choose another --name, or run: agr pool drain <name>"}
+	case "SESSION_CONFLICT":
+		base.Kind = output.KindConflict
+		base.ExitCode = output.ExitGenericError
+		base.Meaning = "The session was started with a different tool or pool than this invocation requests."
+		base.AffectedCommands = affectedCommands(code)
+		base.Fix = []string{"Drop --tool-name/--tool-id/--pool to reuse the session as is, or run: agr session end <name>"}
 	case "REMOTE_CODE_FAILED", "REMOTE_COMMAND_FAILED":
 		base.Kind = output.KindRemoteExecFailed
 		base.ExitCode = output.ExitRemoteExecFailed
@@ -296,7 +337,7 @@ func meaningForCLIUsageCode(code string) string {
 	case "NDJSON_REQUIRES_STREAM":
 		return "ndjson output was requested without a supported streaming command."
 	case "STREAM_JSON_CONFLICT":
-		return "--stream was used together with -o json, which is not supported."
+		return "A streaming command, such as one run with --stream, was used together with -o json, which is not supported."
 	case "TTY_REQUIRED":
 		return "The command requires an interactive TTY terminal."
 	case "INVALID_ADDRESS":
@@ -320,21 +361,73 @@ func meaningForCLIUsageCode(code string) string {
 	case "ADB_NOT_FOUND":
 		return "The adb binary was not found on the local machine."
 	case "MISSING_SEPARATOR":
-		return "The mobile adb command is missing the required -- separator before adb arguments."
+		return "A mobile adb or mirror command is missing the required -- separator before passthrough arguments."
 	case "MISSING_ACTION":
 		return "agr api call was invoked without the required API action name."
 	case "CONFLICTING_FLAGS":
 		return "The provided combination of flags is mutually incompatible."
 	case "INVALID_CLEANUP":
 		return "The cleanup policy for a temporary instance is invalid."
+	case "INVALID_COLLECT":
+		return "A --collect value is not a valid <remote-glob>[:<local-dir>] artifact specification."
 	case "UNIMPLEMENTED_COMMAND":
 		return "A generated command exists in the command tree but does not have an implementation hook."
 	case "PARTIAL_DELETE_FAILED":
 		return "A bulk delete completed partially and one or more resources failed to delete."
+	case "PARTIAL_EXEC_FAILED":
+		return "A command run on several instances failed on one or more of them."
+	case "INVALID_MAX_PARALLEL":
+		return "The --max-parallel value is not a positive number."
+	case "JSON_REQUIRES_BACKGROUND":
+		return "A foreground proxy streams human-readable output and cannot be combined with -o json."
+	case "INVALID_PORTS_FILE":
+		return "The --ports-file could not be read or lists no port mappings."
+	case "PARTIAL_STOP_FAILED":
+		return "One or more tunnel processes could not be confirmed stopped; their registry entries were kept."
+	case "INVALID_TAIL":
+		return "The --tail value is negative."
+	case "AMBIGUOUS_TUNNEL":
+		return "The instance ID matches several tunnels, so the tunnel to act on is ambiguous."
+	case "INVALID_AUTH":
+		return "The --auth mode or --auth-credential of the local proxy listener is invalid."
+	case "INVALID_ALLOW_IP":
+		return "An --allow-ip value is neither an IP address nor a CIDR network."
+	case "INVALID_TLS":
+		return "The TLS certificate or key for the local proxy listener is missing or cannot be loaded."
+	case "INVALID_INTERVAL":
+		return "The polling interval is not a valid duration or is too short."
+	case "INVALID_TIMEOUT":
+		return "The timeout is not a positive duration."
+	case "INVALID_URL":
+		return "The page URL is not an absolute URL."
+	case "INVALID_DURATION":
+		return "The --duration value is not a positive duration or exceeds the command limit, such as 3m for mobile record."
+	case "INVALID_COORDINATE":
+		return "A screen coordinate is missing or is not a non-negative number of pixels."
+	case "INVALID_KEYCODE":
+		return "A key is neither an Android key code nor a key name."
+	case "INVALID_LOG_LEVEL":
+		return "The --level value is not a logcat priority."
+	case "PARTIAL_CONNECT_FAILED":
+		return "A multi-instance mobile connect could not start the tunnel of one or more instances."
+	case "SCRCPY_NOT_FOUND":
+		return "The scrcpy binary used by mobile mirror was not found on the local machine."
+	case "INVALID_BIT_RATE":
+		return "The --bit-rate value is not a scrcpy video bit rate."
 	case "TOOL_NOT_FOUND":
 		return "The referenced sandbox tool does not exist."
 	case "INVALID_REQUEST_INPUT":
 		return "The provided request input does not match the expected request shape."
+	case "INVALID_POOL_SIZE":
+		return "The warm pool size must be at least 1."
+	case "INVALID_POOL_NAME":
+		return "The warm pool name contains characters other than letters, digits, '.', '-' or '_'."
+	case "PARTIAL_POOL_FILL":
+		return "Some warm pool instances failed to start, so the pool holds fewer ready instances than its size."
+	case "INVALID_SESSION_NAME":
+		return "The session name contains characters other than letters, digits, '.', '-' or '_'."
+	case "INVALID_SESSION_TTL":
+		return "The --session-ttl value is not a positive duration."
 	default:
 		return "The command line arguments are invalid for the requested command."
 	}
@@ -388,14 +481,66 @@ func fixForCLIUsageCode(code string) []string {
 		return []string{"Review the command help and provide only one option from each mutually exclusive flag set."}
 	case "INVALID_CLEANUP":
 		return []string{"Use one of: always, success, never."}
+	case "INVALID_COLLECT":
+		return []string{"Use an absolute sandbox path or glob, optionally followed by :<local-dir>, for example --collect '/tmp/out/*.png:./artifacts'."}
 	case "UNIMPLEMENTED_COMMAND":
 		return []string{"Update the CLI implementation so the generated command has a runtime hook."}
 	case "PARTIAL_DELETE_FAILED":
 		return []string{"Inspect the warnings and retry the failed resource deletions individually."}
+	case "PARTIAL_EXEC_FAILED":
+		return []string{"Inspect Data.Items for per-instance exit codes and output.", "agr instance exec --instances <failed-ids> -- <command>"}
+	case "INVALID_MAX_PARALLEL":
+		return []string{"Use --max-parallel 1 or larger."}
+	case "JSON_REQUIRES_BACKGROUND":
+		return []string{"agr instance proxy <instance-id> <port> --background -o json"}
+	case "INVALID_PORTS_FILE":
+		return []string{"List one [local_port:]remote_port mapping per line, for example 3000 or 8080:80; '#' starts a comment."}
+	case "PARTIAL_STOP_FAILED":
+		return []string{"Inspect Data.FailedIds; the processes may have been replaced by other programs.", "agr tunnel list"}
+	case "INVALID_TAIL":
+		return []string{"Use --tail 0 for the whole log, or a positive line count."}
+	case "AMBIGUOUS_TUNNEL":
+		return []string{"agr tunnel list", "Pass the tunnel ID, for example proxy:<instance-id>:<local-port>."}
+	case "INVALID_AUTH":
+		return []string{"Use --auth token or --auth basic; a basic credential must be user:password."}
+	case "INVALID_ALLOW_IP":
+		return []string{"Pass --allow-ip 192.168.1.10 or --allow-ip 192.168.1.0/24."}
+	case "INVALID_TLS":
+		return []string{"Pass both --tls-cert and --tls-key as PEM files, or use --tls for a self-signed certificate."}
+	case "INVALID_INTERVAL":
+		return []string{"Pass a Go duration of at least 500ms, for example --auto-interval 2s or --interval 2s."}
+	case "INVALID_TIMEOUT":
+		return []string{"Pass a Go duration, for example --timeout 30s or --timeout 2m."}
+	case "INVALID_URL":
+		return []string{"Include the scheme, for example https://example.com."}
+	case "INVALID_DURATION":
+		return []string{"Pass a Go duration, for example --duration 30s or --duration 5m.", "agr instance mobile record accepts at most --duration 3m."}
+	case "INVALID_COORDINATE":
+		return []string{"Pass coordinates in screen pixels; 'agr instance mobile info <instance-id>' shows the screen size."}
+	case "INVALID_KEYCODE":
+		return []string{"Use a key code such as 3 or a name such as home, back, enter or KEYCODE_VOLUME_UP."}
+	case "INVALID_LOG_LEVEL":
+		return []string{"Use verbose, debug, info, warn, error or fatal, or their first letter."}
+	case "PARTIAL_CONNECT_FAILED":
+		return []string{"Inspect Data.Items for per-instance errors; the other instances stay connected.", "agr instance mobile connect <failed-ids>"}
+	case "SCRCPY_NOT_FOUND":
+		return []string{"Install scrcpy (https://github.com/Genymobile/scrcpy) or set SCRCPY_PATH to a valid scrcpy binary."}
+	case "INVALID_BIT_RATE":
+		return []string{"Use a positive bit rate with an optional K or M suffix, for example 4M or 800K."}
 	case "TOOL_NOT_FOUND":
 		return []string{"agr tool list", "Verify the tool ID is correct and the tool has not been deleted."}
 	case "INVALID_REQUEST_INPUT":
 		return []string{"Review the command schema or request skeleton and retry with a matching JSON shape.", "agr schema -o json"}
+	case "INVALID_POOL_SIZE":
+		return []string{"Use --size 1 or larger; to remove a pool run: agr pool drain <name>."}
+	case "INVALID_POOL_NAME":
+		return []string{"Use a name made of letters, digits, '.', '-' or '_', for example --name py-3.12."}
+	case "PARTIAL_POOL_FILL":
+		return []string{"Inspect the warnings, then rerun agr pool create with the same flags to fill the remaining slots."}
+	case "INVALID_SESSION_NAME":
+		return []string{"Use a name made of letters, digits, '.', '-' or '_' in --session or AGR_SESSION."}
+	case "INVALID_SESSION_TTL":
+		return []string{"Use a Go duration such as 30m or 2h, for example --session-ttl 1h."}
 	default:
 		return []string{"agr schema -o json", "Review the command help and retry with valid flags or arguments."}
 	}
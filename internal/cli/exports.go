@@ -224,6 +224,12 @@ func AcquireInstanceToken(ctx context.Context, instanceID string) (string, error
 	return acquireInstanceToken(ctx, instanceID)
 }
 
+// InvalidateInstanceToken drops the cached access token for an instance, e.g.
+// after the sandbox gateway rejected it.
+func InvalidateInstanceToken(instanceID string) {
+	invalidateInstanceToken(instanceID)
+}
+
 // OverlayCloudCreate creates the temporary instance used by overlay workflows.
 func OverlayCloudCreate(ctx context.Context, toolName, toolID string) (string, error) {
 	return overlayCloudCreate(ctx, toolName, toolID)
@@ -280,6 +286,14 @@ func CloudUpdateSandboxInstance(ctx context.Context, sdk *ags.Client, req *ags.U
 	return cloudUpdateSandboxInstance(ctx, sdk, req)
 }
 
+// SetCloudUpdateSandboxInstanceForTest replaces UpdateSandboxInstance and
+// returns a restore function for tests.
+func SetCloudUpdateSandboxInstanceForTest(fn func(context.Context, *ags.Client, *ags.UpdateSandboxInstanceRequest) (*ags.UpdateSandboxInstanceResponseParams, error)) func() {
+	previous := cloudUpdateSandboxInstance
+	cloudUpdateSandboxInstance = fn
+	return func() { cloudUpdateSandboxInstance = previous }
+}
+
 // CloudPauseSandboxInstance calls the injectable pause-instance function.
 func CloudPauseSandboxInstance(ctx context.Context, sdk *ags.Client, req *ags.PauseSandboxInstanceRequest) (*ags.PauseSandboxInstanceResponseParams, error) {
 	return cloudPauseSandboxInstance(ctx, sdk, req)
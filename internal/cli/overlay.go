@@ -3,7 +3,14 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"syscall"
+	"time"
 
+	"github.com/TencentCloudAgentRuntime/ags-go-sdk/sandbox/code"
+	"github.com/TencentCloudAgentRuntime/ags-go-sdk/tool/filesystem"
 	"github.com/spf13/cobra"
 	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
 
@@ -30,6 +37,13 @@ type ExecutionContext = workflow.ExecutionContext
 // output.
 type ExecutionCleanupResult = workflow.ExecutionCleanupResult
 
+// CollectedArtifact re-exports the per-file --collect result recorded in
+// overlay JSON output.
+type CollectedArtifact = workflow.CollectedArtifact
+
+// ArtifactSource re-exports the data-plane surface used by --collect.
+type ArtifactSource = workflow.ArtifactSource
+
 // OverlayFlags re-exports the shared temporary-instance flag set.
 type OverlayFlags = workflow.OverlayFlags
 
@@ -65,6 +79,17 @@ func ResolveCleanupPolicy(value string) (CleanupPolicy, error) {
 //	               production callers wire it to cloudStartSandboxInstance.
 //	apiDelete    - injection point that deletes a sandbox instance.
 //
+// With flags.Pool set, the temporary instance is leased from the named warm
+// pool instead, and apiCreate is only used when the pool is empty.
+//
+// With flags.Session (or AGR_SESSION) set, the temporary instance is reused
+// across invocations of the same session and kept until `agr session end`.
+//
+// Temporary instances are tagged with the creating host, PID and start time,
+// and cleanup also runs on SIGINT, SIGTERM and SIGHUP, so an interrupted
+// invocation does not leak its instance. Instances left behind by a killed
+// process are found by `agr instance prune --orphans`.
+//
 // The returned ResolvedOverlay is never nil on success.
 func ResolveOverlay(
 	ctx context.Context,
@@ -73,12 +98,55 @@ func ResolveOverlay(
 	apiCreate func(ctx context.Context, toolName, toolID string) (string, error),
 	apiDelete func(ctx context.Context, instanceID string) error,
 ) (*ResolvedOverlay, error) {
-	return workflow.ResolveOverlay(ctx, flags, args, apiCreate, apiDelete, stderr)
+	if flags.Pool != "" {
+		create := apiCreate
+		apiCreate = func(ctx context.Context, _, _ string) (string, error) {
+			return leasePoolInstance(ctx, flags.Pool, create)
+		}
+	}
+	sess, err := overlaySession(&flags, apiDelete)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withCreatorMetadata(ctx, flags)
+	resolved, err := workflow.ResolveSessionOverlay(ctx, flags, args, sess, apiCreate, apiDelete, stderr)
+	if err != nil {
+		return nil, err
+	}
+	resolved.WatchSignals(exitProcess, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	return resolved, nil
+}
+
+// exitProcess terminates the CLI after a signal-triggered cleanup; tests
+// replace it.
+var exitProcess = os.Exit
+
+type creatorMetadataKey struct{}
+
+// withCreatorMetadata records the orphan-detection metadata for instances
+// created while resolving flags. It travels on the context because the
+// apiCreate injection point only receives the tool.
+func withCreatorMetadata(ctx context.Context, flags OverlayFlags) context.Context {
+	host, _ := os.Hostname()
+	policy := CleanupPolicy(flags.Cleanup)
+	if policy == "" {
+		policy = CleanupAlways
+	}
+	metadata := workflow.CreatorMetadata(host, os.Getpid(), time.Now(), policy, flags.Session)
+	return context.WithValue(ctx, creatorMetadataKey{}, metadata)
 }
 
 // overlayCloudCreate is the production wiring used by the overlay.
-// It creates a sandbox instance via the typed SDK request.
+// It creates a sandbox instance via the typed SDK request, tagged with the
+// creator metadata carried by ctx.
 func overlayCloudCreate(ctx context.Context, toolName, toolID string) (string, error) {
+	metadata, _ := ctx.Value(creatorMetadataKey{}).(map[string]string)
+	return startTaggedInstance(ctx, toolName, toolID, "", metadata)
+}
+
+// startTaggedInstance starts a sandbox instance for the given tool, attaching
+// metadata so CLI-managed instances can be recognized later.
+func startTaggedInstance(ctx context.Context, toolName, toolID, timeout string, metadata map[string]string) (string, error) {
 	client, err := newCloudClient()
 	if err != nil {
 		return "", err
@@ -89,6 +157,10 @@ func overlayCloudCreate(ctx context.Context, toolName, toolID string) (string, e
 	} else {
 		req.ToolName = &toolName
 	}
+	if timeout != "" {
+		req.Timeout = &timeout
+	}
+	req.Metadata = metadataVars(metadata)
 	resp, err := cloudStartSandboxInstance(ctx, client, req)
 	if err != nil {
 		return "", err
@@ -99,6 +171,20 @@ func overlayCloudCreate(ctx context.Context, toolName, toolID string) (string, e
 	return *resp.Instance.InstanceId, nil
 }
 
+// metadataVars converts metadata to SDK variables sorted by name.
+func metadataVars(metadata map[string]string) []*ags.MetadataVar {
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vars := make([]*ags.MetadataVar, 0, len(names))
+	for _, name := range names {
+		vars = append(vars, &ags.MetadataVar{Name: strPtr(name), Value: strPtr(metadata[name])})
+	}
+	return vars
+}
+
 // overlayCloudDelete is the production wiring used by the overlay.
 func overlayCloudDelete(ctx context.Context, instanceID string) error {
 	client, err := newCloudClient()
@@ -110,3 +196,55 @@ func overlayCloudDelete(ctx context.Context, instanceID string) error {
 	_, err = cloudStopSandboxInstance(ctx, client, req)
 	return err
 }
+
+// SandboxArtifactSource adapts a connected sandbox to the --collect download
+// surface, reading files as the given sandbox user.
+func SandboxArtifactSource(sandbox *code.Sandbox, user string) ArtifactSource {
+	return &sandboxArtifactSource{sandbox: sandbox, user: user}
+}
+
+type sandboxArtifactSource struct {
+	sandbox *code.Sandbox
+	user    string
+}
+
+func (s *sandboxArtifactSource) List(ctx context.Context, dir string, depth int) ([]workflow.ArtifactEntry, error) {
+	entries, err := s.sandbox.Files.List(ctx, dir, &filesystem.ListConfig{Depth: depth, User: s.user})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]workflow.ArtifactEntry, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, workflow.ArtifactEntry{
+			Path:  entry.Path,
+			Size:  entry.Size,
+			IsDir: entry.Type != nil && *entry.Type == filesystem.Dir,
+		})
+	}
+	return out, nil
+}
+
+func (s *sandboxArtifactSource) Read(ctx context.Context, remotePath string) (io.Reader, error) {
+	return s.sandbox.Files.Read(ctx, remotePath, &filesystem.ReadConfig{User: s.user})
+}
+
+// TestDataPlaneArtifactSource adapts the legacy test data-plane override to
+// the --collect download surface. The override cannot list directories, so
+// only exact remote paths can be collected through it.
+func TestDataPlaneArtifactSource(dp DataPlaneOverride, instanceID string) ArtifactSource {
+	return &testDataPlaneArtifactSource{dp: dp, instanceID: instanceID}
+}
+
+type testDataPlaneArtifactSource struct {
+	dp         DataPlaneOverride
+	instanceID string
+}
+
+func (s *testDataPlaneArtifactSource) List(_ context.Context, dir string, _ int) ([]workflow.ArtifactEntry, error) {
+	return nil, fmt.Errorf("listing %s is not supported by the test data plane", dir)
+}
+
+func (s *testDataPlaneArtifactSource) Read(ctx context.Context, remotePath string) (io.Reader, error) {
+	reader, _, err := s.dp.Download(ctx, s.instanceID, remotePath)
+	return reader, err
+}
@@ -0,0 +1,190 @@
+package overlay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// CollectSpec is one parsed `--collect <remote-glob>[:<local-dir>]` value.
+type CollectSpec struct {
+	Pattern  string
+	LocalDir string
+}
+
+// CollectedArtifact records one file downloaded by --collect, or one pattern
+// that could not be collected.
+type CollectedArtifact struct {
+	Pattern    string `json:"Pattern"`
+	RemotePath string `json:"RemotePath,omitempty"`
+	LocalPath  string `json:"LocalPath,omitempty"`
+	Size       int64  `json:"Size"`
+	Status     string `json:"Status"` // downloaded | failed | no_match
+	Reason     string `json:"Reason,omitempty"`
+}
+
+// ArtifactEntry is one remote filesystem entry returned by an ArtifactSource.
+type ArtifactEntry struct {
+	Path  string
+	Size  int64
+	IsDir bool
+}
+
+// ArtifactSource is the data-plane surface needed to collect artifacts from
+// the sandbox that ran the command.
+type ArtifactSource interface {
+	// List returns entries below dir up to depth levels deep.
+	List(ctx context.Context, dir string, depth int) ([]ArtifactEntry, error)
+	// Read opens a remote file for download.
+	Read(ctx context.Context, remotePath string) (io.Reader, error)
+}
+
+// ParseCollectSpecs validates --collect values. The remote glob and the local
+// directory are split at the first ':'; the local directory defaults to ".".
+func ParseCollectSpecs(values []string) ([]CollectSpec, error) {
+	specs := make([]CollectSpec, 0, len(values))
+	for _, value := range values {
+		pattern, localDir, _ := strings.Cut(value, ":")
+		pattern = strings.TrimSpace(pattern)
+		if localDir == "" {
+			localDir = "."
+		}
+		if pattern == "" || !path.IsAbs(pattern) {
+			return nil, invalidCollect(value, "the remote glob must be an absolute sandbox path")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, invalidCollect(value, err.Error())
+		}
+		specs = append(specs, CollectSpec{Pattern: path.Clean(pattern), LocalDir: localDir})
+	}
+	return specs, nil
+}
+
+func invalidCollect(value, reason string) error {
+	return output.NewUsageError("INVALID_COLLECT",
+		fmt.Sprintf("invalid --collect value %q: %s", value, reason),
+		"Use --collect <remote-glob>[:<local-dir>], for example --collect '/tmp/out/*.png:./artifacts'.")
+}
+
+// CollectArtifacts downloads every file matching the --collect specs and
+// records the outcome in ExecContext.Artifacts. It must run after execution
+// and before Cleanup so a temporary instance still exists. Collection
+// failures are recorded and reported, but never change the execution result.
+func (r *ResolvedOverlay) CollectArtifacts(ctx context.Context, src ArtifactSource) {
+	if r == nil || len(r.collect) == 0 || src == nil {
+		return
+	}
+	notify := r.notify
+	if notify == nil {
+		notify = func(string, ...any) {}
+	}
+	artifacts := make([]CollectedArtifact, 0, len(r.collect))
+	for _, spec := range r.collect {
+		base, matches, err := matchArtifacts(ctx, src, spec.Pattern)
+		if err != nil {
+			artifacts = append(artifacts, CollectedArtifact{Pattern: spec.Pattern, Status: "failed", Reason: err.Error()})
+			notify("Warning: failed to collect %s: %v\n", spec.Pattern, err)
+			continue
+		}
+		if len(matches) == 0 {
+			artifacts = append(artifacts, CollectedArtifact{Pattern: spec.Pattern, Status: "no_match"})
+			notify("Warning: --collect %s matched no files\n", spec.Pattern)
+			continue
+		}
+		for _, remote := range matches {
+			artifact := CollectedArtifact{Pattern: spec.Pattern, RemotePath: remote}
+			var size int64
+			localPath, err := localArtifactPath(spec.LocalDir, base, remote)
+			if err == nil {
+				artifact.LocalPath = localPath
+				size, err = downloadArtifact(ctx, src, remote, localPath)
+			}
+			if err != nil {
+				artifact.Status = "failed"
+				artifact.Reason = err.Error()
+				notify("Warning: failed to collect %s: %v\n", remote, err)
+			} else {
+				artifact.Status = "downloaded"
+				artifact.Size = size
+				notify("Collected %s -> %s (%d bytes)\n", remote, artifact.LocalPath, size)
+			}
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	if r.ExecContext != nil {
+		r.ExecContext.Artifacts = artifacts
+	}
+}
+
+// matchArtifacts lists the deepest directory of pattern that contains no glob
+// metacharacters and returns it with the files matching the full pattern.
+func matchArtifacts(ctx context.Context, src ArtifactSource, pattern string) (string, []string, error) {
+	segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	literal := 0
+	for literal < len(segments) && !hasGlobMeta(segments[literal]) {
+		literal++
+	}
+	if literal == len(segments) {
+		return path.Dir(pattern), []string{pattern}, nil
+	}
+	base := "/" + strings.Join(segments[:literal], "/")
+	entries, err := src.List(ctx, base, len(segments)-literal)
+	if err != nil {
+		return "", nil, err
+	}
+	var matches []string
+	for _, entry := range entries {
+		if entry.IsDir {
+			continue
+		}
+		if ok, _ := path.Match(pattern, entry.Path); ok {
+			matches = append(matches, entry.Path)
+		}
+	}
+	sort.Strings(matches)
+	return base, matches, nil
+}
+
+// localArtifactPath keeps the path of remote below base under localDir, so
+// matches of a glob such as /tmp/*/out.png in different directories do not
+// overwrite each other.
+func localArtifactPath(localDir, base, remote string) (string, error) {
+	rel := path.Clean(strings.TrimPrefix(remote, strings.TrimSuffix(base, "/")+"/"))
+	if rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+		return "", fmt.Errorf("remote path %s escapes %s", remote, base)
+	}
+	return filepath.Join(localDir, filepath.FromSlash(rel)), nil
+}
+
+func hasGlobMeta(segment string) bool {
+	return strings.ContainsAny(segment, `*?[\`)
+}
+
+func downloadArtifact(ctx context.Context, src ArtifactSource, remote, localPath string) (int64, error) {
+	reader, err := src.Read(ctx, remote)
+	if err != nil {
+		return 0, err
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return 0, err
+	}
+	file, err := os.Create(localPath)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return size, err
+}
@@ -0,0 +1,153 @@
+package overlay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeArtifactSource struct {
+	files    map[string]string
+	listDir  string
+	listDeep int
+}
+
+func (f *fakeArtifactSource) List(_ context.Context, dir string, depth int) ([]ArtifactEntry, error) {
+	f.listDir = dir
+	f.listDeep = depth
+	var entries []ArtifactEntry
+	for p, content := range f.files {
+		if strings.HasPrefix(p, dir+"/") {
+			entries = append(entries, ArtifactEntry{Path: p, Size: int64(len(content))})
+		}
+	}
+	entries = append(entries, ArtifactEntry{Path: dir + "/sub.png", IsDir: true})
+	return entries, nil
+}
+
+func (f *fakeArtifactSource) Read(_ context.Context, remotePath string) (io.Reader, error) {
+	content, ok := f.files[remotePath]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return strings.NewReader(content), nil
+}
+
+func TestParseCollectSpecs(t *testing.T) {
+	specs, err := ParseCollectSpecs([]string{"/tmp/out/*.png:./artifacts", "/tmp/report.txt"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if specs[0].Pattern != "/tmp/out/*.png" || specs[0].LocalDir != "./artifacts" {
+		t.Fatalf("spec[0]=%+v", specs[0])
+	}
+	if specs[1].LocalDir != "." {
+		t.Fatalf("spec[1] local dir=%q", specs[1].LocalDir)
+	}
+	for _, bad := range []string{"", "relative/*.txt", "/tmp/[.txt"} {
+		if _, err := ParseCollectSpecs([]string{bad}); failureCode(err) != "INVALID_COLLECT" {
+			t.Fatalf("%q: expected INVALID_COLLECT, got %v", bad, err)
+		}
+	}
+}
+
+func TestResolveOverlay_InvalidCollectFailsBeforeCreate(t *testing.T) {
+	created := false
+	create := func(context.Context, string, string) (string, error) { created = true; return "ins-temp", nil }
+	_, err := ResolveOverlay(context.Background(), OverlayFlags{
+		CreateTempInstance: true,
+		ToolName:           "x",
+		Collect:            []string{"out.txt"},
+	}, nil, create, nil, nil)
+	if failureCode(err) != "INVALID_COLLECT" {
+		t.Fatalf("expected INVALID_COLLECT, got %v", err)
+	}
+	if created {
+		t.Fatalf("temporary instance must not be created for an invalid --collect")
+	}
+}
+
+func TestCollectArtifacts_DownloadsMatchesBeforeCleanup(t *testing.T) {
+	localDir := filepath.Join(t.TempDir(), "artifacts")
+	src := &fakeArtifactSource{files: map[string]string{
+		"/tmp/out/a.png":  "aaa",
+		"/tmp/out/b.png":  "bb",
+		"/tmp/out/c.txt":  "c",
+		"/tmp/report.txt": "report",
+	}}
+	r, err := ResolveOverlay(context.Background(), OverlayFlags{
+		Collect: []string{"/tmp/out/*.png:" + localDir, "/tmp/report.txt:" + localDir, "/tmp/none/*.log"},
+	}, []string{"ins-existing"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r.CollectArtifacts(context.Background(), src)
+	r.Cleanup(true)
+
+	if src.listDir != "/tmp/none" || src.listDeep != 1 {
+		t.Fatalf("last list dir=%s depth=%d", src.listDir, src.listDeep)
+	}
+	got := r.ExecContext.Artifacts
+	if len(got) != 4 {
+		t.Fatalf("artifacts=%+v", got)
+	}
+	if got[0].RemotePath != "/tmp/out/a.png" || got[0].Status != "downloaded" || got[0].Size != 3 {
+		t.Fatalf("artifact[0]=%+v", got[0])
+	}
+	if got[1].RemotePath != "/tmp/out/b.png" || got[2].RemotePath != "/tmp/report.txt" {
+		t.Fatalf("artifacts=%+v", got)
+	}
+	if got[3].Status != "no_match" {
+		t.Fatalf("artifact[3]=%+v", got[3])
+	}
+	content, err := os.ReadFile(filepath.Join(localDir, "report.txt"))
+	if err != nil || string(content) != "report" {
+		t.Fatalf("report.txt content=%q err=%v", content, err)
+	}
+}
+
+func TestCollectArtifacts_RecordsReadFailure(t *testing.T) {
+	r, err := ResolveOverlay(context.Background(), OverlayFlags{
+		Collect: []string{"/tmp/missing.txt:" + t.TempDir()},
+	}, []string{"ins-existing"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r.CollectArtifacts(context.Background(), &fakeArtifactSource{})
+	if got := r.ExecContext.Artifacts; len(got) != 1 || got[0].Status != "failed" {
+		t.Fatalf("artifacts=%+v", got)
+	}
+}
+
+func TestCollectArtifacts_KeepsPathsBelowGlobBase(t *testing.T) {
+	localDir := t.TempDir()
+	src := &fakeArtifactSource{files: map[string]string{
+		"/tmp/a/out.png":     "a",
+		"/tmp/b/out.png":     "bb",
+		"/tmp/../etc/passwd": "x",
+	}}
+	r, err := ResolveOverlay(context.Background(), OverlayFlags{
+		Collect: []string{"/tmp/*/out.png:" + localDir, "/tmp/*/*/passwd:" + localDir},
+	}, []string{"ins-existing"}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r.CollectArtifacts(context.Background(), src)
+
+	got := r.ExecContext.Artifacts
+	if len(got) != 3 || got[0].LocalPath != filepath.Join(localDir, "a", "out.png") || got[1].LocalPath != filepath.Join(localDir, "b", "out.png") {
+		t.Fatalf("artifacts=%+v", got)
+	}
+	for name, want := range map[string]string{"a/out.png": "a", "b/out.png": "bb"} {
+		if content, err := os.ReadFile(filepath.Join(localDir, name)); err != nil || string(content) != want {
+			t.Fatalf("%s content=%q err=%v", name, content, err)
+		}
+	}
+	if got[2].Status != "failed" || !strings.Contains(got[2].Reason, "escapes") {
+		t.Fatalf("artifact[2]=%+v", got[2])
+	}
+}
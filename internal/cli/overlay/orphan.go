@@ -0,0 +1,82 @@
+package overlay
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+)
+
+// Metadata names attached to instances created by --create-temp-instance.
+// They let `agr instance prune --orphans` recognize temporary instances whose
+// creating process died before cleanup ran.
+const (
+	// MetadataHost is the hostname of the creating process.
+	MetadataHost = "agr-host"
+	// MetadataPID is the PID of the creating process.
+	MetadataPID = "agr-pid"
+	// MetadataStartedAt is the RFC 3339 time the instance was requested.
+	MetadataStartedAt = "agr-started-at"
+	// MetadataCleanup is the --cleanup policy of the creating invocation.
+	MetadataCleanup = "agr-cleanup"
+	// MetadataSession is the --session name, when the instance backs a session.
+	MetadataSession = "agr-session"
+)
+
+// CreatorMetadata returns the metadata that identifies the current process as
+// the creator of a temporary instance.
+func CreatorMetadata(host string, pid int, now time.Time, policy CleanupPolicy, session string) map[string]string {
+	metadata := map[string]string{
+		MetadataHost:      host,
+		MetadataPID:       strconv.Itoa(pid),
+		MetadataStartedAt: now.UTC().Format(time.RFC3339),
+		MetadataCleanup:   string(policy),
+	}
+	if session != "" {
+		metadata[MetadataSession] = session
+	}
+	return metadata
+}
+
+// OrphanCheck decides whether a tagged temporary instance has lost its
+// creating process.
+type OrphanCheck struct {
+	// Host is the local hostname; only instances created on it can be checked.
+	Host string
+	// Alive reports whether a local process is still running.
+	Alive func(pid int) bool
+}
+
+// Classify reports whether the instance with the given metadata is an orphan
+// and why. Instances created or leased from a pool with --cleanup always or
+// --cleanup success are candidates; a --cleanup success run whose process died
+// never reached its success check. --cleanup never intentionally keeps the
+// instance, and session instances are ended with `agr session end`.
+func (c OrphanCheck) Classify(metadata map[string]string) (bool, string) {
+	host, pidValue := metadata[MetadataHost], metadata[MetadataPID]
+	if host == "" || pidValue == "" {
+		return false, "not created by --create-temp-instance"
+	}
+	if session := metadata[MetadataSession]; session != "" {
+		return false, fmt.Sprintf("session=%s", session)
+	}
+	if policy := metadata[MetadataCleanup]; policy != string(CleanupAlways) && policy != string(CleanupSuccess) {
+		return false, fmt.Sprintf("policy=%s", policy)
+	}
+	if host != c.Host {
+		return false, fmt.Sprintf("created on another host (%s)", host)
+	}
+	pid, err := strconv.Atoi(pidValue)
+	if err != nil || pid <= 0 {
+		return false, fmt.Sprintf("invalid creator pid %q", pidValue)
+	}
+	alive := c.Alive
+	if alive == nil {
+		alive = tunnelstore.IsProcessAlive
+	}
+	if alive(pid) {
+		return false, fmt.Sprintf("creator process %d is still running", pid)
+	}
+	return true, fmt.Sprintf("creator process %d is gone", pid)
+}
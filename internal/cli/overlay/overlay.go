@@ -8,6 +8,7 @@ package overlay
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
 )
@@ -36,7 +37,11 @@ const (
 type ExecutionContext struct {
 	SandboxInstanceId        string                  `json:"SandboxInstanceId"`
 	TemporarySandboxInstance bool                    `json:"TemporarySandboxInstance"`
+	Pool                     string                  `json:"Pool,omitempty"`
+	Session                  string                  `json:"Session,omitempty"`
+	SessionReused            bool                    `json:"SessionReused,omitempty"`
 	Cleanup                  *ExecutionCleanupResult `json:"Cleanup,omitempty"`
+	Artifacts                []CollectedArtifact     `json:"Artifacts,omitempty"`
 }
 
 // ExecutionCleanupResult records the outcome of the cleanup step.
@@ -52,6 +57,10 @@ type OverlayFlags struct {
 	Cleanup            string
 	ToolName           string
 	ToolID             string
+	Pool               string
+	Session            string
+	SessionTTL         string
+	Collect            []string
 }
 
 // ResolvedOverlay returns the instance id the caller should execute against.
@@ -63,14 +72,20 @@ type ResolvedOverlay struct {
 	ExecContext    *ExecutionContext
 	cleanup        func(success bool)
 	preExecCleanup func()
+	collect        []CollectSpec
+	notify         func(format string, args ...any)
+	cleanupOnce    sync.Once
+	stopSignals    func()
 }
 
-// Cleanup invokes the cleanup function with the execution outcome.
+// Cleanup invokes the cleanup function with the execution outcome. Only the
+// first Cleanup or CleanupForPreExecutionFailure call has an effect, so a
+// signal-triggered cleanup and the regular exit path never both run.
 func (r *ResolvedOverlay) Cleanup(success bool) {
 	if r == nil || r.cleanup == nil {
 		return
 	}
-	r.cleanup(success)
+	r.once(func() { r.cleanup(success) })
 }
 
 // CleanupForPreExecutionFailure runs the cleanup path used when execution
@@ -80,12 +95,22 @@ func (r *ResolvedOverlay) CleanupForPreExecutionFailure() {
 		return
 	}
 	if r.preExecCleanup != nil {
-		r.preExecCleanup()
+		r.once(r.preExecCleanup)
 		return
 	}
 	r.Cleanup(false)
 }
 
+// once runs fn as the overlay's single cleanup and stops watching signals.
+func (r *ResolvedOverlay) once(fn func()) {
+	r.cleanupOnce.Do(func() {
+		if r.stopSignals != nil {
+			r.stopSignals()
+		}
+		fn()
+	})
+}
+
 // ResolveCleanupPolicy validates --cleanup and returns the canonical value.
 func ResolveCleanupPolicy(value string) (CleanupPolicy, error) {
 	switch CleanupPolicy(value) {
@@ -105,6 +130,22 @@ func ResolveOverlay(
 	apiCreate func(ctx context.Context, toolName, toolID string) (string, error),
 	apiDelete func(ctx context.Context, instanceID string) error,
 	notify func(format string, args ...any),
+) (*ResolvedOverlay, error) {
+	return ResolveSessionOverlay(ctx, flags, args, nil, apiCreate, apiDelete, notify)
+}
+
+// ResolveSessionOverlay is ResolveOverlay with an optional session. When
+// flags.Session is set, the temporary instance is acquired through session
+// and kept after execution; the cleanup policy is applied when the session
+// ends instead.
+func ResolveSessionOverlay(
+	ctx context.Context,
+	flags OverlayFlags,
+	args []string,
+	session Session,
+	apiCreate func(ctx context.Context, toolName, toolID string) (string, error),
+	apiDelete func(ctx context.Context, instanceID string) error,
+	notify func(format string, args ...any),
 ) (*ResolvedOverlay, error) {
 	if notify == nil {
 		notify = func(string, ...any) {}
@@ -118,6 +159,24 @@ func ResolveOverlay(
 	if err != nil {
 		return nil, err
 	}
+	collect, err := ParseCollectSpecs(flags.Collect)
+	if err != nil {
+		return nil, err
+	}
+
+	if flags.Pool != "" && !flags.CreateTempInstance {
+		return nil, output.NewUsageError("MISSING_REQUIRED_FLAG",
+			"--pool requires --create-temp-instance",
+			"Add --create-temp-instance to lease a temporary instance from the pool.")
+	}
+	if flags.Session != "" && !flags.CreateTempInstance {
+		return nil, output.NewUsageError("MISSING_REQUIRED_FLAG",
+			"--session requires --create-temp-instance",
+			"Add --create-temp-instance to reuse the session's temporary instance.")
+	}
+	if flags.Session != "" && session == nil {
+		return nil, fmt.Errorf("--session %s requires a session store", flags.Session)
+	}
 
 	hasInstanceID := len(args) > 0 && args[0] != ""
 	if hasInstanceID {
@@ -133,6 +192,8 @@ func ResolveOverlay(
 			ExecContext:    &ExecutionContext{SandboxInstanceId: args[0]},
 			cleanup:        func(bool) {},
 			preExecCleanup: func() {},
+			collect:        collect,
+			notify:         notify,
 		}, nil
 	}
 
@@ -142,25 +203,49 @@ func ResolveOverlay(
 			"Provide an instance id, or add --create-temp-instance to spin up a temporary sandbox.")
 	}
 
-	if flags.ToolName == "" && flags.ToolID == "" {
-		return nil, output.NewUsageError("MISSING_REQUIRED_FLAG",
-			"--create-temp-instance requires --tool-name/-t or --tool-id",
-			"Provide --tool-name <existing-tool-name> or --tool-id sdt-xxxx.")
+	if flags.Pool != "" && (flags.ToolName != "" || flags.ToolID != "") {
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--pool cannot be used together with --tool-name or --tool-id",
+			"The pool already determines the tool; drop --tool-name/--tool-id.")
 	}
 	if flags.ToolName != "" && flags.ToolID != "" {
 		return nil, output.NewUsageError("CONFLICTING_FLAGS",
 			"--tool-name and --tool-id are mutually exclusive",
 			"Pick exactly one.")
 	}
+	missingTool := flags.Pool == "" && flags.ToolName == "" && flags.ToolID == ""
+	missingToolErr := output.NewUsageError("MISSING_REQUIRED_FLAG",
+		"--create-temp-instance requires --tool-name/-t, --tool-id, or --pool",
+		"Provide --tool-name <existing-tool-name>, --tool-id sdt-xxxx, or --pool <name>.")
+	create := func(ctx context.Context) (string, error) {
+		// A reused session does not need a tool, so the check is deferred
+		// until an instance actually has to be created.
+		if missingTool {
+			return "", missingToolErr
+		}
+		id, err := apiCreate(ctx, flags.ToolName, flags.ToolID)
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary instance: %w", err)
+		}
+		return id, nil
+	}
 
-	id, err := apiCreate(ctx, flags.ToolName, flags.ToolID)
+	if flags.Session != "" {
+		return resolveSession(ctx, flags, policy, session, create, collect, notify)
+	}
+	if missingTool {
+		return nil, missingToolErr
+	}
+
+	id, err := create(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temporary instance: %w", err)
+		return nil, err
 	}
 
 	exec := &ExecutionContext{
 		SandboxInstanceId:        id,
 		TemporarySandboxInstance: true,
+		Pool:                     flags.Pool,
 		Cleanup: &ExecutionCleanupResult{
 			Policy: string(policy),
 			Status: "skipped",
@@ -182,7 +267,9 @@ func ResolveOverlay(
 				return
 			}
 		}
-		if err := apiDelete(ctx, id); err != nil {
+		// Cleanup also runs after the caller's context was cancelled, e.g. on
+		// SIGINT, so the delete must not inherit that cancellation.
+		if err := apiDelete(context.WithoutCancel(ctx), id); err != nil {
 			exec.Cleanup.Status = "failed"
 			exec.Cleanup.Reason = err.Error()
 			notify("Warning: failed to delete temporary sandbox %s: %v\n", id, err)
@@ -203,5 +290,7 @@ func ResolveOverlay(
 		ExecContext:    exec,
 		cleanup:        cleanupFn,
 		preExecCleanup: preExecCleanupFn,
+		collect:        collect,
+		notify:         notify,
 	}, nil
 }
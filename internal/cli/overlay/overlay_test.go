@@ -3,7 +3,9 @@ package overlay
 import (
 	"context"
 	"errors"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
 )
@@ -174,3 +176,160 @@ var errFakeDelete = &fakeError{}
 type fakeError struct{}
 
 func (e *fakeError) Error() string { return "fake delete error" }
+
+func TestResolveOverlay_PoolLeasesThroughCreate(t *testing.T) {
+	ctx := context.Background()
+	create := func(_ context.Context, toolName, toolID string) (string, error) {
+		if toolName != "" || toolID != "" {
+			t.Errorf("pool lease got tool selection %q/%q", toolName, toolID)
+		}
+		return "ins-pooled", nil
+	}
+	r, err := ResolveOverlay(ctx, OverlayFlags{CreateTempInstance: true, Pool: "py"}, nil, create, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if r.InstanceID != "ins-pooled" || r.ExecContext.Pool != "py" {
+		t.Fatalf("resolved=%+v ctx=%+v", r, r.ExecContext)
+	}
+}
+
+func TestResolveOverlay_PoolValidation(t *testing.T) {
+	ctx := context.Background()
+	_, err := ResolveOverlay(ctx, OverlayFlags{Pool: "py"}, nil, nil, nil, nil)
+	if got := failureCode(err); got != "MISSING_REQUIRED_FLAG" {
+		t.Fatalf("expected MISSING_REQUIRED_FLAG, got code=%s err=%v", got, err)
+	}
+	_, err = ResolveOverlay(ctx, OverlayFlags{CreateTempInstance: true, Pool: "py", ToolID: "sdt-1"}, nil, nil, nil, nil)
+	if got := failureCode(err); got != "CONFLICTING_FLAGS" {
+		t.Fatalf("expected CONFLICTING_FLAGS, got code=%s err=%v", got, err)
+	}
+}
+
+type fakeSession struct {
+	instanceID string
+	released   []bool
+}
+
+func (f *fakeSession) Acquire(ctx context.Context, create func(context.Context) (string, error)) (string, bool, error) {
+	if f.instanceID != "" {
+		return f.instanceID, true, nil
+	}
+	id, err := create(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	f.instanceID = id
+	return id, false, nil
+}
+
+func (f *fakeSession) Release(_ string, success bool) {
+	f.released = append(f.released, success)
+}
+
+func TestResolveSessionOverlay_ReusesInstanceAndDefersCleanup(t *testing.T) {
+	ctx := context.Background()
+	sess := &fakeSession{}
+	creates := 0
+	create := func(context.Context, string, string) (string, error) {
+		creates++
+		return "ins-session", nil
+	}
+	del := func(context.Context, string) error {
+		t.Fatal("session executions must not delete the instance")
+		return nil
+	}
+	flags := OverlayFlags{CreateTempInstance: true, Session: "build", ToolID: "sdt-1"}
+	first, err := ResolveSessionOverlay(ctx, flags, nil, sess, create, del, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	first.Cleanup(false)
+
+	// A reused session does not need the tool flags again.
+	second, err := ResolveSessionOverlay(ctx, OverlayFlags{CreateTempInstance: true, Session: "build"}, nil, sess, create, del, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	second.CleanupForPreExecutionFailure()
+
+	if creates != 1 || second.InstanceID != "ins-session" || !second.ExecContext.SessionReused {
+		t.Fatalf("creates=%d second=%+v", creates, second.ExecContext)
+	}
+	if len(sess.released) != 1 || sess.released[0] {
+		t.Fatalf("released=%v", sess.released)
+	}
+	if first.ExecContext.Cleanup.Status != "skipped" || first.ExecContext.Session != "build" {
+		t.Fatalf("cleanup=%+v", first.ExecContext)
+	}
+}
+
+func TestResolveSessionOverlay_Validation(t *testing.T) {
+	ctx := context.Background()
+	_, err := ResolveSessionOverlay(ctx, OverlayFlags{Session: "build"}, nil, &fakeSession{}, nil, nil, nil)
+	if got := failureCode(err); got != "MISSING_REQUIRED_FLAG" {
+		t.Fatalf("expected MISSING_REQUIRED_FLAG, got code=%s err=%v", got, err)
+	}
+	_, err = ResolveSessionOverlay(ctx, OverlayFlags{CreateTempInstance: true, Session: "build"}, nil, &fakeSession{}, nil, nil, nil)
+	if got := failureCode(err); got != "MISSING_REQUIRED_FLAG" {
+		t.Fatalf("new session without a tool: expected MISSING_REQUIRED_FLAG, got code=%s err=%v", got, err)
+	}
+}
+
+func TestResolveOverlay_CleanupRunsOnceAndIgnoresCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	deletes := 0
+	create := func(context.Context, string, string) (string, error) { return "ins-temp", nil }
+	del := func(ctx context.Context, _ string) error {
+		deletes++
+		return ctx.Err()
+	}
+	r, err := ResolveOverlay(ctx, OverlayFlags{CreateTempInstance: true, ToolID: "sdt-1"}, nil, create, del, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r.WatchSignals(func(int) { t.Errorf("exit called without a signal") }, syscall.SIGHUP)
+	cancel()
+	r.Cleanup(true)
+	r.CleanupForPreExecutionFailure()
+	r.Cleanup(false)
+	if deletes != 1 {
+		t.Fatalf("deletes = %d, want 1", deletes)
+	}
+	if r.ExecContext.Cleanup.Status != "deleted" {
+		t.Fatalf("cleanup = %+v", r.ExecContext.Cleanup)
+	}
+}
+
+func withPool(metadata map[string]string, pool string) map[string]string {
+	metadata["agr-pool"] = pool
+	return metadata
+}
+
+func TestOrphanCheckClassify(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	check := OrphanCheck{Host: "dev", Alive: func(pid int) bool { return pid == 42 }}
+	cases := []struct {
+		name     string
+		metadata map[string]string
+		orphan   bool
+	}{
+		{"dead creator", CreatorMetadata("dev", 7, started, CleanupAlways, ""), true},
+		{"live creator", CreatorMetadata("dev", 42, started, CleanupAlways, ""), false},
+		{"other host", CreatorMetadata("ci", 7, started, CleanupAlways, ""), false},
+		{"kept on purpose", CreatorMetadata("dev", 7, started, CleanupNever, ""), false},
+		{"crashed success run", CreatorMetadata("dev", 7, started, CleanupSuccess, ""), true},
+		{"leaked pool lease", withPool(CreatorMetadata("dev", 7, started, CleanupAlways, ""), "py"), true},
+		{"session", CreatorMetadata("dev", 7, started, CleanupAlways, "build"), false},
+		{"untagged", map[string]string{"agr-pool": "py"}, false},
+	}
+	for _, tc := range cases {
+		orphan, reason := check.Classify(tc.metadata)
+		if orphan != tc.orphan || reason == "" {
+			t.Errorf("%s: orphan=%v reason=%q", tc.name, orphan, reason)
+		}
+	}
+	if got := CreatorMetadata("dev", 7, started, CleanupAlways, "")[MetadataStartedAt]; got != "2026-01-02T03:04:05Z" {
+		t.Fatalf("started-at = %q", got)
+	}
+}
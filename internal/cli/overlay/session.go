@@ -0,0 +1,65 @@
+package overlay
+
+import (
+	"context"
+	"fmt"
+)
+
+// Session binds overlay invocations to one long-lived temporary instance.
+// Implementations persist the binding so separate CLI processes that share a
+// session name execute against the same instance.
+type Session interface {
+	// Acquire returns the session's instance, calling create when the session
+	// has no usable instance yet. reused reports whether an existing instance
+	// was returned.
+	Acquire(ctx context.Context, create func(ctx context.Context) (string, error)) (instanceID string, reused bool, err error)
+	// Release records the outcome of one execution against instanceID. The
+	// instance is kept until the session ends.
+	Release(instanceID string, success bool)
+}
+
+// resolveSession acquires the session instance and defers cleanup to
+// `agr session end`.
+func resolveSession(
+	ctx context.Context,
+	flags OverlayFlags,
+	policy CleanupPolicy,
+	session Session,
+	create func(ctx context.Context) (string, error),
+	collect []CollectSpec,
+	notify func(format string, args ...any),
+) (*ResolvedOverlay, error) {
+	id, reused, err := session.Acquire(ctx, create)
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		notify("Reusing temporary sandbox %s from session %s\n", id, flags.Session)
+	}
+	exec := &ExecutionContext{
+		SandboxInstanceId:        id,
+		TemporarySandboxInstance: true,
+		Pool:                     flags.Pool,
+		Session:                  flags.Session,
+		SessionReused:            reused,
+		Cleanup: &ExecutionCleanupResult{
+			Policy: string(policy),
+			Status: "skipped",
+			Reason: fmt.Sprintf("session=%s", flags.Session),
+		},
+	}
+	return &ResolvedOverlay{
+		InstanceID:  id,
+		IsTemp:      true,
+		Policy:      policy,
+		ExecContext: exec,
+		cleanup: func(success bool) {
+			session.Release(id, success)
+		},
+		// Local validation failures never reached the sandbox, so they do not
+		// count against the session's cleanup policy.
+		preExecCleanup: func() {},
+		collect:        collect,
+		notify:         notify,
+	}, nil
+}
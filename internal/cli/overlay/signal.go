@@ -0,0 +1,41 @@
+package overlay
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSignals makes sure a temporary instance is cleaned up when the process
+// is interrupted. When one of sigs arrives before Cleanup, the cleanup policy
+// is applied as for a failed execution and exit is called with the
+// conventional 128+signal status. Cleanup stops watching. It is a no-op for
+// existing instances.
+func (r *ResolvedOverlay) WatchSignals(exit func(code int), sigs ...os.Signal) {
+	if r == nil || !r.IsTemp || len(sigs) == 0 {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	r.stopSignals = func() {
+		signal.Stop(ch)
+		close(done)
+	}
+	go func() {
+		select {
+		case sig := <-ch:
+			r.notify("Received %s; cleaning up temporary sandbox %s\n", sig, r.InstanceID)
+			r.Cleanup(false)
+			exit(signalExitCode(sig))
+		case <-done:
+		}
+	}()
+}
+
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// newPoolStore opens the warm pool registry; tests replace it with a
+// temp-dir store.
+var newPoolStore = func() (*pool.Store, error) { return pool.NewStore() }
+
+// instanceRunning reports whether a pooled or session instance is still usable.
+var instanceRunning = func(ctx context.Context, instanceID string) (bool, error) {
+	client, err := newCloudClient()
+	if err != nil {
+		return false, err
+	}
+	req := ags.NewDescribeSandboxInstanceListRequest()
+	req.InstanceIds = []*string{&instanceID}
+	resp, err := cloudDescribeSandboxInstanceList(ctx, client, req)
+	if err != nil {
+		return false, err
+	}
+	for _, inst := range resp.InstanceSet {
+		if inst != nil && derefString(inst.InstanceId) == instanceID {
+			return derefString(inst.Status) == "RUNNING", nil
+		}
+	}
+	return false, nil
+}
+
+// startPoolReplenish launches a detached `agr pool replenish` process so the
+// pool refills after this command exits.
+var startPoolReplenish = func(name string) error {
+	selfPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	args := []string{"pool", "replenish", name}
+	if cfgFile != "" {
+		args = append(args, "--config", cfgFile)
+	}
+	if region != "" {
+		args = append(args, "--region", region)
+	}
+	cmd := exec.Command(selfPath, args...)
+	cmd.Env = os.Environ()
+	if secretID != "" {
+		cmd.Env = append(cmd.Env, "TENCENTCLOUD_SECRET_ID="+secretID)
+	}
+	if secretKey != "" {
+		cmd.Env = append(cmd.Env, "TENCENTCLOUD_SECRET_KEY="+secretKey)
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		logPath := filepath.Join(homeDir, pool.StoreDir, fmt.Sprintf("pool-%s.log", name))
+		if logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600); err == nil {
+			cmd.Stdout = logFile
+			cmd.Stderr = logFile
+			defer logFile.Close()
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start pool replenisher: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// NewPoolStore opens the warm pool registry at ~/.agr/pools.json.
+func NewPoolStore() (*pool.Store, error) {
+	return newPoolStore()
+}
+
+// PoolStartInstance starts one instance for a warm pool, tagged with the pool
+// name via instance metadata.
+func PoolStartInstance(ctx context.Context, p pool.Pool) (string, error) {
+	return startTaggedInstance(ctx, "", p.ToolID, p.Timeout, map[string]string{pool.MetadataKey: p.Name})
+}
+
+// tagLeasedInstance replaces the metadata of a leased pool instance with the
+// creator metadata carried by ctx, keeping the pool tag.
+func tagLeasedInstance(ctx context.Context, instanceID, poolName string) error {
+	creator, _ := ctx.Value(creatorMetadataKey{}).(map[string]string)
+	if len(creator) == 0 {
+		return nil
+	}
+	client, err := newCloudClient()
+	if err != nil {
+		return err
+	}
+	req := ags.NewUpdateSandboxInstanceRequest()
+	req.InstanceId = &instanceID
+	metadata := map[string]string{pool.MetadataKey: poolName}
+	for name, value := range creator {
+		metadata[name] = value
+	}
+	req.Metadata = metadataVars(metadata)
+	_, err = cloudUpdateSandboxInstance(ctx, client, req)
+	return err
+}
+
+// PoolNotFoundError returns the structured error for an unknown pool name.
+func PoolNotFoundError(name string) error {
+	return output.NewNotFoundError("POOL_NOT_FOUND",
+		fmt.Sprintf("pool %q not found", name),
+		"List pools with: agr pool status. Create one with: agr pool create --name "+name+" --tool-id <tool-id> --size <n>")
+}
+
+// SetPoolHooksForTest replaces the pool store, liveness check, and background
+// replenisher used by --pool, and returns a restore function.
+func SetPoolHooksForTest(store *pool.Store, running func(context.Context, string) (bool, error), replenish func(string) error) func() {
+	prevStore, prevRunning, prevReplenish := newPoolStore, instanceRunning, startPoolReplenish
+	newPoolStore = func() (*pool.Store, error) { return store, nil }
+	instanceRunning = running
+	startPoolReplenish = replenish
+	return func() {
+		newPoolStore, instanceRunning, startPoolReplenish = prevStore, prevRunning, prevReplenish
+	}
+}
+
+// leasePoolInstance takes a running instance from the named pool and starts a
+// background replenisher. When the pool is empty it falls back to create with
+// the pool's tool so the execution still proceeds.
+func leasePoolInstance(ctx context.Context, name string, create func(ctx context.Context, toolName, toolID string) (string, error)) (string, error) {
+	store, err := newPoolStore()
+	if err != nil {
+		return "", err
+	}
+	leased := ""
+	var current pool.Pool
+	for {
+		inst, p, taken, err := store.Take(name)
+		if err != nil {
+			return "", err
+		}
+		if p.Name == "" {
+			return "", PoolNotFoundError(name)
+		}
+		current = p
+		if !taken {
+			break
+		}
+		// A failed liveness check should not discard a possibly healthy
+		// instance; only a confirmed non-running instance is skipped.
+		running, err := instanceRunning(ctx, inst.InstanceID)
+		if err != nil || running {
+			leased = inst.InstanceID
+			break
+		}
+		stderr("Discarding pooled instance %s (no longer running)\n", inst.InstanceID)
+	}
+	if err := startPoolReplenish(name); err != nil {
+		stderr("Warning: %v\n", err)
+	}
+	if leased != "" {
+		stderr("Leased instance %s from pool %s\n", leased, name)
+		// The replenisher started the instance; tag it with this process so
+		// `instance prune --orphans` finds it if the process dies.
+		if err := tagLeasedInstance(ctx, leased, name); err != nil {
+			stderr("Warning: failed to tag leased instance %s: %v\n", leased, err)
+		}
+		return leased, nil
+	}
+	stderr("Pool %s has no ready instance; starting a new one\n", name)
+	return create(ctx, "", current.ToolID)
+}
@@ -0,0 +1,294 @@
+// Package pool manages locally tracked warm pools of pre-started sandbox
+// instances used by the temporary sandbox workflow.
+//
+// Pool state lives in ~/.agr/pools.json. Cross-process safety is ensured via
+// flock file locking and atomic writes, so a background replenisher and the
+// commands that lease instances can update the same pool concurrently.
+package pool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/jsonfile"
+	"github.com/gofrs/flock"
+)
+
+const (
+	// StoreDir is the directory name under user home for storing pool state.
+	StoreDir = ".agr"
+	// StoreFile is the filename for the pool registry.
+	StoreFile = "pools.json"
+	// StoreVersion is the current version of the pool file format.
+	StoreVersion = 1
+	// MetadataKey is the instance metadata name that tags pool-owned instances.
+	MetadataKey = "agr-pool"
+)
+
+// Instance is one pre-started instance waiting to be leased.
+type Instance struct {
+	InstanceID string    `json:"instance_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Pool is the desired size and ready instances of one warm pool.
+type Pool struct {
+	Name      string     `json:"name"`
+	ToolID    string     `json:"tool_id"`
+	Size      int        `json:"size"`
+	Timeout   string     `json:"timeout,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	Ready     []Instance `json:"ready"`
+}
+
+// Deficit returns how many instances must be started to reach Size.
+func (p Pool) Deficit() int {
+	if n := p.Size - len(p.Ready); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// StoreData represents the structure of the pool file.
+type StoreData struct {
+	Version int              `json:"version"`
+	Pools   map[string]*Pool `json:"pools"`
+}
+
+// Store manages the pool registry file with cross-process locking.
+type Store struct {
+	file *jsonfile.File // pools.json, locked through pools.json.lock
+}
+
+// NewStore creates a Store. The registry is stored at ~/.agr/pools.json.
+func NewStore() (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	dir := filepath.Join(homeDir, StoreDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return NewStoreAt(filepath.Join(dir, StoreFile)), nil
+}
+
+// NewStoreAt creates a Store backed by the given file path.
+func NewStoreAt(path string) *Store {
+	return &Store{file: jsonfile.New(path, "pool store")}
+}
+
+// Get returns a copy of the named pool.
+func (s *Store) Get(name string) (Pool, bool, error) {
+	var out Pool
+	var found bool
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		if p, ok := data.Pools[name]; ok && p != nil {
+			out, found = clonePool(p), true
+		}
+		return false, nil
+	})
+	return out, found, err
+}
+
+// List returns copies of all pools keyed by name.
+func (s *Store) List() (map[string]Pool, error) {
+	out := map[string]Pool{}
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		for name, p := range data.Pools {
+			if p != nil {
+				out[name] = clonePool(p)
+			}
+		}
+		return false, nil
+	})
+	return out, err
+}
+
+// Upsert creates the named pool or updates its size and timeout, keeping the
+// instances that are already ready. It fails when an existing pool belongs to
+// a different tool.
+func (s *Store) Upsert(p Pool) (Pool, error) {
+	var out Pool
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		existing, ok := data.Pools[p.Name]
+		if ok && existing != nil {
+			if existing.ToolID != p.ToolID {
+				return false, &ToolMismatchError{Name: p.Name, ToolID: existing.ToolID}
+			}
+			existing.Size = p.Size
+			existing.Timeout = p.Timeout
+			out = clonePool(existing)
+			return true, nil
+		}
+		created := clonePool(&p)
+		data.Pools[p.Name] = &created
+		out = clonePool(&created)
+		return true, nil
+	})
+	return out, err
+}
+
+// Take removes and returns the oldest ready instance of the named pool. The
+// boolean result is false when the pool does not exist or has no ready
+// instance; the returned Pool is the state after the lease.
+func (s *Store) Take(name string) (Instance, Pool, bool, error) {
+	var inst Instance
+	var out Pool
+	var taken bool
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		p, ok := data.Pools[name]
+		if !ok || p == nil {
+			return false, nil
+		}
+		if len(p.Ready) > 0 {
+			inst = p.Ready[0]
+			p.Ready = p.Ready[1:]
+			taken = true
+		}
+		out = clonePool(p)
+		return taken, nil
+	})
+	return inst, out, taken, err
+}
+
+// Add appends a ready instance to the named pool. It returns false without
+// storing the instance when the pool no longer exists or is already full, so
+// the caller can delete the surplus instance.
+func (s *Store) Add(name string, inst Instance) (bool, error) {
+	var added bool
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		p, ok := data.Pools[name]
+		if !ok || p == nil || len(p.Ready) >= p.Size {
+			return false, nil
+		}
+		p.Ready = append(p.Ready, inst)
+		added = true
+		return true, nil
+	})
+	return added, err
+}
+
+// Remove deletes the named pool and returns the instances it still held.
+func (s *Store) Remove(name string) ([]Instance, bool, error) {
+	var ready []Instance
+	var found bool
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		p, ok := data.Pools[name]
+		if !ok {
+			return false, nil
+		}
+		found = true
+		if p != nil {
+			ready = append(ready, p.Ready...)
+		}
+		delete(data.Pools, name)
+		return true, nil
+	})
+	return ready, found, err
+}
+
+// ToolMismatchError reports that a pool name is already bound to another tool.
+type ToolMismatchError struct {
+	Name   string
+	ToolID string
+}
+
+// Error returns the mismatch message.
+func (e *ToolMismatchError) Error() string {
+	return fmt.Sprintf("pool %q already exists for tool %s", e.Name, e.ToolID)
+}
+
+// StartFunc starts one instance for the pool and returns its id.
+type StartFunc func(ctx context.Context, p Pool) (string, error)
+
+// DeleteFunc deletes a surplus instance that could not be added to the pool.
+type DeleteFunc func(ctx context.Context, instanceID string) error
+
+// FillResult records the outcome of one Fill call.
+type FillResult struct {
+	Added   []string
+	Surplus []string
+	Errors  []error
+}
+
+// Fill starts instances until the named pool reaches its configured size.
+// Instances started concurrently by another process can overshoot the size;
+// such surplus instances are deleted instead of being added.
+func Fill(ctx context.Context, store *Store, name string, start StartFunc, del DeleteFunc, now func() time.Time) (FillResult, error) {
+	var result FillResult
+	p, ok, err := store.Get(name)
+	if err != nil {
+		return result, err
+	}
+	if !ok {
+		return result, fmt.Errorf("pool %q not found", name)
+	}
+	for i := p.Deficit(); i > 0; i-- {
+		id, err := start(ctx, p)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			continue
+		}
+		added, err := store.Add(name, Instance{InstanceID: id, CreatedAt: now()})
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+		}
+		if added {
+			result.Added = append(result.Added, id)
+			continue
+		}
+		result.Surplus = append(result.Surplus, id)
+		if err := del(ctx, id); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to delete surplus instance %s: %w", id, err))
+		}
+	}
+	return result, nil
+}
+
+// withLock acquires the file lock, loads the store, runs fn, and saves the
+// store when fn reports a change.
+func (s *Store) withLock(fn func(*StoreData) (bool, error)) error {
+	unlock, err := s.file.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data := &StoreData{Version: StoreVersion}
+	if _, err := s.file.Load(data); err != nil {
+		return err
+	}
+	if data.Version < StoreVersion {
+		data.Version = StoreVersion
+	}
+	if data.Pools == nil {
+		data.Pools = map[string]*Pool{}
+	}
+	changed, err := fn(data)
+	if err != nil || !changed {
+		return err
+	}
+	return s.file.Save(data)
+}
+
+func clonePool(p *Pool) Pool {
+	out := *p
+	out.Ready = append([]Instance(nil), p.Ready...)
+	return out
+}
+
+// TryLockReplenish takes the per-pool replenisher lock without waiting, so at
+// most one background replenisher fills a pool at a time. ok is false when
+// another process holds the lock.
+func (s *Store) TryLockReplenish(name string) (unlock func(), ok bool, err error) {
+	fl := flock.New(fmt.Sprintf("%s.%s.replenish.lock", s.file.Path(), name))
+	locked, err := fl.TryLock()
+	if err != nil || !locked {
+		return func() {}, false, err
+	}
+	return func() { _ = fl.Unlock() }, true, nil
+}
@@ -0,0 +1,116 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStoreAt(filepath.Join(t.TempDir(), "pools.json"))
+}
+
+func TestStoreUpsertTakeAndRemove(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := store.Upsert(Pool{Name: "py", ToolID: "sdt-1", Size: 2}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	for _, id := range []string{"ins-1", "ins-2", "ins-3"} {
+		if _, err := store.Add("py", Instance{InstanceID: id}); err != nil {
+			t.Fatalf("Add %s: %v", id, err)
+		}
+	}
+	p, ok, err := store.Get("py")
+	if err != nil || !ok || len(p.Ready) != 2 {
+		t.Fatalf("pool=%+v ok=%v err=%v", p, ok, err)
+	}
+
+	inst, after, taken, err := store.Take("py")
+	if err != nil || !taken || inst.InstanceID != "ins-1" || after.Deficit() != 1 {
+		t.Fatalf("take=%+v after=%+v taken=%v err=%v", inst, after, taken, err)
+	}
+
+	if _, err := store.Upsert(Pool{Name: "py", ToolID: "sdt-other", Size: 1}); !errors.As(err, new(*ToolMismatchError)) {
+		t.Fatalf("expected ToolMismatchError, got %v", err)
+	}
+
+	ready, found, err := store.Remove("py")
+	if err != nil || !found || len(ready) != 1 || ready[0].InstanceID != "ins-2" {
+		t.Fatalf("remove ready=%+v found=%v err=%v", ready, found, err)
+	}
+	if _, _, taken, _ := store.Take("py"); taken {
+		t.Fatalf("take from removed pool should fail")
+	}
+}
+
+func TestFillStartsDeficitAndDeletesSurplus(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := store.Upsert(Pool{Name: "py", ToolID: "sdt-1", Size: 3}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	started := 0
+	start := func(_ context.Context, p Pool) (string, error) {
+		started++
+		if p.ToolID != "sdt-1" {
+			t.Fatalf("tool id=%s", p.ToolID)
+		}
+		if started == 2 {
+			// Simulate a concurrent replenisher filling the last slots.
+			_, _ = store.Add("py", Instance{InstanceID: "ins-other-a"})
+			_, _ = store.Add("py", Instance{InstanceID: "ins-other-b"})
+		}
+		return fmt.Sprintf("ins-%d", started), nil
+	}
+	var deleted []string
+	del := func(_ context.Context, id string) error {
+		deleted = append(deleted, id)
+		return nil
+	}
+
+	result, err := Fill(context.Background(), store, "py", start, del, time.Now)
+	if err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+	if started != 3 || len(result.Added) != 1 || len(result.Surplus) != 2 || len(deleted) != 2 {
+		t.Fatalf("started=%d result=%+v deleted=%v", started, result, deleted)
+	}
+	if p, _, _ := store.Get("py"); p.Deficit() != 0 {
+		t.Fatalf("pool not full: %+v", p)
+	}
+}
+
+func TestFillRecordsStartErrors(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := store.Upsert(Pool{Name: "py", ToolID: "sdt-1", Size: 1}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	start := func(context.Context, Pool) (string, error) { return "", errors.New("quota exceeded") }
+	result, err := Fill(context.Background(), store, "py", start, nil, time.Now)
+	if err != nil || len(result.Errors) != 1 || len(result.Added) != 0 {
+		t.Fatalf("result=%+v err=%v", result, err)
+	}
+	if _, err := Fill(context.Background(), store, "missing", start, nil, time.Now); err == nil {
+		t.Fatalf("expected missing pool error")
+	}
+}
+
+func TestTryLockReplenishIsExclusive(t *testing.T) {
+	store := newTestStore(t)
+	unlock, ok, err := store.TryLockReplenish("py")
+	if err != nil || !ok {
+		t.Fatalf("first lock ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := NewStoreAt(store.file.Path()).TryLockReplenish("py"); err != nil || ok {
+		t.Fatalf("second lock ok=%v err=%v", ok, err)
+	}
+	unlock()
+	if unlock, ok, _ := store.TryLockReplenish("py"); !ok {
+		t.Fatalf("lock not released")
+	} else {
+		unlock()
+	}
+}
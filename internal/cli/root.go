@@ -702,13 +702,13 @@ func invalidNDJSONOutputError(cmd *cobra.Command) *output.CLIError {
 		}
 		return output.NewUsageError(
 			"NDJSON_REQUIRES_STREAM",
-			"-o ndjson is only supported with 'instance code run --stream' and 'instance exec --stream'",
+			"-o ndjson is only supported with "+ndjsonCommandList(),
 			"Use -o json for a single envelope, or add --stream on a supported streaming command.",
 		)
 	}
 	return output.NewUsageError(
 		"INVALID_CONFIG",
-		"-o ndjson is only supported with 'instance code run --stream' and 'instance exec --stream'",
+		"-o ndjson is only supported with "+ndjsonCommandList(),
 		"Set output to 'text' or 'json', or override with -o text/-o json for this command.",
 	)
 }
@@ -736,13 +736,36 @@ func isCobraUsageError(err error) bool {
 		strings.Contains(msg, "unknown shorthand")
 }
 
+// ndjsonCommands lists the commands that may stream -o ndjson, with the
+// invocation named in error messages.
+var ndjsonCommands = []struct {
+	ID    string
+	Usage string
+}{
+	{ID: "instance.code.run", Usage: "instance code run --stream"},
+	{ID: "instance.exec", Usage: "instance exec --stream"},
+	{ID: "instance.proxy", Usage: "instance proxy --auto"},
+	{ID: "instance.mobile.logcat", Usage: "instance mobile logcat"},
+	{ID: "instance.mobile.list", Usage: "instance mobile list --watch"},
+}
+
 func isNDJSONAllowedCommand(cmd *cobra.Command) bool {
-	switch canonicalCommandID(cmd) {
-	case "instance.code.run", "instance.exec":
-		return true
-	default:
-		return false
+	id := canonicalCommandID(cmd)
+	for _, c := range ndjsonCommands {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ndjsonCommandList names the ndjson commands, for example "'a', 'b' and 'c'".
+func ndjsonCommandList() string {
+	names := make([]string, len(ndjsonCommands))
+	for i, c := range ndjsonCommands {
+		names[i] = "'" + c.Usage + "'"
 	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
 }
 
 func shouldSkipConfigPreflight(cmd *cobra.Command) bool {
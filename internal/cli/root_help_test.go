@@ -56,11 +56,16 @@ func TestIsNDJSONAllowedCommand(t *testing.T) {
 	code := &cobra.Command{Use: "code"}
 	run := &cobra.Command{Use: "run"}
 	exec := &cobra.Command{Use: "exec"}
+	proxy := &cobra.Command{Use: "proxy"}
+	mobile := &cobra.Command{Use: "mobile"}
+	logcat := &cobra.Command{Use: "logcat"}
+	mobileList := &cobra.Command{Use: "list"}
 	tool := &cobra.Command{Use: "tool"}
 	toolExec := &cobra.Command{Use: "exec"}
 
 	root.AddCommand(instance, tool)
-	instance.AddCommand(code, exec)
+	instance.AddCommand(code, exec, proxy, mobile)
+	mobile.AddCommand(logcat, mobileList)
 	code.AddCommand(run)
 	tool.AddCommand(toolExec)
 
@@ -70,7 +75,26 @@ func TestIsNDJSONAllowedCommand(t *testing.T) {
 	if !isNDJSONAllowedCommand(exec) {
 		t.Fatal("expected instance.exec to allow ndjson")
 	}
+	if !isNDJSONAllowedCommand(proxy) {
+		t.Fatal("expected instance.proxy to allow ndjson")
+	}
+	if !isNDJSONAllowedCommand(logcat) {
+		t.Fatal("expected instance.mobile.logcat to allow ndjson")
+	}
+	if !isNDJSONAllowedCommand(mobileList) {
+		t.Fatal("expected instance.mobile.list to allow ndjson")
+	}
 	if isNDJSONAllowedCommand(toolExec) {
 		t.Fatal("expected tool.exec to reject ndjson")
 	}
 }
+
+func TestNDJSONCommandListNamesEveryAllowedCommand(t *testing.T) {
+	want := "'instance code run --stream', 'instance exec --stream', 'instance proxy --auto', 'instance mobile logcat' and 'instance mobile list --watch'"
+	if got := ndjsonCommandList(); got != want {
+		t.Fatalf("ndjsonCommandList() = %q, want %q", got, want)
+	}
+	if msg := invalidNDJSONOutputError(&cobra.Command{Use: "agr"}).Error(); !strings.Contains(msg, want) {
+		t.Fatalf("error = %q", msg)
+	}
+}
@@ -98,6 +98,14 @@ func acquireInstanceToken(ctx context.Context, instanceID string) (string, error
 	return accessToken, nil
 }
 
+// invalidateInstanceToken removes the cached access token for the given
+// instance so that the next acquire asks the control plane again.
+func invalidateInstanceToken(instanceID string) {
+	if tokenCache, err := token.NewCache(); err == nil {
+		_ = tokenCache.Delete(instanceID)
+	}
+}
+
 // GetCachedTokenOrAcquire gets the access token from cache, or acquires a new one.
 func GetCachedTokenOrAcquire(ctx context.Context, instanceID string) (string, error) {
 	tokenCache, err := token.NewCache()
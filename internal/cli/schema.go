@@ -1009,8 +1009,12 @@ func buildHandwrittenSchemas() []CommandSchema {
 				{Name: "cleanup", Type: "enum", Values: []string{"always", "success", "never"}, Default: "always"},
 				{Name: "tool-name", Shorthand: "t", Type: "string"},
 				{Name: "tool-id", Type: "string"},
+				{Name: "pool", Type: "string"},
+				{Name: "session", Type: "string"},
+				{Name: "session-ttl", Type: "string", Default: "30m"},
+				{Name: "collect", Type: "string_array"},
 			},
-			Output: "RunResult", Failures: []string{"MISSING_INSTANCE", "REMOTE_CODE_FAILED", "CONFLICTING_INPUTS", "MISSING_CODE", "UNSUPPORTED_LANGUAGE", "CONFLICTING_FLAGS", "INVALID_CLEANUP", "MISSING_REQUIRED_FLAG"},
+			Output: "RunResult", Failures: []string{"MISSING_INSTANCE", "REMOTE_CODE_FAILED", "CONFLICTING_INPUTS", "MISSING_CODE", "UNSUPPORTED_LANGUAGE", "CONFLICTING_FLAGS", "INVALID_CLEANUP", "INVALID_COLLECT", "MISSING_REQUIRED_FLAG", "POOL_NOT_FOUND", "INVALID_SESSION_NAME", "INVALID_SESSION_TTL", "SESSION_CONFLICT"},
 		},
 		{
 			Name: "instance.exec", Summary: "Execute command in an existing or temporary sandbox instance",
@@ -1031,8 +1035,15 @@ func buildHandwrittenSchemas() []CommandSchema {
 				{Name: "cleanup", Type: "enum", Values: []string{"always", "success", "never"}, Default: "always"},
 				{Name: "tool-name", Shorthand: "t", Type: "string"},
 				{Name: "tool-id", Type: "string"},
+				{Name: "pool", Type: "string"},
+				{Name: "session", Type: "string"},
+				{Name: "session-ttl", Type: "string", Default: "30m"},
+				{Name: "collect", Type: "string_array"},
+				{Name: "instances", Type: "string"},
+				{Name: "all-running", Type: "bool"},
+				{Name: "max-parallel", Type: "integer", Default: "4"},
 			},
-			Output: "ExecResult", Failures: []string{"MISSING_INSTANCE", "REMOTE_COMMAND_FAILED", "INVALID_ENV", "CONFLICTING_FLAGS", "INVALID_CLEANUP", "MISSING_REQUIRED_FLAG"},
+			Output: "ExecResult", Failures: []string{"MISSING_INSTANCE", "REMOTE_COMMAND_FAILED", "INVALID_ENV", "CONFLICTING_FLAGS", "INVALID_CLEANUP", "INVALID_COLLECT", "MISSING_REQUIRED_FLAG", "POOL_NOT_FOUND", "INVALID_SESSION_NAME", "INVALID_SESSION_TTL", "SESSION_CONFLICT", "INVALID_MAX_PARALLEL", "PARTIAL_EXEC_FAILED"},
 		},
 		{
 			Name: "instance.file.upload", Summary: "Upload file to sandbox instance",
@@ -1070,6 +1081,98 @@ func buildHandwrittenSchemas() []CommandSchema {
 			SupportsRequest: false,
 			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}},
 		},
+		{
+			Name: "instance.browser.cdp", Summary: "Serve a local CDP endpoint for a browser sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: true,
+			RequiresAuth: true, SupportsJson: false, SupportsNdjson: false, SupportsJq: false,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}},
+			Flags: []FlagSchema{
+				{Name: "address", Type: "string"},
+				{Name: "port", Type: "integer"},
+				{Name: "verbose", Type: "bool"},
+				{Name: "allow-origin", Type: "string_array"},
+			},
+			Failures: []string{"INVALID_ADDRESS", "INVALID_PORT", "JSON_REQUIRES_BACKGROUND"},
+		},
+		{
+			Name: "instance.browser.eval", Summary: "Evaluate JavaScript in a browser sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}, {Name: "Expression", Type: "string", Required: true}},
+			Flags: []FlagSchema{
+				{Name: "port", Type: "integer"},
+				{Name: "timeout", Type: "string"},
+				{Name: "url", Type: "string"},
+			},
+			Output:   "BrowserEvalResult",
+			Failures: []string{"INVALID_PORT", "INVALID_TIMEOUT", "INVALID_URL"},
+		},
+		{
+			Name: "instance.browser.navigate", Summary: "Load a URL in a browser sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}, {Name: "Url", Type: "string", Required: true}},
+			Flags: []FlagSchema{
+				{Name: "port", Type: "integer"},
+				{Name: "timeout", Type: "string"},
+			},
+			Output:   "BrowserPage",
+			Failures: []string{"INVALID_PORT", "INVALID_TIMEOUT", "INVALID_URL"},
+		},
+		{
+			Name: "instance.browser.pdf", Summary: "Print a browser sandbox page to PDF",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}, {Name: "LocalPath", Type: "string"}},
+			Flags: []FlagSchema{
+				{Name: "port", Type: "integer"},
+				{Name: "timeout", Type: "string"},
+				{Name: "url", Type: "string"},
+				{Name: "landscape", Type: "bool"},
+			},
+			Output:   "BrowserCapture",
+			Failures: []string{"INVALID_PORT", "INVALID_TIMEOUT", "INVALID_URL", "INVALID_LOCAL_PATH"},
+		},
+		{
+			Name: "instance.browser.record", Summary: "Record the screen of a browser instance",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}, {Name: "LocalPath", Type: "string"}},
+			Flags: []FlagSchema{
+				{Name: "port", Type: "integer"},
+				{Name: "duration", Type: "string"},
+				{Name: "format", Type: "enum", Values: []string{"jpeg", "png"}},
+				{Name: "quality", Type: "integer"},
+			},
+			Output:   "BrowserRecording",
+			Failures: []string{"INVALID_PORT", "INVALID_DURATION", "INVALID_USAGE", "INVALID_LOCAL_PATH"},
+		},
+		{
+			Name: "instance.browser.screenshot", Summary: "Save a screenshot of a browser sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}, {Name: "LocalPath", Type: "string"}},
+			Flags: []FlagSchema{
+				{Name: "port", Type: "integer"},
+				{Name: "timeout", Type: "string"},
+				{Name: "url", Type: "string"},
+				{Name: "full-page", Type: "bool"},
+			},
+			Output:   "BrowserCapture",
+			Failures: []string{"INVALID_PORT", "INVALID_TIMEOUT", "INVALID_URL", "INVALID_LOCAL_PATH"},
+		},
 		{
 			Name: "instance.browser.vnc", Summary: "Show VNC URL for browser sandbox",
 			Mutation: false, CreatesResource: false,
@@ -1077,25 +1180,75 @@ func buildHandwrittenSchemas() []CommandSchema {
 			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
 			SupportsRequest: false,
 			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}},
-			Flags:           []FlagSchema{{Name: "port", Type: "integer"}},
-			Output:          "BrowserUrls",
-			Failures:        []string{"INVALID_PORT"},
+			Flags: []FlagSchema{
+				{Name: "port", Type: "integer"},
+				{Name: "open", Type: "bool"},
+				{Name: "local-port", Type: "integer"},
+				{Name: "rfb-port", Type: "integer"},
+			},
+			Output:   "BrowserUrls",
+			Failures: []string{"INVALID_PORT", "JSON_REQUIRES_BACKGROUND"},
 		},
 		{
-			Name: "instance.proxy", Summary: "Forward a sandbox port to localhost",
+			Name: "instance.forward", Summary: "Forward raw TCP ports of a sandbox to localhost",
 			Mutation: false, CreatesResource: false,
 			Idempotency: "none", SupportsDryRun: false, Interactive: true,
 			RequiresAuth: true, SupportsJson: false, SupportsNdjson: false, SupportsJq: false,
 			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}},
+			Flags: []FlagSchema{
+				{Name: "tcp", Type: "string_array"},
+				{Name: "address", Type: "string"},
+				{Name: "bridge-port", Type: "integer"},
+			},
+			Failures: []string{"INVALID_ADDRESS", "INVALID_PORT", "MISSING_REQUIRED_FLAG"},
+		},
+		{
+			Name: "instance.proxy", Summary: "Forward sandbox ports to localhost",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: true,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
 			Args: []ArgSchema{
 				{Name: "InstanceId", Type: "string", Required: true},
-				{Name: "PortSpec", Type: "string", Required: true},
+				{Name: "PortSpec", Type: "string", Variadic: true},
 			},
 			Flags: []FlagSchema{
 				{Name: "address", Type: "string"},
 				{Name: "verbose", Type: "bool"},
+				{Name: "ports-file", Type: "string"},
+				{Name: "socks5", Type: "string"},
+				{Name: "reverse", Type: "string"},
+				{Name: "auto", Type: "bool"},
+				{Name: "auto-range", Type: "string"},
+				{Name: "auto-interval", Type: "string"},
+				{Name: "helper-port", Type: "integer"},
+				{Name: "auth", Type: "string"},
+				{Name: "auth-credential", Type: "string"},
+				{Name: "allow-ip", Type: "string_array"},
+				{Name: "allow-origin", Type: "string_array"},
+				{Name: "tls", Type: "bool"},
+				{Name: "tls-cert", Type: "string"},
+				{Name: "tls-key", Type: "string"},
+				{Name: "access-log", Type: "string"},
+				{Name: "har", Type: "string"},
+				{Name: "background", Type: "bool"},
 			},
-			Failures: []string{"INVALID_ADDRESS", "INVALID_PORT"},
+			Output:   "BackgroundProxy",
+			Failures: []string{"INVALID_ADDRESS", "INVALID_PORT", "INVALID_PORTS_FILE", "MISSING_REQUIRED_ARG", "CONFLICTING_FLAGS", "JSON_REQUIRES_BACKGROUND", "INVALID_AUTH", "INVALID_ALLOW_IP", "INVALID_TLS", "INVALID_LOCAL_PATH", "INVALID_INTERVAL", "NDJSON_REQUIRES_STREAM"},
+		},
+		{
+			Name: "instance.prune", Summary: "Delete temporary instances left behind by killed invocations",
+			Mutation: true, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: true, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Flags: []FlagSchema{
+				{Name: "orphans", Type: "bool"},
+				{Name: "dry-run", Type: "bool"},
+				{Name: "yes", Shorthand: "y", Type: "bool"},
+			},
+			Output: "PruneData", Failures: []string{"MISSING_REQUIRED_FLAG", "PARTIAL_DELETE_FAILED"},
 		},
 		{
 			Name: "instance.mobile.connect", Summary: "Connect to mobile sandbox",
@@ -1103,8 +1256,15 @@ func buildHandwrittenSchemas() []CommandSchema {
 			Idempotency: "none", SupportsDryRun: false, Interactive: false,
 			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
 			SupportsRequest: false,
-			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}},
-			Failures:        []string{"ADB_NOT_FOUND"},
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: false, Variadic: true}},
+			Flags: []FlagSchema{
+				{Name: "port", Type: "integer", Default: "0"},
+				{Name: "tool-id", Type: "string"},
+				{Name: "all-running", Type: "bool"},
+				{Name: "max-parallel", Type: "integer", Default: "4"},
+				{Name: "supervise", Type: "bool"},
+			},
+			Failures: []string{"ADB_NOT_FOUND", "MISSING_INSTANCE", "MISSING_REQUIRED_FLAG", "CONFLICTING_FLAGS", "INVALID_MAX_PARALLEL", "INSTANCE_NOT_FOUND", "PARTIAL_CONNECT_FAILED"},
 		},
 		{
 			Name: "instance.mobile.disconnect", Summary: "Disconnect from mobile sandbox",
@@ -1112,7 +1272,7 @@ func buildHandwrittenSchemas() []CommandSchema {
 			Idempotency: "none", SupportsDryRun: false, Interactive: false,
 			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
 			SupportsRequest: false,
-			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: false}},
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: false, Variadic: true}},
 			Flags:           []FlagSchema{{Name: "all", Type: "bool"}},
 			Failures:        []string{"NO_ACTIVE_TUNNEL"},
 		},
@@ -1120,8 +1280,14 @@ func buildHandwrittenSchemas() []CommandSchema {
 			Name: "instance.mobile.list", Summary: "List active mobile sandbox connections",
 			Mutation: false, CreatesResource: false,
 			Idempotency: "none", SupportsDryRun: false, Interactive: false,
-			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: true, SupportsJq: true,
 			SupportsRequest: false,
+			Flags: []FlagSchema{
+				{Name: "prune", Type: "bool"},
+				{Name: "watch", Type: "bool"},
+				{Name: "interval", Type: "string", Default: "2s"},
+			},
+			Failures: []string{"STREAM_JSON_CONFLICT", "INVALID_INTERVAL"},
 		},
 		{
 			Name: "instance.mobile.adb", Summary: "Execute adb command on mobile sandbox",
@@ -1135,6 +1301,239 @@ func buildHandwrittenSchemas() []CommandSchema {
 			},
 			Failures: []string{"NO_ACTIVE_TUNNEL", "REMOTE_COMMAND_FAILED", "MISSING_SEPARATOR"},
 		},
+		{
+			Name: "instance.mobile.logcat", Summary: "Stream the device log of a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: true, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "InstanceId", Type: "string", Required: true}},
+			Flags: []FlagSchema{
+				{Name: "tag", Shorthand: "t", Type: "string_array"},
+				{Name: "level", Shorthand: "l", Type: "enum", Values: []string{"verbose", "debug", "info", "warn", "error", "fatal"}},
+				{Name: "package", Type: "string"},
+				{Name: "dump", Shorthand: "d", Type: "bool"},
+				{Name: "clear", Shorthand: "c", Type: "bool"},
+			},
+			Output:   "MobileLogEntryList",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_LOG_LEVEL", "STREAM_JSON_CONFLICT"},
+		},
+		{
+			Name: "instance.mobile.mirror", Summary: "Mirror and control a mobile sandbox with scrcpy",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: true,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "ScrcpyArgs", Type: "string", Variadic: true, AfterDash: true},
+			},
+			Flags: []FlagSchema{
+				{Name: "bit-rate", Type: "string", Default: "4M"},
+				{Name: "max-size", Type: "integer", Default: "1280"},
+				{Name: "max-fps", Type: "integer", Default: "30"},
+				{Name: "no-control", Type: "bool"},
+			},
+			Output:   "MobileMirror",
+			Failures: []string{"SCRCPY_NOT_FOUND", "ADB_NOT_FOUND", "INVALID_BIT_RATE", "MISSING_SEPARATOR"},
+		},
+		{
+			Name: "instance.mobile.pull", Summary: "Copy files from a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "RemotePath", Type: "string", Required: true},
+				{Name: "LocalPath", Type: "string"},
+			},
+			Output:   "MobileFileTransfer",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_LOCAL_PATH"},
+		},
+		{
+			Name: "instance.mobile.push", Summary: "Copy files to a mobile sandbox",
+			Mutation: true, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "LocalPath", Type: "string", Required: true},
+				{Name: "RemotePath", Type: "string", Required: true},
+			},
+			Output:   "MobileFileTransfer",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_LOCAL_PATH"},
+		},
+		{
+			Name: "instance.mobile.record", Summary: "Record the screen of a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "LocalPath", Type: "string"},
+			},
+			Flags:    []FlagSchema{{Name: "duration", Type: "string", Default: "30s"}},
+			Output:   "MobileRecording",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_LOCAL_PATH", "INVALID_DURATION"},
+		},
+		{
+			Name: "instance.mobile.screenshot", Summary: "Save a screenshot of a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "LocalPath", Type: "string"},
+			},
+			Output:   "MobileScreenshot",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_LOCAL_PATH"},
+		},
+		{
+			Name: "instance.mobile.tap", Summary: "Tap the screen of a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "X", Type: "integer", Required: true},
+				{Name: "Y", Type: "integer", Required: true},
+			},
+			Output:   "MobileInputResult",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_COORDINATE"},
+		},
+		{
+			Name: "instance.mobile.swipe", Summary: "Swipe on the screen of a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "X1", Type: "integer", Required: true},
+				{Name: "Y1", Type: "integer", Required: true},
+				{Name: "X2", Type: "integer", Required: true},
+				{Name: "Y2", Type: "integer", Required: true},
+			},
+			Flags: []FlagSchema{
+				{Name: "duration", Type: "string"},
+			},
+			Output:   "MobileInputResult",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_COORDINATE", "INVALID_DURATION"},
+		},
+		{
+			Name: "instance.mobile.text", Summary: "Type text on a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "Text", Type: "string", Required: true},
+			},
+			Output:   "MobileInputResult",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_USAGE"},
+		},
+		{
+			Name: "instance.mobile.keyevent", Summary: "Send key events to a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "Key", Type: "string", Required: true, Variadic: true},
+			},
+			Output:   "MobileInputResult",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_KEYCODE"},
+		},
+		{
+			Name: "instance.mobile.install", Summary: "Install APKs on a mobile sandbox",
+			Mutation: true, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+				{Name: "Apk", Type: "string", Required: true, Variadic: true},
+			},
+			Flags: []FlagSchema{
+				{Name: "split", Type: "bool"},
+				{Name: "grant", Shorthand: "g", Type: "bool"},
+				{Name: "downgrade", Type: "bool"},
+			},
+			Output:   "MobileInstallResult",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL", "INVALID_LOCAL_PATH"},
+		},
+		{
+			Name: "instance.mobile.info", Summary: "Show device information of a mobile sandbox",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args: []ArgSchema{
+				{Name: "InstanceId", Type: "string", Required: true},
+			},
+			Flags: []FlagSchema{
+				{Name: "all-packages", Type: "bool"},
+			},
+			Output:   "MobileDeviceInfo",
+			Failures: []string{"ADB_NOT_FOUND", "NO_ACTIVE_TUNNEL"},
+		},
+		{
+			Name: "pool.create", Summary: "Create or resize a warm pool and fill it with running instances",
+			Mutation: true, CreatesResource: true,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Flags: []FlagSchema{
+				{Name: "name", Type: "string"},
+				{Name: "tool-id", Type: "string"},
+				{Name: "size", Type: "integer"},
+				{Name: "timeout", Type: "string"},
+			},
+			Output: "Pool", Failures: []string{"MISSING_REQUIRED_FLAG", "INVALID_POOL_SIZE", "INVALID_POOL_NAME", "POOL_CONFLICT", "PARTIAL_POOL_FILL"},
+		},
+		{
+			Name: "pool.status", Summary: "Show warm pools and their ready instances",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "Name", Type: "string", Required: false}},
+			Output:          "PoolList", Failures: []string{"POOL_NOT_FOUND"},
+		},
+		{
+			Name: "pool.drain", Summary: "Remove a warm pool and delete its ready instances",
+			Mutation: true, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "Name", Type: "string", Required: true}},
+			Output:          "DeleteResult", Failures: []string{"POOL_NOT_FOUND", "INVALID_POOL_NAME", "PARTIAL_DELETE_FAILED"},
+		},
+		{
+			Name: "session.end", Summary: "End a session and apply its cleanup policy",
+			Mutation: true, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: true, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "Name", Type: "string", Required: true}},
+			Flags:           []FlagSchema{{Name: "cleanup", Type: "enum", Values: []string{"always", "success", "never"}}},
+			Output:          "SessionEnd", Failures: []string{"SESSION_NOT_FOUND", "INVALID_SESSION_NAME", "INVALID_CLEANUP", "MISSING_REQUIRED_ARG"},
+		},
+		{
+			Name: "session.list", Summary: "List sessions and their temporary instances",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Output:          "SessionList",
+		},
 		{
 			Name: "tool.create", Summary: "Create a new sandbox tool",
 			Mutation: true, CreatesResource: true,
@@ -1279,6 +1678,49 @@ func buildHandwrittenSchemas() []CommandSchema {
 			Args:            []ArgSchema{{Name: "ToolId", Type: "string", Required: true, Variadic: true}},
 			Failures:        []string{"MISSING_REQUIRED_ARG", "REQUEST_FLAG_CONFLICT"},
 		},
+		{
+			Name: "tunnel.list", Summary: "List background proxies and ADB tunnels",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Output:          "TunnelList",
+		},
+		{
+			Name: "tunnel.logs", Summary: "Print the log of a background proxy or ADB tunnel",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: true,
+			RequiresAuth: false, SupportsJson: false, SupportsNdjson: false, SupportsJq: false,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "TunnelId", Type: "string", Required: true}},
+			Flags: []FlagSchema{
+				{Name: "tail", Type: "integer"},
+				{Name: "follow", Shorthand: "f", Type: "bool"},
+			},
+			Failures: []string{"TUNNEL_NOT_FOUND", "AMBIGUOUS_TUNNEL", "INVALID_TAIL"},
+		},
+		{
+			Name: "tunnel.stop", Summary: "Stop background proxies and ADB tunnels",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: false,
+			RequiresAuth: false, SupportsJson: true, SupportsNdjson: false, SupportsJq: true,
+			SupportsRequest: false,
+			Args:            []ArgSchema{{Name: "TunnelId", Type: "string", Variadic: true}},
+			Flags:           []FlagSchema{{Name: "all", Type: "bool"}},
+			Output:          "TunnelStopResult", Failures: []string{"TUNNEL_NOT_FOUND", "CONFLICTING_FLAGS", "MISSING_REQUIRED_ARG", "PARTIAL_STOP_FAILED"},
+		},
+		{
+			Name: "gateway", Summary: "Serve every instance port behind one local address",
+			Mutation: false, CreatesResource: false,
+			Idempotency: "none", SupportsDryRun: false, Interactive: true,
+			RequiresAuth: true, SupportsJson: false, SupportsNdjson: false, SupportsJq: false,
+			SupportsRequest: false,
+			Flags: []FlagSchema{
+				{Name: "address", Type: "string"},
+				{Name: "verbose", Type: "bool"},
+			},
+			Failures: []string{"INVALID_ADDRESS", "JSON_REQUIRES_BACKGROUND"},
+		},
 		{
 			Name: "apikey.create", Summary: "Create a new API key",
 			Mutation: true, CreatesResource: true,
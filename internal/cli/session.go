@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	workflow "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/overlay"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/session"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// newSessionStore opens the session registry; tests replace it with a
+// temp-dir store.
+var newSessionStore = func() (*session.Store, error) { return session.NewStore() }
+
+// NewSessionStore opens the session registry at ~/.agr/sessions.json.
+func NewSessionStore() (*session.Store, error) {
+	return newSessionStore()
+}
+
+// SessionNotFoundError returns the structured error for an unknown session.
+func SessionNotFoundError(name string) error {
+	return output.NewNotFoundError("SESSION_NOT_FOUND",
+		fmt.Sprintf("session %q not found", name),
+		"List sessions with: agr session list")
+}
+
+// SetSessionHooksForTest replaces the session store and instance liveness
+// check used by --session, and returns a restore function.
+func SetSessionHooksForTest(store *session.Store, running func(context.Context, string) (bool, error)) func() {
+	prevStore, prevRunning := newSessionStore, instanceRunning
+	newSessionStore = func() (*session.Store, error) { return store, nil }
+	instanceRunning = running
+	return func() {
+		newSessionStore, instanceRunning = prevStore, prevRunning
+	}
+}
+
+// overlaySession resolves --session (or AGR_SESSION) into the session binding
+// used by the overlay. It returns nil when no session applies.
+func overlaySession(flags *OverlayFlags, apiDelete func(ctx context.Context, instanceID string) error) (workflow.Session, error) {
+	if flags.Session == "" && flags.CreateTempInstance {
+		flags.Session = os.Getenv(session.EnvName)
+	}
+	if flags.Session == "" {
+		return nil, nil
+	}
+	if err := session.ValidateName(flags.Session); err != nil {
+		return nil, err
+	}
+	ttl, err := session.ParseIdleTTL(flags.SessionTTL)
+	if err != nil {
+		return nil, err
+	}
+	policy := flags.Cleanup
+	if policy == "" {
+		policy = string(CleanupAlways)
+	}
+	store, err := newSessionStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+	return &storeSession{store: store, flags: *flags, policy: policy, ttl: ttl, apiDelete: apiDelete}, nil
+}
+
+// storeSession implements workflow.Session on top of the local session store.
+type storeSession struct {
+	store     *session.Store
+	flags     OverlayFlags
+	policy    string
+	ttl       time.Duration
+	apiDelete func(ctx context.Context, instanceID string) error
+}
+
+func (s *storeSession) Acquire(ctx context.Context, create func(ctx context.Context) (string, error)) (string, bool, error) {
+	name := s.flags.Session
+	now := time.Now()
+	notes, err := SweepExpiredSessions(ctx, s.store, now, s.apiDelete)
+	if err != nil {
+		return "", false, err
+	}
+	for _, note := range notes {
+		stderr("%s\n", note)
+	}
+	existing, ok, err := s.store.Get(name)
+	if err != nil {
+		return "", false, err
+	}
+	if ok {
+		if s.toolMismatch(existing) {
+			return "", false, output.NewConflictError("SESSION_CONFLICT",
+				fmt.Sprintf("session %q already uses instance %s of a different tool", name, existing.InstanceID),
+				"End it first with: agr session end "+name+", or choose another --session.")
+		}
+		if running, err := instanceRunning(ctx, existing.InstanceID); err == nil && !running {
+			// A failed liveness check should not drop a possibly healthy
+			// instance; only a confirmed non-running instance is replaced.
+			stderr("Session %s instance %s is no longer running; starting a new one\n", name, existing.InstanceID)
+			_, _, _ = s.store.RemoveInstance(name, existing.InstanceID)
+		} else {
+			if err := s.store.Touch(name, existing.InstanceID, now); err != nil {
+				stderr("Warning: failed to update session %s: %v\n", name, err)
+			}
+			return existing.InstanceID, true, nil
+		}
+	}
+
+	id, err := create(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	stored, created, err := s.store.Create(session.Session{
+		Name:       name,
+		InstanceID: id,
+		ToolName:   s.flags.ToolName,
+		ToolID:     s.flags.ToolID,
+		Pool:       s.flags.Pool,
+		Cleanup:    s.policy,
+		IdleTTL:    s.ttl.String(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+	})
+	if err != nil {
+		_ = s.apiDelete(ctx, id)
+		return "", false, fmt.Errorf("failed to record session %s: %w", name, err)
+	}
+	if !created {
+		// Another invocation started the session concurrently; use its
+		// instance and discard ours.
+		if err := s.apiDelete(ctx, id); err != nil {
+			stderr("Warning: failed to delete duplicate session instance %s: %v\n", id, err)
+		}
+		return stored.InstanceID, true, nil
+	}
+	stderr("Session %s started with temporary sandbox %s (end it with: agr session end %s)\n", name, id, name)
+	return id, false, nil
+}
+
+func (s *storeSession) Release(instanceID string, success bool) {
+	if err := s.store.Record(s.flags.Session, instanceID, time.Now(), success); err != nil {
+		stderr("Warning: failed to update session %s: %v\n", s.flags.Session, err)
+	}
+}
+
+// toolMismatch reports whether this invocation asks for a different tool than
+// the one the session was started with.
+func (s *storeSession) toolMismatch(existing session.Session) bool {
+	f := s.flags
+	if f.ToolName == "" && f.ToolID == "" && f.Pool == "" {
+		return false
+	}
+	return f.ToolName != existing.ToolName || f.ToolID != existing.ToolID || f.Pool != existing.Pool
+}
+
+// SweepExpiredSessions ends every session that has been idle longer than its
+// TTL and applies its cleanup policy once. Deletion uses del. It returns one
+// note per ended session for the caller to show as a warning.
+func SweepExpiredSessions(ctx context.Context, store *session.Store, now time.Time, del func(ctx context.Context, instanceID string) error) ([]string, error) {
+	all, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(all))
+	for name, sess := range all {
+		if sess.Expired(now) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var notes []string
+	for _, name := range names {
+		sess := all[name]
+		// Removing first claims the session, so concurrent sweeps apply the
+		// cleanup policy only once.
+		if _, removed, err := store.RemoveInstance(name, sess.InstanceID); err != nil || !removed {
+			continue
+		}
+		result := session.End(ctx, sess, CleanupPolicy(sess.Cleanup), del)
+		prefix := fmt.Sprintf("Session %s was idle for more than %s and was ended", name, sess.IdleTTL)
+		switch result.Status {
+		case "deleted":
+			notes = append(notes, fmt.Sprintf("%s; temporary sandbox %s deleted (--cleanup %s)", prefix, sess.InstanceID, result.Policy))
+		case "failed":
+			notes = append(notes, fmt.Sprintf("%s; failed to delete temporary sandbox %s: %s", prefix, sess.InstanceID, result.Reason))
+		default:
+			notes = append(notes, fmt.Sprintf("%s; temporary sandbox %s kept (%s)", prefix, sess.InstanceID, result.Reason))
+		}
+	}
+	return notes, nil
+}
@@ -0,0 +1,258 @@
+// Package session records temporary sandbox instances that are reused across
+// overlay invocations sharing a session name.
+//
+// Session state lives in ~/.agr/sessions.json. Cross-process safety is
+// ensured via flock file locking and atomic writes, so concurrent invocations
+// of the same session agree on a single instance.
+package session
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/overlay"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/jsonfile"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const (
+	// StoreDir is the directory name under user home for storing session state.
+	StoreDir = ".agr"
+	// StoreFile is the filename for the session registry.
+	StoreFile = "sessions.json"
+	// StoreVersion is the current version of the session file format.
+	StoreVersion = 1
+	// EnvName is the environment variable that supplies a default --session.
+	EnvName = "AGR_SESSION"
+	// DefaultIdleTTL is how long a session may stay unused before its instance
+	// is cleaned up and replaced on the next invocation.
+	DefaultIdleTTL = 30 * time.Minute
+)
+
+// Session is one named binding to a temporary instance.
+type Session struct {
+	Name       string    `json:"name"`
+	InstanceID string    `json:"instance_id"`
+	ToolName   string    `json:"tool_name,omitempty"`
+	ToolID     string    `json:"tool_id,omitempty"`
+	Pool       string    `json:"pool,omitempty"`
+	Cleanup    string    `json:"cleanup"`
+	IdleTTL    string    `json:"idle_ttl"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Executions int       `json:"executions"`
+	Failures   int       `json:"failures"`
+}
+
+// Expired reports whether the session has been idle longer than its TTL.
+func (s Session) Expired(now time.Time) bool {
+	ttl, err := time.ParseDuration(s.IdleTTL)
+	if err != nil || ttl <= 0 {
+		return false
+	}
+	return now.Sub(s.LastUsedAt) > ttl
+}
+
+// StoreData represents the structure of the session file.
+type StoreData struct {
+	Version  int                 `json:"version"`
+	Sessions map[string]*Session `json:"sessions"`
+}
+
+// Store manages the session registry file with cross-process locking.
+type Store struct {
+	file *jsonfile.File // sessions.json, locked through sessions.json.lock
+}
+
+// NewStore creates a Store. The registry is stored at ~/.agr/sessions.json.
+func NewStore() (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	dir := filepath.Join(homeDir, StoreDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return NewStoreAt(filepath.Join(dir, StoreFile)), nil
+}
+
+// NewStoreAt creates a Store backed by the given file path.
+func NewStoreAt(path string) *Store {
+	return &Store{file: jsonfile.New(path, "session store")}
+}
+
+// Get returns the named session.
+func (s *Store) Get(name string) (Session, bool, error) {
+	var out Session
+	var found bool
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		if sess, ok := data.Sessions[name]; ok && sess != nil {
+			out, found = *sess, true
+		}
+		return false, nil
+	})
+	return out, found, err
+}
+
+// List returns all sessions keyed by name.
+func (s *Store) List() (map[string]Session, error) {
+	out := map[string]Session{}
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		for name, sess := range data.Sessions {
+			if sess != nil {
+				out[name] = *sess
+			}
+		}
+		return false, nil
+	})
+	return out, err
+}
+
+// Create stores sess unless the session already exists. It returns the stored
+// session and whether sess was the one stored, so a caller that lost a race
+// can switch to the winner's instance.
+func (s *Store) Create(sess Session) (Session, bool, error) {
+	var out Session
+	var created bool
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		if existing, ok := data.Sessions[sess.Name]; ok && existing != nil {
+			out = *existing
+			return false, nil
+		}
+		stored := sess
+		data.Sessions[sess.Name] = &stored
+		out, created = stored, true
+		return true, nil
+	})
+	return out, created, err
+}
+
+// Touch marks the session as used at now, keeping it alive for another TTL.
+func (s *Store) Touch(name, instanceID string, now time.Time) error {
+	return s.update(name, instanceID, func(sess *Session) { sess.LastUsedAt = now })
+}
+
+// Record counts one execution against the session.
+func (s *Store) Record(name, instanceID string, now time.Time, success bool) error {
+	return s.update(name, instanceID, func(sess *Session) {
+		sess.LastUsedAt = now
+		sess.Executions++
+		if !success {
+			sess.Failures++
+		}
+	})
+}
+
+// Remove deletes the named session and returns it.
+func (s *Store) Remove(name string) (Session, bool, error) {
+	return s.RemoveInstance(name, "")
+}
+
+// RemoveInstance deletes the named session only while it is still bound to
+// instanceID. An empty instanceID removes the session unconditionally.
+func (s *Store) RemoveInstance(name, instanceID string) (Session, bool, error) {
+	var out Session
+	var removed bool
+	err := s.withLock(func(data *StoreData) (bool, error) {
+		sess, ok := data.Sessions[name]
+		if !ok || sess == nil || (instanceID != "" && sess.InstanceID != instanceID) {
+			return false, nil
+		}
+		out, removed = *sess, true
+		delete(data.Sessions, name)
+		return true, nil
+	})
+	return out, removed, err
+}
+
+func (s *Store) update(name, instanceID string, fn func(*Session)) error {
+	return s.withLock(func(data *StoreData) (bool, error) {
+		sess, ok := data.Sessions[name]
+		if !ok || sess == nil || sess.InstanceID != instanceID {
+			return false, nil
+		}
+		fn(sess)
+		return true, nil
+	})
+}
+
+// ValidateName rejects session names that are unsafe to show in hints and
+// pass between scripts.
+func ValidateName(name string) error {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return output.NewUsageError("INVALID_SESSION_NAME",
+				fmt.Sprintf("invalid session name %q", name),
+				"Use letters, digits, '.', '-' or '_' only.")
+		}
+	}
+	return nil
+}
+
+// ParseIdleTTL validates a --session-ttl value. Empty means DefaultIdleTTL.
+func ParseIdleTTL(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultIdleTTL, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, output.NewUsageError("INVALID_SESSION_TTL",
+			fmt.Sprintf("invalid --session-ttl value: %q", value),
+			"Use a positive Go duration such as 30m or 2h.")
+	}
+	return ttl, nil
+}
+
+// End applies policy to the session's instance exactly once. Deletion uses
+// del; the returned result mirrors the per-execution cleanup result of a
+// non-session overlay run.
+func End(ctx context.Context, sess Session, policy overlay.CleanupPolicy, del func(ctx context.Context, instanceID string) error) *overlay.ExecutionCleanupResult {
+	result := &overlay.ExecutionCleanupResult{Policy: string(policy), Status: "skipped"}
+	switch policy {
+	case overlay.CleanupNever:
+		result.Reason = "policy=never"
+		return result
+	case overlay.CleanupSuccess:
+		if sess.Failures > 0 {
+			result.Reason = fmt.Sprintf("policy=success and %d of %d executions did not succeed", sess.Failures, sess.Executions)
+			return result
+		}
+	}
+	if err := del(ctx, sess.InstanceID); err != nil {
+		result.Status = "failed"
+		result.Reason = err.Error()
+		return result
+	}
+	result.Status = "deleted"
+	return result
+}
+
+// withLock acquires the file lock, loads the store, runs fn, and saves the
+// store when fn reports a change.
+func (s *Store) withLock(fn func(*StoreData) (bool, error)) error {
+	unlock, err := s.file.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data := &StoreData{Version: StoreVersion}
+	if _, err := s.file.Load(data); err != nil {
+		return err
+	}
+	if data.Version < StoreVersion {
+		data.Version = StoreVersion
+	}
+	if data.Sessions == nil {
+		data.Sessions = map[string]*Session{}
+	}
+	changed, err := fn(data)
+	if err != nil || !changed {
+		return err
+	}
+	return s.file.Save(data)
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/overlay"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStoreAt(filepath.Join(t.TempDir(), "sessions.json"))
+}
+
+func TestStoreCreateRecordAndRemove(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if _, created, err := store.Create(Session{Name: "build", InstanceID: "ins-1", IdleTTL: "30m", LastUsedAt: now}); err != nil || !created {
+		t.Fatalf("create created=%v err=%v", created, err)
+	}
+	stored, created, err := store.Create(Session{Name: "build", InstanceID: "ins-2"})
+	if err != nil || created || stored.InstanceID != "ins-1" {
+		t.Fatalf("second create stored=%+v created=%v err=%v", stored, created, err)
+	}
+
+	if err := store.Record("build", "ins-1", now.Add(time.Minute), false); err != nil {
+		t.Fatal(err)
+	}
+	// Records for a replaced instance must not touch the current session.
+	if err := store.Record("build", "ins-old", now.Add(time.Hour), true); err != nil {
+		t.Fatal(err)
+	}
+	sess, ok, err := store.Get("build")
+	if err != nil || !ok || sess.Executions != 1 || sess.Failures != 1 || !sess.LastUsedAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("session=%+v ok=%v err=%v", sess, ok, err)
+	}
+	if sess.Expired(now.Add(30*time.Minute)) || !sess.Expired(now.Add(32*time.Minute)) {
+		t.Fatalf("unexpected expiry for %+v", sess)
+	}
+
+	if _, removed, _ := store.RemoveInstance("build", "ins-other"); removed {
+		t.Fatal("RemoveInstance removed a session bound to another instance")
+	}
+	if _, removed, _ := store.Remove("build"); !removed {
+		t.Fatal("Remove did not remove the session")
+	}
+}
+
+func TestEndAppliesCleanupPolicyOnce(t *testing.T) {
+	var deleted []string
+	del := func(_ context.Context, id string) error {
+		deleted = append(deleted, id)
+		return nil
+	}
+	ok := Session{InstanceID: "ins-ok", Executions: 2}
+	failed := Session{InstanceID: "ins-failed", Executions: 2, Failures: 1}
+
+	if r := End(context.Background(), failed, overlay.CleanupSuccess, del); r.Status != "skipped" {
+		t.Fatalf("success policy with failures: %+v", r)
+	}
+	if r := End(context.Background(), ok, overlay.CleanupNever, del); r.Status != "skipped" || r.Reason != "policy=never" {
+		t.Fatalf("never policy: %+v", r)
+	}
+	if r := End(context.Background(), ok, overlay.CleanupSuccess, del); r.Status != "deleted" {
+		t.Fatalf("success policy: %+v", r)
+	}
+	if len(deleted) != 1 || deleted[0] != "ins-ok" {
+		t.Fatalf("deleted=%v", deleted)
+	}
+	boom := func(context.Context, string) error { return errors.New("boom") }
+	if r := End(context.Background(), ok, overlay.CleanupAlways, boom); r.Status != "failed" || r.Reason != "boom" {
+		t.Fatalf("failed delete: %+v", r)
+	}
+}
+
+func TestParseIdleTTL(t *testing.T) {
+	if ttl, err := ParseIdleTTL(""); err != nil || ttl != DefaultIdleTTL {
+		t.Fatalf("default ttl=%v err=%v", ttl, err)
+	}
+	for _, value := range []string{"0s", "-1m", "soon"} {
+		if _, err := ParseIdleTTL(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os/signal"
+	"syscall"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	dataplanegateway "github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/gateway"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Server is the local gateway process managed by the command.
+type Server interface {
+	Start() (string, error)
+	Stop()
+}
+
+// RuntimeDeps contains token, gateway construction and wait hooks that tests
+// can replace without opening real network listeners.
+type RuntimeDeps struct {
+	AcquireToken    func(ctx context.Context, instanceID string) (string, error)
+	InvalidateToken func(instanceID string)
+	NewGateway      func(dataplanegateway.Options) (Server, error)
+	Wait            func(context.Context)
+}
+
+// Module returns the "gateway" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "gateway",
+		Path:  []string{"gateway"},
+		Use:   "gateway",
+		Short: "Serve every instance port behind one local address",
+		Long: `Serve the ports of all your instances behind one local HTTP address.
+
+Requests are routed by host name or by path:
+  http://<port>-<instance>.localhost:8000/        Host routing
+  http://localhost:8000/i/<instance>/<port>/...   Path routing
+
+Browsers resolve *.localhost to the loopback address, so host routing needs no
+DNS setup and keeps absolute links working. Path routing removes the
+/i/<instance>/<port> prefix before forwarding and passes it upstream as
+X-Forwarded-Prefix.
+
+Access tokens are acquired on the first request for each instance, cached in
+the token cache and refreshed when the sandbox gateway rejects them. WebSocket
+upgrades are supported.
+
+Examples:
+  agr gateway
+  agr gateway --address 127.0.0.1:9000
+  curl http://3000-ins-xxxx.localhost:8000/`,
+		Flags: []command.FlagSpec{
+			{Name: "address", Usage: "Local host:port to listen on", Type: command.FlagString, Default: dataplanegateway.DefaultListenAddress},
+			{Name: "verbose", Usage: "Enable verbose request logging", Type: command.FlagBool},
+		},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := runtimeDeps(deps.DataPlane)
+			return command.Runtime{
+				Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+					return runGateway(ctx, req, deps, rt)
+				}),
+			}, nil
+		},
+	}
+}
+
+func runtimeDeps(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	if rt.AcquireToken == nil {
+		rt.AcquireToken = cli.AcquireInstanceToken
+	}
+	if rt.InvalidateToken == nil {
+		rt.InvalidateToken = cli.InvalidateInstanceToken
+	}
+	if rt.NewGateway == nil {
+		rt.NewGateway = func(opts dataplanegateway.Options) (Server, error) {
+			return dataplanegateway.New(opts)
+		}
+	}
+	if rt.Wait == nil {
+		rt.Wait = waitForSignal
+	}
+	return rt
+}
+
+func runGateway(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	if cli.IsJSONOutput() {
+		return nil, output.NewUsageError("JSON_REQUIRES_BACKGROUND",
+			"agr gateway runs in the foreground and does not support -o json",
+			"Run it without -o json.")
+	}
+	address := dataplanegateway.DefaultListenAddress
+	if flag, ok := req.Flags["address"]; ok && flag.String != "" {
+		address = flag.String
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, output.NewUsageError("INVALID_ADDRESS", fmt.Sprintf("invalid --address %q: %v", address, err), "Use host:port, for example 127.0.0.1:8000.")
+	}
+	if err := cli.ValidateListenAddress(host); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if host != "127.0.0.1" && host != "localhost" && host != "::1" {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: binding to %s exposes every instance (and their access tokens) to the network.\n", host)
+	}
+	domain := config.Get().DataPlaneRegionDomain()
+	server, err := rt.NewGateway(dataplanegateway.Options{
+		Domain:        domain,
+		ListenAddress: address,
+		Logger:        log.New(deps.IO.ErrOut, "", log.LstdFlags),
+		Verbose:       req.Flags["verbose"].Bool,
+		TokenProvider: func(instanceID string) (string, error) {
+			return rt.AcquireToken(ctx, instanceID)
+		},
+		InvalidateToken: rt.InvalidateToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gateway: %w", err)
+	}
+	addr, err := server.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start gateway: %w", err)
+	}
+	if _, bound, err := net.SplitHostPort(addr); err == nil {
+		port = bound
+	}
+
+	fmt.Fprintf(deps.IO.Out, "Gateway listening on %s\n", addr)
+	fmt.Fprintf(deps.IO.Out, "  Host:   http://<port>-<instance>.localhost:%s/\n", port)
+	fmt.Fprintf(deps.IO.Out, "  Path:   http://%s/i/<instance>/<port>/\n", addr)
+	fmt.Fprintf(deps.IO.Out, "  Remote: https://<port>-<instance>.%s\n", domain)
+	fmt.Fprintln(deps.IO.Out, "\nPress Ctrl+C to stop.")
+
+	rt.Wait(ctx)
+
+	fmt.Fprintln(deps.IO.Out, "\nStopping gateway...")
+	server.Stop()
+	return &command.Result{StreamDone: true}, nil
+}
+
+func waitForSignal(ctx context.Context) {
+	waitCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-waitCtx.Done()
+}
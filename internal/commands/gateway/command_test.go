@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	dataplanegateway "github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/gateway"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+)
+
+func TestModuleDescriptor(t *testing.T) {
+	module := Module()
+	if module.Descriptor.Spec.ID != "gateway" || strings.Join(module.Descriptor.Spec.Path, " ") != "gateway" {
+		t.Fatalf("spec=%#v", module.Descriptor.Spec)
+	}
+}
+
+func TestRunGateway(t *testing.T) {
+	setupConfig(t)
+	fake := &fakeServer{addr: "127.0.0.1:9000"}
+	var opts dataplanegateway.Options
+	var invalidated []string
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(_ context.Context, instanceID string) (string, error) {
+				return "token-" + instanceID, nil
+			},
+			InvalidateToken: func(instanceID string) { invalidated = append(invalidated, instanceID) },
+			NewGateway: func(o dataplanegateway.Options) (Server, error) {
+				opts = o
+				return fake, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Flags: map[string]command.FlagValue{
+			"address": {Name: "address", Type: command.FlagString, String: "127.0.0.1:9000"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone || !fake.started || !fake.stopped {
+		t.Fatalf("result=%#v fake=%#v", result, fake)
+	}
+	if opts.ListenAddress != "127.0.0.1:9000" || opts.Domain == "" {
+		t.Fatalf("opts=%#v", opts)
+	}
+	if token, err := opts.TokenProvider("ins-a"); err != nil || token != "token-ins-a" {
+		t.Fatalf("token=%q err=%v", token, err)
+	}
+	opts.InvalidateToken("ins-a")
+	if len(invalidated) != 1 || invalidated[0] != "ins-a" {
+		t.Fatalf("invalidated=%v", invalidated)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "http://<port>-<instance>.localhost:9000/") || !strings.Contains(out, "http://127.0.0.1:9000/i/<instance>/<port>/") {
+		t.Fatalf("stdout=%q", out)
+	}
+
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Flags: map[string]command.FlagValue{
+			"address": {Name: "address", Type: command.FlagString, String: "8000"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid --address") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+type fakeServer struct {
+	addr    string
+	started bool
+	stopped bool
+}
+
+func (f *fakeServer) Start() (string, error) {
+	f.started = true
+	return f.addr, nil
+}
+
+func (f *fakeServer) Stop() { f.stopped = true }
+
+func setupConfig(t *testing.T) {
+	t.Helper()
+	if err := config.Init(); err != nil {
+		t.Fatalf("config.Init: %v", err)
+	}
+	config.SetSecretID("AKIDfake")
+	config.SetSecretKey("fakeSecretKey")
+	config.SetRegion("ap-guangzhou")
+}
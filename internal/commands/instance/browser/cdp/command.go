@@ -0,0 +1,190 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os/signal"
+	"syscall"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	dataplanecdp "github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/cdp"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Server is the local CDP endpoint managed by the command.
+type Server interface {
+	Start() (string, error)
+	Stop()
+}
+
+// RuntimeDeps contains token, endpoint construction and wait hooks that tests
+// can replace without opening real network listeners.
+type RuntimeDeps struct {
+	AcquireToken    func(ctx context.Context, instanceID string) (string, error)
+	InvalidateToken func(instanceID string)
+	NewServer       func(dataplanecdp.Options) (Server, error)
+	Wait            func(context.Context)
+}
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.browser.cdp",
+		Path:  []string{"instance", "browser", "cdp"},
+		Use:   "cdp <instance-id>",
+		Short: "Serve a local CDP endpoint for a browser instance",
+		Long: `Serve a local Chrome DevTools Protocol endpoint for a browser sandbox.
+
+The endpoint answers /json/version and /json/list with WebSocket URLs that
+point at the local address and forwards the DevTools WebSockets to the sandbox
+with the access token injected, so CDP clients never see the token:
+
+  Playwright: chromium.connectOverCDP("http://127.0.0.1:9222")
+  Puppeteer:  puppeteer.connect({ browserURL: "http://127.0.0.1:9222" })
+
+Like Chrome's --remote-allow-origins, the endpoint only answers requests
+addressed to localhost or an IP address and refuses DevTools WebSockets opened
+by web pages. Pass --allow-origin for a web-based DevTools client.
+
+Examples:
+  agr instance browser cdp ins-xxxx
+  agr instance browser cdp ins-xxxx --address 127.0.0.1:9333`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+		},
+		Flags: []command.FlagSpec{
+			{Name: "address", Usage: "Local host:port to listen on", Type: command.FlagString, Default: dataplanecdp.DefaultListenAddress},
+			{Name: "port", Shorthand: "p", Usage: "Browser service port in the sandbox", Type: command.FlagInt, Default: dataplanecdp.DefaultRemotePort},
+			{Name: "verbose", Usage: "Enable verbose request logging", Type: command.FlagBool},
+			{Name: "allow-origin", Usage: "Let web pages from this origin open DevTools WebSockets, or * for any (repeatable)", Type: command.FlagStringArray},
+		},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec: spec,
+			Groups: []command.GroupSpec{
+				{
+					Path:    []string{"instance"},
+					Use:     "instance",
+					Short:   "Manage sandbox instances",
+					Long:    "Manage sandbox instances and related data-plane workflows.",
+					Aliases: []string{"i"},
+				},
+				{Path: []string{"instance", "browser"}, Use: "browser", Short: "Browser sandbox commands"},
+			},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := runtimeDeps(deps.DataPlane)
+			return command.Runtime{
+				Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+					return runCDP(ctx, req, deps, rt)
+				}),
+			}, nil
+		},
+	}
+}
+
+func runtimeDeps(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	if rt.AcquireToken == nil {
+		rt.AcquireToken = cli.AcquireInstanceToken
+	}
+	if rt.InvalidateToken == nil {
+		rt.InvalidateToken = cli.InvalidateInstanceToken
+	}
+	if rt.NewServer == nil {
+		rt.NewServer = func(opts dataplanecdp.Options) (Server, error) {
+			return dataplanecdp.New(opts)
+		}
+	}
+	if rt.Wait == nil {
+		rt.Wait = waitForSignal
+	}
+	return rt
+}
+
+func runCDP(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := req.ArgValues["instance-id"]
+	if instanceID == "" && len(req.Args) > 0 {
+		instanceID = req.Args[0]
+	}
+	if cli.IsJSONOutput() {
+		return nil, output.NewUsageError("JSON_REQUIRES_BACKGROUND",
+			"agr instance browser cdp runs in the foreground and does not support -o json",
+			"Run it without -o json.")
+	}
+	address := dataplanecdp.DefaultListenAddress
+	if flag, ok := req.Flags["address"]; ok && flag.String != "" {
+		address = flag.String
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, output.NewUsageError("INVALID_ADDRESS", fmt.Sprintf("invalid --address %q: %v", address, err), "Use host:port, for example 127.0.0.1:9222.")
+	}
+	if err := cli.ValidateListenAddress(host); err != nil {
+		return nil, err
+	}
+	port := req.Flags["port"].Int
+	if port == 0 {
+		port = dataplanecdp.DefaultRemotePort
+	}
+	if port < 0 || port > 65535 {
+		return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid port: %d", port), "Provide a port between 1 and 65535.")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if host != "127.0.0.1" && host != "localhost" && host != "::1" {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: binding to %s gives anyone on the network full control of the browser.\n", host)
+	}
+	token, err := rt.AcquireToken(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire access token: %w", err)
+	}
+	server, err := rt.NewServer(dataplanecdp.Options{
+		InstanceID:     instanceID,
+		Domain:         config.Get().DataPlaneRegionDomain(),
+		RemotePort:     port,
+		ListenAddress:  address,
+		Logger:         log.New(deps.IO.ErrOut, "", log.LstdFlags),
+		Verbose:        req.Flags["verbose"].Bool,
+		AllowedOrigins: req.Flags["allow-origin"].Strings,
+		Token:          token,
+		TokenProvider: func() (string, error) {
+			return rt.AcquireToken(ctx, instanceID)
+		},
+		InvalidateToken: func() { rt.InvalidateToken(instanceID) },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CDP endpoint: %w", err)
+	}
+	addr, err := server.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start CDP endpoint: %w", err)
+	}
+
+	endpoint := "http://" + addr
+	fmt.Fprintf(deps.IO.Out, "CDP endpoint for %s listening on %s\n", instanceID, endpoint)
+	fmt.Fprintf(deps.IO.Out, "  Version:    %s/json/version\n", endpoint)
+	fmt.Fprintf(deps.IO.Out, "  Playwright: chromium.connectOverCDP(%q)\n", endpoint)
+	fmt.Fprintf(deps.IO.Out, "  Puppeteer:  puppeteer.connect({ browserURL: %q })\n", endpoint)
+	fmt.Fprintln(deps.IO.Out, "\nPress Ctrl+C to stop.")
+
+	rt.Wait(ctx)
+
+	fmt.Fprintln(deps.IO.Out, "\nStopping CDP endpoint...")
+	server.Stop()
+	return &command.Result{StreamDone: true}, nil
+}
+
+func waitForSignal(ctx context.Context) {
+	waitCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-waitCtx.Done()
+}
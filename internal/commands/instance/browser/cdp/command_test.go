@@ -0,0 +1,99 @@
+package cdp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	dataplanecdp "github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/cdp"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+)
+
+func TestModuleDescriptor(t *testing.T) {
+	module := Module()
+	if module.Descriptor.Spec.ID != "instance.browser.cdp" || strings.Join(module.Descriptor.Spec.Path, " ") != "instance browser cdp" {
+		t.Fatalf("spec=%#v", module.Descriptor.Spec)
+	}
+}
+
+func TestRunCDP(t *testing.T) {
+	setupConfig(t)
+	fake := &fakeServer{addr: "127.0.0.1:9222"}
+	var opts dataplanecdp.Options
+	var invalidated []string
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(_ context.Context, instanceID string) (string, error) {
+				return "token-" + instanceID, nil
+			},
+			InvalidateToken: func(instanceID string) { invalidated = append(invalidated, instanceID) },
+			NewServer: func(o dataplanecdp.Options) (Server, error) {
+				opts = o
+				return fake, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone || !fake.started || !fake.stopped {
+		t.Fatalf("result=%#v fake=%#v", result, fake)
+	}
+	if opts.InstanceID != "ins-1" || opts.ListenAddress != dataplanecdp.DefaultListenAddress || opts.RemotePort != 9000 || opts.Token != "token-ins-1" {
+		t.Fatalf("opts=%#v", opts)
+	}
+	opts.InvalidateToken()
+	if len(invalidated) != 1 || invalidated[0] != "ins-1" {
+		t.Fatalf("invalidated=%v", invalidated)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, `chromium.connectOverCDP("http://127.0.0.1:9222")`) || strings.Contains(out, "token-ins-1") {
+		t.Fatalf("stdout=%q", out)
+	}
+
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags: map[string]command.FlagValue{
+			"address": {Name: "address", Type: command.FlagString, String: "9222"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid --address") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+type fakeServer struct {
+	addr    string
+	started bool
+	stopped bool
+}
+
+func (f *fakeServer) Start() (string, error) {
+	f.started = true
+	return f.addr, nil
+}
+
+func (f *fakeServer) Stop() { f.stopped = true }
+
+func setupConfig(t *testing.T) {
+	t.Helper()
+	if err := config.Init(); err != nil {
+		t.Fatalf("config.Init: %v", err)
+	}
+	config.SetSecretID("AKIDfake")
+	config.SetSecretKey("fakeSecretKey")
+	config.SetRegion("ap-guangzhou")
+}
@@ -0,0 +1,111 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/cdp"
+)
+
+// RuntimeDeps contains the token and browser connection hooks.
+type RuntimeDeps = cdpsession.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	flags := append(cdpsession.Flags(),
+		command.FlagSpec{Name: "url", Usage: "Load this URL before evaluating", Type: command.FlagString},
+	)
+	spec := command.Spec{
+		ID:    "instance.browser.eval",
+		Path:  []string{"instance", "browser", "eval"},
+		Use:   "eval <instance-id> <expression>",
+		Short: "Evaluate JavaScript in a browser instance",
+		Long: `Evaluate a JavaScript expression in the first tab of a browser sandbox.
+
+Promises are awaited and the value is returned as JSON. Values that cannot be
+serialized, such as DOM nodes, are returned by their description. An uncaught
+exception fails the command.
+
+Examples:
+  agr instance browser eval ins-xxxx 'document.title'
+  agr instance browser eval ins-xxxx 'document.querySelectorAll("a").length' --url https://example.com
+  agr instance browser eval ins-xxxx 'fetch("/api/health").then(r => r.status)' -o json --jq '.Value'`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "expression", Required: true},
+		},
+		Flags:        flags,
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "BrowserEvalResult"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: cdpsession.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := cdpsession.Defaults(deps.DataPlane)
+			return command.Runtime{
+				Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+					return runEval(ctx, req, rt)
+				}),
+			}, nil
+		},
+	}
+}
+
+func runEval(ctx context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	action, err := cdpsession.ParseAction(req)
+	if err != nil {
+		return nil, err
+	}
+	expression := req.ArgValues["expression"]
+	if expression == "" && len(req.Args) > 1 {
+		expression = req.Args[1]
+	}
+	pageURL := req.Flags["url"].String
+	if pageURL != "" {
+		if err := cdpsession.ValidateURL("--url", pageURL); err != nil {
+			return nil, err
+		}
+	}
+	var result cdp.EvalResult
+	report, err := cdpsession.Run(ctx, rt, action, pageURL, func(ctx context.Context, page cdpsession.Page) error {
+		var evalErr error
+		result, evalErr = page.Evaluate(ctx, expression)
+		return evalErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := report.Data()
+	data["Type"] = result.Type
+	data["Value"] = result.Value
+	if result.Description != "" {
+		data["Description"] = result.Description
+	}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		report.Print(w, instanceview.KeyValue{Key: "Type", Value: result.Type}, instanceview.KeyValue{Key: "Value", Value: formatValue(result)})
+	}}, nil
+}
+
+// formatValue renders the value as JSON, falling back to its description.
+func formatValue(result cdp.EvalResult) string {
+	if result.Value == nil {
+		if result.Description != "" {
+			return result.Description
+		}
+		return result.Type
+	}
+	encoded, err := json.Marshal(result.Value)
+	if err != nil {
+		return result.Description
+	}
+	return string(encoded)
+}
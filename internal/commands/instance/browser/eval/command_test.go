@@ -0,0 +1,54 @@
+package eval
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession/cdpsessiontest"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/cdp"
+)
+
+func TestRunEval(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	page := cdpsessiontest.NewPage()
+	page.Value = cdp.EvalResult{Type: "object", Value: map[string]any{"ok": true}}
+	req := command.Request{
+		Args:      []string{"ins-1", "({ok: true})"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "expression": "({ok: true})"},
+	}
+	result, err := cdpsessiontest.Run(t, Module(), cdpsessiontest.RuntimeDeps(page), req)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	data := result.Data.(map[string]any)
+	if data["Type"] != "object" || data["Value"].(map[string]any)["ok"] != true {
+		t.Fatalf("data=%#v", data)
+	}
+	var out bytes.Buffer
+	result.Text(&out)
+	if !strings.Contains(out.String(), `Value:          {"ok":true}`) {
+		t.Fatalf("out=%q", out.String())
+	}
+
+	page.EvalErr = &cdp.ExceptionError{Message: "Error: nope"}
+	if _, err := cdpsessiontest.Run(t, Module(), cdpsessiontest.RuntimeDeps(page), req); err == nil || !strings.Contains(err.Error(), "Error: nope") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	for _, tc := range []struct {
+		result cdp.EvalResult
+		want   string
+	}{
+		{cdp.EvalResult{Type: "string", Value: "hi"}, `"hi"`},
+		{cdp.EvalResult{Type: "undefined"}, "undefined"},
+		{cdp.EvalResult{Type: "object", Description: "HTMLDivElement"}, "HTMLDivElement"},
+	} {
+		if got := formatValue(tc.result); got != tc.want {
+			t.Fatalf("formatValue(%#v)=%q, want %q", tc.result, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package cdpsessiontest provides a fake browser tab and command helpers for
+// testing the 'agr instance browser' action commands without a sandbox.
+package cdpsessiontest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/cdp"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+)
+
+// Page is a cdpsession.Page that answers from its fields and records the
+// calls made to it.
+type Page struct {
+	URL, Title  string
+	Errors      []string
+	Value       cdp.EvalResult
+	EvalErr     error
+	NavigateErr error
+	// Frames is the number of screencast frames delivered, half a second
+	// apart, before the screencast stops.
+	Frames int
+
+	// Navigated is the last URL loaded.
+	Navigated string
+	// Captured describes the last screenshot or PDF, for example
+	// "jpeg full=true" or "pdf landscape=false".
+	Captured string
+	// ScreencastOptions are the options of the last screencast.
+	ScreencastOptions cdp.ScreencastOptions
+}
+
+// NewPage returns a page showing https://example.com/home with one console
+// error.
+func NewPage() *Page {
+	return &Page{URL: "https://example.com/home", Title: "Example", Errors: []string{"boom"}}
+}
+
+// Navigate records url.
+func (p *Page) Navigate(_ context.Context, url string) error {
+	p.Navigated = url
+	return p.NavigateErr
+}
+
+// Evaluate returns Value and EvalErr.
+func (p *Page) Evaluate(context.Context, string) (cdp.EvalResult, error) {
+	return p.Value, p.EvalErr
+}
+
+// Info returns URL and Title.
+func (p *Page) Info(context.Context) (string, string, error) {
+	return p.URL, p.Title, nil
+}
+
+// Screenshot returns the bytes "image".
+func (p *Page) Screenshot(_ context.Context, format string, fullPage bool) ([]byte, error) {
+	p.Captured = fmt.Sprintf("%s full=%v", format, fullPage)
+	return []byte("image"), nil
+}
+
+// PDF returns a minimal PDF header.
+func (p *Page) PDF(_ context.Context, landscape bool) ([]byte, error) {
+	p.Captured = fmt.Sprintf("pdf landscape=%v", landscape)
+	return []byte("%PDF-1.4"), nil
+}
+
+// Screencast delivers Frames frames with the data "frame-1", "frame-2", ...
+// and then stops, as the screencast does when its context is cancelled.
+func (p *Page) Screencast(_ context.Context, opts cdp.ScreencastOptions, onFrame func(cdp.Frame) error) error {
+	p.ScreencastOptions = opts
+	start := time.Now().Add(-time.Minute)
+	for i := 0; i < p.Frames; i++ {
+		frame := cdp.Frame{Data: []byte(fmt.Sprintf("frame-%d", i+1)), Timestamp: start.Add(time.Duration(i) * 500 * time.Millisecond)}
+		if err := onFrame(frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConsoleErrors returns Errors.
+func (p *Page) ConsoleErrors() []string { return p.Errors }
+
+// RuntimeDeps returns hooks that attach every action to page.
+func RuntimeDeps(page cdpsession.Page) cdpsession.RuntimeDeps {
+	return cdpsession.RuntimeDeps{
+		Open: func(context.Context, string, int) (cdpsession.Page, func(), error) {
+			return page, func() {}, nil
+		},
+	}
+}
+
+// Run builds module with rt as its data-plane hooks and runs req.
+func Run(t *testing.T, module command.Module, rt any, req command.Request) (*command.Result, error) {
+	t.Helper()
+	return RunWithIO(t, IO(), module, rt, req)
+}
+
+// RunWithIO is Run with the given streams.
+func RunWithIO(t *testing.T, ios *iostreams.IOStreams, module command.Module, rt any, req command.Request) (*command.Result, error) {
+	t.Helper()
+	runtime, err := module.Build(command.Deps{IO: ios, DataPlane: rt})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	return runtime.Handler.Run(context.Background(), req)
+}
+
+// SetupConfig initializes a configuration with fake credentials.
+func SetupConfig(t *testing.T) {
+	t.Helper()
+	if err := config.Init(); err != nil {
+		t.Fatalf("config.Init: %v", err)
+	}
+	config.SetSecretID("AKIDfake")
+	config.SetSecretKey("fakeSecretKey")
+	config.SetRegion("ap-guangzhou")
+}
+
+// IO returns streams backed by buffers.
+func IO() *iostreams.IOStreams {
+	return &iostreams.IOStreams{In: &bytes.Buffer{}, Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+}
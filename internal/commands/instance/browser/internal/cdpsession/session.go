@@ -0,0 +1,218 @@
+// Package cdpsession connects the 'agr instance browser' action commands to
+// the DevTools endpoint of a browser sandbox.
+package cdpsession
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/cdp"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// DefaultTimeout bounds one action, including loading --url.
+const DefaultTimeout = 30 * time.Second
+
+// Page is the part of *cdp.Page used by the action commands.
+type Page interface {
+	Navigate(ctx context.Context, url string) error
+	Evaluate(ctx context.Context, expression string) (cdp.EvalResult, error)
+	Info(ctx context.Context) (url, title string, err error)
+	Screenshot(ctx context.Context, format string, fullPage bool) ([]byte, error)
+	PDF(ctx context.Context, landscape bool) ([]byte, error)
+	Screencast(ctx context.Context, opts cdp.ScreencastOptions, onFrame func(cdp.Frame) error) error
+	ConsoleErrors() []string
+}
+
+// RuntimeDeps contains the token and browser connection hooks that tests can
+// replace without a sandbox.
+type RuntimeDeps struct {
+	AcquireToken    func(ctx context.Context, instanceID string) (string, error)
+	InvalidateToken func(instanceID string)
+	// Open attaches to the first tab of the browser serving port in the
+	// sandbox. The returned function detaches and closes the connection.
+	Open func(ctx context.Context, instanceID string, port int) (Page, func(), error)
+}
+
+// Defaults fills the hooks missing from injected.
+func Defaults(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	if rt.AcquireToken == nil {
+		rt.AcquireToken = cli.AcquireInstanceToken
+	}
+	if rt.InvalidateToken == nil {
+		rt.InvalidateToken = cli.InvalidateInstanceToken
+	}
+	if rt.Open == nil {
+		rt.Open = func(ctx context.Context, instanceID string, port int) (Page, func(), error) {
+			return open(ctx, rt, instanceID, port)
+		}
+	}
+	return rt
+}
+
+// CDPURL returns the browser-level DevTools WebSocket URL of a browser
+// sandbox. The URL embeds the access token.
+func CDPURL(instanceID, region, domain, accessToken string, port int) string {
+	host := fmt.Sprintf("%d-%s.%s.%s", port, instanceID, region, domain)
+	return fmt.Sprintf("https://%s/cdp?access_token=%s", host, accessToken)
+}
+
+// open dials the sandbox, refreshing the token once if it is rejected.
+func open(ctx context.Context, rt RuntimeDeps, instanceID string, port int) (Page, func(), error) {
+	cfg := config.Get()
+	dial := func() (*cdp.Client, error) {
+		token, err := rt.AcquireToken(ctx, instanceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire access token: %w", err)
+		}
+		// The WebSocket dialer accepts https URLs as wss.
+		return cdp.Dial(ctx, CDPURL(instanceID, cfg.Region, cfg.DataPlaneDomain(), token, port), false)
+	}
+	client, err := dial()
+	if errors.Is(err, cdp.ErrUnauthorized) {
+		rt.InvalidateToken(instanceID)
+		client, err = dial()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := cdp.AttachPage(ctx, client)
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+	return page, func() {
+		detachCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = page.Detach(detachCtx)
+		_ = client.Close()
+	}, nil
+}
+
+// Flags returns the flags shared by the action commands.
+func Flags() []command.FlagSpec {
+	return []command.FlagSpec{
+		{Name: "port", Shorthand: "p", Usage: "Browser service port in the sandbox", Type: command.FlagInt, Default: cdp.DefaultRemotePort},
+		{Name: "timeout", Usage: "Maximum time for the action, for example 30s or 2m", Type: command.FlagString, Default: DefaultTimeout.String()},
+	}
+}
+
+// Groups returns the command groups of the browser commands.
+func Groups() []command.GroupSpec {
+	return []command.GroupSpec{
+		{
+			Path:    []string{"instance"},
+			Use:     "instance",
+			Short:   "Manage sandbox instances",
+			Long:    "Manage sandbox instances and related data-plane workflows.",
+			Aliases: []string{"i"},
+		},
+		{Path: []string{"instance", "browser"}, Use: "browser", Short: "Browser sandbox commands"},
+	}
+}
+
+// Action is a parsed action request.
+type Action struct {
+	InstanceID string
+	Port       int
+	Timeout    time.Duration
+}
+
+// ParseAction reads the instance ID and the shared flags.
+func ParseAction(req command.Request) (Action, error) {
+	action := Action{InstanceID: req.ArgValues["instance-id"], Port: req.Flags["port"].Int, Timeout: DefaultTimeout}
+	if action.InstanceID == "" && len(req.Args) > 0 {
+		action.InstanceID = req.Args[0]
+	}
+	if action.Port == 0 {
+		action.Port = cdp.DefaultRemotePort
+	}
+	if action.Port < 0 || action.Port > 65535 {
+		return Action{}, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid port: %d", action.Port), "Provide a port between 1 and 65535.")
+	}
+	if text := req.Flags["timeout"].String; text != "" {
+		d, err := time.ParseDuration(text)
+		if err != nil || d <= 0 {
+			return Action{}, output.NewUsageError("INVALID_TIMEOUT", fmt.Sprintf("invalid --timeout %q", text), "Use a positive duration, for example 30s or 2m.")
+		}
+		action.Timeout = d
+	}
+	return action, nil
+}
+
+// ValidateURL checks a page URL given on the command line.
+func ValidateURL(flag, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return output.NewUsageError("INVALID_URL", fmt.Sprintf("invalid %s %q", flag, raw), "Pass an absolute URL such as https://example.com.")
+	}
+	return nil
+}
+
+// Report describes the page after an action.
+type Report struct {
+	InstanceID    string
+	URL           string
+	Title         string
+	ConsoleErrors []string
+}
+
+// Run opens the browser of action.InstanceID, optionally loads pageURL, runs
+// fn and reports the state of the page afterwards.
+func Run(ctx context.Context, rt RuntimeDeps, action Action, pageURL string, fn func(context.Context, Page) error) (Report, error) {
+	if err := config.Validate(); err != nil {
+		return Report{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, action.Timeout)
+	defer cancel()
+	page, closePage, err := rt.Open(ctx, action.InstanceID, action.Port)
+	if err != nil {
+		return Report{}, err
+	}
+	defer closePage()
+	if pageURL != "" {
+		if err := page.Navigate(ctx, pageURL); err != nil {
+			return Report{}, err
+		}
+	}
+	if fn != nil {
+		if err := fn(ctx, page); err != nil {
+			return Report{}, err
+		}
+	}
+	report := Report{InstanceID: action.InstanceID, ConsoleErrors: page.ConsoleErrors()}
+	if report.URL, report.Title, err = page.Info(ctx); err != nil {
+		return Report{}, err
+	}
+	return report, nil
+}
+
+// Data returns the JSON fields of the report.
+func (r Report) Data() map[string]any {
+	return map[string]any{
+		"InstanceId":    r.InstanceID,
+		"Url":           r.URL,
+		"Title":         r.Title,
+		"ConsoleErrors": r.ConsoleErrors,
+	}
+}
+
+// Print writes the report as aligned rows, with extra rows after the
+// instance ID.
+func (r Report) Print(w io.Writer, extra ...instanceview.KeyValue) {
+	rows := append([]instanceview.KeyValue{{Key: "Instance ID", Value: r.InstanceID}}, extra...)
+	rows = append(rows, instanceview.KeyValue{Key: "URL", Value: r.URL}, instanceview.KeyValue{Key: "Title", Value: r.Title})
+	for _, text := range r.ConsoleErrors {
+		rows = append(rows, instanceview.KeyValue{Key: "Console error", Value: text})
+	}
+	instanceview.PrintKV(w, rows)
+}
@@ -0,0 +1,98 @@
+package cdpsession_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession/cdpsessiontest"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+)
+
+func TestParseAction(t *testing.T) {
+	action, err := cdpsession.ParseAction(command.Request{Args: []string{"ins-1"}})
+	if err != nil || action.InstanceID != "ins-1" || action.Port != 9000 || action.Timeout != cdpsession.DefaultTimeout {
+		t.Fatalf("action=%#v err=%v", action, err)
+	}
+	action, err = cdpsession.ParseAction(command.Request{
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags: map[string]command.FlagValue{
+			"port":    {Name: "port", Type: command.FlagInt, Int: 9222},
+			"timeout": {Name: "timeout", Type: command.FlagString, String: "2m"},
+		},
+	})
+	if err != nil || action.Port != 9222 || action.Timeout != 2*time.Minute {
+		t.Fatalf("action=%#v err=%v", action, err)
+	}
+	for name, flag := range map[string]command.FlagValue{
+		"invalid port":      {Name: "port", Type: command.FlagInt, Int: 70000},
+		"invalid --timeout": {Name: "timeout", Type: command.FlagString, String: "soon"},
+	} {
+		_, err := cdpsession.ParseAction(command.Request{Args: []string{"ins-1"}, Flags: map[string]command.FlagValue{flag.Name: flag}})
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Fatalf("%s: err=%v", name, err)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	if err := cdpsession.ValidateURL("url", "https://example.com"); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if err := cdpsession.ValidateURL("url", "example.com"); err == nil || !strings.Contains(err.Error(), "invalid url") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestRunLoadsURLAndReportsPage(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	page := &cdpsessiontest.Page{URL: "https://example.com/", Title: "Example", Errors: []string{"boom"}}
+	var closed bool
+	var opened string
+	rt := cdpsession.Defaults(cdpsession.RuntimeDeps{
+		Open: func(_ context.Context, instanceID string, port int) (cdpsession.Page, func(), error) {
+			opened = instanceID
+			if port != 9000 {
+				t.Fatalf("port=%d", port)
+			}
+			return page, func() { closed = true }, nil
+		},
+	})
+	var ran bool
+	report, err := cdpsession.Run(context.Background(), rt, cdpsession.Action{InstanceID: "ins-1", Port: 9000, Timeout: time.Second}, "https://example.com", func(context.Context, cdpsession.Page) error {
+		ran = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if opened != "ins-1" || !closed || !ran || page.Navigated != "https://example.com" {
+		t.Fatalf("opened=%q closed=%v ran=%v page=%#v", opened, closed, ran, page)
+	}
+	if report.URL != "https://example.com/" || report.Title != "Example" || len(report.ConsoleErrors) != 1 {
+		t.Fatalf("report=%#v", report)
+	}
+	var out bytes.Buffer
+	report.Print(&out, instanceview.KeyValue{Key: "Saved", Value: "a.png"})
+	if !strings.Contains(out.String(), "Saved:          a.png") || !strings.Contains(out.String(), "Console error:  boom") {
+		t.Fatalf("out=%q", out.String())
+	}
+
+	page.NavigateErr = errors.New("failed to load")
+	closed = false
+	if _, err := cdpsession.Run(context.Background(), rt, cdpsession.Action{InstanceID: "ins-1", Port: 9000, Timeout: time.Second}, "https://example.com", nil); err == nil || !closed {
+		t.Fatalf("err=%v closed=%v", err, closed)
+	}
+}
+
+func TestCDPURL(t *testing.T) {
+	got := cdpsession.CDPURL("ins-1", "ap-guangzhou", "example.com", "token", 9000)
+	if got != "https://9000-ins-1.ap-guangzhou.example.com/cdp?access_token=token" {
+		t.Fatalf("got=%q", got)
+	}
+}
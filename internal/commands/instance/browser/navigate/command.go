@@ -0,0 +1,74 @@
+package navigate
+
+import (
+	"context"
+	"io"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
+)
+
+// RuntimeDeps contains the token and browser connection hooks.
+type RuntimeDeps = cdpsession.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.browser.navigate",
+		Path:  []string{"instance", "browser", "navigate"},
+		Use:   "navigate <instance-id> <url>",
+		Short: "Load a URL in a browser instance",
+		Long: `Load a URL in the first tab of a browser sandbox and wait for the load event.
+
+The result reports the final URL after redirects, the page title and the
+console errors, uncaught exceptions and failed requests seen while loading.
+
+Examples:
+  agr instance browser navigate ins-xxxx https://example.com
+  agr instance browser navigate ins-xxxx https://example.com -o json --jq '.ConsoleErrors'`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "url", Required: true},
+		},
+		Flags:        cdpsession.Flags(),
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "BrowserPage"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: cdpsession.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := cdpsession.Defaults(deps.DataPlane)
+			return command.Runtime{
+				Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+					return runNavigate(ctx, req, rt)
+				}),
+			}, nil
+		},
+	}
+}
+
+func runNavigate(ctx context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	action, err := cdpsession.ParseAction(req)
+	if err != nil {
+		return nil, err
+	}
+	pageURL := req.ArgValues["url"]
+	if pageURL == "" && len(req.Args) > 1 {
+		pageURL = req.Args[1]
+	}
+	if err := cdpsession.ValidateURL("url", pageURL); err != nil {
+		return nil, err
+	}
+	report, err := cdpsession.Run(ctx, rt, action, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &command.Result{Data: report.Data(), Text: func(w io.Writer) {
+		report.Print(w)
+	}}, nil
+}
@@ -0,0 +1,42 @@
+package navigate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession/cdpsessiontest"
+)
+
+func TestRunNavigate(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	page := cdpsessiontest.NewPage()
+	result, err := cdpsessiontest.Run(t, Module(), cdpsessiontest.RuntimeDeps(page), command.Request{
+		Args:      []string{"ins-1", "https://example.com"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "url": "https://example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if page.Navigated != "https://example.com" {
+		t.Fatalf("page=%#v", page)
+	}
+	data := result.Data.(map[string]any)
+	if data["Url"] != "https://example.com/home" || data["Title"] != "Example" || len(data["ConsoleErrors"].([]string)) != 1 {
+		t.Fatalf("data=%#v", data)
+	}
+	var out bytes.Buffer
+	result.Text(&out)
+	if !strings.Contains(out.String(), "Console error:  boom") {
+		t.Fatalf("out=%q", out.String())
+	}
+
+	_, err = cdpsessiontest.Run(t, Module(), cdpsessiontest.RuntimeDeps(page), command.Request{
+		Args:      []string{"ins-1", "example.com"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "url": "example.com"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid url") {
+		t.Fatalf("err=%v", err)
+	}
+}
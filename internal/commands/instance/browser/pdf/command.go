@@ -0,0 +1,105 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/localfile"
+)
+
+// RuntimeDeps contains the token and browser connection hooks.
+type RuntimeDeps = cdpsession.RuntimeDeps
+
+const defaultOutput = "page.pdf"
+
+// Module returns this package's command module.
+func Module() command.Module {
+	flags := append(cdpsession.Flags(),
+		command.FlagSpec{Name: "url", Usage: "Load this URL before printing", Type: command.FlagString},
+		command.FlagSpec{Name: "landscape", Usage: "Print in landscape orientation", Type: command.FlagBool},
+	)
+	spec := command.Spec{
+		ID:    "instance.browser.pdf",
+		Path:  []string{"instance", "browser", "pdf"},
+		Use:   "pdf <instance-id> [local-path]",
+		Short: "Print a browser instance page to PDF",
+		Long: `Print the first tab of a browser sandbox, with backgrounds, to a local PDF
+file (default page.pdf).
+
+With --url the page is loaded first and printed after the load event.
+
+Examples:
+  agr instance browser pdf ins-xxxx
+  agr instance browser pdf ins-xxxx report.pdf --url https://example.com --landscape`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "local-path"},
+		},
+		Flags:        flags,
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "BrowserCapture"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: cdpsession.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := cdpsession.Defaults(deps.DataPlane)
+			return command.Runtime{
+				Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+					return runPDF(ctx, req, rt)
+				}),
+			}, nil
+		},
+	}
+}
+
+func runPDF(ctx context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	action, err := cdpsession.ParseAction(req)
+	if err != nil {
+		return nil, err
+	}
+	pageURL := req.Flags["url"].String
+	if pageURL != "" {
+		if err := cdpsession.ValidateURL("--url", pageURL); err != nil {
+			return nil, err
+		}
+	}
+	path := req.ArgValues["local-path"]
+	if path == "" && len(req.Args) > 1 {
+		path = req.Args[1]
+	}
+	if path == "" {
+		path = defaultOutput
+	}
+	path, err = localfile.ValidateOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	var size int
+	report, err := cdpsession.Run(ctx, rt, action, pageURL, func(ctx context.Context, page cdpsession.Page) error {
+		data, err := page.PDF(ctx, req.Flags["landscape"].Bool)
+		if err != nil {
+			return err
+		}
+		size = len(data)
+		return os.WriteFile(path, data, 0o644)
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := report.Data()
+	data["Path"] = path
+	data["Bytes"] = size
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		report.Print(w, instanceview.KeyValue{Key: "Saved", Value: fmt.Sprintf("%s (%d bytes)", path, size)})
+	}}, nil
+}
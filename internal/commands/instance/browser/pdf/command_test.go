@@ -0,0 +1,39 @@
+package pdf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession/cdpsessiontest"
+)
+
+func TestRunPDF(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	page := cdpsessiontest.NewPage()
+	path := filepath.Join(t.TempDir(), "page.pdf")
+	result, err := cdpsessiontest.Run(t, Module(), cdpsessiontest.RuntimeDeps(page), command.Request{
+		Args:      []string{"ins-1", path},
+		ArgValues: map[string]string{"instance-id": "ins-1", "local-path": path},
+		Flags: map[string]command.FlagValue{
+			"landscape": {Name: "landscape", Type: command.FlagBool, Bool: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if page.Navigated != "" || page.Captured != "pdf landscape=true" {
+		t.Fatalf("page=%#v", page)
+	}
+	if content, err := os.ReadFile(path); err != nil || !strings.HasPrefix(string(content), "%PDF") {
+		t.Fatalf("content=%q err=%v", content, err)
+	}
+	var out bytes.Buffer
+	result.Text(&out)
+	if !strings.Contains(out.String(), path+" (8 bytes)") {
+		t.Fatalf("out=%q", out.String())
+	}
+}
@@ -0,0 +1,220 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/localfile"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/cdp"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const defaultOutput = "recording"
+
+// videoExtensions are the local paths that ask for an assembled video.
+var videoExtensions = map[string]bool{".webm": true, ".mp4": true, ".mkv": true, ".mov": true, ".gif": true}
+
+// RuntimeDeps contains the browser connection, video assembly and wait hooks
+// that tests can replace without a sandbox or ffmpeg.
+type RuntimeDeps struct {
+	cdpsession.RuntimeDeps
+	// Assemble encodes the frames listed in an ffconcat file into a video.
+	// It returns errFFmpegNotFound when ffmpeg is not installed.
+	Assemble func(ctx context.Context, concatFile, video string) error
+	// FFmpegAvailable reports whether Assemble can run.
+	FFmpegAvailable func() bool
+	// Wait returns a context that is done on SIGINT or SIGTERM.
+	Wait func(context.Context) (context.Context, context.CancelFunc)
+}
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.browser.record",
+		Path:  []string{"instance", "browser", "record"},
+		Use:   "record <instance-id> [local-path]",
+		Short: "Record the screen of a browser instance",
+		Long: `Record the first tab of a browser sandbox until Ctrl+C or --duration.
+
+Frames are captured with the DevTools screencast, which sends a frame whenever
+the page repaints, and saved as an image sequence with a manifest.json listing
+each frame's timestamp. When local-path ends in .webm, .mp4, .mkv, .mov or
+.gif, the frames are kept next to it in <name>-frames/ and assembled into the
+video with ffmpeg, if it is installed, using the recorded timing. Any other
+local-path is the frame directory (default recording/).
+
+Examples:
+  agr instance browser record ins-xxxx
+  agr instance browser record ins-xxxx session.webm
+  agr instance browser record ins-xxxx frames/ --duration 1m --format png`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "local-path"},
+		},
+		Flags: []command.FlagSpec{
+			{Name: "port", Shorthand: "p", Usage: "Browser service port in the sandbox", Type: command.FlagInt, Default: cdp.DefaultRemotePort},
+			{Name: "duration", Usage: "Stop after this long, for example 30s (default: until Ctrl+C)", Type: command.FlagString},
+			{Name: "format", Usage: "Frame image format: jpeg or png", Type: command.FlagString, Default: "jpeg"},
+			{Name: "quality", Usage: "JPEG quality from 1 to 100", Type: command.FlagInt, Default: 80},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "BrowserRecording"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: cdpsession.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := runtimeDeps(deps.DataPlane)
+			return command.Runtime{
+				Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+					return runRecord(ctx, req, deps, rt)
+				}),
+			}, nil
+		},
+	}
+}
+
+func runtimeDeps(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	rt.RuntimeDeps = cdpsession.Defaults(rt.RuntimeDeps)
+	if rt.Assemble == nil {
+		rt.Assemble = assembleWithFFmpeg
+	}
+	if rt.FFmpegAvailable == nil {
+		rt.FFmpegAvailable = ffmpegAvailable
+	}
+	if rt.Wait == nil {
+		rt.Wait = func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		}
+	}
+	return rt
+}
+
+func runRecord(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := req.ArgValues["instance-id"]
+	if instanceID == "" && len(req.Args) > 0 {
+		instanceID = req.Args[0]
+	}
+	port := req.Flags["port"].Int
+	if port == 0 {
+		port = cdp.DefaultRemotePort
+	}
+	if port < 0 || port > 65535 {
+		return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid port: %d", port), "Provide a port between 1 and 65535.")
+	}
+	var duration time.Duration
+	if text := req.Flags["duration"].String; text != "" {
+		d, err := time.ParseDuration(text)
+		if err != nil || d <= 0 {
+			return nil, output.NewUsageError("INVALID_DURATION", fmt.Sprintf("invalid --duration %q", text), "Use a positive duration, for example 30s or 5m.")
+		}
+		duration = d
+	}
+	format := req.Flags["format"].String
+	if format == "" {
+		format = "jpeg"
+	}
+	if format != "jpeg" && format != "png" {
+		return nil, output.NewUsageError("INVALID_USAGE", fmt.Sprintf("invalid --format %q", format), "Use jpeg or png.")
+	}
+	quality := req.Flags["quality"].Int
+	if quality == 0 {
+		quality = 80
+	}
+	if quality < 1 || quality > 100 {
+		return nil, output.NewUsageError("INVALID_USAGE", fmt.Sprintf("invalid --quality %d", quality), "Use a value from 1 to 100.")
+	}
+
+	path := req.ArgValues["local-path"]
+	if path == "" && len(req.Args) > 1 {
+		path = req.Args[1]
+	}
+	if path == "" {
+		path = defaultOutput
+	}
+	path, err := localfile.ValidateOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, video := path, ""
+	if ext := strings.ToLower(filepath.Ext(path)); videoExtensions[ext] {
+		dir, video = strings.TrimSuffix(path, filepath.Ext(path))+"-frames", path
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if video != "" && !rt.FFmpegAvailable() {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: ffmpeg was not found; only the frames and manifest will be written to %s.\n", dir)
+		video = ""
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	recordCtx, stop := rt.Wait(ctx)
+	defer stop()
+	if duration > 0 {
+		var cancel context.CancelFunc
+		recordCtx, cancel = context.WithTimeout(recordCtx, duration)
+		defer cancel()
+	}
+	page, closePage, err := rt.Open(recordCtx, instanceID, port)
+	if err != nil {
+		return nil, err
+	}
+	defer closePage()
+
+	rec := newRecording(instanceID, dir, format)
+	fmt.Fprintf(deps.IO.ErrOut, "Recording %s to %s. Press Ctrl+C to stop.\n", instanceID, dir)
+	err = page.Screencast(recordCtx, cdp.ScreencastOptions{Format: format, Quality: quality}, rec.add)
+	rec.finish(time.Now())
+	if err != nil && recordCtx.Err() == nil {
+		return nil, fmt.Errorf("recording stopped after %d frames: %w", len(rec.Frames), err)
+	}
+	manifest, err := rec.writeManifest()
+	if err != nil {
+		return nil, err
+	}
+	if video != "" && len(rec.Frames) > 0 {
+		concat, err := rec.writeConcat()
+		if err != nil {
+			return nil, err
+		}
+		// The recording context is done; give ffmpeg its own.
+		if err := rt.Assemble(context.WithoutCancel(ctx), concat, video); err != nil {
+			return nil, fmt.Errorf("failed to assemble %s (frames kept in %s): %w", video, dir, err)
+		}
+	} else {
+		video = ""
+	}
+
+	data := map[string]any{
+		"InstanceId": instanceID,
+		"Directory":  dir,
+		"Manifest":   manifest,
+		"Frames":     len(rec.Frames),
+		"DurationMs": rec.EndedAt.Sub(rec.StartedAt).Milliseconds(),
+		"Video":      video,
+	}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		fmt.Fprintf(w, "Recorded %d frames over %s to %s\n", len(rec.Frames), rec.EndedAt.Sub(rec.StartedAt).Round(time.Millisecond), dir)
+		if video != "" {
+			fmt.Fprintf(w, "Wrote %s\n", video)
+		}
+	}}, nil
+}
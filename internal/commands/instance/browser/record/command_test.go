@@ -0,0 +1,132 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession/cdpsessiontest"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+)
+
+func TestRunRecordWritesFramesAndManifest(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	page := &cdpsessiontest.Page{Frames: 3}
+	dir := filepath.Join(t.TempDir(), "frames")
+	result, err := run(t, page, RuntimeDeps{}, command.Request{
+		Args:      []string{"ins-1", dir + "/"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "local-path": dir + "/"},
+		Flags: map[string]command.FlagValue{
+			"format": {Name: "format", Type: command.FlagString, String: "png"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if page.ScreencastOptions.Format != "png" || page.ScreencastOptions.Quality != 80 {
+		t.Fatalf("opts=%#v", page.ScreencastOptions)
+	}
+	data := result.Data.(map[string]any)
+	if data["Frames"] != 3 || data["Directory"] != dir || data["Video"] != "" {
+		t.Fatalf("data=%#v", data)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "frame-000002.png")); err != nil || string(content) != "frame-2" {
+		t.Fatalf("content=%q err=%v", content, err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	var manifest recording
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		t.Fatalf("manifest=%s err=%v", raw, err)
+	}
+	if manifest.InstanceID != "ins-1" || len(manifest.Frames) != 3 || manifest.Frames[2].OffsetMs != 1000 {
+		t.Fatalf("manifest=%#v", manifest)
+	}
+}
+
+func TestRunRecordAssemblesVideo(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	video := filepath.Join(t.TempDir(), "session.webm")
+	var concat, assembled string
+	rt := RuntimeDeps{
+		FFmpegAvailable: func() bool { return true },
+		Assemble: func(_ context.Context, list, out string) error {
+			content, err := os.ReadFile(list)
+			concat, assembled = string(content), out
+			return err
+		},
+	}
+	result, err := run(t, &cdpsessiontest.Page{Frames: 2}, rt, command.Request{
+		Args:      []string{"ins-1", video},
+		ArgValues: map[string]string{"instance-id": "ins-1", "local-path": video},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	data := result.Data.(map[string]any)
+	framesDir := strings.TrimSuffix(video, ".webm") + "-frames"
+	if assembled != video || data["Video"] != video || data["Directory"] != framesDir {
+		t.Fatalf("assembled=%q data=%#v", assembled, data)
+	}
+	if !strings.Contains(concat, "file 'frame-000001.jpg'\nduration 0.500\n") || !strings.HasSuffix(concat, "file 'frame-000002.jpg'\n") {
+		t.Fatalf("concat=%q", concat)
+	}
+}
+
+func TestRunRecordWithoutFFmpegKeepsFrames(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	video := filepath.Join(t.TempDir(), "session.mp4")
+	ios := cdpsessiontest.IO()
+	rt := RuntimeDeps{
+		FFmpegAvailable: func() bool { return false },
+		Assemble: func(context.Context, string, string) error {
+			t.Fatal("Assemble called without ffmpeg")
+			return nil
+		},
+	}
+	result, err := runWithIO(t, ios, &cdpsessiontest.Page{Frames: 1}, rt, command.Request{
+		Args:      []string{"ins-1", video},
+		ArgValues: map[string]string{"instance-id": "ins-1", "local-path": video},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if data := result.Data.(map[string]any); data["Video"] != "" || data["Frames"] != 1 {
+		t.Fatalf("data=%#v", data)
+	}
+	if !strings.Contains(ios.ErrOut.(*bytes.Buffer).String(), "ffmpeg was not found") {
+		t.Fatalf("stderr=%q", ios.ErrOut)
+	}
+}
+
+func TestRunRecordRejectsInvalidDuration(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	_, err := run(t, &cdpsessiontest.Page{}, RuntimeDeps{}, command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags: map[string]command.FlagValue{
+			"duration": {Name: "duration", Type: command.FlagString, String: "soon"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid --duration") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func run(t *testing.T, page *cdpsessiontest.Page, rt RuntimeDeps, req command.Request) (*command.Result, error) {
+	t.Helper()
+	return runWithIO(t, cdpsessiontest.IO(), page, rt, req)
+}
+
+func runWithIO(t *testing.T, ios *iostreams.IOStreams, page *cdpsessiontest.Page, rt RuntimeDeps, req command.Request) (*command.Result, error) {
+	t.Helper()
+	rt.RuntimeDeps = cdpsessiontest.RuntimeDeps(page)
+	return cdpsessiontest.RunWithIO(t, ios, Module(), rt, req)
+}
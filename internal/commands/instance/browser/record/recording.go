@@ -0,0 +1,133 @@
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/cdp"
+)
+
+// errFFmpegNotFound reports that ffmpeg is not installed.
+var errFFmpegNotFound = errors.New("ffmpeg not found in PATH")
+
+// minFrameDuration is shown for a frame that the page replaced immediately
+// or, for the last frame, that was still on screen when recording stopped.
+const minFrameDuration = 40 * time.Millisecond
+
+// recording is the manifest written next to the frames.
+type recording struct {
+	InstanceID string        `json:"InstanceId"`
+	Format     string        `json:"Format"`
+	StartedAt  time.Time     `json:"StartedAt"`
+	EndedAt    time.Time     `json:"EndedAt"`
+	Frames     []frameRecord `json:"Frames"`
+
+	dir string
+}
+
+// frameRecord is one frame in the manifest.
+type frameRecord struct {
+	File      string    `json:"File"`
+	Timestamp time.Time `json:"Timestamp"`
+	OffsetMs  int64     `json:"OffsetMs"`
+}
+
+func newRecording(instanceID, dir, format string) *recording {
+	return &recording{InstanceID: instanceID, Format: format, StartedAt: time.Now(), Frames: []frameRecord{}, dir: dir}
+}
+
+// add saves one frame.
+func (r *recording) add(frame cdp.Frame) error {
+	ext := ".jpg"
+	if r.Format == "png" {
+		ext = ".png"
+	}
+	name := fmt.Sprintf("frame-%06d%s", len(r.Frames)+1, ext)
+	if err := os.WriteFile(filepath.Join(r.dir, name), frame.Data, 0o644); err != nil {
+		return fmt.Errorf("failed to save frame: %w", err)
+	}
+	if len(r.Frames) == 0 && frame.Timestamp.Before(r.StartedAt) {
+		// The first frame shows the page as it was when recording began.
+		r.StartedAt = frame.Timestamp
+	}
+	r.Frames = append(r.Frames, frameRecord{
+		File:      name,
+		Timestamp: frame.Timestamp,
+		OffsetMs:  frame.Timestamp.Sub(r.StartedAt).Milliseconds(),
+	})
+	return nil
+}
+
+func (r *recording) finish(at time.Time) {
+	r.EndedAt = at
+	if n := len(r.Frames); n > 0 && r.EndedAt.Before(r.Frames[n-1].Timestamp) {
+		r.EndedAt = r.Frames[n-1].Timestamp
+	}
+}
+
+// writeManifest writes manifest.json and returns its path.
+func (r *recording) writeManifest() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(r.dir, "manifest.json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return path, nil
+}
+
+// writeConcat writes an ffconcat list that shows every frame until the next
+// one, so that the video keeps the recorded timing, and returns its path.
+func (r *recording) writeConcat() (string, error) {
+	var b strings.Builder
+	b.WriteString("ffconcat version 1.0\n")
+	for i, frame := range r.Frames {
+		end := r.EndedAt
+		if i+1 < len(r.Frames) {
+			end = r.Frames[i+1].Timestamp
+		}
+		fmt.Fprintf(&b, "file '%s'\nduration %.3f\n", frame.File, max(end.Sub(frame.Timestamp), minFrameDuration).Seconds())
+	}
+	// The concat demuxer ignores the duration of the last entry.
+	fmt.Fprintf(&b, "file '%s'\n", r.Frames[len(r.Frames)-1].File)
+	path := filepath.Join(r.dir, "frames.ffconcat")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write frame list: %w", err)
+	}
+	return path, nil
+}
+
+func ffmpegAvailable() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil
+}
+
+func assembleWithFFmpeg(ctx context.Context, concatFile, video string) error {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return errFFmpegNotFound
+	}
+	args := []string{"-hide_banner", "-loglevel", "error", "-y",
+		"-f", "concat", "-safe", "0", "-i", concatFile,
+		"-vsync", "vfr",
+	}
+	if !strings.EqualFold(filepath.Ext(video), ".gif") {
+		// Most encoders need even dimensions for yuv420p.
+		args = append(args, "-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2", "-pix_fmt", "yuv420p")
+	}
+	args = append(args, video)
+	out, err := exec.CommandContext(ctx, ffmpeg, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
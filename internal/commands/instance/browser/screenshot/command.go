@@ -0,0 +1,114 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/localfile"
+)
+
+// RuntimeDeps contains the token and browser connection hooks.
+type RuntimeDeps = cdpsession.RuntimeDeps
+
+const defaultOutput = "screenshot.png"
+
+// Module returns this package's command module.
+func Module() command.Module {
+	flags := append(cdpsession.Flags(),
+		command.FlagSpec{Name: "url", Usage: "Load this URL before capturing", Type: command.FlagString},
+		command.FlagSpec{Name: "full-page", Usage: "Capture the whole page rather than the viewport", Type: command.FlagBool},
+	)
+	spec := command.Spec{
+		ID:    "instance.browser.screenshot",
+		Path:  []string{"instance", "browser", "screenshot"},
+		Use:   "screenshot <instance-id> [local-path]",
+		Short: "Save a screenshot of a browser instance",
+		Long: `Save a screenshot of the first tab of a browser sandbox to a local file
+(default screenshot.png). A .jpg or .jpeg path selects JPEG, otherwise PNG.
+
+With --url the page is loaded first and the capture is taken after the load
+event.
+
+Examples:
+  agr instance browser screenshot ins-xxxx
+  agr instance browser screenshot ins-xxxx page.jpg --url https://example.com --full-page`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "local-path"},
+		},
+		Flags:        flags,
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "BrowserCapture"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: cdpsession.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := cdpsession.Defaults(deps.DataPlane)
+			return command.Runtime{
+				Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+					return runScreenshot(ctx, req, rt)
+				}),
+			}, nil
+		},
+	}
+}
+
+func runScreenshot(ctx context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	action, err := cdpsession.ParseAction(req)
+	if err != nil {
+		return nil, err
+	}
+	pageURL := req.Flags["url"].String
+	if pageURL != "" {
+		if err := cdpsession.ValidateURL("--url", pageURL); err != nil {
+			return nil, err
+		}
+	}
+	path := req.ArgValues["local-path"]
+	if path == "" && len(req.Args) > 1 {
+		path = req.Args[1]
+	}
+	if path == "" {
+		path = defaultOutput
+	}
+	path, err = localfile.ValidateOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	format := "png"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		format = "jpeg"
+	}
+	var size int
+	report, err := cdpsession.Run(ctx, rt, action, pageURL, func(ctx context.Context, page cdpsession.Page) error {
+		data, err := page.Screenshot(ctx, format, req.Flags["full-page"].Bool)
+		if err != nil {
+			return err
+		}
+		size = len(data)
+		return os.WriteFile(path, data, 0o644)
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := report.Data()
+	data["Path"] = path
+	data["Format"] = format
+	data["Bytes"] = size
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		report.Print(w, instanceview.KeyValue{Key: "Saved", Value: fmt.Sprintf("%s (%d bytes)", path, size)})
+	}}, nil
+}
@@ -0,0 +1,47 @@
+package screenshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession/cdpsessiontest"
+)
+
+func TestRunScreenshot(t *testing.T) {
+	cdpsessiontest.SetupConfig(t)
+	page := cdpsessiontest.NewPage()
+	path := filepath.Join(t.TempDir(), "shot.jpg")
+	result, err := cdpsessiontest.Run(t, Module(), cdpsessiontest.RuntimeDeps(page), command.Request{
+		Args:      []string{"ins-1", path},
+		ArgValues: map[string]string{"instance-id": "ins-1", "local-path": path},
+		Flags: map[string]command.FlagValue{
+			"url":       {Name: "url", Type: command.FlagString, String: "https://example.com"},
+			"full-page": {Name: "full-page", Type: command.FlagBool, Bool: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if page.Navigated != "https://example.com" || page.Captured != "jpeg full=true" {
+		t.Fatalf("page=%#v", page)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "image" {
+		t.Fatalf("content=%q err=%v", content, err)
+	}
+	data := result.Data.(map[string]any)
+	if data["Path"] != path || data["Bytes"] != 5 || data["Format"] != "jpeg" {
+		t.Fatalf("data=%#v", data)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing", "shot.png")
+	_, err = cdpsessiontest.Run(t, Module(), cdpsessiontest.RuntimeDeps(page), command.Request{
+		Args:      []string{"ins-1", missing},
+		ArgValues: map[string]string{"instance-id": "ins-1", "local-path": missing},
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("err=%v", err)
+	}
+}
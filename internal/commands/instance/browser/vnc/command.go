@@ -7,14 +7,31 @@ import (
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/adbtunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/utils"
 )
 
+// Server is a local listener started by --open: the noVNC proxy or the raw
+// RFB tunnel.
+type Server interface {
+	Start() (string, error)
+	Stop()
+}
+
 // RuntimeDeps contains the token acquisition hook needed to construct browser
-// access URLs.
+// access URLs, and the listener, browser and wait hooks used by --open.
 type RuntimeDeps struct {
-	AcquireToken func(ctx context.Context, instanceID string) (string, error)
+	AcquireToken    func(ctx context.Context, instanceID string) (string, error)
+	InvalidateToken func(instanceID string)
+	NewProxy        func(proxy.Options) (Server, error)
+	NewTunnel       func(adbtunnel.TunnelOptions) (Server, error)
+	OpenBrowser     func(url string) error
+	Wait            func(context.Context)
 }
 
 // Module returns this package's command module.
@@ -26,14 +43,26 @@ func Module() command.Module {
 		Short: "Show VNC URL for browser instance",
 		Long: `Show the VNC URL for accessing a browser sandbox instance.
 
+The URLs embed the access token. With --open, the token stays in the CLI
+instead: a loopback server serves the noVNC page and proxies its websockify
+connection with the token injected, the local viewer URL is opened in the
+default browser, and a raw RFB port is offered for native VNC clients. Both
+run until Ctrl+C. The viewer serves only /novnc/ and /websockify; to connect
+CDP clients without handling the token, use 'agr instance browser cdp'.
+
 Examples:
   agr instance browser vnc ins-xxxx
-  agr instance browser vnc ins-xxxx --port 9000`,
+  agr instance browser vnc ins-xxxx --port 9000
+  agr instance browser vnc ins-xxxx --open
+  agr instance browser vnc ins-xxxx --open --rfb-port 5900`,
 		Args: []command.ArgSpec{
 			{Name: "instance-id", Required: true},
 		},
 		Flags: []command.FlagSpec{
 			{Name: "port", Shorthand: "p", Usage: "VNC service port", Type: command.FlagInt, Default: 9000},
+			{Name: "open", Usage: "Serve a local viewer with the token hidden and open it in the browser", Type: command.FlagBool},
+			{Name: "local-port", Usage: "Local port for the viewer with --open (0 = auto-assign)", Type: command.FlagInt},
+			{Name: "rfb-port", Usage: "Local port for native VNC clients with --open (0 = auto-assign)", Type: command.FlagInt},
 		},
 		SupportsJSON: true,
 		Output:       command.OutputSpec{DataType: "BrowserURLs"},
@@ -70,6 +99,25 @@ func runtimeDeps(injected any) RuntimeDeps {
 	if rt.AcquireToken == nil {
 		rt.AcquireToken = cli.AcquireInstanceToken
 	}
+	if rt.InvalidateToken == nil {
+		rt.InvalidateToken = cli.InvalidateInstanceToken
+	}
+	if rt.NewProxy == nil {
+		rt.NewProxy = func(opts proxy.Options) (Server, error) {
+			return proxy.New(opts)
+		}
+	}
+	if rt.NewTunnel == nil {
+		rt.NewTunnel = func(opts adbtunnel.TunnelOptions) (Server, error) {
+			return adbtunnel.New(opts)
+		}
+	}
+	if rt.OpenBrowser == nil {
+		rt.OpenBrowser = utils.OpenBrowser
+	}
+	if rt.Wait == nil {
+		rt.Wait = waitForSignal
+	}
 	return rt
 }
 
@@ -85,6 +133,9 @@ func runVNC(ctx context.Context, req command.Request, deps command.Deps, rt Runt
 	if port <= 0 || port > 65535 {
 		return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid port: %d", port), "Provide a port between 1 and 65535.")
 	}
+	if boolFlag(req, "open") {
+		return runViewer(ctx, req, deps, rt, instanceID, port)
+	}
 	if err := config.Validate(); err != nil {
 		return nil, err
 	}
@@ -95,17 +146,17 @@ func runVNC(ctx context.Context, req command.Request, deps command.Deps, rt Runt
 
 	cfg := config.Get()
 	vncURL := buildVNCURL(instanceID, cfg.Region, cfg.DataPlaneDomain(), accessToken, port)
-	cdpURL := buildCDPURL(instanceID, cfg.Region, cfg.DataPlaneDomain(), accessToken, port)
+	cdpURL := cdpsession.CDPURL(instanceID, cfg.Region, cfg.DataPlaneDomain(), accessToken, port)
 	data := map[string]any{
 		"InstanceId": instanceID,
 		"VncUrl":     vncURL,
 		"CdpUrl":     cdpURL,
 	}
 	return &command.Result{Data: data, Text: func(w io.Writer) {
-		printKV(w, []keyValue{
-			{key: "Instance ID", value: instanceID},
-			{key: "VNC URL", value: vncURL},
-			{key: "CDP URL", value: cdpURL},
+		instanceview.PrintKV(w, []instanceview.KeyValue{
+			{Key: "Instance ID", Value: instanceID},
+			{Key: "VNC URL", Value: vncURL},
+			{Key: "CDP URL", Value: cdpURL},
 		})
 	}}, nil
 }
@@ -115,26 +166,8 @@ func buildVNCURL(instanceID, region, domain, accessToken string, port int) strin
 	return fmt.Sprintf("https://%s/novnc/vnc_lite.html?&path=websockify?access_token=%s", host, accessToken)
 }
 
-func buildCDPURL(instanceID, region, domain, accessToken string, port int) string {
-	host := fmt.Sprintf("%d-%s.%s.%s", port, instanceID, region, domain)
-	return fmt.Sprintf("https://%s/cdp?access_token=%s", host, accessToken)
-}
-
-type keyValue struct {
-	key   string
-	value string
-}
-
-func printKV(w io.Writer, pairs []keyValue) {
-	maxLen := 0
-	for _, kv := range pairs {
-		if len(kv.key) > maxLen {
-			maxLen = len(kv.key)
-		}
-	}
-	for _, kv := range pairs {
-		fmt.Fprintf(w, "%-*s  %s\n", maxLen, kv.key+":", kv.value)
-	}
+func boolFlag(req command.Request, name string) bool {
+	return req.Flags[name].Bool
 }
 
 func intFlag(req command.Request, name string) int {
@@ -7,7 +7,10 @@ import (
 	"testing"
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/internal/cdpsession"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/adbtunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
 )
 
@@ -57,17 +60,85 @@ func TestModuleRejectsInvalidPort(t *testing.T) {
 	}
 }
 
+func TestModuleOpenServesViewerWithoutToken(t *testing.T) {
+	setupConfig(t)
+	viewer := &fakeServer{addr: "127.0.0.1:53000"}
+	rfb := &fakeServer{addr: "127.0.0.1:5900"}
+	var proxyOpts proxy.Options
+	var tunnelOpts adbtunnel.TunnelOptions
+	var opened string
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "secret-token", nil },
+			NewProxy: func(o proxy.Options) (Server, error) {
+				proxyOpts = o
+				return viewer, nil
+			},
+			NewTunnel: func(o adbtunnel.TunnelOptions) (Server, error) {
+				tunnelOpts = o
+				return rfb, nil
+			},
+			OpenBrowser: func(url string) error {
+				opened = url
+				return nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags: map[string]command.FlagValue{
+			"port":     {Name: "port", Type: command.FlagInt, Int: 9000},
+			"open":     {Name: "open", Type: command.FlagBool, Bool: true},
+			"rfb-port": {Name: "rfb-port", Type: command.FlagInt, Int: 5900},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone || !viewer.stopped || !rfb.stopped {
+		t.Fatalf("result=%#v viewer=%#v rfb=%#v", result, viewer, rfb)
+	}
+	if proxyOpts.RemotePort != 9000 || proxyOpts.ListenAddress != "127.0.0.1:0" || proxyOpts.Token != "secret-token" || strings.Join(proxyOpts.Paths, ",") != "/novnc/,/websockify" || !proxyOpts.LocalHostsOnly {
+		t.Fatalf("proxyOpts=%#v", proxyOpts)
+	}
+	if tunnelOpts.Path != "/websockify" || tunnelOpts.ListenAddress != "127.0.0.1:5900" || !tunnelOpts.GatewayAuth {
+		t.Fatalf("tunnelOpts=%#v", tunnelOpts)
+	}
+	if opened != "http://127.0.0.1:53000/novnc/vnc_lite.html?path=websockify" {
+		t.Fatalf("opened=%q", opened)
+	}
+	if strings.Contains(stdout.String(), "secret-token") || !strings.Contains(stdout.String(), "RFB:    127.0.0.1:5900") {
+		t.Fatalf("stdout=%q", stdout.String())
+	}
+}
+
 func TestBuildURLs(t *testing.T) {
 	vncURL := buildVNCURL("ins-1", "ap-guangzhou", "example.com", "token", 9000)
 	if !strings.Contains(vncURL, "9000-ins-1.ap-guangzhou.example.com") || !strings.Contains(vncURL, "access_token=token") {
 		t.Fatalf("vncURL=%q", vncURL)
 	}
-	cdpURL := buildCDPURL("ins-1", "ap-guangzhou", "example.com", "token", 9000)
+	cdpURL := cdpsession.CDPURL("ins-1", "ap-guangzhou", "example.com", "token", 9000)
 	if !strings.Contains(cdpURL, "/cdp?access_token=token") {
 		t.Fatalf("cdpURL=%q", cdpURL)
 	}
 }
 
+type fakeServer struct {
+	addr    string
+	stopped bool
+}
+
+func (f *fakeServer) Start() (string, error) { return f.addr, nil }
+
+func (f *fakeServer) Stop() { f.stopped = true }
+
 func setupConfig(t *testing.T) {
 	t.Helper()
 	if err := config.Init(); err != nil {
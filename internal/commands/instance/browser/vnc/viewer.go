@@ -0,0 +1,122 @@
+package vnc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/adbtunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const (
+	// novncPath holds the noVNC page and its scripts.
+	novncPath = "/novnc/"
+	// viewerPath is the noVNC page served by browser sandboxes; its path
+	// parameter names the websockify endpoint relative to the page host.
+	viewerPath = "/novnc/vnc_lite.html?path=websockify"
+	// websockifyPath carries the RFB stream over a WebSocket.
+	websockifyPath = "/websockify"
+)
+
+// runViewer serves the sandbox's noVNC page and an RFB port on loopback until
+// interrupted, so that the access token never reaches a browser URL. The
+// viewer forwards only noVNC and its websockify stream, not the DevTools
+// endpoint served on the same sandbox port, and only to requests addressed to
+// localhost or an IP address, so a web page cannot take over the desktop
+// through DNS rebinding.
+func runViewer(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps, instanceID string, port int) (*command.Result, error) {
+	if cli.IsJSONOutput() {
+		return nil, output.NewUsageError("JSON_REQUIRES_BACKGROUND",
+			"agr instance browser vnc --open runs in the foreground and does not support -o json",
+			"Run it without -o json, or drop --open to print the URLs.")
+	}
+	localPort, rfbPort := intFlag(req, "local-port"), intFlag(req, "rfb-port")
+	for _, p := range []int{localPort, rfbPort} {
+		if p < 0 || p > 65535 {
+			return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid port: %d", p), "Provide a port between 0 and 65535; 0 picks a free port.")
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	token, err := rt.AcquireToken(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire access token: %w", err)
+	}
+	domain := config.Get().DataPlaneRegionDomain()
+	logger := log.New(deps.IO.ErrOut, "", log.LstdFlags)
+	provide := func() (string, error) {
+		return rt.AcquireToken(ctx, instanceID)
+	}
+
+	viewer, err := rt.NewProxy(proxy.Options{
+		InstanceID:      instanceID,
+		Domain:          domain,
+		RemotePort:      port,
+		Token:           token,
+		TokenProvider:   provide,
+		InvalidateToken: func() { rt.InvalidateToken(instanceID) },
+		ListenAddress:   net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)),
+		Logger:          logger,
+		Paths:           []string{novncPath, websockifyPath},
+		LocalHostsOnly:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create viewer: %w", err)
+	}
+	viewerAddr, err := viewer.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start viewer: %w", err)
+	}
+	rfb, err := rt.NewTunnel(adbtunnel.TunnelOptions{
+		InstanceID:    instanceID,
+		Domain:        domain,
+		TokenProvider: provide,
+		ListenAddress: net.JoinHostPort("127.0.0.1", strconv.Itoa(rfbPort)),
+		Logger:        logger,
+		RemotePort:    port,
+		Path:          websockifyPath,
+		Label:         "VNC",
+		GatewayAuth:   true,
+	})
+	if err != nil {
+		viewer.Stop()
+		return nil, fmt.Errorf("failed to create RFB port: %w", err)
+	}
+	rfbAddr, err := rfb.Start()
+	if err != nil {
+		viewer.Stop()
+		return nil, fmt.Errorf("failed to start RFB port: %w", err)
+	}
+
+	viewerURL := "http://" + viewerAddr + viewerPath
+	fmt.Fprintf(deps.IO.Out, "VNC viewer for %s\n", instanceID)
+	fmt.Fprintf(deps.IO.Out, "  Viewer: %s\n", viewerURL)
+	fmt.Fprintf(deps.IO.Out, "  RFB:    %s (for native VNC clients)\n", rfbAddr)
+	if err := rt.OpenBrowser(viewerURL); err != nil {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: failed to open a browser (%v); open the viewer URL manually.\n", err)
+	}
+	fmt.Fprintln(deps.IO.Out, "\nPress Ctrl+C to stop.")
+
+	rt.Wait(ctx)
+
+	fmt.Fprintln(deps.IO.Out, "\nStopping viewer...")
+	rfb.Stop()
+	viewer.Stop()
+	return &command.Result{StreamDone: true}, nil
+}
+
+func waitForSignal(ctx context.Context) {
+	waitCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-waitCtx.Done()
+}
@@ -36,6 +36,8 @@ Supported languages: python (default), javascript, typescript, r, java, bash`,
 			`echo "print('Hello')" | agr instance code run ins-xxxx`,
 			`agr instance code run --create-temp-instance --tool-name my-tool -c "print('hello')"`,
 			"agr instance code run --create-temp-instance --tool-id sdt-xxxx -f script.py --cleanup never",
+			"agr instance code run --create-temp-instance --tool-id sdt-xxxx -f plot.py --collect '/tmp/*.png:./out'",
+			"agr instance code run --create-temp-instance --pool py -c \"print('hello')\"",
 		},
 		Args: []cmdcore.ArgSpec{
 			{Name: "instance-id", Description: "Sandbox instance ID."},
@@ -49,6 +51,10 @@ Supported languages: python (default), javascript, typescript, r, java, bash`,
 			{Name: "cleanup", Usage: "Cleanup policy for temporary instance: always|success|never", Type: cmdcore.FlagString, Default: "always", Workflow: true},
 			{Name: "tool-name", Shorthand: "t", Usage: "Tool name for temporary instance", Type: cmdcore.FlagString, Workflow: true},
 			{Name: "tool-id", Usage: "Tool ID for temporary instance", Type: cmdcore.FlagString, Workflow: true},
+			{Name: "pool", Usage: "Lease the temporary instance from a warm pool created with agr pool create", Type: cmdcore.FlagString, Workflow: true},
+			{Name: "session", Usage: "Reuse one temporary instance across invocations with this session name (default: $AGR_SESSION)", Type: cmdcore.FlagString, Workflow: true},
+			{Name: "session-ttl", Usage: "Idle time after which a new session's instance is cleaned up and replaced", Type: cmdcore.FlagString, Default: "30m", Workflow: true},
+			{Name: "collect", Usage: "Download remote files matching <remote-glob>[:<local-dir>] after execution, before cleanup (repeatable)", Type: cmdcore.FlagStringArray, Workflow: true},
 		},
 		SupportsJSON:   true,
 		SupportsNDJSON: true,
@@ -115,8 +121,9 @@ func runCode(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 	}
 	if testDP := cli.TestDataPlane(); testDP != nil && !opts.Stream {
 		stdout, stderrText, results, remoteErr, count, err := testDP.RunCode(ctx, instanceID, codeStr, opts.Language)
+		resolved.CollectArtifacts(ctx, cli.TestDataPlaneArtifactSource(testDP, instanceID))
 		if err != nil {
-			resolved.CleanupForPreExecutionFailure()
+			resolved.Cleanup(false)
 			return nil, err
 		}
 		data := &output.CodeRunData{Stdout: stdout, Stderr: stderrText, Results: results, Error: remoteErr, ExecutionCount: count, ExecutionContext: resolved.ExecContext}
@@ -148,12 +155,14 @@ func runCode(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 	}
 
 	runConfig := &toolcode.RunCodeConfig{Language: opts.Language}
+	artifacts := cli.SandboxArtifactSource(sandbox, cli.ResolveUser(""))
 	if opts.Stream {
 		callbacks := &toolcode.OnOutputConfig{
 			OnStdout: func(s string) { fmt.Fprint(deps.IO.Out, s) },
 			OnStderr: func(s string) { fmt.Fprint(deps.IO.ErrOut, s) },
 		}
 		result, err := sandbox.Code.RunCode(ctx, codeStr, runConfig, callbacks)
+		resolved.CollectArtifacts(ctx, artifacts)
 		if err != nil {
 			resolved.Cleanup(false)
 			return nil, err
@@ -171,6 +180,7 @@ func runCode(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 	}
 
 	result, err := sandbox.Code.RunCode(ctx, codeStr, runConfig, nil)
+	resolved.CollectArtifacts(ctx, artifacts)
 	if err != nil {
 		resolved.Cleanup(false)
 		return nil, fmt.Errorf("failed to execute code: %w", err)
@@ -236,9 +246,10 @@ func runCodeStreamNDJSON(ctx context.Context, deps cmdcore.Deps, opts codeOption
 		OnStderr: func(s string) { _ = nw.WriteStderr(s) },
 	}
 	result, err := sandbox.Code.RunCode(ctx, codeStr, runConfig, callbacks)
+	resolved.CollectArtifacts(ctx, cli.SandboxArtifactSource(sandbox, cli.ResolveUser("")))
 	if err != nil {
 		cliErr := cli.ClassifyCLIError(err)
-		resolved.CleanupForPreExecutionFailure()
+		resolved.Cleanup(false)
 		_ = nw.WriteFailed(map[string]any{"ExecutionContext": resolved.ExecContext}, cliErr.Failure)
 		return &cmdcore.Result{StreamDone: true, ExitCode: cliErr.ExitCode}, nil
 	}
@@ -302,6 +313,10 @@ func codeOptionsFromRequest(req cmdcore.Request) codeOptions {
 			Cleanup:            stringFlag(req, "cleanup"),
 			ToolName:           stringFlag(req, "tool-name"),
 			ToolID:             stringFlag(req, "tool-id"),
+			Pool:               stringFlag(req, "pool"),
+			Session:            stringFlag(req, "session"),
+			SessionTTL:         stringFlag(req, "session-ttl"),
+			Collect:            stringsFlag(req, "collect"),
 		},
 	}
 }
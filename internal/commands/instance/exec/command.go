@@ -26,15 +26,25 @@ func Module() cmdcore.Module {
 Provide an instance id, or use --create-temp-instance to spin up a
 temporary sandbox for this single execution.
 
+Use --instances or --tool-id with --all-running to run the same command on
+several existing instances in parallel. Text output is prefixed with the
+instance id; JSON output lists one result per instance and reports a partial
+status when the command failed on some of them.
+
 Use '--' to separate flags from the remote command.
 
 Examples:
   agr instance exec ins-xxxx -- ls -la
   agr instance exec ins-xxxx -s -- ping -c 5 localhost
   agr instance exec ins-xxxx --env FOO=bar -- echo $FOO
+  agr instance exec ins-xxxx --collect '/tmp/out/*.png:./artifacts' -- python render.py
   # Create the tool first, then reuse its name or id here.
   agr instance exec --create-temp-instance --tool-name my-tool -- python -V
-  agr instance exec --create-temp-instance --tool-id sdt-xxxx --cleanup never -- bash`,
+  agr instance exec --create-temp-instance --tool-id sdt-xxxx --cleanup never -- bash
+  agr instance exec --create-temp-instance --pool py -- python -V
+  agr instance exec --create-temp-instance --tool-id sdt-xxxx --session build -- make test
+  agr instance exec --instances ins-a,ins-b -- uptime
+  agr instance exec --tool-id sdt-xxxx --all-running --max-parallel 8 -s -- git pull`,
 		Args: []cmdcore.ArgSpec{
 			{Name: "args", Repeatable: true, Description: "Optional instance id followed by remote command arguments."},
 		},
@@ -47,6 +57,13 @@ Examples:
 			{Name: "cleanup", Usage: "Cleanup policy for temporary instance: always|success|never", Type: cmdcore.FlagString, Default: "always", Workflow: true},
 			{Name: "tool-name", Shorthand: "t", Usage: "Tool name for temporary instance", Type: cmdcore.FlagString, Workflow: true},
 			{Name: "tool-id", Usage: "Tool ID for temporary instance", Type: cmdcore.FlagString, Workflow: true},
+			{Name: "pool", Usage: "Lease the temporary instance from a warm pool created with agr pool create", Type: cmdcore.FlagString, Workflow: true},
+			{Name: "session", Usage: "Reuse one temporary instance across invocations with this session name (default: $AGR_SESSION)", Type: cmdcore.FlagString, Workflow: true},
+			{Name: "session-ttl", Usage: "Idle time after which a new session's instance is cleaned up and replaced", Type: cmdcore.FlagString, Default: "30m", Workflow: true},
+			{Name: "collect", Usage: "Download remote files matching <remote-glob>[:<local-dir>] after execution, before cleanup (repeatable)", Type: cmdcore.FlagStringArray, Workflow: true},
+			{Name: "instances", Usage: "Run on several existing instances (comma-separated ids)", Type: cmdcore.FlagString, Workflow: true},
+			{Name: "all-running", Usage: "Run on every running instance of --tool-id", Type: cmdcore.FlagBool, Workflow: true},
+			{Name: "max-parallel", Usage: "Maximum concurrent executions with --instances or --all-running", Type: cmdcore.FlagInt, Default: "4", Workflow: true},
 		},
 		SupportsJSON:   true,
 		SupportsNDJSON: true,
@@ -100,6 +117,11 @@ func runExec(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 			"Use '--' to separate the remote command from flags.",
 		)
 	}
+	if fanoutRequested(opts) {
+		if err := validateFanout(opts, instanceArgs); err != nil {
+			return nil, err
+		}
+	}
 	cmdStr := shellJoin(remoteArgs)
 	envs, err := parseExecEnv(opts.Env)
 	if err != nil {
@@ -108,6 +130,13 @@ func runExec(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 	if err := config.Validate(); err != nil {
 		return nil, err
 	}
+	if fanoutRequested(opts) {
+		ids, err := fanoutTargets(ctx, opts, deps)
+		if err != nil {
+			return nil, err
+		}
+		return runExecFanout(ctx, deps, opts, ids, cmdStr, remoteArgs, envs)
+	}
 	resolved, err := cli.ResolveOverlay(ctx, opts.Overlay, instanceArgs, cli.OverlayCloudCreate, cli.OverlayCloudDelete)
 	if err != nil {
 		return nil, err
@@ -120,8 +149,9 @@ func runExec(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 
 	if testDP := cli.TestDataPlane(); testDP != nil && !opts.Stream {
 		stdout, stderrText, exitCode, remoteErr, err := testDP.Exec(ctx, instanceID, remoteArgs)
+		resolved.CollectArtifacts(ctx, cli.TestDataPlaneArtifactSource(testDP, instanceID))
 		if err != nil {
-			resolved.CleanupForPreExecutionFailure()
+			resolved.Cleanup(false)
 			return nil, err
 		}
 		data := &output.ExecData{Stdout: stdout, Stderr: stderrText, ExitCode: exitCode, Error: remoteErr, ExecutionContext: resolved.ExecContext}
@@ -149,6 +179,7 @@ func runExec(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 	if opts.Cwd != "" {
 		procConfig.Cwd = &opts.Cwd
 	}
+	artifacts := cli.SandboxArtifactSource(sandbox, procConfig.User)
 
 	if opts.Stream {
 		callbacks := &sdkcommand.OnOutputConfig{
@@ -156,6 +187,7 @@ func runExec(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 			OnStderr: func(data []byte) { fmt.Fprint(deps.IO.ErrOut, string(data)) },
 		}
 		result, err := sandbox.Commands.Run(ctx, cmdStr, procConfig, callbacks)
+		resolved.CollectArtifacts(ctx, artifacts)
 		if err != nil {
 			resolved.Cleanup(false)
 			return nil, fmt.Errorf("failed to execute command: %w", err)
@@ -165,6 +197,7 @@ func runExec(ctx context.Context, req cmdcore.Request, deps cmdcore.Deps) (*cmdc
 	}
 
 	result, err := sandbox.Commands.Run(ctx, cmdStr, procConfig, nil)
+	resolved.CollectArtifacts(ctx, artifacts)
 	if err != nil {
 		resolved.Cleanup(false)
 		return nil, fmt.Errorf("failed to execute command: %w", err)
@@ -234,6 +267,7 @@ func runExecStreamNDJSON(ctx context.Context, deps cmdcore.Deps, opts execOption
 		OnStderr: func(data []byte) { _ = nw.WriteStderr(string(data)) },
 	}
 	result, err := sandbox.Commands.Run(ctx, cmdStr, procConfig, callbacks)
+	resolved.CollectArtifacts(ctx, cli.SandboxArtifactSource(sandbox, procConfig.User))
 	if err != nil {
 		cliErr := cli.ClassifyCLIError(err)
 		resolved.Cleanup(false)
@@ -254,24 +288,34 @@ func runExecStreamNDJSON(ctx context.Context, deps cmdcore.Deps, opts execOption
 }
 
 type execOptions struct {
-	Stream  bool
-	Cwd     string
-	Env     []string
-	User    string
-	Overlay cli.OverlayFlags
+	Stream      bool
+	Cwd         string
+	Env         []string
+	User        string
+	Instances   string
+	AllRunning  bool
+	MaxParallel int
+	Overlay     cli.OverlayFlags
 }
 
 func execOptionsFromRequest(req cmdcore.Request) execOptions {
 	return execOptions{
-		Stream: boolFlag(req, "stream"),
-		Cwd:    stringFlag(req, "cwd"),
-		Env:    stringsFlag(req, "env"),
-		User:   stringFlag(req, "user"),
+		Stream:      boolFlag(req, "stream"),
+		Cwd:         stringFlag(req, "cwd"),
+		Env:         stringsFlag(req, "env"),
+		User:        stringFlag(req, "user"),
+		Instances:   stringFlag(req, "instances"),
+		AllRunning:  boolFlag(req, "all-running"),
+		MaxParallel: maxParallelFlag(req),
 		Overlay: cli.OverlayFlags{
 			CreateTempInstance: boolFlag(req, "create-temp-instance"),
 			Cleanup:            stringFlag(req, "cleanup"),
 			ToolName:           stringFlag(req, "tool-name"),
 			ToolID:             stringFlag(req, "tool-id"),
+			Pool:               stringFlag(req, "pool"),
+			Session:            stringFlag(req, "session"),
+			SessionTTL:         stringFlag(req, "session-ttl"),
+			Collect:            stringsFlag(req, "collect"),
 		},
 	}
 }
@@ -330,3 +374,11 @@ func boolFlag(req cmdcore.Request, name string) bool {
 	flag, ok := req.Flags[name]
 	return ok && flag.Bool
 }
+
+func maxParallelFlag(req cmdcore.Request) int {
+	flag, ok := req.Flags["max-parallel"]
+	if !ok || !flag.Changed {
+		return defaultMaxParallel
+	}
+	return flag.Int
+}
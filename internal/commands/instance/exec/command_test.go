@@ -3,11 +3,18 @@ package exec
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/session"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
@@ -86,6 +93,182 @@ func TestRunExecWithTestDataPlaneAndTempOverlay(t *testing.T) {
 	}
 }
 
+func TestRunExecCollectsArtifactsBeforeTempCleanup(t *testing.T) {
+	setupConfig(t)
+	dp := &fakeExecDataPlane{}
+	defer cli.SetTestDataPlaneForTest(dp)()
+	defer cli.SetCloudStartSandboxInstanceForTest(func(context.Context, *ags.Client, *ags.StartSandboxInstanceRequest) (*ags.StartSandboxInstanceResponseParams, error) {
+		id := "ins-temp-exec"
+		return &ags.StartSandboxInstanceResponseParams{Instance: &ags.SandboxInstance{InstanceId: &id}}, nil
+	})()
+	defer cli.SetCloudStopSandboxInstanceForTest(func(context.Context, *ags.Client, *ags.StopSandboxInstanceRequest) (*ags.StopSandboxInstanceResponseParams, error) {
+		if len(dp.downloads) != 1 {
+			t.Fatalf("temporary instance deleted before artifacts were collected: %v", dp.downloads)
+		}
+		return &ags.StopSandboxInstanceResponseParams{}, nil
+	})()
+
+	localDir := filepath.Join(t.TempDir(), "out")
+	ios, _, _, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:    []string{"ls"},
+		DashPos: 0,
+		Flags: map[string]command.FlagValue{
+			"create-temp-instance": {Name: "create-temp-instance", Type: command.FlagBool, Bool: true, Changed: true},
+			"tool-name":            {Name: "tool-name", Type: command.FlagString, String: "code-interpreter-v1", Changed: true},
+			"collect":              {Name: "collect", Type: command.FlagStringArray, Strings: []string{"/tmp/report.txt:" + localDir}, Changed: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	ec := result.Data.(*output.ExecData).ExecutionContext.(*cli.ExecutionContext)
+	if len(ec.Artifacts) != 1 || ec.Artifacts[0].Status != "downloaded" || ec.Artifacts[0].Size != 8 {
+		t.Fatalf("artifacts=%#v", ec.Artifacts)
+	}
+	if content, err := os.ReadFile(filepath.Join(localDir, "report.txt")); err != nil || string(content) != "artifact" {
+		t.Fatalf("collected content=%q err=%v", content, err)
+	}
+}
+
+func TestRunExecCollectsArtifactsWhenExecFails(t *testing.T) {
+	setupConfig(t)
+	dp := &fakeExecDataPlane{execErr: errors.New("connection reset")}
+	defer cli.SetTestDataPlaneForTest(dp)()
+
+	ios, _, _, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:    []string{"ins-1", "ls"},
+		DashPos: 1,
+		Flags: map[string]command.FlagValue{
+			"collect": {Name: "collect", Type: command.FlagStringArray, Strings: []string{"/tmp/report.txt:" + t.TempDir()}, Changed: true},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("error = %v, want connection reset", err)
+	}
+	if len(dp.downloads) != 1 {
+		t.Fatalf("artifacts were not collected after the failed exec: %v", dp.downloads)
+	}
+}
+
+func TestRunExecLeasesTempInstanceFromPool(t *testing.T) {
+	setupConfig(t)
+	store := pool.NewStoreAt(filepath.Join(t.TempDir(), "pools.json"))
+	if _, err := store.Upsert(pool.Pool{Name: "py", ToolID: "sdt-1", Size: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Add("py", pool.Instance{InstanceID: "ins-pooled"}); err != nil {
+		t.Fatal(err)
+	}
+	replenished := ""
+	defer cli.SetPoolHooksForTest(store,
+		func(context.Context, string) (bool, error) { return true, nil },
+		func(name string) error { replenished = name; return nil })()
+	defer cli.SetCloudStartSandboxInstanceForTest(func(context.Context, *ags.Client, *ags.StartSandboxInstanceRequest) (*ags.StartSandboxInstanceResponseParams, error) {
+		t.Fatal("pooled execution must not start a new instance")
+		return nil, nil
+	})()
+	tagged := map[string]string{}
+	defer cli.SetCloudUpdateSandboxInstanceForTest(func(_ context.Context, _ *ags.Client, req *ags.UpdateSandboxInstanceRequest) (*ags.UpdateSandboxInstanceResponseParams, error) {
+		for _, m := range req.Metadata {
+			tagged[stringValue(m.Name)] = stringValue(m.Value)
+		}
+		return &ags.UpdateSandboxInstanceResponseParams{}, nil
+	})()
+	deleted := ""
+	defer cli.SetCloudStopSandboxInstanceForTest(func(_ context.Context, _ *ags.Client, req *ags.StopSandboxInstanceRequest) (*ags.StopSandboxInstanceResponseParams, error) {
+		deleted = stringValue(req.InstanceId)
+		return &ags.StopSandboxInstanceResponseParams{}, nil
+	})()
+	dp := &fakeExecDataPlane{}
+	defer cli.SetTestDataPlaneForTest(dp)()
+
+	ios, _, _, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:    []string{"ls"},
+		DashPos: 0,
+		Flags: map[string]command.FlagValue{
+			"create-temp-instance": {Name: "create-temp-instance", Type: command.FlagBool, Bool: true, Changed: true},
+			"pool":                 {Name: "pool", Type: command.FlagString, String: "py", Changed: true},
+			"cleanup":              {Name: "cleanup", Type: command.FlagString, String: string(cli.CleanupAlways)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if dp.gotInstanceID != "ins-pooled" || deleted != "ins-pooled" || replenished != "py" {
+		t.Fatalf("exec=%s deleted=%s replenished=%s", dp.gotInstanceID, deleted, replenished)
+	}
+	ec := result.Data.(*output.ExecData).ExecutionContext.(*cli.ExecutionContext)
+	if ec.Pool != "py" {
+		t.Fatalf("execution context pool=%q", ec.Pool)
+	}
+	if tagged["agr-pool"] != "py" || tagged["agr-pid"] == "" || tagged["agr-cleanup"] != "always" {
+		t.Fatalf("lease metadata = %v", tagged)
+	}
+}
+
+func TestRunExecReusesSessionInstance(t *testing.T) {
+	setupConfig(t)
+	t.Setenv("AGR_SESSION", "build")
+	store := session.NewStoreAt(filepath.Join(t.TempDir(), "sessions.json"))
+	defer cli.SetSessionHooksForTest(store, func(context.Context, string) (bool, error) { return true, nil })()
+	creates := 0
+	defer cli.SetCloudStartSandboxInstanceForTest(func(context.Context, *ags.Client, *ags.StartSandboxInstanceRequest) (*ags.StartSandboxInstanceResponseParams, error) {
+		creates++
+		id := "ins-session"
+		return &ags.StartSandboxInstanceResponseParams{Instance: &ags.SandboxInstance{InstanceId: &id}}, nil
+	})()
+	defer cli.SetCloudStopSandboxInstanceForTest(func(context.Context, *ags.Client, *ags.StopSandboxInstanceRequest) (*ags.StopSandboxInstanceResponseParams, error) {
+		t.Fatal("session executions must not delete the instance")
+		return nil, nil
+	})()
+	dp := &fakeExecDataPlane{}
+	defer cli.SetTestDataPlaneForTest(dp)()
+
+	ios, _, _, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	var ec *cli.ExecutionContext
+	for i := 0; i < 2; i++ {
+		result, err := runtime.Handler.Run(context.Background(), command.Request{
+			Args:    []string{"ls"},
+			DashPos: 0,
+			Flags: map[string]command.FlagValue{
+				"create-temp-instance": {Name: "create-temp-instance", Type: command.FlagBool, Bool: true, Changed: true},
+				"tool-id":              {Name: "tool-id", Type: command.FlagString, String: "sdt-1", Changed: true},
+				"cleanup":              {Name: "cleanup", Type: command.FlagString, String: string(cli.CleanupAlways)},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Run %d returned error: %v", i, err)
+		}
+		ec = result.Data.(*output.ExecData).ExecutionContext.(*cli.ExecutionContext)
+	}
+	if creates != 1 || dp.gotInstanceID != "ins-session" || !ec.SessionReused || ec.Session != "build" {
+		t.Fatalf("creates=%d instance=%s ctx=%+v", creates, dp.gotInstanceID, ec)
+	}
+	sess, ok, _ := store.Get("build")
+	if !ok || sess.Executions != 2 || sess.Cleanup != "always" {
+		t.Fatalf("session=%+v ok=%v", sess, ok)
+	}
+}
+
 func TestRunExecRejectsMissingSeparator(t *testing.T) {
 	setupConfig(t)
 	runtime, err := Module().Build(command.Deps{})
@@ -152,6 +335,172 @@ func TestRunExecLocalValidationHappensBeforeTempOverlay(t *testing.T) {
 	}
 }
 
+func TestRunExecFansOutAndReportsPartialFailure(t *testing.T) {
+	setupConfig(t)
+	dp := &fanoutDataPlane{exitCodes: map[string]int{"ins-b": 3}}
+	defer cli.SetTestDataPlaneForTest(dp)()
+
+	ios, _, stdout, stderr := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:    []string{"hostname"},
+		DashPos: 0,
+		Flags: map[string]command.FlagValue{
+			"instances":    {Name: "instances", Type: command.FlagString, String: "ins-a, ins-b,ins-c,ins-a", Changed: true},
+			"max-parallel": {Name: "max-parallel", Type: command.FlagInt, Int: 2, Changed: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if dp.maxInFlight > 2 {
+		t.Fatalf("max in flight = %d, want <= 2", dp.maxInFlight)
+	}
+	if result.ExitCode != output.ExitPartialSuccess || result.Failure == nil || result.Failure.Kind != output.KindPartialSuccess {
+		t.Fatalf("result = %#v", result)
+	}
+	data := result.Data.(map[string]any)
+	items := data["Items"].([]InstanceResult)
+	if len(items) != 3 || items[0].InstanceId != "ins-a" || items[1].Status != "failed" || items[1].ExitCode != 3 || items[2].Status != "succeeded" {
+		t.Fatalf("items = %#v", items)
+	}
+	if ids := data["FailedIds"].([]string); len(ids) != 1 || ids[0] != "ins-b" {
+		t.Fatalf("failed ids = %#v", ids)
+	}
+	result.Text(stdout)
+	if stdout.String() != "[ins-a] ins-a\n[ins-b] ins-b\n[ins-c] ins-c\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "command failed on 1 of 3 instance(s): ins-b") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestRunExecFanoutStreamPrintsInstanceErrors(t *testing.T) {
+	setupConfig(t)
+	dp := &fanoutDataPlane{errs: map[string]error{"ins-b": errors.New("connection refused")}}
+	defer cli.SetTestDataPlaneForTest(dp)()
+
+	ios, _, stdout, stderr := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:    []string{"hostname"},
+		DashPos: 0,
+		Flags: map[string]command.FlagValue{
+			"instances": {Name: "instances", Type: command.FlagString, String: "ins-a,ins-b", Changed: true},
+			"stream":    {Name: "stream", Type: command.FlagBool, Bool: true, Changed: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone || result.ExitCode != output.ExitPartialSuccess {
+		t.Fatalf("result = %#v", result)
+	}
+	if stdout.String() != "[ins-a] ins-a\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "[ins-b] error: connection refused\n") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestRunExecAllRunningSelectsToolInstances(t *testing.T) {
+	setupConfig(t)
+	dp := &fanoutDataPlane{}
+	defer cli.SetTestDataPlaneForTest(dp)()
+
+	running, stopped, tool, other := "RUNNING", "STOPPED", "sdt-1", "sdt-2"
+	cp := &fakeLister{instances: []*ags.SandboxInstance{
+		{InstanceId: strPtr("ins-2"), ToolId: &tool, Status: &running},
+		{InstanceId: strPtr("ins-1"), ToolId: &tool, Status: &running},
+		{InstanceId: strPtr("ins-3"), ToolId: &tool, Status: &stopped},
+		{InstanceId: strPtr("ins-4"), ToolId: &other, Status: &running},
+	}}
+	ios, _, _, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios, ControlPlane: cp})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:    []string{"true"},
+		DashPos: 0,
+		Flags: map[string]command.FlagValue{
+			"tool-id":     {Name: "tool-id", Type: command.FlagString, String: tool, Changed: true},
+			"all-running": {Name: "all-running", Type: command.FlagBool, Bool: true, Changed: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.Failure != nil {
+		t.Fatalf("failure = %#v", result.Failure)
+	}
+	items := result.Data.(map[string]any)["Items"].([]InstanceResult)
+	if len(items) != 2 || items[0].InstanceId != "ins-1" || items[1].InstanceId != "ins-2" {
+		t.Fatalf("items = %#v", items)
+	}
+}
+
+func TestRunExecFanoutValidation(t *testing.T) {
+	setupConfig(t)
+	runtime, err := Module().Build(command.Deps{})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	cases := []struct {
+		name  string
+		args  []string
+		flags map[string]command.FlagValue
+		code  string
+	}{
+		{"all-running without tool", []string{"true"}, map[string]command.FlagValue{
+			"all-running": {Name: "all-running", Type: command.FlagBool, Bool: true, Changed: true},
+		}, "MISSING_REQUIRED_FLAG"},
+		{"tool id with instances", []string{"true"}, map[string]command.FlagValue{
+			"instances": {Name: "instances", Type: command.FlagString, String: "ins-a", Changed: true},
+			"tool-id":   {Name: "tool-id", Type: command.FlagString, String: "sdt-1", Changed: true},
+		}, "CONFLICTING_FLAGS"},
+		{"positional id", []string{"ins-x", "true"}, map[string]command.FlagValue{
+			"instances": {Name: "instances", Type: command.FlagString, String: "ins-a", Changed: true},
+		}, "CONFLICTING_FLAGS"},
+		{"temp instance", []string{"true"}, map[string]command.FlagValue{
+			"instances":            {Name: "instances", Type: command.FlagString, String: "ins-a", Changed: true},
+			"create-temp-instance": {Name: "create-temp-instance", Type: command.FlagBool, Bool: true, Changed: true},
+		}, "CONFLICTING_FLAGS"},
+		{"max parallel", []string{"true"}, map[string]command.FlagValue{
+			"instances":    {Name: "instances", Type: command.FlagString, String: "ins-a", Changed: true},
+			"max-parallel": {Name: "max-parallel", Type: command.FlagInt, Int: 0, Changed: true},
+		}, "INVALID_MAX_PARALLEL"},
+	}
+	for _, tc := range cases {
+		dashPos := len(tc.args) - 1
+		_, err := runtime.Handler.Run(context.Background(), command.Request{Args: tc.args, DashPos: dashPos, Flags: tc.flags})
+		var cliErr *output.CLIError
+		if !errors.As(err, &cliErr) || cliErr.Failure.Code != tc.code {
+			t.Errorf("%s: err = %v, want %s", tc.name, err, tc.code)
+		}
+	}
+}
+
+func TestPrefixWriterBuffersPartialLines(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	w := newPrefixWriter(&buf, &mu, "ins-a")
+	_, _ = w.Write([]byte("hel"))
+	_, _ = w.Write([]byte("lo\nwor"))
+	w.Flush()
+	if buf.String() != "[ins-a] hello\n[ins-a] wor\n" {
+		t.Fatalf("output = %q", buf.String())
+	}
+}
+
 func TestShellJoinPreservesArgumentBoundaries(t *testing.T) {
 	got := shellJoin([]string{"printf", "%s\n", "a b", "quote's"})
 	want := `'printf' '%s
@@ -176,6 +525,8 @@ func TestParseExecEnv(t *testing.T) {
 
 type fakeExecDataPlane struct {
 	gotInstanceID string
+	downloads     []string
+	execErr       error
 }
 
 func (f *fakeExecDataPlane) RunCode(context.Context, string, string, string) (string, string, any, any, int, error) {
@@ -184,6 +535,9 @@ func (f *fakeExecDataPlane) RunCode(context.Context, string, string, string) (st
 
 func (f *fakeExecDataPlane) Exec(_ context.Context, instanceID string, _ []string) (string, string, int, any, error) {
 	f.gotInstanceID = instanceID
+	if f.execErr != nil {
+		return "", "", 0, nil, f.execErr
+	}
 	return "ok\n", "", 0, nil, nil
 }
 
@@ -191,8 +545,9 @@ func (f *fakeExecDataPlane) Upload(context.Context, string, string, string, io.R
 	return "", 0, nil
 }
 
-func (f *fakeExecDataPlane) Download(context.Context, string, string) (io.Reader, int64, error) {
-	return nil, 0, nil
+func (f *fakeExecDataPlane) Download(_ context.Context, _ string, remotePath string) (io.Reader, int64, error) {
+	f.downloads = append(f.downloads, remotePath)
+	return strings.NewReader("artifact"), 8, nil
 }
 
 func setupConfig(t *testing.T) {
@@ -212,3 +567,39 @@ func stringValue(p *string) string {
 	}
 	return *p
 }
+
+type fanoutDataPlane struct {
+	fakeExecDataPlane
+	exitCodes   map[string]int
+	errs        map[string]error
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *fanoutDataPlane) Exec(_ context.Context, instanceID string, _ []string) (string, string, int, any, error) {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	f.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+	if err := f.errs[instanceID]; err != nil {
+		return "", "", 0, nil, err
+	}
+	return instanceID + "\n", "", f.exitCodes[instanceID], nil, nil
+}
+
+type fakeLister struct {
+	instances []*ags.SandboxInstance
+}
+
+func (f *fakeLister) ListInstances(context.Context) ([]*ags.SandboxInstance, error) {
+	return f.instances, nil
+}
+
+func strPtr(s string) *string { return &s }
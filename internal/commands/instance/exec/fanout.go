@@ -0,0 +1,320 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	sdkcommand "github.com/TencentCloudAgentRuntime/ags-go-sdk/tool/command"
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	cmdcore "github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// defaultMaxParallel bounds concurrent executions when --max-parallel is unset.
+const defaultMaxParallel = 4
+
+// InstanceLister lists sandbox instances for --all-running.
+type InstanceLister interface {
+	ListInstances(ctx context.Context) ([]*ags.SandboxInstance, error)
+}
+
+// InstanceResult is the outcome of the command on one instance of a fan-out
+// execution.
+type InstanceResult struct {
+	InstanceId string `json:"InstanceId"`
+	Status     string `json:"Status"` // succeeded | failed
+	Stdout     string `json:"Stdout"`
+	Stderr     string `json:"Stderr"`
+	ExitCode   int    `json:"ExitCode"`
+	Error      any    `json:"Error"`
+}
+
+// fanoutRequested reports whether the invocation targets several instances.
+func fanoutRequested(opts execOptions) bool {
+	return opts.Instances != "" || opts.AllRunning
+}
+
+// validateFanout rejects flag combinations that do not apply to a fan-out
+// execution. It runs before any instance is contacted.
+func validateFanout(opts execOptions, instanceArgs []string) error {
+	o := opts.Overlay
+	switch {
+	case opts.Instances != "" && opts.AllRunning:
+		return output.NewUsageError("CONFLICTING_FLAGS",
+			"--instances and --all-running are mutually exclusive",
+			"Pick exactly one.")
+	case opts.Instances != "" && o.ToolID != "":
+		return output.NewUsageError("CONFLICTING_FLAGS",
+			"--tool-id cannot be used together with --instances",
+			"Drop --tool-id, or use --all-running --tool-id sdt-xxxx to select every running instance of the tool.")
+	case opts.AllRunning && o.ToolID == "":
+		return output.NewUsageError("MISSING_REQUIRED_FLAG",
+			"--all-running requires --tool-id",
+			"Provide --tool-id sdt-xxxx to run on every running instance of that tool.")
+	case len(instanceArgs) > 0:
+		return output.NewUsageError("CONFLICTING_FLAGS",
+			"a positional instance id cannot be used together with --instances or --all-running",
+			"List every target instance in --instances, or drop the positional id.")
+	case o.CreateTempInstance || o.Pool != "" || o.Session != "" || len(o.Collect) > 0 || o.ToolName != "":
+		return output.NewUsageError("CONFLICTING_FLAGS",
+			"--instances and --all-running run on existing instances and cannot be combined with temporary-instance flags",
+			"Drop --create-temp-instance, --tool-name, --pool, --session and --collect.")
+	case opts.MaxParallel < 1:
+		return output.NewUsageError("INVALID_MAX_PARALLEL",
+			fmt.Sprintf("--max-parallel must be >= 1 (got %d)", opts.MaxParallel),
+			"Use --max-parallel 1 to run on one instance at a time.")
+	case opts.Stream && cli.IsNDJSON():
+		return output.NewUsageError("CONFLICTING_FLAGS",
+			"-o ndjson --stream is not supported with --instances or --all-running",
+			"Use --stream with text output, or -o json without --stream.")
+	}
+	return nil
+}
+
+// fanoutTargets returns the instances selected by --instances or
+// --all-running, in a stable order.
+func fanoutTargets(ctx context.Context, opts execOptions, deps cmdcore.Deps) ([]string, error) {
+	if opts.Instances != "" {
+		seen := map[string]bool{}
+		var ids []string
+		for _, id := range strings.Split(opts.Instances, ",") {
+			id = strings.TrimSpace(id)
+			if id != "" && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil, output.NewUsageError("MISSING_INSTANCE",
+				"--instances lists no instance ids",
+				"Use --instances ins-a,ins-b.")
+		}
+		return ids, nil
+	}
+	lister, ok := deps.ControlPlane.(InstanceLister)
+	if !ok {
+		return nil, fmt.Errorf("instance.exec --all-running requires command.Deps.ControlPlane implementing instance/exec.InstanceLister")
+	}
+	instances, err := lister.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	toolID := opts.Overlay.ToolID
+	var ids []string
+	for _, inst := range instances {
+		if inst == nil || inst.InstanceId == nil || deref(inst.ToolId) != toolID || deref(inst.Status) != "RUNNING" {
+			continue
+		}
+		ids = append(ids, *inst.InstanceId)
+	}
+	if len(ids) == 0 {
+		return nil, output.NewNotFoundError("INSTANCE_NOT_FOUND",
+			fmt.Sprintf("no running instances of tool %s", toolID),
+			"Run 'agr instance list' to find active instances.")
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// runExecFanout runs the command on every target instance with at most
+// --max-parallel executions in flight.
+func runExecFanout(ctx context.Context, deps cmdcore.Deps, opts execOptions, ids []string, cmdStr string, remoteArgs []string, envs map[string]string) (*cmdcore.Result, error) {
+	var mu sync.Mutex // serializes prefixed stream output
+	results := make([]InstanceResult, len(ids))
+	sem := make(chan struct{}, opts.MaxParallel)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			var stdout, stderr io.Writer
+			if opts.Stream {
+				stdout = newPrefixWriter(deps.IO.Out, &mu, id)
+				stderr = newPrefixWriter(deps.IO.ErrOut, &mu, id)
+			}
+			results[i] = execOne(ctx, opts, id, cmdStr, remoteArgs, envs, stdout, stderr)
+			if opts.Stream {
+				stdout.(*prefixWriter).Flush()
+				stderr.(*prefixWriter).Flush()
+				if err := results[i].Error; err != nil {
+					fmt.Fprintf(stderr, "error: %v\n", errorMessage(err))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var failedIDs []string
+	for _, r := range results {
+		if r.Status != "succeeded" {
+			failedIDs = append(failedIDs, r.InstanceId)
+		}
+	}
+	summary := fmt.Sprintf("command failed on %d of %d instance(s): %s\n", len(failedIDs), len(results), strings.Join(failedIDs, ", "))
+	result := &cmdcore.Result{
+		Data: map[string]any{
+			"Items":     results,
+			"Total":     len(results),
+			"Succeeded": len(results) - len(failedIDs),
+			"Failed":    len(failedIDs),
+			"FailedIds": failedIDs,
+		},
+		Text: func(w io.Writer) {
+			for _, r := range results {
+				writePrefixed(w, r.InstanceId, r.Stdout)
+				writePrefixed(deps.IO.ErrOut, r.InstanceId, r.Stderr)
+				if r.Error != nil {
+					fmt.Fprintf(deps.IO.ErrOut, "[%s] error: %v\n", r.InstanceId, errorMessage(r.Error))
+				}
+			}
+			if len(failedIDs) > 0 {
+				fmt.Fprint(deps.IO.ErrOut, summary)
+			}
+		},
+	}
+	if len(failedIDs) > 0 {
+		result.Failure = &output.Failure{
+			Code:    "PARTIAL_EXEC_FAILED",
+			Kind:    output.KindPartialSuccess,
+			Message: fmt.Sprintf("command failed on %d of %d instances", len(failedIDs), len(results)),
+			Hint:    "Inspect Data.Items for per-instance exit codes and retry with --instances set to Data.FailedIds.",
+		}
+		result.ExitCode = output.ExitPartialSuccess
+	}
+	if opts.Stream {
+		// Output was already written line by line; only the summary remains.
+		if len(failedIDs) > 0 {
+			fmt.Fprint(deps.IO.ErrOut, summary)
+		}
+		return &cmdcore.Result{StreamDone: true, ExitCode: result.ExitCode}, nil
+	}
+	return result, nil
+}
+
+// execOne runs the command on one instance. When stdout and stderr are set,
+// output is streamed to them as it arrives.
+func execOne(ctx context.Context, opts execOptions, instanceID, cmdStr string, remoteArgs []string, envs map[string]string, stdout, stderr io.Writer) InstanceResult {
+	out := InstanceResult{InstanceId: instanceID, Status: "failed"}
+	if testDP := cli.TestDataPlane(); testDP != nil {
+		so, se, exitCode, remoteErr, err := testDP.Exec(ctx, instanceID, remoteArgs)
+		if err != nil {
+			out.Error = map[string]any{"Message": err.Error()}
+			return out
+		}
+		if stdout != nil {
+			fmt.Fprint(stdout, so)
+			fmt.Fprint(stderr, se)
+		}
+		out.Stdout, out.Stderr, out.ExitCode, out.Error = so, se, exitCode, remoteErr
+		if exitCode == 0 {
+			out.Status = "succeeded"
+		}
+		return out
+	}
+
+	sandbox, err := cli.ConnectSandboxWithCache(ctx, instanceID)
+	if err != nil {
+		out.Error = map[string]any{"Message": fmt.Sprintf("failed to connect to instance %s: %v", instanceID, err)}
+		return out
+	}
+	procConfig := &sdkcommand.ProcessConfig{User: cli.ResolveUser(opts.User), Envs: envs}
+	if opts.Cwd != "" {
+		procConfig.Cwd = &opts.Cwd
+	}
+	var callbacks *sdkcommand.OnOutputConfig
+	if stdout != nil {
+		callbacks = &sdkcommand.OnOutputConfig{
+			OnStdout: func(data []byte) { _, _ = stdout.Write(data) },
+			OnStderr: func(data []byte) { _, _ = stderr.Write(data) },
+		}
+	}
+	result, err := sandbox.Commands.Run(ctx, cmdStr, procConfig, callbacks)
+	if err != nil {
+		out.Error = map[string]any{"Message": fmt.Sprintf("failed to execute command: %v", err)}
+		return out
+	}
+	out.Stdout, out.Stderr, out.ExitCode = string(result.Stdout), string(result.Stderr), int(result.ExitCode)
+	if result.Error != nil {
+		out.Error = map[string]any{"Message": *result.Error}
+	}
+	if result.ExitCode == 0 {
+		out.Status = "succeeded"
+	}
+	return out
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func errorMessage(err any) any {
+	if m, ok := err.(map[string]any); ok {
+		if msg, ok := m["Message"]; ok {
+			return msg
+		}
+	}
+	return err
+}
+
+// writePrefixed writes every line of text to w prefixed with the instance ID.
+func writePrefixed(w io.Writer, instanceID, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n") {
+		fmt.Fprintf(w, "[%s] %s\n", instanceID, strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// prefixWriter prefixes complete lines with the instance ID, so output of
+// concurrent executions interleaves by line rather than by chunk.
+type prefixWriter struct {
+	w          io.Writer
+	mu         *sync.Mutex
+	instanceID string
+	buf        bytes.Buffer
+}
+
+func newPrefixWriter(w io.Writer, mu *sync.Mutex, instanceID string) *prefixWriter {
+	return &prefixWriter{w: w, mu: mu, instanceID: instanceID}
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf.Write(data)
+	for {
+		line, err := p.buf.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next chunk.
+			p.buf.Reset()
+			p.buf.WriteString(line)
+			return len(data), nil
+		}
+		p.writeLine(line)
+	}
+}
+
+// Flush writes a trailing line that did not end in a newline.
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() > 0 {
+		p.writeLine(p.buf.String() + "\n")
+		p.buf.Reset()
+	}
+}
+
+func (p *prefixWriter) writeLine(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "[%s] %s", p.instanceID, line)
+}
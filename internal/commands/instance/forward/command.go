@@ -0,0 +1,293 @@
+package forward
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/adbtunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tcpbridge"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Tunnel is one TCP-over-WebSocket bridge managed by the command.
+type Tunnel interface {
+	Start() (string, error)
+	Probe() error
+	Stop()
+}
+
+// Bridge is the WebSocket-to-TCP bridge running in the sandbox.
+type Bridge interface {
+	Stop(ctx context.Context) error
+}
+
+// RuntimeDeps contains token, bridge, tunnel construction, and wait hooks
+// that tests can replace without opening real network listeners.
+type RuntimeDeps struct {
+	AcquireToken    func(ctx context.Context, instanceID string) (string, error)
+	InvalidateToken func(instanceID string)
+	StartBridge     func(ctx context.Context, instanceID string, opts tcpbridge.Options) (Bridge, error)
+	NewTunnel       func(adbtunnel.TunnelOptions) (Tunnel, error)
+	Wait            func(context.Context)
+}
+
+// Module returns the "instance forward" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.forward",
+		Path:  []string{"instance", "forward"},
+		Use:   "forward <instance-id> --tcp [local_port:]<remote_port>",
+		Short: "Forward raw TCP ports of an instance to localhost",
+		Long: `Forward raw TCP ports of an instance to a local address.
+
+Unlike 'agr instance proxy', which speaks HTTP and WebSocket, forward carries
+arbitrary TCP streams (Postgres, Redis, gRPC, ...) over a WebSocket through the
+sandbox gateway. The gateway only speaks HTTP, so a small bridge is started in
+the sandbox (python3 is required) on --bridge-port; it connects each WebSocket
+to the sandbox port it names. Each --tcp mapping gets its own local listener.
+A connection whose access token is rejected is re-dialed once with a fresh
+token; after repeated failures the mapping is reported unreachable and probed
+in the background until the sandbox port answers again.
+
+Port Syntax:
+  <remote_port>                Forward remote port to the same local port
+  <local_port>:<remote_port>   Forward remote port to a specific local port`,
+		Examples: []string{
+			"agr instance forward ins-xxxx --tcp 5432",
+			"agr instance forward ins-xxxx --tcp 15432:5432 --tcp 6379",
+		},
+		Args: []command.ArgSpec{{Name: "instance-id", Required: true}},
+		Flags: []command.FlagSpec{
+			{Name: "tcp", Usage: "TCP port mapping [local_port:]remote_port (repeatable)", Type: command.FlagStringArray},
+			{Name: "address", Usage: "Local address to bind to", Type: command.FlagString, Default: "127.0.0.1"},
+			{Name: "bridge-port", Usage: "Sandbox port the TCP bridge listens on", Type: command.FlagInt, Default: tcpbridge.DefaultPort},
+		},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec: spec,
+			Groups: []command.GroupSpec{
+				{
+					Path:    []string{"instance"},
+					Use:     "instance",
+					Short:   "Manage sandbox instances",
+					Long:    "Manage sandbox instances and related data-plane workflows.",
+					Aliases: []string{"i"},
+				},
+			},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := runtimeDeps(deps.DataPlane)
+			return command.Runtime{
+				Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+					return runForward(ctx, req, deps, rt)
+				}),
+			}, nil
+		},
+	}
+}
+
+func runtimeDeps(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	if rt.AcquireToken == nil {
+		rt.AcquireToken = cli.AcquireInstanceToken
+	}
+	if rt.InvalidateToken == nil {
+		rt.InvalidateToken = cli.InvalidateInstanceToken
+	}
+	if rt.StartBridge == nil {
+		rt.StartBridge = func(ctx context.Context, instanceID string, opts tcpbridge.Options) (Bridge, error) {
+			sandbox, err := cli.ConnectSandboxWithCache(ctx, instanceID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect to instance: %w", err)
+			}
+			bridge, err := tcpbridge.Launch(ctx, sandbox, opts)
+			if err != nil {
+				return nil, err
+			}
+			return bridge, nil
+		}
+	}
+	if rt.NewTunnel == nil {
+		rt.NewTunnel = func(opts adbtunnel.TunnelOptions) (Tunnel, error) {
+			return adbtunnel.New(opts)
+		}
+	}
+	if rt.Wait == nil {
+		rt.Wait = waitForSignal
+	}
+	return rt
+}
+
+type mapping struct {
+	Local  int
+	Remote int
+}
+
+func runForward(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := req.ArgValues["instance-id"]
+	if instanceID == "" && len(req.Args) > 0 {
+		instanceID = req.Args[0]
+	}
+	specs := req.Flags["tcp"].Strings
+	if len(specs) == 0 {
+		return nil, output.NewUsageError("MISSING_REQUIRED_FLAG",
+			"agr instance forward requires --tcp",
+			"Run: agr instance forward <instance-id> --tcp 5432")
+	}
+	mappings := make([]mapping, 0, len(specs))
+	seen := map[int]bool{}
+	for _, spec := range specs {
+		local, remote, err := parsePortSpec(spec)
+		if err != nil {
+			return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid --tcp mapping: %v", err), "Use [local_port:]remote_port with values between 1 and 65535.")
+		}
+		if seen[local] {
+			return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid --tcp mapping: local port %d is used twice", local), "Give each mapping its own local port.")
+		}
+		seen[local] = true
+		mappings = append(mappings, mapping{Local: local, Remote: remote})
+	}
+	bridgePort := tcpbridge.DefaultPort
+	if flag, ok := req.Flags["bridge-port"]; ok && flag.Changed {
+		bridgePort = flag.Int
+	}
+	invalid := bridgePort <= 0 || bridgePort > 65535
+	remotes := make([]int, 0, len(mappings))
+	for _, m := range mappings {
+		remotes = append(remotes, m.Remote)
+		invalid = invalid || m.Remote == bridgePort
+	}
+	if invalid {
+		return nil, output.NewUsageError("INVALID_PORT",
+			fmt.Sprintf("invalid --bridge-port %d", bridgePort),
+			"Use a free sandbox port between 1 and 65535 that differs from the forwarded ports.")
+	}
+	address := req.Flags["address"].String
+	if address == "" {
+		address = "127.0.0.1"
+	}
+	if err := cli.ValidateListenAddress(address); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if address != "127.0.0.1" && address != "localhost" && address != "::1" {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: binding to %s exposes the forwarded ports to the network.\n", address)
+	}
+	domain := config.Get().DataPlaneRegionDomain()
+
+	bridge, err := rt.StartBridge(ctx, instanceID, tcpbridge.Options{
+		Port:   bridgePort,
+		Ports:  remotes,
+		Logger: log.New(deps.IO.ErrOut, "", log.LstdFlags),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start TCP bridge: %w", err)
+	}
+	var tunnels []Tunnel
+	stopAll := func() {
+		for _, t := range tunnels {
+			t.Stop()
+		}
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := bridge.Stop(stopCtx); err != nil {
+			fmt.Fprintf(deps.IO.ErrOut, "Warning: %v\n", err)
+		}
+	}
+	addrs := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		tunnel, err := rt.NewTunnel(adbtunnel.TunnelOptions{
+			InstanceID: instanceID,
+			Domain:     domain,
+			TokenProvider: func() (string, error) {
+				return rt.AcquireToken(ctx, instanceID)
+			},
+			InvalidateToken: func() {
+				rt.InvalidateToken(instanceID)
+			},
+			ListenAddress: net.JoinHostPort(address, strconv.Itoa(m.Local)),
+			RemotePort:    bridgePort,
+			Path:          tcpbridge.Path(m.Remote),
+			Label:         fmt.Sprintf("TCP %d", m.Remote),
+			GatewayAuth:   true,
+			OnStateChange: stateReporter(deps, m.Remote),
+		})
+		if err != nil {
+			stopAll()
+			return nil, fmt.Errorf("failed to create tunnel for port %d: %w", m.Remote, err)
+		}
+		addr, err := tunnel.Start()
+		if err != nil {
+			stopAll()
+			return nil, fmt.Errorf("failed to start tunnel for port %d: %w", m.Remote, err)
+		}
+		tunnels = append(tunnels, tunnel)
+		addrs = append(addrs, addr)
+		if err := tunnel.Probe(); err != nil {
+			fmt.Fprintf(deps.IO.ErrOut, "Warning: port %d is not reachable yet (%v); connections will be retried.\n", m.Remote, err)
+		}
+	}
+
+	tw := tabwriter.NewWriter(deps.IO.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOCAL\tREMOTE")
+	for i, m := range mappings {
+		fmt.Fprintf(tw, "%s\t%s:%d\n", addrs[i], instanceID, m.Remote)
+	}
+	_ = tw.Flush()
+	fmt.Fprintln(deps.IO.Out, "\nPress Ctrl+C to stop.")
+
+	rt.Wait(ctx)
+
+	fmt.Fprintln(deps.IO.Out, "\nStopping forward...")
+	stopAll()
+	return &command.Result{StreamDone: true}, nil
+}
+
+// stateReporter reports degraded and recovered transitions of one mapping.
+func stateReporter(deps command.Deps, remotePort int) func(adbtunnel.TunnelState) {
+	return func(state adbtunnel.TunnelState) {
+		if state == adbtunnel.StateDegraded {
+			fmt.Fprintf(deps.IO.ErrOut, "[WARN] port %d is unreachable; new connections are refused until it recovers\n", remotePort)
+			return
+		}
+		fmt.Fprintf(deps.IO.ErrOut, "[INFO] port %d is reachable again\n", remotePort)
+	}
+}
+
+func parsePortSpec(spec string) (int, int, error) {
+	localStr, remoteStr, found := strings.Cut(spec, ":")
+	if !found {
+		remoteStr = localStr
+	}
+	local, err := strconv.Atoi(localStr)
+	if err != nil || local <= 0 || local > 65535 {
+		return 0, 0, fmt.Errorf("local port must be between 1 and 65535, got %q", localStr)
+	}
+	remote, err := strconv.Atoi(remoteStr)
+	if err != nil || remote <= 0 || remote > 65535 {
+		return 0, 0, fmt.Errorf("remote port must be between 1 and 65535, got %q", remoteStr)
+	}
+	return local, remote, nil
+}
+
+func waitForSignal(ctx context.Context) {
+	waitCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-waitCtx.Done()
+}
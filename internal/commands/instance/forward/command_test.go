@@ -0,0 +1,190 @@
+package forward
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/adbtunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tcpbridge"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+)
+
+func TestRunForwardStartsOneTunnelPerMapping(t *testing.T) {
+	setupConfig(t)
+	var opts []adbtunnel.TunnelOptions
+	var fakes []*fakeTunnel
+	var bridgeOpts tcpbridge.Options
+	bridge := &fakeBridge{}
+	var invalidated []string
+	ios, _, stdout, stderr := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken:    func(context.Context, string) (string, error) { return "token", nil },
+			InvalidateToken: func(id string) { invalidated = append(invalidated, id) },
+			StartBridge: func(_ context.Context, instanceID string, o tcpbridge.Options) (Bridge, error) {
+				bridgeOpts = o
+				return bridge, nil
+			},
+			NewTunnel: func(o adbtunnel.TunnelOptions) (Tunnel, error) {
+				opts = append(opts, o)
+				fake := &fakeTunnel{addr: o.ListenAddress}
+				if o.Path == "/tcp/6379" {
+					fake.probeErr = errors.New("bad handshake")
+				}
+				fakes = append(fakes, fake)
+				return fake, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags: map[string]command.FlagValue{
+			"tcp":     {Name: "tcp", Type: command.FlagStringArray, Strings: []string{"15432:5432", "6379"}},
+			"address": {Name: "address", Type: command.FlagString, String: "127.0.0.1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone || len(opts) != 2 {
+		t.Fatalf("result=%#v opts=%#v", result, opts)
+	}
+	if bridgeOpts.Port != tcpbridge.DefaultPort || len(bridgeOpts.Ports) != 2 || bridgeOpts.Ports[0] != 5432 || bridgeOpts.Ports[1] != 6379 {
+		t.Fatalf("bridgeOpts=%#v", bridgeOpts)
+	}
+	if opts[0].ListenAddress != "127.0.0.1:15432" || opts[0].RemotePort != tcpbridge.DefaultPort || opts[0].Path != "/tcp/5432" || !opts[0].GatewayAuth || opts[1].Path != "/tcp/6379" {
+		t.Fatalf("opts=%#v", opts)
+	}
+	if token, err := opts[0].TokenProvider(); err != nil || token != "token" {
+		t.Fatalf("token=%q err=%v", token, err)
+	}
+	opts[0].InvalidateToken()
+	if len(invalidated) != 1 || invalidated[0] != "ins-1" {
+		t.Fatalf("invalidated=%v", invalidated)
+	}
+	for _, fake := range fakes {
+		if !fake.stopped {
+			t.Fatalf("tunnel %s was not stopped", fake.addr)
+		}
+	}
+	if !bridge.stopped {
+		t.Fatal("bridge was not stopped")
+	}
+	if !strings.Contains(stdout.String(), "127.0.0.1:15432  ins-1:5432") {
+		t.Fatalf("stdout=%q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "port 6379 is not reachable yet") {
+		t.Fatalf("stderr=%q", stderr.String())
+	}
+
+	opts[0].OnStateChange(adbtunnel.StateDegraded)
+	if !strings.Contains(stderr.String(), "port 5432 is unreachable") {
+		t.Fatalf("stderr=%q", stderr.String())
+	}
+}
+
+func TestRunForwardValidatesMappings(t *testing.T) {
+	setupConfig(t)
+	ios, _, _, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	for _, tc := range []struct {
+		specs      []string
+		bridgePort int
+		want       string
+	}{
+		{specs: nil, want: "requires --tcp"},
+		{specs: []string{"abc"}, want: "invalid --tcp mapping"},
+		{specs: []string{"5432:70000"}, want: "remote port"},
+		{specs: []string{"5432", "5432:6379"}, want: "used twice"},
+		{specs: []string{"5432"}, bridgePort: 5432, want: "invalid --bridge-port 5432"},
+		{specs: []string{"5432"}, bridgePort: 70000, want: "invalid --bridge-port"},
+	} {
+		flags := map[string]command.FlagValue{"tcp": {Name: "tcp", Type: command.FlagStringArray, Strings: tc.specs}}
+		if tc.bridgePort != 0 {
+			flags["bridge-port"] = command.FlagValue{Name: "bridge-port", Type: command.FlagInt, Int: tc.bridgePort, Changed: true}
+		}
+		_, err := runtime.Handler.Run(context.Background(), command.Request{
+			Args:      []string{"ins-1"},
+			ArgValues: map[string]string{"instance-id": "ins-1"},
+			Flags:     flags,
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("specs=%v err=%v, want %q", tc.specs, err, tc.want)
+		}
+	}
+}
+
+func TestRunForwardStopsTheBridgeWhenATunnelFails(t *testing.T) {
+	setupConfig(t)
+	bridge := &fakeBridge{}
+	ios, _, _, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			StartBridge: func(context.Context, string, tcpbridge.Options) (Bridge, error) {
+				return bridge, nil
+			},
+			NewTunnel: func(o adbtunnel.TunnelOptions) (Tunnel, error) {
+				return nil, errors.New("address in use")
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags:     map[string]command.FlagValue{"tcp": {Name: "tcp", Type: command.FlagStringArray, Strings: []string{"5432"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "address in use") {
+		t.Fatalf("err=%v", err)
+	}
+	if !bridge.stopped {
+		t.Fatal("bridge was not stopped")
+	}
+}
+
+type fakeBridge struct {
+	stopped bool
+}
+
+func (f *fakeBridge) Stop(context.Context) error {
+	f.stopped = true
+	return nil
+}
+
+type fakeTunnel struct {
+	addr     string
+	probeErr error
+	stopped  bool
+}
+
+func (f *fakeTunnel) Start() (string, error) { return f.addr, nil }
+func (f *fakeTunnel) Probe() error           { return f.probeErr }
+func (f *fakeTunnel) Stop()                  { f.stopped = true }
+
+func setupConfig(t *testing.T) {
+	t.Helper()
+	if err := config.Init(); err != nil {
+		t.Fatalf("config.Init: %v", err)
+	}
+	config.SetSecretID("AKIDfake")
+	config.SetSecretKey("fakeSecretKey")
+	config.SetRegion("ap-guangzhou")
+}
@@ -55,7 +55,7 @@ func PrintKV(w io.Writer, pairs []KeyValue) {
 		}
 	}
 	for _, kv := range pairs {
-		fmt.Fprintf(w, "%-*s  %s\n", maxLen, kv.Key+":", kv.Value)
+		fmt.Fprintf(w, "%-*s  %s\n", maxLen+1, kv.Key+":", kv.Value)
 	}
 }
 
@@ -1,12 +1,21 @@
 package instanceview
 
 import (
+	"bytes"
 	"strings"
 	"testing"
 
 	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
 )
 
+func TestPrintKVAlignsValues(t *testing.T) {
+	var buf bytes.Buffer
+	PrintKV(&buf, []KeyValue{{Key: "A", Value: "1"}, {Key: "Long key", Value: "2"}})
+	if got := buf.String(); got != "A:         1\nLong key:  2\n" {
+		t.Fatalf("output = %q", got)
+	}
+}
+
 func TestTimeout(t *testing.T) {
 	for _, tc := range []struct {
 		seconds uint64
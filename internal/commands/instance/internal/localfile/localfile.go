@@ -0,0 +1,24 @@
+// Package localfile validates the local files written by the instance
+// commands that save data from a sandbox, such as screenshots and recordings.
+package localfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// ValidateOutput checks that the directory of a local output file exists and
+// returns the file's absolute path.
+func ValidateOutput(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("invalid local path %q: %v", path, err), "Pass a writable file path.")
+	}
+	if info, err := os.Stat(filepath.Dir(abs)); err != nil || !info.IsDir() {
+		return "", output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("directory of local path %q does not exist", path), "Create the directory or pass another path.")
+	}
+	return abs, nil
+}
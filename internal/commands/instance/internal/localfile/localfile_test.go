@@ -0,0 +1,22 @@
+package localfile
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+func TestValidateOutput(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shot.png")
+	if got, err := ValidateOutput(path); err != nil || got != path {
+		t.Fatalf("ValidateOutput(%q) = %q, %v", path, got, err)
+	}
+	_, err := ValidateOutput(filepath.Join(dir, "missing", "shot.png"))
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != "INVALID_LOCAL_PATH" {
+		t.Fatalf("error = %v, want INVALID_LOCAL_PATH", err)
+	}
+}
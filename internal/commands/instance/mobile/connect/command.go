@@ -1,68 +1,58 @@
 package connect
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"time"
 
-	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
-	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
-	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
-	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/tunnelconn"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
 )
 
 // Store is the tunnel registry dependency used to replace stale connections and
 // persist the new tunnel.
-type Store interface {
-	Get(string) (tunnelstore.TunnelEntry, bool, error)
-	Cleanup(string) error
-	Save(string, tunnelstore.TunnelEntry) error
-}
+type Store = tunnelconn.Store
 
 // TunnelReady is the daemon readiness payload emitted by the hidden tunnel
 // command and consumed by connect.
-type TunnelReady struct {
-	Port    int
-	PID     int
-	ExePath string
-	LogPath string
-}
+type TunnelReady = tunnelconn.TunnelReady
 
 // RuntimeDeps contains adb, config, tunnel, and store hooks that tests can
 // replace without spawning a daemon process.
-type RuntimeDeps struct {
-	RequireADB     func() (string, error)
-	ValidateConfig func() error
-	NewStore       func() (Store, error)
-	DisconnectADB  func(adbPath, addr string) error
-	StartTunnel    func(ctx context.Context, instanceID string, port int) (TunnelReady, error)
-	ConnectADB     func(adbPath, addr string, maxRetries int, out io.Writer) error
-}
-
-type readyMessage struct {
-	Status  string `json:"status"`
-	Port    int    `json:"port,omitempty"`
-	PID     int    `json:"pid,omitempty"`
-	Message string `json:"message,omitempty"`
-}
+type RuntimeDeps = tunnelconn.RuntimeDeps
 
 // Module returns this package's command module.
 func Module() command.Module {
 	return mobileModule(command.Spec{
-		ID:           "instance.mobile.connect",
-		Path:         []string{"instance", "mobile", "connect"},
-		Use:          "connect <instance-id>",
-		Short:        "Connect to mobile instance (background tunnel + adb connect)",
-		Args:         []command.ArgSpec{{Name: "instance-id", Required: true}},
-		Flags:        []command.FlagSpec{{Name: "port", Shorthand: "p", Usage: "Local port to listen on (0 = auto-assign)", Type: command.FlagInt, Default: 0}},
+		ID:    "instance.mobile.connect",
+		Path:  []string{"instance", "mobile", "connect"},
+		Use:   "connect [instance-id]...",
+		Short: "Connect to mobile instance (background tunnel + adb connect)",
+		Long: `Connect to mobile instances through background ADB tunnels.
+
+Each instance gets a tunnel daemon listening on 127.0.0.1 and is connected to
+the local adb server, so its adb serial is 127.0.0.1:<port>. Several instance
+ids, or --tool-id with --all-running, connect many instances at once with at
+most --max-parallel tunnels starting concurrently.
+
+With --supervise a background supervisor restarts a tunnel daemon that dies
+on the same port, backing off between attempts, so the adb serial stays valid.
+'agr instance mobile list' shows the restart count and 'disconnect' stops the
+supervisor together with the tunnel.`,
+		Examples: []string{
+			"agr instance mobile connect ins-xxxx",
+			"agr instance mobile connect --tool-id sdt-xxxx --all-running --max-parallel 8",
+			"agr instance mobile connect ins-aaaa ins-bbbb -o json",
+		},
+		Args: []command.ArgSpec{{Name: "instance-id", Repeatable: true}},
+		Flags: []command.FlagSpec{
+			{Name: "port", Shorthand: "p", Usage: "Local port to listen on (0 = auto-assign); single instance only", Type: command.FlagInt, Default: 0},
+			{Name: "tool-id", Usage: "Tool ID whose running instances --all-running connects", Type: command.FlagString},
+			{Name: "all-running", Usage: "Connect every running instance of --tool-id", Type: command.FlagBool},
+			{Name: "max-parallel", Usage: "Maximum number of tunnels started concurrently", Type: command.FlagInt, Default: defaultMaxParallel},
+			{Name: "supervise", Usage: "Restart tunnel daemons that die, on the same port", Type: command.FlagBool},
+		},
 		SupportsJSON: true,
 		Output:       command.OutputSpec{DataType: "MobileConnection"},
 	})
@@ -108,40 +98,31 @@ Examples:
 }
 
 func runtimeDeps(injected any) RuntimeDeps {
-	rt, _ := injected.(RuntimeDeps)
-	if rt.RequireADB == nil {
-		rt.RequireADB = mobileadb.Require
-	}
-	if rt.ValidateConfig == nil {
-		rt.ValidateConfig = config.Validate
-	}
-	if rt.NewStore == nil {
-		rt.NewStore = func() (Store, error) { return tunnelstore.NewStore() }
-	}
-	if rt.DisconnectADB == nil {
-		rt.DisconnectADB = func(adbPath, addr string) error {
-			return mobileadb.Run(adbPath, "disconnect", addr)
-		}
-	}
-	if rt.StartTunnel == nil {
-		rt.StartTunnel = startTunnelDaemon
-	}
-	if rt.ConnectADB == nil {
-		rt.ConnectADB = mobileadb.ConnectWithRetry
-	}
-	return rt
+	return tunnelconn.Defaults(injected)
 }
 
 func runConnect(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
-	instanceID := req.ArgValues["instance-id"]
-	if instanceID == "" && len(req.Args) > 0 {
-		instanceID = req.Args[0]
-	}
-
 	port := intFlag(req, "port")
 	if port < 0 || port > 65535 {
 		return nil, output.NewUsageError("INVALID_PORT", "--port must be between 0 and 65535", "Use a valid port number (1-65535) or 0 for auto-assign.")
 	}
+	opts := fanoutOptions{
+		ToolID:      stringFlag(req, "tool-id"),
+		AllRunning:  boolFlag(req, "all-running"),
+		MaxParallel: defaultMaxParallel,
+		Supervise:   boolFlag(req, "supervise"),
+	}
+	if flag, ok := req.Flags["max-parallel"]; ok && flag.Changed {
+		opts.MaxParallel = flag.Int
+	}
+	if err := validateFanout(opts, req.Args, port); err != nil {
+		return nil, err
+	}
+	if !opts.AllRunning && len(req.Args) == 0 {
+		return nil, output.NewUsageError("MISSING_INSTANCE",
+			"must specify an instance-id or use --tool-id with --all-running",
+			"Pass one or more instance ids, or --tool-id sdt-xxxx --all-running.")
+	}
 
 	adbPath, err := rt.RequireADB()
 	if err != nil {
@@ -151,35 +132,34 @@ func runConnect(ctx context.Context, req command.Request, deps command.Deps, rt
 		return nil, err
 	}
 
+	if opts.AllRunning || len(req.Args) > 1 {
+		ids, err := fanoutTargets(ctx, opts, req.Args, deps)
+		if err != nil {
+			return nil, err
+		}
+		store, err := rt.NewStore()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize tunnel store: %w", err)
+		}
+		return runConnectFanout(ctx, deps, rt, store, adbPath, ids, opts), nil
+	}
+
+	instanceID := req.ArgValues["instance-id"]
+	if instanceID == "" {
+		instanceID = req.Args[0]
+	}
 	store, err := rt.NewStore()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize tunnel store: %w", err)
 	}
-
-	if oldEntry, ok, _ := store.Get(instanceID); ok {
-		oldAddr := fmt.Sprintf("127.0.0.1:%d", oldEntry.Port)
-		_ = rt.DisconnectADB(adbPath, oldAddr)
-	}
-	if err := store.Cleanup(instanceID); err != nil {
-		fmt.Fprintf(deps.IO.ErrOut, "Warning: failed to cleanup existing tunnel: %v\n", err)
+	conn, err := tunnelconn.Connect(ctx, rt, store, adbPath, instanceID, tunnelconn.Options{Port: port, Supervise: opts.Supervise}, deps.Now(), deps.IO.ErrOut)
+	for _, warning := range conn.Warnings {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: %s\n", warning)
 	}
-
-	ready, err := rt.StartTunnel(ctx, instanceID, port)
 	if err != nil {
 		return nil, err
 	}
-
-	if err := store.Save(instanceID, tunnelstore.TunnelEntry{
-		PID:       ready.PID,
-		Port:      ready.Port,
-		CreatedAt: deps.Now(),
-		ExePath:   ready.ExePath,
-	}); err != nil {
-		fmt.Fprintf(deps.IO.ErrOut, "Warning: failed to save tunnel mapping: %v\n", err)
-	}
-
-	adbAddr := fmt.Sprintf("127.0.0.1:%d", ready.Port)
-	adbConnectErr := rt.ConnectADB(adbPath, adbAddr, 3, deps.IO.ErrOut)
+	ready, adbAddr, adbConnectErr := conn.Ready, conn.Address, conn.ADBErr
 
 	data := map[string]any{
 		"InstanceId": instanceID,
@@ -190,6 +170,9 @@ func runConnect(ctx context.Context, req command.Request, deps command.Deps, rt
 	if ready.LogPath != "" {
 		data["LogPath"] = ready.LogPath
 	}
+	if conn.SupervisorPID != 0 {
+		data["SupervisorPid"] = conn.SupervisorPID
+	}
 
 	return &command.Result{Data: data, Text: func(w io.Writer) {
 		if adbConnectErr != nil {
@@ -201,126 +184,10 @@ func runConnect(ctx context.Context, req command.Request, deps command.Deps, rt
 		if ready.LogPath != "" {
 			fmt.Fprintf(deps.IO.ErrOut, "tunnel log: %s\n", ready.LogPath)
 		}
-	}}, nil
-}
-
-func startTunnelDaemon(_ context.Context, instanceID string, port int) (TunnelReady, error) {
-	selfPath, err := os.Executable()
-	if err != nil {
-		return TunnelReady{}, fmt.Errorf("failed to get executable path: %w", err)
-	}
-
-	tunnelArgs := []string{"instance", "mobile", "tunnel", instanceID, "--daemon", fmt.Sprintf("--port=%d", port)}
-	if cli.CfgFile() != "" {
-		tunnelArgs = append(tunnelArgs, "--config", cli.CfgFile())
-	}
-	if cli.RegionFlag() != "" {
-		tunnelArgs = append(tunnelArgs, "--region", cli.RegionFlag())
-	}
-	if cli.DomainFlag() != "" {
-		tunnelArgs = append(tunnelArgs, "--domain", cli.DomainFlag())
-	}
-
-	cmd := exec.Command(selfPath, tunnelArgs...)
-	logPath, logFile := openTunnelLog(instanceID)
-	if logFile != nil {
-		cmd.Stderr = logFile
-	}
-	cmd.Env = tunnelEnv()
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		_ = closeIfOpen(logFile)
-		return TunnelReady{}, fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-	if err := cmd.Start(); err != nil {
-		_ = closeIfOpen(logFile)
-		return TunnelReady{}, fmt.Errorf("failed to start tunnel process: %w", err)
-	}
-
-	go func() {
-		_ = cmd.Wait()
-		_ = closeIfOpen(logFile)
-	}()
-
-	ready, err := readTunnelReady(stdout)
-	if err != nil {
-		_ = cmd.Process.Kill()
-		return TunnelReady{}, err
-	}
-	if ready.Status != "ready" || ready.Port == 0 {
-		_ = cmd.Process.Kill()
-		return TunnelReady{}, fmt.Errorf("tunnel reported error: %s", ready.Message)
-	}
-	return TunnelReady{Port: ready.Port, PID: ready.PID, ExePath: selfPath, LogPath: logPath}, nil
-}
-
-func readTunnelReady(stdout io.Reader) (readyMessage, error) {
-	readyCh := make(chan readyMessage, 1)
-	errCh := make(chan error, 1)
-
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		if scanner.Scan() {
-			var msg readyMessage
-			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
-				errCh <- fmt.Errorf("failed to parse tunnel ready message: %w", err)
-				return
-			}
-			readyCh <- msg
-			return
-		}
-		if err := scanner.Err(); err != nil {
-			errCh <- fmt.Errorf("failed to read tunnel output: %w", err)
-		} else {
-			errCh <- fmt.Errorf("tunnel process exited without ready message")
+		if conn.SupervisorPID != 0 {
+			fmt.Fprintf(deps.IO.ErrOut, "supervisor running (PID %d); dead tunnels restart on port %d\n", conn.SupervisorPID, ready.Port)
 		}
-	}()
-
-	timer := time.NewTimer(30 * time.Second)
-	defer timer.Stop()
-
-	select {
-	case ready := <-readyCh:
-		return ready, nil
-	case err := <-errCh:
-		return readyMessage{}, err
-	case <-timer.C:
-		return readyMessage{}, fmt.Errorf("tunnel did not become ready within 30s")
-	}
-}
-
-func openTunnelLog(instanceID string) (string, *os.File) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", nil
-	}
-	logDir := filepath.Join(homeDir, ".agr")
-	if err := os.MkdirAll(logDir, 0700); err != nil {
-		return "", nil
-	}
-	logPath := filepath.Join(logDir, fmt.Sprintf("tunnel-%s.log", instanceID))
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
-	if err != nil {
-		return "", nil
-	}
-	return logPath, logFile
-}
-
-func tunnelEnv() []string {
-	env := os.Environ()
-	cfg := config.Get()
-	if cli.SecretIDFlag() != "" {
-		env = append(env, "TENCENTCLOUD_SECRET_ID="+cli.SecretIDFlag())
-	} else if cfg.Auth.SecretID != "" {
-		env = append(env, "TENCENTCLOUD_SECRET_ID="+cfg.Auth.SecretID)
-	}
-	if cli.SecretKeyFlag() != "" {
-		env = append(env, "TENCENTCLOUD_SECRET_KEY="+cli.SecretKeyFlag())
-	} else if cfg.Auth.SecretKey != "" {
-		env = append(env, "TENCENTCLOUD_SECRET_KEY="+cfg.Auth.SecretKey)
-	}
-	return env
+	}}, nil
 }
 
 func intFlag(req command.Request, name string) int {
@@ -331,9 +198,15 @@ func intFlag(req command.Request, name string) int {
 	return flag.Int
 }
 
-func closeIfOpen(file *os.File) error {
-	if file == nil {
-		return nil
+func stringFlag(req command.Request, name string) string {
+	flag, ok := req.Flags[name]
+	if !ok {
+		return ""
 	}
-	return file.Close()
+	return flag.String
+}
+
+func boolFlag(req command.Request, name string) bool {
+	flag, ok := req.Flags[name]
+	return ok && flag.Bool
 }
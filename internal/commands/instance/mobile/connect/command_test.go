@@ -6,12 +6,17 @@ import (
 	"errors"
 	"io"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
 )
 
 func TestModuleDescriptor(t *testing.T) {
@@ -66,6 +71,48 @@ func TestRunConnectTreatsADBConnectFailureAsWarning(t *testing.T) {
 	}
 }
 
+func TestRunConnectStartsSupervisorOnTunnelPort(t *testing.T) {
+	store := &fakeStore{}
+	stderr := &bytes.Buffer{}
+	runtime, err := Module().Build(command.Deps{
+		IO:  testIO(stderr),
+		Now: func() time.Time { return time.Unix(100, 0) },
+		DataPlane: RuntimeDeps{
+			RequireADB:     func() (string, error) { return "/adb", nil },
+			ValidateConfig: func() error { return nil },
+			NewStore:       func() (Store, error) { return store, nil },
+			DisconnectADB:  func(string, string) error { return nil },
+			StartTunnel: func(context.Context, string, int) (TunnelReady, error) {
+				return TunnelReady{Port: 5555, PID: 123}, nil
+			},
+			ConnectADB: func(string, string, int, io.Writer) error { return nil },
+			StartSupervisor: func(_ context.Context, id string, port int) (int, error) {
+				if id != "ins-1" || port != 5555 || store.savedID != "ins-1" {
+					t.Errorf("supervisor started for %s:%d before the entry was saved", id, port)
+				}
+				return 456, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:  []string{"ins-1"},
+		Flags: map[string]command.FlagValue{"supervise": {Name: "supervise", Type: command.FlagBool, Bool: true, Changed: true}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if result.Data.(map[string]any)["SupervisorPid"] != 456 {
+		t.Fatalf("data = %#v", result.Data)
+	}
+	result.Text(io.Discard)
+	if !strings.Contains(stderr.String(), "supervisor running (PID 456)") {
+		t.Fatalf("stderr=%q", stderr.String())
+	}
+}
+
 func TestRunConnectRequiresADB(t *testing.T) {
 	runtime, err := Module().Build(command.Deps{
 		IO: testIO(&bytes.Buffer{}),
@@ -82,6 +129,135 @@ func TestRunConnectRequiresADB(t *testing.T) {
 	}
 }
 
+func TestRunConnectManyInstancesConcurrently(t *testing.T) {
+	store := &fakeStore{}
+	var inFlight, peak atomic.Int32
+	runtime := buildFanoutRuntime(t, store, nil, func(_ context.Context, id string, port int) (TunnelReady, error) {
+		if port != 0 {
+			t.Errorf("port = %d, want auto-assign", port)
+		}
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if id == "ins-bad" {
+			return TunnelReady{}, errors.New("tunnel refused")
+		}
+		return TunnelReady{Port: map[string]int{"ins-1": 5555, "ins-2": 5556, "ins-3": 5557}[id], PID: 1}, nil
+	})
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:  []string{"ins-3", "ins-1", "ins-bad", "ins-2", "ins-1"},
+		Flags: map[string]command.FlagValue{"max-parallel": {Name: "max-parallel", Type: command.FlagInt, Int: 2, Changed: true}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("peak concurrency = %d, want <= 2", peak.Load())
+	}
+	data := result.Data.(map[string]any)
+	items := data["Items"].([]InstanceConnection)
+	if data["Total"] != 4 || data["Connected"] != 3 || data["Failed"] != 1 || len(items) != 4 {
+		t.Fatalf("data = %#v", data)
+	}
+	if items[0].InstanceId != "ins-3" || items[0].AdbAddress != "127.0.0.1:5557" || items[2].Status != "failed" || items[2].Error != "tunnel refused" {
+		t.Fatalf("items = %#v", items)
+	}
+	if result.Failure == nil || result.Failure.Code != "PARTIAL_CONNECT_FAILED" || result.ExitCode == 0 {
+		t.Fatalf("failure = %#v exit = %d", result.Failure, result.ExitCode)
+	}
+	if len(store.entries) != 3 || store.entries["ins-2"].Port != 5556 {
+		t.Fatalf("store = %#v", store.entries)
+	}
+	out := &bytes.Buffer{}
+	result.Text(out)
+	if !strings.Contains(out.String(), "INSTANCE") || !strings.Contains(out.String(), "127.0.0.1:5556") {
+		t.Fatalf("table = %q", out.String())
+	}
+}
+
+func TestRunConnectAllRunningInstancesOfTool(t *testing.T) {
+	store := &fakeStore{}
+	var connected []string
+	var mu sync.Mutex
+	runtime := buildFanoutRuntime(t, store, &fakeLister{instances: []*ags.SandboxInstance{
+		{InstanceId: ptr("ins-b"), ToolId: ptr("sdt-1"), Status: ptr("RUNNING")},
+		{InstanceId: ptr("ins-a"), ToolId: ptr("sdt-1"), Status: ptr("RUNNING")},
+		{InstanceId: ptr("ins-c"), ToolId: ptr("sdt-1"), Status: ptr("STOPPED")},
+		{InstanceId: ptr("ins-d"), ToolId: ptr("sdt-2"), Status: ptr("RUNNING")},
+	}}, func(_ context.Context, id string, _ int) (TunnelReady, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		connected = append(connected, id)
+		return TunnelReady{Port: 6000 + len(connected)}, nil
+	})
+	result, err := runtime.Handler.Run(context.Background(), command.Request{Flags: map[string]command.FlagValue{
+		"tool-id":     {Name: "tool-id", Type: command.FlagString, String: "sdt-1", Changed: true},
+		"all-running": {Name: "all-running", Type: command.FlagBool, Bool: true, Changed: true},
+	}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	items := result.Data.(map[string]any)["Items"].([]InstanceConnection)
+	if len(items) != 2 || items[0].InstanceId != "ins-a" || items[1].InstanceId != "ins-b" || result.Failure != nil {
+		t.Fatalf("items = %#v failure = %#v", items, result.Failure)
+	}
+}
+
+func TestRunConnectValidatesMultiInstanceFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		flags map[string]command.FlagValue
+		code  string
+	}{
+		{name: "missing instance", code: "MISSING_INSTANCE"},
+		{name: "all-running without tool", flags: map[string]command.FlagValue{"all-running": {Bool: true}}, code: "MISSING_REQUIRED_FLAG"},
+		{name: "tool without all-running", args: []string{"ins-1"}, flags: map[string]command.FlagValue{"tool-id": {String: "sdt-1"}}, code: "MISSING_REQUIRED_FLAG"},
+		{name: "ids with all-running", args: []string{"ins-1"}, flags: map[string]command.FlagValue{"all-running": {Bool: true}, "tool-id": {String: "sdt-1"}}, code: "CONFLICTING_FLAGS"},
+		{name: "port with many", args: []string{"ins-1", "ins-2"}, flags: map[string]command.FlagValue{"port": {Int: 5555}}, code: "CONFLICTING_FLAGS"},
+		{name: "max-parallel", args: []string{"ins-1", "ins-2"}, flags: map[string]command.FlagValue{"max-parallel": {Int: 0, Changed: true}}, code: "INVALID_MAX_PARALLEL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildRuntime(t, &fakeStore{}, &bytes.Buffer{}, nil).Handler.Run(context.Background(), command.Request{Args: tt.args, Flags: tt.flags})
+			var cliErr *output.CLIError
+			if !errors.As(err, &cliErr) || cliErr.Failure.Code != tt.code {
+				t.Fatalf("error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func buildFanoutRuntime(t *testing.T, store *fakeStore, lister *fakeLister, start func(context.Context, string, int) (TunnelReady, error)) command.Runtime {
+	t.Helper()
+	deps := command.Deps{
+		IO:  testIO(&bytes.Buffer{}),
+		Now: func() time.Time { return time.Unix(100, 0) },
+		DataPlane: RuntimeDeps{
+			RequireADB:     func() (string, error) { return "/adb", nil },
+			ValidateConfig: func() error { return nil },
+			NewStore:       func() (Store, error) { return store, nil },
+			DisconnectADB:  func(string, string) error { return nil },
+			StartTunnel:    start,
+			ConnectADB:     func(string, string, int, io.Writer) error { return nil },
+		},
+	}
+	if lister != nil {
+		deps.ControlPlane = lister
+	}
+	runtime, err := Module().Build(deps)
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	return runtime
+}
+
 func buildRuntime(t *testing.T, store *fakeStore, stderr *bytes.Buffer, adbConnectErr error) command.Runtime {
 	t.Helper()
 	runtime, err := Module().Build(command.Deps{
@@ -114,6 +290,7 @@ func testIO(stderr *bytes.Buffer) *iostreams.IOStreams {
 }
 
 type fakeStore struct {
+	mu           sync.Mutex
 	entries      map[string]tunnelstore.TunnelEntry
 	cleaned      string
 	savedID      string
@@ -122,17 +299,37 @@ type fakeStore struct {
 }
 
 func (f *fakeStore) Get(id string) (tunnelstore.TunnelEntry, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	entry, ok := f.entries[id]
 	return entry, ok, nil
 }
 
 func (f *fakeStore) Cleanup(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.cleaned = id
 	return nil
 }
 
 func (f *fakeStore) Save(id string, entry tunnelstore.TunnelEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.savedID = id
 	f.saved = entry
+	if f.entries == nil {
+		f.entries = map[string]tunnelstore.TunnelEntry{}
+	}
+	f.entries[id] = entry
 	return nil
 }
+
+type fakeLister struct {
+	instances []*ags.SandboxInstance
+}
+
+func (f *fakeLister) ListInstances(context.Context) ([]*ags.SandboxInstance, error) {
+	return f.instances, nil
+}
+
+func ptr(s string) *string { return &s }
@@ -0,0 +1,214 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/tunnelconn"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// defaultMaxParallel bounds concurrent tunnel starts when --max-parallel is
+// unset.
+const defaultMaxParallel = 4
+
+// InstanceLister lists sandbox instances for --all-running.
+type InstanceLister interface {
+	ListInstances(ctx context.Context) ([]*ags.SandboxInstance, error)
+}
+
+// InstanceConnection is the outcome of connecting one instance of a
+// multi-instance connect.
+type InstanceConnection struct {
+	InstanceId string `json:"InstanceId"`
+	Status     string `json:"Status"` // connected | tunnel-only | failed
+	AdbAddress string `json:"AdbAddress,omitempty"`
+	Port       int    `json:"Port,omitempty"`
+	Pid        int    `json:"Pid,omitempty"`
+	LogPath    string `json:"LogPath,omitempty"`
+	// SupervisorPid is set with --supervise.
+	SupervisorPid int    `json:"SupervisorPid,omitempty"`
+	Error         string `json:"Error,omitempty"`
+}
+
+type fanoutOptions struct {
+	ToolID      string
+	AllRunning  bool
+	MaxParallel int
+	Supervise   bool
+}
+
+// validateFanout rejects flag combinations that do not apply to the selected
+// instances. It runs before adb or the control plane is used.
+func validateFanout(opts fanoutOptions, instanceArgs []string, port int) error {
+	switch {
+	case opts.AllRunning && opts.ToolID == "":
+		return output.NewUsageError("MISSING_REQUIRED_FLAG",
+			"--all-running requires --tool-id",
+			"Provide --tool-id sdt-xxxx to connect every running instance of that tool.")
+	case opts.ToolID != "" && !opts.AllRunning:
+		return output.NewUsageError("MISSING_REQUIRED_FLAG",
+			"--tool-id requires --all-running",
+			"Add --all-running, or pass instance ids instead of --tool-id.")
+	case opts.AllRunning && len(instanceArgs) > 0:
+		return output.NewUsageError("CONFLICTING_FLAGS",
+			"instance ids cannot be used together with --all-running",
+			"Pass instance ids or --tool-id with --all-running, not both.")
+	case port != 0 && (opts.AllRunning || len(instanceArgs) > 1):
+		return output.NewUsageError("CONFLICTING_FLAGS",
+			"--port applies to a single instance",
+			"Drop --port; each tunnel of a multi-instance connect gets its own free port.")
+	case opts.MaxParallel < 1:
+		return output.NewUsageError("INVALID_MAX_PARALLEL",
+			fmt.Sprintf("--max-parallel must be >= 1 (got %d)", opts.MaxParallel),
+			"Use --max-parallel 1 to start one tunnel at a time.")
+	}
+	return nil
+}
+
+// fanoutTargets returns the instances given as arguments or selected by
+// --all-running, in a stable order.
+func fanoutTargets(ctx context.Context, opts fanoutOptions, instanceArgs []string, deps command.Deps) ([]string, error) {
+	if !opts.AllRunning {
+		seen := map[string]bool{}
+		var ids []string
+		for _, id := range instanceArgs {
+			if id != "" && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+	lister, ok := deps.ControlPlane.(InstanceLister)
+	if !ok {
+		return nil, fmt.Errorf("instance.mobile.connect --all-running requires command.Deps.ControlPlane implementing instance/mobile/connect.InstanceLister")
+	}
+	instances, err := lister.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, inst := range instances {
+		if inst == nil || inst.InstanceId == nil || deref(inst.ToolId) != opts.ToolID || deref(inst.Status) != "RUNNING" {
+			continue
+		}
+		ids = append(ids, *inst.InstanceId)
+	}
+	if len(ids) == 0 {
+		return nil, output.NewNotFoundError("INSTANCE_NOT_FOUND",
+			fmt.Sprintf("no running instances of tool %s", opts.ToolID),
+			"Run 'agr instance list' to find active instances.")
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// runConnectFanout connects every instance with at most opts.MaxParallel tunnels
+// starting at once. Instances whose tunnel failed are reported in the result
+// instead of aborting the others.
+func runConnectFanout(ctx context.Context, deps command.Deps, rt RuntimeDeps, store Store, adbPath string, ids []string, opts fanoutOptions) *command.Result {
+	results := make([]InstanceConnection, len(ids))
+	var warnings []string
+	var mu sync.Mutex // guards warnings
+	sem := make(chan struct{}, opts.MaxParallel)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// adb connect output of concurrent tunnels would interleave; the
+			// table reports the outcome instead.
+			conn, err := tunnelconn.Connect(ctx, rt, store, adbPath, id, tunnelconn.Options{Supervise: opts.Supervise}, deps.Now(), io.Discard)
+			mu.Lock()
+			for _, warning := range conn.Warnings {
+				warnings = append(warnings, fmt.Sprintf("%s: %s", id, warning))
+			}
+			mu.Unlock()
+			results[i] = instanceConnection(id, conn, err)
+		}()
+	}
+	wg.Wait()
+
+	var failedIDs []string
+	connected := 0
+	for _, r := range results {
+		switch r.Status {
+		case "failed":
+			failedIDs = append(failedIDs, r.InstanceId)
+		case "connected":
+			connected++
+		}
+	}
+	result := &command.Result{
+		Data: map[string]any{
+			"Items":     results,
+			"Total":     len(results),
+			"Connected": connected,
+			"Failed":    len(failedIDs),
+			"FailedIds": failedIDs,
+		},
+		Warnings: warnings,
+		Text: func(w io.Writer) {
+			printConnections(w, results)
+			if len(failedIDs) > 0 {
+				fmt.Fprintf(deps.IO.ErrOut, "failed to connect %d of %d instance(s)\n", len(failedIDs), len(results))
+			}
+		},
+	}
+	if len(failedIDs) > 0 {
+		result.Failure = &output.Failure{
+			Code:    "PARTIAL_CONNECT_FAILED",
+			Kind:    output.KindPartialSuccess,
+			Message: fmt.Sprintf("failed to connect %d of %d instances", len(failedIDs), len(results)),
+			Hint:    "Inspect Data.Items for per-instance errors and retry with the ids in Data.FailedIds.",
+		}
+		result.ExitCode = output.ExitPartialSuccess
+	}
+	return result
+}
+
+func instanceConnection(instanceID string, conn tunnelconn.Connection, err error) InstanceConnection {
+	out := InstanceConnection{InstanceId: instanceID, Status: "failed"}
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	out.AdbAddress, out.Port, out.Pid, out.LogPath = conn.Address, conn.Ready.Port, conn.Ready.PID, conn.Ready.LogPath
+	out.SupervisorPid = conn.SupervisorPID
+	out.Status = "connected"
+	if conn.ADBErr != nil {
+		out.Status = "tunnel-only"
+		out.Error = fmt.Sprintf("adb connect failed: %v", conn.ADBErr)
+	}
+	return out
+}
+
+func printConnections(w io.Writer, results []InstanceConnection) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTANCE\tSERIAL\tSTATUS\tERROR")
+	for _, r := range results {
+		serial := r.AdbAddress
+		if serial == "" {
+			serial = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.InstanceId, serial, r.Status, r.Error)
+	}
+	_ = tw.Flush()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
@@ -33,9 +34,9 @@ func Module() command.Module {
 	spec := command.Spec{
 		ID:    "instance.mobile.disconnect",
 		Path:  []string{"instance", "mobile", "disconnect"},
-		Use:   "disconnect [instance-id]",
+		Use:   "disconnect [instance-id]...",
 		Short: "Disconnect from mobile instance",
-		Args:  []command.ArgSpec{{Name: "instance-id"}},
+		Args:  []command.ArgSpec{{Name: "instance-id", Repeatable: true}},
 		Flags: []command.FlagSpec{{Name: "all", Usage: "Disconnect all active connections", Type: command.FlagBool}},
 		Output: command.OutputSpec{
 			DataType: "MobileDisconnectResult",
@@ -101,6 +102,9 @@ func runDisconnect(_ context.Context, req command.Request, deps command.Deps, rt
 	if all {
 		return disconnectAll(store, deps, rt)
 	}
+	if len(req.Args) > 1 {
+		return disconnectMany(store, req.Args, deps, rt)
+	}
 	instanceID := req.ArgValues["instance-id"]
 	if instanceID == "" && len(req.Args) > 0 {
 		instanceID = req.Args[0]
@@ -116,8 +120,7 @@ func runDisconnect(_ context.Context, req command.Request, deps command.Deps, rt
 		return nil, noActiveTunnelError(instanceID, false)
 	}
 	if adbPath, err := rt.RequireADB(); err == nil {
-		adbAddr := fmt.Sprintf("127.0.0.1:%d", entry.Port)
-		_ = rt.RunADB(adbPath, "disconnect", adbAddr)
+		_ = rt.RunADB(adbPath, "disconnect", mobileadb.LocalSerial(entry.Port))
 	}
 	if err := store.Cleanup(instanceID); err != nil {
 		return nil, fmt.Errorf("failed to cleanup tunnel: %w", err)
@@ -128,6 +131,65 @@ func runDisconnect(_ context.Context, req command.Request, deps command.Deps, rt
 	}}, nil
 }
 
+// disconnectMany disconnects several instances, as connected together by a
+// multi-instance connect. Every instance must have a tunnel; none is
+// disconnected otherwise. An instance whose tunnel cannot be stopped is
+// reported and the others are still disconnected.
+func disconnectMany(store Store, instanceIDs []string, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	entries := map[string]tunnelstore.TunnelEntry{}
+	for _, id := range instanceIDs {
+		entry, ok, err := store.Get(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tunnel store: %w", err)
+		}
+		if !ok {
+			return nil, noActiveTunnelError(id, false)
+		}
+		entries[id] = entry
+	}
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	adbPath, _ := rt.RequireADB()
+	disconnected := make([]string, 0, len(ids))
+	var failed, warnings []string
+	for _, id := range ids {
+		if adbPath != "" {
+			_ = rt.RunADB(adbPath, "disconnect", mobileadb.LocalSerial(entries[id].Port))
+		}
+		if err := store.Cleanup(id); err != nil {
+			failed = append(failed, id)
+			warnings = append(warnings, fmt.Sprintf("Failed to disconnect %s: %v", id, err))
+			continue
+		}
+		disconnected = append(disconnected, id)
+	}
+
+	data := map[string]any{"Disconnected": disconnected, "Count": len(disconnected)}
+	result := &command.Result{Data: data, Warnings: warnings, Text: func(w io.Writer) {
+		for _, id := range disconnected {
+			fmt.Fprintf(deps.IO.ErrOut, "disconnected from %s\n", id)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(deps.IO.ErrOut, "%s\n", warning)
+		}
+	}}
+	if len(failed) > 0 {
+		data["FailedIds"] = failed
+		result.Failure = &output.Failure{
+			Code:    "PARTIAL_DISCONNECT_FAILED",
+			Kind:    output.KindPartialSuccess,
+			Message: fmt.Sprintf("failed to disconnect %d of %d instances", len(failed), len(ids)),
+			Hint:    "The tunnel processes may have been replaced by other programs; inspect Data.FailedIds and stop them manually.",
+		}
+		result.ExitCode = output.ExitPartialSuccess
+	}
+	return result, nil
+}
+
 func disconnectAll(store Store, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
 	entries, err := store.List()
 	if err != nil {
@@ -139,16 +201,20 @@ func disconnectAll(store Store, deps command.Deps, rt RuntimeDeps) (*command.Res
 			fmt.Fprintln(deps.IO.ErrOut, "no active connections")
 		}}, nil
 	}
+	return disconnectEntries(entries, deps, rt, store.CleanupAll)
+}
+
+func disconnectEntries(entries map[string]tunnelstore.TunnelEntry, deps command.Deps, rt RuntimeDeps, cleanup func() error) (*command.Result, error) {
 	adbPath, _ := rt.RequireADB()
-	var disconnected []string
+	disconnected := make([]string, 0, len(entries))
 	for id, entry := range entries {
 		if adbPath != "" {
-			adbAddr := fmt.Sprintf("127.0.0.1:%d", entry.Port)
-			_ = rt.RunADB(adbPath, "disconnect", adbAddr)
+			_ = rt.RunADB(adbPath, "disconnect", mobileadb.LocalSerial(entry.Port))
 		}
 		disconnected = append(disconnected, id)
 	}
-	if err := store.CleanupAll(); err != nil {
+	sort.Strings(disconnected)
+	if err := cleanup(); err != nil {
 		return nil, fmt.Errorf("failed to cleanup tunnels: %w", err)
 	}
 	data := map[string]any{"Disconnected": disconnected, "Count": len(disconnected)}
@@ -3,6 +3,7 @@ package disconnect
 import (
 	"bytes"
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
 )
 
 func TestModuleDisconnectsSingleConnection(t *testing.T) {
@@ -40,6 +42,54 @@ func TestModuleDisconnectsAllConnections(t *testing.T) {
 	}
 }
 
+func TestModuleDisconnectsSeveralInstances(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{
+		"ins-1": {Port: 5555},
+		"ins-2": {Port: 5556},
+		"ins-3": {Port: 5557},
+	}}
+	result, stderr := runWithStore(t, store, command.Request{Args: []string{"ins-2", "ins-1"}})
+	data := result.Data.(map[string]any)
+	if data["Count"] != 2 || strings.Join(data["Disconnected"].([]string), ",") != "ins-1,ins-2" || len(store.cleanedIDs) != 2 || store.cleanedAll {
+		t.Fatalf("result=%#v store=%#v", result, store)
+	}
+	result.Text(ioDiscard{})
+	if !strings.Contains(stderr.String(), "disconnected from ins-1") || strings.Contains(stderr.String(), "ins-3") {
+		t.Fatalf("stderr=%q", stderr.String())
+	}
+}
+
+func TestModuleReportsInstancesThatFailedToDisconnect(t *testing.T) {
+	store := &fakeStore{
+		entries: map[string]tunnelstore.TunnelEntry{
+			"ins-1": {Port: 5555},
+			"ins-2": {Port: 5556},
+			"ins-3": {Port: 5557},
+		},
+		failCleanup: map[string]bool{"ins-2": true},
+	}
+	result, stderr := runWithStore(t, store, command.Request{Args: []string{"ins-1", "ins-2", "ins-3"}})
+	data := result.Data.(map[string]any)
+	if strings.Join(data["Disconnected"].([]string), ",") != "ins-1,ins-3" || strings.Join(data["FailedIds"].([]string), ",") != "ins-2" {
+		t.Fatalf("data=%#v", data)
+	}
+	if result.Failure == nil || result.Failure.Code != "PARTIAL_DISCONNECT_FAILED" || result.ExitCode != output.ExitPartialSuccess {
+		t.Fatalf("result=%#v", result)
+	}
+	result.Text(ioDiscard{})
+	if !strings.Contains(stderr.String(), "disconnected from ins-3") || !strings.Contains(stderr.String(), "Failed to disconnect ins-2") {
+		t.Fatalf("stderr=%q", stderr.String())
+	}
+}
+
+func TestModuleDisconnectsNothingWhenOneInstanceHasNoTunnel(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{"ins-1": {Port: 5555}}}
+	_, err := buildRuntime(t, store).Handler.Run(context.Background(), command.Request{Args: []string{"ins-1", "ins-2"}})
+	if err == nil || !strings.Contains(err.Error(), "no active tunnel for ins-2") || len(store.cleanedIDs) != 0 {
+		t.Fatalf("error=%v store=%#v", err, store)
+	}
+}
+
 func TestModuleRejectsAllWithInstanceID(t *testing.T) {
 	_, err := buildRuntime(t, &fakeStore{}).Handler.Run(context.Background(), command.Request{
 		Args:  []string{"ins-1"},
@@ -90,9 +140,11 @@ func buildRuntimeWithIO(t *testing.T, store *fakeStore, ios *iostreams.IOStreams
 }
 
 type fakeStore struct {
-	entries    map[string]tunnelstore.TunnelEntry
-	cleaned    string
-	cleanedAll bool
+	entries     map[string]tunnelstore.TunnelEntry
+	failCleanup map[string]bool
+	cleaned     string
+	cleanedIDs  []string
+	cleanedAll  bool
 }
 
 func (f *fakeStore) Get(id string) (tunnelstore.TunnelEntry, bool, error) {
@@ -105,7 +157,11 @@ func (f *fakeStore) List() (map[string]tunnelstore.TunnelEntry, error) {
 }
 
 func (f *fakeStore) Cleanup(id string) error {
+	if f.failCleanup[id] {
+		return errors.New("tunnel process could not be terminated")
+	}
 	f.cleaned = id
+	f.cleanedIDs = append(f.cleanedIDs, id)
 	return nil
 }
 
@@ -0,0 +1,111 @@
+package info
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+)
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.info",
+		Path:  []string{"instance", "mobile", "info"},
+		Use:   "info <instance-id>",
+		Short: "Show device information of a mobile instance",
+		Long: `Show the model, Android version, screen and installed apps of a connected
+mobile sandbox. JSON output also includes every system property.
+
+Examples:
+  agr instance mobile info ins-xxxx
+  agr instance mobile info ins-xxxx --all-packages -o json`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+		},
+		Flags: []command.FlagSpec{
+			{Name: "all-packages", Usage: "List system packages too, not only installed apps", Type: command.FlagBool},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileDeviceInfo"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runInfo(ctx, req, rt)
+			})}, nil
+		},
+	}
+}
+
+func runInfo(_ context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	props, err := device.Properties()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device properties: %w", err)
+	}
+	size, density, err := device.ScreenSize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read screen size: %w", err)
+	}
+	pkgs, err := device.Packages(!req.Flags["all-packages"].Bool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	packages := make([]map[string]any, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		packages = append(packages, map[string]any{"Package": pkg.Name, "VersionCode": pkg.VersionCode})
+	}
+	sdk, _ := strconv.Atoi(props["ro.build.version.sdk"])
+	data := map[string]any{
+		"InstanceId":     instanceID,
+		"Serial":         device.Serial,
+		"Manufacturer":   props["ro.product.manufacturer"],
+		"Model":          props["ro.product.model"],
+		"AndroidVersion": props["ro.build.version.release"],
+		"SdkVersion":     sdk,
+		"Abi":            props["ro.product.cpu.abi"],
+		"Fingerprint":    props["ro.build.fingerprint"],
+		"ScreenSize":     size,
+		"Density":        density,
+		"Packages":       packages,
+		"Properties":     props,
+	}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		instanceview.PrintKV(w, []instanceview.KeyValue{
+			{Key: "Instance ID", Value: instanceID},
+			{Key: "Serial", Value: device.Serial},
+			{Key: "Model", Value: fmt.Sprintf("%s %s", props["ro.product.manufacturer"], props["ro.product.model"])},
+			{Key: "Android", Value: fmt.Sprintf("%s (API %s)", props["ro.build.version.release"], props["ro.build.version.sdk"])},
+			{Key: "ABI", Value: props["ro.product.cpu.abi"]},
+			{Key: "Screen", Value: fmt.Sprintf("%s @ %d dpi", size, density)},
+			{Key: "Packages", Value: strconv.Itoa(len(pkgs))},
+		})
+		if len(pkgs) > 0 {
+			fmt.Fprintln(w)
+		}
+		for _, pkg := range pkgs {
+			fmt.Fprintf(w, "  %s (%s)\n", pkg.Name, pkg.VersionCode)
+		}
+	}}, nil
+}
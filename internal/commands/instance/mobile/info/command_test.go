@@ -0,0 +1,45 @@
+package info
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+)
+
+func TestRunInfo(t *testing.T) {
+	replies := map[string]string{
+		"shell getprop": "[ro.product.manufacturer]: [Google]\n[ro.product.model]: [Pixel 7]\n" +
+			"[ro.build.version.release]: [14]\n[ro.build.version.sdk]: [34]\n[ro.product.cpu.abi]: [arm64-v8a]\n",
+		"shell wm size; wm density":                       "Physical size: 1080x2400\nPhysical density: 420\n",
+		"shell pm list packages -f --show-versioncode -3": "package:/data/app/~~a/com.app-1/base.apk=com.app versionCode:7\n",
+	}
+	adb := &adbdevicetest.ADB{Reply: func(args ...string) (string, string, int, error) {
+		return replies[strings.Join(args, " ")], "", 0, nil
+	}}
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1"}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	data := result.Data.(map[string]any)
+	if data["Model"] != "Pixel 7" || data["SdkVersion"] != 34 || data["ScreenSize"] != "1080x2400" || data["Density"] != 420 {
+		t.Fatalf("data = %#v", data)
+	}
+	packages := data["Packages"].([]map[string]any)
+	if len(packages) != 1 || packages[0]["Package"] != "com.app" || packages[0]["VersionCode"] != "7" {
+		t.Fatalf("packages = %#v", packages)
+	}
+	if data["Properties"].(map[string]string)["ro.product.cpu.abi"] != "arm64-v8a" {
+		t.Fatalf("properties = %#v", data["Properties"])
+	}
+
+	var out bytes.Buffer
+	result.Text(&out)
+	for _, want := range []string{"Model:        Google Pixel 7", "Android:      14 (API 34)", "  com.app (7)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("text output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.install",
+		Path:  []string{"instance", "mobile", "install"},
+		Use:   "install <instance-id> <apk>...",
+		Short: "Install APKs on a mobile instance",
+		Long: `Install APKs on a connected mobile sandbox, replacing installed versions,
+and report the package name and version of each installed app.
+
+Each APK is installed as its own app. With --split all APKs are installed
+together as the base and split APKs of one app, and a directory argument always
+stands for the split APKs it contains, such as the output of bundletool.
+
+Examples:
+  agr instance mobile install ins-xxxx app.apk
+  agr instance mobile install ins-xxxx one.apk two.apk --grant
+  agr instance mobile install ins-xxxx base.apk split_config.arm64_v8a.apk --split
+  agr instance mobile install ins-xxxx ./splits/ -o json`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "apk", Required: true, Repeatable: true},
+		},
+		Flags: []command.FlagSpec{
+			{Name: "split", Usage: "Install the APKs as splits of one app", Type: command.FlagBool},
+			{Name: "grant", Shorthand: "g", Usage: "Grant all runtime permissions", Type: command.FlagBool},
+			{Name: "downgrade", Usage: "Allow installing a lower version", Type: command.FlagBool},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileInstallResult"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runInstall(ctx, req, deps, rt)
+			})}, nil
+		},
+	}
+}
+
+// installed is the outcome of installing one app.
+type installed struct {
+	APKs        []string
+	Package     string
+	VersionCode string
+	VersionName string
+}
+
+func runInstall(_ context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	if len(req.Args) < 2 {
+		return nil, output.NewUsageError("INVALID_LOCAL_PATH", "missing APK", "Pass one or more APK files or a directory of split APKs.")
+	}
+	units, err := installUnits(req.Args[1:], req.Flags["split"].Bool)
+	if err != nil {
+		return nil, err
+	}
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	opts := mobileadb.InstallOptions{Grant: req.Flags["grant"].Bool, Downgrade: req.Flags["downgrade"].Bool}
+
+	before, err := device.Packages(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	results := make([]installed, 0, len(units))
+	for _, apks := range units {
+		fmt.Fprintf(deps.IO.ErrOut, "Installing %s...\n", strings.Join(baseNames(apks), ", "))
+		opts.Split = len(apks) > 1
+		if err := device.Install(apks, opts); err != nil {
+			return nil, err
+		}
+		after, err := device.Packages(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list packages: %w", err)
+		}
+		result := installed{APKs: apks}
+		if pkg, ok := changedPackage(before, after); ok {
+			result.Package, result.VersionCode = pkg.Name, pkg.VersionCode
+			if result.VersionName, err = device.VersionName(pkg.Name); err != nil {
+				fmt.Fprintf(deps.IO.ErrOut, "Warning: failed to read the version of %s: %v\n", pkg.Name, err)
+			}
+		}
+		results = append(results, result)
+		before = after
+	}
+
+	items := make([]map[string]any, 0, len(results))
+	for _, r := range results {
+		items = append(items, map[string]any{
+			"Apks":        r.APKs,
+			"Package":     r.Package,
+			"VersionCode": r.VersionCode,
+			"VersionName": r.VersionName,
+		})
+	}
+	data := map[string]any{"InstanceId": instanceID, "Serial": device.Serial, "Installed": items}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		for _, r := range results {
+			if r.Package == "" {
+				fmt.Fprintf(w, "Installed %s\n", strings.Join(baseNames(r.APKs), ", "))
+				continue
+			}
+			fmt.Fprintf(w, "Installed %s %s (%s)\n", r.Package, r.VersionName, r.VersionCode)
+		}
+	}}, nil
+}
+
+// installUnits groups the APK arguments into one list per app, in argument
+// order. With split, all files form one app where the first file appears.
+func installUnits(args []string, split bool) ([][]string, error) {
+	var units [][]string
+	filesUnit := -1
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("APK %q does not exist", arg), "Pass existing APK files or a directory of split APKs.")
+		}
+		if !info.IsDir() {
+			if !split {
+				units = append(units, []string{arg})
+			} else if filesUnit < 0 {
+				filesUnit = len(units)
+				units = append(units, []string{arg})
+			} else {
+				units[filesUnit] = append(units[filesUnit], arg)
+			}
+			continue
+		}
+		apks, _ := filepath.Glob(filepath.Join(arg, "*.apk"))
+		if len(apks) == 0 {
+			return nil, output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("directory %q contains no APKs", arg), "Pass a directory that contains the base and split APKs.")
+		}
+		sort.Strings(apks)
+		units = append(units, apks)
+	}
+	return units, nil
+}
+
+// changedPackage returns the package that an install added or replaced. A
+// reinstall moves the package to a new path on current Android versions.
+func changedPackage(before, after []mobileadb.Package) (mobileadb.Package, bool) {
+	old := make(map[string]mobileadb.Package, len(before))
+	for _, pkg := range before {
+		old[pkg.Name] = pkg
+	}
+	for _, pkg := range after {
+		if prev, ok := old[pkg.Name]; !ok || prev != pkg {
+			return pkg, true
+		}
+	}
+	return mobileadb.Package{}, false
+}
+
+func baseNames(paths []string) []string {
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = filepath.Base(path)
+	}
+	return names
+}
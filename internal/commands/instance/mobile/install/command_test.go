@@ -0,0 +1,111 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+)
+
+func TestRunInstallReportsInstalledPackages(t *testing.T) {
+	dir := t.TempDir()
+	app := writeFile(t, dir, "app.apk")
+	splits := filepath.Join(dir, "splits")
+	if err := os.Mkdir(splits, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	base := writeFile(t, splits, "base.apk")
+	config := writeFile(t, splits, "split_config.en.apk")
+
+	adb := &fakeADB{packages: []string{"package:/system/app/S/S.apk=com.system versionCode:1\n"}}
+	adb.onInstall = func(call string) {
+		switch {
+		case strings.HasPrefix(call, "install -r -g "):
+			adb.packages = append(adb.packages, "package:/data/app/~~a/com.app-1/base.apk=com.app versionCode:7\n")
+		case strings.HasPrefix(call, "install-multiple -r -g "):
+			adb.packages = append(adb.packages, "package:/data/app/~~b/com.split-1/base.apk=com.split versionCode:42\n")
+		}
+	}
+	result, err := run(t, adb, command.Request{
+		Args:  []string{"ins-1", app, splits},
+		Flags: map[string]command.FlagValue{"grant": {Name: "grant", Type: command.FlagBool, Bool: true}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	wantInstalls := []string{"install -r -g " + app, "install-multiple -r -g " + base + " " + config}
+	if strings.Join(adb.installs, "\n") != strings.Join(wantInstalls, "\n") {
+		t.Fatalf("installs = %q, want %q", adb.installs, wantInstalls)
+	}
+	items := result.Data.(map[string]any)["Installed"].([]map[string]any)
+	if len(items) != 2 || items[0]["Package"] != "com.app" || items[0]["VersionCode"] != "7" || items[0]["VersionName"] != "com.app-1.0" {
+		t.Fatalf("items = %#v", items)
+	}
+	if items[1]["Package"] != "com.split" || len(items[1]["Apks"].([]string)) != 2 {
+		t.Fatalf("items = %#v", items)
+	}
+}
+
+func TestRunInstallSplitFlagGroupsFiles(t *testing.T) {
+	dir := t.TempDir()
+	base, split := writeFile(t, dir, "base.apk"), writeFile(t, dir, "split.apk")
+	adb := &fakeADB{}
+	_, err := run(t, adb, command.Request{
+		Args:  []string{"ins-1", base, split},
+		Flags: map[string]command.FlagValue{"split": {Name: "split", Type: command.FlagBool, Bool: true}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(adb.installs) != 1 || adb.installs[0] != "install-multiple -r "+base+" "+split {
+		t.Fatalf("installs = %q", adb.installs)
+	}
+}
+
+func TestRunInstallRejectsMissingAPK(t *testing.T) {
+	_, err := run(t, &fakeADB{}, command.Request{Args: []string{"ins-1", filepath.Join(t.TempDir(), "missing.apk")}})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("error = %v, want missing APK", err)
+	}
+}
+
+type fakeADB struct {
+	packages  []string
+	installs  []string
+	onInstall func(call string)
+}
+
+func (f *fakeADB) reply(args ...string) (string, string, int, error) {
+	call := strings.Join(args, " ")
+	switch {
+	case strings.HasPrefix(call, "install"):
+		f.installs = append(f.installs, call)
+		if f.onInstall != nil {
+			f.onInstall(call)
+		}
+		return "Success\n", "", 0, nil
+	case strings.HasPrefix(call, "shell pm list packages"):
+		return strings.Join(f.packages, ""), "", 0, nil
+	case strings.HasPrefix(call, "shell dumpsys package "):
+		pkg := strings.Trim(strings.TrimPrefix(call, "shell dumpsys package "), "'")
+		return "Packages:\n    versionName=" + pkg + "-1.0\n", "", 0, nil
+	}
+	return "", "unexpected call", 1, nil
+}
+
+func run(t *testing.T, adb *fakeADB, req command.Request) (*command.Result, error) {
+	t.Helper()
+	return adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(&adbdevicetest.ADB{Reply: adb.reply}), req)
+}
+
+func writeFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("apk"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
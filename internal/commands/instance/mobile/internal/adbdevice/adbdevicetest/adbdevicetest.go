@@ -0,0 +1,88 @@
+// Package adbdevicetest provides a fake adb binary and tunnel registry for
+// testing the 'agr instance mobile' action commands without a device.
+package adbdevicetest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+)
+
+// Serial is the device serial of ins-1, whose tunnel listens on port 5555.
+const Serial = "127.0.0.1:5555"
+
+// Store is a tunnel registry that answers from the map.
+type Store map[string]tunnelstore.TunnelEntry
+
+// Get returns the tunnel of id.
+func (s Store) Get(id string) (tunnelstore.TunnelEntry, bool, error) {
+	entry, ok := s[id]
+	return entry, ok, nil
+}
+
+// ADB records adb invocations and answers them with Reply. It is safe for
+// concurrent use.
+type ADB struct {
+	// Reply answers a call given its arguments after "-s <serial>". A nil
+	// Reply succeeds without output.
+	Reply func(args ...string) (stdout, stderr string, exitCode int, err error)
+
+	mu    sync.Mutex
+	calls []string
+}
+
+// Run is an adbdevice.RuntimeDeps.RunADB hook.
+func (a *ADB) Run(_ string, args ...string) (string, string, int, error) {
+	a.mu.Lock()
+	a.calls = append(a.calls, strings.Join(args, " "))
+	a.mu.Unlock()
+	if a.Reply == nil {
+		return "", "", 0, nil
+	}
+	return a.Reply(args[2:]...)
+}
+
+// Calls returns the recorded invocations, for example
+// "-s 127.0.0.1:5555 shell input tap 540 1200".
+func (a *ADB) Calls() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.calls...)
+}
+
+// RuntimeDeps returns hooks that run adb through a against ins-1.
+func RuntimeDeps(a *ADB) adbdevice.RuntimeDeps {
+	return adbdevice.RuntimeDeps{
+		NewStore:   func() (adbdevice.Store, error) { return Store{"ins-1": {Port: 5555}}, nil },
+		RequireADB: func() (string, error) { return "/adb", nil },
+		RunADB:     a.Run,
+	}
+}
+
+// Run builds module with rt as its data-plane hooks and runs req.
+func Run(t *testing.T, module command.Module, rt any, req command.Request) (*command.Result, error) {
+	t.Helper()
+	return RunWithDeps(t, module, command.Deps{IO: IO(), DataPlane: rt}, req)
+}
+
+// RunWithDeps builds module with deps and runs req.
+func RunWithDeps(t *testing.T, module command.Module, deps command.Deps, req command.Request) (*command.Result, error) {
+	t.Helper()
+	runtime, err := module.Build(deps)
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	return runtime.Handler.Run(context.Background(), req)
+}
+
+// IO returns streams backed by buffers.
+func IO() *iostreams.IOStreams {
+	return &iostreams.IOStreams{In: &bytes.Buffer{}, Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+}
@@ -0,0 +1,172 @@
+// Package adbdevice resolves the device of a mobile sandbox for the
+// 'agr instance mobile' action commands from the local ADB tunnel registered
+// by 'agr instance mobile connect'.
+package adbdevice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Store is the tunnel registry reader used to resolve an instance id to its
+// local adb tunnel port.
+type Store interface {
+	Get(string) (tunnelstore.TunnelEntry, bool, error)
+}
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps struct {
+	NewStore   func() (Store, error)
+	RequireADB func() (string, error)
+	RunADB     func(adbPath string, args ...string) (stdout string, stderr string, exitCode int, err error)
+	StreamADB  func(ctx context.Context, adbPath string, args []string, stdout io.Writer) error
+}
+
+// Defaults fills the hooks missing from injected.
+func Defaults(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	if rt.NewStore == nil {
+		rt.NewStore = func() (Store, error) { return tunnelstore.NewStore() }
+	}
+	if rt.RequireADB == nil {
+		rt.RequireADB = mobileadb.Require
+	}
+	if rt.RunADB == nil {
+		rt.RunADB = mobileadb.RunBuffered
+	}
+	if rt.StreamADB == nil {
+		rt.StreamADB = mobileadb.RunContext
+	}
+	return rt
+}
+
+// Groups returns the command groups of the mobile commands.
+func Groups() []command.GroupSpec {
+	return []command.GroupSpec{
+		{Path: []string{"instance"}, Use: "instance", Short: "Manage sandbox instances", Long: "Manage sandbox instances and related data-plane workflows.", Aliases: []string{"i"}},
+		{Path: []string{"instance", "mobile"}, Use: "mobile", Short: "Mobile sandbox ADB commands", Long: `Manage ADB connections to mobile sandbox instances.
+
+Examples:
+  agr instance mobile connect <instance-id>
+  agr instance mobile list
+  agr instance mobile adb <instance-id> -- shell ls /sdcard
+  agr instance mobile disconnect <instance-id>`},
+	}
+}
+
+// InstanceID returns the instance-id argument.
+func InstanceID(req command.Request) string {
+	if id := req.ArgValues["instance-id"]; id != "" {
+		return id
+	}
+	if len(req.Args) > 0 {
+		return req.Args[0]
+	}
+	return ""
+}
+
+// Arg returns the argument name, found at index of the positional arguments
+// when the request has no named values.
+func Arg(req command.Request, name string, index int) string {
+	if value := req.ArgValues[name]; value != "" {
+		return value
+	}
+	if len(req.Args) > index {
+		return req.Args[index]
+	}
+	return ""
+}
+
+// Open returns the device of instanceID through its connected tunnel.
+func Open(rt RuntimeDeps, instanceID string) (*mobileadb.Device, error) {
+	adbPath, err := rt.RequireADB()
+	if err != nil {
+		return nil, output.NewUsageError("ADB_NOT_FOUND", err.Error(), "Install Android SDK Platform-Tools or set ADB_PATH to a valid adb binary.")
+	}
+	store, err := rt.NewStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tunnel store: %w", err)
+	}
+	entry, ok, err := store.Get(instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tunnel store: %w", err)
+	}
+	if !ok {
+		return nil, output.NewNotFoundError("NO_ACTIVE_TUNNEL",
+			fmt.Sprintf("no active tunnel for %s; run 'agr instance mobile connect %s' first", instanceID, instanceID),
+			"Run 'agr instance mobile connect <instance-id>' to establish a local ADB tunnel first.")
+	}
+	if entry.SupervisorExit != "" {
+		return nil, output.NewNotFoundError("NO_ACTIVE_TUNNEL",
+			fmt.Sprintf("the tunnel for %s is down: %s", instanceID, entry.SupervisorExit),
+			"Run 'agr instance mobile connect <instance-id>' to reconnect.")
+	}
+	return &mobileadb.Device{ADBPath: adbPath, Serial: mobileadb.LocalSerial(entry.Port), Run: rt.RunADB, Stream: rt.StreamADB}, nil
+}
+
+// Coordinates parses screen coordinates given as arguments.
+func Coordinates(names []string, values []string) ([]int, error) {
+	out := make([]int, len(names))
+	for i, name := range names {
+		if i >= len(values) {
+			return nil, output.NewUsageError("INVALID_COORDINATE", fmt.Sprintf("missing %s", name), "Pass screen coordinates in pixels.")
+		}
+		n, err := strconv.Atoi(values[i])
+		if err != nil || n < 0 {
+			return nil, output.NewUsageError("INVALID_COORDINATE", fmt.Sprintf("invalid %s %q", name, values[i]), "Pass screen coordinates as non-negative pixels.")
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+// LocalSize returns the total size and number of regular files at path, which
+// may be a file or a directory.
+func LocalSize(path string) (bytes int64, files int, err error) {
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		bytes += info.Size()
+		files++
+		return nil
+	})
+	return bytes, files, err
+}
+
+// TransferResult returns the result of copying files between localPath and
+// remotePath on the device with serial.
+func TransferResult(instanceID, serial, localPath, remotePath string, bytes int64, files int) *command.Result {
+	data := map[string]any{
+		"InstanceId": instanceID,
+		"Serial":     serial,
+		"LocalPath":  localPath,
+		"RemotePath": remotePath,
+		"Files":      files,
+		"Bytes":      bytes,
+	}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		instanceview.PrintKV(w, []instanceview.KeyValue{
+			{Key: "Instance ID", Value: instanceID},
+			{Key: "Local path", Value: localPath},
+			{Key: "Remote path", Value: remotePath},
+			{Key: "Files", Value: strconv.Itoa(files)},
+			{Key: "Bytes", Value: strconv.FormatInt(bytes, 10)},
+		})
+	}}
+}
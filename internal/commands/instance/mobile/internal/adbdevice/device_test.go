@@ -0,0 +1,82 @@
+package adbdevice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+)
+
+func TestOpenResolvesTunnelSerial(t *testing.T) {
+	rt := Defaults(RuntimeDeps{
+		NewStore:   func() (Store, error) { return fakeStore{"ins-1": {Port: 5555}}, nil },
+		RequireADB: func() (string, error) { return "/adb", nil },
+	})
+	device, err := Open(rt, "ins-1")
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if device.ADBPath != "/adb" || device.Serial != "127.0.0.1:5555" || device.Run == nil {
+		t.Fatalf("device = %#v", device)
+	}
+	_, err = Open(rt, "ins-2")
+	if err == nil || !strings.Contains(err.Error(), "agr instance mobile connect ins-2") {
+		t.Fatalf("error = %v, want no active tunnel", err)
+	}
+}
+
+func TestOpenReportsWhySupervisorGaveUp(t *testing.T) {
+	rt := Defaults(RuntimeDeps{
+		NewStore: func() (Store, error) {
+			return fakeStore{"ins-1": {Port: 5555, SupervisorExit: "instance ins-1 is STOPPED"}}, nil
+		},
+		RequireADB: func() (string, error) { return "/adb", nil },
+	})
+	_, err := Open(rt, "ins-1")
+	if err == nil || !strings.Contains(err.Error(), "instance ins-1 is STOPPED") {
+		t.Fatalf("error = %v, want the supervisor exit reason", err)
+	}
+}
+
+func TestCoordinates(t *testing.T) {
+	got, err := Coordinates([]string{"x", "y"}, []string{"10", "20"})
+	if err != nil || got[0] != 10 || got[1] != 20 {
+		t.Fatalf("got = %v, err = %v", got, err)
+	}
+	for _, values := range [][]string{{"10"}, {"10", "-1"}, {"a", "1"}} {
+		if _, err := Coordinates([]string{"x", "y"}, values); err == nil {
+			t.Fatalf("Coordinates(%v) returned no error", values)
+		}
+	}
+}
+
+func TestLocalSize(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"a.txt": 3, "sub/b.txt": 5} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bytes, files, err := LocalSize(dir); err != nil || bytes != 8 || files != 2 {
+		t.Fatalf("LocalSize(dir) = %d, %d, %v", bytes, files, err)
+	}
+	if bytes, files, err := LocalSize(filepath.Join(dir, "a.txt")); err != nil || bytes != 3 || files != 1 {
+		t.Fatalf("LocalSize(file) = %d, %d, %v", bytes, files, err)
+	}
+	if _, _, err := LocalSize(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("LocalSize(missing) returned no error")
+	}
+}
+
+type fakeStore map[string]tunnelstore.TunnelEntry
+
+func (f fakeStore) Get(id string) (tunnelstore.TunnelEntry, bool, error) {
+	entry, ok := f[id]
+	return entry, ok, nil
+}
@@ -0,0 +1,163 @@
+// Package tunnelconn starts the background ADB tunnel of a mobile sandbox,
+// records it in the tunnel registry and connects the local adb server to it.
+// It is shared by 'agr instance mobile connect' and the commands that need a
+// tunnel for the duration of one run.
+package tunnelconn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/internal/tunneldaemon"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+)
+
+// Store is the tunnel registry dependency used to replace stale connections and
+// persist the new tunnel.
+type Store interface {
+	Get(string) (tunnelstore.TunnelEntry, bool, error)
+	Cleanup(string) error
+	Save(string, tunnelstore.TunnelEntry) error
+}
+
+// TunnelReady is the daemon readiness payload emitted by the hidden tunnel
+// command and consumed by Connect.
+type TunnelReady struct {
+	Port    int
+	PID     int
+	ExePath string
+	LogPath string
+}
+
+// RuntimeDeps contains adb, config, tunnel, and store hooks that tests can
+// replace without spawning a daemon process.
+type RuntimeDeps struct {
+	RequireADB     func() (string, error)
+	ValidateConfig func() error
+	NewStore       func() (Store, error)
+	DisconnectADB  func(adbPath, addr string) error
+	StartTunnel    func(ctx context.Context, instanceID string, port int) (TunnelReady, error)
+	ConnectADB     func(adbPath, addr string, maxRetries int, out io.Writer) error
+	// StartSupervisor starts the daemon that restarts the tunnel of
+	// instanceID on port when it dies. It returns the supervisor's PID.
+	StartSupervisor func(ctx context.Context, instanceID string, port int) (int, error)
+}
+
+// Defaults fills the hooks missing from injected.
+func Defaults(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	if rt.RequireADB == nil {
+		rt.RequireADB = mobileadb.Require
+	}
+	if rt.ValidateConfig == nil {
+		rt.ValidateConfig = config.Validate
+	}
+	if rt.NewStore == nil {
+		rt.NewStore = func() (Store, error) { return tunnelstore.NewStore() }
+	}
+	if rt.DisconnectADB == nil {
+		rt.DisconnectADB = func(adbPath, addr string) error {
+			return mobileadb.Run(adbPath, "disconnect", addr)
+		}
+	}
+	if rt.StartTunnel == nil {
+		rt.StartTunnel = startTunnelDaemon
+	}
+	if rt.ConnectADB == nil {
+		rt.ConnectADB = mobileadb.ConnectWithRetry
+	}
+	if rt.StartSupervisor == nil {
+		rt.StartSupervisor = startSupervisorDaemon
+	}
+	return rt
+}
+
+// Options configures Connect.
+type Options struct {
+	// Port is the local port to listen on; 0 picks a free port.
+	Port int
+	// Supervise starts a supervisor that restarts the tunnel when it dies.
+	Supervise bool
+}
+
+// Connection is the outcome of connecting one instance.
+type Connection struct {
+	Ready   TunnelReady
+	Address string
+	// ADBErr is set when the tunnel is up but adb connect failed.
+	ADBErr error
+	// SupervisorPID is set when a supervisor was started.
+	SupervisorPID int
+	Warnings      []string
+}
+
+// Connect replaces any existing tunnel of instanceID with a new daemon,
+// records it in the store as created at now, optionally starts its supervisor
+// and connects adb to it. The output of adb connect is written to out. An
+// error means no tunnel is running; a failed adb connect is reported in
+// Connection.ADBErr instead.
+func Connect(ctx context.Context, rt RuntimeDeps, store Store, adbPath, instanceID string, opts Options, now time.Time, out io.Writer) (Connection, error) {
+	var conn Connection
+	if oldEntry, ok, _ := store.Get(instanceID); ok {
+		_ = rt.DisconnectADB(adbPath, mobileadb.LocalSerial(oldEntry.Port))
+	}
+	if err := store.Cleanup(instanceID); err != nil {
+		conn.Warnings = append(conn.Warnings, fmt.Sprintf("failed to cleanup existing tunnel: %v", err))
+	}
+
+	ready, err := rt.StartTunnel(ctx, instanceID, opts.Port)
+	if err != nil {
+		return conn, err
+	}
+	conn.Ready = ready
+
+	if err := store.Save(instanceID, tunnelstore.TunnelEntry{
+		PID:       ready.PID,
+		Port:      ready.Port,
+		CreatedAt: now,
+		ExePath:   ready.ExePath,
+		LogPath:   ready.LogPath,
+	}); err != nil {
+		conn.Warnings = append(conn.Warnings, fmt.Sprintf("failed to save tunnel mapping: %v", err))
+	}
+
+	if opts.Supervise {
+		pid, err := rt.StartSupervisor(ctx, instanceID, ready.Port)
+		if err != nil {
+			conn.Warnings = append(conn.Warnings, fmt.Sprintf("failed to start tunnel supervisor: %v", err))
+		}
+		conn.SupervisorPID = pid
+	}
+
+	conn.Address = mobileadb.LocalSerial(ready.Port)
+	conn.ADBErr = rt.ConnectADB(adbPath, conn.Address, 3, out)
+	return conn, nil
+}
+
+// Close disconnects adb from the tunnel of instanceID and stops the tunnel.
+func Close(rt RuntimeDeps, store Store, adbPath, instanceID string, conn Connection) error {
+	_ = rt.DisconnectADB(adbPath, conn.Address)
+	return store.Cleanup(instanceID)
+}
+
+func startTunnelDaemon(_ context.Context, instanceID string, port int) (TunnelReady, error) {
+	args := []string{"instance", "mobile", "tunnel", instanceID, "--daemon", fmt.Sprintf("--port=%d", port)}
+	proc, err := tunneldaemon.Start(args, "tunnel-"+instanceID)
+	if err != nil {
+		return TunnelReady{}, err
+	}
+	return TunnelReady(proc), nil
+}
+
+func startSupervisorDaemon(_ context.Context, instanceID string, port int) (int, error) {
+	args := []string{"instance", "mobile", "supervise", instanceID, "--daemon", fmt.Sprintf("--port=%d", port)}
+	proc, err := tunneldaemon.Start(args, "supervise-"+instanceID)
+	if err != nil {
+		return 0, err
+	}
+	return proc.PID, nil
+}
@@ -0,0 +1,80 @@
+package keyevent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.keyevent",
+		Path:  []string{"instance", "mobile", "keyevent"},
+		Use:   "keyevent <instance-id> <key>...",
+		Short: "Send key events to a mobile instance",
+		Long: `Send key events to a connected mobile sandbox, in order. A key is an
+Android key code such as 3, or its name with or without the KEYCODE_ prefix,
+in any case: home, back, enter, app_switch, volume_up.
+
+Examples:
+  agr instance mobile keyevent ins-xxxx home
+  agr instance mobile keyevent ins-xxxx tab tab enter`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "key", Required: true, Repeatable: true},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileInputResult"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runKeyEvent(ctx, req, rt)
+			})}, nil
+		},
+	}
+}
+
+func runKeyEvent(_ context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	if len(req.Args) < 2 {
+		return nil, output.NewUsageError("INVALID_KEYCODE", "missing key", "Pass a key code or name, for example home or 3.")
+	}
+	keys := make([]string, 0, len(req.Args)-1)
+	for _, key := range req.Args[1:] {
+		code, err := mobileadb.KeyCode(key)
+		if err != nil {
+			return nil, output.NewUsageError("INVALID_KEYCODE", err.Error(), "Pass a key code or name, for example home or 3.")
+		}
+		keys = append(keys, code)
+	}
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := device.KeyEvent(keys...); err != nil {
+		return nil, err
+	}
+	data := map[string]any{"InstanceId": instanceID, "Action": "keyevent", "Keys": keys}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		fmt.Fprintf(w, "Sent %s to %s\n", strings.Join(keys, " "), instanceID)
+	}}, nil
+}
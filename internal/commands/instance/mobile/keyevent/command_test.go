@@ -0,0 +1,29 @@
+package keyevent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+)
+
+func TestRunKeyEvent(t *testing.T) {
+	adb := &adbdevicetest.ADB{}
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", "home", "KEYCODE_BACK", "66"}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if strings.Join(adb.Calls(), "\n") != "-s 127.0.0.1:5555 shell input keyevent KEYCODE_HOME KEYCODE_BACK 66" {
+		t.Fatalf("adb calls = %q", adb.Calls())
+	}
+	if keys := result.Data.(map[string]any)["Keys"].([]string); len(keys) != 3 || keys[0] != "KEYCODE_HOME" {
+		t.Fatalf("keys = %v", keys)
+	}
+
+	adb = &adbdevicetest.ADB{}
+	_, err = adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", "home;reboot"}})
+	if err == nil || !strings.Contains(err.Error(), "invalid key") || len(adb.Calls()) != 0 {
+		t.Fatalf("error = %v, calls = %q, want invalid key before running adb", err, adb.Calls())
+	}
+}
@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
@@ -26,18 +30,39 @@ type RuntimeDeps struct {
 	NewStore   func() (Store, error)
 	RequireADB func() (string, error)
 	RunADB     func(adbPath string, args ...string) error
+	// Wait returns a context that is done on SIGINT or SIGTERM; it ends
+	// --watch.
+	Wait func(context.Context) (context.Context, context.CancelFunc)
+	// Sleep waits between --watch polls and reports whether ctx is active.
+	Sleep func(ctx context.Context, d time.Duration) bool
 }
 
 // Module returns this package's command module.
 func Module() command.Module {
 	spec := command.Spec{
-		ID:           "instance.mobile.list",
-		Path:         []string{"instance", "mobile", "list"},
-		Use:          "list",
-		Short:        "List active mobile instance connections",
-		SupportsJSON: true,
-		Flags:        []command.FlagSpec{{Name: "prune", Usage: "Remove unreachable connections (kill tunnel, adb disconnect)", Type: command.FlagBool}},
-		Output:       command.OutputSpec{DataType: "MobileConnectionList"},
+		ID:    "instance.mobile.list",
+		Path:  []string{"instance", "mobile", "list"},
+		Use:   "list",
+		Short: "List active mobile instance connections",
+		Long: `List active mobile instance connections.
+
+With --watch the command keeps polling the tunnel registry and prints a line
+whenever a connection appears, changes status (connected, unreachable or,
+when supervised, restarting), is restarted or goes away, until Ctrl+C. With
+-o ndjson every change is a "state" event.`,
+		Examples: []string{
+			"agr instance mobile list",
+			"agr instance mobile list --prune",
+			"agr instance mobile list --watch -o ndjson",
+		},
+		SupportsJSON:   true,
+		SupportsNDJSON: true,
+		Flags: []command.FlagSpec{
+			{Name: "prune", Usage: "Remove unreachable connections (kill tunnel, adb disconnect)", Type: command.FlagBool},
+			{Name: "watch", Shorthand: "w", Usage: "Stream connection state changes until Ctrl+C", Type: command.FlagBool},
+			{Name: "interval", Usage: "How often --watch polls, for example 2s", Type: command.FlagString, Default: defaultWatchInterval.String()},
+		},
+		Output: command.OutputSpec{DataType: "MobileConnectionList"},
 	}
 	return command.Module{
 		Descriptor: command.Descriptor{
@@ -62,6 +87,9 @@ Examples:
 				if flag, ok := req.Flags["prune"]; ok {
 					prune = flag.Bool
 				}
+				if flag, ok := req.Flags["watch"]; ok && flag.Bool {
+					return runWatch(ctx, req, deps, rt, prune)
+				}
 				return runList(ctx, deps, rt, prune)
 			})}, nil
 		},
@@ -79,12 +107,24 @@ func runtimeDeps(injected any) RuntimeDeps {
 	if rt.RunADB == nil {
 		rt.RunADB = mobileadb.Run
 	}
+	if rt.Wait == nil {
+		rt.Wait = func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		}
+	}
+	if rt.Sleep == nil {
+		rt.Sleep = sleep
+	}
 	return rt
 }
 
 // entryStatus returns the display status for a tunnel entry.
-// Empty Status field (from older tunnel daemons) defaults to "connected".
+// Empty Status field (from older tunnel daemons) defaults to "connected". A
+// supervised entry whose daemon has died is "restarting".
 func entryStatus(entry tunnelstore.TunnelEntry) string {
+	if entry.SupervisorPID != 0 && !entry.Alive() {
+		return "restarting"
+	}
 	if entry.Status == "" {
 		return "connected"
 	}
@@ -127,14 +167,20 @@ func runList(_ context.Context, deps command.Deps, rt RuntimeDeps, prune bool) (
 	for id, entry := range entries {
 		addr := fmt.Sprintf("127.0.0.1:%d", entry.Port)
 		status := entryStatus(entry)
-		items = append(items, map[string]any{
+		item := map[string]any{
 			"InstanceId": id,
 			"AdbAddress": addr,
 			"Port":       entry.Port,
 			"Pid":        entry.PID,
 			"CreatedAt":  entry.CreatedAt.Format(time.RFC3339),
 			"Status":     status,
-		})
+			"Supervised": entry.SupervisorPID != 0,
+			"Restarts":   entry.Restarts,
+		}
+		if entry.SupervisorExit != "" {
+			item["SupervisorExit"] = entry.SupervisorExit
+		}
+		items = append(items, item)
 	}
 	data := map[string]any{"Items": items, "Total": len(items)}
 	return &command.Result{Data: data, Text: func(w io.Writer) {
@@ -143,12 +189,13 @@ func runList(_ context.Context, deps command.Deps, rt RuntimeDeps, prune bool) (
 			fmt.Fprintln(deps.IO.ErrOut, "Use 'agr instance mobile connect <instance-id>' to connect.")
 			return
 		}
-		headers := []string{"INSTANCE", "ADB ADDRESS", "STATUS"}
+		headers := []string{"INSTANCE", "ADB ADDRESS", "STATUS", "RESTARTS"}
 		rows := make([][]string, 0, len(entries))
 		for id, entry := range entries {
 			addr := fmt.Sprintf("127.0.0.1:%d", entry.Port)
-			rows = append(rows, []string{id, addr, entryStatus(entry)})
+			rows = append(rows, []string{id, addr, entryStatus(entry), strconv.Itoa(entry.Restarts)})
 		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
 		printTable(w, headers, rows)
 	}}, nil
 }
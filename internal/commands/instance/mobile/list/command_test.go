@@ -3,12 +3,14 @@ package list
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
 )
@@ -101,6 +103,122 @@ func TestModuleReturnsListError(t *testing.T) {
 	}
 }
 
+func TestModuleWatchStreamsStateTransitions(t *testing.T) {
+	degraded := time.Date(2026, 5, 21, 10, 0, 5, 0, time.UTC)
+	store := &pollStore{polls: []map[string]tunnelstore.TunnelEntry{
+		{"ins-1": {PID: 1, Port: 5555}},
+		{"ins-1": {PID: 1, Port: 5555}, "ins-2": {PID: 2, Port: 5556}},
+		{"ins-1": {PID: 1, Port: 5555, Status: "unreachable", DegradedAt: &degraded}, "ins-2": {PID: 2, Port: 5556}},
+		{"ins-1": {PID: 3, Port: 5555, Restarts: 1}},
+	}}
+	ios, _, stdout, _ := iostreams.Test()
+	result, err := watchList(t, ios, store, command.Request{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone {
+		t.Fatalf("result=%#v", result)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	want := []string{
+		"ins-1  127.0.0.1:5555  connected",
+		"ins-2  127.0.0.1:5556  connected",
+		"ins-1  127.0.0.1:5555  connected -> unreachable  degraded since 2026-05-21T10:00:05Z",
+		"ins-1  127.0.0.1:5555  unreachable -> connected  restarts 1",
+		"ins-2  127.0.0.1:5556  connected -> disconnected",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("stdout=%q", stdout.String())
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Fatalf("line %d = %q, want suffix %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestModuleWatchWritesNDJSONEvents(t *testing.T) {
+	config.SetOutput("ndjson")
+	t.Cleanup(func() { config.SetOutput("") })
+	store := &pollStore{polls: []map[string]tunnelstore.TunnelEntry{
+		{"ins-1": {PID: 1, Port: 5555}},
+		{},
+	}}
+	ios, _, stdout, _ := iostreams.Test()
+	if _, err := watchList(t, ios, store, command.Request{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 4 || events[0]["Type"] != "started" || events[1]["Type"] != "state" || events[3]["Type"] != "completed" {
+		t.Fatalf("events=%v", events)
+	}
+	gone := events[2]["Data"].(map[string]any)
+	if gone["Status"] != "disconnected" || gone["PreviousStatus"] != "connected" || gone["InstanceId"] != "ins-1" {
+		t.Fatalf("event=%v", events[2])
+	}
+}
+
+func TestModuleWatchRejectsJSONAndShortInterval(t *testing.T) {
+	config.SetOutput("json")
+	t.Cleanup(func() { config.SetOutput("") })
+	_, err := watchList(t, testIO(), &pollStore{}, command.Request{})
+	if err == nil || !strings.Contains(err.Error(), "does not support -o json") {
+		t.Fatalf("error=%v, want STREAM_JSON_CONFLICT", err)
+	}
+	config.SetOutput("")
+	_, err = watchList(t, testIO(), &pollStore{}, command.Request{Flags: map[string]command.FlagValue{"interval": {String: "10ms"}}})
+	if err == nil || !strings.Contains(err.Error(), "invalid --interval") {
+		t.Fatalf("error=%v, want INVALID_INTERVAL", err)
+	}
+}
+
+// watchList runs list --watch, polling store once per snapshot.
+func watchList(t *testing.T, ios *iostreams.IOStreams, store *pollStore, req command.Request) (*command.Result, error) {
+	t.Helper()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			NewStore: func() (Store, error) { return store, nil },
+			Wait: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				return context.WithCancel(ctx)
+			},
+			Sleep: func(context.Context, time.Duration) bool { return store.next < len(store.polls) },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if req.Flags == nil {
+		req.Flags = map[string]command.FlagValue{}
+	}
+	req.Flags["watch"] = command.FlagValue{Name: "watch", Type: command.FlagBool, Bool: true}
+	return runtime.Handler.Run(context.Background(), req)
+}
+
+// pollStore returns one snapshot per List call.
+type pollStore struct {
+	polls []map[string]tunnelstore.TunnelEntry
+	next  int
+}
+
+func (p *pollStore) List() (map[string]tunnelstore.TunnelEntry, error) {
+	if p.next >= len(p.polls) {
+		return map[string]tunnelstore.TunnelEntry{}, nil
+	}
+	entries := p.polls[p.next]
+	p.next++
+	return entries, nil
+}
+
+func (p *pollStore) Cleanup(string) error { return nil }
+
 type fakeStore struct {
 	entries map[string]tunnelstore.TunnelEntry
 	err     error
@@ -0,0 +1,192 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const (
+	// defaultWatchInterval is how often --watch polls the tunnel registry.
+	defaultWatchInterval = 2 * time.Second
+	// minWatchInterval keeps --watch from spinning on the registry lock.
+	minWatchInterval = 500 * time.Millisecond
+	// eventState is the NDJSON event type of one state change.
+	eventState = "state"
+	// statusDisconnected is reported when a connection leaves the registry.
+	statusDisconnected = "disconnected"
+)
+
+// connState is the watched part of a connection.
+type connState struct {
+	Port       int
+	Status     string
+	DegradedAt *time.Time
+	Restarts   int
+}
+
+// transition is a change of one connection between two polls.
+type transition struct {
+	InstanceID string
+	Previous   string
+	State      connState
+}
+
+func runWatch(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps, prune bool) (*command.Result, error) {
+	ndjson := cli.IsNDJSON()
+	if cli.IsJSONOutput() && !ndjson {
+		return nil, output.NewUsageError("STREAM_JSON_CONFLICT",
+			"agr instance mobile list --watch streams state changes and does not support -o json",
+			"Use -o ndjson for one event per change, or drop --watch to get a JSON envelope.")
+	}
+	interval := defaultWatchInterval
+	if text := req.Flags["interval"].String; text != "" {
+		d, err := time.ParseDuration(text)
+		if err != nil || d < minWatchInterval {
+			return nil, output.NewUsageError("INVALID_INTERVAL", fmt.Sprintf("invalid --interval %q", text), "Use a duration of at least 500ms, for example 2s.")
+		}
+		interval = d
+	}
+	store, err := rt.NewStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tunnel store: %w", err)
+	}
+	if prune {
+		if entries, err := store.List(); err == nil {
+			pruneUnreachable(deps, rt, store, entries)
+		}
+	}
+
+	var nw *output.NDJSONWriter
+	if ndjson {
+		nw = output.NewNDJSONWriter(deps.IO.Out, "instance.mobile.list")
+		_ = nw.WriteStarted(map[string]any{"Interval": interval.String()})
+	}
+	waitCtx, stop := rt.Wait(ctx)
+	defer stop()
+
+	var prev map[string]connState
+	count := 0
+	for {
+		entries, err := store.List()
+		var recovered *tunnelstore.CorruptStoreRecoveredError
+		if errors.As(err, &recovered) {
+			fmt.Fprintf(deps.IO.ErrOut, "Warning: %s\n", recovered.Error())
+			entries, err = map[string]tunnelstore.TunnelEntry{}, nil
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to list tunnels: %w", err)
+			if nw != nil {
+				cliErr := cli.ClassifyCLIError(err)
+				_ = nw.WriteFailed(map[string]any{"Transitions": count}, cliErr.Failure)
+				return &command.Result{StreamDone: true, ExitCode: cliErr.ExitCode}, nil
+			}
+			return nil, err
+		}
+		cur := snapshot(entries)
+		for _, t := range transitions(prev, cur) {
+			count++
+			if nw != nil {
+				_ = nw.WriteEvent(eventState, t.data(deps.Now()))
+			} else {
+				fmt.Fprintln(deps.IO.Out, t.String(deps.Now()))
+			}
+		}
+		prev = cur
+		if !rt.Sleep(waitCtx, interval) {
+			break
+		}
+	}
+	if nw != nil {
+		_ = nw.WriteCompleted(map[string]any{"Transitions": count})
+	}
+	return &command.Result{StreamDone: true}, nil
+}
+
+func snapshot(entries map[string]tunnelstore.TunnelEntry) map[string]connState {
+	states := make(map[string]connState, len(entries))
+	for id, entry := range entries {
+		states[id] = connState{Port: entry.Port, Status: entryStatus(entry), DegradedAt: entry.DegradedAt, Restarts: entry.Restarts}
+	}
+	return states
+}
+
+// transitions returns the connections that appeared, changed or went away
+// between prev and cur, ordered by instance ID. Every connection of the first
+// poll, when prev is nil, is reported with an empty previous status.
+func transitions(prev, cur map[string]connState) []transition {
+	var out []transition
+	for id, state := range cur {
+		old, ok := prev[id]
+		if ok && old.Status == state.Status && old.Restarts == state.Restarts && old.Port == state.Port && sameTime(old.DegradedAt, state.DegradedAt) {
+			continue
+		}
+		out = append(out, transition{InstanceID: id, Previous: old.Status, State: state})
+	}
+	for id, old := range prev {
+		if _, ok := cur[id]; !ok {
+			out = append(out, transition{InstanceID: id, Previous: old.Status, State: connState{Port: old.Port, Status: statusDisconnected, Restarts: old.Restarts}})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].InstanceID < out[j].InstanceID })
+	return out
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func (t transition) data(now time.Time) map[string]any {
+	data := map[string]any{
+		"Timestamp":      now.Format(time.RFC3339),
+		"InstanceId":     t.InstanceID,
+		"AdbAddress":     mobileadb.LocalSerial(t.State.Port),
+		"Status":         t.State.Status,
+		"PreviousStatus": t.Previous,
+		"Restarts":       t.State.Restarts,
+	}
+	if t.State.DegradedAt != nil {
+		data["DegradedAt"] = t.State.DegradedAt.Format(time.RFC3339)
+	}
+	return data
+}
+
+// String formats the transition as one line of text output.
+func (t transition) String(now time.Time) string {
+	change := t.State.Status
+	if t.Previous != "" {
+		change = t.Previous + " -> " + t.State.Status
+	}
+	parts := []string{now.Format(time.RFC3339), t.InstanceID, mobileadb.LocalSerial(t.State.Port), change}
+	if t.State.DegradedAt != nil {
+		parts = append(parts, "degraded since "+t.State.DegradedAt.Format(time.RFC3339))
+	}
+	if t.State.Restarts > 0 {
+		parts = append(parts, fmt.Sprintf("restarts %d", t.State.Restarts))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// sleep waits for d and reports whether ctx is still active.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
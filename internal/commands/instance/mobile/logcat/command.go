@@ -0,0 +1,216 @@
+package logcat
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// eventLog is the NDJSON event type of one log entry.
+const eventLog = "log"
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps struct {
+	adbdevice.RuntimeDeps
+	// Wait returns a context that is done on SIGINT or SIGTERM.
+	Wait func(context.Context) (context.Context, context.CancelFunc)
+}
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.logcat",
+		Path:  []string{"instance", "mobile", "logcat"},
+		Use:   "logcat <instance-id>",
+		Short: "Stream the device log of a mobile instance",
+		Long: `Stream the device log of a connected mobile sandbox until Ctrl+C, or print the
+buffered log and exit with --dump.
+
+Entries can be filtered by tag, minimum level and app. --package keeps the
+entries of the app's processes, including processes started while streaming.
+With -o ndjson every entry is a "log" event with Timestamp, Pid, Tid, Level,
+Tag and Message; with --dump, -o json returns all entries at once.
+
+Examples:
+  agr instance mobile logcat ins-xxxx --package com.example.app
+  agr instance mobile logcat ins-xxxx --level warn --tag ActivityManager --tag AndroidRuntime
+  agr instance mobile logcat ins-xxxx --clear -o ndjson
+  agr instance mobile logcat ins-xxxx --dump --level error -o json`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+		},
+		Flags: []command.FlagSpec{
+			{Name: "tag", Shorthand: "t", Usage: "Only show entries with this tag (repeatable)", Type: command.FlagStringArray},
+			{Name: "level", Shorthand: "l", Usage: "Minimum level: verbose, debug, info, warn, error or fatal", Type: command.FlagString},
+			{Name: "package", Usage: "Only show entries of this app's processes", Type: command.FlagString},
+			{Name: "dump", Shorthand: "d", Usage: "Print the buffered log and exit", Type: command.FlagBool},
+			{Name: "clear", Shorthand: "c", Usage: "Clear the log buffers first", Type: command.FlagBool},
+		},
+		SupportsJSON:   true,
+		SupportsNDJSON: true,
+		Output:         command.OutputSpec{DataType: "MobileLogEntryList"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := runtimeDeps(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runLogcat(ctx, req, deps, rt)
+			})}, nil
+		},
+	}
+}
+
+func runtimeDeps(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	rt.RuntimeDeps = adbdevice.Defaults(rt.RuntimeDeps)
+	if rt.Wait == nil {
+		rt.Wait = func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		}
+	}
+	return rt
+}
+
+// filter selects the entries to show.
+type filter struct {
+	tags  map[string]bool
+	level string
+	pkg   string
+
+	mu   sync.Mutex
+	pids map[int]bool
+}
+
+// match reports whether entry passes the filter. Process starts of the
+// package are tracked before filtering so that restarts keep matching.
+func (f *filter) match(entry mobileadb.LogEntry) bool {
+	if f.pkg != "" {
+		f.mu.Lock()
+		if pid, process, ok := mobileadb.ProcessStart(entry); ok && (process == f.pkg || strings.HasPrefix(process, f.pkg+":")) {
+			f.pids[pid] = true
+		}
+		ok := f.pids[entry.PID]
+		f.mu.Unlock()
+		if !ok {
+			return false
+		}
+	}
+	if len(f.tags) > 0 && !f.tags[entry.Tag] {
+		return false
+	}
+	return f.level == "" || entry.AtLeast(f.level)
+}
+
+func runLogcat(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	dump := req.Flags["dump"].Bool
+	ndjson := cli.IsNDJSON()
+	if cli.IsJSONOutput() && !ndjson && !dump {
+		return nil, output.NewUsageError("STREAM_JSON_CONFLICT",
+			"agr instance mobile logcat streams the log and supports -o json only with --dump",
+			"Use -o ndjson for one event per entry, or add --dump to get a JSON envelope.")
+	}
+	f := &filter{tags: map[string]bool{}, pkg: req.Flags["package"].String, pids: map[int]bool{}}
+	for _, tag := range req.Flags["tag"].Strings {
+		f.tags[tag] = true
+	}
+	if text := req.Flags["level"].String; text != "" {
+		level, err := mobileadb.LogLevel(text)
+		if err != nil {
+			return nil, output.NewUsageError("INVALID_LOG_LEVEL", err.Error(), "Use verbose, debug, info, warn, error or fatal.")
+		}
+		f.level = level
+	}
+
+	device, err := adbdevice.Open(rt.RuntimeDeps, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Flags["clear"].Bool {
+		if err := device.ClearLogcat(); err != nil {
+			return nil, fmt.Errorf("failed to clear the log: %w", err)
+		}
+	}
+	if f.pkg != "" {
+		pids, err := device.PIDs(f.pkg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the processes of %s: %w", f.pkg, err)
+		}
+		for _, pid := range pids {
+			f.pids[pid] = true
+		}
+		if len(pids) == 0 && !dump {
+			fmt.Fprintf(deps.IO.ErrOut, "%s is not running; waiting for it to start.\n", f.pkg)
+		}
+	}
+
+	streamCtx, stop := rt.Wait(ctx)
+	defer stop()
+	var entries []map[string]any
+	var nw *output.NDJSONWriter
+	if ndjson {
+		nw = output.NewNDJSONWriter(deps.IO.Out, "instance.mobile.logcat")
+		_ = nw.WriteStarted(map[string]any{"InstanceId": instanceID, "Serial": device.Serial})
+	}
+	count := 0
+	err = device.Logcat(streamCtx, dump, func(entry mobileadb.LogEntry) {
+		if !f.match(entry) {
+			return
+		}
+		count++
+		switch {
+		case nw != nil:
+			_ = nw.WriteEvent(eventLog, entryData(entry))
+		case cli.IsJSONOutput():
+			entries = append(entries, entryData(entry))
+		default:
+			fmt.Fprintln(deps.IO.Out, entry.String())
+		}
+	})
+	if err != nil {
+		if nw != nil {
+			cliErr := cli.ClassifyCLIError(err)
+			_ = nw.WriteFailed(map[string]any{"InstanceId": instanceID, "Entries": count}, cliErr.Failure)
+			return &command.Result{StreamDone: true, ExitCode: cliErr.ExitCode}, nil
+		}
+		return nil, fmt.Errorf("logcat failed: %w", err)
+	}
+	if nw != nil {
+		_ = nw.WriteCompleted(map[string]any{"InstanceId": instanceID, "Entries": count})
+		return &command.Result{StreamDone: true}, nil
+	}
+	if cli.IsJSONOutput() {
+		if entries == nil {
+			entries = []map[string]any{}
+		}
+		return &command.Result{Data: map[string]any{"InstanceId": instanceID, "Items": entries, "Total": len(entries)}}, nil
+	}
+	return &command.Result{StreamDone: true}, nil
+}
+
+func entryData(entry mobileadb.LogEntry) map[string]any {
+	return map[string]any{
+		"Timestamp": entry.Time,
+		"Pid":       entry.PID,
+		"Tid":       entry.TID,
+		"Level":     entry.Level,
+		"Tag":       entry.Tag,
+		"Message":   entry.Message,
+	}
+}
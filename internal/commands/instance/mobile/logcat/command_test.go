@@ -0,0 +1,145 @@
+package logcat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+)
+
+const deviceLog = `--------- beginning of main
+06-01 12:00:00.000   100   100 I App     : started
+06-01 12:00:00.100   300   300 E Other   : not the app
+06-01 12:00:01.000   500   520 I ActivityManager: Start proc 200:com.example.app:remote/u0a1 for service
+06-01 12:00:01.500   200   201 W App     : remote process
+06-01 12:00:02.000   100   100 D App     : debug line
+`
+
+func TestRunLogcatFiltersByPackageAndLevel(t *testing.T) {
+	var calls []string
+	ios, stdout := testIO()
+	result, err := run(t, ios, &calls, command.Request{
+		Args: []string{"ins-1"},
+		Flags: map[string]command.FlagValue{
+			"package": {Name: "package", Type: command.FlagString, String: "com.example.app"},
+			"level":   {Name: "level", Type: command.FlagString, String: "info"},
+			"clear":   {Name: "clear", Type: command.FlagBool, Bool: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone {
+		t.Fatalf("result = %#v", result)
+	}
+	want := "06-01 12:00:00.000   100   100 I App: started\n06-01 12:00:01.500   200   201 W App: remote process\n"
+	if stdout.String() != want {
+		t.Fatalf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if strings.Join(calls, "\n") != "logcat -c\nshell pidof 'com.example.app'\nlogcat -v threadtime" {
+		t.Fatalf("adb calls = %q", calls)
+	}
+}
+
+func TestRunLogcatEmitsNDJSONEvents(t *testing.T) {
+	config.SetOutput("ndjson")
+	t.Cleanup(func() { config.SetOutput("text") })
+
+	var calls []string
+	ios, stdout := testIO()
+	_, err := run(t, ios, &calls, command.Request{
+		Args:  []string{"ins-1"},
+		Flags: map[string]command.FlagValue{"tag": {Name: "tag", Type: command.FlagStringArray, Strings: []string{"Other"}}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("events = %q", lines)
+	}
+	var event struct {
+		SchemaVersion string
+		Type          string
+		Data          map[string]any
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("invalid event %q: %v", lines[1], err)
+	}
+	if event.SchemaVersion != "agr.events.v1" || event.Type != "log" || event.Data["Pid"] != float64(300) || event.Data["Level"] != "E" || event.Data["Message"] != "not the app" {
+		t.Fatalf("event = %#v", event)
+	}
+	if !strings.Contains(lines[2], `"Type":"completed"`) || !strings.Contains(lines[2], `"Entries":1`) {
+		t.Fatalf("completed = %q", lines[2])
+	}
+}
+
+func TestRunLogcatJSONRequiresDump(t *testing.T) {
+	config.SetOutput("json")
+	t.Cleanup(func() { config.SetOutput("text") })
+
+	var calls []string
+	ios, _ := testIO()
+	_, err := run(t, ios, &calls, command.Request{Args: []string{"ins-1"}})
+	if err == nil || !strings.Contains(err.Error(), "--dump") {
+		t.Fatalf("error = %v, want --dump usage", err)
+	}
+	result, err := run(t, ios, &calls, command.Request{
+		Args:  []string{"ins-1"},
+		Flags: map[string]command.FlagValue{"dump": {Name: "dump", Type: command.FlagBool, Bool: true}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if data := result.Data.(map[string]any); data["Total"] != 5 {
+		t.Fatalf("data = %#v", data)
+	}
+	if calls[len(calls)-1] != "logcat -v threadtime -d" {
+		t.Fatalf("adb calls = %q", calls)
+	}
+}
+
+func run(t *testing.T, ios *iostreams.IOStreams, calls *[]string, req command.Request) (*command.Result, error) {
+	t.Helper()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			RuntimeDeps: adbdevice.RuntimeDeps{
+				NewStore:   func() (adbdevice.Store, error) { return adbdevicetest.Store{"ins-1": {Port: 5555}}, nil },
+				RequireADB: func() (string, error) { return "/adb", nil },
+				RunADB: func(_ string, args ...string) (string, string, int, error) {
+					*calls = append(*calls, strings.Join(args[2:], " "))
+					if args[2] == "shell" {
+						return "100\n", "", 0, nil
+					}
+					return "", "", 0, nil
+				},
+				StreamADB: func(_ context.Context, _ string, args []string, stdout io.Writer) error {
+					*calls = append(*calls, strings.Join(args[2:], " "))
+					_, err := io.WriteString(stdout, deviceLog)
+					return err
+				},
+			},
+			Wait: func(ctx context.Context) (context.Context, context.CancelFunc) {
+				return context.WithCancel(ctx)
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	return runtime.Handler.Run(context.Background(), req)
+}
+
+func testIO() (*iostreams.IOStreams, *bytes.Buffer) {
+	stdout := &bytes.Buffer{}
+	return &iostreams.IOStreams{In: &bytes.Buffer{}, Out: stdout, ErrOut: &bytes.Buffer{}}, stdout
+}
@@ -0,0 +1,214 @@
+// Package mirror implements 'agr instance mobile mirror', which opens a local
+// scrcpy window on the screen of a mobile sandbox through its ADB tunnel.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/tunnelconn"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const (
+	defaultBitRate = "4M"
+	defaultMaxSize = 1280
+	defaultMaxFPS  = 30
+)
+
+// RuntimeDeps contains the tunnel hooks shared with connect plus scrcpy hooks,
+// so tests can run without scrcpy, adb or a tunnel daemon.
+type RuntimeDeps struct {
+	tunnelconn.RuntimeDeps
+	RequireScrcpy func() (string, error)
+	RunScrcpy     func(scrcpyPath, adbPath string, args []string, stdin io.Reader, stdout, stderr io.Writer) (int, error)
+	// HoldSignals absorbs SIGINT and SIGTERM until the returned func is
+	// called, so closing scrcpy with Ctrl+C still cleans up a tunnel created
+	// for the mirror.
+	HoldSignals func() (release func())
+}
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.mirror",
+		Path:  []string{"instance", "mobile", "mirror"},
+		Use:   "mirror <instance-id> [-- scrcpy-args...]",
+		Short: "Mirror and control a mobile instance with scrcpy",
+		Long: `Open a local scrcpy window on the screen of a mobile sandbox.
+
+The ADB tunnel registered by 'agr instance mobile connect' is reused when
+present. Otherwise a tunnel is started for the mirror and removed when scrcpy
+exits. scrcpy is looked up in SCRCPY_PATH, then PATH, and uses the same adb
+binary as agr. Arguments after '--' are passed to scrcpy unchanged.`,
+		Examples: []string{
+			"agr instance mobile mirror ins-xxxx",
+			"agr instance mobile mirror ins-xxxx --bit-rate 8M --max-size 0",
+			"agr instance mobile mirror ins-xxxx --no-control -- --stay-awake",
+		},
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "scrcpy-args", Repeatable: true},
+		},
+		Flags: []command.FlagSpec{
+			{Name: "bit-rate", Usage: "Video bit rate, for example 800K or 8M", Type: command.FlagString, Default: defaultBitRate},
+			{Name: "max-size", Usage: "Limit the longer side of the video in pixels (0 = device size)", Type: command.FlagInt, Default: defaultMaxSize},
+			{Name: "max-fps", Usage: "Limit the frame rate (0 = device rate)", Type: command.FlagInt, Default: defaultMaxFPS},
+			{Name: "no-control", Usage: "View only; do not forward keyboard and mouse input", Type: command.FlagBool},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileMirror"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := runtimeDeps(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runMirror(ctx, req, deps, rt)
+			})}, nil
+		},
+	}
+}
+
+func runtimeDeps(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	rt.RuntimeDeps = tunnelconn.Defaults(rt.RuntimeDeps)
+	if rt.RequireScrcpy == nil {
+		rt.RequireScrcpy = mobileadb.RequireScrcpy
+	}
+	if rt.RunScrcpy == nil {
+		rt.RunScrcpy = mobileadb.RunScrcpy
+	}
+	if rt.HoldSignals == nil {
+		rt.HoldSignals = holdSignals
+	}
+	return rt
+}
+
+func runMirror(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	var extra []string
+	if len(req.Args) > 1 {
+		if req.DashPos != 1 {
+			return nil, output.NewUsageError("MISSING_SEPARATOR",
+				"scrcpy arguments must follow '--'",
+				"Use: agr instance mobile mirror <instance-id> -- <scrcpy-args...>")
+		}
+		extra = req.Args[1:]
+	}
+	opts := mobileadb.MirrorOptions{
+		BitRate:   req.Flags["bit-rate"].String,
+		MaxSize:   defaultMaxSize,
+		MaxFPS:    defaultMaxFPS,
+		NoControl: req.Flags["no-control"].Bool,
+		Title:     instanceID,
+	}
+	if opts.BitRate == "" {
+		opts.BitRate = defaultBitRate
+	}
+	if flag, ok := req.Flags["max-size"]; ok && flag.Changed {
+		opts.MaxSize = flag.Int
+	}
+	if flag, ok := req.Flags["max-fps"]; ok && flag.Changed {
+		opts.MaxFPS = flag.Int
+	}
+	if !mobileadb.ValidBitRate(opts.BitRate) {
+		return nil, output.NewUsageError("INVALID_BIT_RATE", fmt.Sprintf("invalid --bit-rate %q", opts.BitRate), "Use a positive bit rate with an optional K or M suffix, for example 4M.")
+	}
+	if opts.MaxSize < 0 || opts.MaxFPS < 0 {
+		return nil, output.NewUsageError("INVALID_USAGE", "--max-size and --max-fps must not be negative", "Use 0 to keep the device size or frame rate.")
+	}
+
+	scrcpyPath, err := rt.RequireScrcpy()
+	if err != nil {
+		return nil, output.NewUsageError("SCRCPY_NOT_FOUND", err.Error(), "Install scrcpy (https://github.com/Genymobile/scrcpy) or set SCRCPY_PATH to a valid scrcpy binary.")
+	}
+	adbPath, err := rt.RequireADB()
+	if err != nil {
+		return nil, output.NewUsageError("ADB_NOT_FOUND", err.Error(), "Install Android SDK Platform-Tools or set ADB_PATH to a valid adb binary.")
+	}
+	store, err := rt.NewStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tunnel store: %w", err)
+	}
+	entry, ok, err := store.Get(instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tunnel store: %w", err)
+	}
+
+	var conn tunnelconn.Connection
+	serial := mobileadb.LocalSerial(entry.Port)
+	created := !ok
+	if created {
+		if err := rt.ValidateConfig(); err != nil {
+			return nil, err
+		}
+		conn, err = tunnelconn.Connect(ctx, rt.RuntimeDeps, store, adbPath, instanceID, tunnelconn.Options{}, deps.Now(), deps.IO.ErrOut)
+		for _, warning := range conn.Warnings {
+			fmt.Fprintf(deps.IO.ErrOut, "Warning: %s\n", warning)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if conn.ADBErr != nil {
+			_ = tunnelconn.Close(rt.RuntimeDeps, store, adbPath, instanceID, conn)
+			return nil, fmt.Errorf("adb connect %s failed: %w", conn.Address, conn.ADBErr)
+		}
+		serial = conn.Address
+	}
+
+	// scrcpy output goes to stderr in JSON mode so stdout holds only the
+	// envelope.
+	stdout := deps.IO.Out
+	if cli.IsJSON() {
+		stdout = deps.IO.ErrOut
+	}
+	release := rt.HoldSignals()
+	exitCode, runErr := rt.RunScrcpy(scrcpyPath, adbPath, mobileadb.ScrcpyArgs(serial, opts, extra), deps.IO.In, stdout, deps.IO.ErrOut)
+	release()
+
+	if created {
+		if err := tunnelconn.Close(rt.RuntimeDeps, store, adbPath, instanceID, conn); err != nil {
+			fmt.Fprintf(deps.IO.ErrOut, "Warning: failed to stop tunnel: %v\n", err)
+		}
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("failed to run scrcpy: %w", runErr)
+	}
+
+	data := map[string]any{
+		"InstanceId":    instanceID,
+		"Serial":        serial,
+		"TunnelCreated": created,
+		"ExitCode":      exitCode,
+	}
+	return &command.Result{Data: data, ExitCode: exitCode, Text: func(w io.Writer) {
+		instanceview.PrintKV(w, []instanceview.KeyValue{
+			{Key: "Instance ID", Value: instanceID},
+			{Key: "Serial", Value: serial},
+			{Key: "Tunnel created", Value: strconv.FormatBool(created)},
+			{Key: "Exit code", Value: strconv.Itoa(exitCode)},
+		})
+	}}, nil
+}
+
+func holdSignals() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	return func() { signal.Stop(signals) }
+}
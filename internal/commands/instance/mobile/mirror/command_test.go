@@ -0,0 +1,161 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/tunnelconn"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+func TestModuleDescriptor(t *testing.T) {
+	spec := Module().Descriptor.Spec
+	if spec.ID != "instance.mobile.mirror" || !spec.SupportsJSON {
+		t.Fatalf("spec = %#v", spec)
+	}
+}
+
+func TestRunMirrorReusesConnectedTunnel(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{"ins-1": {PID: 10, Port: 5555}}}
+	f := &fakeScrcpy{}
+	result, err := run(t, store, f, command.Request{
+		Args:    []string{"ins-1", "--stay-awake"},
+		DashPos: 1,
+		Flags: map[string]command.FlagValue{
+			"no-control": {Name: "no-control", Type: command.FlagBool, Bool: true},
+			"max-fps":    {Name: "max-fps", Type: command.FlagInt, Int: 60, Changed: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := "--serial=127.0.0.1:5555 --video-bit-rate=4M --max-size=1280 --max-fps=60 --no-control --window-title=ins-1 --stay-awake"
+	if got := strings.Join(f.args, " "); got != want {
+		t.Fatalf("scrcpy args = %q, want %q", got, want)
+	}
+	if f.adbPath != "/bin/adb" {
+		t.Fatalf("adb path = %q", f.adbPath)
+	}
+	if store.cleaned != "" || store.saved {
+		t.Fatalf("existing tunnel was touched: %#v", store)
+	}
+	if data := result.Data.(map[string]any); data["TunnelCreated"] != false {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestRunMirrorCreatesAndRemovesTunnel(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{}}
+	f := &fakeScrcpy{code: 2}
+	result, err := run(t, store, f, command.Request{Args: []string{"ins-1"}, DashPos: -1})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if f.args[0] != "--serial=127.0.0.1:6000" {
+		t.Fatalf("scrcpy args = %v", f.args)
+	}
+	if !store.saved || store.cleaned != "ins-1" {
+		t.Fatalf("tunnel was not created and removed: %#v", store)
+	}
+	if result.ExitCode != 2 || result.Data.(map[string]any)["TunnelCreated"] != true {
+		t.Fatalf("result = %#v", result)
+	}
+}
+
+func TestRunMirrorFailsWithoutScrcpy(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{}}
+	_, err := run(t, store, &fakeScrcpy{missing: true}, command.Request{Args: []string{"ins-1"}, DashPos: -1})
+	assertCode(t, err, "SCRCPY_NOT_FOUND")
+}
+
+func TestRunMirrorValidatesArguments(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{}}
+	_, err := run(t, store, &fakeScrcpy{}, command.Request{
+		Args:    []string{"ins-1"},
+		DashPos: -1,
+		Flags:   map[string]command.FlagValue{"bit-rate": {Name: "bit-rate", Type: command.FlagString, String: "4G"}},
+	})
+	assertCode(t, err, "INVALID_BIT_RATE")
+
+	_, err = run(t, store, &fakeScrcpy{}, command.Request{Args: []string{"ins-1", "--stay-awake"}, DashPos: -1})
+	assertCode(t, err, "MISSING_SEPARATOR")
+}
+
+func run(t *testing.T, store *fakeStore, f *fakeScrcpy, req command.Request) (*command.Result, error) {
+	t.Helper()
+	ios, _, _, _ := iostreams.Test()
+	rt := RuntimeDeps{
+		RuntimeDeps: tunnelconn.RuntimeDeps{
+			RequireADB:     func() (string, error) { return "/bin/adb", nil },
+			ValidateConfig: func() error { return nil },
+			NewStore:       func() (tunnelconn.Store, error) { return store, nil },
+			DisconnectADB:  func(string, string) error { return nil },
+			StartTunnel: func(context.Context, string, int) (tunnelconn.TunnelReady, error) {
+				return tunnelconn.TunnelReady{PID: 20, Port: 6000}, nil
+			},
+			ConnectADB: func(string, string, int, io.Writer) error { return nil },
+		},
+		RequireScrcpy: func() (string, error) {
+			if f.missing {
+				return "", errors.New("scrcpy not found in PATH")
+			}
+			return "/bin/scrcpy", nil
+		},
+		RunScrcpy: func(_ string, adbPath string, args []string, _ io.Reader, _, _ io.Writer) (int, error) {
+			f.adbPath, f.args = adbPath, args
+			return f.code, nil
+		},
+		HoldSignals: func() func() { return func() {} },
+	}
+	runtime, err := Module().Build(command.Deps{IO: ios, DataPlane: rt})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	return runtime.Handler.Run(context.Background(), req)
+}
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != code {
+		t.Fatalf("error = %v, want %s", err, code)
+	}
+}
+
+type fakeScrcpy struct {
+	missing bool
+	code    int
+	adbPath string
+	args    []string
+}
+
+type fakeStore struct {
+	entries map[string]tunnelstore.TunnelEntry
+	saved   bool
+	cleaned string
+}
+
+func (f *fakeStore) Get(id string) (tunnelstore.TunnelEntry, bool, error) {
+	entry, ok := f.entries[id]
+	return entry, ok, nil
+}
+
+func (f *fakeStore) Cleanup(id string) error {
+	if f.saved {
+		f.cleaned = id
+	}
+	delete(f.entries, id)
+	return nil
+}
+
+func (f *fakeStore) Save(id string, entry tunnelstore.TunnelEntry) error {
+	f.saved = true
+	f.entries[id] = entry
+	return nil
+}
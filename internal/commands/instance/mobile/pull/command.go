@@ -0,0 +1,87 @@
+package pull
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/localfile"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+)
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.pull",
+		Path:  []string{"instance", "mobile", "pull"},
+		Use:   "pull <instance-id> <remote-path> [local-path]",
+		Short: "Copy files from a mobile instance",
+		Long: `Copy a file or directory from a connected mobile sandbox to local-path
+(default: the current directory) with adb pull through the instance's tunnel.
+The result reports the number of files and bytes transferred.`,
+		Examples: []string{
+			"agr instance mobile pull ins-xxxx /sdcard/Download/report.pdf",
+			"agr instance mobile pull ins-xxxx /sdcard/DCIM ./artifacts -o json",
+		},
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "remote-path", Required: true},
+			{Name: "local-path"},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileFileTransfer"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runPull(ctx, req, rt)
+			})}, nil
+		},
+	}
+}
+
+func runPull(_ context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	remote := adbdevice.Arg(req, "remote-path", 1)
+	local := adbdevice.Arg(req, "local-path", 2)
+	if local == "" {
+		local = "."
+	}
+	// adb pull into an existing directory keeps the remote name.
+	dest := local
+	if info, err := os.Stat(local); err == nil && info.IsDir() {
+		dest = filepath.Join(local, path.Base(remote))
+	}
+	dest, err := localfile.ValidateOutput(dest)
+	if err != nil {
+		return nil, err
+	}
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	transfer, ok, err := device.Pull(remote, dest)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if transfer.Bytes, transfer.Files, err = adbdevice.LocalSize(dest); err != nil {
+			return nil, fmt.Errorf("failed to read pulled files: %w", err)
+		}
+	}
+	return adbdevice.TransferResult(instanceID, device.Serial, dest, remote, transfer.Bytes, transfer.Files), nil
+}
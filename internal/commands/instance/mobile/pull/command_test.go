@@ -0,0 +1,43 @@
+package pull
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+)
+
+func TestRunPullIntoDirectoryKeepsRemoteName(t *testing.T) {
+	dir := t.TempDir()
+	adb := &adbdevicetest.ADB{Reply: func(...string) (string, string, int, error) {
+		return "/sdcard/a.mp4: 1 file pulled, 0 skipped. 9.1 MB/s (123456 bytes in 0.013s)\n", "", 0, nil
+	}}
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", "/sdcard/a.mp4", dir}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	dest := filepath.Join(dir, "a.mp4")
+	if calls := adb.Calls(); len(calls) != 1 || calls[0] != "-s 127.0.0.1:5555 pull /sdcard/a.mp4 "+dest {
+		t.Fatalf("adb calls = %q", calls)
+	}
+	data := result.Data.(map[string]any)
+	if data["LocalPath"] != dest || data["RemotePath"] != "/sdcard/a.mp4" || data["Bytes"] != int64(123456) || data["Files"] != 1 {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestRunPullFallsBackToLocalSize(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "log.txt")
+	adb := &adbdevicetest.ADB{Reply: func(args ...string) (string, string, int, error) {
+		return "", "", 0, os.WriteFile(args[len(args)-1], []byte("hello"), 0o644)
+	}}
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", "/sdcard/log.txt", dest}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if data := result.Data.(map[string]any); data["Bytes"] != int64(5) || data["Files"] != 1 {
+		t.Fatalf("data = %#v", data)
+	}
+}
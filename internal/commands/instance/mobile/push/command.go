@@ -0,0 +1,83 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.push",
+		Path:  []string{"instance", "mobile", "push"},
+		Use:   "push <instance-id> <local-path> <remote-path>",
+		Short: "Copy files to a mobile instance",
+		Long: `Copy a local file or directory to remote-path on a connected mobile sandbox
+with adb push through the instance's tunnel. The result reports the number of
+files and bytes transferred.`,
+		Examples: []string{
+			"agr instance mobile push ins-xxxx fixtures.json /sdcard/Download/",
+			"agr instance mobile push ins-xxxx ./media /sdcard/Pictures -o json",
+		},
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "local-path", Required: true},
+			{Name: "remote-path", Required: true},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileFileTransfer"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runPush(ctx, req, rt)
+			})}, nil
+		},
+	}
+}
+
+func runPush(_ context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	local := adbdevice.Arg(req, "local-path", 1)
+	remote := adbdevice.Arg(req, "remote-path", 2)
+	if _, err := os.Stat(local); err != nil {
+		return nil, output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("local path %q does not exist", local), "Pass an existing file or directory to push.")
+	}
+	local, err := filepath.Abs(local)
+	if err != nil {
+		return nil, output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("invalid local path %q: %v", local, err), "Pass an existing file or directory to push.")
+	}
+	bytes, files, err := adbdevice.LocalSize(local)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", local, err)
+	}
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	transfer, ok, err := device.Push(local, remote)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		bytes, files = transfer.Bytes, transfer.Files
+	}
+	return adbdevice.TransferResult(instanceID, device.Serial, local, remote, bytes, files), nil
+}
@@ -0,0 +1,53 @@
+package push
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+func TestRunPushReportsLocalSizeWithoutSummary(t *testing.T) {
+	adb := &adbdevicetest.ADB{}
+	local := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(local, []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", local, "/sdcard/Download/"}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if calls := adb.Calls(); len(calls) != 1 || calls[0] != "-s 127.0.0.1:5555 push "+local+" /sdcard/Download/" {
+		t.Fatalf("adb calls = %q", calls)
+	}
+	data := result.Data.(map[string]any)
+	if data["LocalPath"] != local || data["Bytes"] != int64(7) || data["Files"] != 1 {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestRunPushPrefersADBSummary(t *testing.T) {
+	dir := t.TempDir()
+	adb := &adbdevicetest.ADB{Reply: func(...string) (string, string, int, error) {
+		return "dir/: 3 files pushed, 0 skipped. 1.2 MB/s (2048 bytes in 0.002s)\n", "", 0, nil
+	}}
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", dir, "/sdcard/"}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if data := result.Data.(map[string]any); data["Bytes"] != int64(2048) || data["Files"] != 3 {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestRunPushRequiresLocalPath(t *testing.T) {
+	_, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(&adbdevicetest.ADB{}), command.Request{Args: []string{"ins-1", filepath.Join(t.TempDir(), "missing"), "/sdcard/"}})
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != "INVALID_LOCAL_PATH" {
+		t.Fatalf("error = %v, want INVALID_LOCAL_PATH", err)
+	}
+}
@@ -0,0 +1,170 @@
+// Package record implements 'agr instance mobile record', which records the
+// screen of a mobile sandbox with screenrecord and pulls the video through
+// the instance's ADB tunnel.
+package record
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/localfile"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const (
+	defaultDuration = 30 * time.Second
+	defaultPath     = "record.mp4"
+)
+
+// RuntimeDeps contains the device hooks plus a signal hook, so tests can run
+// without a real adb binary, tunnel registry or signals.
+type RuntimeDeps struct {
+	adbdevice.RuntimeDeps
+	// Wait returns a context that is done on SIGINT or SIGTERM, which stops
+	// the recording early.
+	Wait func(context.Context) (context.Context, context.CancelFunc)
+}
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.record",
+		Path:  []string{"instance", "mobile", "record"},
+		Use:   "record <instance-id> [local-path]",
+		Short: "Record the screen of a mobile instance",
+		Long: `Record the screen of a connected mobile sandbox to an MP4 file at local-path
+(default record.mp4).
+
+The video is recorded on the device with screenrecord for --duration (at most
+3m), pulled through the instance's tunnel and then removed from the device.
+Ctrl+C stops the recording early and still saves it.`,
+		Examples: []string{
+			"agr instance mobile record ins-xxxx",
+			"agr instance mobile record ins-xxxx demo.mp4 --duration 30s -o json",
+		},
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "local-path"},
+		},
+		Flags: []command.FlagSpec{
+			{Name: "duration", Usage: "Recording length, at most 3m", Type: command.FlagString, Default: defaultDuration.String()},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileRecording"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := runtimeDeps(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runRecord(ctx, req, deps, rt)
+			})}, nil
+		},
+	}
+}
+
+func runtimeDeps(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	rt.RuntimeDeps = adbdevice.Defaults(rt.RuntimeDeps)
+	if rt.Wait == nil {
+		rt.Wait = func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		}
+	}
+	return rt
+}
+
+func runRecord(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	duration := defaultDuration
+	if text := req.Flags["duration"].String; text != "" {
+		d, err := time.ParseDuration(text)
+		if err != nil || d <= 0 || d > mobileadb.MaxScreenRecord {
+			return nil, output.NewUsageError("INVALID_DURATION", fmt.Sprintf("invalid --duration %q", text), "Use a duration between 1s and 3m, for example 30s.")
+		}
+		duration = d
+	}
+	path := adbdevice.Arg(req, "local-path", 1)
+	if path == "" {
+		path = defaultPath
+	}
+	path, err := localfile.ValidateOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	device, err := adbdevice.Open(rt.RuntimeDeps, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := fmt.Sprintf("/sdcard/agr-record-%d.mp4", deps.Now().UnixNano())
+	if !cli.IsJSON() {
+		fmt.Fprintf(deps.IO.ErrOut, "Recording %s for %s. Press Ctrl+C to stop early.\n", instanceID, duration)
+	}
+	waitCtx, stop := rt.Wait(ctx)
+	done := make(chan error, 1)
+	started := deps.Now()
+	go func() { done <- device.ScreenRecord(remote, duration) }()
+	interrupted := false
+	select {
+	case err = <-done:
+	case <-waitCtx.Done():
+		interrupted = true
+		_ = device.StopScreenRecord(remote)
+		err = <-done
+	}
+	stop()
+	// screenrecord stops at the time limit; anything beyond is adb overhead.
+	elapsed := min(deps.Now().Sub(started), duration).Round(100 * time.Millisecond)
+	// screenrecord may report the interrupt as a failure; the file is still
+	// complete.
+	if err != nil && !interrupted {
+		_ = device.Remove(remote)
+		return nil, fmt.Errorf("screenrecord failed: %w", err)
+	}
+
+	transfer, ok, err := device.Pull(remote, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull recording %s: %w", remote, err)
+	}
+	if !ok {
+		if transfer.Bytes, _, err = adbdevice.LocalSize(path); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	var warnings []string
+	if err := device.Remove(remote); err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to remove %s from the device: %v", remote, err))
+	}
+
+	data := map[string]any{
+		"InstanceId":  instanceID,
+		"Serial":      device.Serial,
+		"Path":        path,
+		"Bytes":       transfer.Bytes,
+		"Duration":    elapsed.String(),
+		"Interrupted": interrupted,
+	}
+	return &command.Result{Data: data, Warnings: warnings, Text: func(w io.Writer) {
+		instanceview.PrintKV(w, []instanceview.KeyValue{
+			{Key: "Instance ID", Value: instanceID},
+			{Key: "Path", Value: path},
+			{Key: "Bytes", Value: strconv.FormatInt(transfer.Bytes, 10)},
+		})
+	}}, nil
+}
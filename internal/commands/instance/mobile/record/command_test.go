@@ -0,0 +1,153 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const (
+	remote  = "/sdcard/agr-record-1000000000.mp4"
+	pidFile = "'" + remote + ".pid'"
+)
+
+func TestRunRecordPullsAndRemovesVideo(t *testing.T) {
+	// adb takes a little longer than the recording itself.
+	adb := &fakeADB{elapsed: 10*time.Second + 300*time.Millisecond}
+	path := filepath.Join(t.TempDir(), "demo.mp4")
+	result, err := run(t, adb, nil, command.Request{
+		Args:  []string{"ins-1", path},
+		Flags: map[string]command.FlagValue{"duration": {Name: "duration", Type: command.FlagString, String: "10s"}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	want := []string{
+		"shell echo $$ > " + pidFile + " && exec screenrecord --time-limit 10 '" + remote + "'",
+		"shell rm -f " + pidFile,
+		"pull " + remote + " " + path,
+		"shell rm -f '" + remote + "'",
+	}
+	if got := adb.recorded(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("adb calls = %q, want %q", got, want)
+	}
+	data := result.Data.(map[string]any)
+	if data["Path"] != path || data["Bytes"] != int64(4096) || data["Duration"] != "10s" || data["Interrupted"] != false {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestRunRecordStopsEarlyOnInterrupt(t *testing.T) {
+	adb := &fakeADB{stopped: make(chan struct{}), elapsed: 3200 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := run(t, adb, ctx, command.Request{Args: []string{"ins-1", filepath.Join(t.TempDir(), "demo.mp4")}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	// The recording and kill run concurrently, so only the pull must follow.
+	calls := adb.recorded()
+	stopCall := `shell kill -INT "$(cat ` + pidFile + `)"`
+	if len(calls) != 5 || !strings.Contains(strings.Join(calls[:3], "\n"), stopCall) || !strings.HasPrefix(calls[3], "pull ") {
+		t.Fatalf("adb calls = %q", calls)
+	}
+	data := result.Data.(map[string]any)
+	if data["Interrupted"] != true || data["Duration"] != "3.2s" {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestRunRecordValidatesDuration(t *testing.T) {
+	for _, duration := range []string{"0s", "4m", "soon"} {
+		_, err := run(t, &fakeADB{}, nil, command.Request{
+			Args:  []string{"ins-1", filepath.Join(t.TempDir(), "demo.mp4")},
+			Flags: map[string]command.FlagValue{"duration": {Name: "duration", Type: command.FlagString, String: duration}},
+		})
+		var cliErr *output.CLIError
+		if !errors.As(err, &cliErr) || cliErr.Failure.Code != "INVALID_DURATION" {
+			t.Fatalf("--duration %s: error = %v", duration, err)
+		}
+	}
+}
+
+// run runs the command with ctx as the signal context; nil never fires.
+func run(t *testing.T, adb *fakeADB, ctx context.Context, req command.Request) (*command.Result, error) {
+	t.Helper()
+	rt := RuntimeDeps{
+		RuntimeDeps: adbdevice.RuntimeDeps{
+			NewStore:   func() (adbdevice.Store, error) { return adbdevicetest.Store{"ins-1": {Port: 5555}}, nil },
+			RequireADB: func() (string, error) { return "/adb", nil },
+			RunADB:     adb.run,
+		},
+		Wait: func(parent context.Context) (context.Context, context.CancelFunc) {
+			if ctx != nil {
+				return ctx, func() {}
+			}
+			return context.WithCancel(parent)
+		},
+	}
+	runtime, err := Module().Build(command.Deps{
+		IO:        &iostreams.IOStreams{In: &bytes.Buffer{}, Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}},
+		DataPlane: rt,
+		Now:       adb.now,
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	return runtime.Handler.Run(context.Background(), req)
+}
+
+// fakeADB answers device commands. With stopped set, screenrecord blocks
+// until it is killed. The clock starts at 1s and screenrecord advances it by
+// elapsed.
+type fakeADB struct {
+	mu      sync.Mutex
+	calls   []string
+	stopped chan struct{}
+	elapsed time.Duration
+	clock   time.Duration
+}
+
+func (f *fakeADB) now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return time.Unix(1, 0).Add(f.clock)
+}
+
+func (f *fakeADB) run(_ string, args ...string) (string, string, int, error) {
+	call := strings.Join(args[2:], " ")
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+	switch {
+	case strings.Contains(call, "exec screenrecord"):
+		if f.stopped != nil {
+			<-f.stopped
+		}
+		f.mu.Lock()
+		f.clock += f.elapsed
+		f.mu.Unlock()
+	case strings.HasPrefix(call, "shell kill -INT"):
+		close(f.stopped)
+	case strings.HasPrefix(call, "pull "):
+		return "1 file pulled, 0 skipped. 3.2 MB/s (4096 bytes in 0.001s)\n", "", 0, nil
+	}
+	return "", "", 0, nil
+}
+
+func (f *fakeADB) recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
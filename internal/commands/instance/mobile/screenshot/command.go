@@ -0,0 +1,93 @@
+package screenshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/instanceview"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/internal/localfile"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+)
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.screenshot",
+		Path:  []string{"instance", "mobile", "screenshot"},
+		Use:   "screenshot <instance-id> [local-path]",
+		Short: "Save a screenshot of a mobile instance",
+		Long: `Save a PNG screenshot of a connected mobile sandbox to local-path
+(default screenshot.png).
+
+Examples:
+  agr instance mobile screenshot ins-xxxx
+  agr instance mobile screenshot ins-xxxx home.png -o json`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "local-path"},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileScreenshot"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runScreenshot(ctx, req, rt)
+			})}, nil
+		},
+	}
+}
+
+func runScreenshot(_ context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	path := req.ArgValues["local-path"]
+	if path == "" && len(req.Args) > 1 {
+		path = req.Args[1]
+	}
+	if path == "" {
+		path = "screenshot.png"
+	}
+	path, err := localfile.ValidateOutput(path)
+	if err != nil {
+		return nil, err
+	}
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	image, err := device.Screencap()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, image, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	data := map[string]any{
+		"InstanceId": instanceID,
+		"Serial":     device.Serial,
+		"Path":       path,
+		"Bytes":      len(image),
+	}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		instanceview.PrintKV(w, []instanceview.KeyValue{
+			{Key: "Instance ID", Value: instanceID},
+			{Key: "Path", Value: path},
+			{Key: "Bytes", Value: strconv.Itoa(len(image))},
+		})
+	}}, nil
+}
@@ -0,0 +1,46 @@
+package screenshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+)
+
+func TestRunScreenshotWritesPNG(t *testing.T) {
+	adb := &adbdevicetest.ADB{Reply: func(...string) (string, string, int, error) {
+		return "\x89PNG-image", "", 0, nil
+	}}
+	path := filepath.Join(t.TempDir(), "home.png")
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{
+		Args:      []string{"ins-1", path},
+		ArgValues: map[string]string{"instance-id": "ins-1", "local-path": path},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if calls := adb.Calls(); len(calls) != 1 || calls[0] != "-s 127.0.0.1:5555 exec-out screencap -p" {
+		t.Fatalf("adb calls = %q", calls)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "\x89PNG-image" {
+		t.Fatalf("content = %q, err = %v", content, err)
+	}
+	data := result.Data.(map[string]any)
+	if data["Path"] != path || data["Bytes"] != 10 || data["Serial"] != "127.0.0.1:5555" {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestRunScreenshotRequiresTunnel(t *testing.T) {
+	rt := adbdevicetest.RuntimeDeps(&adbdevicetest.ADB{})
+	rt.NewStore = func() (adbdevice.Store, error) { return adbdevicetest.Store{}, nil }
+	path := filepath.Join(t.TempDir(), "home.png")
+	_, err := adbdevicetest.Run(t, Module(), rt, command.Request{Args: []string{"ins-1", path}})
+	if err == nil || !strings.Contains(err.Error(), "no active tunnel") {
+		t.Fatalf("error = %v, want no active tunnel", err)
+	}
+}
@@ -0,0 +1,317 @@
+// Package supervise implements the hidden supervisor daemon started by
+// 'agr instance mobile connect --supervise'. The supervisor watches the ADB
+// tunnel daemon of one instance and restarts it on the same port when it
+// dies, so the adb serial 127.0.0.1:<port> stays valid. It gives up after
+// repeated failed restarts or once the instance is gone.
+package supervise
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/internal/tunneldaemon"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+)
+
+const (
+	// DefaultPollInterval is how often the tunnel daemon is checked.
+	DefaultPollInterval = 2 * time.Second
+	// DefaultMinBackoff is the delay before the second restart in a row.
+	DefaultMinBackoff = time.Second
+	// DefaultMaxBackoff caps the delay between restarts. A tunnel that stays
+	// up this long resets the delay.
+	DefaultMaxBackoff = time.Minute
+	// DefaultMaxRestarts is how many restarts in a row may fail before the
+	// supervisor gives up. A restart fails when the tunnel daemon does not
+	// start or dies again before staying up for MaxBackoff.
+	DefaultMaxRestarts = 10
+)
+
+// Store is the tunnel registry used to claim the entry and record restarts.
+type Store interface {
+	Get(string) (tunnelstore.TunnelEntry, bool, error)
+	SetSupervisor(string, int) (bool, error)
+	RecordRestart(string, int, time.Time) error
+	RecordSupervisorExit(string, int, string, time.Time) error
+}
+
+// ControlPlane supplies the instance lookup used to stop supervising an
+// instance that is gone.
+type ControlPlane interface {
+	GetInstance(ctx context.Context, instanceID string) (*ags.SandboxInstance, error)
+	IsNotFound(error) bool
+}
+
+// RuntimeDeps contains the store, process, tunnel and timing hooks that tests
+// can replace without spawning daemons.
+type RuntimeDeps struct {
+	NewStore    func() (Store, error)
+	Alive       func(tunnelstore.TunnelEntry) bool
+	StartTunnel func(ctx context.Context, instanceID string, port int) (tunneldaemon.Process, error)
+	// ConnectADB reconnects the local adb server to a restarted tunnel.
+	ConnectADB func(addr string) error
+	// InstanceGone reports why instanceID can no longer be reached, or ""
+	// while it may still come back.
+	InstanceGone func(ctx context.Context, instanceID string) (string, error)
+	// Wait returns a context that is done on SIGINT or SIGTERM.
+	Wait  func(context.Context) (context.Context, context.CancelFunc)
+	Sleep func(ctx context.Context, d time.Duration) bool
+	Now   func() time.Time
+	PID   int
+
+	PollInterval time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	MaxRestarts  int
+}
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:     "instance.mobile.supervise",
+		Path:   []string{"instance", "mobile", "supervise"},
+		Use:    "supervise <instance-id>",
+		Short:  "Restart the ADB tunnel of an instance when it dies (used internally by connect --supervise)",
+		Hidden: true,
+		Args:   []command.ArgSpec{{Name: "instance-id", Required: true}},
+		Flags: []command.FlagSpec{
+			{Name: "daemon", Usage: "Run in daemon mode (used by connect)", Type: command.FlagBool},
+			{Name: "port", Usage: "Local port the tunnel listens on", Type: command.FlagInt, Default: 0},
+		},
+		Output: command.OutputSpec{DataType: "MobileTunnelSupervisor"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec: spec,
+			Groups: []command.GroupSpec{
+				{Path: []string{"instance"}, Use: "instance", Short: "Manage sandbox instances", Long: "Manage sandbox instances and related data-plane workflows.", Aliases: []string{"i"}},
+				{Path: []string{"instance", "mobile"}, Use: "mobile", Short: "Mobile sandbox ADB commands", Long: `Manage ADB connections to mobile sandbox instances.
+
+Examples:
+  agr instance mobile connect <instance-id>
+  agr instance mobile list
+  agr instance mobile adb <instance-id> -- shell ls /sdcard
+  agr instance mobile disconnect <instance-id>`},
+			},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := runtimeDeps(deps.DataPlane, deps.ControlPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runSupervise(ctx, req, deps, rt)
+			})}, nil
+		},
+	}
+}
+
+func runtimeDeps(injected, controlPlane any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	if rt.NewStore == nil {
+		rt.NewStore = func() (Store, error) { return tunnelstore.NewStore() }
+	}
+	if rt.Alive == nil {
+		rt.Alive = tunnelstore.TunnelEntry.Alive
+	}
+	if rt.StartTunnel == nil {
+		rt.StartTunnel = StartTunnelDaemon
+	}
+	if rt.ConnectADB == nil {
+		rt.ConnectADB = func(addr string) error {
+			adbPath, err := mobileadb.Require()
+			if err != nil {
+				return err
+			}
+			return mobileadb.ConnectWithRetry(adbPath, addr, 3, log.Writer())
+		}
+	}
+	if rt.InstanceGone == nil {
+		rt.InstanceGone = func(ctx context.Context, instanceID string) (string, error) {
+			cp, ok := controlPlane.(ControlPlane)
+			if !ok {
+				return "", nil
+			}
+			return instanceGone(ctx, cp, instanceID)
+		}
+	}
+	if rt.Wait == nil {
+		rt.Wait = func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		}
+	}
+	if rt.Sleep == nil {
+		rt.Sleep = sleep
+	}
+	if rt.Now == nil {
+		rt.Now = time.Now
+	}
+	if rt.PID == 0 {
+		rt.PID = os.Getpid()
+	}
+	if rt.PollInterval == 0 {
+		rt.PollInterval = DefaultPollInterval
+	}
+	if rt.MinBackoff == 0 {
+		rt.MinBackoff = DefaultMinBackoff
+	}
+	if rt.MaxBackoff == 0 {
+		rt.MaxBackoff = DefaultMaxBackoff
+	}
+	if rt.MaxRestarts == 0 {
+		rt.MaxRestarts = DefaultMaxRestarts
+	}
+	return rt
+}
+
+// instanceGone reports why instanceID will not serve a tunnel again: it was
+// deleted, or it is stopping, stopped or failed.
+func instanceGone(ctx context.Context, cp ControlPlane, instanceID string) (string, error) {
+	inst, err := cp.GetInstance(ctx, instanceID)
+	if err != nil {
+		if cp.IsNotFound(err) {
+			return fmt.Sprintf("instance %s no longer exists", instanceID), nil
+		}
+		return "", err
+	}
+	status := ""
+	if inst != nil && inst.Status != nil {
+		status = *inst.Status
+	}
+	switch status {
+	case "STOPPING", "STOPPED", "FAILED":
+		return fmt.Sprintf("instance %s is %s", instanceID, status), nil
+	}
+	return "", nil
+}
+
+// StartTunnelDaemon starts the tunnel daemon of instanceID on port.
+func StartTunnelDaemon(_ context.Context, instanceID string, port int) (tunneldaemon.Process, error) {
+	args := []string{"instance", "mobile", "tunnel", instanceID, "--daemon", fmt.Sprintf("--port=%d", port)}
+	return tunneldaemon.Start(args, "tunnel-"+instanceID)
+}
+
+func runSupervise(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := req.ArgValues["instance-id"]
+	if instanceID == "" && len(req.Args) > 0 {
+		instanceID = req.Args[0]
+	}
+	daemon := req.Flags["daemon"].Bool
+	port := req.Flags["port"].Int
+	fail := func(err error) (*command.Result, error) {
+		if daemon {
+			tunneldaemon.WriteError(deps.IO.Out, err.Error())
+		}
+		return nil, err
+	}
+	if port <= 0 || port > 65535 {
+		return fail(output.NewUsageError("INVALID_PORT", "--port must be between 1 and 65535", "Pass the port of the tunnel to supervise."))
+	}
+	store, err := rt.NewStore()
+	if err != nil {
+		return fail(fmt.Errorf("failed to initialize tunnel store: %w", err))
+	}
+	ok, err := store.SetSupervisor(instanceID, rt.PID)
+	if err != nil {
+		return fail(fmt.Errorf("failed to register supervisor: %w", err))
+	}
+	if !ok {
+		return fail(output.NewNotFoundError("NO_ACTIVE_TUNNEL",
+			fmt.Sprintf("no active tunnel for %s", instanceID),
+			"Run 'agr instance mobile connect <instance-id> --supervise' instead."))
+	}
+	if daemon {
+		if err := tunneldaemon.WriteReady(deps.IO.Out, port); err != nil {
+			return nil, fmt.Errorf("failed to write ready message: %w", err)
+		}
+	} else {
+		fmt.Fprintf(deps.IO.ErrOut, "Supervising the tunnel of %s on port %d. Press Ctrl+C to stop.\n", instanceID, port)
+	}
+
+	waitCtx, stop := rt.Wait(ctx)
+	defer stop()
+	supervise(waitCtx, rt, store, instanceID, port)
+	return &command.Result{StreamDone: true}, nil
+}
+
+// supervise restarts the tunnel daemon of instanceID whenever it is found
+// dead, until ctx is done or the entry is removed or taken over by another
+// connect. The first restart is immediate; later ones back off exponentially
+// until the tunnel stays up for MaxBackoff. After MaxRestarts failed restarts
+// in a row, or once the instance is gone, the supervisor records why in the
+// entry and exits.
+func supervise(ctx context.Context, rt RuntimeDeps, store Store, instanceID string, port int) {
+	backoff := rt.MinBackoff
+	failed := 0
+	var next, lastRestart time.Time
+	for rt.Sleep(ctx, rt.PollInterval) {
+		entry, ok, err := store.Get(instanceID)
+		if err != nil {
+			log.Printf("[WARN] Failed to read tunnel store: %v", err)
+			continue
+		}
+		if !ok || entry.SupervisorPID != rt.PID {
+			log.Printf("[INFO] Tunnel of %s was disconnected or replaced; supervisor exiting", instanceID)
+			return
+		}
+		now := rt.Now()
+		if rt.Alive(entry) {
+			if !lastRestart.IsZero() && now.Sub(lastRestart) >= rt.MaxBackoff {
+				backoff, lastRestart, failed = rt.MinBackoff, time.Time{}, 0
+			}
+			continue
+		}
+		if now.Before(next) {
+			continue
+		}
+		reason, err := rt.InstanceGone(ctx, instanceID)
+		if err != nil {
+			log.Printf("[WARN] Failed to look up instance %s: %v", instanceID, err)
+		}
+		if reason == "" && failed >= rt.MaxRestarts {
+			reason = fmt.Sprintf("gave up after %d failed restarts", failed)
+		}
+		if reason != "" {
+			log.Printf("[ERROR] Not restarting the tunnel of %s: %s; supervisor exiting", instanceID, reason)
+			if err := store.RecordSupervisorExit(instanceID, rt.PID, reason, now); err != nil {
+				log.Printf("[WARN] Failed to record supervisor exit: %v", err)
+			}
+			return
+		}
+		failed++
+		log.Printf("[WARN] Tunnel daemon of %s (PID %d) exited; restarting on port %d", instanceID, entry.PID, port)
+		next, lastRestart = now.Add(backoff), now
+		backoff = min(backoff*2, rt.MaxBackoff)
+		proc, err := rt.StartTunnel(ctx, instanceID, port)
+		if err != nil {
+			log.Printf("[ERROR] Failed to restart tunnel: %v", err)
+			continue
+		}
+		if err := store.RecordRestart(instanceID, proc.PID, now); err != nil {
+			log.Printf("[WARN] Failed to record restart: %v", err)
+		}
+		if err := rt.ConnectADB(mobileadb.LocalSerial(proc.Port)); err != nil {
+			log.Printf("[WARN] adb connect after restart failed: %v", err)
+		}
+	}
+}
+
+// sleep waits for d and reports whether ctx is still active.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,319 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/internal/tunneldaemon"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+)
+
+func TestModuleDescriptor(t *testing.T) {
+	spec := Module().Descriptor.Spec
+	if spec.ID != "instance.mobile.supervise" || !spec.Hidden {
+		t.Fatalf("spec = %#v", spec)
+	}
+}
+
+func TestSuperviseRestartsDeadTunnelWithBackoff(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{"ins-1": {PID: 10, Port: 5555, SupervisorPID: 99}}}
+	clock := time.Unix(0, 0)
+	var restarts []time.Duration
+	var connected []string
+	rt := testRuntime(store, &clock, 20)
+	rt.StartTunnel = func(_ context.Context, id string, port int) (tunneldaemon.Process, error) {
+		if id != "ins-1" || port != 5555 {
+			t.Errorf("restarted %s on port %d", id, port)
+		}
+		restarts = append(restarts, clock.Sub(time.Unix(0, 0)))
+		return tunneldaemon.Process{PID: 10 + len(restarts), Port: port}, nil
+	}
+	rt.ConnectADB = func(addr string) error {
+		connected = append(connected, addr)
+		return nil
+	}
+
+	supervise(context.Background(), rt, store, "ins-1", 5555)
+
+	want := []time.Duration{1 * time.Second, 3 * time.Second, 7 * time.Second, 15 * time.Second}
+	if len(restarts) != len(want) {
+		t.Fatalf("restarts at %v, want %v", restarts, want)
+	}
+	for i := range want {
+		if restarts[i] != want[i] {
+			t.Fatalf("restarts at %v, want %v", restarts, want)
+		}
+	}
+	if entry := store.entries["ins-1"]; entry.Restarts != 4 || entry.PID != 14 {
+		t.Fatalf("entry = %#v", entry)
+	}
+	if len(connected) != 4 || connected[0] != "127.0.0.1:5555" {
+		t.Fatalf("adb connect = %v", connected)
+	}
+}
+
+func TestSuperviseResetsBackoffOnceTunnelIsStable(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{"ins-1": {PID: 10, Port: 5555, SupervisorPID: 99}}}
+	clock := time.Unix(0, 0)
+	rt := testRuntime(store, &clock, 30)
+	var restarts []time.Duration
+	rt.StartTunnel = func(context.Context, string, int) (tunneldaemon.Process, error) {
+		restarts = append(restarts, clock.Sub(time.Unix(0, 0)))
+		return tunneldaemon.Process{PID: 11, Port: 5555}, nil
+	}
+	// The tunnel dies at once, stays up from 3s until 20s and then keeps dying.
+	rt.Alive = func(tunnelstore.TunnelEntry) bool {
+		elapsed := clock.Sub(time.Unix(0, 0))
+		return elapsed > 2*time.Second && elapsed < 20*time.Second
+	}
+
+	supervise(context.Background(), rt, store, "ins-1", 5555)
+
+	// Without the reset the restart after 20s would wait 4s.
+	if len(restarts) < 3 || restarts[0] != time.Second || restarts[1] != 20*time.Second || restarts[2] != 22*time.Second {
+		t.Fatalf("restarts at %v, want 1s, 20s and 22s", restarts)
+	}
+}
+
+func TestSuperviseExitsWhenEntryIsRemovedOrReplaced(t *testing.T) {
+	for name, entries := range map[string]map[string]tunnelstore.TunnelEntry{
+		"removed":  {},
+		"replaced": {"ins-1": {PID: 10, Port: 5555, SupervisorPID: 100}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := &fakeStore{entries: entries}
+			clock := time.Unix(0, 0)
+			rt := testRuntime(store, &clock, 100)
+			rt.StartTunnel = func(context.Context, string, int) (tunneldaemon.Process, error) {
+				t.Fatal("tunnel restarted")
+				return tunneldaemon.Process{}, nil
+			}
+			supervise(context.Background(), rt, store, "ins-1", 5555)
+			if clock.Sub(time.Unix(0, 0)) != time.Second {
+				t.Fatalf("supervisor ran until %v", clock)
+			}
+		})
+	}
+}
+
+func TestSuperviseGivesUpAfterRepeatedFailedRestarts(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{"ins-1": {PID: 10, Port: 5555, SupervisorPID: 99}}}
+	clock := time.Unix(0, 0)
+	rt := testRuntime(store, &clock, 100)
+	rt.MaxRestarts = 3
+	restarts := 0
+	rt.StartTunnel = func(context.Context, string, int) (tunneldaemon.Process, error) {
+		restarts++
+		if restarts == 2 {
+			return tunneldaemon.Process{}, errors.New("no route to sandbox")
+		}
+		return tunneldaemon.Process{PID: 11, Port: 5555}, nil
+	}
+
+	supervise(context.Background(), rt, store, "ins-1", 5555)
+
+	if restarts != 3 {
+		t.Fatalf("restarted %d times, want 3", restarts)
+	}
+	entry := store.entries["ins-1"]
+	if entry.SupervisorPID != 0 || entry.SupervisorExit != "gave up after 3 failed restarts" || entry.Status != "unreachable" {
+		t.Fatalf("entry = %#v", entry)
+	}
+	if elapsed := clock.Sub(time.Unix(0, 0)); elapsed > 20*time.Second {
+		t.Fatalf("supervisor ran until %v", elapsed)
+	}
+}
+
+func TestSuperviseExitsWhenInstanceIsGone(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{"ins-1": {PID: 10, Port: 5555, SupervisorPID: 99}}}
+	clock := time.Unix(0, 0)
+	rt := testRuntime(store, &clock, 100)
+	rt.InstanceGone = func(_ context.Context, id string) (string, error) {
+		return "instance " + id + " is STOPPED", nil
+	}
+	rt.StartTunnel = func(context.Context, string, int) (tunneldaemon.Process, error) {
+		t.Fatal("tunnel restarted")
+		return tunneldaemon.Process{}, nil
+	}
+
+	supervise(context.Background(), rt, store, "ins-1", 5555)
+
+	if entry := store.entries["ins-1"]; entry.SupervisorPID != 0 || entry.SupervisorExit != "instance ins-1 is STOPPED" {
+		t.Fatalf("entry = %#v", entry)
+	}
+}
+
+func TestSuperviseKeepsRestartingWhenInstanceLookupFails(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{"ins-1": {PID: 10, Port: 5555, SupervisorPID: 99}}}
+	clock := time.Unix(0, 0)
+	rt := testRuntime(store, &clock, 5)
+	rt.InstanceGone = func(context.Context, string) (string, error) { return "", errors.New("timeout") }
+	restarts := 0
+	rt.StartTunnel = func(context.Context, string, int) (tunneldaemon.Process, error) {
+		restarts++
+		return tunneldaemon.Process{PID: 11, Port: 5555}, nil
+	}
+
+	supervise(context.Background(), rt, store, "ins-1", 5555)
+
+	if restarts == 0 || store.entries["ins-1"].SupervisorExit != "" {
+		t.Fatalf("restarts = %d entry = %#v", restarts, store.entries["ins-1"])
+	}
+}
+
+func TestInstanceGone(t *testing.T) {
+	for _, tc := range []struct {
+		cp   fakeControlPlane
+		want string
+	}{
+		{cp: fakeControlPlane{status: "RUNNING"}},
+		{cp: fakeControlPlane{status: "PAUSED"}},
+		{cp: fakeControlPlane{status: "STOPPED"}, want: "instance ins-1 is STOPPED"},
+		{cp: fakeControlPlane{status: "FAILED"}, want: "instance ins-1 is FAILED"},
+		{cp: fakeControlPlane{err: errNotFound}, want: "instance ins-1 no longer exists"},
+	} {
+		got, err := instanceGone(context.Background(), tc.cp, "ins-1")
+		if err != nil || got != tc.want {
+			t.Fatalf("instanceGone(%+v) = %q, %v; want %q", tc.cp, got, err, tc.want)
+		}
+	}
+	if _, err := instanceGone(context.Background(), fakeControlPlane{err: errors.New("timeout")}, "ins-1"); err == nil {
+		t.Fatal("lookup error was not returned")
+	}
+}
+
+func TestRunSuperviseClaimsEntryAndReportsReady(t *testing.T) {
+	store := &fakeStore{entries: map[string]tunnelstore.TunnelEntry{"ins-1": {PID: 10, Port: 5555}}}
+	clock := time.Unix(0, 0)
+	rt := testRuntime(store, &clock, 0)
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios, DataPlane: rt})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args: []string{"ins-1"},
+		Flags: map[string]command.FlagValue{
+			"daemon": {Name: "daemon", Type: command.FlagBool, Bool: true},
+			"port":   {Name: "port", Type: command.FlagInt, Int: 5555},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if store.entries["ins-1"].SupervisorPID != 99 {
+		t.Fatalf("entry = %#v", store.entries["ins-1"])
+	}
+	if !strings.Contains(stdout.String(), `"status":"ready"`) || !strings.Contains(stdout.String(), `"port":5555`) {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+}
+
+func TestRunSuperviseReportsMissingTunnel(t *testing.T) {
+	clock := time.Unix(0, 0)
+	rt := testRuntime(&fakeStore{entries: map[string]tunnelstore.TunnelEntry{}}, &clock, 0)
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{IO: ios, DataPlane: rt})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args: []string{"ins-1"},
+		Flags: map[string]command.FlagValue{
+			"daemon": {Name: "daemon", Type: command.FlagBool, Bool: true},
+			"port":   {Name: "port", Type: command.FlagInt, Int: 5555},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "no active tunnel") || !strings.Contains(stdout.String(), `"status":"error"`) {
+		t.Fatalf("error = %v stdout = %q", err, stdout.String())
+	}
+}
+
+// testRuntime returns hooks on a fake clock that advances by one second per
+// poll and stops after polls polls. The tunnel is always dead.
+func testRuntime(store *fakeStore, clock *time.Time, polls int) RuntimeDeps {
+	return RuntimeDeps{
+		NewStore: func() (Store, error) { return store, nil },
+		Alive:    func(tunnelstore.TunnelEntry) bool { return false },
+		StartTunnel: func(context.Context, string, int) (tunneldaemon.Process, error) {
+			return tunneldaemon.Process{PID: 11, Port: 5555}, nil
+		},
+		ConnectADB:   func(string) error { return nil },
+		InstanceGone: func(context.Context, string) (string, error) { return "", nil },
+		Wait: func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return context.WithCancel(ctx)
+		},
+		Sleep: func(_ context.Context, d time.Duration) bool {
+			if polls == 0 {
+				return false
+			}
+			polls--
+			*clock = clock.Add(d)
+			return true
+		},
+		Now:          func() time.Time { return *clock },
+		PID:          99,
+		PollInterval: time.Second,
+		MinBackoff:   2 * time.Second,
+		MaxBackoff:   8 * time.Second,
+		MaxRestarts:  10,
+	}
+}
+
+type fakeStore struct {
+	entries map[string]tunnelstore.TunnelEntry
+}
+
+func (f *fakeStore) Get(id string) (tunnelstore.TunnelEntry, bool, error) {
+	entry, ok := f.entries[id]
+	return entry, ok, nil
+}
+
+func (f *fakeStore) SetSupervisor(id string, pid int) (bool, error) {
+	entry, ok := f.entries[id]
+	if !ok {
+		return false, nil
+	}
+	entry.SupervisorPID = pid
+	f.entries[id] = entry
+	return true, nil
+}
+
+func (f *fakeStore) RecordRestart(id string, pid int, at time.Time) error {
+	entry := f.entries[id]
+	entry.PID = pid
+	entry.Restarts++
+	entry.RestartedAt = &at
+	f.entries[id] = entry
+	return nil
+}
+
+func (f *fakeStore) RecordSupervisorExit(id string, pid int, reason string, _ time.Time) error {
+	entry := f.entries[id]
+	if entry.SupervisorPID == pid {
+		entry.SupervisorPID = 0
+		entry.SupervisorExit = reason
+		entry.Status = "unreachable"
+		f.entries[id] = entry
+	}
+	return nil
+}
+
+type fakeControlPlane struct {
+	status string
+	err    error
+}
+
+func (f fakeControlPlane) GetInstance(_ context.Context, id string) (*ags.SandboxInstance, error) {
+	return &ags.SandboxInstance{InstanceId: &id, Status: &f.status}, f.err
+}
+
+func (f fakeControlPlane) IsNotFound(err error) bool { return errors.Is(err, errNotFound) }
+
+var errNotFound = errors.New("not found")
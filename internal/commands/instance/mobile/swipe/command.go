@@ -0,0 +1,99 @@
+package swipe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const defaultDuration = 300 * time.Millisecond
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.swipe",
+		Path:  []string{"instance", "mobile", "swipe"},
+		Use:   "swipe <instance-id> <x1> <y1> <x2> <y2>",
+		Short: "Swipe on the screen of a mobile instance",
+		Long: `Swipe on the screen of a connected mobile sandbox from x1, y1 to x2, y2
+in pixels. A long --duration on a single point is a long press.
+
+Examples:
+  agr instance mobile swipe ins-xxxx 540 1800 540 600
+  agr instance mobile swipe ins-xxxx 540 1200 540 1200 --duration 1s`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "x1", Required: true},
+			{Name: "y1", Required: true},
+			{Name: "x2", Required: true},
+			{Name: "y2", Required: true},
+		},
+		Flags: []command.FlagSpec{
+			{Name: "duration", Usage: "How long the swipe takes, for example 300ms or 1s", Type: command.FlagString, Default: defaultDuration.String()},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileInputResult"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runSwipe(ctx, req, rt)
+			})}, nil
+		},
+	}
+}
+
+func runSwipe(_ context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	var args []string
+	if len(req.Args) > 1 {
+		args = req.Args[1:]
+	}
+	p, err := adbdevice.Coordinates([]string{"x1", "y1", "x2", "y2"}, args)
+	if err != nil {
+		return nil, err
+	}
+	duration := defaultDuration
+	if text := req.Flags["duration"].String; text != "" {
+		d, err := time.ParseDuration(text)
+		if err != nil || d <= 0 {
+			return nil, output.NewUsageError("INVALID_DURATION", fmt.Sprintf("invalid --duration %q", text), "Use a positive duration, for example 300ms or 1s.")
+		}
+		duration = d
+	}
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := device.Swipe(p[0], p[1], p[2], p[3], duration); err != nil {
+		return nil, err
+	}
+	data := map[string]any{
+		"InstanceId": instanceID,
+		"Action":     "swipe",
+		"X1":         p[0],
+		"Y1":         p[1],
+		"X2":         p[2],
+		"Y2":         p[3],
+		"DurationMs": duration.Milliseconds(),
+	}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		fmt.Fprintf(w, "Swiped %d,%d -> %d,%d over %s on %s\n", p[0], p[1], p[2], p[3], duration, instanceID)
+	}}, nil
+}
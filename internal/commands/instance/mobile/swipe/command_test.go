@@ -0,0 +1,38 @@
+package swipe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+)
+
+func TestRunSwipe(t *testing.T) {
+	adb := &adbdevicetest.ADB{}
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{
+		Args:  []string{"ins-1", "540", "1800", "540", "600"},
+		Flags: map[string]command.FlagValue{"duration": {Name: "duration", Type: command.FlagString, String: "1s"}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if strings.Join(adb.Calls(), "\n") != "-s 127.0.0.1:5555 shell input swipe 540 1800 540 600 1000" {
+		t.Fatalf("adb calls = %q", adb.Calls())
+	}
+	if data := result.Data.(map[string]any); data["DurationMs"] != int64(1000) || data["Y2"] != 600 {
+		t.Fatalf("data = %#v", data)
+	}
+
+	_, err = adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", "1", "2", "3"}})
+	if err == nil || !strings.Contains(err.Error(), "missing y2") {
+		t.Fatalf("error = %v, want missing coordinate", err)
+	}
+	_, err = adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{
+		Args:  []string{"ins-1", "1", "2", "3", "4"},
+		Flags: map[string]command.FlagValue{"duration": {Name: "duration", Type: command.FlagString, String: "-1s"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid --duration") {
+		t.Fatalf("error = %v, want invalid duration", err)
+	}
+}
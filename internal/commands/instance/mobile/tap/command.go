@@ -0,0 +1,72 @@
+package tap
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+)
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.tap",
+		Path:  []string{"instance", "mobile", "tap"},
+		Use:   "tap <instance-id> <x> <y>",
+		Short: "Tap the screen of a mobile instance",
+		Long: `Tap the screen of a connected mobile sandbox at x, y in pixels.
+
+Examples:
+  agr instance mobile tap ins-xxxx 540 1200`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "x", Required: true},
+			{Name: "y", Required: true},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileInputResult"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runTap(ctx, req, rt)
+			})}, nil
+		},
+	}
+}
+
+func runTap(_ context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	var args []string
+	if len(req.Args) > 1 {
+		args = req.Args[1:]
+	}
+	point, err := adbdevice.Coordinates([]string{"x", "y"}, args)
+	if err != nil {
+		return nil, err
+	}
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := device.Tap(point[0], point[1]); err != nil {
+		return nil, err
+	}
+	data := map[string]any{"InstanceId": instanceID, "Action": "tap", "X": point[0], "Y": point[1]}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		fmt.Fprintf(w, "Tapped %d,%d on %s\n", point[0], point[1], instanceID)
+	}}, nil
+}
@@ -0,0 +1,29 @@
+package tap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+)
+
+func TestRunTap(t *testing.T) {
+	adb := &adbdevicetest.ADB{}
+	result, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", "540", "1200"}})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if strings.Join(adb.Calls(), "\n") != "-s 127.0.0.1:5555 shell input tap 540 1200" {
+		t.Fatalf("adb calls = %q", adb.Calls())
+	}
+	data := result.Data.(map[string]any)
+	if data["X"] != 540 || data["Y"] != 1200 {
+		t.Fatalf("data = %#v", data)
+	}
+
+	_, err = adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", "540", "top"}})
+	if err == nil || !strings.Contains(err.Error(), `invalid y "top"`) {
+		t.Fatalf("error = %v, want invalid coordinate", err)
+	}
+}
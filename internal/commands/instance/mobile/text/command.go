@@ -0,0 +1,77 @@
+package text
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// RuntimeDeps contains store and adb execution hooks so tests can run without
+// a real adb binary or tunnel registry.
+type RuntimeDeps = adbdevice.RuntimeDeps
+
+// Module returns this package's command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.mobile.text",
+		Path:  []string{"instance", "mobile", "text"},
+		Use:   "text <instance-id> <text>",
+		Short: "Type text on a mobile instance",
+		Long: `Type text into the focused field of a connected mobile sandbox. The text is
+quoted for the device shell; Android's input command only types ASCII.
+
+Examples:
+  agr instance mobile text ins-xxxx "hello world"
+  agr instance mobile text ins-xxxx user@example.com && agr instance mobile keyevent ins-xxxx enter`,
+		Args: []command.ArgSpec{
+			{Name: "instance-id", Required: true},
+			{Name: "text", Required: true},
+		},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "MobileInputResult"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: adbdevice.Groups(),
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := adbdevice.Defaults(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runText(ctx, req, rt)
+			})}, nil
+		},
+	}
+}
+
+func runText(_ context.Context, req command.Request, rt RuntimeDeps) (*command.Result, error) {
+	instanceID := adbdevice.InstanceID(req)
+	value := req.ArgValues["text"]
+	if value == "" && len(req.Args) > 1 {
+		value = req.Args[1]
+	}
+	if value == "" {
+		return nil, output.NewUsageError("INVALID_USAGE", "text must not be empty", "Pass the text to type.")
+	}
+	if strings.ContainsAny(value, "\n\r") {
+		return nil, output.NewUsageError("INVALID_USAGE", "text must be a single line", "Type each line separately and send 'keyevent enter' between them.")
+	}
+	device, err := adbdevice.Open(rt, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := device.InputText(value); err != nil {
+		return nil, err
+	}
+	data := map[string]any{"InstanceId": instanceID, "Action": "text", "Text": value}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		fmt.Fprintf(w, "Typed %d characters on %s\n", len([]rune(value)), instanceID)
+	}}, nil
+}
@@ -0,0 +1,28 @@
+package text
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/internal/adbdevice/adbdevicetest"
+)
+
+func TestRunText(t *testing.T) {
+	adb := &adbdevicetest.ADB{}
+	_, err := adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{
+		Args:      []string{"ins-1", "it's me"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "text": "it's me"},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if strings.Join(adb.Calls(), "\n") != `-s 127.0.0.1:5555 shell input text 'it'\''s%sme'` {
+		t.Fatalf("adb calls = %q", adb.Calls())
+	}
+
+	_, err = adbdevicetest.Run(t, Module(), adbdevicetest.RuntimeDeps(adb), command.Request{Args: []string{"ins-1", "two\nlines"}})
+	if err == nil || !strings.Contains(err.Error(), "single line") {
+		t.Fatalf("error = %v, want single line", err)
+	}
+}
@@ -2,11 +2,9 @@ package tunnel
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"os/signal"
 	"strconv"
 	"strings"
@@ -15,6 +13,7 @@ import (
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/internal/tunneldaemon"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/adbtunnel"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
@@ -38,12 +37,8 @@ type RuntimeDeps struct {
 	StopTimeout    time.Duration
 }
 
-type readyMessage struct {
-	Status  string `json:"status"`
-	Port    int    `json:"port,omitempty"`
-	PID     int    `json:"pid,omitempty"`
-	Message string `json:"message,omitempty"`
-}
+// readyMessage is the line the daemon writes once the tunnel is up.
+type readyMessage = tunneldaemon.Ready
 
 // Module returns this package's command module.
 func Module() command.Module {
@@ -152,8 +147,7 @@ func runTunnel(ctx context.Context, req command.Request, deps command.Deps, rt R
 
 	_, portStr, _ := strings.Cut(addr, ":")
 	if daemon {
-		msg := readyMessage{Status: "ready", Port: mustAtoi(portStr), PID: os.Getpid()}
-		if err := json.NewEncoder(deps.IO.Out).Encode(msg); err != nil {
+		if err := tunneldaemon.WriteReady(deps.IO.Out, mustAtoi(portStr)); err != nil {
 			tunnel.Stop()
 			return nil, exitError(output.ExitGenericError, fmt.Errorf("failed to write ready message: %w", err))
 		}
@@ -189,7 +183,7 @@ func writeReadyError(w io.Writer, daemon bool, message string) {
 	if !daemon {
 		return
 	}
-	_ = json.NewEncoder(w).Encode(readyMessage{Status: "error", Message: message})
+	tunneldaemon.WriteError(w, message)
 }
 
 func waitForSignal(ctx context.Context) {
@@ -0,0 +1,224 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	dataplaneproxy "github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+const (
+	// credentialEnv supplies the --auth credential without putting it on the
+	// command line. --background also uses it to hand the credential to the
+	// daemon.
+	credentialEnv = "AGR_PROXY_CREDENTIAL"
+	// tlsCertEnv and tlsKeyEnv hand the PEM certificate and key to the daemon.
+	tlsCertEnv = "AGR_PROXY_TLS_CERT"
+	tlsKeyEnv  = "AGR_PROXY_TLS_KEY"
+
+	// basicUser is the user name of generated basic-auth credentials.
+	basicUser = "agr"
+)
+
+// gate is the resolved access configuration of the local listener.
+type gate struct {
+	Auth       string // "", "token" or "basic"
+	Credential string // bearer token or user:password
+	AllowIPs   []string
+	// AllowOrigins lists the cross-origin web pages that may open
+	// WebSockets through the proxy.
+	AllowOrigins []string
+	TLSSource    string // "self-signed" or the certificate file
+	Fingerprint  string
+	CertPEM      []byte
+	KeyPEM       []byte
+
+	Access    dataplaneproxy.Access
+	TLSConfig *tls.Config
+}
+
+// gateFlagsSet reports whether any access flag was given.
+func gateFlagsSet(req command.Request) bool {
+	return stringFlag(req, "auth") != "" || stringFlag(req, "auth-credential") != "" ||
+		len(req.Flags["allow-ip"].Strings) > 0 || len(req.Flags["allow-origin"].Strings) > 0 || boolFlag(req, "tls") ||
+		stringFlag(req, "tls-cert") != "" || stringFlag(req, "tls-key") != ""
+}
+
+// resolveGate builds the access configuration from the --auth, --allow-ip,
+// --allow-origin and --tls flags. A daemon started by --background reads the credential and
+// certificate from its environment.
+func resolveGate(req command.Request, address string, daemon bool) (gate, error) {
+	var g gate
+	credential := stringFlag(req, "auth-credential")
+	if credential == "" {
+		credential = os.Getenv(credentialEnv)
+	}
+	switch g.Auth = stringFlag(req, "auth"); g.Auth {
+	case "", "none":
+		g.Auth = ""
+		if stringFlag(req, "auth-credential") != "" {
+			return gate{}, output.NewUsageError("INVALID_AUTH", "--auth-credential requires --auth token or --auth basic", "Add --auth token or --auth basic.")
+		}
+	case "token":
+		if credential == "" {
+			secret, err := dataplaneproxy.GenerateSecret()
+			if err != nil {
+				return gate{}, err
+			}
+			credential = secret
+		}
+		g.Credential = credential
+		g.Access.BearerToken = credential
+	case "basic":
+		if credential == "" {
+			secret, err := dataplaneproxy.GenerateSecret()
+			if err != nil {
+				return gate{}, err
+			}
+			credential = basicUser + ":" + secret
+		}
+		user, password, ok := strings.Cut(credential, ":")
+		if !ok || user == "" || password == "" {
+			return gate{}, output.NewUsageError("INVALID_AUTH", "basic auth credential must be user:password", "Pass --auth-credential user:password, or omit it to generate one.")
+		}
+		g.Credential = credential
+		g.Access.BasicUser, g.Access.BasicPassword = user, password
+	default:
+		return gate{}, output.NewUsageError("INVALID_AUTH", fmt.Sprintf("invalid --auth %q", g.Auth), "Use --auth token or --auth basic.")
+	}
+
+	g.AllowIPs = req.Flags["allow-ip"].Strings
+	if len(g.AllowIPs) > 0 {
+		nets, err := dataplaneproxy.ParseAllowList(g.AllowIPs)
+		if err != nil {
+			return gate{}, output.NewUsageError("INVALID_ALLOW_IP", fmt.Sprintf("invalid --allow-ip: %v", err), "Pass an IP address or CIDR network, for example 192.168.1.0/24.")
+		}
+		g.Access.AllowedNets = nets
+	}
+
+	g.AllowOrigins = req.Flags["allow-origin"].Strings
+
+	if err := g.loadTLS(req, address, daemon); err != nil {
+		return gate{}, err
+	}
+	return g, nil
+}
+
+func (g *gate) loadTLS(req command.Request, address string, daemon bool) error {
+	certFile, keyFile := stringFlag(req, "tls-cert"), stringFlag(req, "tls-key")
+	if (certFile == "") != (keyFile == "") {
+		return output.NewUsageError("INVALID_TLS", "--tls-cert and --tls-key must be given together", "Pass both the certificate and its private key, or use --tls for a self-signed certificate.")
+	}
+	switch {
+	case daemon && os.Getenv(tlsCertEnv) != "":
+		g.TLSSource = "inherited"
+		g.CertPEM, g.KeyPEM = []byte(os.Getenv(tlsCertEnv)), []byte(os.Getenv(tlsKeyEnv))
+	case certFile != "":
+		cert, err := os.ReadFile(certFile)
+		if err != nil {
+			return output.NewUsageError("INVALID_TLS", fmt.Sprintf("failed to read --tls-cert: %v", err), "Check the certificate path.")
+		}
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return output.NewUsageError("INVALID_TLS", fmt.Sprintf("failed to read --tls-key: %v", err), "Check the private key path.")
+		}
+		g.TLSSource, g.CertPEM, g.KeyPEM = certFile, cert, key
+	case boolFlag(req, "tls"):
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if ip := net.ParseIP(address); ip != nil && !ip.IsUnspecified() && !ip.IsLoopback() {
+			hosts = append(hosts, address)
+		} else if ip != nil && ip.IsUnspecified() {
+			if hostname, err := os.Hostname(); err == nil {
+				hosts = append(hosts, hostname)
+			}
+		}
+		cert, key, err := dataplaneproxy.SelfSignedCertificate(hosts)
+		if err != nil {
+			return err
+		}
+		g.TLSSource, g.CertPEM, g.KeyPEM = "self-signed", cert, key
+	default:
+		return nil
+	}
+	pair, err := tls.X509KeyPair(g.CertPEM, g.KeyPEM)
+	if err != nil {
+		return output.NewUsageError("INVALID_TLS", fmt.Sprintf("invalid TLS certificate: %v", err), "Pass a PEM certificate and the matching PEM private key.")
+	}
+	g.Fingerprint = dataplaneproxy.Fingerprint(pair)
+	g.TLSConfig = &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}
+	return nil
+}
+
+// Scheme returns the URL scheme of the local listener.
+func (g gate) Scheme() string {
+	if g.TLSConfig != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// DaemonArgs returns the flags that reproduce g in the --background daemon.
+func (g gate) DaemonArgs() []string {
+	var args []string
+	if g.Auth != "" {
+		args = append(args, "--auth", g.Auth)
+	}
+	for _, ip := range g.AllowIPs {
+		args = append(args, "--allow-ip", ip)
+	}
+	for _, origin := range g.AllowOrigins {
+		args = append(args, "--allow-origin", origin)
+	}
+	if g.TLSConfig != nil {
+		args = append(args, "--tls")
+	}
+	return args
+}
+
+// DaemonEnv returns the secrets handed to the --background daemon.
+func (g gate) DaemonEnv() []string {
+	var env []string
+	if g.Credential != "" {
+		env = append(env, credentialEnv+"="+g.Credential)
+	}
+	if g.TLSConfig != nil {
+		env = append(env, tlsCertEnv+"="+string(g.CertPEM), tlsKeyEnv+"="+string(g.KeyPEM))
+	}
+	return env
+}
+
+// Data adds the access details to a JSON result.
+func (g gate) Data(data map[string]any) {
+	if g.Auth != "" {
+		data["Auth"] = g.Auth
+		data["Credential"] = g.Credential
+	}
+	if len(g.AllowIPs) > 0 {
+		data["AllowIps"] = g.AllowIPs
+	}
+	if g.Fingerprint != "" {
+		data["TlsFingerprint"] = g.Fingerprint
+	}
+}
+
+// Print writes how clients authenticate to the listener.
+func (g gate) Print(w io.Writer) {
+	switch g.Auth {
+	case "token":
+		fmt.Fprintf(w, "  Auth:   Authorization: Bearer %s\n", g.Credential)
+	case "basic":
+		fmt.Fprintf(w, "  Auth:   basic %s (curl -u %s)\n", g.Credential, g.Credential)
+	}
+	if len(g.AllowIPs) > 0 {
+		fmt.Fprintf(w, "  Allow:  %s\n", strings.Join(g.AllowIPs, ", "))
+	}
+	if g.Fingerprint != "" {
+		fmt.Fprintf(w, "  TLS:    %s, SHA-256 %s\n", g.TLSSource, g.Fingerprint)
+	}
+}
@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/portwatch"
+	dataplaneproxy "github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// NDJSON event types emitted by --auto.
+const (
+	eventPortOpened = "port_opened"
+	eventPortClosed = "port_closed"
+)
+
+// autoForward is a forward started by --auto for one sandbox port.
+type autoForward struct {
+	proxy Proxy
+	addr  string
+}
+
+// runAuto watches the listening sockets in the sandbox and forwards every
+// port in --auto-range while it is open.
+func runAuto(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps, instanceID string) (*command.Result, error) {
+	if len(req.Args) > 1 || req.ArgValues["port"] != "" || stringFlag(req, "ports-file") != "" ||
+		stringFlag(req, "socks5") != "" || stringFlag(req, "reverse") != "" {
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--auto cannot be combined with port arguments, --ports-file, --socks5 or --reverse",
+			"Drop the port arguments; --auto forwards every listening port in --auto-range.")
+	}
+	if boolFlag(req, "background") || boolFlag(req, "daemon") {
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--auto cannot be combined with --background",
+			"Run 'agr instance proxy --auto' in the foreground.")
+	}
+	ndjson := cli.IsNDJSON()
+	if cli.IsJSONOutput() && !ndjson {
+		return nil, output.NewUsageError("JSON_REQUIRES_BACKGROUND",
+			"agr instance proxy --auto streams changes and does not support -o json",
+			"Use -o ndjson to receive one event per port change.")
+	}
+	portRange := portwatch.DefaultRange
+	if text := stringFlag(req, "auto-range"); text != "" {
+		r, err := portwatch.ParseRange(text)
+		if err != nil {
+			return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid --auto-range: %v", err), "Use min-max, for example 3000-9999.")
+		}
+		portRange = r
+	}
+	interval := portwatch.DefaultInterval
+	if text := stringFlag(req, "auto-interval"); text != "" {
+		d, err := time.ParseDuration(text)
+		if err != nil || d < 500*time.Millisecond {
+			return nil, output.NewUsageError("INVALID_INTERVAL", fmt.Sprintf("invalid --auto-interval %q", text), "Use a duration of at least 500ms, for example 2s.")
+		}
+		interval = d
+	}
+	address := stringFlag(req, "address")
+	if address == "" {
+		address = "127.0.0.1"
+	}
+	if err := cli.ValidateListenAddress(address); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	g, err := resolveGate(req, address, false)
+	if err != nil {
+		return nil, err
+	}
+	c, err := resolveCapture(req)
+	if err != nil {
+		return nil, err
+	}
+	if address != "127.0.0.1" && address != "localhost" && address != "::1" && !g.Access.Enabled() {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: binding to %s exposes the proxy (and the sandbox access token) to the network. Consider --auth token or --allow-ip.\n", address)
+	}
+	token, err := rt.AcquireToken(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire access token: %w", err)
+	}
+	if err := c.Open(); err != nil {
+		return nil, err
+	}
+	domain := config.Get().DataPlaneRegionDomain()
+	logger := log.New(deps.IO.ErrOut, "", log.LstdFlags)
+	refresh := tokenRefresh(ctx, rt, instanceID)
+
+	var nw *output.NDJSONWriter
+	if ndjson {
+		nw = output.NewNDJSONWriter(deps.IO.Out, "instance.proxy")
+		_ = nw.WriteStarted(map[string]any{"InstanceId": instanceID, "PortRange": portRange.String()})
+	} else {
+		fmt.Fprintf(deps.IO.Out, "Watching %s for listening ports in %s (every %s)\n", instanceID, portRange, interval)
+		g.Print(deps.IO.Out)
+		c.Print(deps.IO.Out)
+		fmt.Fprintln(deps.IO.Out, "\nPress Ctrl+C to stop.")
+	}
+
+	forwards := map[int]autoForward{}
+	var opened int
+	start := func(port int) (autoForward, error) {
+		opts := dataplaneproxy.Options{
+			InstanceID:      instanceID,
+			Domain:          domain,
+			RemotePort:      port,
+			Token:           token,
+			TokenProvider:   refresh.provide,
+			InvalidateToken: refresh.invalidate,
+			ListenAddress:   net.JoinHostPort(address, strconv.Itoa(port)),
+			Logger:          logger,
+			Verbose:         boolFlag(req, "verbose"),
+			Access:          g.Access,
+			AllowedOrigins:  g.AllowOrigins,
+			TLSConfig:       g.TLSConfig,
+			AccessLog:       c.AccessLog,
+			HAR:             c.HAR,
+		}
+		p, err := rt.NewProxy(opts)
+		if err != nil {
+			return autoForward{}, err
+		}
+		addr, err := p.Start()
+		if err != nil {
+			// The same local port is taken; fall back to any free port.
+			p.Stop()
+			opts.ListenAddress = net.JoinHostPort(address, "0")
+			if p, err = rt.NewProxy(opts); err != nil {
+				return autoForward{}, err
+			}
+			if addr, err = p.Start(); err != nil {
+				return autoForward{}, err
+			}
+		}
+		return autoForward{proxy: p, addr: addr}, nil
+	}
+	// onEvent starts and stops forwards. A forward that fails to start is
+	// reported back to the watcher, which retries it on the next poll.
+	onEvent := func(event portwatch.Event) error {
+		remote := fmt.Sprintf("https://%d-%s.%s", event.Port, instanceID, domain)
+		switch event.Type {
+		case portwatch.EventOpened:
+			f, err := start(event.Port)
+			if err != nil {
+				fmt.Fprintf(deps.IO.ErrOut, "Warning: failed to forward port %d: %v; retrying\n", event.Port, err)
+				return err
+			}
+			forwards[event.Port] = f
+			opened++
+			local := fmt.Sprintf("%s://%s", g.Scheme(), f.addr)
+			if nw != nil {
+				_ = nw.WriteEvent(eventPortOpened, map[string]any{"InstanceId": instanceID, "RemotePort": event.Port, "LocalAddress": f.addr, "LocalUrl": local, "RemoteUrl": remote})
+				return nil
+			}
+			fmt.Fprintf(deps.IO.Out, "+ Port %d opened: forwarding %s -> %d\n", event.Port, local, event.Port)
+		case portwatch.EventClosed:
+			f, ok := forwards[event.Port]
+			if !ok {
+				return nil
+			}
+			f.proxy.Stop()
+			delete(forwards, event.Port)
+			if nw != nil {
+				_ = nw.WriteEvent(eventPortClosed, map[string]any{"InstanceId": instanceID, "RemotePort": event.Port, "LocalAddress": f.addr})
+				return nil
+			}
+			fmt.Fprintf(deps.IO.Out, "- Port %d closed: stopped forwarding %s\n", event.Port, f.addr)
+		}
+		return nil
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		portwatch.Watch(watchCtx, portwatch.Options{
+			List: func(ctx context.Context) ([]int, error) {
+				return rt.ListPorts(ctx, instanceID)
+			},
+			Interval: interval,
+			Range:    portRange,
+			OnEvent:  onEvent,
+			OnError: func(err error) {
+				fmt.Fprintf(deps.IO.ErrOut, "Warning: %v\n", err)
+			},
+		})
+	}()
+
+	rt.Wait(ctx)
+	cancel()
+	<-done
+
+	report := deps.IO.Out
+	if nw != nil {
+		report = deps.IO.ErrOut
+	} else {
+		fmt.Fprintln(deps.IO.Out, "\nStopping proxy...")
+	}
+	for _, f := range forwards {
+		f.proxy.Stop()
+	}
+	c.Close(report)
+	if nw != nil {
+		_ = nw.WriteCompleted(map[string]any{"InstanceId": instanceID, "PortsForwarded": opened})
+	}
+	return &command.Result{StreamDone: true}, nil
+}
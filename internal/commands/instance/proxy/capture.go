@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	dataplaneproxy "github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// capture is the resolved --access-log and --har configuration. Paths are
+// absolute so that a --background daemon writes to the same files.
+type capture struct {
+	AccessLogPath string
+	HARPath       string
+
+	accessLogFile *os.File
+	AccessLog     *dataplaneproxy.AccessLog
+	HAR           *dataplaneproxy.HARRecorder
+	stopSaving    func()
+}
+
+// captureFlagsSet reports whether --access-log or --har was given.
+func captureFlagsSet(req command.Request) bool {
+	return stringFlag(req, "access-log") != "" || stringFlag(req, "har") != ""
+}
+
+// resolveCapture validates the --access-log and --har paths.
+func resolveCapture(req command.Request) (capture, error) {
+	var c capture
+	for _, f := range []struct {
+		flag string
+		dst  *string
+	}{{"access-log", &c.AccessLogPath}, {"har", &c.HARPath}} {
+		path := stringFlag(req, f.flag)
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return capture{}, output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("invalid --%s path: %v", f.flag, err), "Pass a writable file path.")
+		}
+		if info, err := os.Stat(filepath.Dir(abs)); err != nil || !info.IsDir() {
+			return capture{}, output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("--%s directory %s does not exist", f.flag, filepath.Dir(abs)), "Create the directory or pass another path.")
+		}
+		*f.dst = abs
+	}
+	return c, nil
+}
+
+// Open opens the access log for appending and starts the HAR recorder,
+// which saves the file periodically until Close.
+func (c *capture) Open() error {
+	if c.AccessLogPath != "" {
+		file, err := os.OpenFile(c.AccessLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return output.NewUsageError("INVALID_LOCAL_PATH", fmt.Sprintf("failed to open --access-log: %v", err), "Ensure the access log path is writable.")
+		}
+		c.accessLogFile = file
+		c.AccessLog = dataplaneproxy.NewAccessLog(file)
+	}
+	if c.HARPath != "" {
+		c.HAR = dataplaneproxy.NewHARRecorder(0, 0, 0)
+		c.stopSaving = c.HAR.SaveEvery(c.HARPath, dataplaneproxy.DefaultHARSaveInterval)
+	}
+	return nil
+}
+
+// Close closes the access log and writes the HAR file, reporting to w.
+func (c *capture) Close(w io.Writer) {
+	if c.accessLogFile != nil {
+		_ = c.accessLogFile.Close()
+		c.accessLogFile = nil
+	}
+	if c.HAR != nil {
+		c.stopSaving()
+		if err := c.HAR.Save(c.HARPath); err != nil {
+			fmt.Fprintf(w, "Warning: %v\n", err)
+		} else {
+			fmt.Fprintf(w, "Wrote %d requests to %s\n", c.HAR.Len(), c.HARPath)
+		}
+		c.HAR = nil
+	}
+}
+
+// DaemonArgs returns the flags that reproduce c in the --background daemon.
+func (c capture) DaemonArgs() []string {
+	var args []string
+	if c.AccessLogPath != "" {
+		args = append(args, "--access-log", c.AccessLogPath)
+	}
+	if c.HARPath != "" {
+		args = append(args, "--har", c.HARPath)
+	}
+	return args
+}
+
+// Data adds the capture files to a JSON result.
+func (c capture) Data(data map[string]any) {
+	if c.AccessLogPath != "" {
+		data["AccessLog"] = c.AccessLogPath
+	}
+	if c.HARPath != "" {
+		data["Har"] = c.HARPath
+	}
+}
+
+// Print writes where requests are recorded.
+func (c capture) Print(w io.Writer) {
+	if c.AccessLogPath != "" {
+		fmt.Fprintf(w, "  Access: %s\n", c.AccessLogPath)
+	}
+	if c.HARPath != "" {
+		fmt.Fprintf(w, "  HAR:    %s (saved every 10s and on stop)\n", c.HARPath)
+	}
+}
@@ -3,16 +3,26 @@ package proxy
 import (
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"net"
+	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode"
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/internal/tunneldaemon"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/portwatch"
 	dataplaneproxy "github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/reversetunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
 )
 
@@ -22,12 +32,37 @@ type Proxy interface {
 	Stop()
 }
 
-// RuntimeDeps contains token, proxy construction, and wait hooks that tests can
-// replace without opening real network listeners.
+// Helper is the reverse tunnel helper process running in the sandbox.
+type Helper interface {
+	Stop(context.Context) error
+}
+
+// ReverseTunnel relays connections from the sandbox helper to a local target.
+type ReverseTunnel interface {
+	Start() error
+	Stop()
+}
+
+// Store is the tunnel registry used to record background proxies.
+type Store interface {
+	Cleanup(string) error
+	Save(string, tunnelstore.TunnelEntry) error
+}
+
+// RuntimeDeps contains token, proxy construction, reverse tunnel, daemon,
+// store, and wait hooks that tests can replace without opening real network
+// listeners or spawning a background process.
 type RuntimeDeps struct {
-	AcquireToken func(ctx context.Context, instanceID string) (string, error)
-	NewProxy     func(dataplaneproxy.Options) (Proxy, error)
-	Wait         func(context.Context)
+	AcquireToken    func(ctx context.Context, instanceID string) (string, error)
+	InvalidateToken func(instanceID string)
+	NewProxy        func(dataplaneproxy.Options) (Proxy, error)
+	NewSOCKS        func(dataplaneproxy.SOCKSOptions) (Proxy, error)
+	StartHelper     func(ctx context.Context, instanceID string, opts reversetunnel.HelperOptions) (Helper, error)
+	NewReverse      func(reversetunnel.Options) (ReverseTunnel, error)
+	ListPorts       func(ctx context.Context, instanceID string) ([]int, error)
+	Wait            func(context.Context)
+	StartDaemon     func(args []string, logName string, env []string) (tunneldaemon.Process, error)
+	NewStore        func() (Store, error)
 }
 
 // Module returns this package's command module.
@@ -35,26 +70,93 @@ func Module() command.Module {
 	spec := command.Spec{
 		ID:    "instance.proxy",
 		Path:  []string{"instance", "proxy"},
-		Use:   "proxy <instance-id> [local_port:]<remote_port>",
-		Short: "Forward an instance port to localhost",
-		Long: `Forward a remote instance port to a local address, similar to kubectl port-forward.
+		Use:   "proxy <instance-id> [local_port:]<remote_port>...",
+		Short: "Forward instance ports to localhost",
+		Long: `Forward remote instance ports to a local address, similar to kubectl port-forward.
 
 Port Syntax:
   <remote_port>                Forward remote port to the same local port
   <local_port>:<remote_port>   Forward remote port to a specific local port
 
+Several ports can be forwarded by one process, either as arguments or listed
+in --ports-file (one mapping per line, '#' starts a comment). They share one
+access token and stop together.
+
+With --socks5 the command instead starts a SOCKS5 proxy that reaches every
+port of the instance: clients CONNECT to localhost:<port> (or 127.0.0.1) and
+are forwarded to that sandbox port. Other destinations are refused. The
+streams are carried as HTTP through the gateway, so only HTTP and WebSocket
+clients work; use 'agr instance forward --tcp' for raw TCP protocols. The
+SOCKS5 proxy has no authentication and only binds to loopback addresses.
+
+With --auto the command watches the sockets listening in the instance (every
+--auto-interval, by reading /proc/net/tcp) and forwards each port in
+--auto-range to the same local port, or to a free one if it is taken. Forwards
+are removed when their port closes. Each change is printed, or emitted as an
+NDJSON event with -o ndjson.
+
+With --reverse <remote_port>:[local_host:]<local_port> the direction is
+reversed: a small helper started in the sandbox (it needs python3) listens on
+remote_port, and every connection to it is relayed back to the local service.
+
+The local listener is unauthenticated by default. To share it on the network
+add --auth token (a generated bearer token) or --auth basic (a generated
+agr:<password> credential; supply your own with --auth-credential or
+$AGR_PROXY_CREDENTIAL), restrict clients with --allow-ip, and serve HTTPS with
+--tls (self-signed) or --tls-cert/--tls-key. The local credential is removed
+before requests are forwarded to the sandbox.
+
+--access-log appends one NDJSON entry per request (time, method, URL, status,
+bytes, latency and whether it was a WebSocket upgrade). --har records request
+and response headers and bodies (each body capped at 256 KiB, bodies omitted
+once 64 MiB are kept, the access token redacted) in a HAR file for browser
+devtools, saved every 10 seconds and when the proxy stops.
+
+With --background the proxy keeps running after the command returns. Use
+'agr tunnel list' to see running proxies, 'agr tunnel logs' to read their logs
+and 'agr tunnel stop' to stop them.
+
 Examples:
   agr instance proxy ins-xxxx 8080
   agr instance proxy ins-xxxx 3000:8080
-  agr instance proxy ins-xxxx 3000:8080 --address 0.0.0.0`,
+  agr instance proxy ins-xxxx 3000:8080 --address 0.0.0.0
+  agr instance proxy ins-xxxx 8080 --address 0.0.0.0 --auth token --tls --allow-ip 192.168.1.0/24
+  agr instance proxy ins-xxxx 3000 8080:80 9229
+  agr instance proxy ins-xxxx --ports-file ports.txt
+  agr instance proxy ins-xxxx 3000 --access-log access.ndjson --har session.har
+  agr instance proxy ins-xxxx 8080 --background
+  agr instance proxy ins-xxxx --socks5 127.0.0.1:1080
+  agr instance proxy ins-xxxx --auto --auto-range 3000-9999
+  agr instance proxy ins-xxxx --reverse 11434:localhost:11434`,
 		Args: []command.ArgSpec{
 			{Name: "instance-id", Required: true},
-			{Name: "port", Required: true},
+			{Name: "port", Repeatable: true},
 		},
 		Flags: []command.FlagSpec{
 			{Name: "address", Usage: "Local address to bind to", Type: command.FlagString, Default: "127.0.0.1"},
 			{Name: "verbose", Usage: "Enable verbose request logging", Type: command.FlagBool},
+			{Name: "ports-file", Usage: "Read additional [local_port:]remote_port mappings from a file", Type: command.FlagString},
+			{Name: "socks5", Usage: "Serve a SOCKS5 proxy for all sandbox ports on this address instead of forwarding fixed ports", Type: command.FlagString},
+			{Name: "reverse", Usage: "Expose a local service in the sandbox: <remote_port>:[local_host:]<local_port>", Type: command.FlagString},
+			{Name: "auto", Usage: "Forward every port that listens in the instance, following ports as they open and close", Type: command.FlagBool},
+			{Name: "auto-range", Usage: "Port range watched by --auto", Type: command.FlagString, Default: portwatch.DefaultRange.String()},
+			{Name: "auto-interval", Usage: "How often --auto checks for listening ports", Type: command.FlagString, Default: portwatch.DefaultInterval.String()},
+			{Name: "helper-port", Usage: "Sandbox port used by the --reverse helper for tunnel connections", Type: command.FlagInt, Default: reversetunnel.DefaultHelperPort},
+			{Name: "auth", Usage: "Require a local credential: token|basic", Type: command.FlagString},
+			{Name: "auth-credential", Usage: "Bearer token or user:password for --auth (default: generated)", Type: command.FlagString},
+			{Name: "allow-ip", Usage: "Only accept clients from this IP address or CIDR network (repeatable)", Type: command.FlagStringArray},
+			{Name: "allow-origin", Usage: "Let web pages from this origin open WebSockets through the proxy, or * for any (repeatable)", Type: command.FlagStringArray},
+			{Name: "tls", Usage: "Serve the local listener over HTTPS with a self-signed certificate", Type: command.FlagBool},
+			{Name: "tls-cert", Usage: "PEM certificate for the local HTTPS listener", Type: command.FlagString},
+			{Name: "tls-key", Usage: "PEM private key for --tls-cert", Type: command.FlagString},
+			{Name: "access-log", Usage: "Append an NDJSON entry per request to this file", Type: command.FlagString},
+			{Name: "har", Usage: "Record requests and responses to this HAR file, saved periodically and when the proxy stops", Type: command.FlagString},
+			{Name: "background", Usage: "Run the proxy in the background and return once it listens", Type: command.FlagBool},
+			{Name: "daemon", Usage: "Run in daemon mode (used by --background)", Type: command.FlagBool, Hidden: true},
 		},
+		SupportsJSON:   true,
+		SupportsNDJSON: true,
+		Output:         command.OutputSpec{DataType: "BackgroundProxy"},
 	}
 	return command.Module{
 		Descriptor: command.Descriptor{
@@ -87,30 +189,87 @@ func runtimeDeps(injected any) RuntimeDeps {
 	if rt.AcquireToken == nil {
 		rt.AcquireToken = cli.AcquireInstanceToken
 	}
+	if rt.InvalidateToken == nil {
+		rt.InvalidateToken = cli.InvalidateInstanceToken
+	}
 	if rt.NewProxy == nil {
 		rt.NewProxy = func(opts dataplaneproxy.Options) (Proxy, error) {
 			return dataplaneproxy.New(opts)
 		}
 	}
+	if rt.NewSOCKS == nil {
+		rt.NewSOCKS = func(opts dataplaneproxy.SOCKSOptions) (Proxy, error) {
+			return dataplaneproxy.NewSOCKS5(opts)
+		}
+	}
+	if rt.StartHelper == nil {
+		rt.StartHelper = func(ctx context.Context, instanceID string, opts reversetunnel.HelperOptions) (Helper, error) {
+			sandbox, err := cli.ConnectSandboxWithCache(ctx, instanceID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect to instance: %w", err)
+			}
+			helper, err := reversetunnel.LaunchHelper(ctx, sandbox, opts)
+			if err != nil {
+				return nil, err
+			}
+			return helper, nil
+		}
+	}
+	if rt.NewReverse == nil {
+		rt.NewReverse = func(opts reversetunnel.Options) (ReverseTunnel, error) {
+			return reversetunnel.New(opts)
+		}
+	}
+	if rt.ListPorts == nil {
+		rt.ListPorts = func(ctx context.Context, instanceID string) ([]int, error) {
+			sandbox, err := cli.ConnectSandboxWithCache(ctx, instanceID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect to instance: %w", err)
+			}
+			return portwatch.SandboxLister(sandbox)(ctx)
+		}
+	}
 	if rt.Wait == nil {
 		rt.Wait = waitForSignal
 	}
+	if rt.StartDaemon == nil {
+		rt.StartDaemon = tunneldaemon.StartWithEnv
+	}
+	if rt.NewStore == nil {
+		rt.NewStore = func() (Store, error) { return tunnelstore.NewStore() }
+	}
 	return rt
 }
 
+// mapping is one local-to-remote port forward.
+type mapping struct {
+	Spec   string
+	Local  int
+	Remote int
+}
+
 func runProxy(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps) (*command.Result, error) {
 	instanceID := req.ArgValues["instance-id"]
-	portSpec := req.ArgValues["port"]
 	if instanceID == "" && len(req.Args) > 0 {
 		instanceID = req.Args[0]
 	}
-	if portSpec == "" && len(req.Args) > 1 {
-		portSpec = req.Args[1]
+	if boolFlag(req, "auto") {
+		return runAuto(ctx, req, deps, rt, instanceID)
 	}
-
-	localPort, remotePort, err := parsePortSpec(portSpec)
+	if socksAddress := stringFlag(req, "socks5"); socksAddress != "" {
+		return runSOCKS(ctx, req, deps, rt, instanceID, socksAddress)
+	}
+	if reverse := stringFlag(req, "reverse"); reverse != "" {
+		return runReverse(ctx, req, deps, rt, instanceID, reverse)
+	}
+	if cli.IsNDJSON() {
+		return nil, output.NewUsageError("NDJSON_REQUIRES_STREAM",
+			"-o ndjson can only be used with --auto",
+			"Add --auto to stream port changes, or use -o json with --background.")
+	}
+	mappings, err := portMappings(req)
 	if err != nil {
-		return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid port specification: %v", err), "Use [local_port:]remote_port with values between 1 and 65535.")
+		return nil, err
 	}
 	address := stringFlag(req, "address")
 	if address == "" {
@@ -119,49 +278,456 @@ func runProxy(ctx context.Context, req command.Request, deps command.Deps, rt Ru
 	if err := cli.ValidateListenAddress(address); err != nil {
 		return nil, err
 	}
+	background, daemon := boolFlag(req, "background"), boolFlag(req, "daemon")
+	if cli.IsJSONOutput() && !background {
+		return nil, output.NewUsageError("JSON_REQUIRES_BACKGROUND",
+			"agr instance proxy runs in the foreground and does not support -o json",
+			"Add --background to start the proxy in the background and get its details as JSON.")
+	}
+	if background && len(mappings) > 1 {
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--background forwards a single port per process",
+			"Run 'agr instance proxy <instance-id> <port> --background' once per port.")
+	}
 	if err := config.Validate(); err != nil {
 		return nil, err
 	}
-	if address != "127.0.0.1" && address != "localhost" && address != "::1" {
-		fmt.Fprintf(deps.IO.ErrOut, "Warning: binding to %s exposes the proxy (and the sandbox access token) to the network.\n", address)
+	g, err := resolveGate(req, address, daemon)
+	if err != nil {
+		writeReadyError(deps.IO.Out, daemon, err.Error())
+		return nil, err
+	}
+	c, err := resolveCapture(req)
+	if err != nil {
+		writeReadyError(deps.IO.Out, daemon, err.Error())
+		return nil, err
+	}
+	if !daemon && address != "127.0.0.1" && address != "localhost" && address != "::1" && !g.Access.Enabled() {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: binding to %s exposes the proxy (and the sandbox access token) to the network. Consider --auth token or --allow-ip.\n", address)
+	}
+	if background && !daemon {
+		m := mappings[0]
+		return startBackground(req, deps, rt, g, c, instanceID, m.Spec, address, m.Local, m.Remote)
 	}
 	token, err := rt.AcquireToken(ctx, instanceID)
 	if err != nil {
+		writeReadyError(deps.IO.Out, daemon, fmt.Sprintf("failed to acquire access token: %v", err))
 		return nil, fmt.Errorf("failed to acquire access token: %w", err)
 	}
 	cfg := config.Get()
 	domain := cfg.DataPlaneRegionDomain()
-	listenAddr := net.JoinHostPort(address, strconv.Itoa(localPort))
-
-	proxy, err := rt.NewProxy(dataplaneproxy.Options{
-		InstanceID:    instanceID,
-		Domain:        domain,
-		RemotePort:    remotePort,
-		Token:         token,
-		ListenAddress: listenAddr,
-		Insecure:      false,
-		Verbose:       boolFlag(req, "verbose"),
+	logger := log.New(deps.IO.ErrOut, "", log.LstdFlags)
+	refresh := tokenRefresh(ctx, rt, instanceID)
+	if err := c.Open(); err != nil {
+		writeReadyError(deps.IO.Out, daemon, err.Error())
+		return nil, err
+	}
+	report := deps.IO.Out
+	if daemon {
+		report = deps.IO.ErrOut
+	}
+
+	// All listeners share the token, logger and capture files, and stop
+	// together.
+	var proxies []Proxy
+	stopAll := func() {
+		for _, p := range proxies {
+			p.Stop()
+		}
+		c.Close(report)
+	}
+	addrs := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		proxy, err := rt.NewProxy(dataplaneproxy.Options{
+			InstanceID:      instanceID,
+			Domain:          domain,
+			RemotePort:      m.Remote,
+			Token:           token,
+			TokenProvider:   refresh.provide,
+			InvalidateToken: refresh.invalidate,
+			ListenAddress:   net.JoinHostPort(address, strconv.Itoa(m.Local)),
+			Logger:          logger,
+			Insecure:        false,
+			Verbose:         boolFlag(req, "verbose"),
+			Access:          g.Access,
+			AllowedOrigins:  g.AllowOrigins,
+			TLSConfig:       g.TLSConfig,
+			AccessLog:       c.AccessLog,
+			HAR:             c.HAR,
+		})
+		if err != nil {
+			stopAll()
+			writeReadyError(deps.IO.Out, daemon, fmt.Sprintf("failed to create proxy: %v", err))
+			return nil, fmt.Errorf("failed to create proxy: %w", err)
+		}
+		addr, err := proxy.Start()
+		if err != nil {
+			stopAll()
+			writeReadyError(deps.IO.Out, daemon, fmt.Sprintf("failed to start proxy: %v", err))
+			return nil, fmt.Errorf("failed to start proxy: %w", err)
+		}
+		proxies = append(proxies, proxy)
+		addrs = append(addrs, addr)
+	}
+
+	if daemon {
+		_, port, _ := net.SplitHostPort(addrs[0])
+		boundPort, _ := strconv.Atoi(port)
+		if err := tunneldaemon.WriteReady(deps.IO.Out, boundPort); err != nil {
+			stopAll()
+			return nil, fmt.Errorf("failed to write ready message: %w", err)
+		}
+		rt.Wait(ctx)
+		stopAll()
+		return &command.Result{StreamDone: true}, nil
+	}
+
+	if len(mappings) == 1 {
+		remotePort := mappings[0].Remote
+		fmt.Fprintf(deps.IO.Out, "Forwarding from %s -> %d\n", addrs[0], remotePort)
+		fmt.Fprintf(deps.IO.Out, "  Local:  %s://%s\n", g.Scheme(), addrs[0])
+		fmt.Fprintf(deps.IO.Out, "  Remote: https://%d-%s.%s\n", remotePort, instanceID, domain)
+		g.Print(deps.IO.Out)
+		c.Print(deps.IO.Out)
+	} else {
+		fmt.Fprintf(deps.IO.Out, "Forwarding %d ports from %s:\n", len(mappings), instanceID)
+		tw := tabwriter.NewWriter(deps.IO.Out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "LOCAL\tREMOTE\tURL")
+		for i, m := range mappings {
+			fmt.Fprintf(tw, "%s://%s\t%d\thttps://%d-%s.%s\n", g.Scheme(), addrs[i], m.Remote, m.Remote, instanceID, domain)
+		}
+		_ = tw.Flush()
+		g.Print(deps.IO.Out)
+		c.Print(deps.IO.Out)
+	}
+	fmt.Fprintln(deps.IO.Out, "\nPress Ctrl+C to stop.")
+
+	rt.Wait(ctx)
+
+	fmt.Fprintln(deps.IO.Out, "\nStopping proxy...")
+	stopAll()
+	return &command.Result{StreamDone: true}, nil
+}
+
+// runSOCKS serves a SOCKS5 proxy that forwards CONNECT requests for
+// sandbox-local destinations to the matching sandbox port.
+func runSOCKS(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps, instanceID, address string) (*command.Result, error) {
+	if len(req.Args) > 1 || req.ArgValues["port"] != "" || stringFlag(req, "ports-file") != "" {
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--socks5 cannot be combined with port arguments or --ports-file",
+			"The SOCKS5 proxy reaches every sandbox port; drop the port arguments.")
+	}
+	if boolFlag(req, "background") || boolFlag(req, "daemon") || gateFlagsSet(req) {
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--socks5 cannot be combined with --background, --auth, --allow-ip, --allow-origin or --tls",
+			"Run the SOCKS5 proxy in the foreground on a loopback address.")
+	}
+	if cli.IsJSONOutput() {
+		return nil, output.NewUsageError("JSON_REQUIRES_BACKGROUND",
+			"agr instance proxy --socks5 runs in the foreground and does not support -o json",
+			"Run it without -o json.")
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, output.NewUsageError("INVALID_ADDRESS", fmt.Sprintf("invalid --socks5 address %q: %v", address, err), "Use host:port, for example 127.0.0.1:1080.")
+	}
+	if err := cli.ValidateListenAddress(host); err != nil {
+		return nil, err
+	}
+	// The SOCKS5 proxy cannot authenticate clients, so anyone who can reach
+	// it could use the sandbox access token.
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, output.NewUsageError("INVALID_ADDRESS",
+			fmt.Sprintf("--socks5 must bind to a loopback address, got %s", host),
+			"Use 127.0.0.1:<port>, or forward fixed ports with --address, --auth and --allow-ip to share them.")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	token, err := rt.AcquireToken(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire access token: %w", err)
+	}
+	domain := config.Get().DataPlaneRegionDomain()
+	c, err := resolveCapture(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Open(); err != nil {
+		return nil, err
+	}
+	refresh := tokenRefresh(ctx, rt, instanceID)
+	server, err := rt.NewSOCKS(dataplaneproxy.SOCKSOptions{
+		InstanceID:      instanceID,
+		Domain:          domain,
+		Token:           token,
+		TokenProvider:   refresh.provide,
+		InvalidateToken: refresh.invalidate,
+		ListenAddress:   address,
+		Logger:          log.New(deps.IO.ErrOut, "", log.LstdFlags),
+		Verbose:         boolFlag(req, "verbose"),
+		AccessLog:       c.AccessLog,
+		HAR:             c.HAR,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create proxy: %w", err)
+		c.Close(deps.IO.ErrOut)
+		return nil, fmt.Errorf("failed to create SOCKS5 proxy: %w", err)
 	}
-	addr, err := proxy.Start()
+	addr, err := server.Start()
 	if err != nil {
-		return nil, fmt.Errorf("failed to start proxy: %w", err)
+		c.Close(deps.IO.ErrOut)
+		return nil, fmt.Errorf("failed to start SOCKS5 proxy: %w", err)
 	}
 
-	fmt.Fprintf(deps.IO.Out, "Forwarding from %s -> %d\n", addr, remotePort)
-	fmt.Fprintf(deps.IO.Out, "  Local:  http://%s\n", addr)
-	fmt.Fprintf(deps.IO.Out, "  Remote: https://%d-%s.%s\n", remotePort, instanceID, domain)
+	fmt.Fprintf(deps.IO.Out, "SOCKS5 proxy for %s listening on %s\n", instanceID, addr)
+	fmt.Fprintf(deps.IO.Out, "  Remote: https://<port>-%s.%s\n", instanceID, domain)
+	fmt.Fprintf(deps.IO.Out, "  Try:    curl --socks5-hostname %s http://localhost:<port>/\n", addr)
+	c.Print(deps.IO.Out)
 	fmt.Fprintln(deps.IO.Out, "\nPress Ctrl+C to stop.")
 
 	rt.Wait(ctx)
 
 	fmt.Fprintln(deps.IO.Out, "\nStopping proxy...")
-	proxy.Stop()
+	server.Stop()
+	c.Close(deps.IO.Out)
 	return &command.Result{StreamDone: true}, nil
 }
 
+// runReverse exposes a local service inside the sandbox through the reverse
+// tunnel helper.
+func runReverse(ctx context.Context, req command.Request, deps command.Deps, rt RuntimeDeps, instanceID, spec string) (*command.Result, error) {
+	if len(req.Args) > 1 || req.ArgValues["port"] != "" || stringFlag(req, "ports-file") != "" || stringFlag(req, "socks5") != "" {
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--reverse cannot be combined with port arguments, --ports-file or --socks5",
+			"Run a separate 'agr instance proxy' for local forwards.")
+	}
+	if boolFlag(req, "background") || boolFlag(req, "daemon") || gateFlagsSet(req) || captureFlagsSet(req) {
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--reverse cannot be combined with --background, --auth, --allow-ip, --allow-origin, --tls, --access-log or --har",
+			"Run the reverse tunnel in the foreground.")
+	}
+	if cli.IsJSONOutput() {
+		return nil, output.NewUsageError("JSON_REQUIRES_BACKGROUND",
+			"agr instance proxy --reverse runs in the foreground and does not support -o json",
+			"Run it without -o json.")
+	}
+	remotePort, target, err := parseReverseSpec(spec)
+	if err != nil {
+		return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid --reverse mapping: %v", err),
+			"Use <remote_port>:[local_host:]<local_port>, for example 8000:localhost:8000.")
+	}
+	helperPort := reversetunnel.DefaultHelperPort
+	if flag, ok := req.Flags["helper-port"]; ok && flag.Changed {
+		helperPort = flag.Int
+	}
+	if helperPort <= 0 || helperPort > 65535 || helperPort == remotePort {
+		return nil, output.NewUsageError("INVALID_PORT",
+			fmt.Sprintf("invalid --helper-port %d", helperPort),
+			"Use a free sandbox port between 1 and 65535 that differs from the remote port.")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	logger := log.New(deps.IO.ErrOut, "", log.LstdFlags)
+
+	helper, err := rt.StartHelper(ctx, instanceID, reversetunnel.HelperOptions{
+		RemotePort: remotePort,
+		HelperPort: helperPort,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start reverse tunnel helper: %w", err)
+	}
+	stopHelper := func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := helper.Stop(stopCtx); err != nil {
+			fmt.Fprintf(deps.IO.ErrOut, "Warning: %v\n", err)
+		}
+	}
+	tunnel, err := rt.NewReverse(reversetunnel.Options{
+		InstanceID: instanceID,
+		Domain:     config.Get().DataPlaneRegionDomain(),
+		TokenProvider: func() (string, error) {
+			return rt.AcquireToken(ctx, instanceID)
+		},
+		HelperPort: helperPort,
+		Target:     target,
+		Logger:     logger,
+	})
+	if err != nil {
+		stopHelper()
+		return nil, fmt.Errorf("failed to create reverse tunnel: %w", err)
+	}
+	if err := tunnel.Start(); err != nil {
+		stopHelper()
+		return nil, fmt.Errorf("failed to start reverse tunnel: %w", err)
+	}
+
+	fmt.Fprintf(deps.IO.Out, "Forwarding from %s:%d -> %s\n", instanceID, remotePort, target)
+	fmt.Fprintf(deps.IO.Out, "  Sandbox: localhost:%d\n", remotePort)
+	fmt.Fprintf(deps.IO.Out, "  Local:   %s\n", target)
+	fmt.Fprintln(deps.IO.Out, "\nPress Ctrl+C to stop.")
+
+	rt.Wait(ctx)
+
+	fmt.Fprintln(deps.IO.Out, "\nStopping reverse tunnel...")
+	tunnel.Stop()
+	stopHelper()
+	return &command.Result{StreamDone: true}, nil
+}
+
+// parseReverseSpec parses <remote_port>:[local_host:]<local_port>. The local
+// host defaults to 127.0.0.1.
+func parseReverseSpec(spec string) (int, string, error) {
+	remoteStr, local, found := strings.Cut(spec, ":")
+	if !found {
+		return 0, "", fmt.Errorf("expected <remote_port>:[local_host:]<local_port>, got %q", spec)
+	}
+	remotePort, err := strconv.Atoi(remoteStr)
+	if err != nil || remotePort <= 0 || remotePort > 65535 {
+		return 0, "", fmt.Errorf("remote port must be between 1 and 65535, got %q", remoteStr)
+	}
+	host, portStr := "127.0.0.1", local
+	if i := strings.LastIndex(local, ":"); i >= 0 {
+		host, portStr = strings.Trim(local[:i], "[]"), local[i+1:]
+		if host == "" {
+			return 0, "", fmt.Errorf("local host is empty in %q", spec)
+		}
+	}
+	localPort, err := strconv.Atoi(portStr)
+	if err != nil || localPort <= 0 || localPort > 65535 {
+		return 0, "", fmt.Errorf("local port must be between 1 and 65535, got %q", portStr)
+	}
+	return remotePort, net.JoinHostPort(host, strconv.Itoa(localPort)), nil
+}
+
+// portMappings collects the port specifications given as arguments and in
+// --ports-file, in that order. Local ports must be unique.
+func portMappings(req command.Request) ([]mapping, error) {
+	var specs []string
+	if len(req.Args) > 1 {
+		specs = append(specs, req.Args[1:]...)
+	} else if spec := req.ArgValues["port"]; spec != "" {
+		specs = append(specs, spec)
+	}
+	if path := stringFlag(req, "ports-file"); path != "" {
+		fromFile, err := readPortsFile(path)
+		if err != nil {
+			return nil, output.NewUsageError("INVALID_PORTS_FILE", err.Error(), "List one [local_port:]remote_port per line; lines starting with # are ignored.")
+		}
+		specs = append(specs, fromFile...)
+	}
+	if len(specs) == 0 {
+		return nil, output.NewUsageError("MISSING_REQUIRED_ARG", "missing port specification", "Pass one or more [local_port:]remote_port arguments or --ports-file.")
+	}
+
+	mappings := make([]mapping, 0, len(specs))
+	seen := map[int]string{}
+	for _, spec := range specs {
+		localPort, remotePort, err := parsePortSpec(spec)
+		if err != nil {
+			return nil, output.NewUsageError("INVALID_PORT", fmt.Sprintf("invalid port specification: %v", err), "Use [local_port:]remote_port with values between 1 and 65535.")
+		}
+		if prev, ok := seen[localPort]; ok {
+			return nil, output.NewUsageError("INVALID_PORT",
+				fmt.Sprintf("invalid port specification: local port %d is used by both %q and %q", localPort, prev, spec),
+				"Give each mapping its own local port, for example 8081:80.")
+		}
+		seen[localPort] = spec
+		mappings = append(mappings, mapping{Spec: spec, Local: localPort, Remote: remotePort})
+	}
+	return mappings, nil
+}
+
+// readPortsFile reads port specifications from path. Specifications are
+// separated by whitespace or commas; '#' starts a comment.
+func readPortsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ports file: %w", err)
+	}
+	var specs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		specs = append(specs, strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})...)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("ports file %s lists no ports", path)
+	}
+	return specs, nil
+}
+
+// startBackground runs the proxy in a detached agr process and records it in
+// the tunnel registry so that `agr tunnel` can list and stop it.
+func startBackground(req command.Request, deps command.Deps, rt RuntimeDeps, g gate, c capture, instanceID, portSpec, address string, localPort, remotePort int) (*command.Result, error) {
+	store, err := rt.NewStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tunnel store: %w", err)
+	}
+	key := tunnelstore.ProxyKey(instanceID, localPort)
+	if err := store.Cleanup(key); err != nil {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: failed to cleanup existing proxy: %v\n", err)
+	}
+
+	args := []string{"instance", "proxy", instanceID, portSpec, "--daemon", "--address", address}
+	if boolFlag(req, "verbose") {
+		args = append(args, "--verbose")
+	}
+	args = append(args, g.DaemonArgs()...)
+	args = append(args, c.DaemonArgs()...)
+	proc, err := rt.StartDaemon(args, fmt.Sprintf("proxy-%s-%d", instanceID, localPort), g.DaemonEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to start background proxy: %w", err)
+	}
+	if err := store.Save(key, tunnelstore.TunnelEntry{
+		PID:        proc.PID,
+		Port:       proc.Port,
+		CreatedAt:  deps.Now(),
+		ExePath:    proc.ExePath,
+		Type:       tunnelstore.TypeProxy,
+		InstanceID: instanceID,
+		Address:    address,
+		RemotePort: remotePort,
+		LogPath:    proc.LogPath,
+	}); err != nil {
+		fmt.Fprintf(deps.IO.ErrOut, "Warning: failed to save proxy mapping: %v\n", err)
+	}
+
+	localAddr := net.JoinHostPort(address, strconv.Itoa(proc.Port))
+	data := map[string]any{
+		"TunnelId":     key,
+		"InstanceId":   instanceID,
+		"LocalAddress": localAddr,
+		"Port":         proc.Port,
+		"RemotePort":   remotePort,
+		"Pid":          proc.PID,
+	}
+	if proc.LogPath != "" {
+		data["LogPath"] = proc.LogPath
+	}
+	g.Data(data)
+	c.Data(data)
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		fmt.Fprintf(w, "Forwarding from %s -> %d in the background (PID %d)\n", localAddr, remotePort, proc.PID)
+		fmt.Fprintf(w, "  Local:  %s://%s\n", g.Scheme(), localAddr)
+		g.Print(w)
+		c.Print(w)
+		if proc.LogPath != "" {
+			fmt.Fprintf(w, "  Log:    %s\n", proc.LogPath)
+		}
+		fmt.Fprintf(w, "\nStop it with: agr tunnel stop %s\n", key)
+	}}, nil
+}
+
+func writeReadyError(w io.Writer, daemon bool, message string) {
+	if daemon {
+		tunneldaemon.WriteError(w, message)
+	}
+}
+
 func parsePortSpec(spec string) (int, int, error) {
 	parts := strings.SplitN(spec, ":", 2)
 
@@ -195,6 +761,26 @@ func parsePortSpec(spec string) (int, int, error) {
 	return localPort, remotePort, nil
 }
 
+// tokenRefresher re-acquires the instance access token after the sandbox
+// gateway rejected the cached one.
+type tokenRefresher struct {
+	ctx        context.Context
+	rt         RuntimeDeps
+	instanceID string
+}
+
+func tokenRefresh(ctx context.Context, rt RuntimeDeps, instanceID string) tokenRefresher {
+	return tokenRefresher{ctx: ctx, rt: rt, instanceID: instanceID}
+}
+
+func (r tokenRefresher) provide() (string, error) {
+	return r.rt.AcquireToken(r.ctx, r.instanceID)
+}
+
+func (r tokenRefresher) invalidate() {
+	r.rt.InvalidateToken(r.instanceID)
+}
+
 func waitForSignal(ctx context.Context) {
 	waitCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -3,13 +3,22 @@ package proxy
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/internal/tunneldaemon"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/reversetunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
 	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
 )
 
 func TestModuleDescriptor(t *testing.T) {
@@ -64,6 +73,45 @@ func TestRunProxyStartsAndStops(t *testing.T) {
 	}
 }
 
+func TestRunProxyRefreshesTokenThroughCache(t *testing.T) {
+	setupConfig(t)
+	var opts proxy.Options
+	var acquired int
+	var invalidated []string
+	runtime, err := Module().Build(command.Deps{
+		IO: testIO(),
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) {
+				acquired++
+				return fmt.Sprintf("token-%d", acquired), nil
+			},
+			InvalidateToken: func(instanceID string) { invalidated = append(invalidated, instanceID) },
+			NewProxy: func(o proxy.Options) (Proxy, error) {
+				opts = o
+				return &fakeProxy{addr: "127.0.0.1:3000"}, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if _, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "3000"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+	}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if opts.Token != "token-1" || opts.TokenProvider == nil || opts.InvalidateToken == nil {
+		t.Fatalf("opts=%#v", opts)
+	}
+	opts.InvalidateToken()
+	token, err := opts.TokenProvider()
+	if err != nil || token != "token-2" || len(invalidated) != 1 || invalidated[0] != "ins-1" {
+		t.Fatalf("token=%q err=%v invalidated=%v", token, err, invalidated)
+	}
+}
+
 func TestRunProxyRejectsInvalidPort(t *testing.T) {
 	setupConfig(t)
 	runtime, err := Module().Build(command.Deps{IO: testIO()})
@@ -80,6 +128,491 @@ func TestRunProxyRejectsInvalidPort(t *testing.T) {
 	}
 }
 
+func TestRunProxyBackgroundStartsDaemonAndRecordsTunnel(t *testing.T) {
+	setupConfig(t)
+	store := &fakeStore{saved: map[string]tunnelstore.TunnelEntry{}}
+	var gotArgs []string
+	var gotLog string
+	runtime, err := Module().Build(command.Deps{
+		IO: testIO(),
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) {
+				t.Fatal("parent process must not acquire a token")
+				return "", nil
+			},
+			StartDaemon: func(args []string, logName string, env []string) (tunneldaemon.Process, error) {
+				gotArgs, gotLog = args, logName
+				return tunneldaemon.Process{Port: 3000, PID: 4242, ExePath: "/bin/agr", LogPath: "/tmp/proxy.log"}, nil
+			},
+			NewStore: func() (Store, error) { return store, nil },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "3000:8080"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000:8080"},
+		Flags: map[string]command.FlagValue{
+			"address":    {Name: "address", Type: command.FlagString, String: "127.0.0.1"},
+			"background": {Name: "background", Type: command.FlagBool, Bool: true, Changed: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if strings.Join(gotArgs, " ") != "instance proxy ins-1 3000:8080 --daemon --address 127.0.0.1" || gotLog != "proxy-ins-1-3000" {
+		t.Fatalf("args=%q log=%q", gotArgs, gotLog)
+	}
+	key := "proxy:ins-1:3000"
+	if len(store.cleaned) != 1 || store.cleaned[0] != key {
+		t.Fatalf("cleaned=%v", store.cleaned)
+	}
+	entry := store.saved[key]
+	if entry.Type != tunnelstore.TypeProxy || entry.PID != 4242 || entry.InstanceID != "ins-1" || entry.RemotePort != 8080 || entry.LogPath != "/tmp/proxy.log" {
+		t.Fatalf("entry=%#v", entry)
+	}
+	data := result.Data.(map[string]any)
+	if data["TunnelId"] != key || data["LocalAddress"] != "127.0.0.1:3000" || data["Pid"] != 4242 {
+		t.Fatalf("data=%#v", data)
+	}
+}
+
+func TestRunProxyAuthGate(t *testing.T) {
+	setupConfig(t)
+	var opts proxy.Options
+	ios, _, stdout, stderr := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			NewProxy: func(o proxy.Options) (Proxy, error) {
+				opts = o
+				return &fakeProxy{addr: "0.0.0.0:3000"}, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "3000"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+		Flags: map[string]command.FlagValue{
+			"address":      {Name: "address", Type: command.FlagString, String: "0.0.0.0"},
+			"auth":         {Name: "auth", Type: command.FlagString, String: "token"},
+			"allow-ip":     {Name: "allow-ip", Type: command.FlagStringArray, Strings: []string{"10.0.0.0/8"}},
+			"allow-origin": {Name: "allow-origin", Type: command.FlagStringArray, Strings: []string{"https://app.example"}},
+			"tls":          {Name: "tls", Type: command.FlagBool, Bool: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(opts.Access.BearerToken) != 32 || len(opts.Access.AllowedNets) != 1 || len(opts.AllowedOrigins) != 1 || opts.TLSConfig == nil {
+		t.Fatalf("opts=%#v", opts)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "Local:  https://0.0.0.0:3000") || !strings.Contains(out, "Bearer "+opts.Access.BearerToken) || !strings.Contains(out, "TLS:    self-signed, SHA-256 ") {
+		t.Fatalf("stdout=%q", out)
+	}
+	if strings.Contains(stderr.String(), "exposes the proxy") {
+		t.Fatalf("stderr=%q", stderr.String())
+	}
+
+	for _, tc := range []struct {
+		flags map[string]command.FlagValue
+		want  string
+	}{
+		{flags: map[string]command.FlagValue{"auth": {String: "digest"}}, want: "invalid --auth"},
+		{flags: map[string]command.FlagValue{"auth": {String: "basic"}, "auth-credential": {String: "nopassword"}}, want: "user:password"},
+		{flags: map[string]command.FlagValue{"auth-credential": {String: "x"}}, want: "requires --auth"},
+		{flags: map[string]command.FlagValue{"allow-ip": {Strings: []string{"bogus"}}}, want: "invalid --allow-ip"},
+		{flags: map[string]command.FlagValue{"tls-cert": {String: "cert.pem"}}, want: "must be given together"},
+	} {
+		_, err := runtime.Handler.Run(context.Background(), command.Request{
+			Args:      []string{"ins-1", "3000"},
+			ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+			Flags:     tc.flags,
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("flags=%v err=%v, want %q", tc.flags, err, tc.want)
+		}
+	}
+}
+
+func TestRunProxyBackgroundHandsCredentialToDaemon(t *testing.T) {
+	setupConfig(t)
+	var gotArgs, gotEnv []string
+	runtime, err := Module().Build(command.Deps{
+		IO: testIO(),
+		DataPlane: RuntimeDeps{
+			StartDaemon: func(args []string, logName string, env []string) (tunneldaemon.Process, error) {
+				gotArgs, gotEnv = args, env
+				return tunneldaemon.Process{Port: 3000, PID: 4242}, nil
+			},
+			NewStore: func() (Store, error) { return &fakeStore{saved: map[string]tunnelstore.TunnelEntry{}}, nil },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "3000"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+		Flags: map[string]command.FlagValue{
+			"background":      {Name: "background", Type: command.FlagBool, Bool: true},
+			"auth":            {Name: "auth", Type: command.FlagString, String: "basic"},
+			"auth-credential": {Name: "auth-credential", Type: command.FlagString, String: "me:pw"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !strings.HasSuffix(strings.Join(gotArgs, " "), "--daemon --address 127.0.0.1 --auth basic") {
+		t.Fatalf("args=%q", gotArgs)
+	}
+	if len(gotEnv) != 1 || gotEnv[0] != "AGR_PROXY_CREDENTIAL=me:pw" {
+		t.Fatalf("env=%q", gotEnv)
+	}
+	data := result.Data.(map[string]any)
+	if data["Auth"] != "basic" || data["Credential"] != "me:pw" {
+		t.Fatalf("data=%#v", data)
+	}
+}
+
+func TestRunProxyCapture(t *testing.T) {
+	setupConfig(t)
+	dir := t.TempDir()
+	accessLog, har := filepath.Join(dir, "access.ndjson"), filepath.Join(dir, "session.har")
+	var opts proxy.Options
+	var gotArgs []string
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			NewProxy: func(o proxy.Options) (Proxy, error) {
+				opts = o
+				return &fakeProxy{addr: "127.0.0.1:3000"}, nil
+			},
+			Wait: func(context.Context) {},
+			StartDaemon: func(args []string, logName string, env []string) (tunneldaemon.Process, error) {
+				gotArgs = args
+				return tunneldaemon.Process{Port: 3000, PID: 4242}, nil
+			},
+			NewStore: func() (Store, error) { return &fakeStore{saved: map[string]tunnelstore.TunnelEntry{}}, nil },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	flags := map[string]command.FlagValue{
+		"access-log": {Name: "access-log", Type: command.FlagString, String: accessLog},
+		"har":        {Name: "har", Type: command.FlagString, String: har},
+	}
+	req := command.Request{
+		Args:      []string{"ins-1", "3000"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+		Flags:     flags,
+	}
+	if _, err := runtime.Handler.Run(context.Background(), req); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if opts.AccessLog == nil || opts.HAR == nil {
+		t.Fatalf("opts=%#v", opts)
+	}
+	if _, err := os.Stat(accessLog); err != nil {
+		t.Fatalf("access log not created: %v", err)
+	}
+	if data, err := os.ReadFile(har); err != nil || !strings.Contains(string(data), `"version": "1.2"`) {
+		t.Fatalf("har=%q err=%v", data, err)
+	}
+	if !strings.Contains(stdout.String(), "Wrote 0 requests to "+har) {
+		t.Fatalf("stdout=%q", stdout.String())
+	}
+
+	flags["background"] = command.FlagValue{Name: "background", Type: command.FlagBool, Bool: true}
+	result, err := runtime.Handler.Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !strings.HasSuffix(strings.Join(gotArgs, " "), "--access-log "+accessLog+" --har "+har) {
+		t.Fatalf("args=%q", gotArgs)
+	}
+	if data := result.Data.(map[string]any); data["Har"] != har || data["AccessLog"] != accessLog {
+		t.Fatalf("data=%#v", data)
+	}
+
+	flags["background"] = command.FlagValue{}
+	flags["har"] = command.FlagValue{String: filepath.Join(dir, "missing", "session.har")}
+	if _, err := runtime.Handler.Run(context.Background(), req); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestRunProxyDaemonWritesReadyMessage(t *testing.T) {
+	setupConfig(t)
+	fake := &fakeProxy{addr: "127.0.0.1:3000"}
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			NewProxy:     func(proxy.Options) (Proxy, error) { return fake, nil },
+			Wait:         func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if _, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "3000"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+		Flags: map[string]command.FlagValue{
+			"background": {Name: "background", Type: command.FlagBool, Bool: true},
+			"daemon":     {Name: "daemon", Type: command.FlagBool, Bool: true},
+		},
+	}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	ready, err := tunneldaemon.ReadReady(strings.NewReader(stdout.String()), time.Second)
+	if err != nil || ready.Status != "ready" || ready.Port != 3000 {
+		t.Fatalf("ready=%#v err=%v stdout=%q", ready, err, stdout.String())
+	}
+	if !fake.stopped {
+		t.Fatal("proxy was not stopped after the wait returned")
+	}
+}
+
+func TestRunProxyForwardsSeveralPortsWithOneToken(t *testing.T) {
+	setupConfig(t)
+	portsFile := filepath.Join(t.TempDir(), "ports.txt")
+	if err := os.WriteFile(portsFile, []byte("# debugger\n9229\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var tokens int
+	var opts []proxy.Options
+	var fakes []*fakeProxy
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) {
+				tokens++
+				return "token", nil
+			},
+			NewProxy: func(o proxy.Options) (Proxy, error) {
+				opts = append(opts, o)
+				fake := &fakeProxy{addr: o.ListenAddress}
+				fakes = append(fakes, fake)
+				return fake, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if _, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "3000", "8080:80"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+		Flags: map[string]command.FlagValue{
+			"ports-file": {Name: "ports-file", Type: command.FlagString, String: portsFile, Changed: true},
+		},
+	}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if tokens != 1 || len(opts) != 3 {
+		t.Fatalf("tokens=%d proxies=%d", tokens, len(opts))
+	}
+	if opts[1].ListenAddress != "127.0.0.1:8080" || opts[1].RemotePort != 80 || opts[2].RemotePort != 9229 || opts[0].Logger == nil || opts[0].Logger != opts[2].Logger {
+		t.Fatalf("opts=%#v", opts)
+	}
+	for _, fake := range fakes {
+		if !fake.stopped {
+			t.Fatalf("proxy %s was not stopped", fake.addr)
+		}
+	}
+	if !strings.Contains(stdout.String(), "Forwarding 3 ports from ins-1") || !strings.Contains(stdout.String(), "http://127.0.0.1:8080  80") {
+		t.Fatalf("stdout=%q", stdout.String())
+	}
+}
+
+func TestRunProxyRejectsDuplicateLocalPorts(t *testing.T) {
+	setupConfig(t)
+	runtime, err := Module().Build(command.Deps{IO: testIO()})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "3000", "3000:80"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "local port 3000 is used by both") {
+		t.Fatalf("error=%v, want duplicate local port", err)
+	}
+}
+
+func TestRunProxySOCKS5(t *testing.T) {
+	setupConfig(t)
+	fake := &fakeProxy{addr: "127.0.0.1:1080"}
+	var opts proxy.SOCKSOptions
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			NewSOCKS: func(o proxy.SOCKSOptions) (Proxy, error) {
+				opts = o
+				return fake, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags:     map[string]command.FlagValue{"socks5": {Name: "socks5", Type: command.FlagString, String: "127.0.0.1:1080"}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone || !fake.started || !fake.stopped {
+		t.Fatalf("result=%#v fake=%#v", result, fake)
+	}
+	if opts.InstanceID != "ins-1" || opts.Token != "token" || opts.ListenAddress != "127.0.0.1:1080" {
+		t.Fatalf("opts=%#v", opts)
+	}
+	if !strings.Contains(stdout.String(), "curl --socks5-hostname 127.0.0.1:1080") {
+		t.Fatalf("stdout=%q", stdout.String())
+	}
+
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "8080"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "8080"},
+		Flags:     map[string]command.FlagValue{"socks5": {Name: "socks5", Type: command.FlagString, String: "127.0.0.1:1080"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("err=%v", err)
+	}
+
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags:     map[string]command.FlagValue{"socks5": {Name: "socks5", Type: command.FlagString, String: "0.0.0.0:1080"}},
+	})
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != "INVALID_ADDRESS" {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestRunProxyReverse(t *testing.T) {
+	setupConfig(t)
+	helper := &fakeHelper{}
+	tunnel := &fakeReverse{}
+	var helperOpts reversetunnel.HelperOptions
+	var tunnelOpts reversetunnel.Options
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			StartHelper: func(_ context.Context, instanceID string, o reversetunnel.HelperOptions) (Helper, error) {
+				helperOpts = o
+				return helper, nil
+			},
+			NewReverse: func(o reversetunnel.Options) (ReverseTunnel, error) {
+				tunnelOpts = o
+				return tunnel, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	result, err := runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags:     map[string]command.FlagValue{"reverse": {Name: "reverse", Type: command.FlagString, String: "8000:11434"}},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !result.StreamDone || !tunnel.started || !tunnel.stopped || !helper.stopped {
+		t.Fatalf("result=%#v tunnel=%#v helper=%#v", result, tunnel, helper)
+	}
+	if helperOpts.RemotePort != 8000 || helperOpts.HelperPort != reversetunnel.DefaultHelperPort {
+		t.Fatalf("helperOpts=%#v", helperOpts)
+	}
+	if tunnelOpts.InstanceID != "ins-1" || tunnelOpts.Target != "127.0.0.1:11434" || tunnelOpts.HelperPort != reversetunnel.DefaultHelperPort {
+		t.Fatalf("tunnelOpts=%#v", tunnelOpts)
+	}
+	if token, err := tunnelOpts.TokenProvider(); err != nil || token != "token" {
+		t.Fatalf("token=%q err=%v", token, err)
+	}
+	if !strings.Contains(stdout.String(), "Forwarding from ins-1:8000 -> 127.0.0.1:11434") {
+		t.Fatalf("stdout=%q", stdout.String())
+	}
+}
+
+func TestRunProxyReverseStopsHelperWhenTunnelFails(t *testing.T) {
+	setupConfig(t)
+	helper := &fakeHelper{}
+	runtime, err := Module().Build(command.Deps{
+		IO: testIO(),
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			StartHelper: func(context.Context, string, reversetunnel.HelperOptions) (Helper, error) {
+				return helper, nil
+			},
+			NewReverse: func(reversetunnel.Options) (ReverseTunnel, error) {
+				return &fakeReverse{startErr: errors.New("helper is not reachable")}, nil
+			},
+			Wait: func(context.Context) {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags:     map[string]command.FlagValue{"reverse": {Name: "reverse", Type: command.FlagString, String: "8000:localhost:8000"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "not reachable") || !helper.stopped {
+		t.Fatalf("err=%v helper=%#v", err, helper)
+	}
+}
+
+func TestParseReverseSpec(t *testing.T) {
+	for _, tc := range []struct {
+		spec   string
+		remote int
+		target string
+		ok     bool
+	}{
+		{spec: "8000:8001", remote: 8000, target: "127.0.0.1:8001", ok: true},
+		{spec: "8000:localhost:8000", remote: 8000, target: "localhost:8000", ok: true},
+		{spec: "8000:[::1]:9000", remote: 8000, target: "[::1]:9000", ok: true},
+		{spec: "8000"},
+		{spec: "0:8000"},
+		{spec: "8000::8000"},
+		{spec: "8000:localhost:x"},
+	} {
+		remote, target, err := parseReverseSpec(tc.spec)
+		if tc.ok != (err == nil) || remote != tc.remote || target != tc.target {
+			t.Fatalf("parseReverseSpec(%q) = %d, %q, %v", tc.spec, remote, target, err)
+		}
+	}
+}
+
 func TestParsePortSpec(t *testing.T) {
 	for _, tc := range []struct {
 		spec       string
@@ -107,18 +640,53 @@ func TestParsePortSpec(t *testing.T) {
 }
 
 type fakeProxy struct {
-	addr    string
-	started bool
-	stopped bool
+	addr     string
+	startErr error
+	started  bool
+	stopped  bool
 }
 
 func (f *fakeProxy) Start() (string, error) {
 	f.started = true
-	return f.addr, nil
+	return f.addr, f.startErr
 }
 
 func (f *fakeProxy) Stop() { f.stopped = true }
 
+type fakeStore struct {
+	saved   map[string]tunnelstore.TunnelEntry
+	cleaned []string
+}
+
+func (f *fakeStore) Cleanup(key string) error {
+	f.cleaned = append(f.cleaned, key)
+	return nil
+}
+
+func (f *fakeStore) Save(key string, entry tunnelstore.TunnelEntry) error {
+	f.saved[key] = entry
+	return nil
+}
+
+type fakeHelper struct{ stopped bool }
+
+func (f *fakeHelper) Stop(context.Context) error {
+	f.stopped = true
+	return nil
+}
+
+type fakeReverse struct {
+	startErr         error
+	started, stopped bool
+}
+
+func (f *fakeReverse) Start() error {
+	f.started = true
+	return f.startErr
+}
+
+func (f *fakeReverse) Stop() { f.stopped = true }
+
 func setupConfig(t *testing.T) {
 	t.Helper()
 	if err := config.Init(); err != nil {
@@ -132,3 +700,139 @@ func setupConfig(t *testing.T) {
 func testIO() *iostreams.IOStreams {
 	return &iostreams.IOStreams{In: &bytes.Buffer{}, Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
 }
+
+func TestRunProxyAutoForwardsPortsAsTheyOpenAndClose(t *testing.T) {
+	setupConfig(t)
+	polls := [][]int{{3000, 49983}, {3000, 8080}, {8080}}
+	var calls int
+	done := make(chan struct{})
+	proxies := map[int]*fakeProxy{}
+	ios, _, stdout, _ := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			NewProxy: func(o proxy.Options) (Proxy, error) {
+				fake := &fakeProxy{addr: o.ListenAddress}
+				proxies[o.RemotePort] = fake
+				return fake, nil
+			},
+			ListPorts: func(context.Context, string) ([]int, error) {
+				if calls == len(polls) {
+					close(done)
+					return polls[len(polls)-1], nil
+				}
+				calls++
+				return polls[calls-1], nil
+			},
+			Wait: func(context.Context) {
+				select {
+				case <-done:
+				case <-time.After(10 * time.Second):
+				}
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags: map[string]command.FlagValue{
+			"auto":          {Name: "auto", Type: command.FlagBool, Bool: true, Changed: true},
+			"auto-interval": {Name: "auto-interval", Type: command.FlagString, String: "500ms", Changed: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(proxies) != 2 || proxies[49983] != nil {
+		t.Fatalf("proxies=%#v", proxies)
+	}
+	if !proxies[3000].stopped || !proxies[8080].stopped {
+		t.Fatalf("3000=%#v 8080=%#v", proxies[3000], proxies[8080])
+	}
+	for _, want := range []string{
+		"+ Port 3000 opened: forwarding http://127.0.0.1:3000 -> 3000",
+		"+ Port 8080 opened: forwarding http://127.0.0.1:8080 -> 8080",
+		"- Port 3000 closed: stopped forwarding 127.0.0.1:3000",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestRunProxyAutoRetriesPortsThatFailedToForward(t *testing.T) {
+	setupConfig(t)
+	var calls, created int
+	done := make(chan struct{})
+	ios, _, stdout, stderr := iostreams.Test()
+	runtime, err := Module().Build(command.Deps{
+		IO: ios,
+		DataPlane: RuntimeDeps{
+			AcquireToken: func(context.Context, string) (string, error) { return "token", nil },
+			NewProxy: func(o proxy.Options) (Proxy, error) {
+				created++
+				fake := &fakeProxy{addr: o.ListenAddress}
+				// The first poll fails on the port and its fallback.
+				if created <= 2 {
+					fake.startErr = errors.New("address in use")
+				}
+				return fake, nil
+			},
+			ListPorts: func(context.Context, string) ([]int, error) {
+				if calls == 3 {
+					close(done)
+				}
+				calls++
+				return []int{3000}, nil
+			},
+			Wait: func(context.Context) {
+				select {
+				case <-done:
+				case <-time.After(10 * time.Second):
+				}
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1"},
+		ArgValues: map[string]string{"instance-id": "ins-1"},
+		Flags: map[string]command.FlagValue{
+			"auto":          {Name: "auto", Type: command.FlagBool, Bool: true, Changed: true},
+			"auto-interval": {Name: "auto-interval", Type: command.FlagString, String: "500ms", Changed: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if created != 3 {
+		t.Fatalf("created %d proxies, want 3", created)
+	}
+	if !strings.Contains(stderr.String(), "failed to forward port 3000") || !strings.Contains(stdout.String(), "+ Port 3000 opened") {
+		t.Fatalf("stdout=%s\nstderr=%s", stdout.String(), stderr.String())
+	}
+}
+
+func TestRunProxyAutoRejectsPortArguments(t *testing.T) {
+	setupConfig(t)
+	runtime, err := Module().Build(command.Deps{IO: testIO()})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	_, err = runtime.Handler.Run(context.Background(), command.Request{
+		Args:      []string{"ins-1", "3000"},
+		ArgValues: map[string]string{"instance-id": "ins-1", "port": "3000"},
+		Flags: map[string]command.FlagValue{
+			"auto": {Name: "auto", Type: command.FlagBool, Bool: true, Changed: true},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "--auto cannot be combined") {
+		t.Fatalf("err=%v", err)
+	}
+}
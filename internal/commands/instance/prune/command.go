@@ -0,0 +1,276 @@
+package prune
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	workflow "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/overlay"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	instancedelete "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/delete"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/session"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+)
+
+// ControlPlane is the instance listing and deletion surface used by prune.
+type ControlPlane interface {
+	ListInstances(ctx context.Context) ([]*ags.SandboxInstance, error)
+	DeleteInstance(ctx context.Context, instanceID string) error
+}
+
+// hostname and processAlive identify the local creator processes; tests
+// replace them.
+var (
+	hostname     = os.Hostname
+	processAlive = tunnelstore.IsProcessAlive
+)
+
+// Orphan is one temporary instance whose creating process is gone.
+type Orphan struct {
+	InstanceId string
+	ToolId     string
+	Status     string
+	Host       string
+	Pid        string
+	StartedAt  string
+	Reason     string
+}
+
+// Module returns the "instance prune" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "instance.prune",
+		Path:  []string{"instance", "prune"},
+		Use:   "prune --orphans",
+		Short: "Delete temporary instances left behind by killed invocations",
+		Long: `Delete temporary instances whose creating process is gone.
+
+Instances created with --create-temp-instance are tagged with the creating
+host, PID and start time, and so are instances leased from a warm pool. With
+--orphans, prune lists the instances created or leased on this host with
+--cleanup always or success whose creating process no longer runs, and deletes
+them. Instances kept on purpose (--cleanup never), session instances and
+instances created on other hosts are left alone.
+
+Sessions idle for longer than their TTL are ended as well, applying their
+cleanup policy, and reported as warnings.`,
+		Examples: []string{
+			"agr instance prune --orphans --dry-run",
+			"agr instance prune --orphans --yes",
+		},
+		Flags: []command.FlagSpec{
+			{Name: "orphans", Usage: "Select temporary instances whose creating process is gone", Type: command.FlagBool, Workflow: true},
+			{Name: "dry-run", Usage: "List instances that would be deleted without deleting them", Type: command.FlagBool, Workflow: true},
+			{Name: "yes", Shorthand: "y", Usage: "Skip confirmation prompt", Type: command.FlagBool, Workflow: true},
+		},
+		SupportsJSON: true,
+		Output: command.OutputSpec{
+			DataType:    "PruneData",
+			Description: "Orphaned instances and the delete summary.",
+			Effects:     []string{"delete:instance"},
+		},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec: spec,
+			Groups: []command.GroupSpec{
+				{
+					Path:    []string{"instance"},
+					Use:     "instance",
+					Short:   "Manage sandbox instances",
+					Long:    "Manage sandbox instances and related data-plane workflows.",
+					Aliases: []string{"i"},
+				},
+			},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			cp, ok := deps.ControlPlane.(ControlPlane)
+			if !ok {
+				return command.Runtime{}, fmt.Errorf("%s requires command.Deps.ControlPlane implementing instance/prune.ControlPlane", spec.ID)
+			}
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runPrune(ctx, req, deps, cp)
+			})}, nil
+		},
+	}
+}
+
+func runPrune(ctx context.Context, req command.Request, deps command.Deps, cp ControlPlane) (*command.Result, error) {
+	if !req.Flags["orphans"].Bool {
+		return nil, output.NewUsageError("MISSING_REQUIRED_FLAG",
+			"agr instance prune requires --orphans",
+			"Run: agr instance prune --orphans --dry-run")
+	}
+	host, err := hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine hostname: %w", err)
+	}
+	instances, err := cp.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orphans := findOrphans(instances, workflow.OrphanCheck{Host: host, Alive: processAlive})
+
+	if req.Flags["dry-run"].Bool {
+		return dryRunResult(orphans), nil
+	}
+	if len(orphans) > 0 && !req.Flags["yes"].Bool && !cli.NonInteractive() && deps.IO.IsStdinTTY() {
+		fmt.Fprintf(deps.IO.ErrOut, "The following orphaned instances will be deleted:\n")
+		for _, o := range orphans {
+			fmt.Fprintf(deps.IO.ErrOut, "  - %s (%s)\n", o.InstanceId, o.Reason)
+		}
+		fmt.Fprintf(deps.IO.ErrOut, "\nProceed? [y/N] ")
+		var answer string
+		if _, err := fmt.Fscanln(deps.IO.In, &answer); err != nil || (answer != "y" && answer != "Y") {
+			return &command.Result{
+				Data: map[string]any{"Cancelled": true},
+				Text: func(w io.Writer) { fmt.Fprintln(w, "Cancelled.") },
+			}, nil
+		}
+	}
+
+	summary := instancedelete.Summary{}
+	warnings, err := sweepSessions(ctx, deps, cp)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orphans {
+		err := cp.DeleteInstance(ctx, o.InstanceId)
+		switch {
+		case err == nil:
+			summary.Deleted++
+			summary.DeletedIDs = append(summary.DeletedIDs, o.InstanceId)
+		case isNotFound(deps.ControlPlane, err):
+			summary.AlreadyAbsent = append(summary.AlreadyAbsent, o.InstanceId)
+		default:
+			summary.Failed++
+			summary.FailedIDs = append(summary.FailedIDs, o.InstanceId)
+			warnings = append(warnings, fmt.Sprintf("Failed to delete %s: %v", o.InstanceId, err))
+		}
+	}
+
+	data := summary.Data()
+	data["Orphans"] = orphans
+	result := &command.Result{
+		Data:     data,
+		Warnings: warnings,
+		Text: func(w io.Writer) {
+			if len(orphans) == 0 {
+				fmt.Fprintln(w, "No orphaned instances found")
+				return
+			}
+			for _, id := range summary.DeletedIDs {
+				fmt.Fprintf(w, "Instance deleted: %s\n", id)
+			}
+			for _, id := range summary.AlreadyAbsent {
+				fmt.Fprintf(w, "Instance %s already absent\n", id)
+			}
+			if summary.Failed > 0 {
+				fmt.Fprintf(deps.IO.ErrOut, "failed to delete %d instance(s)\n", summary.Failed)
+			}
+		},
+	}
+	for _, id := range summary.DeletedIDs {
+		result.Effects = append(result.Effects, output.Effect{Kind: "delete", Resource: "instance", Id: id})
+	}
+	if summary.Failed > 0 {
+		result.Failure = &output.Failure{
+			Code:    "PARTIAL_DELETE_FAILED",
+			Kind:    output.KindPartialSuccess,
+			Message: "failed to delete one or more orphaned instances",
+			Hint:    "Inspect Data.FailedIds and retry with: agr instance delete <instance-id>",
+		}
+		result.ExitCode = output.ExitPartialSuccess
+	}
+	return result, nil
+}
+
+// sweepSessions ends the expired sessions.
+func sweepSessions(ctx context.Context, deps command.Deps, cp ControlPlane) ([]string, error) {
+	store, err := cli.NewSessionStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+	return session.SweepExpired(ctx, store, deps, cp)
+}
+
+// findOrphans returns the live instances that check classifies as orphans.
+func findOrphans(instances []*ags.SandboxInstance, check workflow.OrphanCheck) []Orphan {
+	orphans := []Orphan{}
+	for _, inst := range instances {
+		if inst == nil || inst.InstanceId == nil {
+			continue
+		}
+		status := deref(inst.Status)
+		if status == "STOPPED" || status == "STOPPING" {
+			continue
+		}
+		metadata := map[string]string{}
+		for _, m := range inst.Metadata {
+			if m != nil && m.Name != nil {
+				metadata[*m.Name] = deref(m.Value)
+			}
+		}
+		orphan, reason := check.Classify(metadata)
+		if !orphan {
+			continue
+		}
+		orphans = append(orphans, Orphan{
+			InstanceId: *inst.InstanceId,
+			ToolId:     deref(inst.ToolId),
+			Status:     status,
+			Host:       metadata[workflow.MetadataHost],
+			Pid:        metadata[workflow.MetadataPID],
+			StartedAt:  metadata[workflow.MetadataStartedAt],
+			Reason:     reason,
+		})
+	}
+	return orphans
+}
+
+func dryRunResult(orphans []Orphan) *command.Result {
+	ids := make([]string, 0, len(orphans))
+	for _, o := range orphans {
+		ids = append(ids, o.InstanceId)
+	}
+	return &command.Result{
+		Data: map[string]any{
+			"DryRun":      true,
+			"WouldDelete": ids,
+			"Orphans":     orphans,
+		},
+		Text: func(w io.Writer) {
+			if len(orphans) == 0 {
+				fmt.Fprintln(w, "No orphaned instances found")
+				return
+			}
+			fmt.Fprintln(w, "Dry run — the following orphaned instances would be deleted:")
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tTOOL\tSTARTED\tREASON")
+			for _, o := range orphans {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", o.InstanceId, o.ToolId, o.StartedAt, o.Reason)
+			}
+			_ = tw.Flush()
+			fmt.Fprintln(w, "\nNo changes were made.")
+		},
+	}
+}
+
+func isNotFound(classifier any, err error) bool {
+	c, ok := classifier.(instancedelete.NotFoundClassifier)
+	return ok && c.IsNotFound(err)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,164 @@
+package prune
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	workflow "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/overlay"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/session"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+)
+
+type fakeControlPlane struct {
+	instances []*ags.SandboxInstance
+	fail      map[string]error
+	deleted   []string
+}
+
+func (f *fakeControlPlane) ListInstances(context.Context) ([]*ags.SandboxInstance, error) {
+	return f.instances, nil
+}
+
+func (f *fakeControlPlane) DeleteInstance(_ context.Context, instanceID string) error {
+	if err := f.fail[instanceID]; err != nil {
+		return err
+	}
+	f.deleted = append(f.deleted, instanceID)
+	return nil
+}
+
+func tagged(id, status string, pid int, policy workflow.CleanupPolicy) *ags.SandboxInstance {
+	inst := &ags.SandboxInstance{InstanceId: &id, Status: &status}
+	for name, value := range workflow.CreatorMetadata("dev", pid, time.Unix(0, 0), policy, "") {
+		inst.Metadata = append(inst.Metadata, &ags.MetadataVar{Name: strPtr(name), Value: strPtr(value)})
+	}
+	return inst
+}
+
+func strPtr(s string) *string { return &s }
+
+// setup builds the command against cp and a temp session store holding
+// sessions.
+func setup(t *testing.T, cp *fakeControlPlane, sessions ...session.Session) command.Runtime {
+	t.Helper()
+	store := session.NewStoreAt(filepath.Join(t.TempDir(), "sessions.json"))
+	for _, sess := range sessions {
+		if _, _, err := store.Create(sess); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(cli.SetSessionHooksForTest(store, nil))
+	prevHost, prevAlive := hostname, processAlive
+	hostname = func() (string, error) { return "dev", nil }
+	processAlive = func(pid int) bool { return pid == 42 }
+	t.Cleanup(func() { hostname, processAlive = prevHost, prevAlive })
+	cp.instances = []*ags.SandboxInstance{
+		tagged("ins-orphan", "RUNNING", 7, workflow.CleanupAlways),
+		tagged("ins-live", "RUNNING", 42, workflow.CleanupAlways),
+		tagged("ins-kept", "RUNNING", 7, workflow.CleanupNever),
+		tagged("ins-stopped", "STOPPED", 7, workflow.CleanupAlways),
+		tagged("ins-orphan-2", "RUNNING", 8, workflow.CleanupAlways),
+		{InstanceId: strPtr("ins-untagged"), Status: strPtr("RUNNING")},
+	}
+	runtime, err := Module().Build(command.Deps{ControlPlane: cp, Now: func() time.Time { return time.Unix(3600, 0) }})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return runtime
+}
+
+func flags(names ...string) map[string]command.FlagValue {
+	out := map[string]command.FlagValue{}
+	for _, name := range names {
+		out[name] = command.FlagValue{Name: name, Type: command.FlagBool, Bool: true, Changed: true}
+	}
+	return out
+}
+
+func TestPruneDeletesOnlyOrphans(t *testing.T) {
+	cp := &fakeControlPlane{}
+	runtime := setup(t, cp)
+	result, err := runtime.Handler.Run(context.Background(), command.Request{Flags: flags("orphans", "yes")})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(cp.deleted) != 2 || cp.deleted[0] != "ins-orphan" || cp.deleted[1] != "ins-orphan-2" {
+		t.Fatalf("deleted = %#v", cp.deleted)
+	}
+	data := result.Data.(map[string]any)
+	if data["Deleted"] != 2 || data["Failed"] != 0 {
+		t.Fatalf("data = %#v", data)
+	}
+	if orphans := data["Orphans"].([]Orphan); len(orphans) != 2 || orphans[0].Pid != "7" {
+		t.Fatalf("orphans = %#v", orphans)
+	}
+	if len(result.Effects) != 2 {
+		t.Fatalf("effects = %#v", result.Effects)
+	}
+}
+
+func TestPruneDryRunDoesNotDelete(t *testing.T) {
+	cp := &fakeControlPlane{}
+	runtime := setup(t, cp)
+	result, err := runtime.Handler.Run(context.Background(), command.Request{Flags: flags("orphans", "dry-run")})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(cp.deleted) != 0 {
+		t.Fatalf("dry-run deleted %#v", cp.deleted)
+	}
+	data := result.Data.(map[string]any)
+	if ids := data["WouldDelete"].([]string); data["DryRun"] != true || len(ids) != 2 {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestPruneEndsExpiredSessions(t *testing.T) {
+	cp := &fakeControlPlane{}
+	runtime := setup(t, cp,
+		session.Session{Name: "idle", InstanceID: "ins-idle", Cleanup: "always", IdleTTL: "30m", LastUsedAt: time.Unix(0, 0)},
+		session.Session{Name: "busy", InstanceID: "ins-busy", Cleanup: "always", IdleTTL: "30m", LastUsedAt: time.Unix(3000, 0)},
+	)
+	result, err := runtime.Handler.Run(context.Background(), command.Request{Flags: flags("orphans", "yes")})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(cp.deleted) != 3 || cp.deleted[0] != "ins-idle" {
+		t.Fatalf("deleted = %#v", cp.deleted)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "Session idle was idle for more than 30m") {
+		t.Fatalf("warnings = %#v", result.Warnings)
+	}
+}
+
+func TestPrunePartialFailure(t *testing.T) {
+	cp := &fakeControlPlane{fail: map[string]error{"ins-orphan-2": errors.New("boom")}}
+	runtime := setup(t, cp)
+	result, err := runtime.Handler.Run(context.Background(), command.Request{Flags: flags("orphans", "yes")})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.ExitCode != output.ExitPartialSuccess || result.Failure == nil || result.Failure.Code != "PARTIAL_DELETE_FAILED" {
+		t.Fatalf("result = %#v", result)
+	}
+	data := result.Data.(map[string]any)
+	if ids := data["FailedIds"].([]string); len(ids) != 1 || ids[0] != "ins-orphan-2" {
+		t.Fatalf("data = %#v", data)
+	}
+}
+
+func TestPruneRequiresOrphans(t *testing.T) {
+	runtime := setup(t, &fakeControlPlane{})
+	_, err := runtime.Handler.Run(context.Background(), command.Request{Flags: flags("dry-run")})
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != "MISSING_REQUIRED_FLAG" {
+		t.Fatalf("err = %v", err)
+	}
+}
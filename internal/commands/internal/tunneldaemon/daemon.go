@@ -0,0 +1,199 @@
+// Package tunneldaemon starts agr tunnel processes in the background and
+// exchanges the one-line readiness message they print on stdout.
+//
+// The parent runs the agr executable with a hidden --daemon flag, sends the
+// child's stderr to a log file under ~/.agr, and waits for the child to report
+// the port it listens on. The child writes a Ready message as soon as its
+// listener is up, or an error message when it cannot start.
+package tunneldaemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+)
+
+// ReadyTimeout is how long Start waits for the readiness message.
+const ReadyTimeout = 30 * time.Second
+
+// Ready is the first line a daemon writes to stdout.
+type Ready struct {
+	Status  string `json:"status"`
+	Port    int    `json:"port,omitempty"`
+	PID     int    `json:"pid,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Process describes a daemon that reported ready.
+type Process struct {
+	Port    int
+	PID     int
+	ExePath string
+	LogPath string
+}
+
+// Start runs the agr executable with args in the background, forwarding the
+// global config, region, domain and credential settings. The daemon's stderr
+// is written to ~/.agr/<logName>.log. Start returns once the daemon reports
+// ready and kills it when it reports an error or times out.
+func Start(args []string, logName string) (Process, error) {
+	return StartWithEnv(args, logName, nil)
+}
+
+// StartWithEnv is Start with extra KEY=VALUE environment variables, used to
+// hand secrets to the daemon without exposing them in its arguments.
+func StartWithEnv(args []string, logName string, extraEnv []string) (Process, error) {
+	selfPath, err := os.Executable()
+	if err != nil {
+		return Process{}, fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	if cli.CfgFile() != "" {
+		args = append(args, "--config", cli.CfgFile())
+	}
+	if cli.RegionFlag() != "" {
+		args = append(args, "--region", cli.RegionFlag())
+	}
+	if cli.DomainFlag() != "" {
+		args = append(args, "--domain", cli.DomainFlag())
+	}
+
+	cmd := exec.Command(selfPath, args...)
+	logPath, logFile := OpenLog(logName)
+	if logFile != nil {
+		cmd.Stderr = logFile
+	}
+	cmd.Env = append(Env(), extraEnv...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = closeIfOpen(logFile)
+		return Process{}, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		_ = closeIfOpen(logFile)
+		return Process{}, fmt.Errorf("failed to start tunnel process: %w", err)
+	}
+
+	go func() {
+		_ = cmd.Wait()
+		_ = closeIfOpen(logFile)
+	}()
+
+	ready, err := ReadReady(stdout, ReadyTimeout)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return Process{}, err
+	}
+	if ready.Status != "ready" || ready.Port == 0 {
+		_ = cmd.Process.Kill()
+		return Process{}, fmt.Errorf("tunnel reported error: %s", ready.Message)
+	}
+	return Process{Port: ready.Port, PID: ready.PID, ExePath: selfPath, LogPath: logPath}, nil
+}
+
+// ReadReady reads the readiness message from a daemon's stdout.
+func ReadReady(stdout io.Reader, timeout time.Duration) (Ready, error) {
+	readyCh := make(chan Ready, 1)
+	errCh := make(chan error, 1)
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		if scanner.Scan() {
+			var msg Ready
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				errCh <- fmt.Errorf("failed to parse tunnel ready message: %w", err)
+				return
+			}
+			readyCh <- msg
+			return
+		}
+		if err := scanner.Err(); err != nil {
+			errCh <- fmt.Errorf("failed to read tunnel output: %w", err)
+		} else {
+			errCh <- fmt.Errorf("tunnel process exited without ready message")
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ready := <-readyCh:
+		return ready, nil
+	case err := <-errCh:
+		return Ready{}, err
+	case <-timer.C:
+		return Ready{}, fmt.Errorf("tunnel did not become ready within %s", timeout)
+	}
+}
+
+// WriteReady reports from inside the daemon that it listens on port.
+func WriteReady(w io.Writer, port int) error {
+	return json.NewEncoder(w).Encode(Ready{Status: "ready", Port: port, PID: os.Getpid()})
+}
+
+// WriteError reports from inside the daemon that it failed to start.
+func WriteError(w io.Writer, message string) {
+	_ = json.NewEncoder(w).Encode(Ready{Status: "error", Message: message})
+}
+
+// LogPath returns the path of the log file named logName under ~/.agr.
+func LogPath(logName string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".agr", logName+".log"), nil
+}
+
+// OpenLog truncates and opens the daemon log file. It returns an empty path
+// and nil file when the log cannot be created; the daemon then runs without
+// a log.
+func OpenLog(logName string) (string, *os.File) {
+	logPath, err := LogPath(logName)
+	if err != nil {
+		return "", nil
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0700); err != nil {
+		return "", nil
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", nil
+	}
+	return logPath, logFile
+}
+
+// Env returns the daemon environment: the current environment plus the
+// credentials given on the command line or in the config file.
+func Env() []string {
+	env := os.Environ()
+	cfg := config.Get()
+	if cli.SecretIDFlag() != "" {
+		env = append(env, "TENCENTCLOUD_SECRET_ID="+cli.SecretIDFlag())
+	} else if cfg.Auth.SecretID != "" {
+		env = append(env, "TENCENTCLOUD_SECRET_ID="+cfg.Auth.SecretID)
+	}
+	if cli.SecretKeyFlag() != "" {
+		env = append(env, "TENCENTCLOUD_SECRET_KEY="+cli.SecretKeyFlag())
+	} else if cfg.Auth.SecretKey != "" {
+		env = append(env, "TENCENTCLOUD_SECRET_KEY="+cfg.Auth.SecretKey)
+	}
+	return env
+}
+
+func closeIfOpen(file *os.File) error {
+	if file == nil {
+		return nil
+	}
+	return file.Close()
+}
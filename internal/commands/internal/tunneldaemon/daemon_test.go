@@ -0,0 +1,59 @@
+package tunneldaemon
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadReadyRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReady(&buf, 3000); err != nil {
+		t.Fatalf("WriteReady: %v", err)
+	}
+	ready, err := ReadReady(&buf, time.Second)
+	if err != nil {
+		t.Fatalf("ReadReady: %v", err)
+	}
+	if ready.Status != "ready" || ready.Port != 3000 || ready.PID == 0 {
+		t.Fatalf("ready = %#v", ready)
+	}
+}
+
+func TestReadReadyReportsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	WriteError(&buf, "boom")
+	ready, err := ReadReady(&buf, time.Second)
+	if err != nil || ready.Status != "error" || ready.Message != "boom" {
+		t.Fatalf("ready=%#v err=%v", ready, err)
+	}
+
+	if _, err := ReadReady(strings.NewReader(""), time.Second); err == nil || !strings.Contains(err.Error(), "without ready message") {
+		t.Fatalf("err = %v, want exit without ready message", err)
+	}
+	if _, err := ReadReady(strings.NewReader("not json\n"), time.Second); err == nil || !strings.Contains(err.Error(), "parse") {
+		t.Fatalf("err = %v, want parse error", err)
+	}
+
+	r, w := io.Pipe()
+	defer w.Close()
+	if _, err := ReadReady(r, 10*time.Millisecond); err == nil || !strings.Contains(err.Error(), "did not become ready") {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+}
+
+func TestOpenLogUsesAgrDirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	path, file := OpenLog("proxy-ins-1-3000")
+	if file == nil {
+		t.Fatal("OpenLog returned nil file")
+	}
+	defer file.Close()
+	if !strings.HasSuffix(path, "proxy-ins-1-3000.log") || !strings.HasPrefix(path, home) {
+		t.Fatalf("path = %q", path)
+	}
+}
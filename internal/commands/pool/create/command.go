@@ -0,0 +1,140 @@
+package create
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmpool "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Module returns the "pool create" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "pool.create",
+		Path:  []string{"pool", "create"},
+		Use:   "create --tool-id <tool-id> --size <n>",
+		Short: "Create or resize a warm pool and fill it with running instances",
+		Long: `Create a locally tracked warm pool of pre-started instances for one tool,
+or resize an existing pool, then start instances until the pool is full.
+
+Pool instances are tagged with the agr-pool metadata. Set --timeout so pooled
+instances outlive the time they wait to be leased.`,
+		Examples: []string{
+			"agr pool create --tool-id sdt-xxxx --size 3",
+			"agr pool create --name py --tool-id sdt-xxxx --size 5 --timeout 2h",
+		},
+		Flags: []command.FlagSpec{
+			{Name: "name", Usage: "Pool name (default: the tool id)", Type: command.FlagString},
+			{Name: "tool-id", Usage: "Tool ID of the pooled instances (required)", Type: command.FlagString, Required: true},
+			{Name: "size", Usage: "Number of ready instances to keep (required)", Type: command.FlagInt, Required: true},
+			{Name: "timeout", Usage: "Lifetime of each pooled instance (for example 30m, 2h)", Type: command.FlagString},
+		},
+		SupportsJSON: true,
+		Output: command.OutputSpec{
+			DataType:    "Pool",
+			Description: "Pool state after filling.",
+			Effects:     []string{"create:instance"},
+		},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{pool.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			cp, err := pool.RequireControlPlane(spec.ID, deps)
+			if err != nil {
+				return command.Runtime{}, err
+			}
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runCreate(ctx, req, deps, cp)
+			})}, nil
+		},
+	}
+}
+
+func runCreate(ctx context.Context, req command.Request, deps command.Deps, cp pool.ControlPlane) (*command.Result, error) {
+	toolID := req.Flags["tool-id"].String
+	if toolID == "" {
+		return nil, output.NewUsageError("MISSING_REQUIRED_FLAG", "--tool-id is required", "Provide --tool-id sdt-xxxx.")
+	}
+	size := req.Flags["size"].Int
+	if size < 1 {
+		return nil, output.NewUsageError("INVALID_POOL_SIZE", fmt.Sprintf("invalid --size %d", size), "Use a --size of at least 1. To remove a pool, use: agr pool drain <name>.")
+	}
+	name := req.Flags["name"].String
+	if name == "" {
+		name = toolID
+	}
+	if err := pool.ValidateName(name); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	store, err := cli.NewPoolStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize pool store: %w", err)
+	}
+	_, err = store.Upsert(warmpool.Pool{
+		Name:      name,
+		ToolID:    toolID,
+		Size:      size,
+		Timeout:   req.Flags["timeout"].String,
+		CreatedAt: deps.Now(),
+	})
+	if err != nil {
+		var mismatch *warmpool.ToolMismatchError
+		if errors.As(err, &mismatch) {
+			return nil, output.NewConflictError("POOL_CONFLICT", mismatch.Error(), "Choose another --name, or drain the existing pool first.")
+		}
+		return nil, err
+	}
+
+	fill, err := warmpool.Fill(ctx, store, name, pool.StartFunc(deps, cp), cp.DeleteInstance, deps.Now)
+	if err != nil {
+		return nil, err
+	}
+	p, _, err := store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data := pool.Data(p)
+	data["Started"] = append([]string{}, fill.Added...)
+	result := &command.Result{
+		Data: data,
+		Text: func(w io.Writer) {
+			fmt.Fprintf(w, "Pool %s: %d/%d ready (tool %s)\n", p.Name, len(p.Ready), p.Size, p.ToolID)
+			for _, id := range fill.Added {
+				fmt.Fprintf(w, "Instance started: %s\n", id)
+			}
+		},
+	}
+	for _, id := range fill.Added {
+		result.Effects = append(result.Effects, output.Effect{Kind: "create", Resource: "instance", Id: id})
+	}
+	for _, fillErr := range fill.Errors {
+		result.Warnings = append(result.Warnings, fillErr.Error())
+	}
+	if len(fill.Errors) > 0 {
+		result.Failure = &output.Failure{
+			Code:    "PARTIAL_POOL_FILL",
+			Kind:    output.KindPartialSuccess,
+			Message: fmt.Sprintf("pool %s has %d of %d ready instances", p.Name, len(p.Ready), p.Size),
+			Hint:    "Inspect the warnings, then rerun the same agr pool create command to fill the remaining slots.",
+		}
+		result.ExitCode = output.ExitPartialSuccess
+	}
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package drain
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	instancedelete "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/delete"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Module returns the "pool drain" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "pool.drain",
+		Path:  []string{"pool", "drain"},
+		Use:   "drain <name>",
+		Short: "Remove a warm pool and delete its ready instances",
+		Long: `Remove a warm pool and delete the instances that are still waiting to be
+leased. Instances already leased by an execution are left to that execution's
+cleanup policy. Instances that have already expired are reported as
+AlreadyAbsent.`,
+		Examples:     []string{"agr pool drain py"},
+		Args:         []command.ArgSpec{{Name: "name", Required: true, Description: "Pool name."}},
+		SupportsJSON: true,
+		Output: command.OutputSpec{
+			DataType:    "DeleteData",
+			Description: "Delete summary for the pool's ready instances.",
+			Effects:     []string{"delete:instance"},
+		},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{pool.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			cp, err := pool.RequireControlPlane(spec.ID, deps)
+			if err != nil {
+				return command.Runtime{}, err
+			}
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runDrain(ctx, req, deps, cp)
+			})}, nil
+		},
+	}
+}
+
+func runDrain(ctx context.Context, req command.Request, deps command.Deps, cp pool.ControlPlane) (*command.Result, error) {
+	name := req.ArgValues["name"]
+	if name == "" && len(req.Args) > 0 {
+		name = req.Args[0]
+	}
+	if err := pool.ValidateName(name); err != nil {
+		return nil, err
+	}
+	store, err := cli.NewPoolStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize pool store: %w", err)
+	}
+	ready, found, err := store.Remove(name)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cli.PoolNotFoundError(name)
+	}
+
+	summary := instancedelete.Summary{}
+	var warnings []string
+	for _, inst := range ready {
+		err := cp.DeleteInstance(ctx, inst.InstanceID)
+		switch {
+		case err == nil:
+			summary.Deleted++
+			summary.DeletedIDs = append(summary.DeletedIDs, inst.InstanceID)
+		case isNotFound(deps.ControlPlane, err):
+			summary.AlreadyAbsent = append(summary.AlreadyAbsent, inst.InstanceID)
+		default:
+			summary.Failed++
+			summary.FailedIDs = append(summary.FailedIDs, inst.InstanceID)
+			warnings = append(warnings, fmt.Sprintf("Failed to delete %s: %v", inst.InstanceID, err))
+		}
+	}
+
+	data := summary.Data()
+	data["Pool"] = name
+	result := &command.Result{
+		Data:     data,
+		Warnings: warnings,
+		Text: func(w io.Writer) {
+			for _, id := range summary.DeletedIDs {
+				fmt.Fprintf(w, "Instance deleted: %s\n", id)
+			}
+			for _, id := range summary.AlreadyAbsent {
+				fmt.Fprintf(deps.IO.ErrOut, "Instance %s not found (ignored)\n", id)
+			}
+			if summary.Failed > 0 {
+				fmt.Fprintf(deps.IO.ErrOut, "failed to delete %d instance(s)\n", summary.Failed)
+			}
+			fmt.Fprintf(w, "Pool drained: %s\n", name)
+		},
+	}
+	for _, id := range summary.DeletedIDs {
+		result.Effects = append(result.Effects, output.Effect{Kind: "delete", Resource: "instance", Id: id})
+	}
+	if summary.Failed > 0 {
+		result.Failure = &output.Failure{
+			Code:    "PARTIAL_DELETE_FAILED",
+			Kind:    output.KindPartialSuccess,
+			Message: "failed to delete one or more pooled instances",
+			Hint:    "Inspect Data.FailedIds and delete them with: agr instance delete <instance-id>",
+		}
+		result.ExitCode = output.ExitPartialSuccess
+	}
+	return result, nil
+}
+
+func isNotFound(classifier any, err error) bool {
+	c, ok := classifier.(instancedelete.NotFoundClassifier)
+	return ok && c.IsNotFound(err)
+}
@@ -0,0 +1,106 @@
+// Package pool implements the top-level "agr pool" command group.
+//
+// A warm pool keeps pre-started instances of one tool ready so that
+// `--create-temp-instance --pool <name>` can lease an instance instead of
+// waiting for StartSandboxInstance. Pool state is local to this machine and
+// lives in ~/.agr/pools.json; pool-owned instances carry the agr-pool
+// metadata tag.
+package pool
+
+import (
+	"context"
+	"fmt"
+
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmpool "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/internal/resourcewait"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// ControlPlane is the minimal instance dependency required by pool commands.
+type ControlPlane interface {
+	GetInstance(ctx context.Context, instanceID string) (*ags.SandboxInstance, error)
+	DeleteInstance(ctx context.Context, instanceID string) error
+}
+
+// Group returns the shared "pool" command group.
+func Group() command.GroupSpec {
+	return command.GroupSpec{
+		Path:  []string{"pool"},
+		Use:   "pool",
+		Short: "Manage warm pools of pre-started instances",
+		Long: `Manage locally tracked warm pools of pre-started sandbox instances.
+
+Lease a pooled instance for a temporary execution with --pool:
+  agr pool create --name py --tool-id sdt-xxxx --size 3
+  agr instance exec --create-temp-instance --pool py -- python -V
+  agr pool drain py`,
+	}
+}
+
+// RequireControlPlane asserts the pool control-plane dependency.
+func RequireControlPlane(commandID string, deps command.Deps) (ControlPlane, error) {
+	cp, ok := deps.ControlPlane.(ControlPlane)
+	if !ok {
+		return nil, fmt.Errorf("%s requires command.Deps.ControlPlane implementing pool.ControlPlane", commandID)
+	}
+	return cp, nil
+}
+
+// StartFunc starts a pool instance and waits until it is running, so only
+// usable instances are ever added to the pool. Instances that fail to start
+// are deleted on a best-effort basis.
+func StartFunc(deps command.Deps, cp ControlPlane) warmpool.StartFunc {
+	options := resourcewait.OptionsFromDeps(deps)
+	return func(ctx context.Context, p warmpool.Pool) (string, error) {
+		id, err := cli.PoolStartInstance(ctx, p)
+		if err != nil {
+			return "", err
+		}
+		_, err = resourcewait.WaitForInstanceWithPolicy(ctx, id, cp.GetInstance,
+			resourcewait.InstancePolicy(resourcewait.OperationCreate), options)
+		if err != nil {
+			_ = cp.DeleteInstance(ctx, id)
+			return "", err
+		}
+		return id, nil
+	}
+}
+
+// ValidateName rejects pool names that cannot be used as local file names.
+func ValidateName(name string) error {
+	if name == "" {
+		return output.NewUsageError("MISSING_REQUIRED_ARG", "missing pool name", "Provide a pool name, for example: agr pool status py.")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return output.NewUsageError("INVALID_POOL_NAME",
+				fmt.Sprintf("invalid pool name %q", name),
+				"Use letters, digits, '.', '-' or '_' only.")
+		}
+	}
+	return nil
+}
+
+// Data converts a pool into the canonical JSON shape shared by pool commands.
+func Data(p warmpool.Pool) map[string]any {
+	ids := make([]string, 0, len(p.Ready))
+	for _, inst := range p.Ready {
+		ids = append(ids, inst.InstanceID)
+	}
+	data := map[string]any{
+		"Name":        p.Name,
+		"ToolId":      p.ToolID,
+		"Size":        p.Size,
+		"Ready":       len(p.Ready),
+		"InstanceIds": ids,
+		"CreatedAt":   p.CreatedAt,
+	}
+	if p.Timeout != "" {
+		data["Timeout"] = p.Timeout
+	}
+	return data
+}
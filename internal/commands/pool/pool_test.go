@@ -0,0 +1,189 @@
+package pool_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	ags "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/ags/v20250920"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmpool "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	poolcreate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool/create"
+	pooldrain "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool/drain"
+	poolstatus "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool/status"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// fakeCP implements pool.ControlPlane for testing.
+type fakeCP struct {
+	deleted   []string
+	deleteErr map[string]error
+}
+
+func (f *fakeCP) GetInstance(_ context.Context, instanceID string) (*ags.SandboxInstance, error) {
+	status := "RUNNING"
+	return &ags.SandboxInstance{InstanceId: &instanceID, Status: &status}, nil
+}
+
+func (f *fakeCP) DeleteInstance(_ context.Context, instanceID string) error {
+	if err := f.deleteErr[instanceID]; err != nil {
+		return err
+	}
+	f.deleted = append(f.deleted, instanceID)
+	return nil
+}
+
+func setupPool(t *testing.T) *warmpool.Store {
+	t.Helper()
+	cli.SetIOStreams(&iostreams.IOStreams{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}})
+	if err := config.Init(); err != nil {
+		t.Fatalf("config.Init: %v", err)
+	}
+	config.SetSecretID("AKIDfake")
+	config.SetSecretKey("fakeSecretKey")
+	config.SetRegion("ap-guangzhou")
+	store := warmpool.NewStoreAt(filepath.Join(t.TempDir(), "pools.json"))
+	t.Cleanup(cli.SetPoolHooksForTest(store,
+		func(context.Context, string) (bool, error) { return true, nil },
+		func(string) error { return nil }))
+	return store
+}
+
+func run(t *testing.T, mod command.Module, cp *fakeCP, req command.Request) (*command.Result, error) {
+	t.Helper()
+	ios, _, _, _ := iostreams.Test()
+	rt, err := mod.Build(command.Deps{IO: ios, ControlPlane: cp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rt.Handler.Run(context.Background(), req)
+}
+
+func TestPoolCreateFillsPoolWithTaggedInstances(t *testing.T) {
+	store := setupPool(t)
+	started := 0
+	defer cli.SetCloudStartSandboxInstanceForTest(func(_ context.Context, _ *ags.Client, req *ags.StartSandboxInstanceRequest) (*ags.StartSandboxInstanceResponseParams, error) {
+		started++
+		if len(req.Metadata) != 1 || *req.Metadata[0].Name != warmpool.MetadataKey || *req.Metadata[0].Value != "py" {
+			t.Fatalf("metadata = %+v", req.Metadata)
+		}
+		if req.Timeout == nil || *req.Timeout != "2h" {
+			t.Fatalf("timeout = %v", req.Timeout)
+		}
+		id := fmt.Sprintf("ins-pool-%d", started)
+		status := "RUNNING"
+		return &ags.StartSandboxInstanceResponseParams{Instance: &ags.SandboxInstance{InstanceId: &id, Status: &status}}, nil
+	})()
+
+	result, err := run(t, poolcreate.Module(), &fakeCP{}, command.Request{Flags: map[string]command.FlagValue{
+		"name":    {String: "py", Changed: true},
+		"tool-id": {String: "sdt-1", Changed: true},
+		"size":    {Int: 2, Changed: true},
+		"timeout": {String: "2h", Changed: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := result.Data.(map[string]any)
+	if data["Ready"] != 2 || len(result.Effects) != 2 || result.Failure != nil {
+		t.Fatalf("data=%v effects=%v failure=%v", data, result.Effects, result.Failure)
+	}
+	p, ok, _ := store.Get("py")
+	if !ok || len(p.Ready) != 2 || p.ToolID != "sdt-1" {
+		t.Fatalf("stored pool = %+v", p)
+	}
+
+	_, err = run(t, poolcreate.Module(), &fakeCP{}, command.Request{Flags: map[string]command.FlagValue{
+		"name":    {String: "py", Changed: true},
+		"tool-id": {String: "sdt-other", Changed: true},
+		"size":    {Int: 1, Changed: true},
+	}})
+	assertCode(t, err, "POOL_CONFLICT")
+}
+
+func TestPoolCreateReportsPartialFill(t *testing.T) {
+	setupPool(t)
+	defer cli.SetCloudStartSandboxInstanceForTest(func(context.Context, *ags.Client, *ags.StartSandboxInstanceRequest) (*ags.StartSandboxInstanceResponseParams, error) {
+		return nil, errors.New("quota exceeded")
+	})()
+	result, err := run(t, poolcreate.Module(), &fakeCP{}, command.Request{Flags: map[string]command.FlagValue{
+		"tool-id": {String: "sdt-1", Changed: true},
+		"size":    {Int: 1, Changed: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failure == nil || result.Failure.Code != "PARTIAL_POOL_FILL" || result.ExitCode != output.ExitPartialSuccess {
+		t.Fatalf("failure=%+v exit=%d", result.Failure, result.ExitCode)
+	}
+	if data := result.Data.(map[string]any); data["Name"] != "sdt-1" {
+		t.Fatalf("default name = %v", data["Name"])
+	}
+}
+
+func TestPoolCreateValidatesFlags(t *testing.T) {
+	setupPool(t)
+	_, err := run(t, poolcreate.Module(), &fakeCP{}, command.Request{Flags: map[string]command.FlagValue{
+		"tool-id": {String: "sdt-1", Changed: true},
+		"size":    {Int: 0, Changed: true},
+	}})
+	assertCode(t, err, "INVALID_POOL_SIZE")
+	_, err = run(t, poolcreate.Module(), &fakeCP{}, command.Request{Flags: map[string]command.FlagValue{
+		"name":    {String: "../py", Changed: true},
+		"tool-id": {String: "sdt-1", Changed: true},
+		"size":    {Int: 1, Changed: true},
+	}})
+	assertCode(t, err, "INVALID_POOL_NAME")
+}
+
+func TestPoolStatusAndDrain(t *testing.T) {
+	store := setupPool(t)
+	if _, err := store.Upsert(warmpool.Pool{Name: "py", ToolID: "sdt-1", Size: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"ins-1", "ins-2", "ins-3"} {
+		if _, err := store.Add("py", warmpool.Instance{InstanceID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := run(t, poolstatus.Module(), &fakeCP{}, command.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := result.Data.(map[string]any); data["Total"] != 1 {
+		t.Fatalf("status data = %v", data)
+	}
+	_, err = run(t, poolstatus.Module(), &fakeCP{}, command.Request{Args: []string{"missing"}})
+	assertCode(t, err, "POOL_NOT_FOUND")
+
+	cp := &fakeCP{deleteErr: map[string]error{"ins-3": errors.New("boom")}}
+	result, err = run(t, pooldrain.Module(), cp, command.Request{Args: []string{"py"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := result.Data.(map[string]any)
+	if data["Deleted"] != 2 || data["Failed"] != 1 || result.ExitCode != output.ExitPartialSuccess {
+		t.Fatalf("drain data=%v exit=%d", data, result.ExitCode)
+	}
+	if _, ok, _ := store.Get("py"); ok {
+		t.Fatal("pool should be removed after drain")
+	}
+	_, err = run(t, pooldrain.Module(), cp, command.Request{Args: []string{"py"}})
+	assertCode(t, err, "POOL_NOT_FOUND")
+}
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != code {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+}
@@ -0,0 +1,106 @@
+package replenish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmpool "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/config"
+)
+
+// Module returns the hidden "pool replenish" command module. It is spawned in
+// the background after an instance is leased from a pool.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:     "pool.replenish",
+		Path:   []string{"pool", "replenish"},
+		Use:    "replenish <name>",
+		Short:  "Refill a warm pool (used internally after a lease)",
+		Hidden: true,
+		Args:   []command.ArgSpec{{Name: "name", Required: true}},
+		Output: command.OutputSpec{DataType: "PoolReplenish"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{pool.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			cp, err := pool.RequireControlPlane(spec.ID, deps)
+			if err != nil {
+				return command.Runtime{}, err
+			}
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runReplenish(ctx, req, deps, cp)
+			})}, nil
+		},
+	}
+}
+
+func runReplenish(ctx context.Context, req command.Request, deps command.Deps, cp pool.ControlPlane) (*command.Result, error) {
+	name := req.ArgValues["name"]
+	if name == "" && len(req.Args) > 0 {
+		name = req.Args[0]
+	}
+	if err := pool.ValidateName(name); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	store, err := cli.NewPoolStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize pool store: %w", err)
+	}
+	unlock, ok, err := store.TryLockReplenish(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire replenish lock: %w", err)
+	}
+	defer unlock()
+	if !ok {
+		return &command.Result{
+			Data: map[string]any{"Pool": name, "Skipped": true},
+			Text: func(w io.Writer) {
+				fmt.Fprintf(w, "%s pool %s: another replenisher is running\n", deps.Now().Format(time.RFC3339), name)
+			},
+		}, nil
+	}
+	if _, found, err := store.Get(name); err != nil {
+		return nil, err
+	} else if !found {
+		// The pool was drained after the lease; nothing to refill.
+		return &command.Result{Data: map[string]any{"Pool": name, "Skipped": true}}, nil
+	}
+
+	fill, err := warmpool.Fill(ctx, store, name, pool.StartFunc(deps, cp), cp.DeleteInstance, deps.Now)
+	if err != nil {
+		return nil, err
+	}
+	var warnings []string
+	for _, fillErr := range fill.Errors {
+		warnings = append(warnings, fillErr.Error())
+	}
+	return &command.Result{
+		Data:     map[string]any{"Pool": name, "Added": fill.Added, "Surplus": fill.Surplus},
+		Warnings: warnings,
+		Text: func(w io.Writer) {
+			stamp := deps.Now().Format(time.RFC3339)
+			for _, id := range fill.Added {
+				fmt.Fprintf(w, "%s pool %s: added %s\n", stamp, name, id)
+			}
+			for _, id := range fill.Surplus {
+				fmt.Fprintf(w, "%s pool %s: deleted surplus %s\n", stamp, name, id)
+			}
+			for _, warning := range warnings {
+				fmt.Fprintf(w, "%s pool %s: %s\n", stamp, name, warning)
+			}
+		},
+	}, nil
+}
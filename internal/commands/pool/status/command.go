@@ -0,0 +1,97 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmpool "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/pool"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool"
+)
+
+// Module returns the "pool status" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "pool.status",
+		Path:  []string{"pool", "status"},
+		Use:   "status [name]",
+		Short: "Show warm pools and their ready instances",
+		Examples: []string{
+			"agr pool status",
+			"agr pool status py -o json",
+		},
+		Args:         []command.ArgSpec{{Name: "name", Description: "Pool name. Omit to list all pools."}},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "PoolList"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{pool.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runStatus(req, deps)
+			})}, nil
+		},
+	}
+}
+
+func runStatus(req command.Request, deps command.Deps) (*command.Result, error) {
+	name := req.ArgValues["name"]
+	if name == "" && len(req.Args) > 0 {
+		name = req.Args[0]
+	}
+	store, err := cli.NewPoolStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize pool store: %w", err)
+	}
+	all, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pools: %w", err)
+	}
+
+	var pools []warmpool.Pool
+	if name != "" {
+		p, ok := all[name]
+		if !ok {
+			return nil, cli.PoolNotFoundError(name)
+		}
+		pools = append(pools, p)
+	} else {
+		for _, p := range all {
+			pools = append(pools, p)
+		}
+		sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	}
+
+	items := make([]map[string]any, 0, len(pools))
+	for _, p := range pools {
+		items = append(items, pool.Data(p))
+	}
+	data := map[string]any{"Items": items, "Total": len(items)}
+	return &command.Result{Data: data, Text: func(w io.Writer) {
+		if len(pools) == 0 {
+			fmt.Fprintln(deps.IO.ErrOut, "No warm pools.")
+			fmt.Fprintln(deps.IO.ErrOut, "Use 'agr pool create --tool-id <tool-id> --size <n>' to create one.")
+			return
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join([]string{"NAME", "TOOL", "READY", "TIMEOUT"}, "\t"))
+		for _, p := range pools {
+			timeout := p.Timeout
+			if timeout == "" {
+				timeout = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%s\n", p.Name, p.ToolID, len(p.Ready), p.Size, timeout)
+		}
+		_ = tw.Flush()
+	}}, nil
+}
@@ -19,12 +19,19 @@ import (
 	credentialsecretget "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/credential/secret/get"
 	credentialsecretlist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/credential/secret/list"
 	credentialsecretset "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/credential/secret/set"
+	gateway "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/gateway"
 	identitycreate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/identity/create"
 	identitydelete "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/identity/delete"
 	identityget "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/identity/get"
 	identitylist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/identity/list"
 	identitytokencreate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/identity/token/create"
 	identityupdate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/identity/update"
+	instancebrowsercdp "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/cdp"
+	instancebrowsereval "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/eval"
+	instancebrowsernavigate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/navigate"
+	instancebrowserpdf "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/pdf"
+	instancebrowserrecord "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/record"
+	instancebrowserscreenshot "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/screenshot"
 	instancebrowservnc "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/browser/vnc"
 	instancecoderun "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/code/run"
 	instancecreate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/create"
@@ -33,26 +40,50 @@ import (
 	instanceexec "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/exec"
 	instancefiledownload "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/file/download"
 	instancefileupload "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/file/upload"
+	instanceforward "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/forward"
 	instanceget "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/get"
 	instancelist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/list"
 	instancelogin "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/login"
 	instancemobileadb "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/adb"
 	instancemobileconnect "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/connect"
 	instancemobiledisconnect "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/disconnect"
+	instancemobileinfo "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/info"
+	instancemobileinstall "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/install"
+	instancemobilekeyevent "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/keyevent"
 	instancemobilelist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/list"
+	instancemobilelogcat "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/logcat"
+	instancemobilemirror "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/mirror"
+	instancemobilepull "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/pull"
+	instancemobilepush "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/push"
+	instancemobilerecord "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/record"
+	instancemobilescreenshot "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/screenshot"
+	instancemobilesupervise "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/supervise"
+	instancemobileswipe "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/swipe"
+	instancemobiletap "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/tap"
+	instancemobiletext "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/text"
 	instancemobiletunnel "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/mobile/tunnel"
 	instancepause "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/pause"
 	instanceproxy "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/proxy"
+	instanceprune "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/prune"
 	instanceresume "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/resume"
 	instanceupdate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/update"
+	poolcreate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool/create"
+	pooldrain "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool/drain"
+	poolreplenish "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool/replenish"
+	poolstatus "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/pool/status"
 	precacheimagetaskcreate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/precacheimagetask/create"
 	precacheimagetaskget "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/precacheimagetask/get"
+	sessionend "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/session/end"
+	sessionlist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/session/list"
 	toolcreate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tool/create"
 	tooldelete "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tool/delete"
 	toolfork "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tool/fork"
 	toolget "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tool/get"
 	toollist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tool/list"
 	toolupdate "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tool/update"
+	tunnellist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel/list"
+	tunnellogs "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel/logs"
+	tunnelstop "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel/stop"
 )
 
 func Registry() (*command.Registry, error) {
@@ -73,12 +104,19 @@ func Registry() (*command.Registry, error) {
 		credentialsecretget.Module(),
 		credentialsecretlist.Module(),
 		credentialsecretset.Module(),
+		gateway.Module(),
 		identitycreate.Module(),
 		identitydelete.Module(),
 		identityget.Module(),
 		identitylist.Module(),
 		identitytokencreate.Module(),
 		identityupdate.Module(),
+		instancebrowsercdp.Module(),
+		instancebrowsereval.Module(),
+		instancebrowsernavigate.Module(),
+		instancebrowserpdf.Module(),
+		instancebrowserrecord.Module(),
+		instancebrowserscreenshot.Module(),
 		instancebrowservnc.Module(),
 		instancecoderun.Module(),
 		instancecreate.Module(),
@@ -87,26 +125,50 @@ func Registry() (*command.Registry, error) {
 		instanceexec.Module(),
 		instancefiledownload.Module(),
 		instancefileupload.Module(),
+		instanceforward.Module(),
 		instanceget.Module(),
 		instancelist.Module(),
 		instancelogin.Module(),
 		instancemobileadb.Module(),
 		instancemobileconnect.Module(),
 		instancemobiledisconnect.Module(),
+		instancemobileinfo.Module(),
+		instancemobileinstall.Module(),
+		instancemobilekeyevent.Module(),
 		instancemobilelist.Module(),
+		instancemobilelogcat.Module(),
+		instancemobilemirror.Module(),
+		instancemobilepull.Module(),
+		instancemobilepush.Module(),
+		instancemobilerecord.Module(),
+		instancemobilescreenshot.Module(),
+		instancemobilesupervise.Module(),
+		instancemobileswipe.Module(),
+		instancemobiletap.Module(),
+		instancemobiletext.Module(),
 		instancemobiletunnel.Module(),
 		instancepause.Module(),
 		instanceproxy.Module(),
+		instanceprune.Module(),
 		instanceresume.Module(),
 		instanceupdate.Module(),
+		poolcreate.Module(),
+		pooldrain.Module(),
+		poolreplenish.Module(),
+		poolstatus.Module(),
 		precacheimagetaskcreate.Module(),
 		precacheimagetaskget.Module(),
+		sessionend.Module(),
+		sessionlist.Module(),
 		toolcreate.Module(),
 		tooldelete.Module(),
 		toolfork.Module(),
 		toolget.Module(),
 		toollist.Module(),
 		toolupdate.Module(),
+		tunnellist.Module(),
+		tunnellogs.Module(),
+		tunnelstop.Module(),
 	} {
 		if err := registry.Register(module); err != nil {
 			return nil, err
@@ -27,6 +27,7 @@ func TestRegistryIncludesAllKnownCommandModules(t *testing.T) {
 		"credential.secret.get",
 		"credential.secret.list",
 		"credential.secret.set",
+		"gateway",
 		"identity.create",
 		"identity.delete",
 		"identity.get",
@@ -35,6 +36,12 @@ func TestRegistryIncludesAllKnownCommandModules(t *testing.T) {
 		"identity.update",
 		"pre-cache-image-task.create",
 		"pre-cache-image-task.get",
+		"instance.browser.cdp",
+		"instance.browser.eval",
+		"instance.browser.navigate",
+		"instance.browser.pdf",
+		"instance.browser.record",
+		"instance.browser.screenshot",
 		"instance.browser.vnc",
 		"instance.code.run",
 		"instance.create",
@@ -43,24 +50,48 @@ func TestRegistryIncludesAllKnownCommandModules(t *testing.T) {
 		"instance.exec",
 		"instance.file.download",
 		"instance.file.upload",
+		"instance.forward",
 		"instance.get",
 		"instance.list",
 		"instance.login",
 		"instance.mobile.adb",
 		"instance.mobile.connect",
 		"instance.mobile.disconnect",
+		"instance.mobile.info",
+		"instance.mobile.install",
+		"instance.mobile.keyevent",
 		"instance.mobile.list",
+		"instance.mobile.logcat",
+		"instance.mobile.mirror",
+		"instance.mobile.pull",
+		"instance.mobile.push",
+		"instance.mobile.record",
+		"instance.mobile.screenshot",
+		"instance.mobile.supervise",
+		"instance.mobile.swipe",
+		"instance.mobile.tap",
+		"instance.mobile.text",
 		"instance.mobile.tunnel",
 		"instance.pause",
 		"instance.proxy",
+		"instance.prune",
 		"instance.resume",
 		"instance.update",
+		"pool.create",
+		"pool.drain",
+		"pool.replenish",
+		"pool.status",
+		"session.end",
+		"session.list",
 		"tool.create",
 		"tool.delete",
 		"tool.fork",
 		"tool.get",
 		"tool.list",
 		"tool.update",
+		"tunnel.list",
+		"tunnel.logs",
+		"tunnel.stop",
 	}
 	for _, id := range want {
 		if _, ok := registry.Lookup(id); !ok {
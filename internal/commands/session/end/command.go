@@ -0,0 +1,138 @@
+package end
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmsession "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/session"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	instancedelete "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/delete"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/session"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Module returns the "session end" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "session.end",
+		Path:  []string{"session", "end"},
+		Use:   "end <name>",
+		Short: "End a session and apply its cleanup policy",
+		Long: `End a session and apply its cleanup policy to the session's temporary
+instance once. The policy is the --cleanup value of the invocation that started
+the session; --cleanup here overrides it. With --cleanup success, the instance
+is kept when any execution in the session failed.
+
+Other sessions idle for longer than their TTL are ended as well and reported
+as warnings.`,
+		Examples: []string{
+			"agr session end build",
+			"agr session end build --cleanup never",
+		},
+		Args:         []command.ArgSpec{{Name: "name", Required: true, Description: "Session name."}},
+		Flags:        []command.FlagSpec{{Name: "cleanup", Usage: "Override the session's cleanup policy: always|success|never", Type: command.FlagString}},
+		SupportsJSON: true,
+		Output: command.OutputSpec{
+			DataType:    "SessionEnd",
+			Description: "Ended session and its cleanup result.",
+			Effects:     []string{"delete:instance"},
+		},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{session.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			cp, err := session.RequireControlPlane(spec.ID, deps)
+			if err != nil {
+				return command.Runtime{}, err
+			}
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runEnd(ctx, req, deps, cp)
+			})}, nil
+		},
+	}
+}
+
+func runEnd(ctx context.Context, req command.Request, deps command.Deps, cp session.ControlPlane) (*command.Result, error) {
+	name := req.ArgValues["name"]
+	if name == "" && len(req.Args) > 0 {
+		name = req.Args[0]
+	}
+	if err := session.ValidateName(name); err != nil {
+		return nil, err
+	}
+	store, err := cli.NewSessionStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+	sess, ok, err := store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, cli.SessionNotFoundError(name)
+	}
+	policyValue := sess.Cleanup
+	if flag, ok := req.Flags["cleanup"]; ok && flag.String != "" {
+		policyValue = flag.String
+	}
+	policy, err := cli.ResolveCleanupPolicy(policyValue)
+	if err != nil {
+		return nil, err
+	}
+
+	alreadyAbsent := false
+	cleanup := warmsession.End(ctx, sess, policy, func(ctx context.Context, instanceID string) error {
+		err := cp.DeleteInstance(ctx, instanceID)
+		if err != nil && isNotFound(deps.ControlPlane, err) {
+			alreadyAbsent = true
+			return nil
+		}
+		return err
+	})
+	if cleanup.Status == "failed" {
+		// Keep the session so the user can retry the same command.
+		return nil, fmt.Errorf("failed to delete session instance %s: %s", sess.InstanceID, cleanup.Reason)
+	}
+	if alreadyAbsent {
+		cleanup.Reason = "instance already absent"
+	}
+	if _, _, err := store.RemoveInstance(name, sess.InstanceID); err != nil {
+		return nil, err
+	}
+	warnings, err := session.SweepExpired(ctx, store, deps, cp)
+	if err != nil {
+		return nil, err
+	}
+
+	data := session.Data(sess, deps.Now())
+	data["Cleanup"] = cleanup
+	result := &command.Result{
+		Data:     data,
+		Warnings: warnings,
+		Text: func(w io.Writer) {
+			fmt.Fprintf(w, "Session ended: %s (%d executions)\n", name, sess.Executions)
+			switch cleanup.Status {
+			case "deleted":
+				fmt.Fprintf(w, "Temporary sandbox %s deleted (--cleanup %s)\n", sess.InstanceID, cleanup.Policy)
+			default:
+				fmt.Fprintf(w, "Temporary sandbox %s kept (%s)\n", sess.InstanceID, cleanup.Reason)
+			}
+		},
+	}
+	if cleanup.Status == "deleted" && !alreadyAbsent {
+		result.Effects = []output.Effect{{Kind: "delete", Resource: "instance", Id: sess.InstanceID}}
+	}
+	return result, nil
+}
+
+func isNotFound(classifier any, err error) bool {
+	c, ok := classifier.(instancedelete.NotFoundClassifier)
+	return ok && c.IsNotFound(err)
+}
@@ -0,0 +1,94 @@
+package list
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmsession "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/session"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/session"
+)
+
+// Module returns the "session list" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "session.list",
+		Path:  []string{"session", "list"},
+		Use:   "list",
+		Short: "List sessions and their temporary instances",
+		Long: `List sessions and their temporary instances.
+
+Sessions idle for longer than their TTL are ended first, applying their cleanup
+policy, and reported as warnings.`,
+		Aliases:      []string{"ls"},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "SessionList", Effects: []string{"delete:instance"}},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{session.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			cp, err := session.RequireControlPlane(spec.ID, deps)
+			if err != nil {
+				return command.Runtime{}, err
+			}
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runList(ctx, deps, cp)
+			})}, nil
+		},
+	}
+}
+
+func runList(ctx context.Context, deps command.Deps, cp session.ControlPlane) (*command.Result, error) {
+	store, err := cli.NewSessionStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+	now := deps.Now()
+	warnings, err := cli.SweepExpiredSessions(ctx, store, now, cp.DeleteInstance)
+	if err != nil {
+		return nil, err
+	}
+	all, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	sessions := make([]warmsession.Session, 0, len(all))
+	for _, s := range all {
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Name < sessions[j].Name })
+
+	items := make([]map[string]any, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, session.Data(s, now))
+	}
+	data := map[string]any{"Items": items, "Total": len(items)}
+	return &command.Result{Data: data, Warnings: warnings, Text: func(w io.Writer) {
+		if len(sessions) == 0 {
+			fmt.Fprintln(deps.IO.ErrOut, "No sessions.")
+			fmt.Fprintln(deps.IO.ErrOut, "Use '--create-temp-instance --session <name>' to start one.")
+			return
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join([]string{"NAME", "INSTANCE", "CLEANUP", "EXECUTIONS", "LAST USED", "STATUS"}, "\t"))
+		for _, s := range sessions {
+			status := "active"
+			if s.Expired(now) {
+				status = "expired"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", s.Name, s.InstanceID, s.Cleanup, s.Executions, s.LastUsedAt.Format(time.RFC3339), status)
+		}
+		_ = tw.Flush()
+	}}, nil
+}
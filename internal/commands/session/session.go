@@ -0,0 +1,90 @@
+// Package session implements the top-level "agr session" command group.
+//
+// A session reuses one temporary instance across `--create-temp-instance`
+// invocations that share `--session <name>` (or AGR_SESSION). The instance
+// is kept between invocations and the cleanup policy is applied once, by
+// `agr session end`. Session state is local to this machine and lives in
+// ~/.agr/sessions.json.
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmsession "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/session"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	instancedelete "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/instance/delete"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// ControlPlane is the minimal instance dependency required by session commands.
+type ControlPlane interface {
+	DeleteInstance(ctx context.Context, instanceID string) error
+}
+
+// Group returns the shared "session" command group.
+func Group() command.GroupSpec {
+	return command.GroupSpec{
+		Path:  []string{"session"},
+		Use:   "session",
+		Short: "Manage temporary instances reused across executions",
+		Long: `Manage sessions that reuse one temporary instance across executions.
+
+Start or reuse a session with --session (or AGR_SESSION), then end it once:
+  export AGR_SESSION=build
+  agr instance exec --create-temp-instance --tool-id sdt-xxxx -- make deps
+  agr instance exec --create-temp-instance --tool-id sdt-xxxx -- make test
+  agr session end build`,
+	}
+}
+
+// ValidateName rejects a missing or malformed session name.
+func ValidateName(name string) error {
+	if name == "" {
+		return output.NewUsageError("MISSING_REQUIRED_ARG", "missing session name", "Provide a session name, for example: agr session end build.")
+	}
+	return warmsession.ValidateName(name)
+}
+
+// Data converts a session into the canonical JSON shape shared by session
+// commands.
+func Data(s warmsession.Session, now time.Time) map[string]any {
+	return map[string]any{
+		"Name":              s.Name,
+		"SandboxInstanceId": s.InstanceID,
+		"ToolName":          s.ToolName,
+		"ToolId":            s.ToolID,
+		"Pool":              s.Pool,
+		"CleanupPolicy":     s.Cleanup,
+		"IdleTTL":           s.IdleTTL,
+		"CreatedAt":         s.CreatedAt,
+		"LastUsedAt":        s.LastUsedAt,
+		"Executions":        s.Executions,
+		"Failures":          s.Failures,
+		"Expired":           s.Expired(now),
+	}
+}
+
+// SweepExpired ends the sessions in store that have been idle longer than
+// their TTL and returns one warning per ended session. An instance that is
+// already deleted counts as cleaned up.
+func SweepExpired(ctx context.Context, store *warmsession.Store, deps command.Deps, cp ControlPlane) ([]string, error) {
+	return cli.SweepExpiredSessions(ctx, store, deps.Now(), func(ctx context.Context, instanceID string) error {
+		err := cp.DeleteInstance(ctx, instanceID)
+		if c, ok := deps.ControlPlane.(instancedelete.NotFoundClassifier); ok && err != nil && c.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// RequireControlPlane asserts the session control-plane dependency.
+func RequireControlPlane(commandID string, deps command.Deps) (ControlPlane, error) {
+	cp, ok := deps.ControlPlane.(ControlPlane)
+	if !ok {
+		return nil, fmt.Errorf("%s requires command.Deps.ControlPlane implementing session.ControlPlane", commandID)
+	}
+	return cp, nil
+}
@@ -0,0 +1,145 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/cli"
+	warmsession "github.com/TencentCloudAgentRuntime/ags-cli/internal/cli/session"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	sessionend "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/session/end"
+	sessionlist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/session/list"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// fakeCP implements session.ControlPlane for testing.
+type fakeCP struct {
+	deleted []string
+	err     error
+}
+
+func (f *fakeCP) DeleteInstance(_ context.Context, instanceID string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.deleted = append(f.deleted, instanceID)
+	return nil
+}
+
+func setupSessions(t *testing.T) *warmsession.Store {
+	t.Helper()
+	store := warmsession.NewStoreAt(filepath.Join(t.TempDir(), "sessions.json"))
+	t.Cleanup(cli.SetSessionHooksForTest(store, func(context.Context, string) (bool, error) { return true, nil }))
+	return store
+}
+
+func run(t *testing.T, mod command.Module, cp *fakeCP, req command.Request) (*command.Result, error) {
+	t.Helper()
+	ios, _, _, _ := iostreams.Test()
+	rt, err := mod.Build(command.Deps{IO: ios, ControlPlane: cp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rt.Handler.Run(context.Background(), req)
+}
+
+func TestSessionEndAppliesStoredPolicy(t *testing.T) {
+	store := setupSessions(t)
+	now := time.Now()
+	for _, sess := range []warmsession.Session{
+		{Name: "ok", InstanceID: "ins-ok", Cleanup: "success", Executions: 2, LastUsedAt: now},
+		{Name: "flaky", InstanceID: "ins-flaky", Cleanup: "success", Executions: 2, Failures: 1, LastUsedAt: now},
+	} {
+		if _, _, err := store.Create(sess); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	listed, err := run(t, sessionlist.Module(), &fakeCP{}, command.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := listed.Data.(map[string]any); data["Total"] != 2 {
+		t.Fatalf("list data = %v", data)
+	}
+
+	cp := &fakeCP{}
+	result, err := run(t, sessionend.Module(), cp, command.Request{Args: []string{"ok"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := result.Data.(map[string]any)["Cleanup"].(*cli.ExecutionCleanupResult)
+	if cleanup.Status != "deleted" || len(cp.deleted) != 1 || len(result.Effects) != 1 {
+		t.Fatalf("cleanup=%+v deleted=%v effects=%v", cleanup, cp.deleted, result.Effects)
+	}
+
+	result, err = run(t, sessionend.Module(), cp, command.Request{Args: []string{"flaky"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cleanup := result.Data.(map[string]any)["Cleanup"].(*cli.ExecutionCleanupResult); cleanup.Status != "skipped" || len(cp.deleted) != 1 {
+		t.Fatalf("cleanup=%+v deleted=%v", cleanup, cp.deleted)
+	}
+
+	_, err = run(t, sessionend.Module(), cp, command.Request{Args: []string{"ok"}})
+	assertCode(t, err, "SESSION_NOT_FOUND")
+}
+
+func TestSessionListEndsExpiredSessions(t *testing.T) {
+	store := setupSessions(t)
+	now := time.Now()
+	for _, sess := range []warmsession.Session{
+		{Name: "idle", InstanceID: "ins-idle", Cleanup: "always", IdleTTL: "30m", LastUsedAt: now.Add(-time.Hour)},
+		{Name: "kept", InstanceID: "ins-kept", Cleanup: "never", IdleTTL: "30m", LastUsedAt: now.Add(-time.Hour)},
+		{Name: "busy", InstanceID: "ins-busy", Cleanup: "always", IdleTTL: "30m", LastUsedAt: now},
+	} {
+		if _, _, err := store.Create(sess); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cp := &fakeCP{}
+	result, err := run(t, sessionlist.Module(), cp, command.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := result.Data.(map[string]any); data["Total"] != 1 {
+		t.Fatalf("list data = %v", data)
+	}
+	if len(cp.deleted) != 1 || cp.deleted[0] != "ins-idle" || len(result.Warnings) != 2 {
+		t.Fatalf("deleted=%v warnings=%v", cp.deleted, result.Warnings)
+	}
+}
+
+func TestSessionEndKeepsSessionWhenDeleteFails(t *testing.T) {
+	store := setupSessions(t)
+	if _, _, err := store.Create(warmsession.Session{Name: "build", InstanceID: "ins-1", Cleanup: "success"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(t, sessionend.Module(), &fakeCP{err: errors.New("boom")}, command.Request{
+		Args:  []string{"build"},
+		Flags: map[string]command.FlagValue{"cleanup": {String: "always", Changed: true}},
+	}); err == nil {
+		t.Fatal("expected delete failure")
+	}
+	if _, ok, _ := store.Get("build"); !ok {
+		t.Fatal("session should be kept for a retry")
+	}
+	_, err := run(t, sessionend.Module(), &fakeCP{}, command.Request{
+		Args:  []string{"build"},
+		Flags: map[string]command.FlagValue{"cleanup": {String: "sometimes", Changed: true}},
+	})
+	assertCode(t, err, "INVALID_CLEANUP")
+}
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != code {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+}
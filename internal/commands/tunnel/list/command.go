@@ -0,0 +1,84 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+)
+
+// Module returns the "tunnel list" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:           "tunnel.list",
+		Path:         []string{"tunnel", "list"},
+		Use:          "list",
+		Short:        "List background proxies and ADB tunnels",
+		Aliases:      []string{"ls"},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "TunnelList"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{tunnel.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := tunnel.Runtime(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runList(deps, rt)
+			})}, nil
+		},
+	}
+}
+
+func runList(deps command.Deps, rt tunnel.RuntimeDeps) (*command.Result, error) {
+	store, err := rt.NewStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tunnel store: %w", err)
+	}
+	var warnings []string
+	entries, err := store.ListAll()
+	if err != nil {
+		var recovered *tunnelstore.CorruptStoreRecoveredError
+		if !errors.As(err, &recovered) {
+			return nil, fmt.Errorf("failed to list tunnels: %w", err)
+		}
+		warnings = append(warnings, recovered.Error())
+	}
+
+	keys := tunnel.Keys(entries)
+	items := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, tunnel.Data(key, entries[key]))
+	}
+	data := map[string]any{"Items": items, "Total": len(items)}
+	return &command.Result{Data: data, Warnings: warnings, Text: func(w io.Writer) {
+		for _, warning := range warnings {
+			fmt.Fprintf(deps.IO.ErrOut, "Warning: %s\n", warning)
+		}
+		if len(keys) == 0 {
+			fmt.Fprintln(deps.IO.ErrOut, "No active tunnels.")
+			fmt.Fprintln(deps.IO.ErrOut, "Use 'agr instance proxy <instance-id> <port> --background' or 'agr instance mobile connect <instance-id>' to start one.")
+			return
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join([]string{"ID", "TYPE", "INSTANCE", "LOCAL", "REMOTE", "PID", "STATUS"}, "\t"))
+		for _, key := range keys {
+			entry := entries[key]
+			fmt.Fprintln(tw, strings.Join([]string{
+				key, entry.Kind(), entry.Instance(key), tunnel.LocalAddress(entry), tunnel.Remote(entry), strconv.Itoa(entry.PID), tunnel.Status(entry),
+			}, "\t"))
+		}
+		_ = tw.Flush()
+	}}, nil
+}
@@ -0,0 +1,154 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// followInterval is how often --follow checks the log file for new output.
+var followInterval = 500 * time.Millisecond
+
+// Module returns the "tunnel logs" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "tunnel.logs",
+		Path:  []string{"tunnel", "logs"},
+		Use:   "logs <tunnel-id|instance-id>",
+		Short: "Print the log of a background proxy or ADB tunnel",
+		Long: `Print the log of a background proxy or ADB tunnel.
+
+The argument is a tunnel ID from 'agr tunnel list' or the ID of an instance
+with exactly one tunnel. The log of a tunnel that has already exited can still
+be read by its tunnel ID.`,
+		Examples: []string{
+			"agr tunnel logs proxy:ins-xxxx:8080",
+			"agr tunnel logs ins-xxxx --tail 50 --follow",
+		},
+		Args: []command.ArgSpec{{Name: "tunnel-id", Required: true}},
+		Flags: []command.FlagSpec{
+			{Name: "tail", Usage: "Print only the last N lines (0 = whole log)", Type: command.FlagInt, Default: 0},
+			{Name: "follow", Shorthand: "f", Usage: "Keep printing new log output until interrupted", Type: command.FlagBool},
+		},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{tunnel.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := tunnel.Runtime(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runLogs(ctx, req, deps, rt)
+			})}, nil
+		},
+	}
+}
+
+func runLogs(ctx context.Context, req command.Request, deps command.Deps, rt tunnel.RuntimeDeps) (*command.Result, error) {
+	id := req.ArgValues["tunnel-id"]
+	if id == "" && len(req.Args) > 0 {
+		id = req.Args[0]
+	}
+	tail := req.Flags["tail"].Int
+	if tail < 0 {
+		return nil, output.NewUsageError("INVALID_TAIL", fmt.Sprintf("--tail must be >= 0 (got %d)", tail), "Use --tail 0 to print the whole log.")
+	}
+	path, err := resolveLogPath(rt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, tunnel.NotFoundError(id)
+		}
+		return nil, fmt.Errorf("failed to open tunnel log: %w", err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tunnel log: %w", err)
+	}
+	_, _ = deps.IO.Out.Write(lastLines(content, tail))
+
+	if req.Flags["follow"].Bool {
+		follow(ctx, f, deps.IO.Out)
+	}
+	return &command.Result{StreamDone: true}, nil
+}
+
+// resolveLogPath finds the log of the tunnel selected by id. A tunnel ID that
+// is no longer in the registry resolves to its default log path, so the log of
+// an exited tunnel stays readable.
+func resolveLogPath(rt tunnel.RuntimeDeps, id string) (string, error) {
+	store, err := rt.NewStore()
+	if err != nil {
+		return "", fmt.Errorf("failed to initialize tunnel store: %w", err)
+	}
+	entries, err := store.ListAll()
+	if err != nil {
+		return "", fmt.Errorf("failed to list tunnels: %w", err)
+	}
+	if entry, ok := entries[id]; ok {
+		return tunnel.LogPath(id, entry, true)
+	}
+	matched := tunnel.Match(entries, id)
+	switch len(matched) {
+	case 0:
+		return tunnel.LogPath(id, tunnelstore.TunnelEntry{}, false)
+	case 1:
+		return tunnel.LogPath(matched[0], entries[matched[0]], true)
+	default:
+		return "", output.NewUsageError("AMBIGUOUS_TUNNEL",
+			fmt.Sprintf("instance %s has %d tunnels", id, len(matched)),
+			"Pass one of the tunnel ids from 'agr tunnel list'.")
+	}
+}
+
+// lastLines returns the last n lines of content, or all of it when n is 0.
+func lastLines(content []byte, n int) []byte {
+	if n == 0 {
+		return content
+	}
+	trimmed := bytes.TrimSuffix(content, []byte("\n"))
+	for i := len(trimmed) - 1; i >= 0; i-- {
+		if trimmed[i] == '\n' {
+			n--
+			if n == 0 {
+				return content[i+1:]
+			}
+		}
+	}
+	return content
+}
+
+// follow copies output appended to f until ctx is done or the process is
+// interrupted.
+func follow(ctx context.Context, f *os.File, w io.Writer) {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = io.Copy(w, f)
+		}
+	}
+}
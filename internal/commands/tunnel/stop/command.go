@@ -0,0 +1,137 @@
+package stop
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Module returns the "tunnel stop" command module.
+func Module() command.Module {
+	spec := command.Spec{
+		ID:    "tunnel.stop",
+		Path:  []string{"tunnel", "stop"},
+		Use:   "stop <tunnel-id|instance-id>... | --all",
+		Short: "Stop background proxies and ADB tunnels",
+		Long: `Stop background proxies and ADB tunnels.
+
+Each argument is a tunnel ID from 'agr tunnel list' or an instance ID, which
+stops every tunnel of that instance. ADB tunnels are disconnected from the
+local adb server before they are stopped.`,
+		Examples: []string{
+			"agr tunnel stop proxy:ins-xxxx:8080",
+			"agr tunnel stop ins-xxxx",
+			"agr tunnel stop --all",
+		},
+		Args:         []command.ArgSpec{{Name: "tunnel-id", Repeatable: true}},
+		Flags:        []command.FlagSpec{{Name: "all", Usage: "Stop every background proxy and ADB tunnel", Type: command.FlagBool}},
+		SupportsJSON: true,
+		Output:       command.OutputSpec{DataType: "TunnelStopResult"},
+	}
+	return command.Module{
+		Descriptor: command.Descriptor{
+			Spec:   spec,
+			Groups: []command.GroupSpec{tunnel.Group()},
+			Source: command.SourceWorkflow,
+		},
+		Build: func(deps command.Deps) (command.Runtime, error) {
+			deps = deps.WithDefaults()
+			rt := tunnel.Runtime(deps.DataPlane)
+			return command.Runtime{Handler: command.HandlerFunc(func(ctx context.Context, req command.Request) (*command.Result, error) {
+				return runStop(req, deps, rt)
+			})}, nil
+		},
+	}
+}
+
+func runStop(req command.Request, deps command.Deps, rt tunnel.RuntimeDeps) (*command.Result, error) {
+	all := req.Flags["all"].Bool
+	switch {
+	case all && len(req.Args) > 0:
+		return nil, output.NewUsageError("CONFLICTING_FLAGS",
+			"--all cannot be used with a tunnel id",
+			"Pass tunnel ids or --all, not both.")
+	case !all && len(req.Args) == 0:
+		return nil, output.NewUsageError("MISSING_REQUIRED_ARG",
+			"must specify a tunnel id or use --all",
+			"Run 'agr tunnel list' to see active tunnels.")
+	}
+	store, err := rt.NewStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tunnel store: %w", err)
+	}
+	entries, err := store.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tunnels: %w", err)
+	}
+
+	var keys []string
+	if all {
+		keys = tunnel.Keys(entries)
+	} else {
+		seen := map[string]bool{}
+		for _, id := range req.Args {
+			matched := tunnel.Match(entries, id)
+			if len(matched) == 0 {
+				return nil, tunnel.NotFoundError(id)
+			}
+			for _, key := range matched {
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+
+	adbPath := ""
+	stopped := []string{}
+	var failed, warnings []string
+	for _, key := range keys {
+		entry := entries[key]
+		if entry.Kind() == tunnelstore.TypeADB {
+			if adbPath == "" {
+				adbPath, _ = rt.RequireADB()
+			}
+			if adbPath != "" {
+				_ = rt.RunADB(adbPath, "disconnect", tunnel.LocalAddress(entry))
+			}
+		}
+		if err := store.Cleanup(key); err != nil {
+			failed = append(failed, key)
+			warnings = append(warnings, fmt.Sprintf("Failed to stop %s: %v", key, err))
+			continue
+		}
+		stopped = append(stopped, key)
+	}
+
+	data := map[string]any{"Stopped": stopped, "Count": len(stopped)}
+	result := &command.Result{Data: data, Warnings: warnings, Text: func(w io.Writer) {
+		if len(keys) == 0 {
+			fmt.Fprintln(deps.IO.ErrOut, "No active tunnels.")
+			return
+		}
+		for _, key := range stopped {
+			fmt.Fprintf(w, "Stopped %s\n", key)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(deps.IO.ErrOut, "%s\n", warning)
+		}
+	}}
+	if len(failed) > 0 {
+		data["FailedIds"] = failed
+		result.Failure = &output.Failure{
+			Code:    "PARTIAL_STOP_FAILED",
+			Kind:    output.KindPartialSuccess,
+			Message: fmt.Sprintf("failed to stop %d of %d tunnels", len(failed), len(keys)),
+			Hint:    "The processes may have been replaced by other programs; inspect Data.FailedIds and stop them manually.",
+		}
+		result.ExitCode = output.ExitPartialSuccess
+	}
+	return result, nil
+}
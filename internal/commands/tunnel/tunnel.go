@@ -0,0 +1,162 @@
+// Package tunnel implements the top-level "agr tunnel" command group.
+//
+// Tunnels are the local background processes started by
+// `agr instance mobile connect` (ADB tunnels, keyed by instance ID) and
+// `agr instance proxy --background` (port forwards, keyed by
+// "proxy:<instance-id>:<local-port>"). Both are recorded in
+// ~/.agr/tunnels.json; entries whose process has died are dropped whenever
+// the registry is read.
+package tunnel
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/internal/tunneldaemon"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/mobileadb"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// Store is the tunnel registry used by tunnel commands.
+type Store interface {
+	ListAll() (map[string]tunnelstore.TunnelEntry, error)
+	Cleanup(key string) error
+}
+
+// RuntimeDeps contains store and adb hooks that tests can replace without
+// touching the local tunnel registry or adb daemon.
+type RuntimeDeps struct {
+	NewStore   func() (Store, error)
+	RequireADB func() (string, error)
+	RunADB     func(adbPath string, args ...string) error
+}
+
+// Group returns the shared "tunnel" command group.
+func Group() command.GroupSpec {
+	return command.GroupSpec{
+		Path:  []string{"tunnel"},
+		Use:   "tunnel",
+		Short: "Manage background proxies and ADB tunnels",
+		Long: `Manage the local background processes that forward sandbox ports.
+
+This covers proxies started with 'agr instance proxy --background' and ADB
+tunnels started with 'agr instance mobile connect'.
+
+Examples:
+  agr tunnel list
+  agr tunnel logs proxy:ins-xxxx:8080 --follow
+  agr tunnel stop ins-xxxx`,
+	}
+}
+
+// Runtime returns the injected RuntimeDeps with defaults for unset hooks.
+func Runtime(injected any) RuntimeDeps {
+	rt, _ := injected.(RuntimeDeps)
+	if rt.NewStore == nil {
+		rt.NewStore = func() (Store, error) { return tunnelstore.NewStore() }
+	}
+	if rt.RequireADB == nil {
+		rt.RequireADB = mobileadb.Require
+	}
+	if rt.RunADB == nil {
+		rt.RunADB = mobileadb.Run
+	}
+	return rt
+}
+
+// Keys returns the registry keys in a stable order.
+func Keys(entries map[string]tunnelstore.TunnelEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Match returns the keys selected by id: the entry with that key and every
+// entry of the instance with that ID.
+func Match(entries map[string]tunnelstore.TunnelEntry, id string) []string {
+	var keys []string
+	for _, key := range Keys(entries) {
+		if key == id || entries[key].Instance(key) == id {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// NotFoundError reports that no tunnel matches id.
+func NotFoundError(id string) error {
+	return output.NewNotFoundError("TUNNEL_NOT_FOUND",
+		fmt.Sprintf("no active tunnel matches %s", id),
+		"Run 'agr tunnel list' to see active tunnels.")
+}
+
+// LocalAddress returns the address the tunnel listens on.
+func LocalAddress(entry tunnelstore.TunnelEntry) string {
+	address := entry.Address
+	if address == "" {
+		address = "127.0.0.1"
+	}
+	return net.JoinHostPort(address, strconv.Itoa(entry.Port))
+}
+
+// Remote describes what the tunnel forwards to.
+func Remote(entry tunnelstore.TunnelEntry) string {
+	if entry.Kind() == tunnelstore.TypeProxy {
+		return strconv.Itoa(entry.RemotePort)
+	}
+	return "adb"
+}
+
+// Status returns the display status of an entry; entries written by older
+// tunnel daemons have no status and are reported as connected.
+func Status(entry tunnelstore.TunnelEntry) string {
+	if entry.Status == "" {
+		return "connected"
+	}
+	return entry.Status
+}
+
+// Data converts a registry entry into the canonical JSON shape shared by
+// tunnel commands.
+func Data(key string, entry tunnelstore.TunnelEntry) map[string]any {
+	data := map[string]any{
+		"TunnelId":     key,
+		"Type":         entry.Kind(),
+		"InstanceId":   entry.Instance(key),
+		"LocalAddress": LocalAddress(entry),
+		"Port":         entry.Port,
+		"Pid":          entry.PID,
+		"CreatedAt":    entry.CreatedAt.Format(time.RFC3339),
+		"Status":       Status(entry),
+	}
+	if entry.Kind() == tunnelstore.TypeProxy {
+		data["RemotePort"] = entry.RemotePort
+	}
+	if entry.LogPath != "" {
+		data["LogPath"] = entry.LogPath
+	}
+	return data
+}
+
+// LogPath returns the log file of the tunnel with the given key. Entries
+// written before log paths were recorded use the daemon's default log name.
+func LogPath(key string, entry tunnelstore.TunnelEntry, found bool) (string, error) {
+	if found && entry.LogPath != "" {
+		return entry.LogPath, nil
+	}
+	if rest, ok := strings.CutPrefix(key, "proxy:"); ok {
+		if i := strings.LastIndex(rest, ":"); i > 0 {
+			return tunneldaemon.LogPath("proxy-" + rest[:i] + "-" + rest[i+1:])
+		}
+	}
+	return tunneldaemon.LogPath("tunnel-" + key)
+}
@@ -0,0 +1,168 @@
+package tunnel_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/command"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel"
+	tunnellist "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel/list"
+	tunnellogs "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel/logs"
+	tunnelstop "github.com/TencentCloudAgentRuntime/ags-cli/internal/commands/tunnel/stop"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/tunnelstore"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/iostreams"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/output"
+)
+
+// fakeStore implements tunnel.Store in memory.
+type fakeStore struct {
+	entries map[string]tunnelstore.TunnelEntry
+	cleaned []string
+}
+
+func (f *fakeStore) ListAll() (map[string]tunnelstore.TunnelEntry, error) {
+	out := make(map[string]tunnelstore.TunnelEntry, len(f.entries))
+	for k, v := range f.entries {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (f *fakeStore) Cleanup(key string) error {
+	f.cleaned = append(f.cleaned, key)
+	delete(f.entries, key)
+	return nil
+}
+
+func newStore() *fakeStore {
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	return &fakeStore{entries: map[string]tunnelstore.TunnelEntry{
+		"ins-a": {PID: 11, Port: 15555, CreatedAt: created},
+		"proxy:ins-a:3000": {PID: 12, Port: 3000, CreatedAt: created, Type: tunnelstore.TypeProxy,
+			InstanceID: "ins-a", Address: "127.0.0.1", RemotePort: 80},
+		"proxy:ins-b:8080": {PID: 13, Port: 8080, CreatedAt: created, Type: tunnelstore.TypeProxy,
+			InstanceID: "ins-b", Address: "127.0.0.1", RemotePort: 8080},
+	}}
+}
+
+type adbCalls struct{ args [][]string }
+
+func run(t *testing.T, mod command.Module, store *fakeStore, adb *adbCalls, req command.Request) (*command.Result, *iostreams.IOStreams, error) {
+	t.Helper()
+	ios, _, _, _ := iostreams.Test()
+	rt, err := mod.Build(command.Deps{IO: ios, DataPlane: tunnel.RuntimeDeps{
+		NewStore:   func() (tunnel.Store, error) { return store, nil },
+		RequireADB: func() (string, error) { return "adb", nil },
+		RunADB: func(_ string, args ...string) error {
+			if adb != nil {
+				adb.args = append(adb.args, args)
+			}
+			return nil
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := rt.Handler.Run(context.Background(), req)
+	return result, ios, err
+}
+
+func TestTunnelListIncludesProxiesAndADBTunnels(t *testing.T) {
+	result, _, err := run(t, tunnellist.Module(), newStore(), nil, command.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := result.Data.(map[string]any)
+	items := data["Items"].([]map[string]any)
+	if data["Total"] != 3 || items[0]["TunnelId"] != "ins-a" || items[0]["Type"] != "adb" {
+		t.Fatalf("data = %#v", data)
+	}
+	if items[1]["InstanceId"] != "ins-a" || items[1]["RemotePort"] != 80 || items[1]["LocalAddress"] != "127.0.0.1:3000" {
+		t.Fatalf("proxy item = %#v", items[1])
+	}
+}
+
+func TestTunnelStopByInstanceStopsEveryTunnel(t *testing.T) {
+	store := newStore()
+	adb := &adbCalls{}
+	result, _, err := run(t, tunnelstop.Module(), store, adb, command.Request{Args: []string{"ins-a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := result.Data.(map[string]any)
+	if data["Count"] != 2 || len(store.cleaned) != 2 || store.cleaned[0] != "ins-a" || store.cleaned[1] != "proxy:ins-a:3000" {
+		t.Fatalf("data=%#v cleaned=%v", data, store.cleaned)
+	}
+	if len(adb.args) != 1 || adb.args[0][0] != "disconnect" || adb.args[0][1] != "127.0.0.1:15555" {
+		t.Fatalf("adb calls = %v", adb.args)
+	}
+	if _, ok := store.entries["proxy:ins-b:8080"]; !ok {
+		t.Fatal("unrelated proxy was stopped")
+	}
+}
+
+func TestTunnelStopValidation(t *testing.T) {
+	_, _, err := run(t, tunnelstop.Module(), newStore(), nil, command.Request{Args: []string{"ins-missing"}})
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != "TUNNEL_NOT_FOUND" {
+		t.Fatalf("err = %v, want TUNNEL_NOT_FOUND", err)
+	}
+	_, _, err = run(t, tunnelstop.Module(), newStore(), nil, command.Request{
+		Args:  []string{"ins-a"},
+		Flags: map[string]command.FlagValue{"all": {Name: "all", Type: command.FlagBool, Bool: true}},
+	})
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != "CONFLICTING_FLAGS" {
+		t.Fatalf("err = %v, want CONFLICTING_FLAGS", err)
+	}
+}
+
+func TestTunnelLogsTailsRecordedAndDefaultLogs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	recorded := filepath.Join(t.TempDir(), "proxy.log")
+	if err := os.WriteFile(recorded, []byte("one\ntwo\nthree\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := newStore()
+	entry := store.entries["proxy:ins-b:8080"]
+	entry.LogPath = recorded
+	store.entries["proxy:ins-b:8080"] = entry
+
+	_, ios, err := run(t, tunnellogs.Module(), store, nil, command.Request{
+		Args:  []string{"ins-b"},
+		Flags: map[string]command.FlagValue{"tail": {Name: "tail", Type: command.FlagInt, Int: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ios.Out.(interface{ String() string }).String(); got != "two\nthree\n" {
+		t.Fatalf("output = %q", got)
+	}
+
+	// An exited proxy is gone from the registry but its log stays readable.
+	if err := os.MkdirAll(filepath.Join(home, ".agr"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".agr", "proxy-ins-c-9000.log"), []byte("exited\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, ios, err = run(t, tunnellogs.Module(), store, nil, command.Request{Args: []string{"proxy:ins-c:9000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ios.Out.(interface{ String() string }).String(); got != "exited\n" {
+		t.Fatalf("output = %q", got)
+	}
+
+	store.entries["proxy:ins-b:9090"] = tunnelstore.TunnelEntry{PID: 14, Port: 9090, Type: tunnelstore.TypeProxy, InstanceID: "ins-b", RemotePort: 9090}
+	_, _, err = run(t, tunnellogs.Module(), store, nil, command.Request{Args: []string{"ins-b"}})
+	var cliErr *output.CLIError
+	if !errors.As(err, &cliErr) || cliErr.Failure.Code != "AMBIGUOUS_TUNNEL" {
+		t.Fatalf("err = %v, want AMBIGUOUS_TUNNEL", err)
+	}
+}
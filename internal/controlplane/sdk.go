@@ -232,6 +232,35 @@ func (s *SDK) GetInstance(ctx context.Context, instanceID string) (*ags.SandboxI
 	return resp.InstanceSet[0], nil
 }
 
+// ListInstances returns every sandbox instance in the configured region,
+// fetching all pages.
+func (s *SDK) ListInstances(ctx context.Context) ([]*ags.SandboxInstance, error) {
+	client, err := s.cloudClient()
+	if err != nil {
+		return nil, err
+	}
+	const pageLimit = 100
+	var all []*ags.SandboxInstance
+	for {
+		req := ags.NewDescribeSandboxInstanceListRequest()
+		offset, limit := int64(len(all)), int64(pageLimit)
+		req.Offset = &offset
+		req.Limit = &limit
+		resp, err := callDescribeSandboxInstanceList(ctx, client, req)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, resp.InstanceSet...)
+		total := 0
+		if resp.TotalCount != nil {
+			total = int(*resp.TotalCount)
+		}
+		if len(resp.InstanceSet) < pageLimit || (total > 0 && len(all) >= total) {
+			return all, nil
+		}
+	}
+}
+
 // IsNotFound reports whether err represents a structured not-found failure.
 func (s *SDK) IsNotFound(err error) bool {
 	var cliErr *output.CLIError
@@ -5,6 +5,11 @@
 // gateway. The tunnel supports automatic reconnection with exponential backoff,
 // token refresh on reconnect, graceful handling of server-side preemption, and
 // degraded mode with automatic recovery probing.
+//
+// The bridge is not specific to ADB: with TunnelOptions.RemotePort, Path and
+// GatewayAuth pointing at the in-sandbox TCP bridge (package tcpbridge), it
+// carries arbitrary TCP streams to any sandbox port, which is how
+// `agr instance forward --tcp` reaches non-HTTP services.
 package adbtunnel
 
 import (
@@ -44,6 +49,10 @@ const (
 
 	// probeMaxDelay is the maximum delay between recovery probes in degraded mode.
 	probeMaxDelay = 30 * time.Second
+
+	// adbRemotePort and adbPath locate adb-websockify inside mobile sandboxes.
+	adbRemotePort = 5556
+	adbPath       = "/adb/ws"
 )
 
 // TunnelState represents the health state of the tunnel.
@@ -80,6 +89,20 @@ type TunnelOptions struct {
 	ListenAddress string                 // e.g. "127.0.0.1:0" for random port
 	Logger        *log.Logger            // Optional logger; defaults to log.Default()
 
+	// RemotePort and Path locate the WebSocket endpoint in the sandbox. They
+	// default to adb-websockify (5556, "/adb/ws").
+	RemotePort int
+	Path       string
+	// Label names the tunnel in log messages; defaults to "ADB".
+	Label string
+	// GatewayAuth sends the token in the gateway's X-Access-Token header
+	// instead of as an Authorization bearer token for adb-websockify.
+	GatewayAuth bool
+	// InvalidateToken, when set, is called when the gateway rejects the token
+	// with 401 so that TokenProvider returns a fresh one; the dial is then
+	// retried once.
+	InvalidateToken func()
+
 	// OnStateChange is called when the tunnel transitions between states.
 	// It is called from a background goroutine; the callback must be safe for
 	// concurrent use. If nil, state changes are only logged.
@@ -120,13 +143,28 @@ func New(opts TunnelOptions) (*Tunnel, error) {
 	if opts.ListenAddress == "" {
 		opts.ListenAddress = "127.0.0.1:0" // Ephemeral port
 	}
+	if opts.RemotePort == 0 {
+		opts.RemotePort = adbRemotePort
+	}
+	if opts.RemotePort < 0 || opts.RemotePort > 65535 {
+		return nil, fmt.Errorf("remotePort must be between 1 and 65535")
+	}
+	if opts.Path == "" {
+		opts.Path = adbPath
+	}
+	if !strings.HasPrefix(opts.Path, "/") {
+		opts.Path = "/" + opts.Path
+	}
+	if opts.Label == "" {
+		opts.Label = "ADB"
+	}
 
-	e2bHost := fmt.Sprintf("5556-%s.%s", opts.InstanceID, opts.Domain)
+	e2bHost := fmt.Sprintf("%d-%s.%s", opts.RemotePort, opts.InstanceID, opts.Domain)
 	var wsURL string
 	if opts.Endpoint != "" {
-		wsURL = fmt.Sprintf("wss://%s/adb/ws", opts.Endpoint)
+		wsURL = fmt.Sprintf("wss://%s%s", opts.Endpoint, opts.Path)
 	} else {
-		wsURL = fmt.Sprintf("wss://%s/adb/ws", e2bHost)
+		wsURL = fmt.Sprintf("wss://%s%s", e2bHost, opts.Path)
 	}
 
 	logger := opts.Logger
@@ -168,7 +206,7 @@ func (t *Tunnel) Start() (string, error) {
 	}
 	t.listener = listener
 
-	t.logger.Printf("ADB Tunnel listening on %s (bridging to %s)", listener.Addr().String(), t.wsURL)
+	t.logger.Printf("%s Tunnel listening on %s (bridging to %s)", t.options.Label, listener.Addr().String(), t.wsURL)
 
 	t.wg.Add(1)
 	go t.acceptLoop()
@@ -191,29 +229,17 @@ func (t *Tunnel) Stop() {
 		_ = t.listener.Close()
 	}
 	t.wg.Wait()
-	t.logger.Println("ADB Tunnel stopped.")
+	t.logger.Printf("%s Tunnel stopped.", t.options.Label)
 }
 
 // Probe performs a lightweight WebSocket handshake to verify the upstream tunnel
 // endpoint is reachable and the token is valid. It connects, then immediately
 // sends a Close frame and disconnects. Returns nil if the probe succeeds.
 func (t *Tunnel) Probe() error {
-	dialer := t.newDialer()
-
-	headers := http.Header{}
-	token, err := t.options.TokenProvider()
-	if err != nil {
-		return fmt.Errorf("token provider failed: %w", err)
-	}
-	headers.Add("Authorization", "Bearer "+token)
-	if t.options.Endpoint != "" {
-		headers.Set("Host", t.e2bHost)
-	}
-
 	probeCtx, probeCancel := context.WithTimeout(t.ctx, probeTimeout)
 	defer probeCancel()
 
-	wsConn, _, err := dialer.DialContext(probeCtx, t.wsURL, headers)
+	wsConn, err := t.dial(probeCtx)
 	if err != nil {
 		return fmt.Errorf("upstream WS handshake failed: %w", err)
 	}
@@ -229,6 +255,42 @@ func (t *Tunnel) Probe() error {
 	return nil
 }
 
+// dial opens the upstream WebSocket with a token from TokenProvider. When the
+// gateway answers 401 and InvalidateToken is set, the token is dropped and
+// the dial is retried once with a fresh one.
+func (t *Tunnel) dial(ctx context.Context) (*websocket.Conn, error) {
+	dialer := t.newDialer()
+	for retried := false; ; retried = true {
+		token, err := t.options.TokenProvider()
+		if err != nil {
+			return nil, fmt.Errorf("token provider failed: %w", err)
+		}
+		wsConn, resp, err := dialer.DialContext(ctx, t.wsURL, t.headers(token))
+		if err == nil {
+			return wsConn, nil
+		}
+		if retried || t.options.InvalidateToken == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			return nil, err
+		}
+		t.logger.Printf("[INFO] Access token rejected; retrying with a fresh token")
+		t.options.InvalidateToken()
+	}
+}
+
+// headers returns the WebSocket handshake headers carrying token.
+func (t *Tunnel) headers(token string) http.Header {
+	headers := http.Header{}
+	if t.options.GatewayAuth {
+		headers.Set("X-Access-Token", token)
+	} else {
+		headers.Add("Authorization", "Bearer "+token)
+	}
+	if t.options.Endpoint != "" {
+		headers.Set("Host", t.e2bHost)
+	}
+	return headers
+}
+
 func (t *Tunnel) newDialer() *websocket.Dialer {
 	dialer := &websocket.Dialer{
 		HandshakeTimeout: 15 * time.Second,
@@ -413,19 +475,7 @@ func (t *Tunnel) startRecoveryProbe() {
 // handleConnection bridges a single local TCP connection to a WebSocket upstream.
 // Returns (preempted, error) where preempted=true means server sent close code 4001.
 func (t *Tunnel) handleConnection(localConn net.Conn) (preempted bool, err error) {
-	dialer := t.newDialer()
-
-	headers := http.Header{}
-	token, tokenErr := t.options.TokenProvider()
-	if tokenErr != nil {
-		return false, fmt.Errorf("token provider failed: %w", tokenErr)
-	}
-	headers.Add("Authorization", "Bearer "+token)
-	if t.options.Endpoint != "" {
-		headers.Set("Host", t.e2bHost)
-	}
-
-	wsConn, _, dialErr := dialer.DialContext(t.ctx, t.wsURL, headers)
+	wsConn, dialErr := t.dial(t.ctx)
 	if dialErr != nil {
 		return false, fmt.Errorf("WebSocket dial failed: %w", dialErr)
 	}
@@ -2,6 +2,12 @@ package adbtunnel
 
 import (
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -34,5 +40,65 @@ var _ = Describe("ADB tunnel", func() {
 		Expect(tunnel.e2bHost).To(Equal("5556-sandbox-aaa.ap-guangzhou.tencentags.com"))
 	})
 
+	It("bridges to an arbitrary sandbox port with gateway auth", func() {
+		tunnel, err := New(TunnelOptions{
+			InstanceID: "sandbox-aaa", Domain: "ap-guangzhou.tencentags.com",
+			TokenProvider: func() (string, error) { return "token", nil },
+			RemotePort:    5432, Path: "ws", Label: "TCP", GatewayAuth: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnel.wsURL).To(Equal("wss://5432-sandbox-aaa.ap-guangzhou.tencentags.com/ws"))
+		headers := tunnel.headers("token")
+		Expect(headers.Get("X-Access-Token")).To(Equal("token"))
+		Expect(headers.Get("Authorization")).To(BeEmpty())
+
+		_, err = New(TunnelOptions{InstanceID: "sandbox-aaa", Domain: "ap-guangzhou.tencentags.com",
+			TokenProvider: func() (string, error) { return "token", nil }, RemotePort: 70000})
+		Expect(err).To(MatchError(ContainSubstring("remotePort")))
+	})
+
+	It("redials with a fresh token when the gateway rejects the cached one", func() {
+		requireLocalListen()
+		var mu sync.Mutex
+		var seen []string
+		upgrader := websocket.Upgrader{}
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			seen = append(seen, r.Header.Get("X-Access-Token"))
+			mu.Unlock()
+			if r.Header.Get("X-Access-Token") != "fresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err == nil {
+				_ = conn.Close()
+			}
+		}))
+		defer server.Close()
+
+		token := "stale"
+		invalidated := 0
+		tunnel, err := New(TunnelOptions{
+			InstanceID: "sandbox-aaa", Domain: "ap-guangzhou.tencentags.com",
+			TokenProvider:   func() (string, error) { return token, nil },
+			InvalidateToken: func() { invalidated++; token = "fresh" },
+			Endpoint:        strings.TrimPrefix(server.URL, "https://"),
+			Insecure:        true,
+			RemotePort:      5432, Path: "/", GatewayAuth: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnel.Probe()).To(Succeed())
+		Expect(invalidated).To(Equal(1))
+		Expect(seen).To(Equal([]string{"stale", "fresh"}))
+
+		// A token that keeps being rejected is retried only once.
+		token = "revoked"
+		tunnel.options.InvalidateToken = func() { invalidated++ }
+		Expect(tunnel.Probe()).To(MatchError(ContainSubstring("bad handshake")))
+		Expect(invalidated).To(Equal(2))
+		Expect(seen).To(HaveLen(4))
+	})
+
 	It("can reserve a local listener", func() { requireLocalListen() })
 })
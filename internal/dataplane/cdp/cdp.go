@@ -0,0 +1,267 @@
+// Package cdp serves a local Chrome DevTools Protocol endpoint for a browser
+// sandbox, so that CDP clients can connect to http://localhost:9222 without
+// handling the sandbox access token.
+package cdp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultListenAddress is the conventional DevTools address.
+	DefaultListenAddress = "127.0.0.1:9222"
+	// DefaultRemotePort is the browser sandbox port serving CDP and VNC.
+	DefaultRemotePort = 9000
+	// BrowserPath is the browser-level DevTools WebSocket in the sandbox.
+	BrowserPath = "/cdp"
+)
+
+// Options defines configuration for the CDP endpoint.
+type Options struct {
+	InstanceID    string      // e.g. "sandbox-xxx"
+	Domain        string      // e.g. "ap-guangzhou.tencentags.com" (region-qualified)
+	RemotePort    int         // Browser service port; defaults to DefaultRemotePort
+	ListenAddress string      // e.g. "127.0.0.1:9222"
+	Logger        *log.Logger // Optional logger; defaults to log.Default()
+	Insecure      bool        // Skip TLS verification
+	Verbose       bool        // Enable verbose request logging
+	// AllowedOrigins lists the browser origins that may open DevTools
+	// WebSockets; "*" allows any. Handshakes from other origins are refused.
+	AllowedOrigins []string
+	// Token, TokenProvider and InvalidateToken are passed to proxy.Options.
+	Token           string
+	TokenProvider   func() (string, error)
+	InvalidateToken func()
+}
+
+// Server answers the DevTools discovery endpoints (/json/version and
+// /json/list) with WebSocket URLs that point at itself and forwards every
+// other request, including the DevTools WebSockets, to the sandbox with the
+// access token injected by a proxy.Proxy.
+type Server struct {
+	options  Options
+	logger   *log.Logger
+	proxy    *proxy.Proxy
+	upstream http.Handler
+	listener net.Listener
+	server   *http.Server
+}
+
+// New creates a CDP endpoint but does not start it.
+func New(opts Options) (*Server, error) {
+	if opts.RemotePort == 0 {
+		opts.RemotePort = DefaultRemotePort
+	}
+	if opts.ListenAddress == "" {
+		opts.ListenAddress = DefaultListenAddress
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	p, err := proxy.New(proxy.Options{
+		InstanceID:      opts.InstanceID,
+		Domain:          opts.Domain,
+		RemotePort:      opts.RemotePort,
+		Token:           opts.Token,
+		TokenProvider:   opts.TokenProvider,
+		InvalidateToken: opts.InvalidateToken,
+		Logger:          logger,
+		Insecure:        opts.Insecure,
+		Verbose:         opts.Verbose,
+		AllowedOrigins:  opts.AllowedOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		options:  opts,
+		logger:   logger,
+		proxy:    p,
+		upstream: p.Handler(),
+	}, nil
+}
+
+// Start binds to the local address and begins serving requests. It returns
+// the actual listen address.
+func (s *Server) Start() (string, error) {
+	listener, err := net.Listen("tcp", s.options.ListenAddress)
+	if err != nil {
+		return "", fmt.Errorf("failed to bind local address: %w", err)
+	}
+	s.listener = listener
+	s.server = &http.Server{
+		Handler: s,
+		// ReadTimeout is omitted so that DevTools WebSockets are not capped;
+		// see proxy.Proxy.
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Printf("[ERROR] Server error: %v", err)
+		}
+	}()
+	s.logger.Printf("CDP endpoint listening on %s", listener.Addr().String())
+	return listener.Addr().String(), nil
+}
+
+// Stop shuts down the endpoint and its open DevTools connections.
+func (s *Server) Stop() {
+	s.proxy.Stop()
+	if s.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.server.Shutdown(ctx)
+	}
+}
+
+// ServeHTTP answers the discovery endpoints and forwards everything else.
+//
+// Like Chrome's own DevTools server, it only answers requests addressed to
+// localhost or an IP address, so a web page cannot reach the browser through
+// DNS rebinding, and refuses WebSocket handshakes from web pages unless their
+// origin is allowed.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !proxy.LocalHost(r.Host) {
+		http.Error(w, "Host header is not an IP address or localhost", http.StatusForbidden)
+		return
+	}
+	if origin := r.Header.Get("Origin"); origin != "" && websocket.IsWebSocketUpgrade(r) && !proxy.OriginAllowed(origin, s.options.AllowedOrigins) {
+		s.logger.Printf("[CDP] rejected WebSocket from origin %s; allow it with --allow-origin", origin)
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/json/version":
+		s.serveVersion(w, r)
+	case "/json", "/json/list":
+		s.serveList(w, r)
+	default:
+		s.upstream.ServeHTTP(w, r)
+	}
+}
+
+// serveVersion answers /json/version from the sandbox when it serves one and
+// otherwise describes the browser WebSocket at BrowserPath, which every
+// browser sandbox exposes.
+func (s *Server) serveVersion(w http.ResponseWriter, r *http.Request) {
+	version := map[string]any{}
+	if status, body := s.fetch(r); status != http.StatusOK || json.Unmarshal(body, &version) != nil {
+		if s.options.Verbose {
+			s.logger.Printf("[CDP] sandbox has no /json/version (status %d); using %s", status, BrowserPath)
+		}
+		version = map[string]any{
+			"Browser":          "Chrome",
+			"Protocol-Version": "1.3",
+		}
+	}
+	local := (&url.URL{Scheme: "ws", Host: r.Host, Path: BrowserPath}).String()
+	if raw, ok := version["webSocketDebuggerUrl"].(string); ok {
+		local = rewriteWebSocketURL(raw, r.Host)
+	}
+	version["webSocketDebuggerUrl"] = local
+	writeJSON(w, version)
+}
+
+// serveList answers /json/list with the sandbox's targets, rewriting their
+// WebSocket URLs to this endpoint. Errors from the sandbox are passed on.
+func (s *Server) serveList(w http.ResponseWriter, r *http.Request) {
+	status, body := s.fetch(r)
+	var targets []map[string]any
+	if status != http.StatusOK || json.Unmarshal(body, &targets) != nil {
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+		return
+	}
+	for _, target := range targets {
+		if raw, ok := target["webSocketDebuggerUrl"].(string); ok {
+			target["webSocketDebuggerUrl"] = rewriteWebSocketURL(raw, r.Host)
+		}
+		if raw, ok := target["devtoolsFrontendUrl"].(string); ok {
+			target["devtoolsFrontendUrl"] = rewriteFrontendURL(raw, r.Host)
+		}
+	}
+	if targets == nil {
+		targets = []map[string]any{}
+	}
+	writeJSON(w, targets)
+}
+
+// fetch sends r to the sandbox and returns the buffered response.
+func (s *Server) fetch(r *http.Request) (int, []byte) {
+	out := r.Clone(r.Context())
+	// Let the transport negotiate and decode compression.
+	out.Header.Del("Accept-Encoding")
+	rec := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+	s.upstream.ServeHTTP(rec, out)
+	return rec.status, rec.body.Bytes()
+}
+
+// rewriteWebSocketURL points a DevTools WebSocket URL at host, keeping its
+// path and dropping any access token from the query.
+func rewriteWebSocketURL(raw, host string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Path == "" {
+		return raw
+	}
+	u.Scheme = "ws"
+	u.Host = host
+	query := u.Query()
+	query.Del("access_token")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// rewriteFrontendURL points the ws= or wss= parameter of a DevTools frontend
+// URL at host.
+func rewriteFrontendURL(raw, host string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	query := u.Query()
+	target := query.Get("ws")
+	if target == "" {
+		target = query.Get("wss")
+	}
+	if target == "" {
+		return raw
+	}
+	_, path, _ := strings.Cut(target, "/")
+	query.Del("wss")
+	query.Set("ws", host+"/"+path)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "   ")
+	_ = encoder.Encode(v)
+}
+
+// bufferedResponse collects a response from the upstream handler.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) { b.status = status }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
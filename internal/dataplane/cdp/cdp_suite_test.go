@@ -0,0 +1,13 @@
+package cdp
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCDP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CDP Suite")
+}
@@ -0,0 +1,119 @@
+package cdp
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	newServer := func(upstream http.HandlerFunc) *Server {
+		s, err := New(Options{
+			InstanceID: "ins-a",
+			Domain:     "example.com",
+			Token:      "token",
+			Logger:     log.New(GinkgoWriter, "", 0),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		s.upstream = upstream
+		return s
+	}
+	get := func(s *Server, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:9222"+path, nil))
+		return rec
+	}
+
+	It("applies defaults", func() {
+		s := newServer(nil)
+		Expect(s.options.ListenAddress).To(Equal(DefaultListenAddress))
+		Expect(s.options.RemotePort).To(Equal(DefaultRemotePort))
+	})
+
+	It("rewrites the browser WebSocket from the sandbox's /json/version", func() {
+		s := newServer(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/json/version/"))
+			_, _ = w.Write([]byte(`{"Browser":"HeadlessChrome/120","webSocketDebuggerUrl":"ws://127.0.0.1:9222/devtools/browser/abc?access_token=secret"}`))
+		})
+		rec := get(s, "/json/version/")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var version map[string]any
+		Expect(json.Unmarshal(rec.Body.Bytes(), &version)).To(Succeed())
+		Expect(version["Browser"]).To(Equal("HeadlessChrome/120"))
+		Expect(version["webSocketDebuggerUrl"]).To(Equal("ws://localhost:9222/devtools/browser/abc"))
+	})
+
+	It("falls back to the sandbox CDP path without /json/version", func() {
+		s := newServer(func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		})
+		var version map[string]any
+		Expect(json.Unmarshal(get(s, "/json/version").Body.Bytes(), &version)).To(Succeed())
+		Expect(version["webSocketDebuggerUrl"]).To(Equal("ws://localhost:9222" + BrowserPath))
+	})
+
+	It("rewrites target URLs in /json/list", func() {
+		s := newServer(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[{"id":"p1","type":"page","webSocketDebuggerUrl":"wss://9000-ins-a.example.com/devtools/page/p1","devtoolsFrontendUrl":"/devtools/inspector.html?wss=9000-ins-a.example.com/devtools/page/p1"}]`))
+		})
+		var targets []map[string]any
+		Expect(json.Unmarshal(get(s, "/json/list").Body.Bytes(), &targets)).To(Succeed())
+		Expect(targets).To(HaveLen(1))
+		Expect(targets[0]["webSocketDebuggerUrl"]).To(Equal("ws://localhost:9222/devtools/page/p1"))
+		Expect(targets[0]["devtoolsFrontendUrl"]).To(Equal("/devtools/inspector.html?ws=localhost%3A9222%2Fdevtools%2Fpage%2Fp1"))
+	})
+
+	It("passes sandbox errors for /json/list through", func() {
+		s := newServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("Bad Gateway"))
+		})
+		rec := get(s, "/json")
+		Expect(rec.Code).To(Equal(http.StatusBadGateway))
+		Expect(rec.Body.String()).To(Equal("Bad Gateway"))
+	})
+
+	It("refuses requests addressed to other host names", func() {
+		s := newServer(func(w http.ResponseWriter, r *http.Request) {
+			Fail("request was forwarded")
+		})
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://rebind.example:9222/json/version", nil))
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("refuses DevTools WebSockets from web pages unless their origin is allowed", func() {
+		forwarded := 0
+		s := newServer(func(w http.ResponseWriter, r *http.Request) {
+			forwarded++
+		})
+		handshake := func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:9222/devtools/page/p1", nil)
+			r.Header.Set("Connection", "Upgrade")
+			r.Header.Set("Upgrade", "websocket")
+			r.Header.Set("Origin", "https://evil.example")
+			return r
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, handshake())
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(forwarded).To(BeZero())
+
+		s.options.AllowedOrigins = []string{"https://evil.example"}
+		s.ServeHTTP(httptest.NewRecorder(), handshake())
+		Expect(forwarded).To(Equal(1))
+	})
+
+	It("forwards other requests unchanged", func() {
+		var path string
+		s := newServer(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+		})
+		get(s, "/devtools/page/p1")
+		Expect(path).To(Equal("/devtools/page/p1"))
+	})
+})
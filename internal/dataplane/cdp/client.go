@@ -0,0 +1,212 @@
+package cdp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrUnauthorized is returned by Dial when the sandbox rejects the access
+// token, so that callers can refresh it and dial again.
+var ErrUnauthorized = errors.New("sandbox rejected the access token")
+
+// Event is a CDP event. SessionID is empty for browser-level events.
+type Event struct {
+	Method    string
+	SessionID string
+	Params    json.RawMessage
+}
+
+// Error is an error returned by the browser for a CDP call.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("%s (%s)", e.Message, e.Data)
+	}
+	return e.Message
+}
+
+// message is a CDP call.
+type message struct {
+	ID        int64  `json:"id"`
+	SessionID string `json:"sessionId,omitempty"`
+	Method    string `json:"method"`
+	Params    any    `json:"params"`
+}
+
+// incoming is a response to a call or an event.
+type incoming struct {
+	ID        int64           `json:"id"`
+	SessionID string          `json:"sessionId"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params"`
+	Result    json.RawMessage `json:"result"`
+	Error     *Error          `json:"error"`
+}
+
+// Client is a connection to the browser-level DevTools WebSocket. Calls may
+// be made from several goroutines; events are delivered to the handlers
+// registered with OnEvent from the reading goroutine.
+type Client struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  map[int64]chan incoming
+	handlers []func(Event)
+	closed   chan struct{}
+	err      error
+}
+
+// Dial connects to a DevTools WebSocket URL such as the one returned by
+// 'agr instance browser vnc'.
+func Dial(ctx context.Context, url string, insecure bool) (*Client, error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 30 * time.Second,
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: insecure}, //nolint:gosec
+		ReadBufferSize:   65536,
+		WriteBufferSize:  65536,
+	}
+	conn, resp, err := dialer.DialContext(ctx, url, nil)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return nil, ErrUnauthorized
+		}
+		if resp != nil {
+			return nil, fmt.Errorf("failed to connect to the browser: %w (HTTP %d)", err, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to connect to the browser: %w", err)
+	}
+	// Screenshots and PDFs arrive as one large base64 frame.
+	conn.SetReadLimit(256 << 20)
+	return NewClient(conn), nil
+}
+
+// NewClient starts reading from an open DevTools WebSocket.
+func NewClient(conn *websocket.Conn) *Client {
+	c := &Client{
+		conn:    conn,
+		pending: map[int64]chan incoming{},
+		closed:  make(chan struct{}),
+	}
+	go c.read()
+	return c
+}
+
+// OnEvent registers fn to receive every event.
+func (c *Client) OnEvent(fn func(Event)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, fn)
+}
+
+// Call sends method with params to the target attached as sessionID, or to
+// the browser when sessionID is empty, and decodes the result into result
+// when it is not nil.
+func (c *Client) Call(ctx context.Context, sessionID, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	reply := make(chan incoming, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if params == nil {
+		params = struct{}{}
+	}
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(message{ID: id, SessionID: sessionID, Method: method, Params: params})
+	c.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	select {
+	case msg := <-reply:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %w", method, msg.Error)
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("%s: invalid result: %w", method, err)
+			}
+		}
+		return nil
+	case <-c.closed:
+		return fmt.Errorf("%s: %w", method, c.closeErr())
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	c.writeMu.Lock()
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+	return c.conn.Close()
+}
+
+func (c *Client) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Client) read() {
+	for {
+		var msg incoming
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("browser connection closed: %w", err)
+			c.mu.Unlock()
+			close(c.closed)
+			return
+		}
+		if msg.ID != 0 {
+			c.mu.Lock()
+			reply, ok := c.pending[msg.ID]
+			c.mu.Unlock()
+			if ok {
+				reply <- msg
+			}
+			continue
+		}
+		if msg.Method == "" {
+			continue
+		}
+		c.mu.Lock()
+		handlers := c.handlers
+		c.mu.Unlock()
+		event := Event{Method: msg.Method, SessionID: msg.SessionID, Params: msg.Params}
+		for _, fn := range handlers {
+			fn(event)
+		}
+	}
+}
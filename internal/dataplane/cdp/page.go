@@ -0,0 +1,420 @@
+package cdp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page is a browser tab attached over a Client with a flat session. It
+// records console errors, uncaught exceptions and browser log errors from the
+// moment it is attached.
+type Page struct {
+	client    *Client
+	sessionID string
+	targetID  string
+
+	mu            sync.Mutex
+	loaded        chan struct{}
+	frames        chan screencastFrame
+	consoleErrors []string
+}
+
+// Frame is one screencast image.
+type Frame struct {
+	Data []byte
+	// Timestamp is when the browser painted the frame.
+	Timestamp time.Time
+}
+
+// ScreencastOptions configures Page.Screencast.
+type ScreencastOptions struct {
+	Format        string // "jpeg" or "png"
+	Quality       int    // JPEG quality, 0-100
+	EveryNthFrame int
+}
+
+type screencastFrame struct {
+	Data      string `json:"data"`
+	SessionID int    `json:"sessionId"`
+	Metadata  struct {
+		Timestamp float64 `json:"timestamp"`
+	} `json:"metadata"`
+}
+
+// EvalResult is the value of an evaluated expression.
+type EvalResult struct {
+	// Type is the JavaScript type, e.g. "string", "number" or "object".
+	Type string
+	// Value is the JSON value; it is nil for undefined and for values that
+	// cannot be serialized, which are described by Description instead.
+	Value       any
+	Description string
+}
+
+// ExceptionError reports an expression that threw.
+type ExceptionError struct {
+	Message string
+}
+
+func (e *ExceptionError) Error() string {
+	return "uncaught exception: " + e.Message
+}
+
+type remoteObject struct {
+	Type        string          `json:"type"`
+	Subtype     string          `json:"subtype"`
+	Value       json.RawMessage `json:"value"`
+	Description string          `json:"description"`
+}
+
+type exceptionDetails struct {
+	Text      string        `json:"text"`
+	Exception *remoteObject `json:"exception"`
+}
+
+func (d exceptionDetails) message() string {
+	if d.Exception != nil && d.Exception.Description != "" {
+		return d.Exception.Description
+	}
+	return d.Text
+}
+
+// AttachPage attaches to the first open tab, opening a blank one when the
+// browser has none, and enables the Page, Runtime and Log domains.
+func AttachPage(ctx context.Context, client *Client) (*Page, error) {
+	var targets struct {
+		TargetInfos []struct {
+			TargetID string `json:"targetId"`
+			Type     string `json:"type"`
+		} `json:"targetInfos"`
+	}
+	if err := client.Call(ctx, "", "Target.getTargets", nil, &targets); err != nil {
+		return nil, err
+	}
+	targetID := ""
+	for _, info := range targets.TargetInfos {
+		if info.Type == "page" {
+			targetID = info.TargetID
+			break
+		}
+	}
+	if targetID == "" {
+		var created struct {
+			TargetID string `json:"targetId"`
+		}
+		if err := client.Call(ctx, "", "Target.createTarget", map[string]any{"url": "about:blank"}, &created); err != nil {
+			return nil, err
+		}
+		targetID = created.TargetID
+	}
+	var attached struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := client.Call(ctx, "", "Target.attachToTarget", map[string]any{"targetId": targetID, "flatten": true}, &attached); err != nil {
+		return nil, err
+	}
+	p := &Page{client: client, sessionID: attached.SessionID, targetID: targetID}
+	client.OnEvent(p.handle)
+	for _, domain := range []string{"Page", "Runtime", "Log"} {
+		if err := p.call(ctx, domain+".enable", nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Navigate loads url and waits for the load event.
+func (p *Page) Navigate(ctx context.Context, url string) error {
+	loaded := make(chan struct{}, 1)
+	p.mu.Lock()
+	p.loaded = loaded
+	p.mu.Unlock()
+
+	var result struct {
+		LoaderID  string `json:"loaderId"`
+		ErrorText string `json:"errorText"`
+	}
+	if err := p.call(ctx, "Page.navigate", map[string]any{"url": url}, &result); err != nil {
+		return err
+	}
+	if result.ErrorText != "" {
+		return fmt.Errorf("failed to load %s: %s", url, result.ErrorText)
+	}
+	if result.LoaderID == "" {
+		// Same-document navigation, e.g. a fragment change: no load event.
+		return nil
+	}
+	select {
+	case <-loaded:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for %s to load: %w", url, ctx.Err())
+	}
+}
+
+// Evaluate runs expression in the page, awaiting a returned promise.
+func (p *Page) Evaluate(ctx context.Context, expression string) (EvalResult, error) {
+	var result struct {
+		Result           remoteObject      `json:"result"`
+		ExceptionDetails *exceptionDetails `json:"exceptionDetails"`
+	}
+	params := map[string]any{
+		"expression":    expression,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}
+	if err := p.call(ctx, "Runtime.evaluate", params, &result); err != nil {
+		return EvalResult{}, err
+	}
+	if result.ExceptionDetails != nil {
+		return EvalResult{}, &ExceptionError{Message: result.ExceptionDetails.message()}
+	}
+	out := EvalResult{Type: result.Result.Type, Description: result.Result.Description}
+	if result.Result.Subtype == "null" {
+		out.Type = "null"
+	}
+	if len(result.Result.Value) > 0 {
+		if err := json.Unmarshal(result.Result.Value, &out.Value); err != nil {
+			return EvalResult{}, fmt.Errorf("invalid evaluation result: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// Info returns the current URL and title of the page.
+func (p *Page) Info(ctx context.Context) (url, title string, err error) {
+	result, err := p.Evaluate(ctx, "[location.href, document.title]")
+	if err != nil {
+		return "", "", err
+	}
+	values, _ := result.Value.([]any)
+	if len(values) != 2 {
+		return "", "", errors.New("unexpected page info")
+	}
+	url, _ = values[0].(string)
+	title, _ = values[1].(string)
+	return url, title, nil
+}
+
+// Screenshot captures the page as "png" or "jpeg". With fullPage it captures
+// the whole document rather than the viewport.
+func (p *Page) Screenshot(ctx context.Context, format string, fullPage bool) ([]byte, error) {
+	params := map[string]any{"format": format}
+	if fullPage {
+		var metrics struct {
+			ContentSize    *rect `json:"contentSize"`
+			CSSContentSize *rect `json:"cssContentSize"`
+		}
+		if err := p.call(ctx, "Page.getLayoutMetrics", nil, &metrics); err != nil {
+			return nil, err
+		}
+		size := metrics.CSSContentSize
+		if size == nil {
+			size = metrics.ContentSize
+		}
+		if size != nil {
+			params["clip"] = map[string]any{"x": 0, "y": 0, "width": size.Width, "height": size.Height, "scale": 1}
+			params["captureBeyondViewport"] = true
+		}
+	}
+	return p.data(ctx, "Page.captureScreenshot", params)
+}
+
+// PDF prints the page with its backgrounds.
+func (p *Page) PDF(ctx context.Context, landscape bool) ([]byte, error) {
+	return p.data(ctx, "Page.printToPDF", map[string]any{"landscape": landscape, "printBackground": true})
+}
+
+// Screencast streams frames of the page to onFrame until ctx is done or
+// onFrame fails, then stops the screencast. Each frame is acknowledged after
+// onFrame returns, so a slow consumer slows the browser down instead of
+// queueing frames; frames arriving while the queue is full are dropped and
+// acknowledged at once. The browser only sends frames when the page changes.
+func (p *Page) Screencast(ctx context.Context, opts ScreencastOptions, onFrame func(Frame) error) error {
+	frames := make(chan screencastFrame, 16)
+	p.mu.Lock()
+	p.frames = frames
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.frames = nil
+		p.mu.Unlock()
+	}()
+
+	params := map[string]any{"format": opts.Format}
+	if opts.Quality > 0 {
+		params["quality"] = opts.Quality
+	}
+	if opts.EveryNthFrame > 0 {
+		params["everyNthFrame"] = opts.EveryNthFrame
+	}
+	if err := p.call(ctx, "Page.startScreencast", params, nil); err != nil {
+		return err
+	}
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = p.call(stopCtx, "Page.stopScreencast", nil, nil)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case frame := <-frames:
+			data, err := base64.StdEncoding.DecodeString(frame.Data)
+			if err != nil {
+				return fmt.Errorf("invalid screencast frame: %w", err)
+			}
+			sec, frac := math.Modf(frame.Metadata.Timestamp)
+			if err := onFrame(Frame{Data: data, Timestamp: time.Unix(int64(sec), int64(frac*1e9))}); err != nil {
+				return err
+			}
+			if err := p.call(ctx, "Page.screencastFrameAck", map[string]any{"sessionId": frame.SessionID}, nil); err != nil && ctx.Err() == nil {
+				return err
+			}
+		}
+	}
+}
+
+// ackFrame acknowledges a screencast frame that was dropped because the
+// consumer fell behind.
+func (p *Page) ackFrame(sessionID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = p.call(ctx, "Page.screencastFrameAck", map[string]any{"sessionId": sessionID}, nil)
+}
+
+// ConsoleErrors returns the errors recorded since the page was attached.
+func (p *Page) ConsoleErrors() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.consoleErrors...)
+}
+
+// Detach releases the tab; it stays open in the browser.
+func (p *Page) Detach(ctx context.Context) error {
+	return p.client.Call(ctx, "", "Target.detachFromTarget", map[string]any{"sessionId": p.sessionID}, nil)
+}
+
+type rect struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (p *Page) call(ctx context.Context, method string, params, result any) error {
+	return p.client.Call(ctx, p.sessionID, method, params, result)
+}
+
+// data calls a method returning base64 "data" and decodes it.
+func (p *Page) data(ctx context.Context, method string, params map[string]any) ([]byte, error) {
+	var result struct {
+		Data string `json:"data"`
+	}
+	if err := p.call(ctx, method, params, &result); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(result.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid data: %w", method, err)
+	}
+	return data, nil
+}
+
+func (p *Page) handle(event Event) {
+	if event.SessionID != p.sessionID {
+		return
+	}
+	switch event.Method {
+	case "Page.loadEventFired":
+		p.mu.Lock()
+		if p.loaded != nil {
+			select {
+			case p.loaded <- struct{}{}:
+			default:
+			}
+		}
+		p.mu.Unlock()
+	case "Page.screencastFrame":
+		var frame screencastFrame
+		if json.Unmarshal(event.Params, &frame) != nil {
+			return
+		}
+		p.mu.Lock()
+		if p.frames != nil {
+			select {
+			case p.frames <- frame:
+			default:
+				// The browser sends no more frames until this one is
+				// acknowledged. The ack cannot wait for its reply here, on
+				// the reading goroutine.
+				go p.ackFrame(frame.SessionID)
+			}
+		}
+		p.mu.Unlock()
+	case "Runtime.consoleAPICalled":
+		var params struct {
+			Type string         `json:"type"`
+			Args []remoteObject `json:"args"`
+		}
+		if json.Unmarshal(event.Params, &params) != nil || params.Type != "error" {
+			return
+		}
+		parts := make([]string, 0, len(params.Args))
+		for _, arg := range params.Args {
+			parts = append(parts, arg.text())
+		}
+		p.recordError(strings.Join(parts, " "))
+	case "Runtime.exceptionThrown":
+		var params struct {
+			ExceptionDetails exceptionDetails `json:"exceptionDetails"`
+		}
+		if json.Unmarshal(event.Params, &params) == nil {
+			p.recordError(params.ExceptionDetails.message())
+		}
+	case "Log.entryAdded":
+		var params struct {
+			Entry struct {
+				Level string `json:"level"`
+				Text  string `json:"text"`
+				URL   string `json:"url"`
+			} `json:"entry"`
+		}
+		if json.Unmarshal(event.Params, &params) != nil || params.Entry.Level != "error" {
+			return
+		}
+		text := params.Entry.Text
+		if params.Entry.URL != "" {
+			text += " (" + params.Entry.URL + ")"
+		}
+		p.recordError(text)
+	}
+}
+
+func (p *Page) recordError(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.consoleErrors = append(p.consoleErrors, text)
+}
+
+// text renders a console argument the way DevTools prints it.
+func (o remoteObject) text() string {
+	if len(o.Value) > 0 {
+		var s string
+		if json.Unmarshal(o.Value, &s) == nil {
+			return s
+		}
+		return string(o.Value)
+	}
+	if o.Description != "" {
+		return o.Description
+	}
+	return o.Type
+}
@@ -0,0 +1,277 @@
+package cdp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeBrowser answers CDP calls with canned results and records them.
+type fakeBrowser struct {
+	mu      sync.Mutex
+	methods []string
+	results map[string]any
+	// events are sent, in the session, after the call with the same method.
+	events map[string][]map[string]any
+	// acked records the frames acknowledged with Page.screencastFrameAck.
+	acked []int
+}
+
+func (f *fakeBrowser) ackedFrames() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int{}, f.acked...)
+}
+
+func (f *fakeBrowser) called() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.methods...)
+}
+
+func (f *fakeBrowser) serve(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for {
+		var call struct {
+			ID        int64  `json:"id"`
+			SessionID string `json:"sessionId"`
+			Method    string `json:"method"`
+			Params    struct {
+				SessionID int `json:"sessionId"`
+			} `json:"params"`
+		}
+		if err := conn.ReadJSON(&call); err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.methods = append(f.methods, call.Method)
+		if call.Method == "Page.screencastFrameAck" {
+			f.acked = append(f.acked, call.Params.SessionID)
+		}
+		f.mu.Unlock()
+		reply := map[string]any{"id": call.ID, "result": map[string]any{}}
+		if result, ok := f.results[call.Method]; ok {
+			if e, ok := result.(*Error); ok {
+				reply = map[string]any{"id": call.ID, "error": e}
+			} else {
+				reply["result"] = result
+			}
+		}
+		_ = conn.WriteJSON(reply)
+		for _, event := range f.events[call.Method] {
+			event["sessionId"] = "session-1"
+			_ = conn.WriteJSON(event)
+		}
+	}
+}
+
+var _ = Describe("Page", func() {
+	var (
+		browser *fakeBrowser
+		server  *httptest.Server
+		client  *Client
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		browser = &fakeBrowser{
+			results: map[string]any{
+				"Target.getTargets": map[string]any{"targetInfos": []map[string]any{
+					{"targetId": "worker", "type": "service_worker"},
+					{"targetId": "tab-1", "type": "page"},
+				}},
+				"Target.attachToTarget": map[string]any{"sessionId": "session-1"},
+			},
+			events: map[string][]map[string]any{},
+		}
+		server = httptest.NewServer(http.HandlerFunc(browser.serve))
+		var err error
+		client, err = Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/cdp", false)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = client.Close()
+		server.Close()
+	})
+
+	It("attaches to the first tab and enables its domains", func() {
+		_, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(browser.called()).To(Equal([]string{"Target.getTargets", "Target.attachToTarget", "Page.enable", "Runtime.enable", "Log.enable"}))
+	})
+
+	It("opens a tab when the browser has none", func() {
+		browser.results["Target.getTargets"] = map[string]any{"targetInfos": []any{}}
+		browser.results["Target.createTarget"] = map[string]any{"targetId": "tab-2"}
+		page, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(page.targetID).To(Equal("tab-2"))
+	})
+
+	It("navigates, waits for the load event and records console errors", func() {
+		browser.results["Page.navigate"] = map[string]any{"frameId": "f", "loaderId": "l"}
+		browser.events["Page.navigate"] = []map[string]any{
+			{"method": "Runtime.consoleAPICalled", "params": map[string]any{"type": "error", "args": []any{map[string]any{"type": "string", "value": "boom"}, map[string]any{"type": "number", "value": 42}}}},
+			{"method": "Runtime.consoleAPICalled", "params": map[string]any{"type": "log", "args": []any{map[string]any{"type": "string", "value": "fine"}}}},
+			{"method": "Runtime.exceptionThrown", "params": map[string]any{"exceptionDetails": map[string]any{"text": "Uncaught", "exception": map[string]any{"description": "TypeError: x is undefined"}}}},
+			{"method": "Log.entryAdded", "params": map[string]any{"entry": map[string]any{"level": "error", "text": "Failed to load resource", "url": "https://example.com/a.js"}}},
+			{"method": "Page.loadEventFired", "params": map[string]any{"timestamp": 1}},
+		}
+		page, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(page.Navigate(ctx, "https://example.com")).To(Succeed())
+		Expect(page.ConsoleErrors()).To(Equal([]string{
+			"boom 42",
+			"TypeError: x is undefined",
+			"Failed to load resource (https://example.com/a.js)",
+		}))
+	})
+
+	It("reports navigation errors", func() {
+		browser.results["Page.navigate"] = map[string]any{"frameId": "f", "errorText": "net::ERR_NAME_NOT_RESOLVED"}
+		page, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(page.Navigate(ctx, "https://nope.invalid")).To(MatchError(ContainSubstring("ERR_NAME_NOT_RESOLVED")))
+	})
+
+	It("evaluates expressions and reports exceptions", func() {
+		page, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+
+		browser.results["Runtime.evaluate"] = map[string]any{"result": map[string]any{"type": "object", "value": map[string]any{"a": 1}}}
+		result, err := page.Evaluate(ctx, "({a: 1})")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Type).To(Equal("object"))
+		Expect(result.Value).To(Equal(map[string]any{"a": float64(1)}))
+
+		browser.results["Runtime.evaluate"] = map[string]any{"result": map[string]any{"type": "object", "subtype": "null", "value": nil}}
+		result, err = page.Evaluate(ctx, "null")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Type).To(Equal("null"))
+
+		browser.results["Runtime.evaluate"] = map[string]any{"result": map[string]any{"type": "object"}, "exceptionDetails": map[string]any{"text": "Uncaught", "exception": map[string]any{"description": "Error: nope"}}}
+		_, err = page.Evaluate(ctx, "throw new Error('nope')")
+		var exception *ExceptionError
+		Expect(errors.As(err, &exception)).To(BeTrue())
+		Expect(exception.Message).To(Equal("Error: nope"))
+	})
+
+	It("returns the page URL and title", func() {
+		browser.results["Runtime.evaluate"] = map[string]any{"result": map[string]any{"type": "object", "value": []any{"https://example.com/", "Example"}}}
+		page, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+		url, title, err := page.Info(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(url).To(Equal("https://example.com/"))
+		Expect(title).To(Equal("Example"))
+	})
+
+	It("decodes screenshots and PDFs", func() {
+		browser.results["Page.getLayoutMetrics"] = map[string]any{"cssContentSize": map[string]any{"width": 800, "height": 2000}}
+		browser.results["Page.captureScreenshot"] = map[string]any{"data": base64.StdEncoding.EncodeToString([]byte("png"))}
+		browser.results["Page.printToPDF"] = map[string]any{"data": base64.StdEncoding.EncodeToString([]byte("%PDF"))}
+		page, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := page.Screenshot(ctx, "png", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("png"))
+		Expect(browser.called()).To(ContainElement("Page.getLayoutMetrics"))
+		data, err = page.PDF(ctx, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("%PDF"))
+	})
+
+	It("streams screencast frames and acknowledges them", func() {
+		frame := func(data string, ts float64, id int) map[string]any {
+			return map[string]any{"method": "Page.screencastFrame", "params": map[string]any{
+				"data": base64.StdEncoding.EncodeToString([]byte(data)), "sessionId": id,
+				"metadata": map[string]any{"timestamp": ts},
+			}}
+		}
+		browser.events["Page.startScreencast"] = []map[string]any{frame("a", 1700000000.5, 1), frame("b", 1700000001.25, 2)}
+		page, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+
+		recordCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var got []Frame
+		err = page.Screencast(recordCtx, ScreencastOptions{Format: "jpeg", Quality: 80}, func(f Frame) error {
+			got = append(got, f)
+			if len(got) == 2 {
+				cancel()
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got).To(HaveLen(2))
+		Expect(string(got[1].Data)).To(Equal("b"))
+		Expect(got[1].Timestamp.Sub(got[0].Timestamp)).To(Equal(750 * time.Millisecond))
+		Eventually(browser.called).Should(ContainElements("Page.screencastFrameAck", "Page.stopScreencast"))
+	})
+
+	It("acknowledges the frames it drops while the consumer falls behind", func() {
+		const sent = 40
+		for i := 1; i <= sent; i++ {
+			browser.events["Page.startScreencast"] = append(browser.events["Page.startScreencast"], map[string]any{
+				"method": "Page.screencastFrame", "params": map[string]any{
+					"data": base64.StdEncoding.EncodeToString([]byte("f")), "sessionId": i,
+					"metadata": map[string]any{"timestamp": float64(i)},
+				},
+			})
+		}
+		page, err := AttachPage(ctx, client)
+		Expect(err).NotTo(HaveOccurred())
+
+		recordCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		consumed := 0
+		err = page.Screencast(recordCtx, ScreencastOptions{Format: "jpeg"}, func(Frame) error {
+			consumed++
+			if consumed == 1 {
+				// Hold the first frame until the browser has sent the rest: at
+				// most 16 fit in the queue, the others must be acknowledged.
+				Eventually(func() int { return len(browser.ackedFrames()) }).Should(BeNumerically(">=", sent-1-16))
+				cancel()
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(consumed).To(BeNumerically("<=", 17))
+		Expect(browser.ackedFrames()).To(ContainElement(sent))
+	})
+
+	It("returns browser errors", func() {
+		browser.results["Target.getTargets"] = &Error{Code: -32601, Message: "'Target.getTargets' wasn't found"}
+		_, err := AttachPage(ctx, client)
+		var cdpErr *Error
+		Expect(errors.As(err, &cdpErr)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("Target.getTargets"))
+	})
+})
+
+var _ = Describe("Dial", func() {
+	It("reports a rejected token", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+		_, err := Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http")+"/cdp", false)
+		Expect(err).To(MatchError(ErrUnauthorized))
+	})
+})
@@ -0,0 +1,268 @@
+// Package gateway serves many sandbox ports of many instances behind one local
+// HTTP listener.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/dataplane/proxy"
+)
+
+const (
+	// DefaultListenAddress is the gateway listen address when none is given.
+	DefaultListenAddress = "127.0.0.1:8000"
+
+	// pathPrefix starts path-routed URLs: /i/<instance>/<port>/...
+	pathPrefix = "/i/"
+	// hostSuffix ends host-routed names: <port>-<instance>.localhost
+	hostSuffix = ".localhost"
+)
+
+// instancePattern matches instance IDs that may be used in a gateway host.
+var instancePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Options defines configuration for the gateway.
+type Options struct {
+	Domain        string      // e.g. "ap-guangzhou.tencentags.com" (region-qualified)
+	ListenAddress string      // e.g. "127.0.0.1:8000"
+	Logger        *log.Logger // Optional logger; defaults to log.Default()
+	Insecure      bool        // Skip TLS verification
+	Verbose       bool        // Enable verbose request logging
+	// TokenProvider returns the access token of an instance. It is called
+	// lazily on the first request for the instance and again after the
+	// sandbox gateway rejects the token.
+	TokenProvider func(instanceID string) (string, error)
+	// InvalidateToken, when set, drops the cached token of an instance before
+	// TokenProvider is asked for a replacement.
+	InvalidateToken func(instanceID string)
+}
+
+// Route identifies the sandbox port a request is sent to.
+type Route struct {
+	InstanceID string
+	Port       int
+	// Prefix is the path prefix removed before forwarding, for path routes.
+	Prefix string
+}
+
+// Gateway routes requests to sandbox ports by host name or path:
+//
+//	http://<port>-<instance>.localhost:8000/...
+//	http://localhost:8000/i/<instance>/<port>/...
+//
+// Each instance and port is served by a proxy.Proxy created on first use, so
+// the access token is injected and WebSocket upgrades are bridged exactly as
+// 'agr instance proxy' does.
+type Gateway struct {
+	options  Options
+	logger   *log.Logger
+	listener net.Listener
+	server   *http.Server
+
+	mu      sync.Mutex
+	proxies map[Route]*routeProxy
+	stopped bool
+}
+
+// routeProxy is the proxy serving one instance port and its handler, which
+// is built once so that upstream connections are reused.
+type routeProxy struct {
+	proxy   *proxy.Proxy
+	handler http.Handler
+}
+
+// New creates a gateway but does not start it.
+func New(opts Options) (*Gateway, error) {
+	if opts.Domain == "" || opts.TokenProvider == nil {
+		return nil, errors.New("domain and token provider are required")
+	}
+	if opts.ListenAddress == "" {
+		opts.ListenAddress = DefaultListenAddress
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Gateway{
+		options: opts,
+		logger:  logger,
+		proxies: map[Route]*routeProxy{},
+	}, nil
+}
+
+// Start binds to the local address and begins serving requests. It returns
+// the actual listen address.
+func (g *Gateway) Start() (string, error) {
+	listener, err := net.Listen("tcp", g.options.ListenAddress)
+	if err != nil {
+		return "", fmt.Errorf("failed to bind local address: %w", err)
+	}
+	g.listener = listener
+	g.server = &http.Server{
+		Handler: g,
+		// ReadTimeout is omitted so that WebSocket connections are not capped;
+		// see proxy.Proxy.
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := g.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			g.logger.Printf("[ERROR] Server error: %v", err)
+		}
+	}()
+	g.logger.Printf("Gateway listening on %s", listener.Addr().String())
+	return listener.Addr().String(), nil
+}
+
+// Stop shuts down the gateway and every per-port proxy.
+func (g *Gateway) Stop() {
+	g.mu.Lock()
+	g.stopped = true
+	proxies := g.proxies
+	g.proxies = map[Route]*routeProxy{}
+	g.mu.Unlock()
+
+	for _, p := range proxies {
+		p.proxy.Stop()
+	}
+	if g.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = g.server.Shutdown(ctx)
+	}
+}
+
+// ServeHTTP routes one request. Requests addressed to host names other than
+// localhost, its subdomains or an IP address are refused: through DNS
+// rebinding a web page could otherwise reach every sandbox of the account
+// with the token injected.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !proxy.LocalHost(r.Host) {
+		http.Error(w, "Host header is not an IP address or localhost", http.StatusForbidden)
+		return
+	}
+	route, ok := ParseRoute(r.Host, r.URL.Path)
+	if !ok {
+		http.Error(w, fmt.Sprintf("No sandbox route. Use http://<port>-<instance>%s:<gateway-port>/ or %s<instance>/<port>/.", hostSuffix, pathPrefix), http.StatusNotFound)
+		return
+	}
+	p, err := g.proxy(route)
+	if err != nil {
+		g.logger.Printf("[ERROR] %s port %d: %v", route.InstanceID, route.Port, err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	if route.Prefix != "" {
+		r = stripPrefix(r, route.Prefix)
+	}
+	p.handler.ServeHTTP(w, r)
+}
+
+// proxy returns the proxy for route, creating it on first use.
+func (g *Gateway) proxy(route Route) (*routeProxy, error) {
+	key := Route{InstanceID: route.InstanceID, Port: route.Port}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return nil, errors.New("gateway is stopping")
+	}
+	if existing, ok := g.proxies[key]; ok {
+		return existing, nil
+	}
+	instanceID := route.InstanceID
+	opts := proxy.Options{
+		InstanceID: instanceID,
+		Domain:     g.options.Domain,
+		RemotePort: route.Port,
+		TokenProvider: func() (string, error) {
+			return g.options.TokenProvider(instanceID)
+		},
+		Logger:   g.logger,
+		Insecure: g.options.Insecure,
+		Verbose:  g.options.Verbose,
+	}
+	if g.options.InvalidateToken != nil {
+		opts.InvalidateToken = func() { g.options.InvalidateToken(instanceID) }
+	}
+	p, err := proxy.New(opts)
+	if err != nil {
+		return nil, err
+	}
+	if g.options.Verbose {
+		g.logger.Printf("Routing %s port %d", instanceID, route.Port)
+	}
+	created := &routeProxy{proxy: p, handler: p.Handler()}
+	g.proxies[key] = created
+	return created, nil
+}
+
+// ParseRoute extracts the sandbox route from a request host or path. Host
+// routes take precedence over path routes.
+func ParseRoute(host, path string) (Route, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if label, ok := strings.CutSuffix(host, hostSuffix); ok && !strings.Contains(label, ".") {
+		portText, instanceID, ok := strings.Cut(label, "-")
+		if ok {
+			if route, ok := newRoute(instanceID, portText); ok {
+				return route, true
+			}
+		}
+	}
+	rest, ok := strings.CutPrefix(path, pathPrefix)
+	if !ok {
+		return Route{}, false
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 2 {
+		return Route{}, false
+	}
+	route, ok := newRoute(parts[0], parts[1])
+	if !ok {
+		return Route{}, false
+	}
+	route.Prefix = pathPrefix + parts[0] + "/" + parts[1]
+	return route, true
+}
+
+func newRoute(instanceID, portText string) (Route, bool) {
+	port, err := strconv.Atoi(portText)
+	if err != nil || port <= 0 || port > 65535 || strconv.Itoa(port) != portText {
+		return Route{}, false
+	}
+	if !instancePattern.MatchString(instanceID) {
+		return Route{}, false
+	}
+	return Route{InstanceID: instanceID, Port: port}, true
+}
+
+// stripPrefix returns a copy of r with prefix removed from its path. The
+// prefix is passed upstream as X-Forwarded-Prefix so that apps can build
+// links that keep working behind the gateway.
+func stripPrefix(r *http.Request, prefix string) *http.Request {
+	out := r.Clone(r.Context())
+	out.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+	if out.URL.Path == "" {
+		out.URL.Path = "/"
+	}
+	if r.URL.RawPath != "" {
+		out.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		if out.URL.RawPath == "" {
+			out.URL.RawPath = "/"
+		}
+	}
+	out.RequestURI = ""
+	out.Header.Set("X-Forwarded-Prefix", prefix)
+	return out
+}
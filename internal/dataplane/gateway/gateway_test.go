@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gateway", func() {
+	newGateway := func(calls *[]string) *Gateway {
+		g, err := New(Options{
+			Domain: "example.com",
+			TokenProvider: func(instanceID string) (string, error) {
+				*calls = append(*calls, instanceID)
+				return "token", nil
+			},
+			Logger: log.New(GinkgoWriter, "", 0),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return g
+	}
+
+	It("validates constructor options", func() {
+		_, err := New(Options{Domain: "example.com"})
+		Expect(err).To(HaveOccurred())
+		g, err := New(Options{Domain: "example.com", TokenProvider: func(string) (string, error) { return "", nil }})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(g.options.ListenAddress).To(Equal(DefaultListenAddress))
+	})
+
+	DescribeTable("parses routes",
+		func(host, path string, want Route, ok bool) {
+			got, gotOK := ParseRoute(host, path)
+			Expect(gotOK).To(Equal(ok))
+			Expect(got).To(Equal(want))
+		},
+		Entry("host route", "3000-ins-abc.localhost:8000", "/app", Route{InstanceID: "ins-abc", Port: 3000}, true),
+		Entry("host route without port", "8080-sandbox-1.LOCALHOST", "/", Route{InstanceID: "sandbox-1", Port: 8080}, true),
+		Entry("path route", "localhost:8000", "/i/ins-abc/3000/static/app.js", Route{InstanceID: "ins-abc", Port: 3000, Prefix: "/i/ins-abc/3000"}, true),
+		Entry("path route root", "127.0.0.1:8000", "/i/ins-abc/3000", Route{InstanceID: "ins-abc", Port: 3000, Prefix: "/i/ins-abc/3000"}, true),
+		Entry("host route wins", "3000-ins-a.localhost", "/i/ins-b/80/", Route{InstanceID: "ins-a", Port: 3000}, true),
+		Entry("plain localhost", "localhost:8000", "/", Route{}, false),
+		Entry("bad port", "99999-ins-a.localhost", "/", Route{}, false),
+		Entry("leading zero", "localhost", "/i/ins-a/080/", Route{}, false),
+		Entry("nested host", "3000-ins-a.evil.localhost", "/", Route{}, false),
+		Entry("bad instance", "localhost", "/i/ins_a/80/", Route{}, false),
+		Entry("missing port", "localhost", "/i/ins-a", Route{}, false),
+	)
+
+	It("strips the path prefix and forwards it as a header", func() {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8000/i/ins-a/3000/api?q=1", nil)
+		out := stripPrefix(r, "/i/ins-a/3000")
+		Expect(out.URL.RequestURI()).To(Equal("/api?q=1"))
+		Expect(out.Header.Get("X-Forwarded-Prefix")).To(Equal("/i/ins-a/3000"))
+		Expect(r.URL.Path).To(Equal("/i/ins-a/3000/api"))
+
+		out = stripPrefix(httptest.NewRequest(http.MethodGet, "http://localhost:8000/i/ins-a/3000", nil), "/i/ins-a/3000")
+		Expect(out.URL.Path).To(Equal("/"))
+	})
+
+	It("answers 404 without a route", func() {
+		var calls []string
+		rec := httptest.NewRecorder()
+		newGateway(&calls).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost:8000/", nil))
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(ContainSubstring("<port>-<instance>.localhost"))
+	})
+
+	It("refuses requests addressed to other host names", func() {
+		var calls []string
+		g := newGateway(&calls)
+		for _, target := range []string{"http://rebind.example:8000/i/ins-a/3000/", "http://3000-ins-a.localhost.rebind.example:8000/"} {
+			rec := httptest.NewRecorder()
+			g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			Expect(rec.Code).To(Equal(http.StatusForbidden), target)
+		}
+		Expect(calls).To(BeEmpty())
+		Expect(g.proxies).To(BeEmpty())
+	})
+
+	It("creates one proxy per instance port and acquires tokens lazily", func() {
+		var calls []string
+		g := newGateway(&calls)
+		a, err := g.proxy(Route{InstanceID: "ins-a", Port: 3000, Prefix: "/i/ins-a/3000"})
+		Expect(err).NotTo(HaveOccurred())
+		again, err := g.proxy(Route{InstanceID: "ins-a", Port: 3000})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(BeIdenticalTo(a))
+		b, err := g.proxy(Route{InstanceID: "ins-b", Port: 3000})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b).NotTo(BeIdenticalTo(a))
+		Expect(calls).To(BeEmpty())
+
+		g.Stop()
+		_, err = g.proxy(Route{InstanceID: "ins-c", Port: 3000})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("starts and stops", func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			Skip("local listener unavailable: " + err.Error())
+		}
+		_ = ln.Close()
+		var calls []string
+		g := newGateway(&calls)
+		g.options.ListenAddress = "127.0.0.1:0"
+		addr, err := g.Start()
+		Expect(err).NotTo(HaveOccurred())
+		defer g.Stop()
+		resp, err := http.Get("http://" + addr + "/")
+		Expect(err).NotTo(HaveOccurred())
+		_ = resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
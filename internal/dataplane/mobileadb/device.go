@@ -0,0 +1,381 @@
+package mobileadb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalSerial returns the adb serial of the local tunnel listening on port.
+func LocalSerial(port int) string {
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+// Device runs adb commands against one connected device.
+type Device struct {
+	ADBPath string
+	Serial  string
+	// Run executes adb and returns its output and exit code. It defaults to
+	// RunBuffered.
+	Run func(adbPath string, args ...string) (stdout string, stderr string, exitCode int, err error)
+	// Stream executes a long-running adb command such as logcat, writing its
+	// output to stdout until ctx is done. It defaults to RunContext.
+	Stream func(ctx context.Context, adbPath string, args []string, stdout io.Writer) error
+}
+
+// Package is an installed Android package.
+type Package struct {
+	Name        string
+	VersionCode string
+	// Path is the base APK on the device.
+	Path string
+}
+
+// InstallOptions configures Device.Install.
+type InstallOptions struct {
+	// Split installs the APKs as the splits of one package.
+	Split bool
+	// Grant grants all runtime permissions.
+	Grant bool
+	// Downgrade allows a lower version code than the installed one.
+	Downgrade bool
+}
+
+// CommandError reports an adb command that failed on the device.
+type CommandError struct {
+	Args     []string
+	ExitCode int
+	Output   string
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("adb %s failed", strings.Join(e.Args, " "))
+	if e.ExitCode != 0 {
+		msg += fmt.Sprintf(" (exit code %d)", e.ExitCode)
+	}
+	if e.Output != "" {
+		msg += ": " + e.Output
+	}
+	return msg
+}
+
+// Output runs adb with args for the device and returns stdout. A non-zero
+// exit code is returned as a *CommandError carrying stderr, or stdout when
+// stderr is empty.
+func (d *Device) Output(args ...string) (string, error) {
+	run := d.Run
+	if run == nil {
+		run = RunBuffered
+	}
+	stdout, stderr, code, err := run(d.ADBPath, append([]string{"-s", d.Serial}, args...)...)
+	if err != nil {
+		return "", err
+	}
+	if code != 0 {
+		text := strings.TrimSpace(stderr)
+		if text == "" {
+			text = strings.TrimSpace(stdout)
+		}
+		return "", &CommandError{Args: args, ExitCode: code, Output: text}
+	}
+	return stdout, nil
+}
+
+// Shell runs a command line in the device shell.
+func (d *Device) Shell(commandLine string) (string, error) {
+	return d.Output("shell", commandLine)
+}
+
+// Screencap captures the screen as PNG.
+func (d *Device) Screencap() ([]byte, error) {
+	out, err := d.Output("exec-out", "screencap", "-p")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(out, "\x89PNG") {
+		return nil, fmt.Errorf("screencap returned no PNG image: %s", firstLine(out))
+	}
+	return []byte(out), nil
+}
+
+// Tap taps the screen at x, y.
+func (d *Device) Tap(x, y int) error {
+	_, err := d.Shell(fmt.Sprintf("input tap %d %d", x, y))
+	return err
+}
+
+// Swipe swipes from x1, y1 to x2, y2 over duration.
+func (d *Device) Swipe(x1, y1, x2, y2 int, duration time.Duration) error {
+	_, err := d.Shell(fmt.Sprintf("input swipe %d %d %d %d %d", x1, y1, x2, y2, duration.Milliseconds()))
+	return err
+}
+
+// InputText types text into the focused field.
+func (d *Device) InputText(text string) error {
+	_, err := d.Shell("input text " + Quote(EscapeInputText(text)))
+	return err
+}
+
+// KeyEvent sends key events. Keys are key codes or names as accepted by
+// KeyCode.
+func (d *Device) KeyEvent(keys ...string) error {
+	codes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		code, err := KeyCode(key)
+		if err != nil {
+			return err
+		}
+		codes = append(codes, code)
+	}
+	_, err := d.Shell("input keyevent " + strings.Join(codes, " "))
+	return err
+}
+
+// Install installs local APK files. Without opts.Split each APK is a separate
+// package and they are installed one by one.
+func (d *Device) Install(apks []string, opts InstallOptions) error {
+	verb := "install"
+	if opts.Split {
+		verb = "install-multiple"
+	}
+	args := []string{verb, "-r"}
+	if opts.Grant {
+		args = append(args, "-g")
+	}
+	if opts.Downgrade {
+		args = append(args, "-d")
+	}
+	out, err := d.Output(append(args, apks...)...)
+	if err != nil {
+		return err
+	}
+	// Old adb versions report failures with exit code 0.
+	if i := strings.Index(out, "Failure"); i >= 0 {
+		return &CommandError{Args: append(args, apks...), Output: firstLine(out[i:])}
+	}
+	return nil
+}
+
+// Transfer summarizes an adb pull or push.
+type Transfer struct {
+	Files int
+	Bytes int64
+	// Seconds is the transfer time reported by adb.
+	Seconds float64
+}
+
+var (
+	transferFiles = regexp.MustCompile(`(\d+) files? (?:pulled|pushed)`)
+	transferBytes = regexp.MustCompile(`\((\d+) bytes in ([0-9.]+)s\)`)
+)
+
+// ParseTransfer parses the summary adb prints after pull or push, such as
+// "/sdcard/a.mp4: 1 file pulled, 0 skipped. 9.1 MB/s (123 bytes in 0.013s)".
+// ok is false when the output has no byte count.
+func ParseTransfer(out string) (t Transfer, ok bool) {
+	if m := transferFiles.FindAllStringSubmatch(out, -1); m != nil {
+		t.Files, _ = strconv.Atoi(m[len(m)-1][1])
+	}
+	m := transferBytes.FindAllStringSubmatch(out, -1)
+	if m == nil {
+		return t, false
+	}
+	last := m[len(m)-1]
+	t.Bytes, _ = strconv.ParseInt(last[1], 10, 64)
+	t.Seconds, _ = strconv.ParseFloat(last[2], 64)
+	return t, true
+}
+
+// Pull copies remote from the device to local. ok reports whether adb printed
+// the transferred byte count.
+func (d *Device) Pull(remote, local string) (t Transfer, ok bool, err error) {
+	out, err := d.Output("pull", remote, local)
+	if err != nil {
+		return Transfer{}, false, err
+	}
+	t, ok = ParseTransfer(out)
+	return t, ok, nil
+}
+
+// Push copies local to remote on the device. ok reports whether adb printed
+// the transferred byte count.
+func (d *Device) Push(local, remote string) (t Transfer, ok bool, err error) {
+	out, err := d.Output("push", local, remote)
+	if err != nil {
+		return Transfer{}, false, err
+	}
+	t, ok = ParseTransfer(out)
+	return t, ok, nil
+}
+
+// MaxScreenRecord is the longest recording screenrecord supports.
+const MaxScreenRecord = 180 * time.Second
+
+// ScreenRecord records the screen to remote on the device for limit, rounded
+// up to whole seconds. It returns when the recording ends or is stopped with
+// StopScreenRecord. While it runs, the screenrecord PID is kept next to remote
+// so that only this recording is stopped.
+func (d *Device) ScreenRecord(remote string, limit time.Duration) error {
+	seconds := int((limit + time.Second - 1) / time.Second)
+	pidFile := Quote(screenRecordPIDFile(remote))
+	_, err := d.Shell(fmt.Sprintf("echo $$ > %s && exec screenrecord --time-limit %d %s", pidFile, seconds, Quote(remote)))
+	_, _ = d.Shell("rm -f " + pidFile)
+	return err
+}
+
+// StopScreenRecord ends the ScreenRecord writing to remote. screenrecord
+// finishes the file on SIGINT, so the recording up to now stays playable.
+func (d *Device) StopScreenRecord(remote string) error {
+	_, err := d.Shell(fmt.Sprintf("kill -INT \"$(cat %s)\"", Quote(screenRecordPIDFile(remote))))
+	return err
+}
+
+// screenRecordPIDFile is where ScreenRecord keeps the PID of the recording to
+// remote.
+func screenRecordPIDFile(remote string) string {
+	return remote + ".pid"
+}
+
+// Remove deletes remote on the device.
+func (d *Device) Remove(remote string) error {
+	_, err := d.Shell("rm -f " + Quote(remote))
+	return err
+}
+
+// Properties returns the system properties from getprop.
+func (d *Device) Properties() (map[string]string, error) {
+	out, err := d.Shell("getprop")
+	if err != nil {
+		return nil, err
+	}
+	return ParseProperties(out), nil
+}
+
+// Packages lists the installed packages, or only the third-party ones.
+func (d *Device) Packages(thirdParty bool) ([]Package, error) {
+	cmd := "pm list packages -f --show-versioncode"
+	if thirdParty {
+		cmd += " -3"
+	}
+	out, err := d.Shell(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePackages(out), nil
+}
+
+// VersionName returns the versionName of an installed package.
+func (d *Device) VersionName(pkg string) (string, error) {
+	out, err := d.Shell("dumpsys package " + Quote(pkg))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "versionName="); ok {
+			return value, nil
+		}
+	}
+	return "", nil
+}
+
+// ScreenSize returns the display size, e.g. "1080x2400", and density in dpi.
+// An override set with "wm size" or "wm density" wins over the physical value.
+func (d *Device) ScreenSize() (size string, density int, err error) {
+	out, err := d.Shell("wm size; wm density")
+	if err != nil {
+		return "", 0, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Physical size":
+			if size == "" {
+				size = value
+			}
+		case "Override size":
+			size = value
+		case "Physical density", "Override density":
+			if n, err := strconv.Atoi(value); err == nil && (density == 0 || key == "Override density") {
+				density = n
+			}
+		}
+	}
+	return size, density, nil
+}
+
+var propertyLine = regexp.MustCompile(`^\[([^\]]+)\]: \[(.*)\]$`)
+
+// ParseProperties parses getprop output. Multi-line values keep their first
+// line only.
+func ParseProperties(out string) map[string]string {
+	props := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if m := propertyLine.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+			props[m[1]] = m[2]
+		}
+	}
+	return props
+}
+
+// ParsePackages parses "pm list packages -f --show-versioncode" output, sorted
+// by name.
+func ParsePackages(out string) []Package {
+	pkgs := []Package{}
+	for _, line := range strings.Split(out, "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "package:")
+		if !ok {
+			continue
+		}
+		var pkg Package
+		rest, pkg.VersionCode, _ = strings.Cut(rest, " versionCode:")
+		pkg.Name = rest
+		if i := strings.LastIndex(rest, "="); i >= 0 {
+			pkg.Path, pkg.Name = rest[:i], rest[i+1:]
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	return pkgs
+}
+
+var keyName = regexp.MustCompile(`^[A-Z0-9_]+$`)
+
+// KeyCode normalizes a key to what "input keyevent" accepts: a numeric code
+// as is, and a name such as "home" or "KEYCODE_HOME" as KEYCODE_HOME.
+func KeyCode(key string) (string, error) {
+	if _, err := strconv.Atoi(key); err == nil {
+		return key, nil
+	}
+	name := strings.ToUpper(strings.TrimSpace(key))
+	if !keyName.MatchString(name) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	if !strings.HasPrefix(name, "KEYCODE_") {
+		name = "KEYCODE_" + name
+	}
+	return name, nil
+}
+
+// EscapeInputText encodes spaces the way "input text" expects them.
+func EscapeInputText(text string) string {
+	return strings.ReplaceAll(text, " ", "%s")
+}
+
+// Quote quotes s for the device shell.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
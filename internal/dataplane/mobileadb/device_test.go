@@ -0,0 +1,130 @@
+package mobileadb
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeADB answers adb invocations by the joined arguments after the serial.
+type fakeADB struct {
+	calls   []string
+	replies map[string]string
+	codes   map[string]int
+}
+
+func (f *fakeADB) run(_ string, args ...string) (string, string, int, error) {
+	Expect(args[:2]).To(Equal([]string{"-s", "127.0.0.1:5555"}))
+	call := strings.Join(args[2:], " ")
+	f.calls = append(f.calls, call)
+	if code := f.codes[call]; code != 0 {
+		return "", "error: " + call, code, nil
+	}
+	return f.replies[call], "", 0, nil
+}
+
+var _ = Describe("Device", func() {
+	var (
+		adb    *fakeADB
+		device *Device
+	)
+
+	BeforeEach(func() {
+		adb = &fakeADB{replies: map[string]string{}, codes: map[string]int{}}
+		device = &Device{ADBPath: "/adb", Serial: LocalSerial(5555), Run: adb.run}
+	})
+
+	It("builds input commands", func() {
+		Expect(device.Tap(10, 20)).To(Succeed())
+		Expect(device.Swipe(1, 2, 3, 4, 300*time.Millisecond)).To(Succeed())
+		Expect(device.InputText("it's a test")).To(Succeed())
+		Expect(device.KeyEvent("home", "KEYCODE_BACK", "66")).To(Succeed())
+		Expect(adb.calls).To(Equal([]string{
+			"shell input tap 10 20",
+			"shell input swipe 1 2 3 4 300",
+			`shell input text 'it'\''s%sa%stest'`,
+			"shell input keyevent KEYCODE_HOME KEYCODE_BACK 66",
+		}))
+		Expect(device.KeyEvent("home;reboot")).To(MatchError(ContainSubstring("invalid key")))
+	})
+
+	It("returns stderr of failed commands", func() {
+		adb.codes["shell input tap 1 1"] = 1
+		err := device.Tap(1, 1)
+		var cmdErr *CommandError
+		Expect(err).To(BeAssignableToTypeOf(cmdErr))
+		Expect(err.Error()).To(ContainSubstring("exit code 1"))
+		Expect(err.Error()).To(ContainSubstring("error: shell input tap 1 1"))
+	})
+
+	It("installs single and split APKs", func() {
+		Expect(device.Install([]string{"a.apk"}, InstallOptions{Grant: true})).To(Succeed())
+		Expect(device.Install([]string{"base.apk", "split.apk"}, InstallOptions{Split: true, Downgrade: true})).To(Succeed())
+		Expect(adb.calls).To(Equal([]string{"install -r -g a.apk", "install-multiple -r -d base.apk split.apk"}))
+
+		adb.replies["install -r bad.apk"] = "Performing Streamed Install\nFailure [INSTALL_FAILED_INVALID_APK]\n"
+		Expect(device.Install([]string{"bad.apk"}, InstallOptions{})).To(MatchError(ContainSubstring("Failure [INSTALL_FAILED_INVALID_APK]")))
+	})
+
+	It("parses pull and push summaries", func() {
+		adb.replies["pull /sdcard/a.mp4 ./a.mp4"] = "/sdcard/a.mp4: 1 file pulled, 0 skipped. 9.1 MB/s (123456 bytes in 0.013s)\n"
+		t, ok, err := device.Pull("/sdcard/a.mp4", "./a.mp4")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(t).To(Equal(Transfer{Files: 1, Bytes: 123456, Seconds: 0.013}))
+
+		adb.replies["push dir /sdcard/"] = "dir/: 3 files pushed, 0 skipped. 1.2 MB/s (2048 bytes in 0.002s)\n"
+		t, ok, err = device.Push("dir", "/sdcard/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(t.Files).To(Equal(3))
+		Expect(t.Bytes).To(BeEquivalentTo(2048))
+
+		_, ok = ParseTransfer("1 file pulled.\n")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("records the screen in whole seconds and stops only that recording", func() {
+		Expect(device.ScreenRecord("/sdcard/agr-record-1.mp4", 1500*time.Millisecond)).To(Succeed())
+		Expect(device.StopScreenRecord("/sdcard/agr-record-1.mp4")).To(Succeed())
+		Expect(device.Remove("/sdcard/agr-record-1.mp4")).To(Succeed())
+		Expect(adb.calls).To(Equal([]string{
+			"shell echo $$ > '/sdcard/agr-record-1.mp4.pid' && exec screenrecord --time-limit 2 '/sdcard/agr-record-1.mp4'",
+			"shell rm -f '/sdcard/agr-record-1.mp4.pid'",
+			`shell kill -INT "$(cat '/sdcard/agr-record-1.mp4.pid')"`,
+			"shell rm -f '/sdcard/agr-record-1.mp4'",
+		}))
+	})
+
+	It("rejects screencap output that is not a PNG", func() {
+		adb.replies["exec-out screencap -p"] = "\x89PNG\r\n"
+		Expect(device.Screencap()).To(Equal([]byte("\x89PNG\r\n")))
+		adb.replies["exec-out screencap -p"] = "Error: no display\n"
+		_, err := device.Screencap()
+		Expect(err).To(MatchError(ContainSubstring("no display")))
+	})
+
+	It("reads the screen size and density, preferring overrides", func() {
+		adb.replies["shell wm size; wm density"] = "Physical size: 1080x2400\nOverride size: 720x1600\nPhysical density: 420\n"
+		size, density, err := device.ScreenSize()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal("720x1600"))
+		Expect(density).To(Equal(420))
+	})
+
+	It("parses getprop output", func() {
+		props := ParseProperties("[ro.product.model]: [Pixel 7]\r\n[ro.build.version.sdk]: [34]\n[empty]: []\nnoise\n")
+		Expect(props).To(Equal(map[string]string{"ro.product.model": "Pixel 7", "ro.build.version.sdk": "34", "empty": ""}))
+	})
+
+	It("parses package listings", func() {
+		out := "package:/data/app/~~x==/com.b-y==/base.apk=com.b versionCode:20\npackage:/system/app/A/A.apk=com.a versionCode:1\n"
+		Expect(ParsePackages(out)).To(Equal([]Package{
+			{Name: "com.a", VersionCode: "1", Path: "/system/app/A/A.apk"},
+			{Name: "com.b", VersionCode: "20", Path: "/data/app/~~x==/com.b-y==/base.apk"},
+		}))
+		Expect(ParsePackages("package:com.c\n")).To(Equal([]Package{{Name: "com.c"}}))
+	})
+})
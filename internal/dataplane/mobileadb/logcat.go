@@ -0,0 +1,167 @@
+package mobileadb
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LogEntry is one logcat line in threadtime format.
+type LogEntry struct {
+	// Time is the device's local time as printed by logcat, e.g.
+	// "06-01 12:34:56.789".
+	Time    string
+	PID     int
+	TID     int
+	Level   string
+	Tag     string
+	Message string
+}
+
+// logLevels are the logcat priorities from lowest to highest.
+const logLevels = "VDIWEF"
+
+var threadtimeLine = regexp.MustCompile(`^(\d\d-\d\d \d\d:\d\d:\d\d\.\d+)\s+(\d+)\s+(\d+)\s+([VDIWEFA])\s(.*?)\s*:(?: (.*))?$`)
+
+// ParseLogcatLine parses a "logcat -v threadtime" line. Lines such as
+// "--------- beginning of main" are not entries.
+func ParseLogcatLine(line string) (LogEntry, bool) {
+	m := threadtimeLine.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if m == nil {
+		return LogEntry{}, false
+	}
+	pid, _ := strconv.Atoi(m[2])
+	tid, _ := strconv.Atoi(m[3])
+	level := m[4]
+	if level == "A" {
+		// Assert is printed as A and ranks with fatal.
+		level = "F"
+	}
+	return LogEntry{Time: m[1], PID: pid, TID: tid, Level: level, Tag: strings.TrimSpace(m[5]), Message: m[6]}, true
+}
+
+// LogLevel normalizes a level given as a letter or a name such as "warn".
+func LogLevel(level string) (string, error) {
+	switch strings.ToLower(level) {
+	case "v", "verbose":
+		return "V", nil
+	case "d", "debug":
+		return "D", nil
+	case "i", "info":
+		return "I", nil
+	case "w", "warn", "warning":
+		return "W", nil
+	case "e", "error":
+		return "E", nil
+	case "f", "fatal", "a", "assert":
+		return "F", nil
+	}
+	return "", fmt.Errorf("invalid log level %q", level)
+}
+
+// AtLeast reports whether the entry's level is level or higher.
+func (e LogEntry) AtLeast(level string) bool {
+	return strings.Index(logLevels, e.Level) >= strings.Index(logLevels, level)
+}
+
+// String formats the entry the way "logcat -v threadtime" prints it.
+func (e LogEntry) String() string {
+	return fmt.Sprintf("%s %5d %5d %s %s: %s", e.Time, e.PID, e.TID, e.Level, e.Tag, e.Message)
+}
+
+var startProc = regexp.MustCompile(`^Start proc (\d+):([^/\s]+)`)
+
+// ProcessStart returns the PID and process name announced by an
+// ActivityManager "Start proc" entry.
+func ProcessStart(e LogEntry) (pid int, process string, ok bool) {
+	if e.Tag != "ActivityManager" {
+		return 0, "", false
+	}
+	m := startProc.FindStringSubmatch(e.Message)
+	if m == nil {
+		return 0, "", false
+	}
+	pid, _ = strconv.Atoi(m[1])
+	return pid, m[2], true
+}
+
+// PIDs returns the PIDs of the running processes of pkg, if any.
+func (d *Device) PIDs(pkg string) ([]int, error) {
+	out, err := d.Shell("pidof " + Quote(pkg))
+	if err != nil {
+		if cmdErr, ok := err.(*CommandError); ok && cmdErr.ExitCode == 1 {
+			// pidof exits 1 when no process matches.
+			return nil, nil
+		}
+		return nil, err
+	}
+	var pids []int
+	for _, field := range strings.Fields(out) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// ClearLogcat clears the log buffers.
+func (d *Device) ClearLogcat() error {
+	_, err := d.Output("logcat", "-c")
+	return err
+}
+
+// Logcat runs "logcat -v threadtime" and calls onEntry for every entry until
+// ctx is done or, with dump, until the buffered log has been printed.
+func (d *Device) Logcat(ctx context.Context, dump bool, onEntry func(LogEntry)) error {
+	args := []string{"-s", d.Serial, "logcat", "-v", "threadtime"}
+	if dump {
+		args = append(args, "-d")
+	}
+	stream := d.Stream
+	if stream == nil {
+		stream = RunContext
+	}
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := stream(ctx, d.ADBPath, args, pw)
+		_ = pw.CloseWithError(err)
+		done <- err
+	}()
+	scanner := bufio.NewScanner(pr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if entry, ok := ParseLogcatLine(scanner.Text()); ok {
+			onEntry(entry)
+		}
+	}
+	// Unblock the writer if the scanner stopped early.
+	_ = pr.Close()
+	return <-done
+}
+
+// RunContext executes adb with stdout written to stdout until it exits or
+// ctx is done. A non-zero exit is returned with adb's stderr; cancellation
+// is not an error.
+func RunContext(ctx context.Context, adbPath string, args []string, stdout io.Writer) error {
+	cmd := exec.CommandContext(ctx, adbPath, args...)
+	var stderr strings.Builder
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package mobileadb
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logcat", func() {
+	It("parses threadtime lines", func() {
+		entry, ok := ParseLogcatLine("06-01 12:34:56.789  1234  1250 W ActivityManager: Slow operation: took 120ms\r")
+		Expect(ok).To(BeTrue())
+		Expect(entry).To(Equal(LogEntry{Time: "06-01 12:34:56.789", PID: 1234, TID: 1250, Level: "W", Tag: "ActivityManager", Message: "Slow operation: took 120ms"}))
+		Expect(entry.String()).To(Equal("06-01 12:34:56.789  1234  1250 W ActivityManager: Slow operation: took 120ms"))
+
+		entry, ok = ParseLogcatLine("06-01 12:34:56.789   10   10 A libc    : ")
+		Expect(ok).To(BeTrue())
+		Expect(entry.Level).To(Equal("F"))
+		Expect(entry.Tag).To(Equal("libc"))
+		Expect(entry.Message).To(BeEmpty())
+
+		_, ok = ParseLogcatLine("--------- beginning of main")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("compares levels", func() {
+		level, err := LogLevel("warn")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level).To(Equal("W"))
+		Expect(LogEntry{Level: "E"}.AtLeast(level)).To(BeTrue())
+		Expect(LogEntry{Level: "I"}.AtLeast(level)).To(BeFalse())
+		_, err = LogLevel("loud")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("recognizes process starts", func() {
+		pid, process, ok := ProcessStart(LogEntry{Tag: "ActivityManager", Message: "Start proc 4321:com.example.app/u0a123 for activity {com.example.app/.Main}"})
+		Expect(ok).To(BeTrue())
+		Expect(pid).To(Equal(4321))
+		Expect(process).To(Equal("com.example.app"))
+		_, _, ok = ProcessStart(LogEntry{Tag: "Other", Message: "Start proc 1:x/u0"})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("streams parsed entries", func() {
+		var args []string
+		device := &Device{ADBPath: "/adb", Serial: LocalSerial(5555), Stream: func(_ context.Context, _ string, a []string, stdout io.Writer) error {
+			args = a
+			_, err := io.WriteString(stdout, "--------- beginning of main\n06-01 12:00:00.000   1   2 I Tag: one\n06-01 12:00:00.001   1   2 E Tag: two\n")
+			return err
+		}}
+		var messages []string
+		Expect(device.Logcat(context.Background(), true, func(e LogEntry) { messages = append(messages, e.Message) })).To(Succeed())
+		Expect(strings.Join(args, " ")).To(Equal("-s 127.0.0.1:5555 logcat -v threadtime -d"))
+		Expect(messages).To(Equal([]string{"one", "two"}))
+	})
+
+	It("returns no PIDs when the package is not running", func() {
+		adb := &fakeADB{replies: map[string]string{"shell pidof 'com.a'": "123 456\n"}, codes: map[string]int{"shell pidof 'com.b'": 1}}
+		device := &Device{ADBPath: "/adb", Serial: LocalSerial(5555), Run: adb.run}
+		Expect(device.PIDs("com.a")).To(Equal([]int{123, 456}))
+		Expect(device.PIDs("com.b")).To(BeEmpty())
+	})
+})
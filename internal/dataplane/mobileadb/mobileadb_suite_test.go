@@ -0,0 +1,13 @@
+package mobileadb
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMobileadb(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mobileadb Suite")
+}
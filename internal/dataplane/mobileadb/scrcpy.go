@@ -0,0 +1,92 @@
+package mobileadb
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// bitRatePattern matches scrcpy bit rates such as 8000000, 800K or 4M.
+var bitRatePattern = regexp.MustCompile(`^[1-9][0-9]*[KkMm]?$`)
+
+// MirrorOptions configures the scrcpy window of a mirrored device.
+type MirrorOptions struct {
+	// BitRate is the video bit rate, for example 4M.
+	BitRate string
+	// MaxSize limits the longer side of the video; 0 keeps the device size.
+	MaxSize int
+	// MaxFPS limits the frame rate; 0 keeps the device rate.
+	MaxFPS int
+	// NoControl disables keyboard and mouse input to the device.
+	NoControl bool
+	// Title is the window title.
+	Title string
+}
+
+// ValidBitRate reports whether rate is a bit rate accepted by scrcpy.
+func ValidBitRate(rate string) bool {
+	return bitRatePattern.MatchString(rate)
+}
+
+// RequireScrcpy resolves the scrcpy executable, preferring SCRCPY_PATH when
+// set.
+func RequireScrcpy() (string, error) {
+	if p := os.Getenv("SCRCPY_PATH"); p != "" {
+		info, err := os.Stat(p)
+		if err != nil {
+			return "", fmt.Errorf("SCRCPY_PATH=%q not accessible: %w", p, err)
+		}
+		if !info.Mode().IsRegular() {
+			return "", fmt.Errorf("SCRCPY_PATH=%q is not a regular file", p)
+		}
+		return p, nil
+	}
+	path, err := exec.LookPath("scrcpy")
+	if err != nil {
+		return "", fmt.Errorf("scrcpy not found in PATH; install scrcpy or set SCRCPY_PATH")
+	}
+	return path, nil
+}
+
+// ScrcpyArgs returns the scrcpy arguments that mirror serial with opts,
+// followed by extra.
+func ScrcpyArgs(serial string, opts MirrorOptions, extra []string) []string {
+	args := []string{"--serial=" + serial}
+	if opts.BitRate != "" {
+		args = append(args, "--video-bit-rate="+opts.BitRate)
+	}
+	if opts.MaxSize > 0 {
+		args = append(args, "--max-size="+strconv.Itoa(opts.MaxSize))
+	}
+	if opts.MaxFPS > 0 {
+		args = append(args, "--max-fps="+strconv.Itoa(opts.MaxFPS))
+	}
+	if opts.NoControl {
+		args = append(args, "--no-control")
+	}
+	if opts.Title != "" {
+		args = append(args, "--window-title="+opts.Title)
+	}
+	return append(args, extra...)
+}
+
+// RunScrcpy runs scrcpy in the foreground with adbPath as its adb executable,
+// so scrcpy talks to the same adb server as the tunnel. It returns scrcpy's
+// exit code.
+func RunScrcpy(scrcpyPath, adbPath string, args []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	cmd := exec.Command(scrcpyPath, args...)
+	cmd.Env = append(os.Environ(), "ADB="+adbPath)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,26 @@
+package mobileadb
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scrcpy", func() {
+	It("builds arguments for a serial", func() {
+		args := ScrcpyArgs("127.0.0.1:5555", MirrorOptions{BitRate: "4M", MaxSize: 1280, MaxFPS: 30, NoControl: true, Title: "ins-1"}, []string{"--stay-awake"})
+		Expect(args).To(Equal([]string{
+			"--serial=127.0.0.1:5555", "--video-bit-rate=4M", "--max-size=1280", "--max-fps=30",
+			"--no-control", "--window-title=ins-1", "--stay-awake",
+		}))
+		Expect(ScrcpyArgs("127.0.0.1:5555", MirrorOptions{}, nil)).To(Equal([]string{"--serial=127.0.0.1:5555"}))
+	})
+
+	It("validates bit rates", func() {
+		for _, rate := range []string{"8000000", "800K", "4M", "2m"} {
+			Expect(ValidBitRate(rate)).To(BeTrue(), rate)
+		}
+		for _, rate := range []string{"", "0", "4G", "M", "-4M", "4.5M"} {
+			Expect(ValidBitRate(rate)).To(BeFalse(), rate)
+		}
+	})
+})
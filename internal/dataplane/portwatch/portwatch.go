@@ -0,0 +1,206 @@
+// Package portwatch detects TCP ports that start or stop listening inside a
+// sandbox.
+package portwatch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-go-sdk/sandbox/code"
+)
+
+const (
+	// ListCommand prints the kernel's TCP socket tables in the sandbox.
+	ListCommand = "cat /proc/net/tcp /proc/net/tcp6 2>/dev/null"
+
+	// DefaultInterval is the time between two polls.
+	DefaultInterval = 2 * time.Second
+
+	// tcpListen is the LISTEN state in /proc/net/tcp.
+	tcpListen = "0A"
+)
+
+// Event types reported by Watch.
+const (
+	EventOpened = "opened"
+	EventClosed = "closed"
+)
+
+// Event reports a port that started or stopped listening.
+type Event struct {
+	Type string
+	Port int
+}
+
+// Range is an inclusive port range.
+type Range struct {
+	Min int
+	Max int
+}
+
+// DefaultRange covers the registered ports, which leaves out the sandbox's
+// own services (envd and the code interpreter listen above 49151).
+var DefaultRange = Range{Min: 1024, Max: 49151}
+
+// ParseRange parses "min-max" or a single port.
+func ParseRange(s string) (Range, error) {
+	minText, maxText, found := strings.Cut(strings.TrimSpace(s), "-")
+	if !found {
+		maxText = minText
+	}
+	lo, err := strconv.Atoi(strings.TrimSpace(minText))
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid port range %q", s)
+	}
+	hi, err := strconv.Atoi(strings.TrimSpace(maxText))
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid port range %q", s)
+	}
+	if lo < 1 || hi > 65535 || lo > hi {
+		return Range{}, fmt.Errorf("invalid port range %q: ports must be between 1 and 65535, low to high", s)
+	}
+	return Range{Min: lo, Max: hi}, nil
+}
+
+// Contains reports whether port is in r.
+func (r Range) Contains(port int) bool {
+	return port >= r.Min && port <= r.Max
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// ParseProcNetTCP returns the sorted, de-duplicated ports in the LISTEN state
+// found in the content of /proc/net/tcp and /proc/net/tcp6.
+func ParseProcNetTCP(data string) []int {
+	seen := map[int]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sl local_address rem_address st ...
+		if len(fields) < 4 || fields[3] != tcpListen {
+			continue
+		}
+		i := strings.LastIndexByte(fields[1], ':')
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.ParseUint(fields[1][i+1:], 16, 16)
+		if err != nil || port == 0 {
+			continue
+		}
+		seen[int(port)] = true
+	}
+	ports := make([]int, 0, len(seen))
+	for port := range seen {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// SandboxLister returns a lister that reads the listening ports of sandbox.
+func SandboxLister(sandbox *code.Sandbox) func(context.Context) ([]int, error) {
+	return func(ctx context.Context) ([]int, error) {
+		result, err := sandbox.Commands.Run(ctx, ListCommand, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list listening ports: %w", err)
+		}
+		return ParseProcNetTCP(string(result.Stdout)), nil
+	}
+}
+
+// Options configures Watch.
+type Options struct {
+	// List returns the ports currently listening in the sandbox.
+	List func(context.Context) ([]int, error)
+	// Interval is the time between polls; defaults to DefaultInterval.
+	Interval time.Duration
+	// Range limits the reported ports; the zero value selects DefaultRange.
+	Range Range
+	// OnEvent is called, from the watching goroutine, for every change. An
+	// error for an opened port leaves it out of the open set, so the next
+	// poll reports it again.
+	OnEvent func(Event) error
+	// OnError is called when a poll fails. The previous state is kept, so a
+	// transient failure does not report every port as closed.
+	OnError func(error)
+}
+
+// Watch polls the sandbox until ctx is done, reporting ports in range that
+// open or close. The first poll runs immediately and reports every port that
+// is already listening.
+func Watch(ctx context.Context, opts Options) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	r := opts.Range
+	if r == (Range{}) {
+		r = DefaultRange
+	}
+	open := map[int]bool{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ports, err := opts.List(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+		} else {
+			for _, event := range diff(open, ports, r) {
+				if err := opts.OnEvent(event); err != nil && event.Type == EventOpened {
+					delete(open, event.Port)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// diff updates open to ports and returns the changes, closed ports first.
+func diff(open map[int]bool, ports []int, r Range) []Event {
+	current := map[int]bool{}
+	for _, port := range ports {
+		if r.Contains(port) {
+			current[port] = true
+		}
+	}
+	var closed, opened []int
+	for port := range open {
+		if !current[port] {
+			closed = append(closed, port)
+			delete(open, port)
+		}
+	}
+	for port := range current {
+		if !open[port] {
+			opened = append(opened, port)
+			open[port] = true
+		}
+	}
+	sort.Ints(closed)
+	sort.Ints(opened)
+	events := make([]Event, 0, len(closed)+len(opened))
+	for _, port := range closed {
+		events = append(events, Event{Type: EventClosed, Port: port})
+	}
+	for _, port := range opened {
+		events = append(events, Event{Type: EventOpened, Port: port})
+	}
+	return events
+}
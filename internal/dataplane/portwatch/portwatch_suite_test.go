@@ -0,0 +1,13 @@
+package portwatch
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPortwatch(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Portwatch Suite")
+}
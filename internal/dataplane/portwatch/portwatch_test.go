@@ -0,0 +1,140 @@
+package portwatch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0BB8 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:0BB8 0100007F:A1B2 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:C34F 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+   1: 00000000000000000000000000000000:0BB8 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2002 1 0000000000000000 100 0 0 10 0
+`
+
+var _ = Describe("Portwatch", func() {
+	It("parses listening ports from /proc/net/tcp and tcp6", func() {
+		Expect(ParseProcNetTCP(procNetTCP)).To(Equal([]int{3000, 8080, 49999}))
+		Expect(ParseProcNetTCP("")).To(BeEmpty())
+	})
+
+	It("parses port ranges", func() {
+		r, err := ParseRange("3000-3999")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r).To(Equal(Range{Min: 3000, Max: 3999}))
+		Expect(r.Contains(3000)).To(BeTrue())
+		Expect(r.Contains(4000)).To(BeFalse())
+		r, err = ParseRange("8080")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.String()).To(Equal("8080-8080"))
+		for _, bad := range []string{"", "a-b", "0-10", "10-5", "1-70000"} {
+			_, err := ParseRange(bad)
+			Expect(err).To(HaveOccurred(), bad)
+		}
+	})
+
+	It("reports opened and closed ports in range", func() {
+		open := map[int]bool{}
+		Expect(diff(open, []int{22, 3000, 8080, 49999}, DefaultRange)).To(Equal([]Event{
+			{Type: EventOpened, Port: 3000}, {Type: EventOpened, Port: 8080},
+		}))
+		Expect(diff(open, []int{3000, 5173}, DefaultRange)).To(Equal([]Event{
+			{Type: EventClosed, Port: 8080}, {Type: EventOpened, Port: 5173},
+		}))
+		Expect(diff(open, []int{3000, 5173}, DefaultRange)).To(BeEmpty())
+	})
+
+	It("polls until cancelled and keeps state across failed polls", func() {
+		var mu sync.Mutex
+		polls := [][]int{{3000}, nil, {8080}}
+		var events []Event
+		var errs int
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Watch(ctx, Options{
+				Interval: time.Millisecond,
+				List: func(context.Context) ([]int, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					if len(polls) == 0 {
+						return []int{8080}, nil
+					}
+					next := polls[0]
+					polls = polls[1:]
+					if next == nil {
+						return nil, errors.New("exec failed")
+					}
+					return next, nil
+				},
+				OnEvent: func(e Event) error {
+					mu.Lock()
+					defer mu.Unlock()
+					events = append(events, e)
+					return nil
+				},
+				OnError: func(error) {
+					mu.Lock()
+					defer mu.Unlock()
+					errs++
+				},
+			})
+		}()
+		Eventually(func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(events)
+		}).Should(Equal(3))
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(events).To(Equal([]Event{
+			{Type: EventOpened, Port: 3000}, {Type: EventClosed, Port: 3000}, {Type: EventOpened, Port: 8080},
+		}))
+		Expect(errs).To(Equal(1))
+	})
+
+	It("reports a port again after its opened event failed", func() {
+		var mu sync.Mutex
+		var events []Event
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Watch(ctx, Options{
+				Interval: time.Millisecond,
+				List:     func(context.Context) ([]int, error) { return []int{3000}, nil },
+				OnEvent: func(e Event) error {
+					mu.Lock()
+					defer mu.Unlock()
+					events = append(events, e)
+					if len(events) < 3 {
+						return errors.New("port busy")
+					}
+					return nil
+				},
+			})
+		}()
+		Eventually(func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(events)
+		}).Should(Equal(3))
+		// Once the event succeeds, the open port is not reported again.
+		Consistently(func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(events)
+		}, 20*time.Millisecond).Should(Equal(3))
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(events).To(HaveEach(Event{Type: EventOpened, Port: 3000}))
+	})
+})
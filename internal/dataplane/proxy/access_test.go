@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Access", func() {
+	var forwarded *http.Request
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		w.WriteHeader(http.StatusNoContent)
+	})
+	serve := func(a Access, r *http.Request) *httptest.ResponseRecorder {
+		forwarded = nil
+		rec := httptest.NewRecorder()
+		a.guard(next).ServeHTTP(rec, r)
+		return rec
+	}
+
+	It("requires the bearer token and strips it before forwarding", func() {
+		a := Access{BearerToken: "secret"}
+		Expect(a.Enabled()).To(BeTrue())
+		rec := serve(a, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rec.Header().Get("WWW-Authenticate")).To(HavePrefix("Bearer"))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer wrong")
+		Expect(serve(a, req).Code).To(Equal(http.StatusUnauthorized))
+
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		Expect(serve(a, req).Code).To(Equal(http.StatusNoContent))
+		Expect(forwarded.Header.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("accepts basic auth", func() {
+		a := Access{BasicUser: "agr", BasicPassword: "pw"}
+		rec := serve(a, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(rec.Header().Get("WWW-Authenticate")).To(HavePrefix("Basic"))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth("agr", "pw")
+		Expect(serve(a, req).Code).To(Equal(http.StatusNoContent))
+	})
+
+	It("rejects clients outside the allowlist", func() {
+		nets, err := ParseAllowList([]string{"10.0.0.0/8", "192.168.1.5", "::1"})
+		Expect(err).NotTo(HaveOccurred())
+		a := Access{AllowedNets: nets}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.1.2.3:5000"
+		Expect(serve(a, req).Code).To(Equal(http.StatusNoContent))
+		req.RemoteAddr = "192.168.1.5:5000"
+		Expect(serve(a, req).Code).To(Equal(http.StatusNoContent))
+		req.RemoteAddr = "[::1]:5000"
+		Expect(serve(a, req).Code).To(Equal(http.StatusNoContent))
+		req.RemoteAddr = "192.168.1.6:5000"
+		Expect(serve(a, req).Code).To(Equal(http.StatusForbidden))
+
+		_, err = ParseAllowList([]string{"not-an-ip"})
+		Expect(err).To(MatchError(ContainSubstring("invalid IP address")))
+		_, err = ParseAllowList([]string{"10.0.0.0/99"})
+		Expect(err).To(MatchError(ContainSubstring("invalid network")))
+	})
+
+	It("serves the local listener over TLS with a self-signed certificate", func() {
+		requireLocalListen()
+		certPEM, keyPEM, err := SelfSignedCertificate([]string{"localhost", "127.0.0.1"})
+		Expect(err).NotTo(HaveOccurred())
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Fingerprint(pair)).To(HaveLen(95))
+
+		p, err := New(Options{
+			InstanceID: "sandbox-test", Domain: "ap-guangzhou.tencentags.com", RemotePort: 3000, Token: "token",
+			ListenAddress: "127.0.0.1:0", Logger: log.New(GinkgoWriter, "", 0),
+			Access:    Access{BearerToken: "secret"},
+			TLSConfig: &tls.Config{Certificates: []tls.Certificate{pair}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		addr, err := p.Start()
+		Expect(err).NotTo(HaveOccurred())
+		defer p.Stop()
+
+		block, _ := pem.Decode(certPEM)
+		cert, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		pool := x509.NewCertPool()
+		pool.AddCert(cert)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+		resp, err := client.Get("https://" + addr + "/")
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
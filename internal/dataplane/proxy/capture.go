@@ -0,0 +1,526 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultHARBodyLimit caps the bytes of each request and response body
+	// kept in a HAR capture.
+	DefaultHARBodyLimit = 256 << 10
+
+	// DefaultHAREntryLimit caps the number of requests kept in a HAR capture;
+	// older entries are dropped first.
+	DefaultHAREntryLimit = 5000
+
+	// DefaultHARBudget caps the body bytes kept across all entries of a HAR
+	// capture. Past it, new entries are still recorded but without bodies.
+	DefaultHARBudget = 64 << 20
+
+	// DefaultHARSaveInterval is how often a changed capture is saved while
+	// the proxy runs.
+	DefaultHARSaveInterval = 10 * time.Second
+
+	// redacted replaces secret header values in captures.
+	redacted = "REDACTED"
+)
+
+// AccessLogEntry is one line of the NDJSON access log.
+type AccessLogEntry struct {
+	Time       time.Time `json:"Time"`
+	Method     string    `json:"Method"`
+	Url        string    `json:"Url"`
+	Status     int       `json:"Status"`
+	Bytes      int64     `json:"Bytes"`
+	LatencyMs  float64   `json:"LatencyMs"`
+	WebSocket  bool      `json:"WebSocket"`
+	RemoteAddr string    `json:"RemoteAddr"`
+	Upstream   string    `json:"Upstream"`
+}
+
+// AccessLog writes one NDJSON entry per proxied request. It is safe for
+// concurrent use, so several proxies can share one log.
+type AccessLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewAccessLog returns an access log writing to w.
+func NewAccessLog(w io.Writer) *AccessLog {
+	return &AccessLog{enc: json.NewEncoder(w)}
+}
+
+func (l *AccessLog) write(entry AccessLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_ = l.enc.Encode(entry)
+}
+
+// HARRecorder collects proxied requests and responses and saves them as a
+// HAR 1.2 file that browser devtools can open. Bodies are truncated to the
+// body limit, bodies are omitted once the kept ones reach the budget, and
+// the sandbox access token is redacted. It is safe for concurrent use, so
+// several proxies can share one recorder.
+type HARRecorder struct {
+	bodyLimit  int
+	entryLimit int
+	budget     int
+
+	mu        sync.Mutex
+	entries   []harEntry
+	bodyBytes int
+	dropped   int
+	omitted   int
+	added     int
+	saved     int
+}
+
+// NewHARRecorder returns a recorder; zero limits select the defaults.
+func NewHARRecorder(bodyLimit, entryLimit, budget int) *HARRecorder {
+	if bodyLimit <= 0 {
+		bodyLimit = DefaultHARBodyLimit
+	}
+	if entryLimit <= 0 {
+		entryLimit = DefaultHAREntryLimit
+	}
+	if budget <= 0 {
+		budget = DefaultHARBudget
+	}
+	return &HARRecorder{bodyLimit: bodyLimit, entryLimit: entryLimit, budget: budget}
+}
+
+// Len returns the number of recorded requests.
+func (h *HARRecorder) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entries)
+}
+
+func (h *HARRecorder) add(entry harEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.entries) >= h.entryLimit {
+		h.bodyBytes -= h.entries[0].bodyBytes
+		h.entries = h.entries[1:]
+		h.dropped++
+	}
+	if h.bodyBytes+entry.bodyBytes > h.budget {
+		entry.omitBodies(fmt.Sprintf("body omitted: capture exceeded its %d MiB body budget", h.budget>>20))
+		h.omitted++
+	}
+	h.bodyBytes += entry.bodyBytes
+	h.entries = append(h.entries, entry)
+	h.added++
+}
+
+// SaveEvery saves the capture to path every interval while requests keep
+// arriving, so that a proxy killed without a graceful stop (e.g. a
+// background proxy on Windows) still leaves its capture behind. Errors are
+// left to the final Save. The returned function stops saving and returns
+// once any save in progress has finished.
+func (h *HARRecorder) SaveEvery(path string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				h.mu.Lock()
+				changed := h.added != h.saved
+				h.mu.Unlock()
+				if changed {
+					_ = h.Save(path)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
+
+// Save writes the capture to path, replacing it atomically.
+func (h *HARRecorder) Save(path string) error {
+	h.mu.Lock()
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "agr instance proxy", Version: "1"},
+		Entries: append([]harEntry{}, h.entries...),
+	}}
+	var comments []string
+	if h.dropped > 0 {
+		comments = append(comments, fmt.Sprintf("%d older entries were dropped", h.dropped))
+	}
+	if h.omitted > 0 {
+		comments = append(comments, fmt.Sprintf("bodies of %d entries were omitted", h.omitted))
+	}
+	doc.Log.Comment = strings.Join(comments, "; ")
+	added := h.added
+	h.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".har-*")
+	if err != nil {
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+	h.mu.Lock()
+	h.saved = max(h.saved, added)
+	h.mu.Unlock()
+	return nil
+}
+
+// capture wraps next with access logging and HAR recording.
+func (p *Proxy) capture(next http.Handler) http.Handler {
+	accessLog, har := p.options.AccessLog, p.options.HAR
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		url := fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.RequestURI())
+		websocket := isWebSocketRequest(r)
+
+		var reqHeader http.Header
+		var reqBody *cappedBuffer
+		rec := &recordingWriter{ResponseWriter: w}
+		if har != nil {
+			reqHeader = p.redact(r.Header)
+			if r.Body != nil && r.Body != http.NoBody {
+				reqBody = &cappedBuffer{limit: har.bodyLimit}
+				r.Body = &teeBody{ReadCloser: r.Body, buf: reqBody}
+			}
+			rec.body = &cappedBuffer{limit: har.bodyLimit}
+		}
+
+		next.ServeHTTP(rec, r)
+
+		elapsed := time.Since(start)
+		status := rec.status
+		switch {
+		case rec.hijacked:
+			status = http.StatusSwitchingProtocols
+		case status == 0:
+			status = http.StatusOK
+		}
+		if accessLog != nil {
+			accessLog.write(AccessLogEntry{
+				Time:       start.UTC(),
+				Method:     r.Method,
+				Url:        url,
+				Status:     status,
+				Bytes:      rec.bytes,
+				LatencyMs:  float64(elapsed.Microseconds()) / 1000,
+				WebSocket:  websocket,
+				RemoteAddr: r.RemoteAddr,
+				Upstream:   p.targetHost,
+			})
+		}
+		if har != nil {
+			har.add(newHAREntry(start, elapsed, r, url, reqHeader, reqBody, status, p.redact(rec.Header()), rec))
+		}
+	})
+}
+
+// redact returns a copy of header with the sandbox access token, and the
+// local proxy credential when one is configured, replaced.
+func (p *Proxy) redact(header http.Header) http.Header {
+	out := header.Clone()
+	if out.Get("X-Access-Token") != "" {
+		out.Set("X-Access-Token", redacted)
+	}
+	if (p.options.Access.BearerToken != "" || p.options.Access.BasicUser != "") && out.Get("Authorization") != "" {
+		out.Set("Authorization", redacted)
+	}
+	return out
+}
+
+// recordingWriter records the status, size and (capped) body of a response.
+type recordingWriter struct {
+	http.ResponseWriter
+	status   int
+	bytes    int64
+	body     *cappedBuffer
+	hijacked bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	if w.body != nil {
+		w.body.write(b[:n])
+	}
+	return n, err
+}
+
+// Flush keeps streaming responses (e.g. server-sent events) flowing.
+func (w *recordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades take over the connection.
+func (w *recordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	w.hijacked = true
+	return h.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// cappedBuffer keeps the first limit bytes written and counts the rest.
+type cappedBuffer struct {
+	limit int
+	data  []byte
+	total int64
+}
+
+func (b *cappedBuffer) write(p []byte) {
+	b.total += int64(len(p))
+	if room := b.limit - len(b.data); room > 0 {
+		if len(p) > room {
+			p = p[:room]
+		}
+		b.data = append(b.data, p...)
+	}
+}
+
+func (b *cappedBuffer) truncated() bool {
+	return b.total > int64(len(b.data))
+}
+
+// teeBody copies a request body into buf as it is read.
+type teeBody struct {
+	io.ReadCloser
+	buf *cappedBuffer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.buf.write(p[:n])
+	return n, err
+}
+
+// HAR 1.2 document types (http://www.softwareishard.com/blog/har-12-spec/).
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+	Comment string     `json:"comment,omitempty"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+
+	// bodyBytes counts the body text kept, for the capture's budget.
+	bodyBytes int
+}
+
+// omitBodies drops the request and response body text, noting why.
+func (e *harEntry) omitBodies(reason string) {
+	if e.Request.PostData != nil && e.Request.PostData.Text != "" {
+		e.Request.PostData.Text = ""
+		e.Request.PostData.Comment = reason
+	}
+	if e.Response.Content.Text != "" {
+		e.Response.Content.Text = ""
+		e.Response.Content.Encoding = ""
+		e.Response.Content.Comment = reason
+	}
+	e.bodyBytes = 0
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func newHAREntry(start time.Time, elapsed time.Duration, r *http.Request, url string, reqHeader http.Header, reqBody *cappedBuffer, status int, respHeader http.Header, rec *recordingWriter) harEntry {
+	ms := float64(elapsed.Microseconds()) / 1000
+	entry := harEntry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: harRequest{
+			Method:      r.Method,
+			URL:         url,
+			HTTPVersion: r.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(reqHeader),
+			QueryString: harQuery(r),
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: harResponse{
+			Status:      status,
+			StatusText:  http.StatusText(status),
+			HTTPVersion: r.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(respHeader),
+			Content:     harContent{Size: rec.bytes, MimeType: respHeader.Get("Content-Type")},
+			RedirectURL: respHeader.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    rec.bytes,
+		},
+		Timings: harTimings{Send: 0, Wait: ms, Receive: 0},
+	}
+	if reqBody != nil {
+		entry.Request.BodySize = reqBody.total
+		post := &harPostData{MimeType: reqHeader.Get("Content-Type")}
+		if utf8.Valid(reqBody.data) {
+			post.Text = string(reqBody.data)
+		} else {
+			post.Comment = "binary body omitted"
+		}
+		if reqBody.truncated() {
+			post.Comment = fmt.Sprintf("body truncated to %d of %d bytes", len(reqBody.data), reqBody.total)
+		}
+		entry.Request.PostData = post
+	}
+	if rec.body != nil && len(rec.body.data) > 0 {
+		if utf8.Valid(rec.body.data) {
+			entry.Response.Content.Text = string(rec.body.data)
+		} else {
+			entry.Response.Content.Text = base64.StdEncoding.EncodeToString(rec.body.data)
+			entry.Response.Content.Encoding = "base64"
+		}
+		if rec.body.truncated() {
+			entry.Response.Content.Comment = fmt.Sprintf("body truncated to %d of %d bytes", len(rec.body.data), rec.body.total)
+		}
+	}
+	if entry.Request.PostData != nil {
+		entry.bodyBytes += len(entry.Request.PostData.Text)
+	}
+	entry.bodyBytes += len(entry.Response.Content.Text)
+	if rec.hijacked {
+		entry.Comment = "WebSocket connection; frames are not captured"
+	}
+	return entry
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []harNameValue{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			out = append(out, harNameValue{Name: name, Value: value})
+		}
+	}
+	return out
+}
+
+func harQuery(r *http.Request) []harNameValue {
+	return harHeaders(http.Header(r.URL.Query()))
+}
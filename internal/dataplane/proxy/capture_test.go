@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capture", func() {
+	newProxy := func(opts Options) *Proxy {
+		opts.InstanceID, opts.Domain, opts.Token, opts.RemotePort = "sandbox-1", "example.com", "tok", 3000
+		p, err := New(opts)
+		Expect(err).NotTo(HaveOccurred())
+		return p
+	}
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(append([]byte("echo:"), body...))
+	})
+
+	It("writes one NDJSON access log entry per request", func() {
+		var buf bytes.Buffer
+		p := newProxy(Options{AccessLog: NewAccessLog(&buf)})
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:3000/api?q=1", strings.NewReader("hi"))
+		p.capture(echo).ServeHTTP(httptest.NewRecorder(), req)
+		req = httptest.NewRequest(http.MethodGet, "http://localhost:3000/ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		p.capture(echo).ServeHTTP(httptest.NewRecorder(), req)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		var entry AccessLogEntry
+		Expect(json.Unmarshal([]byte(lines[0]), &entry)).To(Succeed())
+		Expect(entry.Method).To(Equal(http.MethodPost))
+		Expect(entry.Url).To(Equal("http://localhost:3000/api?q=1"))
+		Expect(entry.Status).To(Equal(http.StatusCreated))
+		Expect(entry.Bytes).To(Equal(int64(len("echo:hi"))))
+		Expect(entry.Upstream).To(Equal("3000-sandbox-1.example.com"))
+		Expect(entry.WebSocket).To(BeFalse())
+		Expect(json.Unmarshal([]byte(lines[1]), &entry)).To(Succeed())
+		Expect(entry.WebSocket).To(BeTrue())
+	})
+
+	It("records a HAR file with bodies and redacted secrets", func() {
+		har := NewHARRecorder(4, 0, 0)
+		p := newProxy(Options{HAR: har, Access: Access{BearerToken: "local"}})
+		req := httptest.NewRequest(http.MethodPost, "http://localhost:3000/api?q=1", strings.NewReader("hello"))
+		req.Header.Set("X-Access-Token", "tok")
+		req.Header.Set("Authorization", "Bearer local")
+		p.capture(p.options.Access.guard(echo)).ServeHTTP(httptest.NewRecorder(), req)
+		Expect(har.Len()).To(Equal(1))
+
+		path := filepath.Join(GinkgoT().TempDir(), "session.har")
+		Expect(har.Save(path)).To(Succeed())
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("Bearer local"))
+		Expect(string(data)).NotTo(ContainSubstring(`"tok"`))
+
+		var doc harDocument
+		Expect(json.Unmarshal(data, &doc)).To(Succeed())
+		Expect(doc.Log.Version).To(Equal("1.2"))
+		Expect(doc.Log.Entries).To(HaveLen(1))
+		entry := doc.Log.Entries[0]
+		Expect(entry.Request.Headers).To(ContainElement(harNameValue{Name: "X-Access-Token", Value: redacted}))
+		Expect(entry.Request.QueryString).To(ConsistOf(harNameValue{Name: "q", Value: "1"}))
+		Expect(entry.Request.PostData.Text).To(Equal("hell"))
+		Expect(entry.Request.BodySize).To(Equal(int64(5)))
+		Expect(entry.Response.Status).To(Equal(http.StatusCreated))
+		Expect(entry.Response.Content.Text).To(Equal("echo"))
+		Expect(entry.Response.Content.Comment).To(ContainSubstring("truncated"))
+	})
+
+	It("keeps only the most recent HAR entries", func() {
+		har := NewHARRecorder(0, 2, 0)
+		p := newProxy(Options{HAR: har})
+		for _, path := range []string{"/a", "/b", "/c"} {
+			p.capture(echo).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:3000"+path, nil))
+		}
+		Expect(har.Len()).To(Equal(2))
+		Expect(har.entries[0].Request.URL).To(HaveSuffix("/b"))
+		Expect(har.dropped).To(Equal(1))
+	})
+
+	It("omits bodies but keeps entries once the body budget is spent", func() {
+		har := NewHARRecorder(0, 2, 20)
+		p := newProxy(Options{HAR: har})
+		for _, body := range []string{"first", "second", "third"} {
+			req := httptest.NewRequest(http.MethodPost, "http://localhost:3000/"+body, strings.NewReader(body))
+			p.capture(echo).ServeHTTP(httptest.NewRecorder(), req)
+		}
+		Expect(har.Len()).To(Equal(2))
+		omitted := har.entries[0]
+		Expect(omitted.Request.URL).To(HaveSuffix("/second"))
+		Expect(omitted.Request.PostData.Text).To(BeEmpty())
+		Expect(omitted.Request.PostData.Comment).To(ContainSubstring("body budget"))
+		Expect(omitted.Response.Content.Text).To(BeEmpty())
+		Expect(omitted.Response.Content.Size).To(Equal(int64(len("echo:second"))))
+		// Dropping the oldest entry freed its share of the budget.
+		Expect(har.entries[1].Request.PostData.Text).To(Equal("third"))
+		Expect(har.entries[1].Response.Content.Text).To(Equal("echo:third"))
+		Expect(har.bodyBytes).To(Equal(len("third") + len("echo:third")))
+
+		path := filepath.Join(GinkgoT().TempDir(), "session.har")
+		Expect(har.Save(path)).To(Succeed())
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		var doc harDocument
+		Expect(json.Unmarshal(data, &doc)).To(Succeed())
+		Expect(doc.Log.Comment).To(Equal("1 older entries were dropped; bodies of 1 entries were omitted"))
+	})
+
+	It("saves the capture periodically while requests arrive", func() {
+		har := NewHARRecorder(0, 0, 0)
+		p := newProxy(Options{HAR: har})
+		path := filepath.Join(GinkgoT().TempDir(), "session.har")
+		stop := har.SaveEvery(path, 10*time.Millisecond)
+		defer stop()
+
+		p.capture(echo).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:3000/a", nil))
+		entries := func() int {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return 0
+			}
+			var doc harDocument
+			if json.Unmarshal(data, &doc) != nil {
+				return 0
+			}
+			return len(doc.Log.Entries)
+		}
+		Eventually(entries).Should(Equal(1))
+		p.capture(echo).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:3000/b", nil))
+		Eventually(entries).Should(Equal(2))
+
+		stop()
+		Expect(os.Remove(path)).To(Succeed())
+		p.capture(echo).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:3000/c", nil))
+		Consistently(func() bool {
+			_, err := os.Stat(path)
+			return os.IsNotExist(err)
+		}, 50*time.Millisecond).Should(BeTrue())
+	})
+})
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"path"
 	"strings"
 	"sync"
 	"time"
@@ -26,14 +27,45 @@ type Options struct {
 	InstanceID string // e.g. "sandbox-xxx"
 	Domain     string // e.g. "ap-guangzhou.tencentags.com" (region-qualified)
 	RemotePort int    // Port on the remote sandbox to proxy to
-	// Token is the access token for the sandbox. Its lifetime is bound to the
-	// sandbox instance lifecycle — it stays valid as long as the instance is
-	// running, so no refresh or 401-retry logic is required.
-	Token         string
-	ListenAddress string      // e.g. "127.0.0.1:3000"
-	Logger        *log.Logger // Optional logger; defaults to log.Default()
-	Insecure      bool        // Skip TLS verification
-	Verbose       bool        // Enable verbose request logging
+	// Token is the initial access token for the sandbox. Tokens can change
+	// when an instance is paused and resumed or its token is rotated, so
+	// long-running proxies should also set TokenProvider.
+	Token string
+	// TokenProvider returns the access token. It is called when Token is empty
+	// and again whenever the sandbox gateway rejects the current token with
+	// 401; idempotent requests without a body are then retried once and
+	// WebSocket handshakes are re-dialed.
+	TokenProvider func() (string, error)
+	// InvalidateToken, when set, is called before TokenProvider is asked for a
+	// replacement so that it can drop a cached token.
+	InvalidateToken func()
+	ListenAddress   string      // e.g. "127.0.0.1:3000"
+	Logger          *log.Logger // Optional logger; defaults to log.Default()
+	Insecure        bool        // Skip TLS verification
+	Verbose         bool        // Enable verbose request logging
+
+	// Access restricts who may use the local listener; the zero value allows
+	// everyone.
+	Access Access
+	// AllowedOrigins lists the browser origins, besides pages on the proxy's
+	// own host name, that may open WebSockets through the proxy; "*" allows
+	// any.
+	AllowedOrigins []string
+	// TLSConfig, when set, makes the local listener serve HTTPS.
+	TLSConfig *tls.Config
+	// Paths, when set, limits forwarding to these paths; an entry ending in
+	// "/" covers everything below it. Other requests are answered with 404.
+	Paths []string
+	// LocalHostsOnly refuses requests whose Host header is not localhost, a
+	// subdomain of localhost or an IP address, with 403. Loopback listeners
+	// that hand out control of a sandbox set it against DNS rebinding, which
+	// the origin check cannot catch.
+	LocalHostsOnly bool
+
+	// AccessLog, when set, receives one NDJSON entry per request.
+	AccessLog *AccessLog
+	// HAR, when set, records requests and responses for a HAR file.
+	HAR *HARRecorder
 }
 
 // Proxy manages an active HTTP/WebSocket reverse proxy that forwards local
@@ -45,12 +77,13 @@ type Proxy struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	logger     *log.Logger
+	tokens     *tokenSource
 	targetHost string // e.g. "3000-sandbox-xxx.ap-guangzhou.tencentags.com"
 }
 
 // New creates and initializes a new port-forward proxy but does not start it.
 func New(opts Options) (*Proxy, error) {
-	if opts.InstanceID == "" || opts.Token == "" || opts.Domain == "" {
+	if opts.InstanceID == "" || (opts.Token == "" && opts.TokenProvider == nil) || opts.Domain == "" {
 		return nil, fmt.Errorf("instanceID, token, and domain are required")
 	}
 	if opts.RemotePort <= 0 || opts.RemotePort > 65535 {
@@ -74,6 +107,7 @@ func New(opts Options) (*Proxy, error) {
 		ctx:        ctx,
 		cancel:     cancel,
 		logger:     logger,
+		tokens:     newTokenSource(opts, logger),
 		targetHost: targetHost,
 	}, nil
 }
@@ -86,7 +120,41 @@ func (p *Proxy) Start() (string, error) {
 		return "", fmt.Errorf("failed to bind local address: %w", err)
 	}
 	p.listener = listener
+	if p.options.TLSConfig != nil {
+		listener = tls.NewListener(listener, p.options.TLSConfig)
+	}
+	p.serve(listener)
+	return listener.Addr().String(), nil
+}
+
+// serve starts serving proxy requests accepted by listener in the background.
+func (p *Proxy) serve(listener net.Listener) {
+	p.server = &http.Server{
+		Handler:           p.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// ReadTimeout is intentionally omitted for the HTTP server: setting it
+		// would also cap WebSocket connections (which are long-lived upgrades).
+		// ReadHeaderTimeout alone is sufficient to mitigate Slowloris on the
+		// handshake phase. For the HTTP-only path, the upstream
+		// ResponseHeaderTimeout on the transport provides an additional bound.
+		BaseContext: func(_ net.Listener) context.Context {
+			return p.ctx
+		},
+	}
+
+	p.logger.Printf("Proxy listening on %s (forwarding to https://%s)", listener.Addr().String(), p.targetHost)
+
+	go func() {
+		if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			p.logger.Printf("[ERROR] Server error: %v", err)
+		}
+	}()
+}
 
+// Handler returns the HTTP handler that forwards requests and WebSocket
+// upgrades to the sandbox port without binding a listener, so that callers
+// such as the multi-instance gateway can mount it in their own server.
+func (p *Proxy) Handler() http.Handler {
 	// Build the HTTP reverse proxy
 	targetURL := &url.URL{
 		Scheme: "https",
@@ -95,25 +163,27 @@ func (p *Proxy) Start() (string, error) {
 
 	reverseProxy := httputil.NewSingleHostReverseProxy(targetURL)
 
-	// Customize the transport for TLS
-	reverseProxy.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: p.options.Insecure, //nolint:gosec
+	// Customize the transport for TLS; tokenTransport injects the access
+	// token and refreshes it on 401.
+	reverseProxy.Transport = &tokenTransport{
+		base: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: p.options.Insecure, //nolint:gosec
+			},
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
 		},
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 30 * time.Second,
+		tokens: p.tokens,
 	}
 
-	// Customize the Director to inject token and fix Host header
+	// Customize the Director to fix the Host header
 	originalDirector := reverseProxy.Director
 	reverseProxy.Director = func(req *http.Request) {
 		originalDirector(req)
 		// Set the correct Host header (changeOrigin equivalent)
 		req.Host = p.targetHost
-		// Inject access token. The sandbox gateway authenticates via X-Access-Token only.
-		req.Header.Set("X-Access-Token", p.options.Token)
 		if p.options.Verbose {
 			p.logger.Printf("[HTTP] %s %s", req.Method, req.URL.Path)
 		}
@@ -139,12 +209,20 @@ func (p *Proxy) Start() (string, error) {
 		ReadBufferSize:  65536,
 		WriteBufferSize: 65536,
 		CheckOrigin: func(r *http.Request) bool {
-			return true // Allow all origins for local proxy
+			return checkOrigin(r, p.options.AllowedOrigins)
 		},
 	}
 
 	// Build the HTTP handler that routes between HTTP proxy and WebSocket proxy
 	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.options.LocalHostsOnly && !LocalHost(r.Host) {
+			http.Error(w, "Host header is not an IP address or localhost", http.StatusForbidden)
+			return
+		}
+		if len(p.options.Paths) > 0 && !pathAllowed(r.URL.Path, p.options.Paths) {
+			http.NotFound(w, r)
+			return
+		}
 		if isWebSocketRequest(r) {
 			p.handleWebSocket(w, r, wsUpgrader)
 			return
@@ -152,28 +230,14 @@ func (p *Proxy) Start() (string, error) {
 		reverseProxy.ServeHTTP(w, r)
 	})
 
-	p.server = &http.Server{
-		Handler:           mux,
-		ReadHeaderTimeout: 10 * time.Second,
-		// ReadTimeout is intentionally omitted for the HTTP server: setting it
-		// would also cap WebSocket connections (which are long-lived upgrades).
-		// ReadHeaderTimeout alone is sufficient to mitigate Slowloris on the
-		// handshake phase. For the HTTP-only path, the upstream
-		// ResponseHeaderTimeout on the transport provides an additional bound.
-		BaseContext: func(_ net.Listener) context.Context {
-			return p.ctx
-		},
+	var handler http.Handler = mux
+	if p.options.Access.Enabled() {
+		handler = p.options.Access.guard(mux)
 	}
-
-	p.logger.Printf("Proxy listening on %s (forwarding to https://%s)", listener.Addr().String(), p.targetHost)
-
-	go func() {
-		if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
-			p.logger.Printf("[ERROR] Server error: %v", err)
-		}
-	}()
-
-	return listener.Addr().String(), nil
+	if p.options.AccessLog != nil || p.options.HAR != nil {
+		handler = p.capture(handler)
+	}
+	return handler
 }
 
 // LocalAddr returns the listener's local address, or empty string if not started.
@@ -200,10 +264,8 @@ func (p *Proxy) handleWebSocket(w http.ResponseWriter, r *http.Request, upgrader
 	// Build upstream WebSocket URL
 	upstreamURL := fmt.Sprintf("wss://%s%s", p.targetHost, r.URL.RequestURI())
 
-	// Prepare upstream headers with the pre-acquired token.
-	// The sandbox gateway authenticates via X-Access-Token only.
+	// Prepare upstream headers; the token is added per dial attempt.
 	upstreamHeaders := http.Header{}
-	upstreamHeaders.Set("X-Access-Token", p.options.Token)
 	upstreamHeaders.Set("Host", p.targetHost)
 
 	// Copy relevant headers from the original request
@@ -224,7 +286,11 @@ func (p *Proxy) handleWebSocket(w http.ResponseWriter, r *http.Request, upgrader
 		},
 	}
 
-	upstreamConn, upstreamResp, err := dialer.DialContext(p.ctx, upstreamURL, upstreamHeaders)
+	upstreamConn, upstreamResp, err := p.tokens.dialWithToken(func(token string) (*websocket.Conn, *http.Response, error) {
+		// The sandbox gateway authenticates via X-Access-Token only.
+		upstreamHeaders.Set("X-Access-Token", token)
+		return dialer.DialContext(p.ctx, upstreamURL, upstreamHeaders)
+	})
 	// Close the HTTP response body if present (dial failure with a non-101 HTTP response).
 	if upstreamResp != nil && upstreamResp.Body != nil {
 		defer func() { _ = upstreamResp.Body.Close() }()
@@ -328,6 +394,71 @@ func (p *Proxy) bridgeWebSocket(src, dst *websocket.Conn, direction string) {
 	}
 }
 
+// pathAllowed reports whether the request path p is one of paths or below an
+// entry ending in "/". Paths with dot segments are refused so that they cannot
+// escape a prefix once the sandbox resolves them.
+func pathAllowed(p string, paths []string) bool {
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	if cleaned != p {
+		return false
+	}
+	for _, allowed := range paths {
+		if p == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(p, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin accepts WebSocket handshakes without an Origin (non-browser
+// clients), from a page served on the proxy's host name on any port, so that
+// a frontend forwarded on one local port can reach a backend forwarded on
+// another, or from an allowed origin. Anything else is a web page the user
+// happens to visit trying to reach the sandbox through localhost.
+func checkOrigin(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Hostname() != "" && strings.EqualFold(u.Hostname(), requestHostname(r.Host)) {
+		return true
+	}
+	return OriginAllowed(origin, allowed)
+}
+
+// LocalHost reports whether a Host header names localhost, a subdomain of
+// localhost or an IP address. Local listeners that hand out sandbox access
+// refuse other host names, so that a web page cannot reach them through DNS
+// rebinding.
+func LocalHost(hostport string) bool {
+	host := strings.ToLower(requestHostname(hostport))
+	return host == "localhost" || strings.HasSuffix(host, ".localhost") || net.ParseIP(host) != nil
+}
+
+// requestHostname returns the host name of a Host header without its port.
+func requestHostname(hostport string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// OriginAllowed reports whether origin is listed in allowed, where "*"
+// matches any origin.
+func OriginAllowed(origin string, allowed []string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
 // isWebSocketRequest checks if the HTTP request is a WebSocket upgrade request.
 // Per RFC 6455, a valid WebSocket handshake must have both:
 //   - Upgrade: websocket
@@ -2,6 +2,8 @@ package proxy
 
 import (
 	"net"
+	"net/http"
+	"net/http/httptest"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -38,6 +40,74 @@ var _ = Describe("Proxy", func() {
 		Expect(p.targetHost).To(Equal("5173-sandbox-bbb.ap-guangzhou.internal.tencentags.com"))
 	})
 
+	It("accepts WebSockets only from its own host name unless others are allowed", func() {
+		handshake := func(origin string) *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost:3000/ws", nil)
+			if origin != "" {
+				r.Header.Set("Origin", origin)
+			}
+			return r
+		}
+		Expect(checkOrigin(handshake(""), nil)).To(BeTrue())
+		Expect(checkOrigin(handshake("http://localhost:3000"), nil)).To(BeTrue())
+		Expect(checkOrigin(handshake("https://evil.example"), nil)).To(BeFalse())
+		Expect(checkOrigin(handshake("https://app.example"), []string{"https://app.example/"})).To(BeTrue())
+		Expect(checkOrigin(handshake("https://evil.example"), []string{"*"})).To(BeTrue())
+		Expect(checkOrigin(handshake("http://localhost.evil.example:3000"), nil)).To(BeFalse())
+	})
+
+	It("recognizes local host names", func() {
+		for _, host := range []string{"127.0.0.1:9222", "[::1]:9222", "LOCALHOST", "3000-ins-a.localhost:8000", "10.0.0.5"} {
+			Expect(LocalHost(host)).To(BeTrue(), host)
+		}
+		for _, host := range []string{"rebind.example:8000", "localhost.evil.example", "evil-localhost", ""} {
+			Expect(LocalHost(host)).To(BeFalse(), host)
+		}
+	})
+
+	It("accepts WebSockets from a page forwarded on another local port", func() {
+		// A frontend forwarded on localhost:3000 opens ws://localhost:8080 on
+		// the backend's forward.
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/ws", nil)
+		r.Header.Set("Origin", "http://localhost:3000")
+		Expect(checkOrigin(r, nil)).To(BeTrue())
+
+		r = httptest.NewRequest(http.MethodGet, "http://[::1]:8080/ws", nil)
+		r.Header.Set("Origin", "http://[::1]:3000")
+		Expect(checkOrigin(r, nil)).To(BeTrue())
+
+		r = httptest.NewRequest(http.MethodGet, "http://127.0.0.1:8080/ws", nil)
+		r.Header.Set("Origin", "http://localhost:3000")
+		Expect(checkOrigin(r, nil)).To(BeFalse())
+	})
+
+	It("forwards only the configured paths", func() {
+		p, err := New(Options{InstanceID: "sandbox-test", Domain: "example.com", RemotePort: 9000, Token: "t", Paths: []string{"/novnc/", "/websockify"}})
+		Expect(err).NotTo(HaveOccurred())
+		for _, path := range []string{"/", "/cdp", "/json/version", "/novnc", "/novnc/../cdp", "/websockify/x"} {
+			rec := httptest.NewRecorder()
+			p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil))
+			Expect(rec.Code).To(Equal(http.StatusNotFound), path)
+		}
+		Expect(pathAllowed("/novnc/vnc_lite.html", p.options.Paths)).To(BeTrue())
+		Expect(pathAllowed("/websockify", p.options.Paths)).To(BeTrue())
+	})
+
+	It("refuses other host names when limited to local hosts", func() {
+		p, err := New(Options{InstanceID: "sandbox-test", Domain: "example.com", RemotePort: 9000, Token: "t", Paths: []string{"/websockify"}, LocalHostsOnly: true})
+		Expect(err).NotTo(HaveOccurred())
+		// Under DNS rebinding the Origin names the attacker's host too, so
+		// only the Host check stops the handshake.
+		r := httptest.NewRequest(http.MethodGet, "http://rebind.example:6080/websockify", nil)
+		r.Header.Set("Origin", "http://rebind.example:6080")
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		Expect(checkOrigin(r, nil)).To(BeTrue())
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, r)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+	})
+
 	It("can reserve a local listener in this environment", func() {
 		requireLocalListen()
 	})
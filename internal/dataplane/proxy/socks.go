@@ -0,0 +1,360 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SOCKS5 protocol constants (RFC 1928).
+const (
+	socksVersion5 = 0x05
+
+	socksMethodNoAuth       = 0x00
+	socksMethodNoAcceptable = 0xff
+
+	socksCmdConnect = 0x01
+
+	socksAtypIPv4   = 0x01
+	socksAtypDomain = 0x03
+	socksAtypIPv6   = 0x04
+
+	socksReplySucceeded           = 0x00
+	socksReplyGeneralFailure      = 0x01
+	socksReplyNotAllowed          = 0x02
+	socksReplyCommandNotSupported = 0x07
+	socksReplyAtypNotSupported    = 0x08
+
+	// socksHandshakeTimeout bounds the SOCKS negotiation of a new connection.
+	socksHandshakeTimeout = 10 * time.Second
+)
+
+// SOCKSOptions defines configuration for the SOCKS5 front end.
+type SOCKSOptions struct {
+	InstanceID string // e.g. "sandbox-xxx"
+	Domain     string // e.g. "ap-guangzhou.tencentags.com" (region-qualified)
+	Token      string // Initial access token injected into every forwarded request
+	// TokenProvider and InvalidateToken refresh the token after a 401; see
+	// Options. All ports share one token.
+	TokenProvider   func() (string, error)
+	InvalidateToken func()
+	ListenAddress   string       // e.g. "127.0.0.1:1080"
+	Logger          *log.Logger  // Optional logger; defaults to log.Default()
+	Insecure        bool         // Skip TLS verification
+	Verbose         bool         // Enable verbose request logging
+	AccessLog       *AccessLog   // Optional NDJSON access log shared by every port
+	HAR             *HARRecorder // Optional HAR capture shared by every port
+}
+
+// SOCKSServer is a SOCKS5 proxy that reaches any port of one sandbox.
+//
+// Clients CONNECT to a sandbox-local destination such as localhost:3000. The
+// connection is then served by a Proxy for that port, so requests are sent to
+// the "<port>-<instance>.<domain>" gateway host with the access token
+// injected, exactly as a port-forward Proxy does. Destinations outside the
+// sandbox are refused. Because each port is served by an HTTP Proxy, only
+// HTTP and WebSocket clients work over a CONNECT.
+type SOCKSServer struct {
+	options  SOCKSOptions
+	listener net.Listener
+	logger   *log.Logger
+	tokens   *tokenSource
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	ports map[int]*socksPort
+}
+
+// socksPort serves the connections CONNECTed to one sandbox port.
+type socksPort struct {
+	proxy    *Proxy
+	listener *connListener
+}
+
+// NewSOCKS5 creates a SOCKS5 front end but does not start it.
+func NewSOCKS5(opts SOCKSOptions) (*SOCKSServer, error) {
+	if opts.InstanceID == "" || (opts.Token == "" && opts.TokenProvider == nil) || opts.Domain == "" {
+		return nil, fmt.Errorf("instanceID, token, and domain are required")
+	}
+	if opts.ListenAddress == "" {
+		opts.ListenAddress = "127.0.0.1:1080"
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SOCKSServer{
+		options: opts,
+		logger:  logger,
+		tokens: newTokenSource(Options{
+			Token:           opts.Token,
+			TokenProvider:   opts.TokenProvider,
+			InvalidateToken: opts.InvalidateToken,
+		}, logger),
+		ctx:    ctx,
+		cancel: cancel,
+		ports:  map[int]*socksPort{},
+	}, nil
+}
+
+// Start binds to the local address and begins accepting SOCKS5 clients.
+// It returns the actual listen address.
+func (s *SOCKSServer) Start() (string, error) {
+	listener, err := net.Listen("tcp", s.options.ListenAddress)
+	if err != nil {
+		return "", fmt.Errorf("failed to bind local address: %w", err)
+	}
+	s.listener = listener
+	s.logger.Printf("SOCKS5 proxy listening on %s (forwarding to *-%s.%s)", listener.Addr().String(), s.options.InstanceID, s.options.Domain)
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+	return listener.Addr().String(), nil
+}
+
+// Stop closes the listener and shuts down every per-port proxy.
+func (s *SOCKSServer) Stop() {
+	s.cancel()
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	s.wg.Wait()
+	s.mu.Lock()
+	ports := s.ports
+	s.ports = map[int]*socksPort{}
+	s.mu.Unlock()
+	for _, port := range ports {
+		port.proxy.Stop()
+		_ = port.listener.Close()
+	}
+	s.logger.Println("SOCKS5 proxy stopped.")
+}
+
+func (s *SOCKSServer) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.ctx.Done():
+				return
+			default:
+				s.logger.Printf("SOCKS5 accept failed: %v", err)
+				continue
+			}
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle negotiates one SOCKS5 connection and hands it to the proxy of the
+// requested sandbox port.
+func (s *SOCKSServer) handle(conn net.Conn) {
+	_ = conn.SetDeadline(time.Now().Add(socksHandshakeTimeout))
+	host, port, reply, err := negotiateSOCKS5(conn)
+	if err == nil && !IsSandboxLocal(host) {
+		reply, err = socksReplyNotAllowed, fmt.Errorf("destination %s is outside the sandbox", net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+	var target *socksPort
+	if err == nil {
+		target, err = s.portProxy(port)
+		if err != nil {
+			reply = socksReplyGeneralFailure
+		}
+	}
+	if err != nil {
+		s.logger.Printf("[SOCKS5] %v", err)
+		if reply != 0 {
+			_ = writeSOCKSReply(conn, reply)
+		}
+		_ = conn.Close()
+		return
+	}
+	if err := writeSOCKSReply(conn, socksReplySucceeded); err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+	if s.options.Verbose {
+		s.logger.Printf("[SOCKS5] CONNECT %s:%d", host, port)
+	}
+	if !target.listener.push(conn) {
+		_ = conn.Close()
+	}
+}
+
+// portProxy returns the proxy serving sandbox port, starting it on first use.
+func (s *SOCKSServer) portProxy(port int) (*socksPort, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return nil, errors.New("SOCKS5 proxy is stopping")
+	}
+	if existing, ok := s.ports[port]; ok {
+		return existing, nil
+	}
+	p, err := New(Options{
+		InstanceID:    s.options.InstanceID,
+		Domain:        s.options.Domain,
+		RemotePort:    port,
+		Token:         s.options.Token,
+		TokenProvider: s.tokens.get,
+		ListenAddress: s.options.ListenAddress,
+		Logger:        s.logger,
+		Insecure:      s.options.Insecure,
+		Verbose:       s.options.Verbose,
+		AccessLog:     s.options.AccessLog,
+		HAR:           s.options.HAR,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// All ports share one token so that a single refresh serves every port.
+	p.tokens = s.tokens
+	listener := newConnListener(s.listener.Addr())
+	p.serve(listener)
+	created := &socksPort{proxy: p, listener: listener}
+	s.ports[port] = created
+	return created, nil
+}
+
+// IsSandboxLocal reports whether host names the sandbox itself: localhost or
+// a loopback or unspecified address.
+func IsSandboxLocal(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// negotiateSOCKS5 performs the method negotiation and reads a CONNECT
+// request. On failure it returns the reply code to send, or 0 when the
+// client should just be disconnected.
+func negotiateSOCKS5(rw io.ReadWriter) (host string, port int, reply byte, err error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(rw, header); err != nil {
+		return "", 0, 0, fmt.Errorf("read greeting: %w", err)
+	}
+	if header[0] != socksVersion5 {
+		return "", 0, 0, fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+	methods := make([]byte, int(header[1]))
+	if _, err := io.ReadFull(rw, methods); err != nil {
+		return "", 0, 0, fmt.Errorf("read methods: %w", err)
+	}
+	noAuth := false
+	for _, m := range methods {
+		if m == socksMethodNoAuth {
+			noAuth = true
+		}
+	}
+	if !noAuth {
+		_, _ = rw.Write([]byte{socksVersion5, socksMethodNoAcceptable})
+		return "", 0, 0, errors.New("client does not offer the no-authentication method")
+	}
+	if _, err := rw.Write([]byte{socksVersion5, socksMethodNoAuth}); err != nil {
+		return "", 0, 0, err
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(rw, request); err != nil {
+		return "", 0, 0, fmt.Errorf("read request: %w", err)
+	}
+	if request[0] != socksVersion5 {
+		return "", 0, 0, fmt.Errorf("unsupported SOCKS version %d", request[0])
+	}
+	switch request[3] {
+	case socksAtypIPv4, socksAtypIPv6:
+		size := net.IPv4len
+		if request[3] == socksAtypIPv6 {
+			size = net.IPv6len
+		}
+		addr := make([]byte, size)
+		if _, err := io.ReadFull(rw, addr); err != nil {
+			return "", 0, 0, fmt.Errorf("read address: %w", err)
+		}
+		host = net.IP(addr).String()
+	case socksAtypDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(rw, length); err != nil {
+			return "", 0, 0, fmt.Errorf("read address: %w", err)
+		}
+		name := make([]byte, int(length[0]))
+		if _, err := io.ReadFull(rw, name); err != nil {
+			return "", 0, 0, fmt.Errorf("read address: %w", err)
+		}
+		host = string(name)
+	default:
+		return "", 0, socksReplyAtypNotSupported, fmt.Errorf("unsupported address type %d", request[3])
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(rw, portBytes); err != nil {
+		return "", 0, 0, fmt.Errorf("read port: %w", err)
+	}
+	port = int(binary.BigEndian.Uint16(portBytes))
+	if request[1] != socksCmdConnect {
+		return "", 0, socksReplyCommandNotSupported, fmt.Errorf("unsupported SOCKS command %d", request[1])
+	}
+	if port == 0 {
+		return "", 0, socksReplyNotAllowed, errors.New("destination port 0 is not allowed")
+	}
+	return host, port, 0, nil
+}
+
+func writeSOCKSReply(w io.Writer, reply byte) error {
+	_, err := w.Write([]byte{socksVersion5, reply, 0x00, socksAtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// connListener is a net.Listener fed with connections accepted elsewhere.
+type connListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{addr: addr, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+// push hands conn to Accept. It returns false once the listener is closed.
+func (l *connListener) push(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr { return l.addr }
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// socksConn is an in-memory client stream: reads come from in, writes go to out.
+type socksConn struct {
+	in  *bytes.Reader
+	out bytes.Buffer
+}
+
+func (c *socksConn) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c *socksConn) Write(p []byte) (int, error) { return c.out.Write(p) }
+
+func newSOCKSConn(data ...[]byte) *socksConn {
+	return &socksConn{in: bytes.NewReader(bytes.Join(data, nil))}
+}
+
+var _ = Describe("SOCKS5", func() {
+	greeting := []byte{0x05, 0x01, 0x00}
+
+	It("reads CONNECT requests for domain, IPv4 and IPv6 destinations", func() {
+		conn := newSOCKSConn(greeting, []byte{0x05, 0x01, 0x00, 0x03, 9}, []byte("localhost"), []byte{0x0b, 0xb8})
+		host, port, _, err := negotiateSOCKS5(conn)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(host).To(Equal("localhost"))
+		Expect(port).To(Equal(3000))
+		Expect(conn.out.Bytes()).To(Equal([]byte{0x05, 0x00}))
+
+		host, port, _, err = negotiateSOCKS5(newSOCKSConn(greeting, []byte{0x05, 0x01, 0x00, 0x01, 127, 0, 0, 1, 0x1f, 0x90}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(host).To(Equal("127.0.0.1"))
+		Expect(port).To(Equal(8080))
+
+		host, _, _, err = negotiateSOCKS5(newSOCKSConn(greeting, []byte{0x05, 0x01, 0x00, 0x04}, net.IPv6loopback, []byte{0x00, 0x50}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(host).To(Equal("::1"))
+	})
+
+	It("rejects unsupported methods and commands", func() {
+		conn := newSOCKSConn([]byte{0x05, 0x01, 0x02})
+		_, _, _, err := negotiateSOCKS5(conn)
+		Expect(err).To(MatchError(ContainSubstring("no-authentication")))
+		Expect(conn.out.Bytes()).To(Equal([]byte{0x05, 0xff}))
+
+		_, _, reply, err := negotiateSOCKS5(newSOCKSConn(greeting, []byte{0x05, 0x02, 0x00, 0x01, 127, 0, 0, 1, 0x1f, 0x90}))
+		Expect(err).To(HaveOccurred())
+		Expect(reply).To(Equal(byte(socksReplyCommandNotSupported)))
+
+		_, _, _, err = negotiateSOCKS5(newSOCKSConn([]byte{0x04, 0x01, 0x00}))
+		Expect(err).To(MatchError(ContainSubstring("version")))
+	})
+
+	It("only allows sandbox-local destinations", func() {
+		Expect(IsSandboxLocal("localhost")).To(BeTrue())
+		Expect(IsSandboxLocal("127.0.0.1")).To(BeTrue())
+		Expect(IsSandboxLocal("127.1.2.3")).To(BeTrue())
+		Expect(IsSandboxLocal("::1")).To(BeTrue())
+		Expect(IsSandboxLocal("0.0.0.0")).To(BeTrue())
+		Expect(IsSandboxLocal("example.com")).To(BeFalse())
+		Expect(IsSandboxLocal("10.0.0.1")).To(BeFalse())
+	})
+
+	It("refuses non-sandbox destinations over the wire", func() {
+		requireLocalListen()
+		server, err := NewSOCKS5(SOCKSOptions{InstanceID: "sandbox-test", Domain: "ap-guangzhou.tencentags.com", Token: "token", ListenAddress: "127.0.0.1:0", Logger: log.New(GinkgoWriter, "", 0)})
+		Expect(err).NotTo(HaveOccurred())
+		addr, err := server.Start()
+		Expect(err).NotTo(HaveOccurred())
+		defer server.Stop()
+
+		conn, err := net.Dial("tcp", addr)
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		_, err = conn.Write(append(append(greeting, 0x05, 0x01, 0x00, 0x03, 11), append([]byte("example.com"), 0x00, 0x50)...))
+		Expect(err).NotTo(HaveOccurred())
+		reply := make([]byte, 12)
+		_, err = io.ReadFull(conn, reply)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reply[:4]).To(Equal([]byte{0x05, 0x00, 0x05, socksReplyNotAllowed}))
+		Expect(server.ports).To(BeEmpty())
+
+		local, err := net.Dial("tcp", addr)
+		Expect(err).NotTo(HaveOccurred())
+		defer local.Close()
+		_, err = local.Write(append(append(greeting, 0x05, 0x01, 0x00, 0x03, 9), append([]byte("localhost"), 0x0b, 0xb8)...))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadFull(local, reply)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reply[:4]).To(Equal([]byte{0x05, 0x00, 0x05, socksReplySucceeded}))
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		Expect(server.ports).To(HaveKey(3000))
+		Expect(server.ports[3000].proxy.targetHost).To(Equal("3000-sandbox-test.ap-guangzhou.tencentags.com"))
+	})
+
+	It("validates constructor options", func() {
+		_, err := NewSOCKS5(SOCKSOptions{Domain: "ap-guangzhou.tencentags.com", Token: "token"})
+		Expect(err).To(MatchError(ContainSubstring("instanceID")))
+	})
+})
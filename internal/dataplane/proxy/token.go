@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// minRefreshInterval is the minimum time between two token refreshes, so a
+// sandbox that keeps answering 401 cannot make every request re-acquire the
+// token and wipe the shared token cache.
+const minRefreshInterval = 30 * time.Second
+
+// tokenSource hands out the current access token and replaces it when the
+// sandbox gateway rejects it, e.g. after the instance was paused and resumed.
+type tokenSource struct {
+	provider   func() (string, error)
+	invalidate func()
+	logger     *log.Logger
+	now        func() time.Time
+
+	mu          sync.Mutex
+	current     string
+	refreshedAt time.Time
+}
+
+func newTokenSource(opts Options, logger *log.Logger) *tokenSource {
+	provider := opts.TokenProvider
+	if provider == nil {
+		token := opts.Token
+		provider = func() (string, error) { return token, nil }
+	}
+	return &tokenSource{
+		provider:   provider,
+		invalidate: opts.InvalidateToken,
+		logger:     logger,
+		now:        time.Now,
+		current:    opts.Token,
+	}
+}
+
+// get returns the current token, asking the provider on first use.
+func (s *tokenSource) get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != "" {
+		return s.current, nil
+	}
+	token, err := s.provider()
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", errors.New("token provider returned an empty token")
+	}
+	s.current = token
+	return token, nil
+}
+
+// refresh replaces the rejected token stale. Concurrent callers that saw the
+// same stale token share one refresh, and a refresh within
+// minRefreshInterval of the previous one keeps the current token.
+func (s *tokenSource) refresh(stale string) (string, error) {
+	s.mu.Lock()
+	if s.current != "" && (s.current != stale || s.now().Sub(s.refreshedAt) < minRefreshInterval) {
+		token := s.current
+		s.mu.Unlock()
+		return token, nil
+	}
+	s.refreshedAt = s.now()
+	s.current = ""
+	if s.invalidate != nil {
+		s.invalidate()
+	}
+	s.mu.Unlock()
+	token, err := s.get()
+	if err != nil {
+		s.logger.Printf("[ERROR] Failed to refresh access token: %v", err)
+		return "", err
+	}
+	if token != stale {
+		s.logger.Printf("Access token rejected by the sandbox gateway; using a refreshed token")
+	}
+	return token, nil
+}
+
+// gatewayRejected reports whether resp is the sandbox gateway rejecting the
+// access token rather than a 401 from the application behind it. An
+// application that asks for credentials sends a WWW-Authenticate challenge
+// (RFC 7235); the gateway's token rejection does not.
+func gatewayRejected(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == ""
+}
+
+// tokenTransport injects the access token into upstream requests. When the
+// gateway rejects the token it refreshes it and retries the request once if
+// the request is idempotent and has no body to replay; other requests fail
+// through, but the next one uses the new token.
+type tokenTransport struct {
+	base   http.RoundTripper
+	tokens *tokenSource
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens.get()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(withToken(req, token))
+	if err != nil || !gatewayRejected(resp) {
+		return resp, err
+	}
+	fresh, refreshErr := t.tokens.refresh(token)
+	if refreshErr != nil || fresh == token || !retryable(req) {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	return t.base.RoundTrip(withToken(req, fresh))
+}
+
+func withToken(req *http.Request, token string) *http.Request {
+	out := req.Clone(req.Context())
+	// The sandbox gateway authenticates via X-Access-Token only.
+	out.Header.Set("X-Access-Token", token)
+	return out
+}
+
+// retryable reports whether req can be sent again after a 401.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+// dialWithToken dials an upstream WebSocket with the current token and, if the
+// gateway rejects the handshake, once more with a refreshed token.
+func (s *tokenSource) dialWithToken(dial func(token string) (*websocket.Conn, *http.Response, error)) (*websocket.Conn, *http.Response, error) {
+	token, err := s.get()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, resp, err := dial(token)
+	if err == nil || !gatewayRejected(resp) {
+		return conn, resp, err
+	}
+	fresh, refreshErr := s.refresh(token)
+	if refreshErr != nil || fresh == token {
+		return conn, resp, err
+	}
+	if resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	return dial(fresh)
+}
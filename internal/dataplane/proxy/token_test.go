@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+var _ = Describe("Token refresh", func() {
+	var (
+		issued      []string
+		invalidated int
+		tokens      *tokenSource
+	)
+	BeforeEach(func() {
+		issued = []string{"fresh"}
+		invalidated = 0
+		tokens = newTokenSource(Options{
+			Token: "stale",
+			TokenProvider: func() (string, error) {
+				if len(issued) == 0 {
+					return "", errors.New("no token")
+				}
+				token := issued[0]
+				issued = issued[1:]
+				return token, nil
+			},
+			InvalidateToken: func() { invalidated++ },
+		}, log.New(GinkgoWriter, "", 0))
+	})
+	// gateway accepts only the "fresh" token.
+	gateway := func(seen *[]string) http.RoundTripper {
+		return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			token := r.Header.Get("X-Access-Token")
+			*seen = append(*seen, token)
+			status := http.StatusOK
+			if token != "fresh" {
+				status = http.StatusUnauthorized
+			}
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+		})
+	}
+
+	It("retries idempotent requests once with a refreshed token", func() {
+		var seen []string
+		t := &tokenTransport{base: gateway(&seen), tokens: tokens}
+		resp, err := t.RoundTrip(httptest.NewRequest(http.MethodGet, "https://upstream/", nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(seen).To(Equal([]string{"stale", "fresh"}))
+		Expect(invalidated).To(Equal(1))
+
+		resp, err = t.RoundTrip(httptest.NewRequest(http.MethodGet, "https://upstream/", nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(seen).To(Equal([]string{"stale", "fresh", "fresh"}))
+	})
+
+	It("does not replay requests with a body but refreshes for the next one", func() {
+		var seen []string
+		t := &tokenTransport{base: gateway(&seen), tokens: tokens}
+		resp, err := t.RoundTrip(httptest.NewRequest(http.MethodPost, "https://upstream/", strings.NewReader("x")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(seen).To(Equal([]string{"stale"}))
+
+		token, err := tokens.get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(Equal("fresh"))
+	})
+
+	It("shares one refresh between callers that saw the same token", func() {
+		first, err := tokens.refresh("stale")
+		Expect(err).NotTo(HaveOccurred())
+		second, err := tokens.refresh("stale")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first).To(Equal("fresh"))
+		Expect(second).To(Equal("fresh"))
+		Expect(invalidated).To(Equal(1))
+	})
+
+	It("re-dials a WebSocket rejected with 401", func() {
+		var dialed []string
+		_, _, err := tokens.dialWithToken(func(token string) (*websocket.Conn, *http.Response, error) {
+			dialed = append(dialed, token)
+			if token != "fresh" {
+				return nil, &http.Response{StatusCode: http.StatusUnauthorized}, websocket.ErrBadHandshake
+			}
+			return nil, nil, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dialed).To(Equal([]string{"stale", "fresh"}))
+	})
+
+	It("passes an application's 401 through without refreshing", func() {
+		var seen []string
+		app := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			seen = append(seen, r.Header.Get("X-Access-Token"))
+			header := http.Header{"Www-Authenticate": {`Basic realm="app"`}}
+			return &http.Response{StatusCode: http.StatusUnauthorized, Header: header, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+		})
+		t := &tokenTransport{base: app, tokens: tokens}
+		resp, err := t.RoundTrip(httptest.NewRequest(http.MethodGet, "https://upstream/", nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(seen).To(Equal([]string{"stale"}))
+		Expect(invalidated).To(BeZero())
+		Expect(issued).To(Equal([]string{"fresh"}))
+	})
+
+	It("refreshes at most once per interval", func() {
+		issued = []string{"second", "third"}
+		now := time.Unix(0, 0)
+		tokens.now = func() time.Time { return now }
+		first, err := tokens.refresh("stale")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first).To(Equal("second"))
+
+		again, err := tokens.refresh("second")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(Equal("second"))
+		Expect(invalidated).To(Equal(1))
+
+		now = now.Add(minRefreshInterval)
+		later, err := tokens.refresh("second")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(later).To(Equal("third"))
+		Expect(invalidated).To(Equal(2))
+	})
+
+	It("returns the 401 when no new token is available", func() {
+		issued = nil
+		var seen []string
+		t := &tokenTransport{base: gateway(&seen), tokens: tokens}
+		resp, err := t.RoundTrip(httptest.NewRequest(http.MethodGet, "https://upstream/", nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(seen).To(Equal([]string{"stale"}))
+	})
+})
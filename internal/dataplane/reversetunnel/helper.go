@@ -0,0 +1,85 @@
+package reversetunnel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/TencentCloudAgentRuntime/ags-go-sdk/sandbox/code"
+	"github.com/TencentCloudAgentRuntime/ags-go-sdk/tool/command"
+)
+
+// HelperPath is where the helper script is uploaded in the sandbox.
+const HelperPath = "/tmp/agr-reverse-helper.py"
+
+// HelperOptions configures the sandbox-side helper.
+type HelperOptions struct {
+	RemotePort int         // Sandbox port programs connect to
+	HelperPort int         // Control port the Tunnel dials; defaults to DefaultHelperPort
+	User       string      // Sandbox user running the helper
+	Logger     *log.Logger // Receives the helper's stderr; defaults to log.Default()
+}
+
+// Helper is a helper process running in the sandbox.
+type Helper struct {
+	sandbox *code.Sandbox
+	handle  *command.Handle
+	pattern string
+}
+
+// LaunchHelper uploads the helper script to the sandbox and starts it in the
+// background. A helper left behind for the same sandbox port is stopped first.
+func LaunchHelper(ctx context.Context, sandbox *code.Sandbox, opts HelperOptions) (*Helper, error) {
+	if opts.HelperPort == 0 {
+		opts.HelperPort = DefaultHelperPort
+	}
+	if opts.RemotePort == opts.HelperPort {
+		return nil, fmt.Errorf("remote port %d is reserved for the reverse tunnel helper", opts.RemotePort)
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	result, err := sandbox.Commands.Run(ctx, "command -v python3 >/dev/null 2>&1 && echo found || echo missing", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for python3: %w", err)
+	}
+	if strings.TrimSpace(string(result.Stdout)) != "found" {
+		return nil, fmt.Errorf("python3 is required in the sandbox to run the reverse tunnel helper")
+	}
+	if _, err := sandbox.Files.Write(ctx, HelperPath, bytes.NewReader(HelperScript), nil); err != nil {
+		return nil, fmt.Errorf("failed to upload reverse tunnel helper: %w", err)
+	}
+
+	pattern := fmt.Sprintf("agr-reverse-helper.py --listen %d ", opts.RemotePort)
+	_, _ = sandbox.Commands.Run(ctx, fmt.Sprintf("pkill -f '%s' 2>/dev/null || true", pattern), nil, nil)
+
+	cmd := fmt.Sprintf("python3 %s --listen %d --control %d", HelperPath, opts.RemotePort, opts.HelperPort)
+	handle, err := sandbox.Commands.Start(ctx, cmd, &command.ProcessConfig{User: opts.User}, &command.OnOutputConfig{
+		OnStderr: func(data []byte) {
+			for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+				logger.Printf("[sandbox] %s", line)
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start reverse tunnel helper: %w", err)
+	}
+	return &Helper{sandbox: sandbox, handle: handle, pattern: pattern}, nil
+}
+
+// Stop terminates the helper process.
+func (h *Helper) Stop(ctx context.Context) error {
+	if h.handle != nil {
+		if err := h.handle.Kill(ctx); err == nil {
+			return nil
+		}
+	}
+	if _, err := h.sandbox.Commands.Run(ctx, fmt.Sprintf("pkill -f '%s' 2>/dev/null || true", h.pattern), nil, nil); err != nil {
+		return fmt.Errorf("failed to stop reverse tunnel helper: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+#!/usr/bin/env python3
+"""Reverse tunnel helper for `agr instance proxy --reverse`.
+
+Runs inside the sandbox. Programs in the sandbox connect to --listen; agr keeps
+a pool of idle WebSocket connections open to --control through the sandbox
+gateway. Each accepted connection is paired with one idle WebSocket, announced
+with a text "open" message, and then relayed as binary messages in both
+directions. agr dials the local target when it receives "open".
+
+Only the Python standard library is used.
+"""
+
+import argparse
+import base64
+import hashlib
+import queue
+import socket
+import struct
+import sys
+import threading
+
+GUID = b"258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+OP_CONT, OP_TEXT, OP_BINARY, OP_CLOSE, OP_PING, OP_PONG = 0x0, 0x1, 0x2, 0x8, 0x9, 0xA
+SLOT_WAIT = 10.0
+CHUNK = 32 * 1024
+
+
+def log(msg):
+    sys.stderr.write("agr-reverse: %s\n" % msg)
+    sys.stderr.flush()
+
+
+def read_exact(sock, n):
+    buf = b""
+    while len(buf) < n:
+        chunk = sock.recv(n - len(buf))
+        if not chunk:
+            return None
+        buf += chunk
+    return buf
+
+
+def unmask(data, mask):
+    if not data:
+        return data
+    key = (mask * (len(data) // 4 + 1))[: len(data)]
+    return (int.from_bytes(data, "big") ^ int.from_bytes(key, "big")).to_bytes(len(data), "big")
+
+
+class Slot:
+    """One idle WebSocket from agr, later paired with a sandbox client."""
+
+    def __init__(self, sock):
+        self.sock = sock
+        self.peer = None
+        self.closed = False
+        self.lock = threading.Lock()
+
+    def send(self, opcode, payload=b""):
+        n = len(payload)
+        if n < 126:
+            header = struct.pack(">BB", 0x80 | opcode, n)
+        elif n < 65536:
+            header = struct.pack(">BBH", 0x80 | opcode, 126, n)
+        else:
+            header = struct.pack(">BBQ", 0x80 | opcode, 127, n)
+        with self.lock:
+            self.sock.sendall(header + payload)
+
+    def recv(self):
+        head = read_exact(self.sock, 2)
+        if head is None:
+            return None, b""
+        opcode, n = head[0] & 0x0F, head[1] & 0x7F
+        if n == 126:
+            ext = read_exact(self.sock, 2)
+            n = struct.unpack(">H", ext)[0] if ext else 0
+        elif n == 127:
+            ext = read_exact(self.sock, 8)
+            n = struct.unpack(">Q", ext)[0] if ext else 0
+        mask = read_exact(self.sock, 4) if head[1] & 0x80 else None
+        payload = read_exact(self.sock, n) if n else b""
+        if payload is None:
+            return None, b""
+        if mask:
+            payload = unmask(payload, mask)
+        return opcode, payload
+
+    def close(self):
+        self.closed = True
+        for s in (self.sock, self.peer):
+            if s is not None:
+                try:
+                    s.close()
+                except OSError:
+                    pass
+
+    def serve(self):
+        """Reads frames from agr: answers pings and relays data to the peer."""
+        try:
+            while True:
+                opcode, payload = self.recv()
+                if opcode is None or opcode == OP_CLOSE:
+                    break
+                if opcode == OP_PING:
+                    self.send(OP_PONG, payload)
+                elif opcode in (OP_BINARY, OP_CONT) and self.peer is not None:
+                    self.peer.sendall(payload)
+        except OSError:
+            pass
+        self.close()
+
+
+def handshake(sock):
+    request = b""
+    while b"\r\n\r\n" not in request:
+        chunk = sock.recv(4096)
+        if not chunk or len(request) > 65536:
+            return False
+        request += chunk
+    key = None
+    for line in request.split(b"\r\n")[1:]:
+        name, _, value = line.partition(b":")
+        if name.strip().lower() == b"sec-websocket-key":
+            key = value.strip()
+    if key is None:
+        sock.sendall(b"HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n")
+        return False
+    accept = base64.b64encode(hashlib.sha1(key + GUID).digest())
+    sock.sendall(
+        b"HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n"
+        b"Connection: Upgrade\r\nSec-WebSocket-Accept: " + accept + b"\r\n\r\n"
+    )
+    return True
+
+
+def accept_slots(server, slots):
+    while True:
+        sock, _ = server.accept()
+        threading.Thread(target=register_slot, args=(sock, slots), daemon=True).start()
+
+
+def register_slot(sock, slots):
+    try:
+        if not handshake(sock):
+            sock.close()
+            return
+    except OSError:
+        sock.close()
+        return
+    slot = Slot(sock)
+    threading.Thread(target=slot.serve, daemon=True).start()
+    slots.put(slot)
+
+
+def take_slot(slots):
+    while True:
+        try:
+            slot = slots.get(timeout=SLOT_WAIT)
+        except queue.Empty:
+            return None
+        if not slot.closed:
+            return slot
+
+
+def relay(client, slots):
+    slot = take_slot(slots)
+    if slot is None:
+        log("no connection from agr available; closing client")
+        client.close()
+        return
+    slot.peer = client
+    try:
+        slot.send(OP_TEXT, b"open")
+        while True:
+            data = client.recv(CHUNK)
+            if not data:
+                break
+            slot.send(OP_BINARY, data)
+        slot.send(OP_CLOSE, struct.pack(">H", 1000))
+    except OSError:
+        pass
+    slot.close()
+
+
+def listen(port):
+    server = socket.socket(socket.AF_INET, socket.SOCK_STREAM)
+    server.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+    server.bind(("0.0.0.0", port))
+    server.listen(128)
+    return server
+
+
+def main():
+    parser = argparse.ArgumentParser()
+    parser.add_argument("--listen", type=int, required=True)
+    parser.add_argument("--control", type=int, required=True)
+    args = parser.parse_args()
+
+    slots = queue.Queue()
+    control = listen(args.control)
+    threading.Thread(target=accept_slots, args=(control, slots), daemon=True).start()
+    server = listen(args.listen)
+    log("listening on %d (control %d)" % (args.listen, args.control))
+    while True:
+        client, _ = server.accept()
+        threading.Thread(target=relay, args=(client, slots), daemon=True).start()
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,321 @@
+// Package reversetunnel exposes a service on the local machine inside a
+// sandbox, the mirror image of the port-forwarding proxy.
+//
+// A small helper (helper.py) is started in the sandbox through envd. It
+// listens on the requested sandbox port and on a control port. The Tunnel
+// keeps a pool of idle WebSocket connections open to the control port through
+// the sandbox gateway; when a program in the sandbox connects, the helper
+// pairs it with one of them and sends an "open" message, and the Tunnel dials
+// the local target and relays bytes in both directions.
+package reversetunnel
+
+import (
+	"context"
+	"crypto/tls"
+	_ "embed"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// HelperScript is the sandbox-side helper, run with python3.
+//
+//go:embed helper.py
+var HelperScript []byte
+
+const (
+	// DefaultHelperPort is the sandbox port the helper accepts tunnel
+	// connections on unless HelperPort is set.
+	DefaultHelperPort = 49990
+
+	// DefaultPoolSize is the number of idle connections kept open to the helper.
+	DefaultPoolSize = 4
+
+	// defaultStartTimeout bounds how long Start waits for the helper to accept
+	// the first connection.
+	defaultStartTimeout = 15 * time.Second
+
+	// pingInterval keeps idle connections alive through the gateway.
+	pingInterval = 30 * time.Second
+
+	// dialTimeout bounds dialing the local target.
+	dialTimeout = 10 * time.Second
+
+	// retryBaseDelay and retryMaxDelay bound the backoff between failed dials
+	// to the helper.
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+
+	// openMessage is the text message the helper sends when a sandbox client
+	// has been paired with a connection.
+	openMessage = "open"
+)
+
+// Options defines configuration for the reverse tunnel.
+type Options struct {
+	InstanceID    string                 // e.g. "sandbox-xxx"
+	Domain        string                 // e.g. "ap-guangzhou.tencentags.com"
+	TokenProvider func() (string, error) // Dynamic token provider; called on each dial
+	HelperPort    int                    // Control port of the helper; defaults to DefaultHelperPort
+	Target        string                 // Local service, e.g. "127.0.0.1:11434"
+	PoolSize      int                    // Idle connections kept open; defaults to DefaultPoolSize
+	StartTimeout  time.Duration          // How long Start waits for the helper; defaults to 15s
+	Insecure      bool                   // Skip TLS verification
+	Logger        *log.Logger            // Optional logger; defaults to log.Default()
+}
+
+// Tunnel relays connections accepted by the sandbox helper to a local target.
+type Tunnel struct {
+	options Options
+	wsURL   string
+	host    string
+	logger  *log.Logger
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu    sync.Mutex
+	conns map[*websocket.Conn]struct{}
+}
+
+// New creates a reverse tunnel but does not connect to the helper.
+func New(opts Options) (*Tunnel, error) {
+	if opts.InstanceID == "" || opts.TokenProvider == nil || opts.Domain == "" {
+		return nil, fmt.Errorf("instanceID, tokenProvider, and domain are required")
+	}
+	if opts.Target == "" {
+		return nil, fmt.Errorf("target is required")
+	}
+	if opts.HelperPort == 0 {
+		opts.HelperPort = DefaultHelperPort
+	}
+	if opts.HelperPort < 0 || opts.HelperPort > 65535 {
+		return nil, fmt.Errorf("helperPort must be between 1 and 65535")
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = DefaultPoolSize
+	}
+	if opts.StartTimeout <= 0 {
+		opts.StartTimeout = defaultStartTimeout
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	host := fmt.Sprintf("%d-%s.%s", opts.HelperPort, opts.InstanceID, opts.Domain)
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tunnel{
+		options: opts,
+		wsURL:   fmt.Sprintf("wss://%s/", host),
+		host:    host,
+		logger:  logger,
+		ctx:     ctx,
+		cancel:  cancel,
+		conns:   map[*websocket.Conn]struct{}{},
+	}, nil
+}
+
+// Start waits until the helper accepts a connection, then keeps the pool of
+// idle connections open in the background.
+func (t *Tunnel) Start() error {
+	deadline := time.Now().Add(t.options.StartTimeout)
+	for {
+		conn, err := t.dial()
+		if err == nil {
+			t.logger.Printf("Reverse tunnel connected to %s (relaying to %s)", t.host, t.options.Target)
+			for i := 0; i < t.options.PoolSize; i++ {
+				t.wg.Add(1)
+				go t.worker(conn)
+				conn = nil
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("reverse tunnel helper is not reachable: %w", err)
+		}
+		select {
+		case <-t.ctx.Done():
+			return t.ctx.Err()
+		case <-time.After(retryBaseDelay):
+		}
+	}
+}
+
+// Stop closes every connection to the helper and waits for relays to finish.
+func (t *Tunnel) Stop() {
+	t.cancel()
+	t.mu.Lock()
+	for conn := range t.conns {
+		_ = conn.Close()
+	}
+	t.mu.Unlock()
+	t.wg.Wait()
+	t.logger.Println("Reverse tunnel stopped.")
+}
+
+// worker keeps one idle connection open to the helper. When the helper pairs
+// it with a sandbox client, the worker hands it to a relay and dials a
+// replacement.
+func (t *Tunnel) worker(conn *websocket.Conn) {
+	defer t.wg.Done()
+	attempt := 0
+	for t.ctx.Err() == nil {
+		if conn == nil {
+			var err error
+			conn, err = t.dial()
+			if err != nil {
+				if attempt == 0 {
+					t.logger.Printf("[WARN] Reverse tunnel connection failed: %v. Retrying.", err)
+				}
+				attempt++
+				select {
+				case <-t.ctx.Done():
+				case <-time.After(backoff(attempt)):
+				}
+				continue
+			}
+			if attempt > 0 {
+				t.logger.Printf("[INFO] Reverse tunnel reconnected")
+			}
+			attempt = 0
+		}
+		if !t.awaitOpen(conn) {
+			t.release(conn)
+			conn = nil
+			continue
+		}
+		t.wg.Add(1)
+		go t.relay(conn)
+		conn = nil
+	}
+	if conn != nil {
+		t.release(conn)
+	}
+}
+
+// awaitOpen waits on an idle connection until the helper announces a client,
+// pinging it so that the gateway keeps it open. It returns false when the
+// connection is lost.
+func (t *Tunnel) awaitOpen(conn *websocket.Conn) bool {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
+			}
+		}
+	}()
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			return false
+		}
+		if msgType == websocket.TextMessage && string(data) == openMessage {
+			return true
+		}
+	}
+}
+
+// relay connects a paired helper connection to the local target.
+func (t *Tunnel) relay(conn *websocket.Conn) {
+	defer t.wg.Done()
+	defer t.release(conn)
+
+	local, err := net.DialTimeout("tcp", t.options.Target, dialTimeout)
+	if err != nil {
+		t.logger.Printf("[ERROR] Failed to reach %s: %v", t.options.Target, err)
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "target unreachable"),
+			time.Now().Add(3*time.Second))
+		return
+	}
+	defer func() { _ = local.Close() }()
+
+	go func() {
+		defer func() { _ = local.Close() }()
+		for {
+			msgType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if msgType == websocket.BinaryMessage {
+				if _, err := local.Write(data); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := local.Read(buf)
+		if n > 0 {
+			if werr := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(3*time.Second))
+			return
+		}
+	}
+}
+
+// dial opens one connection to the helper and tracks it for Stop.
+func (t *Tunnel) dial() (*websocket.Conn, error) {
+	token, err := t.options.TokenProvider()
+	if err != nil {
+		return nil, fmt.Errorf("token provider failed: %w", err)
+	}
+	dialer := &websocket.Dialer{HandshakeTimeout: 15 * time.Second}
+	if t.options.Insecure {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	headers := http.Header{}
+	headers.Set("X-Access-Token", token)
+	conn, _, err := dialer.DialContext(t.ctx, t.wsURL, headers)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ctx.Err() != nil {
+		_ = conn.Close()
+		return nil, t.ctx.Err()
+	}
+	t.conns[conn] = struct{}{}
+	return conn, nil
+}
+
+// release closes conn and stops tracking it.
+func (t *Tunnel) release(conn *websocket.Conn) {
+	t.mu.Lock()
+	delete(t.conns, conn)
+	t.mu.Unlock()
+	_ = conn.Close()
+}
+
+func backoff(attempt int) time.Duration {
+	if attempt > 10 {
+		return retryMaxDelay
+	}
+	delay := time.Duration(float64(retryBaseDelay) * math.Pow(2, float64(attempt-1)))
+	if delay > retryMaxDelay {
+		return retryMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,13 @@
+package reversetunnel
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReverseTunnel(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Reverse Tunnel Suite")
+}
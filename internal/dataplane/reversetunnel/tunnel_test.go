@@ -0,0 +1,163 @@
+package reversetunnel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// echoServer accepts TCP connections and echoes every line back upper-cased.
+func echoServer() net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		Skip("local listener unavailable: " + err.Error())
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				scanner.Buffer(make([]byte, 0, 1<<20), 1<<20)
+				for scanner.Scan() {
+					_, _ = fmt.Fprintln(conn, strings.ToUpper(scanner.Text()))
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func newTestTunnel(wsURL, target string) *Tunnel {
+	tunnel, err := New(Options{
+		InstanceID: "sandbox-test", Domain: "ap-guangzhou.tencentags.com",
+		TokenProvider: func() (string, error) { return "token", nil },
+		Target:        target, PoolSize: 2, StartTimeout: 10 * time.Second,
+		Logger: log.New(GinkgoWriter, "", 0),
+	})
+	Expect(err).NotTo(HaveOccurred())
+	tunnel.wsURL = wsURL
+	return tunnel
+}
+
+var _ = Describe("Reverse tunnel", func() {
+	It("validates options and targets the helper port on the gateway", func() {
+		tunnel, err := New(Options{InstanceID: "sandbox-aaa", Domain: "ap-guangzhou.tencentags.com",
+			TokenProvider: func() (string, error) { return "token", nil }, Target: "127.0.0.1:8000"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnel.wsURL).To(Equal("wss://49990-sandbox-aaa.ap-guangzhou.tencentags.com/"))
+		Expect(tunnel.options.PoolSize).To(Equal(DefaultPoolSize))
+
+		_, err = New(Options{InstanceID: "sandbox-aaa", Domain: "ap-guangzhou.tencentags.com",
+			TokenProvider: func() (string, error) { return "token", nil }})
+		Expect(err).To(MatchError(ContainSubstring("target")))
+		_, err = New(Options{Domain: "ap-guangzhou.tencentags.com", Target: "127.0.0.1:8000",
+			TokenProvider: func() (string, error) { return "token", nil }})
+		Expect(err).To(MatchError(ContainSubstring("instanceID")))
+	})
+
+	It("relays a paired connection to the local target", func() {
+		target := echoServer()
+		defer target.Close()
+
+		tokens := make(chan string, 8)
+		conns := make(chan *websocket.Conn, 8)
+		upgrader := websocket.Upgrader{}
+		helper := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokens <- r.Header.Get("X-Access-Token")
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err == nil {
+				conns <- conn
+			}
+		}))
+		defer helper.Close()
+
+		tunnel := newTestTunnel("ws"+strings.TrimPrefix(helper.URL, "http")+"/", target.Addr().String())
+		Expect(tunnel.Start()).To(Succeed())
+		defer tunnel.Stop()
+		Eventually(tokens).Should(Receive(Equal("token")))
+
+		var conn *websocket.Conn
+		Eventually(conns).Should(Receive(&conn))
+		defer conn.Close()
+		Expect(conn.WriteMessage(websocket.TextMessage, []byte("open"))).To(Succeed())
+		Expect(conn.WriteMessage(websocket.BinaryMessage, []byte("hello\n"))).To(Succeed())
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		msgType, data, err := conn.ReadMessage()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msgType).To(Equal(websocket.BinaryMessage))
+		Expect(string(data)).To(Equal("HELLO\n"))
+
+		// The paired connection is replaced so the pool stays full.
+		Eventually(conns, 5*time.Second).Should(Receive())
+	})
+
+	It("works end to end with the python helper", func() {
+		python, err := exec.LookPath("python3")
+		if err != nil {
+			Skip("python3 unavailable")
+		}
+		target := echoServer()
+		defer target.Close()
+		listenPort, controlPort := freePort(), freePort()
+
+		script := filepath.Join(GinkgoT().TempDir(), "helper.py")
+		Expect(os.WriteFile(script, HelperScript, 0o644)).To(Succeed())
+		cmd := exec.Command(python, script, "--listen", fmt.Sprint(listenPort), "--control", fmt.Sprint(controlPort))
+		cmd.Stderr = GinkgoWriter
+		Expect(cmd.Start()).To(Succeed())
+		defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+
+		tunnel := newTestTunnel(fmt.Sprintf("ws://127.0.0.1:%d/", controlPort), target.Addr().String())
+		Expect(tunnel.Start()).To(Succeed())
+		defer tunnel.Stop()
+
+		for i := 0; i < 3; i++ {
+			client, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", listenPort))
+			Expect(err).NotTo(HaveOccurred())
+			_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+			_, err = fmt.Fprintf(client, "ping %d\n", i)
+			Expect(err).NotTo(HaveOccurred())
+			line, err := bufio.NewReader(client).ReadString('\n')
+			Expect(err).NotTo(HaveOccurred())
+			Expect(line).To(Equal(fmt.Sprintf("PING %d\n", i)))
+			_ = client.Close()
+		}
+
+		big := strings.Repeat("x", 100000) + "\n"
+		client, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", listenPort))
+		Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+		_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+		_, err = io.WriteString(client, big)
+		Expect(err).NotTo(HaveOccurred())
+		line, err := bufio.NewReader(client).ReadString('\n')
+		Expect(err).NotTo(HaveOccurred())
+		Expect(line).To(Equal(strings.ToUpper(big)))
+	})
+})
+
+func freePort() int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		Skip("local listener unavailable: " + err.Error())
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,152 @@
+// Package tcpbridge runs a WebSocket-to-TCP bridge inside a sandbox.
+//
+// The sandbox gateway only carries HTTP and WebSocket, so raw TCP services
+// such as Postgres or Redis cannot be dialed through it directly. A small
+// helper (bridge.py) is started in the sandbox through envd. It listens on
+// the bridge port and, for each WebSocket request for Path(port), connects to
+// that sandbox port and relays bytes in both directions. The local side is
+// adbtunnel.Tunnel with RemotePort set to the bridge port.
+package tcpbridge
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TencentCloudAgentRuntime/ags-go-sdk/sandbox/code"
+	"github.com/TencentCloudAgentRuntime/ags-go-sdk/tool/command"
+)
+
+// BridgeScript is the sandbox-side bridge, run with python3.
+//
+//go:embed bridge.py
+var BridgeScript []byte
+
+const (
+	// DefaultPort is the sandbox port the bridge listens on unless Port is set.
+	DefaultPort = 49991
+
+	// ScriptPath is where the bridge script is uploaded in the sandbox.
+	ScriptPath = "/tmp/agr-tcp-bridge.py"
+
+	// startTimeout bounds how long Launch waits for the bridge to listen.
+	startTimeout = 15 * time.Second
+)
+
+// Path returns the WebSocket path that bridges to sandbox port.
+func Path(port int) string {
+	return fmt.Sprintf("/tcp/%d", port)
+}
+
+// Options configures the sandbox-side bridge.
+type Options struct {
+	Port   int         // Sandbox port the bridge listens on; defaults to DefaultPort
+	Ports  []int       // Sandbox ports the bridge may connect to
+	User   string      // Sandbox user running the bridge
+	Logger *log.Logger // Receives the bridge's stderr; defaults to log.Default()
+}
+
+// Bridge is a bridge process running in the sandbox.
+type Bridge struct {
+	sandbox *code.Sandbox
+	handle  *command.Handle
+	pattern string
+}
+
+// Launch uploads the bridge script to the sandbox, starts it in the
+// background and waits until it listens. A bridge left behind on the same
+// sandbox port is stopped first.
+func Launch(ctx context.Context, sandbox *code.Sandbox, opts Options) (*Bridge, error) {
+	if opts.Port == 0 {
+		opts.Port = DefaultPort
+	}
+	if len(opts.Ports) == 0 {
+		return nil, fmt.Errorf("at least one sandbox port is required")
+	}
+	ports := make([]string, 0, len(opts.Ports))
+	for _, port := range opts.Ports {
+		if port == opts.Port {
+			return nil, fmt.Errorf("remote port %d is reserved for the TCP bridge", port)
+		}
+		ports = append(ports, strconv.Itoa(port))
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	result, err := sandbox.Commands.Run(ctx, "command -v python3 >/dev/null 2>&1 && echo found || echo missing", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for python3: %w", err)
+	}
+	if strings.TrimSpace(string(result.Stdout)) != "found" {
+		return nil, fmt.Errorf("python3 is required in the sandbox to run the TCP bridge")
+	}
+	if _, err := sandbox.Files.Write(ctx, ScriptPath, bytes.NewReader(BridgeScript), nil); err != nil {
+		return nil, fmt.Errorf("failed to upload TCP bridge: %w", err)
+	}
+
+	pattern := fmt.Sprintf("agr-tcp-bridge.py --listen %d ", opts.Port)
+	_, _ = sandbox.Commands.Run(ctx, fmt.Sprintf("pkill -f '%s' 2>/dev/null || true", pattern), nil, nil)
+
+	ready := make(chan struct{})
+	var readyOnce sync.Once
+	cmd := fmt.Sprintf("python3 %s --listen %d --ports %s", ScriptPath, opts.Port, strings.Join(ports, ","))
+	handle, err := sandbox.Commands.Start(ctx, cmd, &command.ProcessConfig{User: opts.User}, &command.OnOutputConfig{
+		OnStdout: func(data []byte) {
+			if strings.Contains(string(data), "ready") {
+				readyOnce.Do(func() { close(ready) })
+			}
+		},
+		OnStderr: func(data []byte) {
+			for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+				logger.Printf("[sandbox] %s", line)
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start TCP bridge: %w", err)
+	}
+	bridge := &Bridge{sandbox: sandbox, handle: handle, pattern: pattern}
+
+	exited := make(chan struct{})
+	go func() {
+		_, _ = handle.Wait(context.Background())
+		close(exited)
+	}()
+	timer := time.NewTimer(startTimeout)
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return bridge, nil
+	case <-exited:
+		return nil, fmt.Errorf("TCP bridge exited before listening on sandbox port %d", opts.Port)
+	case <-timer.C:
+		err = fmt.Errorf("TCP bridge did not start within %v", startTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = bridge.Stop(stopCtx)
+	return nil, err
+}
+
+// Stop terminates the bridge process.
+func (b *Bridge) Stop(ctx context.Context) error {
+	if b.handle != nil {
+		if err := b.handle.Kill(ctx); err == nil {
+			return nil
+		}
+	}
+	if _, err := b.sandbox.Commands.Run(ctx, fmt.Sprintf("pkill -f '%s' 2>/dev/null || true", b.pattern), nil, nil); err != nil {
+		return fmt.Errorf("failed to stop TCP bridge: %w", err)
+	}
+	return nil
+}
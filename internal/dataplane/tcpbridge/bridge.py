@@ -0,0 +1,206 @@
+#!/usr/bin/env python3
+"""TCP bridge for `agr instance forward --tcp`.
+
+Runs inside the sandbox. The sandbox gateway only carries HTTP and WebSocket,
+so agr dials --listen through the gateway with a WebSocket request for
+/tcp/<port>. The bridge connects to 127.0.0.1:<port> and relays binary
+messages to and from that connection. Only the ports given with --ports are
+reachable. "ready" is printed on stdout once the bridge listens.
+
+Only the Python standard library is used.
+"""
+
+import argparse
+import base64
+import hashlib
+import re
+import socket
+import struct
+import sys
+import threading
+
+GUID = b"258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+OP_CONT, OP_TEXT, OP_BINARY, OP_CLOSE, OP_PING, OP_PONG = 0x0, 0x1, 0x2, 0x8, 0x9, 0xA
+CONNECT_TIMEOUT = 10.0
+CHUNK = 32 * 1024
+PATH = re.compile(rb"^/tcp/(\d+)$")
+
+
+def log(msg):
+    sys.stderr.write("agr-bridge: %s\n" % msg)
+    sys.stderr.flush()
+
+
+def read_exact(sock, n):
+    buf = b""
+    while len(buf) < n:
+        chunk = sock.recv(n - len(buf))
+        if not chunk:
+            return None
+        buf += chunk
+    return buf
+
+
+def unmask(data, mask):
+    if not data:
+        return data
+    key = (mask * (len(data) // 4 + 1))[: len(data)]
+    return (int.from_bytes(data, "big") ^ int.from_bytes(key, "big")).to_bytes(len(data), "big")
+
+
+class Conn:
+    """One WebSocket from agr, relayed to a sandbox TCP connection."""
+
+    def __init__(self, sock, target):
+        self.sock = sock
+        self.target = target
+        self.lock = threading.Lock()
+
+    def send(self, opcode, payload=b""):
+        n = len(payload)
+        if n < 126:
+            header = struct.pack(">BB", 0x80 | opcode, n)
+        elif n < 65536:
+            header = struct.pack(">BBH", 0x80 | opcode, 126, n)
+        else:
+            header = struct.pack(">BBQ", 0x80 | opcode, 127, n)
+        with self.lock:
+            self.sock.sendall(header + payload)
+
+    def recv(self):
+        head = read_exact(self.sock, 2)
+        if head is None:
+            return None, b""
+        opcode, n = head[0] & 0x0F, head[1] & 0x7F
+        if n == 126:
+            ext = read_exact(self.sock, 2)
+            n = struct.unpack(">H", ext)[0] if ext else 0
+        elif n == 127:
+            ext = read_exact(self.sock, 8)
+            n = struct.unpack(">Q", ext)[0] if ext else 0
+        mask = read_exact(self.sock, 4) if head[1] & 0x80 else None
+        payload = read_exact(self.sock, n) if n else b""
+        if payload is None:
+            return None, b""
+        if mask:
+            payload = unmask(payload, mask)
+        return opcode, payload
+
+    def close(self):
+        for s in (self.sock, self.target):
+            try:
+                s.close()
+            except OSError:
+                pass
+
+    def upstream(self):
+        """Reads frames from agr: answers pings and relays data to the target."""
+        try:
+            while True:
+                opcode, payload = self.recv()
+                if opcode is None:
+                    break
+                if opcode == OP_CLOSE:
+                    self.send(OP_CLOSE, payload[:2])
+                    break
+                if opcode == OP_PING:
+                    self.send(OP_PONG, payload)
+                elif opcode in (OP_BINARY, OP_TEXT, OP_CONT):
+                    self.target.sendall(payload)
+        except OSError:
+            pass
+        self.close()
+
+    def downstream(self):
+        """Relays data from the target to agr until either side closes."""
+        try:
+            while True:
+                data = self.target.recv(CHUNK)
+                if not data:
+                    break
+                self.send(OP_BINARY, data)
+            self.send(OP_CLOSE, struct.pack(">H", 1000))
+        except OSError:
+            pass
+        self.close()
+
+
+def respond(sock, status):
+    sock.sendall(b"HTTP/1.1 " + status + b"\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+
+
+def handshake(sock, ports):
+    """Accepts the WebSocket upgrade and returns the connected target."""
+    request = b""
+    while b"\r\n\r\n" not in request:
+        chunk = sock.recv(4096)
+        if not chunk or len(request) > 65536:
+            return None
+        request += chunk
+    lines = request.split(b"\r\n")
+    parts = lines[0].split(b" ")
+    match = PATH.match(parts[1].split(b"?")[0]) if len(parts) == 3 else None
+    if match is None:
+        respond(sock, b"404 Not Found")
+        return None
+    port = int(match.group(1))
+    if port not in ports:
+        respond(sock, b"403 Forbidden")
+        return None
+    key = None
+    for line in lines[1:]:
+        name, _, value = line.partition(b":")
+        if name.strip().lower() == b"sec-websocket-key":
+            key = value.strip()
+    if key is None:
+        respond(sock, b"400 Bad Request")
+        return None
+    try:
+        target = socket.create_connection(("127.0.0.1", port), timeout=CONNECT_TIMEOUT)
+    except OSError as exc:
+        log("port %d is not reachable: %s" % (port, exc))
+        respond(sock, b"502 Bad Gateway")
+        return None
+    target.settimeout(None)
+    accept = base64.b64encode(hashlib.sha1(key + GUID).digest())
+    sock.sendall(
+        b"HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n"
+        b"Connection: Upgrade\r\nSec-WebSocket-Accept: " + accept + b"\r\n\r\n"
+    )
+    return target
+
+
+def serve(sock, ports):
+    try:
+        target = handshake(sock, ports)
+    except OSError:
+        target = None
+    if target is None:
+        sock.close()
+        return
+    conn = Conn(sock, target)
+    threading.Thread(target=conn.upstream, daemon=True).start()
+    conn.downstream()
+
+
+def main():
+    parser = argparse.ArgumentParser()
+    parser.add_argument("--listen", type=int, required=True)
+    parser.add_argument("--ports", required=True, help="comma-separated sandbox ports agr may reach")
+    args = parser.parse_args()
+    ports = {int(p) for p in args.ports.split(",") if p}
+
+    server = socket.socket(socket.AF_INET, socket.SOCK_STREAM)
+    server.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+    server.bind(("0.0.0.0", args.listen))
+    server.listen(128)
+    log("listening on %d for ports %s" % (args.listen, ",".join(str(p) for p in sorted(ports))))
+    sys.stdout.write("ready\n")
+    sys.stdout.flush()
+    while True:
+        sock, _ = server.accept()
+        threading.Thread(target=serve, args=(sock, ports), daemon=True).start()
+
+
+if __name__ == "__main__":
+    main()
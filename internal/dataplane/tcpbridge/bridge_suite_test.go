@@ -0,0 +1,13 @@
+package tcpbridge
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTCPBridge(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TCP Bridge Suite")
+}
@@ -0,0 +1,123 @@
+package tcpbridge
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// echoServer accepts TCP connections and echoes every line back upper-cased.
+func echoServer() net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		Skip("local listener unavailable: " + err.Error())
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				scanner.Buffer(make([]byte, 0, 1<<20), 1<<20)
+				for scanner.Scan() {
+					_, _ = fmt.Fprintln(conn, strings.ToUpper(scanner.Text()))
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func freePort() int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		Skip("local listener unavailable: " + err.Error())
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+var _ = Describe("TCP bridge", func() {
+	var (
+		listenPort int
+		echoPort   int
+		closedPort int
+	)
+
+	BeforeEach(func() {
+		python, err := exec.LookPath("python3")
+		if err != nil {
+			Skip("python3 unavailable")
+		}
+		echo := echoServer()
+		DeferCleanup(echo.Close)
+		echoPort = echo.Addr().(*net.TCPAddr).Port
+		listenPort, closedPort = freePort(), freePort()
+
+		script := filepath.Join(GinkgoT().TempDir(), "bridge.py")
+		Expect(os.WriteFile(script, BridgeScript, 0o644)).To(Succeed())
+		cmd := exec.Command(python, script, "--listen", fmt.Sprint(listenPort), "--ports", fmt.Sprintf("%d,%d", echoPort, closedPort))
+		cmd.Stderr = GinkgoWriter
+		stdout, err := cmd.StdoutPipe()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cmd.Start()).To(Succeed())
+		DeferCleanup(func() { _ = cmd.Process.Kill(); _ = cmd.Wait() })
+		line, err := bufio.NewReader(stdout).ReadString('\n')
+		Expect(err).NotTo(HaveOccurred())
+		Expect(line).To(Equal("ready\n"))
+	})
+
+	dial := func(port int) (*websocket.Conn, *http.Response, error) {
+		return websocket.DefaultDialer.Dial(fmt.Sprintf("ws://127.0.0.1:%d%s", listenPort, Path(port)), nil)
+	}
+
+	It("relays a WebSocket to the requested sandbox port", func() {
+		conn, _, err := dial(echoPort)
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		pongs := make(chan string, 1)
+		conn.SetPongHandler(func(data string) error { pongs <- data; return nil })
+		Expect(conn.WriteControl(websocket.PingMessage, []byte("keepalive"), time.Now().Add(time.Second))).To(Succeed())
+
+		big := strings.Repeat("x", 100000) + "\n"
+		Expect(conn.WriteMessage(websocket.BinaryMessage, []byte("hello\n"+big))).To(Succeed())
+		var got strings.Builder
+		for got.Len() < len("HELLO\n")+len(big) {
+			msgType, data, err := conn.ReadMessage()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(msgType).To(Equal(websocket.BinaryMessage))
+			got.Write(data)
+		}
+		Expect(got.String()).To(Equal("HELLO\n" + strings.ToUpper(big)))
+		Expect(pongs).To(Receive(Equal("keepalive")))
+	})
+
+	It("refuses unlisted, unreachable and malformed targets", func() {
+		_, resp, err := dial(listenPort)
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+		_, resp, err = dial(closedPort)
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+
+		_, resp, err = websocket.DefaultDialer.Dial(fmt.Sprintf("ws://127.0.0.1:%d/", listenPort), nil)
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
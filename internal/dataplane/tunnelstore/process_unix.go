@@ -3,6 +3,7 @@
 package tunnelstore
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"strconv"
@@ -11,8 +12,9 @@ import (
 	"time"
 )
 
-// isProcessAlive checks if a process is still running by sending signal 0.
-func isProcessAlive(pid int) bool {
+// IsProcessAlive checks if a process is still running by sending signal 0.
+// A process owned by another user still counts as running.
+func IsProcessAlive(pid int) bool {
 	if pid <= 0 {
 		return false
 	}
@@ -21,7 +23,7 @@ func isProcessAlive(pid int) bool {
 		return false
 	}
 	err = process.Signal(syscall.Signal(0))
-	return err == nil
+	return err == nil || errors.Is(err, syscall.EPERM)
 }
 
 // isOurTunnelProcess checks if the process at pid is actually our tunnel process
@@ -31,7 +33,7 @@ func isOurTunnelProcess(pid int, expectedExePath string) bool {
 	if pid <= 0 {
 		return false
 	}
-	if !isProcessAlive(pid) {
+	if !IsProcessAlive(pid) {
 		return false
 	}
 	if expectedExePath == "" {
@@ -78,7 +80,7 @@ func killProcess(pid int, exePath string) bool {
 	if !isOurTunnelProcess(pid, exePath) {
 		// Process either doesn't exist (dead) or identity doesn't match (PID reused).
 		// Check if the PID is alive at all to distinguish the two cases.
-		if !isProcessAlive(pid) {
+		if !IsProcessAlive(pid) {
 			return true // Process is dead, safe to clean up entry
 		}
 		return false // PID reused by different process, cannot kill
@@ -95,7 +97,7 @@ func killProcess(pid int, exePath string) bool {
 	}
 
 	// Poll for exit using identity-aware check. We use isOurTunnelProcess
-	// (not just isProcessAlive) so that if the PID is reused by another process
+	// (not just IsProcessAlive) so that if the PID is reused by another process
 	// during this window, we detect the identity change and stop instead of
 	// sending SIGKILL to an unrelated process.
 	deadline := time.Now().Add(5 * time.Second)
@@ -111,7 +113,7 @@ func killProcess(pid int, exePath string) bool {
 		_ = process.Signal(syscall.SIGKILL)
 		// Give SIGKILL a moment to take effect
 		time.Sleep(100 * time.Millisecond)
-		return !isProcessAlive(pid)
+		return !IsProcessAlive(pid)
 	}
 	return true // Identity changed during final check, original process is gone
 }
@@ -3,38 +3,38 @@
 package tunnelstore
 
 import (
+	"errors"
 	"os"
-	"strings"
+	"syscall"
 )
 
-// isProcessAlive checks if a process is still running on Windows.
-// On Windows, os.FindProcess always succeeds, so we try to open the process
-// with limited access to verify its existence.
-func isProcessAlive(pid int) bool {
+// processQueryLimitedInformation is PROCESS_QUERY_LIMITED_INFORMATION, the
+// least access right that allows GetExitCodeProcess.
+const processQueryLimitedInformation = 0x1000
+
+// stillActive is the exit code GetExitCodeProcess reports for a running
+// process (STILL_ACTIVE).
+const stillActive = 259
+
+// IsProcessAlive checks if a process is still running on Windows.
+// On Windows, os.FindProcess always succeeds and signals other than Kill are
+// not supported, so the process is opened and its exit code queried instead.
+func IsProcessAlive(pid int) bool {
 	if pid <= 0 {
 		return false
 	}
-	process, err := os.FindProcess(pid)
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
 	if err != nil {
-		return false
+		// "Access is denied" means the process exists but belongs to a more
+		// privileged user.
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
 	}
-	// On Windows, Signal(0) is not supported. Instead, try to wait with
-	// a zero timeout — if the process exists, Wait would block (or fail
-	// differently than for a non-existent process). Use a pragmatic approach:
-	// attempt to send os.Interrupt which is a no-op on Windows for non-console
-	// processes but returns an error if the process doesn't exist.
-	err = process.Signal(os.Interrupt)
-	// If error contains "not supported" or "Access is denied", process exists
-	// (we just can't signal it). If "process has already finished", it's dead.
-	if err == nil {
-		return true
-	}
-	errStr := err.Error()
-	// On Windows, "Access is denied" means the process exists but we can't signal it
-	if strings.Contains(errStr, "Access is denied") || strings.Contains(errStr, "not supported") {
-		return true
+	defer func() { _ = syscall.CloseHandle(handle) }()
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
 	}
-	return false
+	return code == stillActive
 }
 
 // isOurTunnelProcess checks if the process at pid is actually our tunnel process.
@@ -47,7 +47,7 @@ func isProcessAlive(pid int) bool {
 // windows.OpenProcess + windows.GetProcessImageFileName to retrieve the process
 // executable path and compare against expectedExePath for full PID reuse protection.
 func isOurTunnelProcess(pid int, expectedExePath string) bool {
-	return isProcessAlive(pid)
+	return IsProcessAlive(pid)
 }
 
 // killProcess terminates a process on Windows using TerminateProcess (via os.Process.Kill).
@@ -58,7 +58,7 @@ func killProcess(pid int, exePath string) bool {
 		return true
 	}
 	if !isOurTunnelProcess(pid, exePath) {
-		if !isProcessAlive(pid) {
+		if !IsProcessAlive(pid) {
 			return true // Process is dead
 		}
 		return false // PID reused by different process
@@ -68,5 +68,5 @@ func killProcess(pid int, exePath string) bool {
 		return true
 	}
 	_ = process.Kill()
-	return !isProcessAlive(pid)
+	return !IsProcessAlive(pid)
 }
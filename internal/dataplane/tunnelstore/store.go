@@ -3,19 +3,22 @@
 // It provides cross-process safe read/write access to the tunnel registry using
 // file-level locking (flock on Unix, LockFileEx on Windows) and atomic writes.
 // Zombie tunnel entries (where the backing process has died) are automatically
-// cleaned on List and Get operations.
+// cleaned on List, ListAll and Get operations.
+//
+// The registry holds both ADB tunnels started by `instance mobile connect`,
+// keyed by instance ID, and background port forwards started by
+// `instance proxy --background`, keyed by ProxyKey.
 package tunnelstore
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/gofrs/flock"
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/jsonfile"
 )
 
 const (
@@ -23,12 +26,22 @@ const (
 	storeDir = ".agr"
 	// storeFile is the filename for tunnel registry.
 	storeFile = "tunnels.json"
-	// lockTimeout is the maximum wait time to acquire the file lock.
-	lockTimeout = 3 * time.Second
-	// lockRetryDelay is the interval between lock acquisition attempts.
-	lockRetryDelay = 100 * time.Millisecond
 )
 
+const (
+	// TypeADB marks an ADB tunnel. Entries written before the type field
+	// existed are ADB tunnels.
+	TypeADB = "adb"
+	// TypeProxy marks a background `instance proxy` port forward.
+	TypeProxy = "proxy"
+)
+
+// ProxyKey returns the registry key of a background proxy listening on
+// localPort for instanceID.
+func ProxyKey(instanceID string, localPort int) string {
+	return fmt.Sprintf("proxy:%s:%d", instanceID, localPort)
+}
+
 // TunnelEntry represents a single active tunnel mapping.
 type TunnelEntry struct {
 	PID       int       `json:"pid"`
@@ -41,12 +54,61 @@ type TunnelEntry struct {
 	Status string `json:"status,omitempty"`
 	// DegradedAt records when the tunnel entered degraded mode. Zero/nil when healthy.
 	DegradedAt *time.Time `json:"degraded_at,omitempty"`
+
+	// Type is TypeADB or TypeProxy; empty means TypeADB.
+	Type string `json:"type,omitempty"`
+	// InstanceID is the sandbox instance of a proxy entry, whose key is not
+	// the instance ID.
+	InstanceID string `json:"instance_id,omitempty"`
+	// Address is the local bind address of a proxy entry.
+	Address string `json:"address,omitempty"`
+	// RemotePort is the sandbox port a proxy entry forwards to.
+	RemotePort int `json:"remote_port,omitempty"`
+	// LogPath is the daemon's log file.
+	LogPath string `json:"log_path,omitempty"`
+
+	// SupervisorPID is the process that restarts the tunnel daemon when it
+	// dies, set by `instance mobile connect --supervise`. The entry stays
+	// registered while the supervisor runs, even between restarts.
+	SupervisorPID int `json:"supervisor_pid,omitempty"`
+	// Restarts counts the tunnel daemons the supervisor has restarted.
+	Restarts int `json:"restarts,omitempty"`
+	// RestartedAt records the last restart.
+	RestartedAt *time.Time `json:"restarted_at,omitempty"`
+	// SupervisorExit records why the supervisor gave up on the tunnel, for
+	// example after repeated failed restarts or once the instance is gone.
+	SupervisorExit string `json:"supervisor_exit,omitempty"`
+}
+
+// Kind returns the entry type, defaulting to TypeADB for legacy entries.
+func (e TunnelEntry) Kind() string {
+	if e.Type == "" {
+		return TypeADB
+	}
+	return e.Type
+}
+
+// Alive reports whether the tunnel daemon of the entry is running.
+func (e TunnelEntry) Alive() bool {
+	return IsProcessAlive(e.PID)
+}
+
+// Supervised reports whether a supervisor is running for the entry.
+func (e TunnelEntry) Supervised() bool {
+	return e.SupervisorPID > 0 && IsProcessAlive(e.SupervisorPID)
+}
+
+// Instance returns the sandbox instance the entry belongs to.
+func (e TunnelEntry) Instance(key string) string {
+	if e.InstanceID != "" {
+		return e.InstanceID
+	}
+	return key
 }
 
 // Store manages the tunnel registry file with cross-process locking.
 type Store struct {
-	path     string // path to tunnels.json
-	lockPath string // path to tunnels.json.lock
+	file *jsonfile.File // tunnels.json, locked through tunnels.json.lock
 }
 
 // CorruptStoreRecoveredError reports that a malformed tunnel registry was moved
@@ -73,26 +135,17 @@ func NewStore() (*Store, error) {
 		return nil, fmt.Errorf("failed to create store directory: %w", err)
 	}
 
-	storePath := filepath.Join(dir, storeFile)
-	lockPath := storePath + ".lock"
-
-	return &Store{
-		path:     storePath,
-		lockPath: lockPath,
-	}, nil
+	return &Store{file: jsonfile.New(filepath.Join(dir, storeFile), "store")}, nil
 }
 
 // Save registers or updates a tunnel entry for the given sandbox ID.
 // Uses exclusive file lock + atomic write for cross-process safety.
 func (s *Store) Save(sandboxID string, entry TunnelEntry) error {
-	fl := flock.New(s.lockPath)
-	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
-	defer cancel()
-	locked, err := fl.TryLockContext(ctx, lockRetryDelay)
-	if err != nil || !locked {
-		return fmt.Errorf("failed to acquire store lock: %w", err)
+	unlock, err := s.file.Lock()
+	if err != nil {
+		return err
 	}
-	defer func() { _ = fl.Unlock() }()
+	defer unlock()
 
 	entries, err := s.loadLocked()
 	if err != nil {
@@ -106,14 +159,11 @@ func (s *Store) Save(sandboxID string, entry TunnelEntry) error {
 // Remove deletes the tunnel entry for the given sandbox ID.
 // Uses exclusive file lock + atomic write.
 func (s *Store) Remove(sandboxID string) error {
-	fl := flock.New(s.lockPath)
-	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
-	defer cancel()
-	locked, err := fl.TryLockContext(ctx, lockRetryDelay)
-	if err != nil || !locked {
-		return fmt.Errorf("failed to acquire store lock: %w", err)
+	unlock, err := s.file.Lock()
+	if err != nil {
+		return err
 	}
-	defer func() { _ = fl.Unlock() }()
+	defer unlock()
 
 	entries, err := s.loadLocked()
 	if err != nil {
@@ -126,9 +176,9 @@ func (s *Store) Remove(sandboxID string) error {
 
 // Get retrieves a single tunnel entry. Returns the entry and true if found,
 // zero value and false if not found, or an error if the store cannot be read.
-// Automatically removes zombie entries via List().
+// Automatically removes zombie entries via ListAll().
 func (s *Store) Get(sandboxID string) (TunnelEntry, bool, error) {
-	entries, err := s.List()
+	entries, err := s.ListAll()
 	if err != nil {
 		return TunnelEntry{}, false, err
 	}
@@ -136,17 +186,30 @@ func (s *Store) Get(sandboxID string) (TunnelEntry, bool, error) {
 	return entry, ok, nil
 }
 
-// List returns all live tunnel entries. Dead entries (where PID is no longer
-// alive) are automatically cleaned up.
+// List returns the live ADB tunnel entries keyed by instance ID. Dead entries
+// of every type are cleaned up as in ListAll.
 func (s *Store) List() (map[string]TunnelEntry, error) {
-	fl := flock.New(s.lockPath)
-	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
-	defer cancel()
-	locked, err := fl.TryLockContext(ctx, lockRetryDelay)
-	if err != nil || !locked {
-		return nil, fmt.Errorf("failed to acquire store lock: %w", err)
+	entries, err := s.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	for key, entry := range entries {
+		if entry.Kind() != TypeADB {
+			delete(entries, key)
+		}
+	}
+	return entries, nil
+}
+
+// ListAll returns all live tunnel entries of every type. Dead entries (where
+// PID is no longer alive and no supervisor runs) are automatically cleaned up,
+// except those whose supervisor recorded why it gave up.
+func (s *Store) ListAll() (map[string]TunnelEntry, error) {
+	unlock, err := s.file.Lock()
+	if err != nil {
+		return nil, err
 	}
-	defer func() { _ = fl.Unlock() }()
+	defer unlock()
 
 	entries, err := s.loadLocked()
 	if err != nil {
@@ -156,7 +219,7 @@ func (s *Store) List() (map[string]TunnelEntry, error) {
 	// Clean zombies
 	cleaned := false
 	for id, entry := range entries {
-		if !isProcessAlive(entry.PID) {
+		if !entry.Alive() && !entry.Supervised() && entry.SupervisorExit == "" {
 			delete(entries, id)
 			cleaned = true
 		}
@@ -172,91 +235,131 @@ func (s *Store) List() (map[string]TunnelEntry, error) {
 // Cleanup kills the tunnel process for the given sandbox ID (if alive)
 // and removes its entry from the store. If the process cannot be confirmed
 // dead (e.g. PID reused by another process), the entry is preserved and
-// an error is returned.
+// an error is returned. The processes are stopped without holding the
+// registry lock, which waiting for them could hold for seconds.
 func (s *Store) Cleanup(sandboxID string) error {
-	fl := flock.New(s.lockPath)
-	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
-	defer cancel()
-	locked, err := fl.TryLockContext(ctx, lockRetryDelay)
-	if err != nil || !locked {
-		return fmt.Errorf("failed to acquire store lock: %w", err)
+	entry, ok, err := s.lookup(sandboxID)
+	if err != nil || !ok {
+		return err
 	}
-	defer func() { _ = fl.Unlock() }()
-
-	entries, err := s.loadLocked()
-	if err != nil {
+	// Stop the supervisor first so that it does not restart the tunnel.
+	if !killProcess(entry.SupervisorPID, entry.ExePath) {
+		return fmt.Errorf("tunnel supervisor (PID %d) could not be terminated — it may have been replaced by another process; entry preserved for manual cleanup", entry.SupervisorPID)
+	}
+	// The supervisor may have restarted the tunnel before it stopped.
+	if entry, ok, err = s.lookup(sandboxID); err != nil || !ok {
 		return err
 	}
-
-	if entry, ok := entries[sandboxID]; ok {
-		if !killProcess(entry.PID, entry.ExePath) {
-			// Process could not be killed (PID reused or still alive).
-			// Keep the entry so the user knows the tunnel may still be running.
-			return fmt.Errorf("tunnel process (PID %d) could not be terminated — it may have been replaced by another process; entry preserved for manual cleanup", entry.PID)
-		}
-		delete(entries, sandboxID)
-		return s.saveLocked(entries)
+	if !killProcess(entry.PID, entry.ExePath) {
+		// Process could not be killed (PID reused or still alive).
+		// Keep the entry so the user knows the tunnel may still be running.
+		return fmt.Errorf("tunnel process (PID %d) could not be terminated — it may have been replaced by another process; entry preserved for manual cleanup", entry.PID)
 	}
-
-	return nil
+	return s.removeStopped(map[string]TunnelEntry{sandboxID: entry})
 }
 
 // UpdateStatus updates the Status and DegradedAt fields of an existing tunnel entry.
 // Only modifies these fields; PID, Port, CreatedAt, ExePath are preserved.
 // This is called by the tunnel daemon when entering or exiting degraded mode.
 func (s *Store) UpdateStatus(sandboxID string, status string, degradedAt *time.Time) error {
-	fl := flock.New(s.lockPath)
-	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
-	defer cancel()
-	locked, err := fl.TryLockContext(ctx, lockRetryDelay)
-	if err != nil || !locked {
-		return fmt.Errorf("failed to acquire store lock: %w", err)
+	_, err := s.update(sandboxID, func(entry *TunnelEntry) {
+		entry.Status = status
+		entry.DegradedAt = degradedAt
+	})
+	return err
+}
+
+// SetSupervisor records pid as the supervisor of an existing tunnel entry.
+// It reports whether the entry exists.
+func (s *Store) SetSupervisor(sandboxID string, pid int) (bool, error) {
+	return s.update(sandboxID, func(entry *TunnelEntry) {
+		entry.SupervisorPID = pid
+	})
+}
+
+// RecordRestart records that the supervisor restarted the tunnel daemon of an
+// existing entry as process pid. The restart count is incremented and the
+// health state reset.
+func (s *Store) RecordRestart(sandboxID string, pid int, at time.Time) error {
+	_, err := s.update(sandboxID, func(entry *TunnelEntry) {
+		entry.PID = pid
+		entry.Restarts++
+		entry.RestartedAt = &at
+		entry.Status = ""
+		entry.DegradedAt = nil
+	})
+	return err
+}
+
+// RecordSupervisorExit records that supervisor pid gave up on the tunnel of
+// an existing entry for reason. The entry is marked unreachable so that
+// `instance mobile list --prune` removes it. An entry claimed by another
+// supervisor is left alone.
+func (s *Store) RecordSupervisorExit(sandboxID string, pid int, reason string, at time.Time) error {
+	_, err := s.update(sandboxID, func(entry *TunnelEntry) {
+		if entry.SupervisorPID != pid {
+			return
+		}
+		entry.SupervisorPID = 0
+		entry.SupervisorExit = reason
+		entry.Status = "unreachable"
+		entry.DegradedAt = &at
+	})
+	return err
+}
+
+// update applies fn to an existing entry under the lock. A missing entry
+// (possibly already cleaned up) is left alone and reported as false.
+func (s *Store) update(sandboxID string, fn func(*TunnelEntry)) (bool, error) {
+	unlock, err := s.file.Lock()
+	if err != nil {
+		return false, err
 	}
-	defer func() { _ = fl.Unlock() }()
+	defer unlock()
 
 	entries, err := s.loadLocked()
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	entry, ok := entries[sandboxID]
 	if !ok {
-		return nil // Entry doesn't exist (possibly already cleaned up)
+		return false, nil
 	}
-
-	entry.Status = status
-	entry.DegradedAt = degradedAt
+	fn(&entry)
 	entries[sandboxID] = entry
-	return s.saveLocked(entries)
+	return true, s.saveLocked(entries)
 }
 
-// CleanupAll kills all tunnel processes and clears the store.
-// Entries whose processes cannot be confirmed dead are preserved.
+// CleanupAll kills all ADB tunnel processes and removes their entries.
+// Entries whose processes cannot be confirmed dead are preserved, and
+// background proxies are left running. As in Cleanup, the processes are
+// stopped without holding the registry lock.
 func (s *Store) CleanupAll() error {
-	fl := flock.New(s.lockPath)
-	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
-	defer cancel()
-	locked, err := fl.TryLockContext(ctx, lockRetryDelay)
-	if err != nil || !locked {
-		return fmt.Errorf("failed to acquire store lock: %w", err)
+	unlock, err := s.file.Lock()
+	if err != nil {
+		return err
 	}
-	defer func() { _ = fl.Unlock() }()
-
 	entries, err := s.loadLocked()
+	unlock()
 	if err != nil {
 		return err
 	}
 
+	stopped := map[string]TunnelEntry{}
 	var warnings []string
 	for id, entry := range entries {
-		if killProcess(entry.PID, entry.ExePath) {
-			delete(entries, id)
+		if entry.Kind() != TypeADB {
+			continue
+		}
+		if killProcess(entry.SupervisorPID, entry.ExePath) && killProcess(entry.PID, entry.ExePath) {
+			stopped[id] = entry
 		} else {
 			warnings = append(warnings, fmt.Sprintf("PID %d (%s)", entry.PID, id))
 		}
 	}
 
-	if err := s.saveLocked(entries); err != nil {
+	if err := s.removeStopped(stopped); err != nil {
 		return err
 	}
 
@@ -266,80 +369,65 @@ func (s *Store) CleanupAll() error {
 	return nil
 }
 
-// loadLocked reads the store file. Must be called while holding the lock.
-func (s *Store) loadLocked() (map[string]TunnelEntry, error) {
-	// Defense-in-depth: reject symlinks to prevent redirection attacks
-	if info, err := os.Lstat(s.path); err == nil {
-		if info.Mode()&os.ModeSymlink != 0 {
-			return nil, fmt.Errorf("store file is a symlink (rejected for security): %s", s.path)
-		}
-	}
-
-	data, err := os.ReadFile(s.path)
+// lookup returns the entry of sandboxID as stored, without cleaning up dead
+// entries.
+func (s *Store) lookup(sandboxID string) (TunnelEntry, bool, error) {
+	unlock, err := s.file.Lock()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return make(map[string]TunnelEntry), nil
-		}
-		return nil, fmt.Errorf("failed to read store file: %w", err)
+		return TunnelEntry{}, false, err
 	}
+	defer unlock()
 
-	entries := make(map[string]TunnelEntry)
-	if err := json.Unmarshal(data, &entries); err != nil {
-		backupPath := s.path + fmt.Sprintf(".corrupt-%d", time.Now().Unix())
-		if renameErr := os.Rename(s.path, backupPath); renameErr != nil {
-			return nil, fmt.Errorf("tunnel state is corrupted and could not be backed up: %w", err)
-		}
-		_ = s.saveLocked(make(map[string]TunnelEntry))
-		return make(map[string]TunnelEntry), &CorruptStoreRecoveredError{Path: s.path, BackupPath: backupPath}
+	entries, err := s.loadLocked()
+	if err != nil {
+		return TunnelEntry{}, false, err
 	}
-
-	return entries, nil
+	entry, ok := entries[sandboxID]
+	return entry, ok, nil
 }
 
-// saveLocked writes the entries to a temp file then atomically renames it.
-// Must be called while holding the lock.
-func (s *Store) saveLocked(entries map[string]TunnelEntry) error {
-	// Defense-in-depth: reject symlinks to prevent redirection attacks
-	if info, err := os.Lstat(s.path); err == nil {
-		if info.Mode()&os.ModeSymlink != 0 {
-			return fmt.Errorf("store file is a symlink (rejected for security): %s", s.path)
-		}
+// removeStopped removes the entries whose processes were stopped. An entry
+// replaced in the meantime by a new tunnel is kept.
+func (s *Store) removeStopped(stopped map[string]TunnelEntry) error {
+	if len(stopped) == 0 {
+		return nil
 	}
-
-	data, err := json.MarshalIndent(entries, "", "  ")
+	unlock, err := s.file.Lock()
 	if err != nil {
-		return fmt.Errorf("failed to marshal store data: %w", err)
+		return err
 	}
+	defer unlock()
 
-	// Atomic write: write to temp file in same directory, then rename.
-	dir := filepath.Dir(s.path)
-	tmpFile, err := os.CreateTemp(dir, "tunnels-*.json.tmp")
+	entries, err := s.loadLocked()
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-
-	if _, err := tmpFile.Write(data); err != nil {
-		_ = tmpFile.Close()
-		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to write temp file: %w", err)
-	}
-
-	if err := tmpFile.Chmod(0600); err != nil {
-		_ = tmpFile.Close()
-		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to set temp file permissions: %w", err)
+		return err
 	}
-
-	if err := tmpFile.Close(); err != nil {
-		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to close temp file: %w", err)
+	for id, entry := range stopped {
+		if current, ok := entries[id]; ok && current.PID == entry.PID {
+			delete(entries, id)
+		}
 	}
+	return s.saveLocked(entries)
+}
 
-	if err := os.Rename(tmpPath, s.path); err != nil {
-		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to rename temp file: %w", err)
+// loadLocked reads the store file. Must be called while holding the lock.
+// A corrupt file is moved aside and replaced by an empty registry, which is
+// reported as a *CorruptStoreRecoveredError.
+func (s *Store) loadLocked() (map[string]TunnelEntry, error) {
+	entries := make(map[string]TunnelEntry)
+	if _, err := s.file.Load(&entries); err != nil {
+		var corrupt *jsonfile.CorruptError
+		if !errors.As(err, &corrupt) {
+			return nil, err
+		}
+		_ = s.saveLocked(make(map[string]TunnelEntry))
+		return make(map[string]TunnelEntry), &CorruptStoreRecoveredError{Path: corrupt.Path, BackupPath: corrupt.BackupPath}
 	}
+	return entries, nil
+}
 
-	return nil
+// saveLocked writes the entries atomically. Must be called while holding the
+// lock.
+func (s *Store) saveLocked(entries map[string]TunnelEntry) error {
+	return s.file.Save(entries)
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/TencentCloudAgentRuntime/ags-cli/internal/jsonfile"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -18,8 +19,7 @@ func TestTunnelStore(t *testing.T) {
 
 func newBDDStore() *Store {
 	dir := GinkgoT().TempDir()
-	storePath := filepath.Join(dir, "tunnels.json")
-	return &Store{path: storePath, lockPath: storePath + ".lock"}
+	return &Store{file: jsonfile.New(filepath.Join(dir, "tunnels.json"), "store")}
 }
 
 var _ = Describe("Tunnel store", func() {
@@ -27,7 +27,7 @@ var _ = Describe("Tunnel store", func() {
 		store, err := NewStore()
 		Expect(err).NotTo(HaveOccurred())
 		Expect(store).NotTo(BeNil())
-		Expect(store.path).NotTo(BeEmpty())
+		Expect(store.file.Path()).NotTo(BeEmpty())
 	})
 
 	It("saves, gets, overwrites and removes entries", func() {
@@ -68,7 +68,7 @@ var _ = Describe("Tunnel store", func() {
 		Expect(store.Cleanup("sandbox")).To(Succeed())
 		Expect(store.Cleanup("missing")).To(Succeed())
 		Expect(store.Save("sandbox", TunnelEntry{PID: os.Getpid(), Port: 15555, CreatedAt: time.Now()})).To(Succeed())
-		data, err := os.ReadFile(store.path)
+		data, err := os.ReadFile(store.file.Path())
 		Expect(err).NotTo(HaveOccurred())
 		var entries map[string]TunnelEntry
 		Expect(json.Unmarshal(data, &entries)).To(Succeed())
@@ -77,4 +77,94 @@ var _ = Describe("Tunnel store", func() {
 		Expect(store.Save("dead-a", TunnelEntry{PID: 99999997, Port: 1, CreatedAt: time.Now()})).To(Succeed())
 		Expect(store.CleanupAll()).To(Succeed())
 	})
+
+	It("keeps an entry replaced while its old process was stopped", func() {
+		store := newBDDStore()
+		// A connect saved a new tunnel after Cleanup read PID 99999995.
+		Expect(store.Save("sandbox", TunnelEntry{PID: os.Getpid(), Port: 15555, CreatedAt: time.Now()})).To(Succeed())
+		Expect(store.removeStopped(map[string]TunnelEntry{"sandbox": {PID: 99999995}})).To(Succeed())
+		got, ok, err := store.Get("sandbox")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(got.PID).To(Equal(os.Getpid()))
+
+		Expect(store.removeStopped(map[string]TunnelEntry{"sandbox": got})).To(Succeed())
+		_, ok, err = store.Get("sandbox")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("separates proxy entries from ADB tunnels", func() {
+		store := newBDDStore()
+		Expect(store.Save("sandbox", TunnelEntry{PID: os.Getpid(), Port: 15555, CreatedAt: time.Now()})).To(Succeed())
+		key := ProxyKey("sandbox", 3000)
+		Expect(key).To(Equal("proxy:sandbox:3000"))
+		Expect(store.Save(key, TunnelEntry{PID: os.Getpid(), Port: 3000, Type: TypeProxy, InstanceID: "sandbox", RemotePort: 80, CreatedAt: time.Now()})).To(Succeed())
+
+		adb, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adb).To(HaveLen(1))
+		Expect(adb["sandbox"].Kind()).To(Equal(TypeADB))
+
+		all, err := store.ListAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(all).To(HaveLen(2))
+		Expect(all[key].Instance(key)).To(Equal("sandbox"))
+		Expect(all["sandbox"].Instance("sandbox")).To(Equal("sandbox"))
+
+		got, ok, err := store.Get(key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(got.RemotePort).To(Equal(80))
+	})
+
+	It("keeps supervised entries and records restarts", func() {
+		store := newBDDStore()
+		Expect(store.Save("sandbox", TunnelEntry{PID: 99999996, Port: 15555, CreatedAt: time.Now(), Status: "unreachable"})).To(Succeed())
+		ok, err := store.SetSupervisor("sandbox", os.Getpid())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		got, ok, err := store.Get("sandbox")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(got.Alive()).To(BeFalse())
+		Expect(got.Supervised()).To(BeTrue())
+
+		at := time.Now().Truncate(time.Second)
+		Expect(store.RecordRestart("sandbox", os.Getpid(), at)).To(Succeed())
+		Expect(store.RecordRestart("sandbox", os.Getpid(), at)).To(Succeed())
+		got, _, err = store.Get("sandbox")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Restarts).To(Equal(2))
+		Expect(got.RestartedAt.Equal(at)).To(BeTrue())
+		Expect(got.Status).To(BeEmpty())
+		Expect(got.Port).To(Equal(15555))
+
+		ok, err = store.SetSupervisor("missing", os.Getpid())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps the entry of a supervisor that gave up with its reason", func() {
+		store := newBDDStore()
+		Expect(store.Save("sandbox", TunnelEntry{PID: 99999996, Port: 15555, CreatedAt: time.Now()})).To(Succeed())
+		_, err := store.SetSupervisor("sandbox", os.Getpid())
+		Expect(err).NotTo(HaveOccurred())
+
+		at := time.Now().Truncate(time.Second)
+		Expect(store.RecordSupervisorExit("sandbox", os.Getpid()+1, "replaced", at)).To(Succeed())
+		got, _, err := store.Get("sandbox")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.SupervisorExit).To(BeEmpty())
+
+		Expect(store.RecordSupervisorExit("sandbox", os.Getpid(), "instance sandbox is STOPPED", at)).To(Succeed())
+		got, ok, err := store.Get("sandbox")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(got.SupervisorPID).To(BeZero())
+		Expect(got.SupervisorExit).To(Equal("instance sandbox is STOPPED"))
+		Expect(got.Status).To(Equal("unreachable"))
+		Expect(got.DegradedAt.Equal(at)).To(BeTrue())
+	})
 })
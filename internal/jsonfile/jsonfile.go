@@ -0,0 +1,148 @@
+// Package jsonfile provides cross-process safe access to small JSON state
+// files under ~/.agr.
+//
+// A File is guarded by a sibling ".lock" file (flock on Unix, LockFileEx on
+// Windows). Reads and writes reject symlinks, malformed files are moved aside
+// to a ".corrupt-<unix>" backup, and writes go to a temp file in the same
+// directory that is atomically renamed over the original.
+package jsonfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	// lockTimeout is the maximum wait time to acquire the file lock.
+	lockTimeout = 3 * time.Second
+	// lockRetryDelay is the interval between lock acquisition attempts.
+	lockRetryDelay = 100 * time.Millisecond
+)
+
+// File is a JSON document guarded by a lock file.
+type File struct {
+	path     string
+	lockPath string
+	name     string
+}
+
+// New returns a File stored at path. name describes the file in error
+// messages, for example "pool store".
+func New(path, name string) *File {
+	return &File{path: path, lockPath: path + ".lock", name: name}
+}
+
+// Path returns the path of the JSON document.
+func (f *File) Path() string {
+	return f.path
+}
+
+// CorruptError reports that a malformed file was moved to BackupPath.
+type CorruptError struct {
+	Name       string
+	Path       string
+	BackupPath string
+	Err        error
+}
+
+// Error returns the message shown when a corrupt file is backed up.
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("%s is corrupted and was moved to %s: %v", e.Name, e.BackupPath, e.Err)
+}
+
+// Unwrap returns the JSON decoding error.
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// Lock acquires the exclusive file lock and returns the function that
+// releases it.
+func (f *File) Lock() (unlock func(), err error) {
+	fl := flock.New(f.lockPath)
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+	locked, err := fl.TryLockContext(ctx, lockRetryDelay)
+	if err != nil || !locked {
+		return nil, fmt.Errorf("failed to acquire %s lock: %w", f.name, err)
+	}
+	return func() { _ = fl.Unlock() }, nil
+}
+
+// Load decodes the file into v and reports whether it exists. A missing file
+// leaves v untouched. A malformed file is moved aside and reported as a
+// *CorruptError. Must be called while holding the lock.
+func (f *File) Load(v any) (bool, error) {
+	// Defense-in-depth: reject symlinks to prevent redirection attacks
+	if err := f.rejectSymlink(); err != nil {
+		return false, err
+	}
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %s: %w", f.name, err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		backupPath := f.path + fmt.Sprintf(".corrupt-%d", time.Now().Unix())
+		if renameErr := os.Rename(f.path, backupPath); renameErr != nil {
+			return false, fmt.Errorf("%s is corrupted and could not be backed up: %w", f.name, err)
+		}
+		return false, &CorruptError{Name: f.name, Path: f.path, BackupPath: backupPath, Err: err}
+	}
+	return true, nil
+}
+
+// Save writes v to a temp file then atomically renames it over the file.
+// Must be called while holding the lock.
+func (f *File) Save(v any) error {
+	if err := f.rejectSymlink(); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", f.name, err)
+	}
+
+	// tunnels.json is written through tunnels-*.json.tmp.
+	base := filepath.Base(f.path)
+	ext := filepath.Ext(base)
+	tmpFile, err := os.CreateTemp(filepath.Dir(f.path), strings.TrimSuffix(base, ext)+"-*"+ext+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	if _, err := tmpFile.Write(raw); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmpFile.Chmod(0600); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to set temp file permissions: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file to %s: %w", f.name, err)
+	}
+	return nil
+}
+
+func (f *File) rejectSymlink() error {
+	if info, err := os.Lstat(f.path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s file is a symlink (rejected for security): %s", f.name, f.path)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package jsonfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoadRoundTrip(t *testing.T) {
+	f := New(filepath.Join(t.TempDir(), "state.json"), "state store")
+	var missing map[string]int
+	unlock, err := f.Lock()
+	if err != nil {
+		t.Fatalf("Lock returned error: %v", err)
+	}
+	if found, err := f.Load(&missing); err != nil || found {
+		t.Fatalf("Load of missing file = %v, %v", found, err)
+	}
+	if err := f.Save(map[string]int{"a": 1}); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	unlock()
+	info, err := os.Stat(f.Path())
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("saved file = %v, %v", info, err)
+	}
+	got := map[string]int{}
+	if found, err := f.Load(&got); err != nil || !found || got["a"] != 1 {
+		t.Fatalf("Load = %v, %v, %v", got, found, err)
+	}
+}
+
+func TestLoadMovesCorruptFileAside(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := New(path, "state store").Load(&map[string]int{})
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) || !strings.HasPrefix(corrupt.BackupPath, path+".corrupt-") {
+		t.Fatalf("Load error = %v", err)
+	}
+	if _, err := os.Stat(corrupt.BackupPath); err != nil {
+		t.Fatalf("backup missing: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("corrupt file still present: %v", err)
+	}
+}
+
+func TestSymlinksAreRejected(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.json")
+	link := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(target, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	f := New(link, "state store")
+	if _, err := f.Load(&map[string]int{}); err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Fatalf("Load error = %v", err)
+	}
+	if err := f.Save(map[string]int{}); err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Fatalf("Save error = %v", err)
+	}
+}